@@ -18,6 +18,7 @@ import (
 	ipAddresses "github.com/riportdev/riport/client/ip_addresses"
 
 	"github.com/riportdev/riport/share/random"
+	"github.com/riportdev/riport/share/ratelimit"
 
 	"github.com/denisbrodbeck/machineid"
 	"github.com/pkg/errors"
@@ -28,6 +29,7 @@ import (
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/net/proxy"
 
+	"github.com/riportdev/riport/client/inventory"
 	"github.com/riportdev/riport/client/monitoring"
 	"github.com/riportdev/riport/client/system"
 	"github.com/riportdev/riport/client/updates"
@@ -63,11 +65,15 @@ type Client struct {
 	cmdExec            system.CmdExecutor
 	systemInfo         system.SysInfo
 	updates            *updates.Updates
+	inventory          *inventory.Inventory
 	monitor            *monitoring.Monitor
 	ipAddressesFetcher *ipAddresses.Fetcher
 	serverCapabilities *models.Capabilities
 	filesAPI           files.FileAPI
 	watchdog           *Watchdog
+	// uploadBandwidthLimiter is shared by all file transfers to limit the bandwidth used by the client
+	uploadBandwidthLimiter *ratelimit.Limiter
+	directTunnels          *directTunnels
 
 	mu sync.RWMutex
 }
@@ -97,19 +103,22 @@ func NewClient(config *ClientConfigHolder, filesAPI files.FileAPI) (*Client, err
 
 	systemInfo := system.NewSystemInfo(cmdExec)
 	client := &Client{
-		SessionID:          sessionID,
-		Logger:             logger,
-		configHolder:       config,
-		running:            true,
-		runningc:           make(chan error, 1),
-		cmdExec:            cmdExec,
-		systemInfo:         systemInfo,
-		updates:            updates.New(logger, config.Client.UpdatesInterval),
-		monitor:            monitoring.NewMonitor(logger, config.Monitoring, systemInfo),
-		ipAddressesFetcher: ipAddresses.NewFetcher(logger, config.Client.IPAPIURL, config.Client.IPRefreshMin),
-		filesAPI:           filesAPI,
-		watchdog:           watchdog,
-	}
+		SessionID:              sessionID,
+		Logger:                 logger,
+		configHolder:           config,
+		running:                true,
+		runningc:               make(chan error, 1),
+		cmdExec:                cmdExec,
+		systemInfo:             systemInfo,
+		updates:                updates.New(logger, config.Client.UpdatesInterval),
+		inventory:              inventory.New(logger, config.Client.InventoryInterval),
+		monitor:                monitoring.NewMonitor(logger, config.Monitoring, systemInfo),
+		ipAddressesFetcher:     ipAddresses.NewFetcher(logger, config.Client.IPAPIURL, config.Client.IPRefreshMin),
+		filesAPI:               filesAPI,
+		uploadBandwidthLimiter: ratelimit.NewLimiter(config.FileReceptionConfig.BandwidthLimit),
+		watchdog:               watchdog,
+	}
+	client.directTunnels = newDirectTunnels(logger.Fork("direct tunnels"), &client.connStats)
 
 	client.sshConfig = &ssh.ClientConfig{
 		User:            config.Client.AuthUser,
@@ -160,6 +169,7 @@ func (c *Client) Start(ctx context.Context) error {
 	go c.connectionLoop(ctx, true)
 
 	c.updates.Start(ctx)
+	c.inventory.Start(ctx)
 
 	return nil
 }
@@ -292,6 +302,7 @@ func (c *Client) connectionLoop(ctx context.Context, withInitialSendRequestDelay
 
 		// Hand over the open SSH connection to subsystems running their own go routines
 		c.updates.SetConn(sshClientConn.Connection)
+		c.inventory.SetConn(sshClientConn.Connection)
 		c.ipAddressesFetcher.SetConn(sshClientConn.Connection)
 		c.monitor.SetConn(sshClientConn.Connection)
 
@@ -310,6 +321,7 @@ func (c *Client) connectionLoop(ctx context.Context, withInitialSendRequestDelay
 		c.setConn(nil)
 		c.monitor.Stop()
 		c.updates.Stop()
+		c.inventory.Stop()
 		c.ipAddressesFetcher.Stop()
 		cancelSwitchback()
 
@@ -436,7 +448,11 @@ func (c *Client) connect(server string) (*sshClientConnection, error) {
 
 	// perform SSH handshake on net.Conn
 	c.Debugf("Handshaking...")
-	sshClientConn, chans, reqs, err := ssh.NewClientConn(conn, "", c.sshConfig)
+	// the credentials change when the client enrolls with a token
+	c.mu.RLock()
+	sshConfig := *c.sshConfig
+	c.mu.RUnlock()
+	sshClientConn, chans, reqs, err := ssh.NewClientConn(conn, "", &sshConfig)
 	if err != nil {
 		if strings.Contains(err.Error(), "unable to authenticate") {
 			c.Errorf("Authentication failed")
@@ -461,6 +477,10 @@ func (c *Client) setupDialer() (d *websocket.Dialer, netDialer *net.Dialer, err
 		Subprotocols:     []string{chshare.ProtocolVersion},
 		NetDialContext:   netDialer.DialContext,
 	}
+	d.TLSClientConfig, err = c.tlsClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
 	if c.configHolder.Client.BindInterface != "" {
 		laddr, err := c.localAddrForInterface(c.configHolder.Client.BindInterface)
 		if err != nil {
@@ -630,6 +650,10 @@ func (c *Client) handleSSHRequests(ctx context.Context, sshClientConn *sshClient
 
 		case comm.RequestTypeUpdateClientAttributes:
 			resp, err = c.updateAttributes(r.Payload)
+		case comm.RequestTypeEnrollment:
+			resp, err = c.storeEnrollmentCredentials(r.Payload)
+		case comm.RequestTypeRenewCertificate:
+			resp, err = c.storeRenewedCertificate(r.Payload)
 		case comm.RequestTypeCheckPort:
 			resp, err = checkPort(r.Payload)
 			// fall through for err and resp handling
@@ -639,22 +663,30 @@ func (c *Client) handleSSHRequests(ctx context.Context, sshClientConn *sshClient
 		case comm.RequestTypeRefreshUpdatesStatus:
 			c.updates.Refresh()
 			// fall through to reply success with empty resp
+		case comm.RequestTypeRefreshInventory:
+			c.inventory.Refresh()
+			// fall through to reply success with empty resp
 		case comm.RequestTypePutCapabilities:
 			c.handlePutCapabilitiesRequest(ctx, r.Payload)
 			// fall through to reply success with empty resp
 		case comm.RequestTypeUpload:
-			uploadManager := NewSSHUploadManager(
-				c.Logger,
-				c.filesAPI,
-				c.configHolder,
-				sshClientConn.Connection,
-				system.SysUserProvider{},
-			)
-			resp, err = uploadManager.HandleUploadRequest(r.Payload)
+			resp, err = c.newUploadManager(sshClientConn.Connection).HandleUploadRequest(r.Payload)
+			// fall through for err and resp handling
+		case comm.RequestTypeUploadDir:
+			resp, err = c.newUploadManager(sshClientConn.Connection).HandleUploadDirRequest(r.Payload)
 			// fall through for err and resp handling
 		case comm.RequestTypeCheckTunnelAllowed:
 			resp, err = c.checkTunnelAllowed(r.Payload)
 			// fall through for err and resp handling
+		case comm.RequestTypeStartDirectTunnel:
+			resp, err = c.startDirectTunnel(r.Payload)
+			// fall through for err and resp handling
+		case comm.RequestTypeStopDirectTunnel:
+			err = c.stopDirectTunnel(r.Payload)
+			// fall through for err handling
+		case comm.RequestTypeDirectTunnelStatus:
+			resp, err = c.getDirectTunnelStatus(r.Payload)
+			// fall through for err and resp handling
 		case comm.RequestTypePing:
 			// use empty reply (and NOT empty resp with success reply)
 			_ = r.Reply(true, nil)
@@ -674,9 +706,28 @@ func (c *Client) handleSSHRequests(ctx context.Context, sshClientConn *sshClient
 		comm.ReplySuccessJSON(c.Logger, r, resp)
 	}
 
+	// the server requests direct tunnels again after reconnecting
+	c.directTunnels.StopAll()
+
 	c.Logger.Debugf("handleSSHRequests finished")
 }
 
+func (c *Client) newUploadManager(conn ssh.Conn) *UploadManager {
+	uploadManager := NewSSHUploadManager(
+		c.Logger,
+		c.filesAPI,
+		c.configHolder,
+		conn,
+		system.SysUserProvider{},
+	)
+	uploadManager.BandwidthLimiter = c.uploadBandwidthLimiter
+	if c.serverCapabilities != nil && c.serverCapabilities.UploadProgressVersion > 0 {
+		uploadManager.ProgressReporter = NewSSHUploadProgressReporter(c.Logger, conn)
+	}
+
+	return uploadManager
+}
+
 func checkPort(payload []byte) (*comm.CheckPortResponse, error) {
 	req, err := comm.DecodeCheckPortRequest(payload)
 	if err != nil {
@@ -0,0 +1,100 @@
+package chclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+// ClientCertificateFile and ClientKeyFile are the files in the data dir the certificate issued by the server is
+// stored in, unless other files are configured
+const (
+	ClientCertificateFile = "client_cert.pem"
+	ClientKeyFile         = "client_key.pem"
+)
+
+// tlsClientConfig returns the TLS config used to connect to servers with https:// URL. The client certificate is
+// read on each connection attempt, so a renewed certificate is used on the next reconnect.
+func (c *Client) tlsClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.configHolder.Client.TLSCAFile != "" {
+		caPEM, err := os.ReadFile(c.configHolder.Client.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read 'tls_ca_file': %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in 'tls_ca_file' %q", c.configHolder.Client.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// the certificate must not be read while it's being renewed
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	certFile, keyFile := c.configHolder.GetClientCertificatePaths()
+	if c.configHolder.Client.TLSCertFile == "" {
+		// no certificate was issued by the server yet
+		if _, err := os.Stat(certFile); errors.Is(err, os.ErrNotExist) {
+			return tlsConfig, nil
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load client certificate: %v", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	return tlsConfig, nil
+}
+
+// writeClientCertificate validates a certificate issued by the server and stores it with its key
+func (c *Client) writeClientCertificate(cert *models.ClientCertificate) (string, error) {
+	_, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.Key))
+	if err != nil {
+		return "", fmt.Errorf("invalid client certificate: %v", err)
+	}
+
+	certFile, keyFile := c.configHolder.GetClientCertificatePaths()
+	err = os.WriteFile(keyFile, []byte(cert.Key), 0600)
+	if err != nil {
+		return "", fmt.Errorf("can't write client certificate key: %v", err)
+	}
+	err = os.WriteFile(certFile, []byte(cert.Certificate), 0600)
+	if err != nil {
+		return "", fmt.Errorf("can't write client certificate: %v", err)
+	}
+
+	return certFile, nil
+}
+
+// storeRenewedCertificate replaces the client certificate before the current one expires
+func (c *Client) storeRenewedCertificate(payload []byte) (any, error) {
+	cert := &models.ClientCertificate{}
+	err := json.Unmarshal(payload, cert)
+	if err != nil {
+		return nil, fmt.Errorf("payload unreadable: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	certFile, err := c.writeClientCertificate(cert)
+	if err != nil {
+		return nil, err
+	}
+	c.Infof("Client certificate renewed, stored in %q", certFile)
+
+	return struct {
+		Status string `json:"status"`
+	}{Status: "OK"}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -64,7 +65,13 @@ func (c *ClientConfigHolder) ParseAndValidate(skipScriptsDirValidation bool) err
 		return fmt.Errorf("remote commands: %v", err)
 	}
 
-	c.Client.AuthUser, c.Client.AuthPass = chshare.ParseAuth(c.Client.Auth)
+	if err := c.parseAuth(); err != nil {
+		return err
+	}
+
+	if err := c.parseTLS(); err != nil {
+		return err
+	}
 
 	if err := c.parseRemoteScripts(skipScriptsDirValidation); err != nil {
 		return err
@@ -89,6 +96,40 @@ func (c *ClientConfigHolder) ParseAndValidate(skipScriptsDirValidation bool) err
 	return nil
 }
 
+// parseAuth uses the configured credentials, the credentials received on enrollment or the enrollment token, in that order
+func (c *ClientConfigHolder) parseAuth() error {
+	if c.Client.Auth != "" || c.Client.EnrollmentToken == "" {
+		c.Client.AuthUser, c.Client.AuthPass = chshare.ParseAuth(c.Client.Auth)
+		return nil
+	}
+
+	credentials, err := LoadEnrollmentCredentials(c.GetEnrollmentCredentialsPath())
+	if err != nil {
+		return err
+	}
+	if credentials != nil {
+		c.Client.AuthUser, c.Client.AuthPass = credentials.ClientAuthID, credentials.Password
+		return nil
+	}
+
+	tokenID, secret := chshare.ParseAuth(c.Client.EnrollmentToken)
+	if tokenID == "" || secret == "" {
+		return errors.New("'enrollment_token' must be in the form '<token-id>:<secret>'")
+	}
+	c.Client.AuthUser, c.Client.AuthPass = models.EnrollmentTokenUserPrefix+tokenID, secret
+	return nil
+}
+
+func (c *ClientConfigHolder) parseTLS() error {
+	if c.Client.TLSCertFile != "" && c.Client.TLSKeyFile == "" {
+		return errors.New("when 'tls_cert_file' is set, 'tls_key_file' must be set as well")
+	}
+	if c.Client.TLSCertFile == "" && c.Client.TLSKeyFile != "" {
+		return errors.New("when 'tls_key_file' is set, 'tls_cert_file' must be set as well")
+	}
+	return nil
+}
+
 func (c *ClientConfigHolder) ParseAndValidateMonitoring() error {
 	if c.Monitoring.Interval < DefaultMonitoringInterval {
 		c.Monitoring.Interval = DefaultMonitoringInterval
@@ -133,6 +174,10 @@ func (c *ClientConfigHolder) ParseAndValidateFilePushConfig() error {
 		}
 	}
 
+	if c.FileReceptionConfig.BandwidthLimit < 0 {
+		return errors.New("'file-reception.bandwidth_limit' cannot be negative")
+	}
+
 	return nil
 }
 
@@ -228,6 +273,13 @@ func (c *ClientConfigHolder) parseRemotes() error {
 		}
 	}
 
+	if c.Client.DirectTunnelsHost != "" && net.ParseIP(c.Client.DirectTunnelsHost) == nil {
+		return fmt.Errorf(`invalid "direct_tunnels_host" config: %q is not an IP address`, c.Client.DirectTunnelsHost)
+	}
+	if gw := c.Client.DirectTunnelsNATPMPGateway; gw != "" && (net.ParseIP(gw) == nil || net.ParseIP(gw).To4() == nil) {
+		return fmt.Errorf(`invalid "direct_tunnels_nat_pmp_gateway" config: %q is not an IPv4 address`, gw)
+	}
+
 	for _, s := range c.Client.Remotes {
 		r, err := models.NewRemote(s)
 		if err != nil {
@@ -304,6 +356,20 @@ func (c *ClientConfigHolder) GetScriptsDir() string {
 	return filepath.Join(c.Client.DataDir, "scripts")
 }
 
+// GetEnrollmentCredentialsPath returns the file the credentials received on enrollment are stored in
+func (c *ClientConfigHolder) GetEnrollmentCredentialsPath() string {
+	return filepath.Join(c.Client.DataDir, EnrollmentCredentialsFile)
+}
+
+// GetClientCertificatePaths returns the files of the client certificate and its key. Unless configured, the
+// certificate issued by the server is stored in the data dir.
+func (c *ClientConfigHolder) GetClientCertificatePaths() (certFile, keyFile string) {
+	if c.Client.TLSCertFile != "" {
+		return c.Client.TLSCertFile, c.Client.TLSKeyFile
+	}
+	return filepath.Join(c.Client.DataDir, ClientCertificateFile), filepath.Join(c.Client.DataDir, ClientKeyFile)
+}
+
 func (c *ClientConfigHolder) GetUploadDir() string {
 	return filepath.Join(c.Client.DataDir, files.DefaultUploadTempFolder)
 }
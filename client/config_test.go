@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
@@ -665,9 +666,10 @@ func TestConfigParseAndValidateFallbackServers(t *testing.T) {
 
 func TestConfigParseAndValidateFilePushConfig(t *testing.T) {
 	testCases := []struct {
-		Name          string
-		FilePushDeny  []string
-		ExpectedError string
+		Name           string
+		FilePushDeny   []string
+		BandwidthLimit int64
+		ExpectedError  string
 	}{
 		{
 			Name:          "nil deny globs",
@@ -694,6 +696,11 @@ func TestConfigParseAndValidateFilePushConfig(t *testing.T) {
 			FilePushDeny:  []string{"[a"},
 			ExpectedError: "invalid glob pattern [a: syntax error in pattern",
 		},
+		{
+			Name:           "negative bandwidth limit",
+			BandwidthLimit: -1,
+			ExpectedError:  "'file-reception.bandwidth_limit' cannot be negative",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -703,7 +710,8 @@ func TestConfigParseAndValidateFilePushConfig(t *testing.T) {
 
 			config := getDefaultValidMinConfig()
 			config.FileReceptionConfig = clientconfig.FileReceptionConfig{
-				Protected: tc.FilePushDeny,
+				Protected:      tc.FilePushDeny,
+				BandwidthLimit: tc.BandwidthLimit,
 			}
 
 			err := config.ParseAndValidate(true)
@@ -814,3 +822,86 @@ func TestConfigParseInterpreterAliases(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigParseAndValidateEnrollmentToken(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		Auth         string
+		Token        string
+		Stored       string
+		ExpectedUser string
+		ExpectedPass string
+		ExpectedErr  string
+	}{
+		{
+			Name:         "auth takes precedence",
+			Auth:         "test:pass123",
+			Token:        "abc:secret",
+			ExpectedUser: "test",
+			ExpectedPass: "pass123",
+		}, {
+			Name:         "not enrolled yet",
+			Token:        "abc:secret",
+			ExpectedUser: models.EnrollmentTokenUserPrefix + "abc",
+			ExpectedPass: "secret",
+		}, {
+			Name:         "enrolled",
+			Token:        "abc:secret",
+			Stored:       `{"client_auth_id":"client-1","password":"pswd"}`,
+			ExpectedUser: "client-1",
+			ExpectedPass: "pswd",
+		}, {
+			Name:        "invalid token",
+			Token:       "abc",
+			ExpectedErr: "'enrollment_token' must be in the form '<token-id>:<secret>'",
+		}, {
+			Name:        "invalid stored credentials",
+			Token:       "abc:secret",
+			Stored:      `{"client_auth_id":"client-1"}`,
+			ExpectedErr: "incomplete enrollment credentials",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := getDefaultValidMinConfig()
+			config.Client.DataDir = t.TempDir()
+			config.Client.Auth = tc.Auth
+			config.Client.EnrollmentToken = tc.Token
+			if tc.Stored != "" {
+				require.NoError(t, os.WriteFile(config.GetEnrollmentCredentialsPath(), []byte(tc.Stored), 0600))
+			}
+
+			err := config.ParseAndValidate(true)
+
+			if tc.ExpectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedUser, config.Client.AuthUser)
+			assert.Equal(t, tc.ExpectedPass, config.Client.AuthPass)
+		})
+	}
+}
+
+func TestConfigParseAndValidateTLS(t *testing.T) {
+	config := getDefaultValidMinConfig()
+	config.Client.TLSCertFile = "client.crt"
+	err := config.ParseAndValidate(true)
+	assert.EqualError(t, err, "when 'tls_cert_file' is set, 'tls_key_file' must be set as well")
+
+	config = getDefaultValidMinConfig()
+	config.Client.DataDir = "/var/lib/riport"
+	certFile, keyFile := config.GetClientCertificatePaths()
+	assert.Equal(t, filepath.Join("/var/lib/riport", ClientCertificateFile), certFile)
+	assert.Equal(t, filepath.Join("/var/lib/riport", ClientKeyFile), keyFile)
+
+	config.Client.TLSCertFile = "client.crt"
+	config.Client.TLSKeyFile = "client.key"
+	require.NoError(t, config.ParseAndValidate(true))
+	certFile, keyFile = config.GetClientCertificatePaths()
+	assert.Equal(t, "client.crt", certFile)
+	assert.Equal(t, "client.key", keyFile)
+}
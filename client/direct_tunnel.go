@@ -0,0 +1,340 @@
+package chclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+)
+
+var (
+	ErrDirectTunnelsDisabled  = errors.New("direct tunnels are disabled by client configuration")
+	ErrDirectTunnelWithoutACL = errors.New("direct tunnels require an ACL")
+)
+
+// directTunnels accepts tunnel connections on the client itself, so the traffic bypasses the server
+type directTunnels struct {
+	*logger.Logger
+	connStats *chshare.ConnStats
+
+	mu      sync.Mutex
+	tunnels map[string]*directTunnel
+}
+
+type directTunnel struct {
+	// Declare 64-bit integer before 32-bit for alignment when compiling Go on 32-bit ARM platforms
+	lastConnClose int64
+	connCount     int32
+	remote        string
+	listener      net.Listener
+	allowedIPs    atomic.Pointer[[]*net.IPNet]
+	// stopped is closed when the listener is closed, it ends the renewal of the port mapping
+	stopped chan struct{}
+
+	mappingMu sync.Mutex
+	// externalAddress is the address on the NAT gateway mapped to the listener, empty if it's not mapped
+	externalAddress string
+}
+
+func newDirectTunnels(logger *logger.Logger, connStats *chshare.ConnStats) *directTunnels {
+	return &directTunnels{
+		Logger:    logger,
+		connStats: connStats,
+		tunnels:   make(map[string]*directTunnel),
+	}
+}
+
+// Start opens a listener on a random port of the given host and returns its port. If a tunnel with the ID exists
+// with the same remote, only its ACL is updated. Tunnel IDs are reused, so a tunnel with another remote replaces it.
+func (d *directTunnels) Start(id, remote, host string, allowedIPs []*net.IPNet) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.tunnels[id]; ok {
+		if t.remote == remote {
+			t.allowedIPs.Store(&allowedIPs)
+			return t.listener.Addr().(*net.TCPAddr).Port, nil
+		}
+		d.Infof("direct tunnel %s is restarted with remote %s instead of %s", id, remote, t.remote)
+		if err := t.close(); err != nil {
+			d.Errorf("Failed to close direct tunnel %s: %v", id, err)
+		}
+		delete(d.tunnels, id)
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+
+	t := &directTunnel{
+		remote:   remote,
+		listener: l,
+		stopped:  make(chan struct{}),
+	}
+	t.allowedIPs.Store(&allowedIPs)
+	d.tunnels[id] = t
+
+	go d.listen(id, t)
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (d *directTunnels) Stop(id string) error {
+	d.mu.Lock()
+	t, ok := d.tunnels[id]
+	delete(d.tunnels, id)
+	d.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("direct tunnel %s not found", id)
+	}
+
+	return t.close()
+}
+
+// StopAll closes the listeners of all direct tunnels, established connections are kept
+func (d *directTunnels) StopAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, t := range d.tunnels {
+		if err := t.close(); err != nil {
+			d.Errorf("Failed to close direct tunnel %s: %v", id, err)
+		}
+		delete(d.tunnels, id)
+	}
+}
+
+// MapPort maps the port of a direct tunnel on the NAT gateway and returns the external address. The mapping is renewed
+// until the tunnel is stopped and deleted then.
+func (d *directTunnels) MapPort(id string, pmp *natPMP) (string, error) {
+	d.mu.Lock()
+	t, ok := d.tunnels[id]
+	d.mu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("direct tunnel %s not found", id)
+	}
+
+	t.mappingMu.Lock()
+	defer t.mappingMu.Unlock()
+	if t.externalAddress != "" {
+		return t.externalAddress, nil
+	}
+
+	externalIP, err := pmp.ExternalAddress()
+	if err != nil {
+		return "", err
+	}
+	port := t.listener.Addr().(*net.TCPAddr).Port
+	externalPort, lifetime, err := pmp.MapTCP(port, natPMPLifetime)
+	if err != nil {
+		return "", err
+	}
+
+	t.externalAddress = net.JoinHostPort(externalIP.String(), strconv.Itoa(externalPort))
+	d.Infof("direct tunnel %s is mapped to %s on the NAT gateway", id, t.externalAddress)
+	go d.renewPortMapping(id, t, pmp, port, lifetime)
+
+	return t.externalAddress, nil
+}
+
+func (d *directTunnels) renewPortMapping(id string, t *directTunnel, pmp *natPMP, port int, lifetime time.Duration) {
+	for {
+		select {
+		case <-t.stopped:
+			if _, _, err := pmp.MapTCP(port, 0); err != nil {
+				d.Errorf("Failed to delete port mapping of direct tunnel %s: %v", id, err)
+			}
+			return
+		case <-time.After(lifetime / 2):
+		}
+
+		var err error
+		_, lifetime, err = pmp.MapTCP(port, natPMPLifetime)
+		if err != nil {
+			d.Errorf("Failed to renew port mapping of direct tunnel %s: %v", id, err)
+			lifetime = 0
+		}
+		if lifetime < 2*time.Minute {
+			// retried within a minute
+			lifetime = 2 * time.Minute
+		}
+	}
+}
+
+func (d *directTunnels) LastActive(id string) (time.Time, error) {
+	d.mu.Lock()
+	t, ok := d.tunnels[id]
+	d.mu.Unlock()
+
+	if !ok {
+		return time.Time{}, fmt.Errorf("direct tunnel %s not found", id)
+	}
+
+	if atomic.LoadInt32(&t.connCount) > 0 {
+		return time.Now(), nil
+	}
+	return time.Unix(atomic.LoadInt64(&t.lastConnClose), 0), nil
+}
+
+func (d *directTunnels) listen(id string, t *directTunnel) {
+	l := d.Fork("direct tunnel#%s", id)
+	l.Infof("listening on %s", t.listener.Addr())
+
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.Errorf("Failed to accept connection: %v", err)
+			}
+			l.Infof("stopped")
+			return
+		}
+
+		if !t.checkAccess(conn.RemoteAddr()) {
+			l.Debugf("Access rejected. Remote addr: %s", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			atomic.AddInt32(&t.connCount, 1)
+			chshare.HandleTCPStream(l.Fork("conn#%d", d.connStats.New()), d.connStats, conn, t.remote)
+			atomic.AddInt32(&t.connCount, -1)
+			atomic.StoreInt64(&t.lastConnClose, time.Now().Unix())
+		}()
+	}
+}
+
+func (t *directTunnel) close() error {
+	close(t.stopped)
+	return t.listener.Close()
+}
+
+// checkAccess never allows connections without an ACL, the listener is reachable by anyone who can reach the client
+func (t *directTunnel) checkAccess(addr net.Addr) bool {
+	allowedIPs := *t.allowedIPs.Load()
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, allowed := range allowedIPs {
+		if allowed.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) startDirectTunnel(payload []byte) (*comm.StartDirectTunnelResponse, error) {
+	if !c.configHolder.Client.DirectTunnelsEnabled {
+		return nil, ErrDirectTunnelsDisabled
+	}
+
+	var req comm.StartDirectTunnelRequest
+	err := json.Unmarshal(payload, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := TunnelIsAllowed(c.configHolder.Client.TunnelAllowed, req.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf(`direct tunnel to %q not allowed with "tunnel_allowed" config`, req.Remote)
+	}
+
+	if len(req.AllowedIPs) == 0 {
+		return nil, ErrDirectTunnelWithoutACL
+	}
+	allowedIPs := make([]*net.IPNet, 0, len(req.AllowedIPs))
+	for _, cidr := range req.AllowedIPs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		allowedIPs = append(allowedIPs, ipNet)
+	}
+
+	host := c.configHolder.Client.DirectTunnelsHost
+	port, err := c.directTunnels.Start(req.ID, req.Remote, host, allowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []string{host}
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		ipv4, ipv6, err := c.localIPAddresses()
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(ipv4, ipv6...)
+	}
+
+	addresses := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		addresses = append(addresses, net.JoinHostPort(h, strconv.Itoa(port)))
+	}
+
+	if c.configHolder.Client.DirectTunnelsNATPMP {
+		externalAddress, err := c.mapDirectTunnelPort(req.ID)
+		if err != nil {
+			// the tunnel is still reachable within the client's network and relayed by the server
+			c.Logger.Infof("Failed to map port of direct tunnel %s with NAT-PMP: %v", req.ID, err)
+		} else {
+			addresses = append(addresses, externalAddress)
+		}
+	}
+
+	return &comm.StartDirectTunnelResponse{
+		Addresses: addresses,
+	}, nil
+}
+
+func (c *Client) mapDirectTunnelPort(id string) (string, error) {
+	pmp, err := newNATPMP(c.configHolder.Client.DirectTunnelsNATPMPGateway)
+	if err != nil {
+		return "", err
+	}
+
+	return c.directTunnels.MapPort(id, pmp)
+}
+
+func (c *Client) stopDirectTunnel(payload []byte) error {
+	var req comm.DirectTunnelRequest
+	err := json.Unmarshal(payload, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.directTunnels.Stop(req.ID)
+}
+
+func (c *Client) getDirectTunnelStatus(payload []byte) (*comm.DirectTunnelStatusResponse, error) {
+	var req comm.DirectTunnelRequest
+	err := json.Unmarshal(payload, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	lastActive, err := c.directTunnels.LastActive(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comm.DirectTunnelStatusResponse{
+		LastActive: lastActive,
+	}, nil
+}
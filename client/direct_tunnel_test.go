@@ -0,0 +1,122 @@
+package chclient
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/clientconfig"
+	"github.com/riportdev/riport/share/logger"
+)
+
+func TestDirectTunnels(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	log := logger.NewLogger("direct-tunnels-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	d := newDirectTunnels(log, &chshare.ConnStats{})
+
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	port, err := d.Start("1", target.Addr().String(), "127.0.0.1", []*net.IPNet{loopback})
+	require.NoError(t, err)
+
+	// starting the same tunnel again only updates the ACL
+	samePort, err := d.Start("1", target.Addr().String(), "127.0.0.1", []*net.IPNet{loopback})
+	require.NoError(t, err)
+	assert.Equal(t, port, samePort)
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	resp := make([]byte, 4)
+	_, err = io.ReadFull(conn, resp)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(resp))
+
+	lastActive, err := d.LastActive("1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), lastActive, time.Second)
+	conn.Close()
+
+	// connections from outside the ACL are closed immediately
+	_, other, err := net.ParseCIDR("198.51.100.0/24")
+	require.NoError(t, err)
+	_, err = d.Start("1", target.Addr().String(), "127.0.0.1", []*net.IPNet{other})
+	require.NoError(t, err)
+	conn, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(resp)
+	assert.Equal(t, io.EOF, err)
+	conn.Close()
+
+	// a new tunnel reusing the ID never forwards to the remote of the previous one
+	otherTarget, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer otherTarget.Close()
+	otherPort, err := d.Start("1", otherTarget.Addr().String(), "127.0.0.1", []*net.IPNet{loopback})
+	require.NoError(t, err)
+	assert.NotEqual(t, port, otherPort)
+	_, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	assert.Error(t, err)
+	conn, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(otherPort)))
+	require.NoError(t, err)
+	accepted, err := otherTarget.Accept()
+	require.NoError(t, err)
+	accepted.Close()
+	conn.Close()
+	port = otherPort
+
+	require.NoError(t, d.Stop("1"))
+	_, err = d.LastActive("1")
+	assert.EqualError(t, err, "direct tunnel 1 not found")
+	_, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	assert.Error(t, err)
+}
+
+func TestStartDirectTunnelRequiresACL(t *testing.T) {
+	log := logger.NewLogger("direct-tunnels-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	c := Client{
+		Logger: log,
+		configHolder: &ClientConfigHolder{
+			Config: &clientconfig.Config{
+				Client: clientconfig.ClientConfig{
+					DirectTunnelsEnabled: true,
+					DirectTunnelsHost:    "127.0.0.1",
+				},
+			},
+		},
+		directTunnels: newDirectTunnels(log, &chshare.ConnStats{}),
+	}
+
+	_, err := c.startDirectTunnel([]byte(`{"id": "1", "remote": "127.0.0.1:22"}`))
+	assert.Equal(t, ErrDirectTunnelWithoutACL, err)
+
+	// a tunnel without ACL never accepts connections
+	tunnel := &directTunnel{}
+	var noACL []*net.IPNet
+	tunnel.allowedIPs.Store(&noACL)
+	assert.False(t, tunnel.checkAccess(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}))
+}
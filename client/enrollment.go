@@ -0,0 +1,86 @@
+package chclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+// EnrollmentCredentialsFile is the file in the data dir the credentials received on enrollment are stored in
+const EnrollmentCredentialsFile = "client_auth.json"
+
+// LoadEnrollmentCredentials returns the stored credentials or nil if the client didn't enroll yet
+func LoadEnrollmentCredentials(path string) (*models.EnrollmentCredentials, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read enrollment credentials: %v", err)
+	}
+
+	credentials := &models.EnrollmentCredentials{}
+	err = json.Unmarshal(data, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode enrollment credentials from %q: %v", path, err)
+	}
+	if credentials.ClientAuthID == "" || credentials.Password == "" {
+		return nil, fmt.Errorf("incomplete enrollment credentials in %q", path)
+	}
+
+	return credentials, nil
+}
+
+// storeEnrollmentCredentials saves the credentials created by the server when the client enrolled with a token,
+// they are used instead of the token from now on
+func (c *Client) storeEnrollmentCredentials(payload []byte) (any, error) {
+	credentials := &models.EnrollmentCredentials{}
+	err := json.Unmarshal(payload, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("payload unreadable: %v", err)
+	}
+	if credentials.ClientAuthID == "" || credentials.Password == "" {
+		return nil, fmt.Errorf("incomplete enrollment credentials")
+	}
+
+	// the certificate is stored in separate files, so it can be renewed
+	cert := credentials.Certificate
+	credentials.Certificate = nil
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, fmt.Errorf("can't serialize enrollment credentials: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cert != nil {
+		certFile, err := c.writeClientCertificate(cert)
+		if err != nil {
+			return nil, err
+		}
+		c.Infof("Client certificate stored in %q", certFile)
+	}
+
+	path := c.configHolder.GetEnrollmentCredentialsPath()
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("can't write enrollment credentials to file: %v", err)
+	}
+
+	c.configHolder.Client.AuthUser = credentials.ClientAuthID
+	c.configHolder.Client.AuthPass = credentials.Password
+	c.sshConfig.User = credentials.ClientAuthID
+	c.sshConfig.Auth = []ssh.AuthMethod{ssh.Password(credentials.Password)}
+
+	c.Infof("Enrolled with client auth id %q, credentials stored in %q", credentials.ClientAuthID, path)
+
+	return struct {
+		Status string `json:"status"`
+	}{Status: "OK"}, nil
+}
@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	gopsnet "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/riportdev/riport/client/updates"
+	"github.com/riportdev/riport/share/models"
+)
+
+// NICCollector lists the network interfaces except loopback
+type NICCollector struct {
+	interfaces func() ([]net.Interface, error)
+}
+
+func NewNICCollector() *NICCollector {
+	return &NICCollector{
+		interfaces: net.Interfaces,
+	}
+}
+
+func (c *NICCollector) Category() string {
+	return models.InventoryCategoryNIC
+}
+
+func (c *NICCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	interfaces, err := c.interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*models.InventoryItem, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		details := map[string]string{
+			"mac": iface.HardwareAddr.String(),
+			"mtu": strconv.Itoa(iface.MTU),
+			"up":  strconv.FormatBool(iface.Flags&net.FlagUp != 0),
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			addresses := make([]string, 0, len(addrs))
+			for _, addr := range addrs {
+				addresses = append(addresses, addr.String())
+			}
+			details["addresses"] = strings.Join(addresses, ",")
+		}
+		items = append(items, &models.InventoryItem{
+			Name:    iface.Name,
+			Details: details,
+		})
+	}
+	return items, nil
+}
+
+// ListeningPortCollector lists the tcp ports in listen state and the bound udp ports
+type ListeningPortCollector struct {
+	connections func(ctx context.Context) ([]gopsnet.ConnectionStat, error)
+}
+
+func NewListeningPortCollector() *ListeningPortCollector {
+	return &ListeningPortCollector{
+		connections: func(ctx context.Context) ([]gopsnet.ConnectionStat, error) {
+			return gopsnet.ConnectionsWithContext(ctx, "inet")
+		},
+	}
+}
+
+func (c *ListeningPortCollector) Category() string {
+	return models.InventoryCategoryListeningPort
+}
+
+func (c *ListeningPortCollector) Collect(ctx context.Context) ([]*models.InventoryItem, error) {
+	connections, err := c.connections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	items := []*models.InventoryItem{}
+	for _, conn := range connections {
+		protocol := protocolName(conn)
+		if protocol == "" {
+			continue
+		}
+		if protocol == "tcp" && conn.Status != "LISTEN" {
+			continue
+		}
+		if protocol == "udp" && conn.Raddr.Port != 0 {
+			continue
+		}
+
+		address := net.JoinHostPort(conn.Laddr.IP, strconv.FormatUint(uint64(conn.Laddr.Port), 10))
+		name := fmt.Sprintf("%s/%s", protocol, address)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		items = append(items, &models.InventoryItem{
+			Name: name,
+			Details: map[string]string{
+				"protocol": protocol,
+				"address":  conn.Laddr.IP,
+				"port":     strconv.FormatUint(uint64(conn.Laddr.Port), 10),
+			},
+		})
+	}
+	return items, nil
+}
+
+func protocolName(conn gopsnet.ConnectionStat) string {
+	const (
+		sockStream = 1
+		sockDgram  = 2
+	)
+	switch conn.Type {
+	case sockStream:
+		return "tcp"
+	case sockDgram:
+		return "udp"
+	}
+	return ""
+}
+
+func run(ctx context.Context, runner updates.Runner, args ...string) (string, error) {
+	out, err := runner.Run(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,248 @@
+//go:build !windows
+// +build !windows
+
+package inventory
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/riportdev/riport/client/updates"
+	"github.com/riportdev/riport/share/models"
+)
+
+func defaultCollectors() []Collector {
+	return []Collector{
+		NewPackageCollector(),
+		NewDiskCollector("/sys"),
+		NewNICCollector(),
+		NewListeningPortCollector(),
+		NewUserCollector("/etc/passwd"),
+		NewSystemCollector("/sys"),
+	}
+}
+
+// PackageCollector lists the packages installed by dpkg or rpm
+type PackageCollector struct {
+	runner  updates.Runner
+	dpkgCmd []string
+	rpmCmd  []string
+}
+
+func NewPackageCollector() *PackageCollector {
+	return &PackageCollector{
+		runner:  &updates.RunnerImpl{},
+		dpkgCmd: []string{"dpkg-query", "-W", "-f", `${binary:Package}\t${Version}\t${Architecture}\t${db:Status-Status}\n`},
+		rpmCmd:  []string{"rpm", "-qa", "--queryformat", `%{NAME}.%{ARCH}\t%{VERSION}-%{RELEASE}\t%{ARCH}\tinstalled\n`},
+	}
+}
+
+func (c *PackageCollector) Category() string {
+	return models.InventoryCategoryPackage
+}
+
+func (c *PackageCollector) Collect(ctx context.Context) ([]*models.InventoryItem, error) {
+	out, dpkgErr := run(ctx, c.runner, c.dpkgCmd...)
+	if dpkgErr == nil {
+		return parsePackages(out), nil
+	}
+	out, rpmErr := run(ctx, c.runner, c.rpmCmd...)
+	if rpmErr == nil {
+		return parsePackages(out), nil
+	}
+	return nil, errors.New("no supported package manager found")
+}
+
+// parsePackages parses the tab separated name, version, architecture and status of each package
+func parsePackages(out string) []*models.InventoryItem {
+	items := []*models.InventoryItem{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 || fields[0] == "" || fields[3] != "installed" {
+			continue
+		}
+		items = append(items, &models.InventoryItem{
+			Name:    fields[0],
+			Version: fields[1],
+			Details: map[string]string{
+				"architecture": fields[2],
+			},
+		})
+	}
+	return items
+}
+
+// DiskCollector lists the block devices of the kernel except virtual ones like loop and ram devices
+type DiskCollector struct {
+	sysDir string
+}
+
+func NewDiskCollector(sysDir string) *DiskCollector {
+	return &DiskCollector{
+		sysDir: sysDir,
+	}
+}
+
+func (c *DiskCollector) Category() string {
+	return models.InventoryCategoryDisk
+}
+
+func (c *DiskCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	blockDir := filepath.Join(c.sysDir, "block")
+	entries, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*models.InventoryItem{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if isVirtualDisk(name) {
+			continue
+		}
+		dir := filepath.Join(blockDir, name)
+		details := map[string]string{
+			"vendor": readSysFile(filepath.Join(dir, "device", "vendor")),
+			"model":  readSysFile(filepath.Join(dir, "device", "model")),
+			"serial": readSysFile(filepath.Join(dir, "device", "serial")),
+		}
+		// the size is given in 512 byte sectors regardless of the device
+		if sectors, err := strconv.ParseUint(readSysFile(filepath.Join(dir, "size")), 10, 64); err == nil {
+			details["size"] = strconv.FormatUint(sectors*512, 10)
+		}
+		if rotational := readSysFile(filepath.Join(dir, "queue", "rotational")); rotational != "" {
+			details["rotational"] = strconv.FormatBool(rotational == "1")
+		}
+		items = append(items, &models.InventoryItem{
+			Name:    name,
+			Version: readSysFile(filepath.Join(dir, "device", "rev")),
+			Details: details,
+		})
+	}
+	return items, nil
+}
+
+func isVirtualDisk(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "zram", "dm-", "md", "sr", "fd"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// UserCollector lists the local users of the passwd file
+type UserCollector struct {
+	passwdFile string
+}
+
+func NewUserCollector(passwdFile string) *UserCollector {
+	return &UserCollector{
+		passwdFile: passwdFile,
+	}
+}
+
+func (c *UserCollector) Category() string {
+	return models.InventoryCategoryUser
+}
+
+func (c *UserCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	f, err := os.Open(c.passwdFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items := []*models.InventoryItem{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) != 7 {
+			continue
+		}
+		items = append(items, &models.InventoryItem{
+			Name: fields[0],
+			Details: map[string]string{
+				"uid":   fields[2],
+				"gid":   fields[3],
+				"home":  fields[5],
+				"shell": fields[6],
+			},
+		})
+	}
+	return items, scanner.Err()
+}
+
+// SystemCollector reads the BIOS and DMI info exposed by the kernel
+type SystemCollector struct {
+	sysDir string
+}
+
+func NewSystemCollector(sysDir string) *SystemCollector {
+	return &SystemCollector{
+		sysDir: sysDir,
+	}
+}
+
+func (c *SystemCollector) Category() string {
+	return models.InventoryCategorySystem
+}
+
+func (c *SystemCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	dmiDir := filepath.Join(c.sysDir, "class", "dmi", "id")
+	if _, err := os.Stat(dmiDir); err != nil {
+		return nil, err
+	}
+
+	read := func(name string) string {
+		return readSysFile(filepath.Join(dmiDir, name))
+	}
+	return []*models.InventoryItem{
+		{
+			Name:    "bios",
+			Version: read("bios_version"),
+			Details: map[string]string{
+				"vendor": read("bios_vendor"),
+				"date":   read("bios_date"),
+			},
+		},
+		{
+			Name:    "system",
+			Version: read("product_version"),
+			Details: map[string]string{
+				"vendor": read("sys_vendor"),
+				"model":  read("product_name"),
+				// readable by root only
+				"serial": read("product_serial"),
+				"uuid":   read("product_uuid"),
+			},
+		},
+		{
+			Name:    "board",
+			Version: read("board_version"),
+			Details: map[string]string{
+				"vendor": read("board_vendor"),
+				"model":  read("board_name"),
+			},
+		},
+	}, nil
+}
+
+// readSysFile returns the trimmed content of a file or an empty string if it's missing or not readable
+func readSysFile(name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
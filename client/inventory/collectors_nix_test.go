@@ -0,0 +1,189 @@
+//go:build !windows
+// +build !windows
+
+package inventory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+type mockRunner struct {
+	outputs map[string]string
+	errors  map[string]error
+}
+
+func newMockRunner() *mockRunner {
+	return &mockRunner{
+		outputs: make(map[string]string),
+		errors:  make(map[string]error),
+	}
+}
+
+func (r *mockRunner) Run(ctx context.Context, args ...string) (string, error) {
+	key := strings.Join(args, " ")
+	if _, ok := r.outputs[key]; !ok {
+		return "", errors.New("command not found")
+	}
+	return r.outputs[key], r.errors[key]
+}
+
+func (r *mockRunner) Register(args []string, output string, err error) {
+	key := strings.Join(args, " ")
+	r.outputs[key] = output
+	r.errors[key] = err
+}
+
+func TestPackageCollector(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		Name          string
+		DpkgOutput    string
+		RpmOutput     string
+		ExpectedItems []*models.InventoryItem
+		ExpectedError string
+	}{
+		{
+			Name: "dpkg",
+			DpkgOutput: "openssl\t3.0.2-0ubuntu1.10\tamd64\tinstalled\n" +
+				"libc6:i386\t2.35-0ubuntu3.1\ti386\tinstalled\n" +
+				"removed-pkg\t1.0\tamd64\tconfig-files\n",
+			ExpectedItems: []*models.InventoryItem{
+				{Name: "openssl", Version: "3.0.2-0ubuntu1.10", Details: map[string]string{"architecture": "amd64"}},
+				{Name: "libc6:i386", Version: "2.35-0ubuntu3.1", Details: map[string]string{"architecture": "i386"}},
+			},
+		},
+		{
+			Name:      "rpm",
+			RpmOutput: "openssl.x86_64\t3.0.7-16.el9\tx86_64\tinstalled\ngpg-pubkey.(none)\t8483c65d-5ccc5b19\t(none)\tinstalled\n",
+			ExpectedItems: []*models.InventoryItem{
+				{Name: "openssl.x86_64", Version: "3.0.7-16.el9", Details: map[string]string{"architecture": "x86_64"}},
+				{Name: "gpg-pubkey.(none)", Version: "8483c65d-5ccc5b19", Details: map[string]string{"architecture": "(none)"}},
+			},
+		},
+		{
+			Name:          "no package manager",
+			ExpectedError: "no supported package manager found",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			mr := newMockRunner()
+			c := NewPackageCollector()
+			c.runner = mr
+			if tc.DpkgOutput != "" {
+				mr.Register(c.dpkgCmd, tc.DpkgOutput, nil)
+			}
+			if tc.RpmOutput != "" {
+				mr.Register(c.rpmCmd, tc.RpmOutput, nil)
+			}
+
+			items, err := c.Collect(ctx)
+
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedItems, items)
+		})
+	}
+}
+
+func writeSysFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content+"\n"), 0600))
+	}
+}
+
+func TestDiskCollector(t *testing.T) {
+	sysDir := t.TempDir()
+	writeSysFiles(t, sysDir, map[string]string{
+		"block/sda/size":             "1953525168",
+		"block/sda/device/vendor":    "ATA",
+		"block/sda/device/model":     "Samsung SSD 860",
+		"block/sda/device/rev":       "RVT04B6Q",
+		"block/sda/queue/rotational": "0",
+		"block/nvme0n1/size":         "2048",
+		"block/loop0/size":           "100",
+	})
+
+	items, err := NewDiskCollector(sysDir).Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []*models.InventoryItem{
+		{
+			Name: "nvme0n1",
+			Details: map[string]string{
+				"vendor": "",
+				"model":  "",
+				"serial": "",
+				"size":   "1048576",
+			},
+		},
+		{
+			Name:    "sda",
+			Version: "RVT04B6Q",
+			Details: map[string]string{
+				"vendor":     "ATA",
+				"model":      "Samsung SSD 860",
+				"serial":     "",
+				"size":       "1000204886016",
+				"rotational": "false",
+			},
+		},
+	}, items)
+}
+
+func TestUserCollector(t *testing.T) {
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	err := os.WriteFile(passwd, []byte("# local users\nroot:x:0:0:root:/root:/bin/bash\n\ninvalid\nadmin:x:1000:1000:Admin,,,:/home/admin:/bin/zsh\n"), 0600)
+	require.NoError(t, err)
+
+	items, err := NewUserCollector(passwd).Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []*models.InventoryItem{
+		{Name: "root", Details: map[string]string{"uid": "0", "gid": "0", "home": "/root", "shell": "/bin/bash"}},
+		{Name: "admin", Details: map[string]string{"uid": "1000", "gid": "1000", "home": "/home/admin", "shell": "/bin/zsh"}},
+	}, items)
+}
+
+func TestSystemCollector(t *testing.T) {
+	sysDir := t.TempDir()
+	writeSysFiles(t, sysDir, map[string]string{
+		"class/dmi/id/bios_vendor":  "Dell Inc.",
+		"class/dmi/id/bios_version": "2.17.0",
+		"class/dmi/id/bios_date":    "03/14/2023",
+		"class/dmi/id/sys_vendor":   "Dell Inc.",
+		"class/dmi/id/product_name": "PowerEdge R640",
+	})
+
+	items, err := NewSystemCollector(sysDir).Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, &models.InventoryItem{
+		Name:    "bios",
+		Version: "2.17.0",
+		Details: map[string]string{"vendor": "Dell Inc.", "date": "03/14/2023"},
+	}, items[0])
+	assert.Equal(t, "PowerEdge R640", items[1].Details["model"])
+
+	_, err = NewSystemCollector(t.TempDir()).Collect(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+//go:build windows
+// +build windows
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/riportdev/riport/client/updates"
+	"github.com/riportdev/riport/share/models"
+)
+
+func defaultCollectors() []Collector {
+	return []Collector{
+		NewPackageCollector(),
+		NewDiskCollector(),
+		NewNICCollector(),
+		NewListeningPortCollector(),
+		NewUserCollector(),
+		NewSystemCollector(),
+	}
+}
+
+var uninstallKeys = []struct {
+	root registry.Key
+	path string
+}{
+	{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`},
+	{registry.LOCAL_MACHINE, `SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall`},
+}
+
+// PackageCollector lists the installed programs registered for uninstall in the registry
+type PackageCollector struct{}
+
+func NewPackageCollector() *PackageCollector {
+	return &PackageCollector{}
+}
+
+func (c *PackageCollector) Category() string {
+	return models.InventoryCategoryPackage
+}
+
+func (c *PackageCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	seen := make(map[string]bool)
+	items := []*models.InventoryItem{}
+	for _, uk := range uninstallKeys {
+		key, err := registry.OpenKey(uk.root, uk.path, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			// the 32 bit node is missing on 32 bit windows
+			continue
+		}
+		names, err := key.ReadSubKeyNames(-1)
+		key.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			item := readUninstallEntry(uk.root, uk.path+`\`+name)
+			if item == nil || seen[item.Name] {
+				continue
+			}
+			seen[item.Name] = true
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func readUninstallEntry(root registry.Key, path string) *models.InventoryItem {
+	key, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
+	if err != nil {
+		return nil
+	}
+	defer key.Close()
+
+	name, _, err := key.GetStringValue("DisplayName")
+	if err != nil || name == "" {
+		return nil
+	}
+	// updates and components are listed under their parent program
+	if isComponent, _, err := key.GetIntegerValue("SystemComponent"); err == nil && isComponent == 1 {
+		return nil
+	}
+	version, _, _ := key.GetStringValue("DisplayVersion")
+	publisher, _, _ := key.GetStringValue("Publisher")
+	installDate, _, _ := key.GetStringValue("InstallDate")
+	return &models.InventoryItem{
+		Name:    name,
+		Version: version,
+		Details: map[string]string{
+			"publisher":    publisher,
+			"install_date": installDate,
+		},
+	}
+}
+
+// DiskCollector lists the physical disks known to WMI
+type DiskCollector struct {
+	runner updates.Runner
+	cmd    []string
+}
+
+func NewDiskCollector() *DiskCollector {
+	return &DiskCollector{
+		runner: &updates.RunnerImpl{},
+		cmd:    powershellJSON("Get-CimInstance Win32_DiskDrive | Select-Object DeviceID,Model,Manufacturer,SerialNumber,FirmwareRevision,Size,InterfaceType"),
+	}
+}
+
+func (c *DiskCollector) Category() string {
+	return models.InventoryCategoryDisk
+}
+
+func (c *DiskCollector) Collect(ctx context.Context) ([]*models.InventoryItem, error) {
+	out, err := run(ctx, c.runner, c.cmd...)
+	if err != nil {
+		return nil, err
+	}
+	var disks []struct {
+		DeviceID         string
+		Model            string
+		Manufacturer     string
+		SerialNumber     string
+		FirmwareRevision string
+		Size             uint64
+		InterfaceType    string
+	}
+	if err := unmarshalPowershellJSON(out, &disks); err != nil {
+		return nil, err
+	}
+
+	items := make([]*models.InventoryItem, 0, len(disks))
+	for _, d := range disks {
+		items = append(items, &models.InventoryItem{
+			Name:    d.DeviceID,
+			Version: d.FirmwareRevision,
+			Details: map[string]string{
+				"vendor":    d.Manufacturer,
+				"model":     d.Model,
+				"serial":    d.SerialNumber,
+				"size":      strconv.FormatUint(d.Size, 10),
+				"interface": d.InterfaceType,
+			},
+		})
+	}
+	return items, nil
+}
+
+// UserCollector lists the local user accounts
+type UserCollector struct {
+	runner updates.Runner
+	cmd    []string
+}
+
+func NewUserCollector() *UserCollector {
+	return &UserCollector{
+		runner: &updates.RunnerImpl{},
+		cmd:    powershellJSON("Get-LocalUser | Select-Object Name,Enabled,@{n='SID';e={$_.SID.Value}},Description"),
+	}
+}
+
+func (c *UserCollector) Category() string {
+	return models.InventoryCategoryUser
+}
+
+func (c *UserCollector) Collect(ctx context.Context) ([]*models.InventoryItem, error) {
+	out, err := run(ctx, c.runner, c.cmd...)
+	if err != nil {
+		return nil, err
+	}
+	var users []struct {
+		Name        string
+		Enabled     bool
+		SID         string
+		Description string
+	}
+	if err := unmarshalPowershellJSON(out, &users); err != nil {
+		return nil, err
+	}
+
+	items := make([]*models.InventoryItem, 0, len(users))
+	for _, u := range users {
+		items = append(items, &models.InventoryItem{
+			Name: u.Name,
+			Details: map[string]string{
+				"sid":         u.SID,
+				"enabled":     strconv.FormatBool(u.Enabled),
+				"description": u.Description,
+			},
+		})
+	}
+	return items, nil
+}
+
+const biosKey = `HARDWARE\DESCRIPTION\System\BIOS`
+
+// SystemCollector reads the BIOS and DMI info windows stores in the registry
+type SystemCollector struct{}
+
+func NewSystemCollector() *SystemCollector {
+	return &SystemCollector{}
+}
+
+func (c *SystemCollector) Category() string {
+	return models.InventoryCategorySystem
+}
+
+func (c *SystemCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, biosKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	read := func(name string) string {
+		value, _, _ := key.GetStringValue(name)
+		return value
+	}
+	return []*models.InventoryItem{
+		{
+			Name:    "bios",
+			Version: read("BIOSVersion"),
+			Details: map[string]string{
+				"vendor": read("BIOSVendor"),
+				"date":   read("BIOSReleaseDate"),
+			},
+		},
+		{
+			Name:    "system",
+			Version: read("SystemVersion"),
+			Details: map[string]string{
+				"vendor": read("SystemManufacturer"),
+				"model":  read("SystemProductName"),
+			},
+		},
+		{
+			Name:    "board",
+			Version: read("BaseBoardVersion"),
+			Details: map[string]string{
+				"vendor": read("BaseBoardManufacturer"),
+				"model":  read("BaseBoardProduct"),
+			},
+		},
+	}, nil
+}
+
+func powershellJSON(command string) []string {
+	return []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-Command", command + " | ConvertTo-Json -Compress"}
+}
+
+// unmarshalPowershellJSON handles ConvertTo-Json returning a single object instead of an array of one and nothing for none
+func unmarshalPowershellJSON[T any](out string, v *[]T) error {
+	if out == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(out), v); err == nil {
+		return nil
+	}
+	var single T
+	if err := json.Unmarshal([]byte(out), &single); err != nil {
+		return err
+	}
+	*v = []T{single}
+	return nil
+}
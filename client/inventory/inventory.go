@@ -0,0 +1,151 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+// Collector gathers the inventory items of one category
+type Collector interface {
+	Category() string
+	Collect(context.Context) ([]*models.InventoryItem, error)
+}
+
+type Inventory struct {
+	// mtx protects both conn and inventory
+	mtx       sync.RWMutex
+	conn      ssh.Conn
+	inventory *models.Inventory
+
+	interval    time.Duration
+	refreshChan chan struct{}
+
+	collectors []Collector
+	logger     *logger.Logger
+}
+
+func New(logger *logger.Logger, interval time.Duration) *Inventory {
+	return &Inventory{
+		interval:    interval,
+		refreshChan: make(chan struct{}),
+		collectors:  defaultCollectors(),
+		logger:      logger,
+	}
+}
+
+func (i *Inventory) Start(ctx context.Context) {
+	if i.interval <= 0 {
+		return
+	}
+
+	go i.refreshLoop(ctx)
+}
+
+func (i *Inventory) Refresh() {
+	select {
+	case i.refreshChan <- struct{}{}:
+	default:
+	}
+}
+
+func (i *Inventory) refreshLoop(ctx context.Context) {
+	for {
+		i.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			i.logger.Debugf("inventory refreshLoop finished")
+			return
+		// acceptable use of time.After, as the number of triggered refreshes is small
+		case <-time.After(i.interval):
+		case <-i.refreshChan:
+		}
+	}
+}
+
+func (i *Inventory) refresh(ctx context.Context) {
+	inventory := i.collect(ctx)
+
+	if len(inventory.Errors) > 0 {
+		i.logger.Infof("Inventory collected with %d items, %d collectors failed: %v", len(inventory.Items), len(inventory.Errors), inventory.Errors)
+	} else {
+		i.logger.Infof("Inventory collected with %d items", len(inventory.Items))
+	}
+
+	i.mtx.Lock()
+	i.inventory = inventory
+	i.mtx.Unlock()
+
+	go i.sendInventory()
+}
+
+func (i *Inventory) collect(ctx context.Context) *models.Inventory {
+	inventory := &models.Inventory{
+		Items: []*models.InventoryItem{},
+	}
+	for _, c := range i.collectors {
+		items, err := c.Collect(ctx)
+		if err != nil {
+			if inventory.Errors == nil {
+				inventory.Errors = make(map[string]string)
+			}
+			inventory.Errors[c.Category()] = err.Error()
+			continue
+		}
+		for _, item := range items {
+			item.Category = c.Category()
+			inventory.Items = append(inventory.Items, item)
+		}
+	}
+	sort.SliceStable(inventory.Items, func(a, b int) bool {
+		return inventory.Items[a].Key() < inventory.Items[b].Key()
+	})
+	inventory.CollectedAt = time.Now()
+
+	return inventory
+}
+
+// sendInventory sends the inventory in background
+func (i *Inventory) sendInventory() {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	if i.conn != nil && i.inventory != nil {
+		data, err := json.Marshal(i.inventory)
+		if err != nil {
+			i.logger.Errorf("Could not marshal json for inventory: %v", err)
+			return
+		}
+
+		_, _, err = i.conn.SendRequest(comm.RequestTypeInventory, false, data)
+		if err != nil {
+			i.logger.Errorf("Could not send inventory: %v", err)
+			return
+		}
+	}
+}
+
+// SetConn sets the connection of a (re)connected client, an already collected inventory is sent right away
+func (i *Inventory) SetConn(c ssh.Conn) {
+	i.mtx.Lock()
+	i.conn = c
+	i.mtx.Unlock()
+
+	go i.sendInventory()
+}
+
+func (i *Inventory) Stop() {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	i.conn = nil
+}
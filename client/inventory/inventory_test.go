@@ -0,0 +1,112 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+type mockCollector struct {
+	category string
+	items    []*models.InventoryItem
+	err      error
+}
+
+func (c *mockCollector) Category() string {
+	return c.category
+}
+
+func (c *mockCollector) Collect(context.Context) ([]*models.InventoryItem, error) {
+	items := make([]*models.InventoryItem, 0, len(c.items))
+	for _, item := range c.items {
+		item := *item
+		items = append(items, &item)
+	}
+	return items, c.err
+}
+
+type mockSSHConn struct {
+	ssh.Conn
+
+	requests chan []byte
+}
+
+func (c *mockSSHConn) SendRequest(name string, _ bool, data []byte) (bool, []byte, error) {
+	if name == comm.RequestTypeInventory {
+		c.requests <- data
+	}
+	return false, nil, nil
+}
+
+func TestInventory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inventory := New(logger.NewLogger("test", logger.NewLogOutput(""), logger.LogLevelDebug), time.Hour)
+	inventory.collectors = []Collector{
+		&mockCollector{
+			category: models.InventoryCategoryUser,
+			items:    []*models.InventoryItem{{Name: "root"}, {Name: "admin"}},
+		},
+		&mockCollector{
+			category: models.InventoryCategoryPackage,
+			err:      errors.New("no supported package manager found"),
+		},
+		&mockCollector{
+			category: models.InventoryCategoryDisk,
+			items:    []*models.InventoryItem{{Name: "sda", Details: map[string]string{"model": "SSD"}}},
+		},
+	}
+	mockConn := &mockSSHConn{
+		requests: make(chan []byte, 10),
+	}
+	inventory.SetConn(mockConn)
+	inventory.Start(ctx)
+
+	var result models.Inventory
+	require.NoError(t, json.Unmarshal(<-mockConn.requests, &result))
+
+	assert.WithinDuration(t, time.Now(), result.CollectedAt, time.Second)
+	assert.Equal(t, map[string]string{"package": "no supported package manager found"}, result.Errors)
+	assert.Equal(t, []*models.InventoryItem{
+		{Category: models.InventoryCategoryDisk, Name: "sda", Details: map[string]string{"model": "SSD"}},
+		{Category: models.InventoryCategoryUser, Name: "admin"},
+		{Category: models.InventoryCategoryUser, Name: "root"},
+	}, result.Items)
+
+	// refresh is ignored while collecting, so retry until the loop waits again
+	assert.Eventually(t, func() bool {
+		inventory.Refresh()
+		return len(mockConn.requests) > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInventorySentOnReconnect(t *testing.T) {
+	inventory := New(logger.NewLogger("test", logger.NewLogOutput(""), logger.LogLevelDebug), 0)
+	inventory.collectors = []Collector{
+		&mockCollector{
+			category: models.InventoryCategoryUser,
+			items:    []*models.InventoryItem{{Name: "root"}},
+		},
+	}
+	inventory.refresh(context.Background())
+
+	mockConn := &mockSSHConn{
+		requests: make(chan []byte, 1),
+	}
+	inventory.SetConn(mockConn)
+
+	var result models.Inventory
+	require.NoError(t, json.Unmarshal(<-mockConn.requests, &result))
+	assert.Len(t, result.Items, 1)
+}
@@ -0,0 +1,145 @@
+package chclient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	natPMPPort              = 5351
+	natPMPOpExternalAddress = 0
+	natPMPOpMapTCP          = 2
+	// natPMPLifetime is the requested lifetime of a port mapping, mappings are renewed at half of the granted lifetime
+	natPMPLifetime = time.Hour
+	// natPMPRetries is the number of requests sent with a doubling timeout as RFC 6886 suggests
+	natPMPRetries = 4
+)
+
+var ErrNoNATPMPResponse = errors.New("no response from NAT-PMP gateway")
+
+// natPMP maps the ports of direct tunnels on the NAT gateway of the client with NAT-PMP (RFC 6886), so users outside
+// of the client's network can connect without a manual port forwarding
+type natPMP struct {
+	gateway string
+	// initialTimeout is doubled for each retry
+	initialTimeout time.Duration
+}
+
+// newNATPMP returns a NAT-PMP client of the given gateway IP, the default gateway of the routing table if it's empty
+func newNATPMP(gateway string) (*natPMP, error) {
+	if gateway == "" {
+		ip, err := defaultGateway()
+		if err != nil {
+			return nil, err
+		}
+		gateway = ip.String()
+	}
+
+	return &natPMP{
+		gateway:        net.JoinHostPort(gateway, fmt.Sprint(natPMPPort)),
+		initialTimeout: 250 * time.Millisecond,
+	}, nil
+}
+
+// ExternalAddress returns the public IP address of the gateway
+func (n *natPMP) ExternalAddress() (net.IP, error) {
+	resp, err := n.request([]byte{0, natPMPOpExternalAddress}, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+// MapTCP maps a TCP port of the client to a port of the gateway and returns it with the granted lifetime.
+// A lifetime of 0 deletes the mapping.
+func (n *natPMP) MapTCP(internalPort int, lifetime time.Duration) (externalPort int, granted time.Duration, err error) {
+	req := make([]byte, 12)
+	req[1] = natPMPOpMapTCP
+	binary.BigEndian.PutUint16(req[4:6], uint16(internalPort))
+	// the same port is suggested, the gateway may choose another one
+	binary.BigEndian.PutUint16(req[6:8], uint16(internalPort))
+	binary.BigEndian.PutUint32(req[8:12], uint32(lifetime.Seconds()))
+
+	resp, err := n.request(req, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	externalPort = int(binary.BigEndian.Uint16(resp[10:12]))
+	granted = time.Duration(binary.BigEndian.Uint32(resp[12:16])) * time.Second
+	return externalPort, granted, nil
+}
+
+func (n *natPMP) request(req []byte, respLen int) ([]byte, error) {
+	conn, err := net.Dial("udp4", n.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp := make([]byte, 16)
+	timeout := n.initialTimeout
+	for i := 0; i < natPMPRetries; i++ {
+		_, err = conn.Write(req)
+		if err != nil {
+			return nil, err
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		l, err := conn.Read(resp)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			timeout *= 2
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if l < respLen || resp[0] != 0 || resp[1] != req[1]+128 {
+			return nil, fmt.Errorf("invalid NAT-PMP response from %s", n.gateway)
+		}
+		if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+			return nil, fmt.Errorf("NAT-PMP gateway %s refused the request with result code %d", n.gateway, code)
+		}
+		return resp[:l], nil
+	}
+
+	return nil, ErrNoNATPMPResponse
+}
+
+// defaultGateway reads the IPv4 default gateway from the routing table, it's only available on Linux
+func defaultGateway() (net.IP, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, fmt.Errorf(`failed to find the default gateway, set "direct_tunnels_nat_pmp_gateway": %v`, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ..., the addresses are hex encoded in little endian
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != 4 {
+			continue
+		}
+		return net.IPv4(gateway[3], gateway[2], gateway[1], gateway[0]), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New(`no default gateway found, set "direct_tunnels_nat_pmp_gateway"`)
+}
@@ -0,0 +1,118 @@
+package chclient
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/logger"
+)
+
+type natPMPGatewayMock struct {
+	conn     net.PacketConn
+	mu       sync.Mutex
+	mappings map[int]uint32
+}
+
+func newNATPMPGatewayMock(t *testing.T) *natPMPGatewayMock {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	g := &natPMPGatewayMock{conn: conn, mappings: make(map[int]uint32)}
+	go g.serve()
+	return g
+}
+
+func (g *natPMPGatewayMock) serve() {
+	req := make([]byte, 12)
+	for {
+		n, addr, err := g.conn.ReadFrom(req)
+		if err != nil {
+			return
+		}
+		if n < 2 {
+			continue
+		}
+
+		var resp []byte
+		switch req[1] {
+		case natPMPOpExternalAddress:
+			resp = make([]byte, 12)
+			copy(resp[8:12], net.IPv4(203, 0, 113, 1).To4())
+		case natPMPOpMapTCP:
+			resp = make([]byte, 16)
+			internalPort := int(binary.BigEndian.Uint16(req[4:6]))
+			lifetime := binary.BigEndian.Uint32(req[8:12])
+			g.mu.Lock()
+			if lifetime == 0 {
+				delete(g.mappings, internalPort)
+			} else {
+				g.mappings[internalPort] = lifetime
+			}
+			g.mu.Unlock()
+			copy(resp[8:10], req[4:6])
+			binary.BigEndian.PutUint16(resp[10:12], 40000)
+			binary.BigEndian.PutUint32(resp[12:16], lifetime)
+		}
+		resp[1] = req[1] + 128
+		_, _ = g.conn.WriteTo(resp, addr)
+	}
+}
+
+func (g *natPMPGatewayMock) mapped(port int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.mappings[port]
+	return ok
+}
+
+func TestDirectTunnelsMapPort(t *testing.T) {
+	gateway := newNATPMPGatewayMock(t)
+	defer gateway.conn.Close()
+	pmp := &natPMP{gateway: gateway.conn.LocalAddr().String(), initialTimeout: 50 * time.Millisecond}
+
+	log := logger.NewLogger("nat-pmp-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	d := newDirectTunnels(log, &chshare.ConnStats{})
+	defer d.StopAll()
+
+	port, err := d.Start("1", "127.0.0.1:22", "127.0.0.1", nil)
+	require.NoError(t, err)
+
+	address, err := d.MapPort("1", pmp)
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.1:40000", address)
+	assert.True(t, gateway.mapped(port))
+
+	// an update of the tunnel keeps its mapping
+	again, err := d.MapPort("1", pmp)
+	require.NoError(t, err)
+	assert.Equal(t, address, again)
+
+	require.NoError(t, d.Stop("1"))
+	assert.Eventually(t, func() bool { return !gateway.mapped(port) }, time.Second, 10*time.Millisecond)
+}
+
+func TestDirectTunnelsMapPortNoGateway(t *testing.T) {
+	// nothing answers on the port, the tunnel is still reachable without the mapping
+	silent, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+	pmp := &natPMP{gateway: silent.LocalAddr().String(), initialTimeout: 10 * time.Millisecond}
+
+	log := logger.NewLogger("nat-pmp-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	d := newDirectTunnels(log, &chshare.ConnStats{})
+	defer d.StopAll()
+
+	_, err = d.Start("1", "127.0.0.1:22", "127.0.0.1", nil)
+	require.NoError(t, err)
+
+	_, err = d.MapPort("1", pmp)
+	assert.ErrorIs(t, err, ErrNoNATPMPResponse)
+}
@@ -1,12 +1,14 @@
 package chclient
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 
 	errors2 "github.com/riportdev/riport/share/errors"
 
@@ -18,9 +20,11 @@ import (
 
 	"golang.org/x/crypto/ssh"
 
+	"github.com/riportdev/riport/share/comm"
 	"github.com/riportdev/riport/share/files"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/ratelimit"
 )
 
 type SourceFileProvider interface {
@@ -33,12 +37,71 @@ type OptionsProvider interface {
 	IsFileReceptionEnabled() bool
 }
 
+// UploadProgressReporter publishes how many bytes of a file were received so far
+type UploadProgressReporter interface {
+	ReportUploadProgress(progress *models.UploadProgress)
+}
+
 type UploadManager struct {
 	*logger.Logger
 	FilesAPI           files.FileAPI
 	OptionsProvider    OptionsProvider
 	SourceFileProvider SourceFileProvider
 	SysUserLookup      system.SysUserLookup
+	// BandwidthLimiter is shared by all transfers of the client, nil means unlimited
+	BandwidthLimiter *ratelimit.Limiter
+	// ProgressReporter is nil if the server doesn't support progress events
+	ProgressReporter UploadProgressReporter
+}
+
+// partialFileSuffix marks files which are not fully received yet, the size of the file is the offset to resume from
+const partialFileSuffix = ".part"
+
+// partialFileTTL is how long partial files of interrupted transfers are kept to resume them
+const partialFileTTL = 24 * time.Hour
+
+var progressReportInterval = time.Second
+
+type SSHUploadProgressReporter struct {
+	*logger.Logger
+	conn ssh.Conn
+}
+
+func NewSSHUploadProgressReporter(l *logger.Logger, conn ssh.Conn) *SSHUploadProgressReporter {
+	return &SSHUploadProgressReporter{
+		Logger: l,
+		conn:   conn,
+	}
+}
+
+func (r *SSHUploadProgressReporter) ReportUploadProgress(progress *models.UploadProgress) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		r.Errorf("failed to encode upload progress: %v", err)
+		return
+	}
+
+	_, _, err = r.conn.SendRequest(comm.RequestTypeUploadProgress, false, data)
+	if err != nil {
+		r.Errorf("failed to send upload progress: %v", err)
+	}
+}
+
+type progressReader struct {
+	r          io.Reader
+	received   int64
+	lastReport time.Time
+	report     func(received int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.received += int64(n)
+	if err == io.EOF || time.Since(pr.lastReport) >= progressReportInterval {
+		pr.lastReport = time.Now()
+		pr.report(pr.received)
+	}
+	return n, err
 }
 
 type SSHFileProvider struct {
@@ -54,6 +117,15 @@ func (ss *SftpSession) Read(p []byte) (n int, err error) {
 	return ss.RemoteFile.Read(p)
 }
 
+func (ss *SftpSession) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := ss.RemoteFile.(io.Seeker)
+	if !ok {
+		return 0, errors.New("remote file doesn't support seeking")
+	}
+
+	return seeker.Seek(offset, whence)
+}
+
 func (ss *SftpSession) Close() error {
 	errs := make([]string, 0, 2)
 
@@ -126,6 +198,75 @@ func (um *UploadManager) HandleUploadRequest(reqPayload []byte) (*models.UploadR
 		return nil, err
 	}
 
+	return um.handleUploadedFile(uploadedFile)
+}
+
+// HandleUploadDirRequest writes the files of an unpacked archive to the destination directory,
+// every file is handled like a single file upload with the same owner, group, mode and write flags.
+func (um *UploadManager) HandleUploadDirRequest(reqPayload []byte) (*models.UploadResponse, error) {
+	if !um.OptionsProvider.IsFileReceptionEnabled() {
+		err := errors2.ErrUploadsDisabled
+		um.Debugf(err.Error())
+		return nil, err
+	}
+
+	um.Debugf("got request %s", string(reqPayload))
+
+	uploadedDir, err := um.getUploadedDir(reqPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	var written, unchanged, deleted int
+	var copiedBytes int64
+	for _, entry := range uploadedDir.Files {
+		fileToWrite := uploadedDir.DirEntryFile(entry)
+		_, err = um.FilesAPI.CreateDirIfNotExists(filepath.Dir(fileToWrite.DestinationPath), dirMode(uploadedDir.DestinationFileMode))
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := um.handleUploadedFile(fileToWrite)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s after %d written files", entry.Path, written)
+		}
+
+		if resp.Status == "ignored" {
+			unchanged++
+			continue
+		}
+		written++
+		copiedBytes += resp.SizeBytes
+	}
+
+	if uploadedDir.DeleteExtraneous {
+		deleted, err = um.deleteExtraneousFiles(uploadedDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	msg := fmt.Sprintf(
+		"%d files written, %d files unchanged, %d extraneous files deleted in %s",
+		written,
+		unchanged,
+		deleted,
+		uploadedDir.DestinationPath,
+	)
+	um.Logger.Infof(msg)
+
+	return &models.UploadResponse{
+		UploadResponseShort: models.UploadResponseShort{
+			ID:        uploadedDir.ID,
+			Filepath:  uploadedDir.DestinationPath,
+			SizeBytes: copiedBytes,
+		},
+		Status:  "success",
+		Message: msg,
+	}, nil
+}
+
+func (um *UploadManager) handleUploadedFile(uploadedFile *models.UploadedFile) (*models.UploadResponse, error) {
 	destinationFileExists, err := um.FilesAPI.Exist(uploadedFile.DestinationPath)
 	if err != nil {
 		return nil, err
@@ -161,16 +302,15 @@ func (um *UploadManager) fileShouldBeSynched(uploadedFile *models.UploadedFile)
 		return false, nil
 	}
 
-	hashSumMatch, err := files.Md5HashMatch(uploadedFile.Md5Checksum, uploadedFile.DestinationPath, um.FilesAPI)
+	hashSumMatch, err := files.ChecksumMatch(uploadedFile.Sha256Checksum, uploadedFile.Md5Checksum, uploadedFile.DestinationPath, um.FilesAPI)
 
 	if err != nil {
 		return false, err
 	}
 	if !hashSumMatch {
 		um.Debugf(
-			"destination file %s has a different hash sum than the provided %x, the file should be synched",
+			"destination file %s has a different hash sum than the provided one, the file should be synched",
 			uploadedFile.DestinationPath,
-			uploadedFile.Md5Checksum,
 		)
 
 		return true, nil
@@ -219,11 +359,7 @@ func (um *UploadManager) fileShouldBeSynched(uploadedFile *models.UploadedFile)
 }
 
 func (um *UploadManager) handleWritingFile(uploadedFile *models.UploadedFile) (resp *models.UploadResponse, err error) {
-	copiedBytes, tempFilePath, err := um.copyFileToTempLocation(
-		uploadedFile.SourceFilePath,
-		uploadedFile.DestinationFileMode,
-		uploadedFile.Md5Checksum,
-	)
+	copiedBytes, tempFilePath, err := um.copyFileToTempLocation(uploadedFile)
 	if err != nil {
 		return nil, err
 	}
@@ -371,13 +507,15 @@ func (um *UploadManager) prepareDestinationDir(destinationPath string, mode os.F
 	return nil
 }
 
-func (um *UploadManager) copyFileToTempLocation(remoteFilePath string, targetFileMode os.FileMode, expectedMd5Checksum []byte) (
+func (um *UploadManager) copyFileToTempLocation(uploadedFile *models.UploadedFile) (
 	bytesCopied int64,
 	tempFilePath string,
 	err error,
 ) {
+	remoteFilePath := uploadedFile.SourceFilePath
 	tempFileName := filepath.Base(remoteFilePath)
 
+	targetFileMode := uploadedFile.DestinationFileMode
 	if targetFileMode == 0 {
 		targetFileMode = files.DefaultMode
 	}
@@ -389,6 +527,7 @@ func (um *UploadManager) copyFileToTempLocation(remoteFilePath string, targetFil
 	if tempDirWasCreated {
 		um.Logger.Debugf("created temp dir %s for uploaded files", um.OptionsProvider.GetUploadDir())
 	}
+	um.removeStalePartialFiles(um.OptionsProvider.GetUploadDir())
 
 	tempFilePath = filepath.Join(um.OptionsProvider.GetUploadDir(), tempFileName)
 
@@ -405,40 +544,141 @@ func (um *UploadManager) copyFileToTempLocation(remoteFilePath string, targetFil
 		}
 	}
 
+	// the temp file name contains the upload ID, so a partial file is left only by an interrupted transfer of the same upload
+	partialFilePath := tempFilePath + partialFileSuffix
+	offset, err := um.getReceivedOffset(partialFilePath, uploadedFile.Size)
+	if err != nil {
+		return 0, tempFilePath, err
+	}
+
 	remoteFile, err := um.SourceFileProvider.Open(remoteFilePath)
 	if err != nil {
 		return 0, tempFilePath, err
 	}
 	defer remoteFile.Close()
 
-	copiedBytes, err := um.FilesAPI.CreateFile(tempFilePath, remoteFile)
+	if offset > 0 {
+		err = seekSourceFile(remoteFile, offset)
+		if err != nil {
+			return 0, tempFilePath, err
+		}
+		um.Logger.Infof("resuming transfer of %s at %d of %d bytes", remoteFilePath, offset, uploadedFile.Size)
+	}
+
+	copiedBytes, err := um.FilesAPI.AppendFile(partialFilePath, um.newTransferReader(remoteFile, uploadedFile, offset))
 	if err != nil {
 		return 0, tempFilePath, err
 	}
-	um.Logger.Debugf("copied %d bytes from server path %s to temp path %s", copiedBytes, remoteFilePath, tempFilePath)
+	copiedBytes += offset
+	um.Logger.Debugf("copied %d bytes from server path %s to temp path %s", copiedBytes, remoteFilePath, partialFilePath)
 
-	hashSumMatch, err := files.Md5HashMatch(expectedMd5Checksum, tempFilePath, um.FilesAPI)
+	hashSumMatch, err := files.ChecksumMatch(uploadedFile.Sha256Checksum, uploadedFile.Md5Checksum, partialFilePath, um.FilesAPI)
 	if err != nil {
 		return 0, tempFilePath, err
 	}
 
 	if !hashSumMatch {
-		err := um.FilesAPI.Remove(tempFilePath)
+		err := um.FilesAPI.Remove(partialFilePath)
 		if err != nil {
-			um.Logger.Errorf("failed to remove %s: %v", tempFilePath, err)
+			um.Logger.Errorf("failed to remove %s: %v", partialFilePath, err)
 		}
 
+		if len(uploadedFile.Sha256Checksum) > 0 {
+			return 0,
+				tempFilePath,
+				fmt.Errorf(
+					"sha256 check failed: checksum from server %x doesn't equal the calculated checksum",
+					uploadedFile.Sha256Checksum,
+				)
+		}
 		return 0,
 			tempFilePath,
 			fmt.Errorf(
 				"md5 check failed: checksum from server %x doesn't equal the calculated checksum",
-				expectedMd5Checksum,
+				uploadedFile.Md5Checksum,
 			)
 	}
 
+	err = um.FilesAPI.Rename(partialFilePath, tempFilePath)
+	if err != nil {
+		return 0, tempFilePath, err
+	}
+
 	return copiedBytes, tempFilePath, nil
 }
 
+// getReceivedOffset returns the size of a partial file left by an interrupted transfer, partial files of unknown size are discarded.
+func (um *UploadManager) getReceivedOffset(partialFilePath string, size int64) (int64, error) {
+	partialFileExists, err := um.FilesAPI.Exist(partialFilePath)
+	if err != nil || !partialFileExists {
+		return 0, err
+	}
+
+	offset, err := um.FilesAPI.GetFileSize(partialFilePath)
+	if err != nil {
+		return 0, err
+	}
+	if offset <= size {
+		return offset, nil
+	}
+
+	um.Logger.Debugf("partial file %s doesn't match the transfer, will delete it", partialFilePath)
+	return 0, um.FilesAPI.Remove(partialFilePath)
+}
+
+// removeStalePartialFiles deletes partial files of transfers which were not resumed within partialFileTTL
+func (um *UploadManager) removeStalePartialFiles(uploadDir string) {
+	entries, err := um.FilesAPI.ReadDir(uploadDir)
+	if err != nil {
+		um.Logger.Errorf("failed to read upload dir %s: %v", uploadDir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), partialFileSuffix) || time.Since(entry.ModTime()) < partialFileTTL {
+			continue
+		}
+		partialFilePath := filepath.Join(uploadDir, entry.Name())
+		um.Logger.Debugf("partial file %s of an abandoned transfer is expired, will delete it", partialFilePath)
+		err = um.FilesAPI.Remove(partialFilePath)
+		if err != nil {
+			um.Logger.Errorf("failed to remove %s: %v", partialFilePath, err)
+		}
+	}
+}
+
+func seekSourceFile(sourceFile io.Reader, offset int64) error {
+	if seeker, ok := sourceFile.(io.Seeker); ok {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+
+	_, err := io.CopyN(io.Discard, sourceFile, offset)
+	return err
+}
+
+// newTransferReader applies the bandwidth limits of the transfer and of the client and reports the progress
+func (um *UploadManager) newTransferReader(sourceFile io.Reader, uploadedFile *models.UploadedFile, offset int64) io.Reader {
+	r := ratelimit.NewReader(sourceFile, ratelimit.NewLimiter(uploadedFile.BandwidthLimit), um.BandwidthLimiter)
+	if um.ProgressReporter == nil {
+		return r
+	}
+
+	return &progressReader{
+		r:          r,
+		received:   offset,
+		lastReport: time.Now(),
+		report: func(received int64) {
+			um.ProgressReporter.ReportUploadProgress(&models.UploadProgress{
+				ID:            uploadedFile.ID,
+				Filepath:      uploadedFile.DestinationPath,
+				ReceivedBytes: received,
+				TotalBytes:    uploadedFile.Size,
+			})
+		},
+	}
+}
+
 func (um *UploadManager) getUploadedFile(reqPayload []byte) (*models.UploadedFile, error) {
 	uploadedFile := new(models.UploadedFile)
 	err := uploadedFile.FromBytes(reqPayload)
@@ -458,6 +698,92 @@ func (um *UploadManager) getUploadedFile(reqPayload []byte) (*models.UploadedFil
 	return uploadedFile, nil
 }
 
+// dirMode adds the execute bit wherever the file mode grants read access, otherwise nested directories can't be traversed.
+func dirMode(fileMode os.FileMode) os.FileMode {
+	if fileMode == 0 {
+		return files.DefaultMode
+	}
+
+	return fileMode | (fileMode&0444)>>2
+}
+
+func (um *UploadManager) getUploadedDir(reqPayload []byte) (*models.UploadedFile, error) {
+	uploadedDir, err := um.getUploadedFile(reqPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uploadedDir.ValidateDirEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// reject the whole request before anything is written
+	for _, entry := range uploadedDir.Files {
+		err = uploadedDir.DirEntryFile(entry).ValidateDestinationPath(um.OptionsProvider.GetProtectedUploadDirs(), um.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return uploadedDir, nil
+}
+
+// deleteExtraneousFiles removes all regular files below the destination directory which are not contained in the upload,
+// files matching protected patterns as well as links and other special files are left untouched.
+func (um *UploadManager) deleteExtraneousFiles(uploadedDir *models.UploadedFile) (int, error) {
+	dirExists, err := um.FilesAPI.Exist(uploadedDir.DestinationPath)
+	if err != nil || !dirExists {
+		return 0, err
+	}
+
+	uploadedPaths := make(map[string]bool, len(uploadedDir.Files))
+	for _, entry := range uploadedDir.Files {
+		uploadedPaths[uploadedDir.DirEntryFile(entry).DestinationPath] = true
+	}
+
+	return um.deleteExtraneousFilesInDir(uploadedDir.DestinationPath, uploadedPaths)
+}
+
+func (um *UploadManager) deleteExtraneousFilesInDir(dir string, uploadedPaths map[string]bool) (int, error) {
+	fileInfos, err := um.FilesAPI.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, fileInfo := range fileInfos {
+		filePath := filepath.Join(dir, fileInfo.Name())
+		if fileInfo.IsDir() {
+			n, err := um.deleteExtraneousFilesInDir(filePath, uploadedPaths)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+			continue
+		}
+
+		if uploadedPaths[filePath] || !fileInfo.Mode().IsRegular() {
+			continue
+		}
+
+		err = models.UploadedFile{DestinationPath: filePath}.ValidateDestinationPath(um.OptionsProvider.GetProtectedUploadDirs(), um.Logger)
+		if err != nil {
+			um.Logger.Infof("extraneous file %s is not deleted: %v", filePath, err)
+			continue
+		}
+
+		err = um.FilesAPI.Remove(filePath)
+		if err != nil {
+			return deleted, err
+		}
+		um.Logger.Debugf("deleted extraneous file %s", filePath)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
 func (um *UploadManager) fileOwnerOrGroupMatch(file, owner, group string) (bool, error) {
 	fileUID, fileGid, err := um.FilesAPI.GetFileOwnerAndGroup(file)
 	if err != nil {
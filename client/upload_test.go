@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/riportdev/riport/share/errors"
 	"github.com/riportdev/riport/share/files"
@@ -86,25 +87,29 @@ func TestHandleUploadRequest(t *testing.T) {
 
 				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp.txt")
 				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(false, nil)
 
 				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), files.DefaultMode).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
 				fs.On("CreateDirIfNotExists", "destination", files.DefaultMode).Return(true, nil)
 
-				fileExpectation := func(f io.ReadCloser) bool {
+				fileExpectation := func(f io.Reader) bool {
 					actualFileContent, err := ioutil.ReadAll(f)
 
 					require.NoError(t, err)
 
 					return string(actualFileContent) == "some content"
 				}
-				fs.On("CreateFile", expectedTempFilePath, mock.MatchedBy(fileExpectation)).Return(int64(10), nil)
+				fs.On("AppendFile", expectedPartialFilePath, mock.MatchedBy(fileExpectation)).Return(int64(10), nil)
 
 				fileMock := &test.ReadWriteCloserMock{}
 				fileMock.Reader = strings.NewReader("some content")
 				fileMock.On("Close").Return(nil)
 
-				fs.On("Open", expectedTempFilePath).Return(fileMock, nil)
+				fs.On("Open", expectedPartialFilePath).Return(fileMock, nil)
 
+				fs.On("Rename", expectedPartialFilePath, expectedTempFilePath).Return(nil)
 				fs.On("Rename", expectedTempFilePath, filepath.Join("destination", "file.txt")).Return(nil)
 			},
 			fileProviderCallback: buildDefaultFileProviderMock(filepath.Join("source", "file_temp.txt"), "some content"),
@@ -146,18 +151,22 @@ func TestHandleUploadRequest(t *testing.T) {
 
 				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp2.txt")
 				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(false, nil)
 
 				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), os.FileMode(0700)).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
 				fs.On("CreateDirIfNotExists", "destination", os.FileMode(0700)).Return(true, nil)
 
 				fileMock := &test.ReadWriteCloserMock{}
 				fileMock.Reader = strings.NewReader("some content")
 				fileMock.On("Close").Return(nil)
 
-				fs.On("Open", expectedTempFilePath).Return(fileMock, nil)
+				fs.On("Open", expectedPartialFilePath).Return(fileMock, nil)
 
-				fs.On("CreateFile", expectedTempFilePath, mock.Anything).Return(int64(12), nil)
+				fs.On("AppendFile", expectedPartialFilePath, mock.Anything).Return(int64(12), nil)
 				fs.On("Remove", filepath.Join("destination", "file2.txt")).Return(nil)
+				fs.On("Rename", expectedPartialFilePath, expectedTempFilePath).Return(nil)
 				fs.On("Rename", expectedTempFilePath, filepath.Join("destination", "file2.txt")).Return(nil)
 				fs.On("ChangeOwner", filepath.Join("data", "filepush", "file_temp2.txt"), "admin", "group").Return(nil)
 				fs.On("ChangeMode", filepath.Join("data", "filepush", "file_temp2.txt"), os.FileMode(0700)).Return(nil)
@@ -224,22 +233,78 @@ func TestHandleUploadRequest(t *testing.T) {
 
 				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp.txt")
 				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(false, nil)
 
 				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), files.DefaultMode).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
 
 				fileMock := &test.ReadWriteCloserMock{}
 				fileMock.Reader = strings.NewReader("some content")
 				fileMock.On("Close").Return(nil)
 
-				fs.On("Open", expectedTempFilePath).Return(fileMock, nil)
+				fs.On("Open", expectedPartialFilePath).Return(fileMock, nil)
 
-				fs.On("CreateFile", expectedTempFilePath, mock.Anything).Return(int64(12), nil)
-				fs.On("Remove", expectedTempFilePath).Return(nil)
+				fs.On("AppendFile", expectedPartialFilePath, mock.Anything).Return(int64(12), nil)
+				fs.On("Remove", expectedPartialFilePath).Return(nil)
 			},
 			fileProviderCallback: buildDefaultFileProviderMock(filepath.Join("source", "file_temp.txt"), "some content"),
 			optionsCallback:      defaultOptionsCallback,
 			wantError:            "md5 check failed: checksum from server 260c194bdd86828158fda34d0fbd5fcd doesn't equal the calculated checksum",
 		},
+		{
+			name: "interrupted transfer resumed",
+			wantUploadedFile: &models.UploadedFile{
+				ID:              "97e97cdd-135a-4620-ab50-d44025b8fe35",
+				SourceFilePath:  filepath.Join("source", "file_temp5.txt"),
+				DestinationPath: filepath.Join("destination", "file5.txt"),
+				Md5Checksum:     test.Md5Hash("some content"),
+				Sha256Checksum:  test.Sha256Hash("some content"),
+				Size:            12,
+			},
+			fsCallback: func(fs *test.FileAPIMock) {
+				fs.On("Exist", filepath.Join("destination", "file5.txt")).Return(false, nil)
+
+				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp5.txt")
+				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(true, nil)
+				fs.On("GetFileSize", expectedPartialFilePath).Return(int64(4), nil)
+
+				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), files.DefaultMode).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
+				fs.On("CreateDirIfNotExists", "destination", files.DefaultMode).Return(true, nil)
+
+				fileExpectation := func(f io.Reader) bool {
+					actualFileContent, err := ioutil.ReadAll(f)
+
+					require.NoError(t, err)
+
+					return string(actualFileContent) == " content"
+				}
+				fs.On("AppendFile", expectedPartialFilePath, mock.MatchedBy(fileExpectation)).Return(int64(8), nil)
+
+				fileMock := &test.ReadWriteCloserMock{}
+				fileMock.Reader = strings.NewReader("some content")
+				fileMock.On("Close").Return(nil)
+
+				fs.On("Open", expectedPartialFilePath).Return(fileMock, nil)
+
+				fs.On("Rename", expectedPartialFilePath, expectedTempFilePath).Return(nil)
+				fs.On("Rename", expectedTempFilePath, filepath.Join("destination", "file5.txt")).Return(nil)
+			},
+			fileProviderCallback: buildDefaultFileProviderMock(filepath.Join("source", "file_temp5.txt"), "some content"),
+			optionsCallback:      defaultOptionsCallback,
+			wantResp: &models.UploadResponse{
+				UploadResponseShort: models.UploadResponseShort{
+					ID:        "97e97cdd-135a-4620-ab50-d44025b8fe35",
+					Filepath:  filepath.Join("destination", "file5.txt"),
+					SizeBytes: 12,
+				},
+				Message: "file successfully copied to destination " + filepath.Join("destination", "file5.txt"),
+				Status:  "success",
+			},
+		},
 		{
 			name: "file exists, sync on",
 			wantUploadedFile: &models.UploadedFile{
@@ -257,17 +322,20 @@ func TestHandleUploadRequest(t *testing.T) {
 
 				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp7.txt")
 				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(false, nil)
 
 				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), os.FileMode(0744)).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
 				fs.On("CreateDirIfNotExists", "destination", os.FileMode(0744)).Return(true, nil)
 
-				fs.On("CreateFile", expectedTempFilePath, mock.Anything).Return(int64(12), nil)
+				fs.On("AppendFile", expectedPartialFilePath, mock.Anything).Return(int64(12), nil)
 
 				existingFileMock := &test.ReadWriteCloserMock{}
 				existingFileMock.Reader = strings.NewReader("some content")
 				existingFileMock.On("Close").Return(nil)
 
-				fs.On("Open", expectedTempFilePath).Return(existingFileMock, nil)
+				fs.On("Open", expectedPartialFilePath).Return(existingFileMock, nil)
 
 				existingFileMock2 := &test.ReadWriteCloserMock{}
 				existingFileMock2.Reader = strings.NewReader("some content")
@@ -279,6 +347,7 @@ func TestHandleUploadRequest(t *testing.T) {
 				fs.On("GetFileOwnerAndGroup", filepath.Join("destination", "file7.txt")).Return(defaultUID, defaultGID, nil)
 
 				fs.On("Remove", filepath.Join("destination", "file7.txt")).Return(nil)
+				fs.On("Rename", expectedPartialFilePath, expectedTempFilePath).Return(nil)
 				fs.On("Rename", expectedTempFilePath, filepath.Join("destination", "file7.txt")).Return(nil)
 				fs.On("ChangeOwner", filepath.Join("data", "filepush", "file_temp7.txt"), "admin", "group").Return(nil)
 				fs.On("ChangeMode", filepath.Join("data", "filepush", "file_temp7.txt"), os.FileMode(0744)).Return(nil)
@@ -323,21 +392,25 @@ func TestHandleUploadRequest(t *testing.T) {
 
 				expectedTempFilePath := filepath.Join("data", files.DefaultUploadTempFolder, "file_temp8.txt")
 				fs.On("Exist", expectedTempFilePath).Return(false, nil)
+				expectedPartialFilePath := expectedTempFilePath + ".part"
+				fs.On("Exist", expectedPartialFilePath).Return(false, nil)
 
 				fs.On("CreateDirIfNotExists", filepath.Join("data", files.DefaultUploadTempFolder), os.FileMode(0744)).Return(true, nil)
+				fs.On("ReadDir", filepath.Join("data", files.DefaultUploadTempFolder)).Return([]os.FileInfo{}, nil)
 				fs.On("CreateDirIfNotExists", "destination", os.FileMode(0744)).Return(true, nil)
 
-				fs.On("CreateFile", expectedTempFilePath, mock.Anything).Return(int64(12), nil)
+				fs.On("AppendFile", expectedPartialFilePath, mock.Anything).Return(int64(12), nil)
 				fs.On("ChangeMode", expectedTempFilePath, os.FileMode(0744)).Return(nil)
 
 				existingFileMock := &test.ReadWriteCloserMock{}
 				existingFileMock.Reader = strings.NewReader("some content")
 				existingFileMock.On("Close").Return(nil)
 
-				fs.On("Open", expectedTempFilePath).Return(existingFileMock, nil)
+				fs.On("Open", expectedPartialFilePath).Return(existingFileMock, nil)
 
 				existingFileMock2 := &test.ReadWriteCloserMock{}
 				existingFileMock2.Reader = strings.NewReader("some content")
+				fs.On("Rename", expectedPartialFilePath, expectedTempFilePath).Return(nil)
 				fs.On("Rename", expectedTempFilePath, filepath.Join("destination", "file8.txt")).Return(nil)
 			},
 			sysUserLookupCallback: func(sysUsrLookup *test.SysUserProviderMock) {
@@ -456,3 +529,150 @@ func defaultOptionsCallback(opts *UploadOptionsProviderMock) {
 	opts.On("GetProtectedUploadDirs").Return([]string{})
 	opts.On("IsFileReceptionEnabled").Return(true)
 }
+
+type localFileProvider struct{}
+
+func (localFileProvider) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func TestHandleUploadDirRequest(t *testing.T) {
+	sourceDir := t.TempDir()
+	destinationDir := t.TempDir()
+	uploadDir := t.TempDir()
+
+	writeFile := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	writeFile(filepath.Join(destinationDir, "unchanged.txt"), "unchanged")
+	writeFile(filepath.Join(destinationDir, "changed.txt"), "old content")
+	writeFile(filepath.Join(destinationDir, "extraneous.txt"), "extraneous")
+	writeFile(filepath.Join(destinationDir, "sub", "extraneous.txt"), "extraneous")
+	writeFile(filepath.Join(destinationDir, "protected", "keep.txt"), "keep")
+
+	uploadedDir := &models.UploadedFile{
+		ID:               "97e97cdd-135a-4620-ab50-d44025b8fe31",
+		SourceFilePath:   sourceDir,
+		DestinationPath:  destinationDir,
+		Sync:             true,
+		Extract:          true,
+		DeleteExtraneous: true,
+	}
+	for i, entry := range map[string]string{
+		"unchanged.txt": "unchanged",
+		"changed.txt":   "new content",
+		"new/new.txt":   "new",
+	} {
+		sourceFilePath := filepath.Join(sourceDir, fmt.Sprintf("id_%s_rport_filepush", strings.ReplaceAll(i, "/", "_")))
+		writeFile(sourceFilePath, entry)
+		uploadedDir.Files = append(uploadedDir.Files, models.UploadedDirEntry{
+			Path:           i,
+			SourceFilePath: sourceFilePath,
+			Md5Checksum:    test.Md5Hash(entry),
+		})
+	}
+	uploadedDirBytes, err := uploadedDir.ToBytes()
+	require.NoError(t, err)
+
+	optionsProvMock := &UploadOptionsProviderMock{}
+	optionsProvMock.On("GetUploadDir").Return(uploadDir)
+	optionsProvMock.On("GetProtectedUploadDirs").Return([]string{filepath.Join(destinationDir, "protected")})
+	optionsProvMock.On("IsFileReceptionEnabled").Return(true)
+
+	um := &UploadManager{
+		FilesAPI:           files.NewFileSystem(),
+		OptionsProvider:    optionsProvMock,
+		Logger:             logger.NewLogger("client-upload-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+		SourceFileProvider: localFileProvider{},
+		SysUserLookup:      &test.SysUserProviderMock{},
+	}
+
+	actualResp, err := um.HandleUploadDirRequest(uploadedDirBytes)
+	require.NoError(t, err)
+	assert.Equal(t, &models.UploadResponse{
+		UploadResponseShort: models.UploadResponseShort{
+			ID:        "97e97cdd-135a-4620-ab50-d44025b8fe31",
+			Filepath:  destinationDir,
+			SizeBytes: 14,
+		},
+		Message: "2 files written, 1 files unchanged, 2 extraneous files deleted in " + destinationDir,
+		Status:  "success",
+	}, actualResp)
+
+	for path, wantContent := range map[string]string{
+		"unchanged.txt":      "unchanged",
+		"changed.txt":        "new content",
+		"new/new.txt":        "new",
+		"protected/keep.txt": "keep",
+	} {
+		content, err := os.ReadFile(filepath.Join(destinationDir, filepath.FromSlash(path)))
+		require.NoError(t, err)
+		assert.Equal(t, wantContent, string(content))
+	}
+	for _, path := range []string{"extraneous.txt", filepath.Join("sub", "extraneous.txt")} {
+		_, err := os.Stat(filepath.Join(destinationDir, path))
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	uploadedDir.Files = []models.UploadedDirEntry{{Path: "protected/file.txt", SourceFilePath: filepath.Join(sourceDir, "id_new_new.txt_rport_filepush")}}
+	uploadedDirBytes, err = uploadedDir.ToBytes()
+	require.NoError(t, err)
+
+	_, err = um.HandleUploadDirRequest(uploadedDirBytes)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches protected pattern")
+}
+
+func TestRemoveStalePartialFiles(t *testing.T) {
+	uploadDir := t.TempDir()
+	expired := time.Now().Add(-partialFileTTL - time.Minute)
+	for name, modTime := range map[string]time.Time{
+		"abandoned_rport_filepush.part": expired,
+		"resumable_rport_filepush.part": time.Now(),
+		"other_rport_filepush":          expired,
+	} {
+		path := filepath.Join(uploadDir, name)
+		require.NoError(t, os.WriteFile(path, []byte("partial"), 0600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	um := &UploadManager{
+		FilesAPI: files.NewFileSystem(),
+		Logger:   logger.NewLogger("client-upload-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+	}
+	um.removeStalePartialFiles(uploadDir)
+
+	entries, err := os.ReadDir(uploadDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"other_rport_filepush", "resumable_rport_filepush.part"}, names)
+}
+
+func TestFileShouldBeSynchedComparesSha256(t *testing.T) {
+	destinationPath := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(destinationPath, []byte("some content"), 0600))
+
+	um := &UploadManager{
+		FilesAPI: files.NewFileSystem(),
+		Logger:   logger.NewLogger("client-upload-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+	}
+	uploadedFile := &models.UploadedFile{
+		DestinationPath: destinationPath,
+		Sync:            true,
+		Md5Checksum:     test.Md5Hash("some content"),
+		Sha256Checksum:  test.Sha256Hash("some content"),
+	}
+	shouldSync, err := um.fileShouldBeSynched(uploadedFile)
+	require.NoError(t, err)
+	assert.False(t, shouldSync)
+
+	// the sha256 checksum is preferred when it's given
+	uploadedFile.Sha256Checksum = test.Sha256Hash("other content")
+	shouldSync, err = um.fileShouldBeSynched(uploadedFile)
+	require.NoError(t, err)
+	assert.True(t, shouldSync)
+}
@@ -62,6 +62,17 @@ var ClientHelp = `
     --auth, Required client authentication credentials in the form: "<client-auth-id>:<password>".
     Alternatively, export credentials to the environment variable RPORT_AUTH.
 
+    --enrollment-token, Token in the form "<token-id>:<secret>" used instead of --auth when the client
+    connects for the first time. The client receives its own credentials and stores them in the data dir.
+    Alternatively, export the token to the environment variable RPORT_ENROLLMENT_TOKEN.
+
+    --tls-cert-file, --tls-key-file, An optional client certificate and its key used to authenticate
+    on a server with https:// URL instead of or in addition to --auth.
+    Certificates issued by the server on enrollment are stored in the data dir and used automatically.
+
+    --tls-ca-file, An optional PEM encoded CA bundle used to verify the server certificate
+    instead of the system certificate pool.
+
     --keepalive, An optional keepalive interval. Since the underlying
     transport is HTTP, in many instances we'll be traversing through
     proxies, often these proxies will close idle connections. You must
@@ -132,6 +143,10 @@ var ClientHelp = `
     --updates-interval, How often after the riport client has started pending updates are summarized.
     Defaults: 4h
 
+    --inventory-interval, How often after the riport client has started installed packages, hardware, listening ports
+    and local users are collected and sent to the server. Set 0 to disable.
+    Defaults: 24h
+
     --fallback-server, Set fallback server(s) to which the client tries to connect if the main server is not reachable.
 
     --server-switchback-interval, If connected to fallback server, try every interval to switch back to the main server.
@@ -174,6 +189,7 @@ var ClientHelp = `
 
    Environment Variables:
     RPORT_AUTH
+    RPORT_ENROLLMENT_TOKEN
     RPORT_FINGERPRINT
 
   Signals:
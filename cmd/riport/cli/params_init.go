@@ -15,6 +15,10 @@ func BindPFlagsToViperConfig(pFlags *pflag.FlagSet, viperCfg *viper.Viper) {
 	// map config fields to CLI args:
 	_ = viperCfg.BindPFlag("client.fingerprint", pFlags.Lookup("fingerprint"))
 	_ = viperCfg.BindPFlag("client.auth", pFlags.Lookup("auth"))
+	_ = viperCfg.BindPFlag("client.enrollment_token", pFlags.Lookup("enrollment-token"))
+	_ = viperCfg.BindPFlag("client.tls_cert_file", pFlags.Lookup("tls-cert-file"))
+	_ = viperCfg.BindPFlag("client.tls_key_file", pFlags.Lookup("tls-key-file"))
+	_ = viperCfg.BindPFlag("client.tls_ca_file", pFlags.Lookup("tls-ca-file"))
 	_ = viperCfg.BindPFlag("client.proxy", pFlags.Lookup("proxy"))
 	_ = viperCfg.BindPFlag("client.use_system_id", pFlags.Lookup("use-system-id"))
 	_ = viperCfg.BindPFlag("client.id", pFlags.Lookup("id"))
@@ -23,6 +27,7 @@ func BindPFlagsToViperConfig(pFlags *pflag.FlagSet, viperCfg *viper.Viper) {
 	_ = viperCfg.BindPFlag("client.tags", pFlags.Lookup("tag"))
 	_ = viperCfg.BindPFlag("client.allow_root", pFlags.Lookup("allow-root"))
 	_ = viperCfg.BindPFlag("client.updates_interval", pFlags.Lookup("updates-interval"))
+	_ = viperCfg.BindPFlag("client.inventory_interval", pFlags.Lookup("inventory-interval"))
 	_ = viperCfg.BindPFlag("client.fallback_servers", pFlags.Lookup("fallback-server"))
 	_ = viperCfg.BindPFlag("client.server_switchback_interval", pFlags.Lookup("server-switchback-interval"))
 	_ = viperCfg.BindPFlag("client.data_dir", pFlags.Lookup("data-dir"))
@@ -55,6 +60,7 @@ func BindPFlagsToViperConfig(pFlags *pflag.FlagSet, viperCfg *viper.Viper) {
 
 	_ = viperCfg.BindPFlag("file-reception.protected", pFlags.Lookup("file-reception-protected"))
 	_ = viperCfg.BindPFlag("file-reception.enabled", pFlags.Lookup("file-reception-enabled"))
+	_ = viperCfg.BindPFlag("file-reception.bandwidth_limit", pFlags.Lookup("file-reception-bandwidth-limit"))
 }
 
 func SetPFlags(pFlags *pflag.FlagSet) {
@@ -73,6 +79,10 @@ func SetPFlags(pFlags *pflag.FlagSet) {
 	// present in config file
 	pFlags.String("fingerprint", os.Getenv("RPORT_FINGERPRINT"), "")
 	pFlags.String("auth", os.Getenv("RPORT_AUTH"), "")
+	pFlags.String("enrollment-token", os.Getenv("RPORT_ENROLLMENT_TOKEN"), "")
+	pFlags.String("tls-cert-file", "", "")
+	pFlags.String("tls-key-file", "", "")
+	pFlags.String("tls-ca-file", "", "")
 	pFlags.Duration("keepalive", 0, "")
 	pFlags.Int("max-retry-count", 0, "")
 	pFlags.Duration("max-retry-interval", 0, "")
@@ -92,6 +102,7 @@ func SetPFlags(pFlags *pflag.FlagSet) {
 	pFlags.String("data-dir", chclient.DefaultDataDir, "")
 	pFlags.Int("remote-commands-send-back-limit", 0, "")
 	pFlags.Duration("updates-interval", 0, "")
+	pFlags.Duration("inventory-interval", 0, "")
 	pFlags.StringArray("fallback-server", []string{}, "")
 	pFlags.Duration("server-switchback-interval", 0, "")
 	pFlags.Bool("monitoring-enabled", false, "")
@@ -107,6 +118,7 @@ func SetPFlags(pFlags *pflag.FlagSet) {
 	pFlags.StringArray("monitoring-net-wan", []string{}, "")
 	pFlags.StringArray("file-reception-protected", []string{}, "")
 	pFlags.Bool("file-reception-enabled", true, "")
+	pFlags.Int64("file-reception-bandwidth-limit", 0, "")
 	pFlags.String("bind-interface", "", "")
 }
 
@@ -127,6 +139,7 @@ func SetViperConfigDefaults(viperCfg *viper.Viper) {
 
 	viperCfg.SetDefault("client.server_switchback_interval", 2*time.Minute)
 	viperCfg.SetDefault("client.updates_interval", 4*time.Hour)
+	viperCfg.SetDefault("client.inventory_interval", 24*time.Hour)
 	viperCfg.SetDefault("client.data_dir", chclient.DefaultDataDir)
 	viperCfg.SetDefault("client.attributes_file_path", "")
 	viperCfg.SetDefault("client.ip_refresh_min", 30)
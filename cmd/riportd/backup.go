@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/riportdev/riport/server/backup"
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/logger"
+)
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "backup the server data",
+		Long: "Take a consistent snapshot of all databases and auth files and write it to a single archive. " +
+			"The server can keep running, except for the alerting database of plus which needs the backup api of the running server.",
+		Example: "rportd backup -c /etc/rport/rportd.conf -o /var/backups/rportd.tar.gz",
+		Run: func(*cobra.Command, []string) {
+			mLog := logger.NewMemLogger()
+			err := decodeAndValidateConfig(&mLog)
+			if err != nil {
+				log.Fatalf("Invalid config: %v. See rportd --help", err)
+			}
+
+			output := *backupOutputFlag
+			if output == "" {
+				output = fmt.Sprintf("riportd-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+			}
+			f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			manifest, err := backup.Backup(context.Background(), f, backup.Stores(cfg), chshare.BuildVersion)
+			if err != nil {
+				f.Close()
+				os.Remove(output)
+				log.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("Backup of %d stores written to %s\n", len(manifest.Stores), output)
+		},
+	}
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "restore the server data from a backup",
+		Long: "Validate a backup archive and replace the databases and auth files with the data of the backup. " +
+			"The server must be stopped.",
+		Example: "rportd restore -c /etc/rport/rportd.conf -i /var/backups/rportd.tar.gz",
+		Run: func(*cobra.Command, []string) {
+			mLog := logger.NewMemLogger()
+			err := decodeAndValidateConfig(&mLog)
+			if err != nil {
+				log.Fatalf("Invalid config: %v. See rportd --help", err)
+			}
+
+			for _, address := range []string{cfg.Server.ListenAddress, cfg.API.Address} {
+				if err := checkNotListening(address); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			f, err := os.Open(*restoreInputFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+
+			manifest, err := backup.Restore(context.Background(), f, backup.Stores(cfg))
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("Restored %d stores of the backup taken at %s by rportd %s\n", len(manifest.Stores), manifest.CreatedAt.Format(time.RFC3339), manifest.Version)
+		},
+	}
+
+	backupOutputFlag *string
+	restoreInputFlag *string
+)
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	RootCmd.AddCommand(restoreCmd)
+
+	backupOutputFlag = backupCmd.Flags().StringP("output", "o", "", "archive to write, defaults to riportd-backup-<time>.tar.gz in the current directory")
+	restoreInputFlag = restoreCmd.Flags().StringP("input", "i", "", "archive to restore [required]")
+	err := restoreCmd.MarkFlagRequired("input")
+	if err != nil {
+		// This will return error if the flag doesn't exist, so it's ok to panic because it can only happen when changing the code
+		panic(err)
+	}
+}
+
+// checkNotListening fails if the server is running and listens on the address
+func checkNotListening(address string) error {
+	if address == "" {
+		return nil
+	}
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return fmt.Errorf("%s is in use, stop rportd before restoring", address)
+		}
+		return nil
+	}
+	return l.Close()
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/riportdev/riport/server/api/message"
 	auditlog "github.com/riportdev/riport/server/auditlog/config"
 	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clientcerts"
+	"github.com/riportdev/riport/server/cluster"
 	chshare "github.com/riportdev/riport/share"
 	"github.com/riportdev/riport/share/files"
 )
@@ -47,6 +49,7 @@ const (
 	DefaultRunRemoteCmdTimeoutSec           = 60
 	DefaultMonitoringDataStorageDuration    = "7d"
 	DefaultPairingURL                       = "https://pairing.riport.io"
+	DefaultClientCertValidity               = 90 * 24 * time.Hour
 )
 
 var (
@@ -353,7 +356,10 @@ func init() {
 	viperCfg.SetDefault("server.pairing_url", DefaultPairingURL)
 	viperCfg.SetDefault("server.ban_time", 3600)
 	viperCfg.SetDefault("server.jobs_max_results", 10000)
+	viperCfg.SetDefault("server.tunnel_connections_retention", "30d")
 	viperCfg.SetDefault("server.tls_min", "1.3")
+	viperCfg.SetDefault("server.client_cert_identity", clientcerts.IdentityCommonName)
+	viperCfg.SetDefault("server.client_cert_validity", DefaultClientCertValidity)
 	viperCfg.SetDefault("api.user_header", "Authentication-User")
 	viperCfg.SetDefault("api.default_user_group", "Administrators")
 	viperCfg.SetDefault("api.user_login_wait", 2)
@@ -369,13 +375,17 @@ func init() {
 	viperCfg.SetDefault("monitoring.enabled", true)
 	viperCfg.SetDefault("api.max_request_bytes", DefaultMaxRequestBytes)
 	viperCfg.SetDefault("api.max_filepush_size", DefaultMaxFilePushBytes)
+	viperCfg.SetDefault("api.filepush_resume_timeout", 10*time.Minute)
 	viperCfg.SetDefault("api.enable_ws_test_endpoints", false)
 	viperCfg.SetDefault("api.totp_login_session_ttl", time.Minute*10)
 	viperCfg.SetDefault("api.totp_account_name", "RPort")
+	viperCfg.SetDefault("api.webauthn_rp_name", "RPort")
 	viperCfg.SetDefault("api.password_min_length", 14)
 	viperCfg.SetDefault("api.password_zxcvbn_minscore", 0)
 	viperCfg.SetDefault("api.tls_min", "1.3")
 	viperCfg.SetDefault("notifications.notification_script_dir", "/usr/local/lib/riport/notification_scripts")
+	viperCfg.SetDefault("cluster.enabled", false)
+	viperCfg.SetDefault("cluster.heartbeat_interval", cluster.DefaultHeartbeatInterval)
 }
 
 func bindPFlags() {
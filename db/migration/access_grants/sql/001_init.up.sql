@@ -0,0 +1,19 @@
+CREATE TABLE access_grants (
+    id TEXT PRIMARY KEY NOT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    user_group TEXT NOT NULL DEFAULT '',
+    client_id TEXT NOT NULL DEFAULT '',
+    client_group_id TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL DEFAULT '[]',
+    starts_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    revoked_at DATETIME DEFAULT NULL,
+    revoked_by TEXT DEFAULT NULL
+);
+
+CREATE INDEX access_grants_status ON access_grants (status);
+CREATE INDEX access_grants_expires_at ON access_grants (expires_at);
@@ -0,0 +1,29 @@
+CREATE TABLE approval_policies (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    sudo_only INTEGER(1) DEFAULT 0 NOT NULL,
+    client_group_ids TEXT NOT NULL DEFAULT '[]',
+    approver_groups TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE TABLE approval_requests (
+    id TEXT PRIMARY KEY NOT NULL,
+    policy_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    client_ids TEXT NOT NULL DEFAULT '[]',
+    request TEXT NOT NULL,
+    requested_at DATETIME NOT NULL,
+    requested_by TEXT NOT NULL,
+    decided_at DATETIME DEFAULT NULL,
+    decided_by TEXT DEFAULT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    result TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX approval_requests_status ON approval_requests (status);
+CREATE INDEX approval_requests_requested_at ON approval_requests (requested_at DESC);
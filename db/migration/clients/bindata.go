@@ -6,6 +6,14 @@
 // 002_stored_tunnels.up.sql (251B)
 // 003_add_tunnel_fields.down.sql (0)
 // 003_add_tunnel_fields.up.sql (104B)
+// 004_persistent_stored_tunnels.down.sql (186B)
+// 004_persistent_stored_tunnels.up.sql (460B)
+// 005_tunnel_templates.down.sql (29B)
+// 005_tunnel_templates.up.sql (489B)
+// 006_enrollment_tokens.down.sql (54B)
+// 006_enrollment_tokens.up.sql (787B)
+// 007_stored_tunnels_owner.down.sql (46B)
+// 007_stored_tunnels_owner.up.sql (63B)
 
 package clients
 
@@ -193,6 +201,166 @@ func _003_add_tunnel_fieldsUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __004_persistent_stored_tunnelsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x2e\xc9\x2f\x4a\x4d\x89\x2f\x29\xcd\xcb\x4b\xcd\x89\x2f\xc8\x2f\x2a\x29\xb6\xe6\x72\x01\xc9\x7b\xfa\xb9\xb8\x46\x28\x64\xa6\x54\xc4\xa3\xa8\x29\x8e\x4f\xce\xc9\x4c\xcd\x2b\x89\x4f\x2f\xca\x2f\x2d\x88\xcf\x4c\xb1\xe6\x72\xf4\x09\x71\x0d\xc2\x66\x5e\xb1\x02\xd8\x24\x67\x7f\x9f\x50\x5f\x3f\x85\x82\xd4\xa2\xe2\xcc\xe2\x12\xa0\x5e\xa2\xb5\x60\x58\x05\x00\x27\x97\xe9\x1a\xba\x00\x00\x00")
+
+func _004_persistent_stored_tunnelsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_persistent_stored_tunnelsDownSql,
+		"004_persistent_stored_tunnels.down.sql",
+	)
+}
+
+func _004_persistent_stored_tunnelsDownSql() (*asset, error) {
+	bytes, err := _004_persistent_stored_tunnelsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_persistent_stored_tunnels.down.sql", size: 186, mode: os.FileMode(0644), modTime: time.Unix(1792381059, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xff, 0x91, 0x19, 0xc5, 0x51, 0x82, 0xf1, 0x8f, 0x33, 0xab, 0xe1, 0x1f, 0x4, 0xfb, 0x7f, 0xf9, 0x86, 0x6f, 0x26, 0xb7, 0xd1, 0xf0, 0x2f, 0x54, 0x45, 0x19, 0x21, 0x42, 0x83, 0x88, 0xd2, 0xa6}}
+	return a, nil
+}
+
+var __004_persistent_stored_tunnelsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7d\x90\x4d\x0b\xc2\x30\x0c\x86\xef\xfd\x15\xb9\x39\xc1\x83\xf7\x9d\xb2\x35\x82\x58\x3b\xa9\x15\xf4\x54\xd0\x0d\x29\x8c\x6d\x6c\x1d\xf8\xf3\x8d\x1f\x20\xab\x62\xa1\x97\x26\x7d\xde\x27\x41\x65\xc9\x80\xc5\x4c\x11\x0c\xa1\xed\xab\xd2\x85\xb1\x69\xaa\x7a\x00\x94\x12\x2e\xb5\xaf\x9a\xe0\xae\x7d\x3b\x76\xce\x97\x60\xe9\x68\x41\x17\x7c\x0f\x4a\x81\xa4\x15\x1e\x94\x85\xd9\x2c\x15\xf8\x1f\xd4\x55\xfd\xe0\x87\xc0\x30\xc8\x8a\x42\x11\xea\x6f\xcc\x32\x15\x22\x37\x84\x96\x60\xad\x25\x1d\xc1\x97\x37\x37\x65\xb9\x58\xa8\xd0\x71\x5a\x12\xb5\xcc\x3f\xd4\x1f\x76\xae\x6b\xfb\xc0\x9f\x04\xf0\x99\x56\xbe\xc6\x35\xb4\x22\x43\x3a\xa7\x7d\x94\x99\x70\xca\xc3\x44\x92\x22\x8e\xc9\x71\x9f\xa3\xa4\xc5\x93\xf9\xb6\x89\x61\xaf\x62\x37\x9e\x6b\x7f\x79\x3a\xf0\xeb\x36\xe3\x0d\x4e\x1b\x76\x66\xbd\x45\x73\x82\x0d\x9d\x20\x89\xf5\x16\x1f\xf8\x5c\xf0\x98\x77\x08\xaf\x47\x08\xcc\x01\x00\x00")
+
+func _004_persistent_stored_tunnelsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_persistent_stored_tunnelsUpSql,
+		"004_persistent_stored_tunnels.up.sql",
+	)
+}
+
+func _004_persistent_stored_tunnelsUpSql() (*asset, error) {
+	bytes, err := _004_persistent_stored_tunnelsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_persistent_stored_tunnels.up.sql", size: 460, mode: os.FileMode(0644), modTime: time.Unix(1792381059, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x7c, 0xa5, 0x3a, 0x54, 0x72, 0xc5, 0x10, 0xb6, 0xc8, 0xbb, 0xd6, 0xcc, 0x9d, 0x2a, 0x1, 0x8, 0xaf, 0x75, 0x2a, 0x94, 0xa1, 0xf, 0xf8, 0xb4, 0xd7, 0x3e, 0x17, 0x4a, 0x2c, 0xea, 0x55, 0x91}}
+	return a, nil
+}
+
+var __005_tunnel_templatesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x29\xcd\xcb\x4b\xcd\x89\x2f\x49\xcd\x2d\xc8\x49\x2c\x49\x2d\xb6\xe6\x02\x00\x8f\x8a\x99\xc9\x1d\x00\x00\x00")
+
+func _005_tunnel_templatesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__005_tunnel_templatesDownSql,
+		"005_tunnel_templates.down.sql",
+	)
+}
+
+func _005_tunnel_templatesDownSql() (*asset, error) {
+	bytes, err := _005_tunnel_templatesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "005_tunnel_templates.down.sql", size: 29, mode: os.FileMode(0644), modTime: time.Unix(1792381369, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x29, 0x18, 0x83, 0x2e, 0xbd, 0xf3, 0x5e, 0x68, 0xe9, 0xe2, 0x62, 0xa7, 0x85, 0x57, 0x96, 0xb8, 0xb7, 0x82, 0x4c, 0x46, 0xbe, 0xce, 0x62, 0x90, 0xd, 0xdd, 0x6d, 0x99, 0xe0, 0xd8, 0x34, 0xe1}}
+	return a, nil
+}
+
+var __005_tunnel_templatesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x91\x31\x6f\x83\x30\x10\x85\x77\x7e\xc5\x6d\x69\xa5\x0e\xdd\x3b\x41\xb8\x4a\x28\x60\x5a\x64\xa4\x64\xb2\x28\x5c\x85\x25\x83\x2d\x73\x48\xc9\xbf\xaf\x03\x19\x92\x26\x55\x3d\xbe\xfb\xfc\xfc\xce\x6f\x5b\x61\x2c\x11\x64\x9c\xe4\x08\x3c\x8f\x23\x19\xc5\x34\x38\xd3\x30\x4d\xf0\x14\x41\x38\xba\x03\x89\x7b\x09\x1f\x55\x56\xc4\xd5\x01\x76\x78\x00\x51\x4a\x10\x75\x9e\xbf\x2c\xc4\xd8\x0c\xb4\x32\xb7\x7a\xeb\x29\xf8\x74\xaa\x61\x48\xc3\x33\x32\x2b\xf0\x0f\xe2\xeb\xf4\xe8\xbe\xf3\x96\x6d\x6b\xcd\xed\x0c\x52\x7c\x8f\xeb\x5c\xc2\x66\xb3\x62\x53\xdb\xd3\x25\xc0\x2a\x78\x1a\x2c\x93\xd2\xee\x5e\x73\xd6\x73\x70\x29\x12\xac\x56\xbd\x69\xcd\x15\xa5\x3b\x43\x8a\xf5\x40\x76\x66\x35\xe8\x71\x3e\x7f\xc3\x35\xde\x33\x3b\x15\x72\x1d\x4f\x90\x94\x65\x8e\xb1\xb8\xcf\xf5\x7a\x41\xed\xc4\xaa\xa7\xa6\x23\xff\xcf\x02\xdf\xb3\xe7\x9e\xbc\xb2\x8e\xb5\x1d\xa7\x85\x8e\x9e\xdf\xa2\x68\xbb\xd6\x53\x8b\xec\xb3\x46\xc8\x44\x8a\xfb\x10\xf1\xa8\x7e\x37\xa5\x96\x06\x4a\xf1\xa0\xc2\xf3\x24\x58\xfd\x00\x3e\x51\x17\xc0\xe9\x01\x00\x00")
+
+func _005_tunnel_templatesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__005_tunnel_templatesUpSql,
+		"005_tunnel_templates.up.sql",
+	)
+}
+
+func _005_tunnel_templatesUpSql() (*asset, error) {
+	bytes, err := _005_tunnel_templatesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "005_tunnel_templates.up.sql", size: 489, mode: os.FileMode(0644), modTime: time.Unix(1792381369, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xb4, 0xd5, 0xbd, 0x10, 0x66, 0xe2, 0xe2, 0x29, 0x91, 0x97, 0x2e, 0x86, 0x73, 0x2, 0x63, 0xa7, 0xb4, 0x82, 0xa6, 0x90, 0x9b, 0x8, 0x42, 0xf3, 0xb, 0xcd, 0x93, 0x92, 0xfd, 0xe4, 0x1b, 0x4d}}
+	return a, nil
+}
+
+var __006_enrollment_tokensDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\xcd\x2b\xca\xcf\xc9\xc9\x4d\xcd\x2b\x29\xb6\xe6\x72\xc1\x26\x1e\x5f\x92\x9f\x9d\x9a\x07\x94\x05\x00\x1b\x1d\x03\x61\x36\x00\x00\x00")
+
+func _006_enrollment_tokensDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__006_enrollment_tokensDownSql,
+		"006_enrollment_tokens.down.sql",
+	)
+}
+
+func _006_enrollment_tokensDownSql() (*asset, error) {
+	bytes, err := _006_enrollment_tokensDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "006_enrollment_tokens.down.sql", size: 54, mode: os.FileMode(0644), modTime: time.Unix(1792385935, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x47, 0xc6, 0x1f, 0xe3, 0xd, 0xf7, 0x95, 0xf4, 0xa8, 0xfa, 0xf9, 0xbe, 0xf2, 0xbe, 0xc8, 0xb9, 0xae, 0x24, 0x3e, 0x8a, 0x37, 0xfc, 0xe6, 0xa8, 0x9b, 0x92, 0x50, 0x26, 0xc, 0x41, 0xc9, 0x7f}}
+	return a, nil
+}
+
+var __006_enrollment_tokensUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x52\xc1\x6e\x82\x40\x10\xbd\xf3\x15\x73\x53\x93\x1e\x7a\xf7\x84\x30\x36\xa4\x88\xcd\xba\x26\x9a\xa6\xd9\xac\x32\x51\x52\x04\xb2\xbb\x36\x34\x4d\xff\xbd\xab\x88\x11\x05\xba\xc7\x7d\x6f\x66\xde\xbc\x37\x1e\x43\x97\x23\x70\x77\x12\x22\x50\xa6\xf2\x34\x3d\x50\x66\x84\xc9\x3f\x29\xd3\x30\x74\xc0\xbe\x24\x06\x8e\x2b\x0e\x6f\x2c\x98\xb9\x6c\x0d\xaf\xb8\x86\x68\xce\x21\x5a\x86\xe1\xd3\x99\xa1\x69\xab\xc8\x88\xbd\xd4\xfb\x8a\xda\x84\x63\xd2\x5b\x95\x14\x26\xc9\xb3\x26\x0c\x3e\x4e\xdd\x65\xc8\x61\x30\xa8\x98\xb6\x8d\x34\x14\x0b\x69\xc0\xb7\xc2\x78\x30\xc3\xbb\x5e\x35\x63\xf3\xdd\x36\x89\xca\x22\x51\xa4\x7b\xea\x0f\xb2\x14\x47\x4d\xda\x7e\xcd\x26\xc8\xee\xd0\x36\xe4\x2a\xf2\xb9\xe2\x28\xfa\xb2\xee\x34\x34\x56\x80\x91\x3b\xdd\xb5\xdf\xfb\xc7\x65\xc3\x54\x6e\x28\xed\xa4\xfd\xfc\x5e\x68\x3b\x95\x1f\x0b\x51\x3b\xdf\xe2\x97\x33\x1a\x3b\x8e\xd7\x1e\x5f\x1d\xdc\x39\xc5\x87\x26\x17\x1f\xd3\xe4\x14\x74\x2f\x28\x8f\x66\xdf\xc1\x50\x74\xc8\x0d\x89\xa4\xf8\x27\xd0\x4a\x54\x6f\xa2\xd3\x39\xc3\xe0\x25\x3a\x9f\xd5\xb0\x96\x3c\x02\x86\x53\x64\x18\x79\xb8\x78\x3c\xcc\xe1\x89\x30\x8f\xec\xb4\x10\xed\xfe\x9e\xbb\xf0\x5c\x1f\x6f\x2d\x09\x22\x1f\x57\xf6\x72\x4b\x71\x63\x8b\xb8\x1a\x62\x6b\x1b\x76\x5d\xc7\x8e\x9d\x3f\x13\x53\x82\x5e\x13\x03\x00\x00")
+
+func _006_enrollment_tokensUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__006_enrollment_tokensUpSql,
+		"006_enrollment_tokens.up.sql",
+	)
+}
+
+func _006_enrollment_tokensUpSql() (*asset, error) {
+	bytes, err := _006_enrollment_tokensUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "006_enrollment_tokens.up.sql", size: 787, mode: os.FileMode(0644), modTime: time.Unix(1792385935, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x74, 0xb0, 0x98, 0xab, 0xed, 0x3e, 0xad, 0xed, 0x33, 0x7f, 0xb6, 0x66, 0xd9, 0x12, 0x84, 0xa4, 0xd7, 0xec, 0x5a, 0x9c, 0xe, 0x73, 0xf4, 0x32, 0x5, 0x0, 0x25, 0x18, 0xa2, 0x7c, 0xc, 0x82}}
+	return a, nil
+}
+
+var __007_stored_tunnels_ownerDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x2e\xc9\x2f\x4a\x4d\x89\x2f\x29\xcd\xcb\x4b\xcd\x29\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xc8\x2f\xcf\x4b\x2d\xb2\xe6\x02\x00\xac\x05\x4b\x02\x2e\x00\x00\x00")
+
+func _007_stored_tunnels_ownerDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__007_stored_tunnels_ownerDownSql,
+		"007_stored_tunnels_owner.down.sql",
+	)
+}
+
+func _007_stored_tunnels_ownerDownSql() (*asset, error) {
+	bytes, err := _007_stored_tunnels_ownerDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "007_stored_tunnels_owner.down.sql", size: 46, mode: os.FileMode(0644), modTime: time.Unix(1792389174, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x4e, 0x1a, 0xc2, 0x7d, 0xc3, 0x47, 0x23, 0x3a, 0xe3, 0xf8, 0xac, 0xf0, 0x6f, 0x9a, 0x47, 0xe3, 0x35, 0x8d, 0x7e, 0x3f, 0x19, 0x6d, 0xf3, 0xcf, 0xe1, 0xb2, 0x29, 0x4d, 0x78, 0x43, 0x8d, 0x36}}
+	return a, nil
+}
+
+var __007_stored_tunnels_ownerUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x2e\xc9\x2f\x4a\x4d\x89\x2f\x29\xcd\xcb\x4b\xcd\x29\x56\x70\x74\x71\x51\xc8\x2f\xcf\x4b\x2d\x52\x08\x71\x8d\x08\x51\xf0\xf3\x07\xe2\x50\x1f\x1f\x05\x17\x57\x37\xc7\x50\x9f\x10\x05\x75\x75\x6b\x2e\x00\x5c\xf1\xcb\xfb\x3f\x00\x00\x00")
+
+func _007_stored_tunnels_ownerUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__007_stored_tunnels_ownerUpSql,
+		"007_stored_tunnels_owner.up.sql",
+	)
+}
+
+func _007_stored_tunnels_ownerUpSql() (*asset, error) {
+	bytes, err := _007_stored_tunnels_ownerUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "007_stored_tunnels_owner.up.sql", size: 63, mode: os.FileMode(0644), modTime: time.Unix(1792389174, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x67, 0xaa, 0x93, 0x81, 0x19, 0x5, 0xa3, 0x97, 0x80, 0xfc, 0xbf, 0x3, 0x5c, 0xb7, 0xa7, 0xb1, 0xe2, 0x5f, 0x2c, 0xcb, 0x8b, 0x90, 0x47, 0xb9, 0x72, 0xc3, 0xf0, 0x67, 0x6b, 0x86, 0x2c, 0x9c}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -284,12 +452,20 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_init.down.sql":              _001_initDownSql,
-	"001_init.up.sql":                _001_initUpSql,
-	"002_stored_tunnels.down.sql":    _002_stored_tunnelsDownSql,
-	"002_stored_tunnels.up.sql":      _002_stored_tunnelsUpSql,
-	"003_add_tunnel_fields.down.sql": _003_add_tunnel_fieldsDownSql,
-	"003_add_tunnel_fields.up.sql":   _003_add_tunnel_fieldsUpSql,
+	"001_init.down.sql":                      _001_initDownSql,
+	"001_init.up.sql":                        _001_initUpSql,
+	"002_stored_tunnels.down.sql":            _002_stored_tunnelsDownSql,
+	"002_stored_tunnels.up.sql":              _002_stored_tunnelsUpSql,
+	"003_add_tunnel_fields.down.sql":         _003_add_tunnel_fieldsDownSql,
+	"003_add_tunnel_fields.up.sql":           _003_add_tunnel_fieldsUpSql,
+	"004_persistent_stored_tunnels.down.sql": _004_persistent_stored_tunnelsDownSql,
+	"004_persistent_stored_tunnels.up.sql":   _004_persistent_stored_tunnelsUpSql,
+	"005_tunnel_templates.down.sql":          _005_tunnel_templatesDownSql,
+	"005_tunnel_templates.up.sql":            _005_tunnel_templatesUpSql,
+	"006_enrollment_tokens.down.sql":         _006_enrollment_tokensDownSql,
+	"006_enrollment_tokens.up.sql":           _006_enrollment_tokensUpSql,
+	"007_stored_tunnels_owner.down.sql": _007_stored_tunnels_ownerDownSql,
+	"007_stored_tunnels_owner.up.sql": _007_stored_tunnels_ownerUpSql,
 }
 
 // AssetDebug is true if the assets were built with the debug flag enabled.
@@ -338,12 +514,20 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_init.down.sql":              {_001_initDownSql, map[string]*bintree{}},
-	"001_init.up.sql":                {_001_initUpSql, map[string]*bintree{}},
-	"002_stored_tunnels.down.sql":    {_002_stored_tunnelsDownSql, map[string]*bintree{}},
-	"002_stored_tunnels.up.sql":      {_002_stored_tunnelsUpSql, map[string]*bintree{}},
-	"003_add_tunnel_fields.down.sql": {_003_add_tunnel_fieldsDownSql, map[string]*bintree{}},
-	"003_add_tunnel_fields.up.sql":   {_003_add_tunnel_fieldsUpSql, map[string]*bintree{}},
+	"001_init.down.sql":                      {_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":                        {_001_initUpSql, map[string]*bintree{}},
+	"002_stored_tunnels.down.sql":            {_002_stored_tunnelsDownSql, map[string]*bintree{}},
+	"002_stored_tunnels.up.sql":              {_002_stored_tunnelsUpSql, map[string]*bintree{}},
+	"003_add_tunnel_fields.down.sql":         {_003_add_tunnel_fieldsDownSql, map[string]*bintree{}},
+	"003_add_tunnel_fields.up.sql":           {_003_add_tunnel_fieldsUpSql, map[string]*bintree{}},
+	"004_persistent_stored_tunnels.down.sql": {_004_persistent_stored_tunnelsDownSql, map[string]*bintree{}},
+	"004_persistent_stored_tunnels.up.sql":   {_004_persistent_stored_tunnelsUpSql, map[string]*bintree{}},
+	"005_tunnel_templates.down.sql":          {_005_tunnel_templatesDownSql, map[string]*bintree{}},
+	"005_tunnel_templates.up.sql":            {_005_tunnel_templatesUpSql, map[string]*bintree{}},
+	"006_enrollment_tokens.down.sql":         {_006_enrollment_tokensDownSql, map[string]*bintree{}},
+	"006_enrollment_tokens.up.sql":           {_006_enrollment_tokensUpSql, map[string]*bintree{}},
+	"007_stored_tunnels_owner.down.sql": {_007_stored_tunnels_ownerDownSql, map[string]*bintree{}},
+	"007_stored_tunnels_owner.up.sql": {_007_stored_tunnels_ownerUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
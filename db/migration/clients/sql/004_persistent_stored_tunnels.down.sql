@@ -0,0 +1,4 @@
+DROP TABLE stored_tunnel_ports;
+DROP INDEX idx_stored_tunnels_client_group_id;
+ALTER TABLE stored_tunnels DROP COLUMN persistent;
+ALTER TABLE stored_tunnels DROP COLUMN client_group_id;
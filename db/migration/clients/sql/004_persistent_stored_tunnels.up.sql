@@ -0,0 +1,11 @@
+ALTER TABLE stored_tunnels ADD client_group_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE stored_tunnels ADD persistent BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_stored_tunnels_client_group_id ON stored_tunnels (client_group_id);
+
+CREATE TABLE stored_tunnel_ports (
+    stored_tunnel_id TEXT NOT NULL REFERENCES stored_tunnels(id) ON DELETE CASCADE,
+    client_id TEXT NOT NULL,
+    public_port NUMBER NOT NULL,
+    PRIMARY KEY (stored_tunnel_id, client_id)
+);
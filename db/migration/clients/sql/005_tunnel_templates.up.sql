@@ -0,0 +1,17 @@
+CREATE TABLE tunnel_templates (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    protocol TEXT NOT NULL DEFAULT '',
+    scheme TEXT,
+    remote_ip TEXT,
+    remote_port NUMBER,
+    acl TEXT,
+    idle_timeout_minutes NUMBER,
+    http_proxy BOOLEAN NOT NULL DEFAULT 0,
+    host_header TEXT NOT NULL DEFAULT '',
+    further_options TEXT
+);
+
+CREATE UNIQUE INDEX idx_tunnel_templates_name ON tunnel_templates (name);
@@ -0,0 +1,25 @@
+CREATE TABLE enrollment_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    secret_hash TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    max_uses NUMBER NOT NULL,
+    uses NUMBER NOT NULL DEFAULT 0,
+    revoked_at DATETIME,
+    tags TEXT NOT NULL DEFAULT '[]',
+    labels TEXT NOT NULL DEFAULT '{}',
+    group_id TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE enrollments (
+    token_id TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    client_auth_id TEXT NOT NULL,
+    remote_ip TEXT NOT NULL DEFAULT '',
+    enrolled_at DATETIME NOT NULL,
+    FOREIGN KEY (token_id) REFERENCES enrollment_tokens(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_enrollments_token_id ON enrollments (token_id);
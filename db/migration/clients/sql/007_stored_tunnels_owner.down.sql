@@ -0,0 +1 @@
+ALTER TABLE stored_tunnels DROP COLUMN owner;
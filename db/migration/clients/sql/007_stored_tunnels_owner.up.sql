@@ -0,0 +1 @@
+ALTER TABLE stored_tunnels ADD owner TEXT NOT NULL DEFAULT '';
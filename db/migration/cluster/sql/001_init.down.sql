@@ -0,0 +1,3 @@
+DROP TABLE leases;
+DROP TABLE client_owners;
+DROP TABLE nodes;
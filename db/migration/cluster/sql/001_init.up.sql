@@ -0,0 +1,20 @@
+CREATE TABLE nodes (
+    id TEXT PRIMARY KEY NOT NULL,
+    api_url TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    heartbeat_at DATETIME NOT NULL
+);
+
+CREATE TABLE client_owners (
+    client_id TEXT PRIMARY KEY NOT NULL,
+    node_id TEXT NOT NULL,
+    connected_at DATETIME NOT NULL
+);
+
+CREATE INDEX client_owners_node_id ON client_owners (node_id);
+
+CREATE TABLE leases (
+    name TEXT PRIMARY KEY NOT NULL,
+    node_id TEXT NOT NULL,
+    expires_at DATETIME NOT NULL
+);
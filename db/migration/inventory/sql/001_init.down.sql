@@ -0,0 +1,3 @@
+DROP TABLE inventory_changes;
+DROP TABLE inventory_items;
+DROP TABLE inventories;
@@ -0,0 +1,32 @@
+CREATE TABLE inventories (
+    client_id TEXT PRIMARY KEY NOT NULL,
+    collected_at DATETIME NOT NULL,
+    received_at DATETIME NOT NULL,
+    errors TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE inventory_items (
+    client_id TEXT NOT NULL,
+    category TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '{}',
+    first_seen_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (client_id, category, name)
+);
+
+CREATE TABLE inventory_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    client_id TEXT NOT NULL,
+    detected_at DATETIME NOT NULL,
+    category TEXT NOT NULL,
+    name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    old_version TEXT NOT NULL DEFAULT '',
+    new_version TEXT NOT NULL DEFAULT '',
+    old_details TEXT NOT NULL DEFAULT '{}',
+    new_details TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX inventory_changes_client_detected_at ON inventory_changes (client_id, detected_at);
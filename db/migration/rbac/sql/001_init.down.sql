@@ -0,0 +1,2 @@
+DROP TABLE role_bindings;
+DROP TABLE roles;
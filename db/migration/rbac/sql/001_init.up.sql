@@ -0,0 +1,18 @@
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rules TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE TABLE role_bindings (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_group TEXT NOT NULL,
+    role TEXT NOT NULL,
+    client_group_ids TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE INDEX role_bindings_user_group ON role_bindings (user_group);
@@ -0,0 +1,19 @@
+CREATE TABLE tunnel_connections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    client_id TEXT NOT NULL,
+    tunnel_id TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    source_ip TEXT NOT NULL,
+    source_port INTEGER NOT NULL DEFAULT 0,
+    destination TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    bytes_in INTEGER NOT NULL DEFAULT 0,
+    bytes_out INTEGER NOT NULL DEFAULT 0,
+    close_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX tunnel_connections_client_tunnel ON tunnel_connections (client_id, tunnel_id);
+CREATE INDEX tunnel_connections_ended_at ON tunnel_connections (ended_at);
@@ -0,0 +1,35 @@
+package accessgrants
+
+import (
+	"context"
+
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/share/logger"
+)
+
+// ExpiryTask revokes grants automatically once they expire.
+type ExpiryTask struct {
+	log      *logger.Logger
+	manager  *Manager
+	auditLog *auditlog.AuditLog
+}
+
+func NewExpiryTask(log *logger.Logger, manager *Manager, auditLog *auditlog.AuditLog) *ExpiryTask {
+	return &ExpiryTask{
+		log:      log,
+		manager:  manager,
+		auditLog: auditLog,
+	}
+}
+
+func (t *ExpiryTask) Run(ctx context.Context) error {
+	expired, err := t.manager.ExpireDue(ctx)
+	for _, g := range expired {
+		t.auditLog.Entry(auditlog.ApplicationAccessGrant, auditlog.ActionExpire).
+			WithID(g.ID).
+			WithRequest(g).
+			Save()
+		t.log.Infof("Access grant [id=%q] expired.", g.ID)
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package accessgrants
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/types"
+)
+
+const (
+	StatusActive  = "active"
+	StatusRevoked = "revoked"
+	StatusExpired = "expired"
+)
+
+// ClientPermissions are the permissions that can be granted, those checked on client routes.
+var ClientPermissions = []string{
+	users.PermissionTunnels,
+	users.PermissionCommands,
+	users.PermissionScripts,
+	users.PermissionMonitoring,
+}
+
+var SupportedFilters = map[string]bool{
+	"status":          true,
+	"username":        true,
+	"user_group":      true,
+	"client_id":       true,
+	"client_group_id": true,
+	"created_by":      true,
+}
+
+var SupportedSorts = map[string]bool{
+	"starts_at":  true,
+	"expires_at": true,
+	"created_at": true,
+}
+
+var ListDefaultSort = map[string][]string{
+	"sort": {"-created_at"},
+}
+
+// Grant gives a user or a user group temporary access to a client or a client group.
+type Grant struct {
+	ID            string `json:"id" db:"id"`
+	Username      string `json:"username" db:"username"`
+	UserGroup     string `json:"user_group" db:"user_group"`
+	ClientID      string `json:"client_id" db:"client_id"`
+	ClientGroupID string `json:"client_group_id" db:"client_group_id"`
+	// Permissions are granted in addition to the user group permissions, on the granted clients only.
+	Permissions types.StringSlice `json:"permissions" db:"permissions"`
+	StartsAt    time.Time         `json:"starts_at" db:"starts_at"`
+	ExpiresAt   time.Time         `json:"expires_at" db:"expires_at"`
+	Reason      string            `json:"reason" db:"reason"`
+	Status      string            `json:"status" db:"status"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	CreatedBy   string            `json:"created_by" db:"created_by"`
+	RevokedAt   *time.Time        `json:"revoked_at" db:"revoked_at"`
+	RevokedBy   *string           `json:"revoked_by" db:"revoked_by"`
+}
+
+func (g *Grant) Validate() error {
+	if (g.Username == "") == (g.UserGroup == "") {
+		return errors.New("exactly one of username or user_group must be set")
+	}
+	if (g.ClientID == "") == (g.ClientGroupID == "") {
+		return errors.New("exactly one of client_id or client_group_id must be set")
+	}
+	for _, p := range g.Permissions {
+		if !isClientPermission(p) {
+			return fmt.Errorf("permission %q cannot be granted, allowed permissions: %v", p, ClientPermissions)
+		}
+	}
+	if g.ExpiresAt.IsZero() {
+		return errors.New("expires_at cannot be empty")
+	}
+	if !g.ExpiresAt.After(g.StartsAt) {
+		return errors.New("expires_at must be after starts_at")
+	}
+	if g.Reason == "" {
+		return errors.New("reason cannot be empty")
+	}
+	return nil
+}
+
+func isClientPermission(p string) bool {
+	for _, known := range ClientPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEffective returns whether a grant is active and its time window includes a given time.
+func (g *Grant) IsEffective(now time.Time) bool {
+	return g.Status == StatusActive && !now.Before(g.StartsAt) && now.Before(g.ExpiresAt)
+}
+
+// AppliesToUser returns whether a grant was given to a user or to one of the user groups.
+func (g *Grant) AppliesToUser(username string, userGroups []string) bool {
+	if g.Username != "" {
+		return g.Username == username
+	}
+	for _, ug := range userGroups {
+		if ug == g.UserGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesToClient returns whether a grant was given to a client or to a client group the client belongs to.
+func (g *Grant) AppliesToClient(client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool {
+	if g.ClientID != "" {
+		return g.ClientID == client.GetID()
+	}
+	for _, group := range clientGroups {
+		if group.ID == g.ClientGroupID {
+			return client.BelongsTo(group)
+		}
+	}
+	return false
+}
+
+func (g *Grant) HasPermission(permission string) bool {
+	for _, p := range g.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package accessgrants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+)
+
+func TestGrantValidate(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := Grant{
+		Username:    "contractor",
+		ClientID:    "client-1",
+		Permissions: []string{users.PermissionCommands},
+		StartsAt:    start,
+		ExpiresAt:   start.Add(time.Hour),
+		Reason:      "ticket 123",
+	}
+
+	testCases := []struct {
+		Name          string
+		Modify        func(g *Grant)
+		ExpectedError string
+	}{
+		{
+			Name:   "valid",
+			Modify: func(g *Grant) {},
+		},
+		{
+			Name:          "user and user group",
+			Modify:        func(g *Grant) { g.UserGroup = "oncall" },
+			ExpectedError: "exactly one of username or user_group must be set",
+		},
+		{
+			Name:          "no client",
+			Modify:        func(g *Grant) { g.ClientID = "" },
+			ExpectedError: "exactly one of client_id or client_group_id must be set",
+		},
+		{
+			Name:          "not a client permission",
+			Modify:        func(g *Grant) { g.Permissions = []string{users.PermissionVault} },
+			ExpectedError: `permission "vault" cannot be granted, allowed permissions: [tunnels commands scripts monitoring]`,
+		},
+		{
+			Name:          "expires before start",
+			Modify:        func(g *Grant) { g.ExpiresAt = start },
+			ExpectedError: "expires_at must be after starts_at",
+		},
+		{
+			Name:          "no reason",
+			Modify:        func(g *Grant) { g.Reason = "" },
+			ExpectedError: "reason cannot be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			g := valid
+			tc.Modify(&g)
+
+			err := g.Validate()
+
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGrantApplies(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := &clientdata.Client{ID: "client-1"}
+	clientGroups := []*cgroups.ClientGroup{
+		{ID: "servers", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-*"}}},
+		{ID: "desktops", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"desktop-*"}}},
+	}
+
+	userGrant := &Grant{Username: "user1", ClientGroupID: "servers", Status: StatusActive, StartsAt: start, ExpiresAt: start.Add(time.Hour)}
+	groupGrant := &Grant{UserGroup: "oncall", ClientGroupID: "desktops", Status: StatusActive, StartsAt: start, ExpiresAt: start.Add(time.Hour)}
+
+	assert.True(t, userGrant.AppliesToUser("user1", nil))
+	assert.False(t, userGrant.AppliesToUser("user2", []string{"oncall"}))
+	assert.True(t, groupGrant.AppliesToUser("user2", []string{"others", "oncall"}))
+	assert.False(t, groupGrant.AppliesToUser("user2", []string{"others"}))
+
+	assert.True(t, userGrant.AppliesToClient(client, clientGroups))
+	assert.False(t, groupGrant.AppliesToClient(client, clientGroups))
+
+	assert.False(t, userGrant.IsEffective(start.Add(-time.Second)))
+	assert.True(t, userGrant.IsEffective(start))
+	assert.False(t, userGrant.IsEffective(start.Add(time.Hour)))
+}
@@ -0,0 +1,151 @@
+package accessgrants
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/query"
+)
+
+var now = time.Now
+
+// Manager persists grants and keeps the active ones in memory, so access checks don't hit the database.
+type Manager struct {
+	provider Provider
+
+	mu     sync.RWMutex
+	active map[string]*Grant
+}
+
+func NewManager(ctx context.Context, provider Provider) (*Manager, error) {
+	active, err := provider.ListByStatus(ctx, StatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load active access grants: %w", err)
+	}
+
+	m := &Manager{
+		provider: provider,
+		active:   make(map[string]*Grant, len(active)),
+	}
+	for _, g := range active {
+		m.active[g.ID] = g
+	}
+	return m, nil
+}
+
+func (m *Manager) List(ctx context.Context, options *query.ListOptions) ([]*Grant, error) {
+	return m.provider.List(ctx, options)
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (*Grant, error) {
+	return m.provider.Get(ctx, id)
+}
+
+// Create persists a new active grant.
+func (m *Manager) Create(ctx context.Context, g *Grant) error {
+	g.Status = StatusActive
+	if err := m.provider.Save(ctx, g); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[g.ID] = g
+	return nil
+}
+
+// Revoke ends a grant before its expiry.
+func (m *Manager) Revoke(ctx context.Context, g *Grant, revokedBy string) error {
+	revokedAt := now()
+	g.Status = StatusRevoked
+	g.RevokedAt = &revokedAt
+	g.RevokedBy = &revokedBy
+	if err := m.provider.Save(ctx, g); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, g.ID)
+	return nil
+}
+
+// ExpireDue marks all active grants past their expiry as expired and returns them.
+func (m *Manager) ExpireDue(ctx context.Context) ([]*Grant, error) {
+	current := now()
+
+	m.mu.RLock()
+	var due []*Grant
+	for _, g := range m.active {
+		if !current.Before(g.ExpiresAt) {
+			due = append(due, g)
+		}
+	}
+	m.mu.RUnlock()
+
+	expired := make([]*Grant, 0, len(due))
+	for _, g := range due {
+		updated := *g
+		updated.Status = StatusExpired
+		if err := m.provider.Save(ctx, &updated); err != nil {
+			return expired, fmt.Errorf("failed to expire access grant %q: %w", g.ID, err)
+		}
+
+		m.mu.Lock()
+		delete(m.active, g.ID)
+		m.mu.Unlock()
+
+		expired = append(expired, &updated)
+	}
+	return expired, nil
+}
+
+// HasClientAccess returns whether an effective grant gives a user access to a client.
+func (m *Manager) HasClientAccess(username string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool {
+	return m.findEffective(username, userGroups, client, clientGroups, func(*Grant) bool { return true })
+}
+
+// HasClientPermission returns whether an effective grant gives a user a permission on a client.
+func (m *Manager) HasClientPermission(username string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup, permission string) bool {
+	return m.findEffective(username, userGroups, client, clientGroups, func(g *Grant) bool { return g.HasPermission(permission) })
+}
+
+func (m *Manager) findEffective(
+	username string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup, match func(*Grant) bool,
+) bool {
+	current := now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, g := range m.active {
+		if g.IsEffective(current) && g.AppliesToUser(username, userGroups) && g.AppliesToClient(client, clientGroups) && match(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync reloads the active grants, so grants created, revoked or expired by other servers sharing the database take effect.
+func (m *Manager) Sync(ctx context.Context) error {
+	// the lock is held while loading, so changes made meanwhile by this server are not overwritten
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, err := m.provider.ListByStatus(ctx, StatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to load active access grants: %w", err)
+	}
+	m.active = make(map[string]*Grant, len(active))
+	for _, g := range active {
+		m.active[g.ID] = g
+	}
+	return nil
+}
+
+func (m *Manager) Close() error {
+	return m.provider.Close()
+}
@@ -0,0 +1,126 @@
+package accessgrants
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	accessgrantsmigration "github.com/riportdev/riport/db/migration/access_grants"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/query"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	current := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	db, err := sqlite.New(":memory:", accessgrantsmigration.AssetNames(), accessgrantsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(db)
+
+	existing := &Grant{
+		ID:        "g0",
+		UserGroup: "oncall",
+		ClientID:  "client-1",
+		StartsAt:  current.Add(-2 * time.Hour),
+		ExpiresAt: current.Add(-time.Hour),
+		Reason:    "incident",
+		Status:    StatusActive,
+		CreatedAt: current.Add(-2 * time.Hour),
+		CreatedBy: "admin",
+	}
+	require.NoError(t, p.Save(ctx, existing))
+
+	m, err := NewManager(ctx, p)
+	require.NoError(t, err)
+	defer m.Close()
+
+	grant := &Grant{
+		ID:          "g1",
+		Username:    "contractor",
+		ClientID:    "client-1",
+		Permissions: []string{users.PermissionCommands},
+		StartsAt:    current,
+		ExpiresAt:   current.Add(time.Hour),
+		Reason:      "ticket 123",
+		CreatedAt:   current,
+		CreatedBy:   "admin",
+	}
+	require.NoError(t, m.Create(ctx, grant))
+
+	client := &clientdata.Client{ID: "client-1"}
+	assert.True(t, m.HasClientAccess("contractor", nil, client, nil))
+	assert.True(t, m.HasClientPermission("contractor", nil, client, nil, users.PermissionCommands))
+	assert.False(t, m.HasClientPermission("contractor", nil, client, nil, users.PermissionTunnels))
+	assert.False(t, m.HasClientAccess("someone", []string{"oncall"}, client, nil))
+	assert.False(t, m.HasClientAccess("contractor", nil, &clientdata.Client{ID: "client-2"}, nil))
+
+	expired, err := m.ExpireDue(ctx)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "g0", expired[0].ID)
+	assert.Equal(t, StatusExpired, expired[0].Status)
+
+	require.NoError(t, m.Revoke(ctx, grant, "admin"))
+	assert.False(t, m.HasClientAccess("contractor", nil, client, nil))
+
+	stored, err := m.Get(ctx, "g1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRevoked, stored.Status)
+	require.NotNil(t, stored.RevokedBy)
+	assert.Equal(t, "admin", *stored.RevokedBy)
+
+	active, err := m.List(ctx, &query.ListOptions{
+		Filters: []query.FilterOption{{Column: []string{"status"}, Values: []string{StatusActive}}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+func TestManagerSync(t *testing.T) {
+	ctx := context.Background()
+	current := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	db, err := sqlite.New(":memory:", accessgrantsmigration.AssetNames(), accessgrantsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(db)
+
+	// two servers sharing the database
+	m1, err := NewManager(ctx, p)
+	require.NoError(t, err)
+	defer m1.Close()
+	m2, err := NewManager(ctx, p)
+	require.NoError(t, err)
+
+	grant := &Grant{
+		ID:        "g1",
+		Username:  "contractor",
+		ClientID:  "client-1",
+		StartsAt:  current,
+		ExpiresAt: current.Add(time.Hour),
+		Reason:    "ticket 123",
+		CreatedAt: current,
+		CreatedBy: "admin",
+	}
+	require.NoError(t, m1.Create(ctx, grant))
+
+	client := &clientdata.Client{ID: "client-1"}
+	assert.False(t, m2.HasClientAccess("contractor", nil, client, nil))
+	require.NoError(t, m2.Sync(ctx))
+	assert.True(t, m2.HasClientAccess("contractor", nil, client, nil))
+
+	require.NoError(t, m1.Revoke(ctx, grant, "admin"))
+	require.NoError(t, m2.Sync(ctx))
+	assert.False(t, m2.HasClientAccess("contractor", nil, client, nil))
+}
@@ -0,0 +1,81 @@
+package accessgrants
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/query"
+)
+
+type Provider interface {
+	List(ctx context.Context, options *query.ListOptions) ([]*Grant, error)
+	ListByStatus(ctx context.Context, status string) ([]*Grant, error)
+	Get(ctx context.Context, id string) (*Grant, error)
+	Save(ctx context.Context, g *Grant) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+	}
+}
+
+func (p *SqliteProvider) List(ctx context.Context, options *query.ListOptions) ([]*Grant, error) {
+	q, params := p.converter.ConvertListOptionsToQuery(options, "SELECT * FROM access_grants")
+
+	res := []*Grant{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) ListByStatus(ctx context.Context, status string) ([]*Grant, error) {
+	res := []*Grant{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM access_grants WHERE status = ? ORDER BY created_at, id", status)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Get(ctx context.Context, id string) (*Grant, error) {
+	res := &Grant{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM access_grants WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Save(ctx context.Context, g *Grant) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO access_grants (
+			id, username, user_group, client_id, client_group_id, permissions, starts_at, expires_at, reason, status,
+			created_at, created_by, revoked_at, revoked_by
+		) VALUES (
+			:id, :username, :user_group, :client_id, :client_group_id, :permissions, :starts_at, :expires_at, :reason, :status,
+			:created_at, :created_by, :revoked_at, :revoked_by
+		)`,
+		g,
+	)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
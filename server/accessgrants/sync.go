@@ -0,0 +1,18 @@
+package accessgrants
+
+import "context"
+
+type SyncTask struct {
+	manager *Manager
+}
+
+// NewSyncTask returns a task to load the access grants created, revoked or expired by other servers sharing the database
+func NewSyncTask(manager *Manager) *SyncTask {
+	return &SyncTask{
+		manager: manager,
+	}
+}
+
+func (t *SyncTask) Run(ctx context.Context) error {
+	return t.manager.Sync(ctx)
+}
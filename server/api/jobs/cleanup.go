@@ -2,12 +2,14 @@ package jobs
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 type CleanupProvider interface {
 	CleanupJobsMultiJobs(context.Context, int) error
+	ExpirePendingJobs(context.Context, time.Time) error
 }
 
 type CleanupTask struct {
@@ -23,6 +25,9 @@ func NewCleanupTask(provider CleanupProvider, maxJobs int) *CleanupTask {
 }
 
 func (t *CleanupTask) Run(ctx context.Context) error {
+	if err := t.provider.ExpirePendingJobs(ctx, time.Now()); err != nil {
+		return errors.Wrap(err, "expiring pending jobs")
+	}
 	return t.provider.CleanupJobsMultiJobs(ctx, t.maxJobs)
 }
 
@@ -225,6 +225,7 @@ type JobDetails struct {
 	Error       string            `json:"error"`
 	Result      *models.JobResult `json:"result"`
 	ClientName  string            `json:"client_name"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
 }
 
 func (d *JobDetails) Scan(value interface{}) error {
@@ -281,6 +282,7 @@ func (j *jobSqlite) convert() *models.Job {
 		res.Cwd = j.Details.Cwd
 		res.IsSudo = j.Details.IsSudo
 		res.IsScript = j.Details.IsScript
+		res.ExpiresAt = j.Details.ExpiresAt
 	}
 	if j.FinishedAt.Valid {
 		res.FinishedAt = &j.FinishedAt.Time
@@ -309,6 +311,7 @@ func convertToSqlite(job *models.Job) *jobSqlite {
 			Cwd:         job.Cwd,
 			IsSudo:      job.IsSudo,
 			IsScript:    job.IsScript,
+			ExpiresAt:   job.ExpiresAt,
 		},
 	}
 	if job.MultiJobID != nil {
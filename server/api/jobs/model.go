@@ -5,18 +5,24 @@ import (
 	"github.com/riportdev/riport/share/models"
 )
 
+// DefaultDeliverExpirySec is used when a job should be delivered on reconnect but no expiry is given
+const DefaultDeliverExpirySec = 24 * 60 * 60
+
 type MultiJobRequest struct {
-	ClientIDs           []string              `json:"client_ids"`
-	GroupIDs            []string              `json:"group_ids"`
-	ClientTags          *models.JobClientTags `json:"tags"`
-	Command             string                `json:"command"`
-	Script              string                `json:"script"`
-	Cwd                 string                `json:"cwd"`
-	IsSudo              bool                  `json:"is_sudo"`
-	Interpreter         string                `json:"interpreter"`
-	TimeoutSec          int                   `json:"timeout_sec"`
-	ExecuteConcurrently bool                  `json:"execute_concurrently"`
-	AbortOnError        *bool                 `json:"abort_on_error"` // pointer is used because it's default value is true. Otherwise it would be more difficult to check whether this field is missing or not
+	ClientIDs           []string                 `json:"client_ids"`
+	GroupIDs            []string                 `json:"group_ids"`
+	ClientTags          *models.JobClientTags    `json:"tags"`
+	Command             string                   `json:"command"`
+	Script              string                   `json:"script"`
+	Cwd                 string                   `json:"cwd"`
+	IsSudo              bool                     `json:"is_sudo"`
+	Interpreter         string                   `json:"interpreter"`
+	TimeoutSec          int                      `json:"timeout_sec"`
+	ExecuteConcurrently bool                     `json:"execute_concurrently"`
+	AbortOnError        *bool                    `json:"abort_on_error"` // pointer is used because it's default value is true. Otherwise it would be more difficult to check whether this field is missing or not
+	Strategy            *models.MultiJobStrategy `json:"strategy"`
+	DeliverOnReconnect  bool                     `json:"deliver_on_reconnect"`
+	DeliverExpirySec    int                      `json:"deliver_expiry_sec"`
 
 	Username       string               `json:"-"`
 	IsScript       bool                 `json:"-"`
@@ -117,6 +117,41 @@ INSERT OR REPLACE INTO multi_jobs (
 	return err
 }
 
+// InterruptMultiJobs marks multi-client jobs with an execution strategy that were left unfinished by a previous run
+// of the server on a given node as interrupted. Their execution state is lost, so they can't be continued.
+func (p *SqliteProvider) InterruptMultiJobs(ctx context.Context, nodeID string) (int, error) {
+	var res []*multiJobSqlite
+	err := p.db.SelectContext(ctx, &res, `SELECT * FROM multi_jobs WHERE details LIKE '%"strategy":%'`)
+	if err != nil {
+		return 0, err
+	}
+
+	interrupted := 0
+	for _, cur := range res {
+		job := cur.convert()
+		if job.NodeID != nodeID {
+			continue
+		}
+		switch job.Status {
+		case models.MultiJobStatusRunning, models.MultiJobStatusPaused, models.MultiJobStatusWaitingApproval:
+		default:
+			continue
+		}
+
+		job.Status = models.MultiJobStatusInterrupted
+		for _, batch := range job.Batches {
+			if batch.Status == models.BatchStatusRunning {
+				batch.Status = models.BatchStatusFailed
+			}
+		}
+		if err := p.SaveMultiJob(job); err != nil {
+			return interrupted, err
+		}
+		interrupted++
+	}
+	return interrupted, nil
+}
+
 type multiJobSqlite struct {
 	multiJobSummarySqlite
 	Details *multiJobDetailSqlite `db:"details"`
@@ -140,6 +175,15 @@ type multiJobDetailSqlite struct {
 	TimeoutSec  int                   `json:"timeout_sec"`
 	Concurrent  bool                  `json:"concurrent"`
 	AbortOnErr  bool                  `json:"abort_on_err"`
+
+	DeliverOnReconnect bool `json:"deliver_on_reconnect,omitempty"`
+	DeliverExpirySec   int  `json:"deliver_expiry_sec,omitempty"`
+
+	Strategy           *models.MultiJobStrategy `json:"strategy,omitempty"`
+	Status             string                   `json:"status,omitempty"`
+	Batches            []*models.MultiJobBatch  `json:"batches,omitempty"`
+	RemainingClientIDs []string                 `json:"remaining_client_ids,omitempty"`
+	NodeID             string                   `json:"node_id,omitempty"`
 }
 
 func (d *multiJobDetailSqlite) Scan(value interface{}) error {
@@ -189,17 +233,24 @@ func (j *multiJobSqlite) convert() *models.MultiJob {
 	js := j.multiJobSummarySqlite.convert()
 	d := j.Details
 	return &models.MultiJob{
-		MultiJobSummary: *js,
-		ClientIDs:       d.ClientIDs,
-		GroupIDs:        d.GroupIDs,
-		ClientTags:      d.ClientTags,
-		Command:         d.Command,
-		Cwd:             d.Cwd,
-		IsSudo:          d.IsSudo,
-		Interpreter:     d.Interpreter,
-		TimeoutSec:      d.TimeoutSec,
-		Concurrent:      d.Concurrent,
-		AbortOnErr:      d.AbortOnErr,
+		MultiJobSummary:    *js,
+		ClientIDs:          d.ClientIDs,
+		GroupIDs:           d.GroupIDs,
+		ClientTags:         d.ClientTags,
+		Command:            d.Command,
+		Cwd:                d.Cwd,
+		IsSudo:             d.IsSudo,
+		Interpreter:        d.Interpreter,
+		TimeoutSec:         d.TimeoutSec,
+		Concurrent:         d.Concurrent,
+		AbortOnErr:         d.AbortOnErr,
+		DeliverOnReconnect: d.DeliverOnReconnect,
+		DeliverExpirySec:   d.DeliverExpirySec,
+		Strategy:           d.Strategy,
+		Status:             d.Status,
+		Batches:            d.Batches,
+		RemainingClientIDs: d.RemainingClientIDs,
+		NodeID:             d.NodeID,
 	}
 }
 
@@ -212,16 +263,23 @@ func convertMultiJobToSqlite(job *models.MultiJob) *multiJobSqlite {
 			ScheduleID: job.ScheduleID,
 		},
 		Details: &multiJobDetailSqlite{
-			ClientIDs:   job.ClientIDs,
-			GroupIDs:    job.GroupIDs,
-			ClientTags:  job.ClientTags,
-			Command:     job.Command,
-			Interpreter: job.Interpreter,
-			Cwd:         job.Cwd,
-			IsSudo:      job.IsSudo,
-			TimeoutSec:  job.TimeoutSec,
-			Concurrent:  job.Concurrent,
-			AbortOnErr:  job.AbortOnErr,
+			ClientIDs:          job.ClientIDs,
+			GroupIDs:           job.GroupIDs,
+			ClientTags:         job.ClientTags,
+			Command:            job.Command,
+			Interpreter:        job.Interpreter,
+			Cwd:                job.Cwd,
+			IsSudo:             job.IsSudo,
+			TimeoutSec:         job.TimeoutSec,
+			Concurrent:         job.Concurrent,
+			AbortOnErr:         job.AbortOnErr,
+			DeliverOnReconnect: job.DeliverOnReconnect,
+			DeliverExpirySec:   job.DeliverExpirySec,
+			Strategy:           job.Strategy,
+			Status:             job.Status,
+			Batches:            job.Batches,
+			RemainingClientIDs: job.RemainingClientIDs,
+			NodeID:             job.NodeID,
 		},
 	}
 }
@@ -90,3 +90,50 @@ func TestMultiJobsSqliteProvider(t *testing.T) {
 	require.NoError(t, err)
 	assert.EqualValues(t, []*models.MultiJobSummary{&job1.MultiJobSummary, &job2.MultiJobSummary, &job3.MultiJobSummary}, gotJSs)
 }
+
+func TestInterruptMultiJobs(t *testing.T) {
+	ctx := context.Background()
+	jobsDB, err := sqlite.New(":memory:", jobs.AssetNames(), jobs.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(jobsDB, testLog)
+	defer p.Close()
+
+	withStrategy := func(jid, status, nodeID string) *models.MultiJob {
+		job := jb.NewMulti(t).JID(jid).Build()
+		job.Strategy = &models.MultiJobStrategy{BatchSize: 1}
+		job.Status = status
+		job.NodeID = nodeID
+		job.Batches = []*models.MultiJobBatch{
+			{Index: 0, ClientIDs: []string{"client-1"}, Status: models.BatchStatusSuccessful},
+			{Index: 1, ClientIDs: []string{"client-2"}, Status: models.BatchStatusRunning},
+			{Index: 2, ClientIDs: []string{"client-3"}, Status: models.BatchStatusPending},
+		}
+		require.NoError(t, p.SaveMultiJob(job))
+		return job
+	}
+	running := withStrategy("1", models.MultiJobStatusRunning, "")
+	paused := withStrategy("2", models.MultiJobStatusPaused, "")
+	waiting := withStrategy("3", models.MultiJobStatusWaitingApproval, "")
+	finished := withStrategy("4", models.MultiJobStatusFinished, "")
+	otherNode := withStrategy("5", models.MultiJobStatusRunning, "node-2")
+	noStrategy := jb.NewMulti(t).JID("6").Build()
+	require.NoError(t, p.SaveMultiJob(noStrategy))
+
+	interrupted, err := p.InterruptMultiJobs(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, interrupted)
+
+	for _, job := range []*models.MultiJob{running, paused, waiting} {
+		got, err := p.GetMultiJob(ctx, job.JID)
+		require.NoError(t, err)
+		assert.Equal(t, models.MultiJobStatusInterrupted, got.Status)
+		assert.Equal(t, models.BatchStatusSuccessful, got.Batches[0].Status)
+		assert.Equal(t, models.BatchStatusFailed, got.Batches[1].Status)
+		assert.Equal(t, models.BatchStatusPending, got.Batches[2].Status)
+	}
+	for _, job := range []*models.MultiJob{finished, otherNode, noStrategy} {
+		got, err := p.GetMultiJob(ctx, job.JID)
+		require.NoError(t, err)
+		assert.Equal(t, job.Status, got.Status)
+	}
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+const ErrMsgPendingJobExpired = "job expired before the client reconnected"
+
+// ListPendingJobs returns jobs waiting for a given client to reconnect in order they were created.
+// If client ID is empty, pending jobs of all clients are returned.
+func (p *SqliteProvider) ListPendingJobs(ctx context.Context, clientID string) ([]*models.Job, error) {
+	options := &query.ListOptions{
+		Filters: []query.FilterOption{
+			{Column: []string{"status"}, Values: []string{models.JobStatusPending}},
+		},
+		Sorts: []query.SortOption{
+			{Column: "started_at", IsASC: true},
+		},
+	}
+	if clientID != "" {
+		options.Filters = append(options.Filters, query.FilterOption{Column: []string{"client_id"}, Values: []string{clientID}})
+	}
+	return p.List(ctx, options)
+}
+
+// ExpirePendingJobs marks pending jobs that were not delivered to their clients in time as failed.
+func (p *SqliteProvider) ExpirePendingJobs(ctx context.Context, now time.Time) error {
+	pending, err := p.ListPendingJobs(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, job := range pending {
+		if !IsPendingJobExpired(job, now) {
+			continue
+		}
+		ExpirePendingJob(job, now)
+		if err := p.SaveJob(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func IsPendingJobExpired(job *models.Job, now time.Time) bool {
+	return job.ExpiresAt != nil && now.After(*job.ExpiresAt)
+}
+
+func ExpirePendingJob(job *models.Job, now time.Time) {
+	job.Status = models.JobStatusFailed
+	job.FinishedAt = &now
+	job.Error = ErrMsgPendingJobExpired
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/db/migration/jobs"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/test/jb"
+	"github.com/riportdev/riport/share/models"
+)
+
+func TestPendingJobs(t *testing.T) {
+	ctx := context.Background()
+	jobsDB, err := sqlite.New(":memory:", jobs.AssetNames(), jobs.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(jobsDB, testLog)
+	defer p.Close()
+
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	j1 := jb.New(t).ClientID("client-1").Status(models.JobStatusPending).StartedAt(now.Add(-time.Hour)).ExpiresAt(now.Add(time.Hour)).Result(nil).Build()
+	j2 := jb.New(t).ClientID("client-1").Status(models.JobStatusPending).StartedAt(now.Add(-2 * time.Hour)).ExpiresAt(now.Add(-time.Minute)).Result(nil).Build()
+	j3 := jb.New(t).ClientID("client-2").Status(models.JobStatusPending).StartedAt(now.Add(-time.Hour)).ExpiresAt(now.Add(time.Hour)).Result(nil).Build()
+	j4 := jb.New(t).ClientID("client-1").StartedAt(now.Add(-time.Hour)).Build()
+	for _, j := range []*models.Job{j1, j2, j3, j4} {
+		require.NoError(t, p.SaveJob(j))
+	}
+
+	gotJobs, err := p.ListPendingJobs(ctx, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Job{j2, j1}, gotJobs)
+
+	err = p.ExpirePendingJobs(ctx, now)
+	require.NoError(t, err)
+
+	gotJobs, err = p.ListPendingJobs(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*models.Job{j1, j3}, gotJobs)
+
+	gotJob, err := p.GetByJID(j2.ClientID, j2.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, gotJob.Status)
+	assert.Equal(t, ErrMsgPendingJobExpired, gotJob.Error)
+	require.NotNil(t, gotJob.FinishedAt)
+	assert.True(t, now.Equal(*gotJob.FinishedAt))
+}
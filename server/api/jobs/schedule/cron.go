@@ -12,6 +12,7 @@ type CronImplementation struct {
 	cronParser cron.ScheduleParser
 	cron       *cron.Cron
 	mapping    map[string]cron.EntryID
+	schedules  map[string]string
 }
 
 func newCron() *CronImplementation {
@@ -19,6 +20,7 @@ func newCron() *CronImplementation {
 		cronParser: cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
 		cron:       cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
 		mapping:    make(map[string]cron.EntryID),
+		schedules:  make(map[string]string),
 	}
 	c.cron.Start()
 	return c
@@ -43,6 +45,7 @@ func (c *CronImplementation) Add(id string, schedule string, f func(context.Cont
 	}))
 
 	c.mapping[id] = entryID
+	c.schedules[id] = schedule
 
 	return nil
 }
@@ -54,4 +57,17 @@ func (c *CronImplementation) Remove(id string) {
 	entryID := c.mapping[id]
 	c.cron.Remove(entryID)
 	delete(c.mapping, id)
+	delete(c.schedules, id)
+}
+
+// Schedules returns the cron expressions of all added schedules by id
+func (c *CronImplementation) Schedules() map[string]string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	res := make(map[string]string, len(c.schedules))
+	for id, schedule := range c.schedules {
+		res[id] = schedule
+	}
+	return res
 }
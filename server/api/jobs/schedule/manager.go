@@ -55,6 +55,12 @@ type Cron interface {
 	Validate(string) error
 	Add(string, string, func(context.Context, string)) error
 	Remove(string)
+	Schedules() map[string]string
+}
+
+// Leader decides whether this server runs the schedules when several servers share the database
+type Leader interface {
+	IsLeader() bool
 }
 
 type JobRunner interface {
@@ -66,6 +72,7 @@ type Manager struct {
 	jobRunner JobRunner
 	provider  Provider
 	cron      Cron
+	leader    Leader
 
 	runRemoteCmdTimeoutSec int
 }
@@ -100,6 +107,41 @@ func NewManager(jobRunner JobRunner, db *sqlx.DB, logger *logger.Logger, runRemo
 	return m
 }
 
+// SetLeader makes the schedules run only while the leader is this server
+func (m *Manager) SetLeader(leader Leader) {
+	m.leader = leader
+}
+
+// Sync updates the schedules to run with the schedules changed in the database by other servers
+func (m *Manager) Sync(ctx context.Context) error {
+	existing, err := m.provider.List(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	added := m.cron.Schedules()
+	for _, s := range existing {
+		schedule, ok := added[s.ID]
+		delete(added, s.ID)
+		if ok && schedule == s.Schedule {
+			continue
+		}
+
+		m.cron.Remove(s.ID)
+		err := m.addCron(s)
+		if err != nil {
+			m.Errorf("Could not add schedule %s: %v", s.ID, err)
+		}
+	}
+
+	// the remaining schedules were deleted
+	for id := range added {
+		m.cron.Remove(id)
+	}
+
+	return nil
+}
+
 func (m *Manager) List(ctx context.Context, r *http.Request) (*api.SuccessPayload, error) {
 	listOptions := query.GetListOptions(r)
 
@@ -238,6 +280,14 @@ func (m *Manager) validate(s *Schedule) error {
 		}
 	}
 
+	if s.Details.DeliverExpirySec < 0 {
+		return &errors.APIError{
+			Message:    "Invalid delivery expiry.",
+			Err:        fmt.Errorf("deliver_expiry_sec cannot be negative"),
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+
 	switch s.Type {
 	case TypeCommand:
 		if s.Details.Command == "" {
@@ -273,6 +323,11 @@ func (m *Manager) addCron(s *Schedule) error {
 }
 
 func (m *Manager) run(ctx context.Context, id string) {
+	if m.leader != nil && !m.leader.IsLeader() {
+		m.Debugf("Skipping schedule %s, it's run by the cluster leader.", id)
+		return
+	}
+
 	schedule, err := m.provider.Get(ctx, id)
 	if err != nil {
 		m.Errorf("Could not get schedule %s: %v", id, err)
@@ -315,6 +370,8 @@ func (m *Manager) run(ctx context.Context, id string) {
 		TimeoutSec:          schedule.Details.TimeoutSec,
 		ExecuteConcurrently: schedule.Details.ExecuteConcurrently,
 		AbortOnError:        schedule.Details.AbortOnError,
+		DeliverOnReconnect:  schedule.Details.DeliverOnReconnect,
+		DeliverExpirySec:    schedule.Details.DeliverExpirySec,
 		IsScript:            schedule.Type == TypeScript,
 	})
 	if err != nil {
@@ -1,11 +1,37 @@
 package schedule
 
 import (
+	"context"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jobsmigration "github.com/riportdev/riport/db/migration/jobs"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
 )
 
+var testLog = logger.NewLogger("schedule-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+
+type jobRunnerMock struct {
+	requests []*jobs.MultiJobRequest
+}
+
+func (r *jobRunnerMock) StartMultiClientJob(ctx context.Context, multiJobRequest *jobs.MultiJobRequest) (*models.MultiJob, error) {
+	r.requests = append(r.requests, multiJobRequest)
+	return &models.MultiJob{}, nil
+}
+
+type leaderMock bool
+
+func (l leaderMock) IsLeader() bool {
+	return bool(l)
+}
+
 func TestValidate(t *testing.T) {
 	manager := &Manager{
 		cron: newCron(),
@@ -120,3 +146,55 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", jobsmigration.AssetNames(), jobsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	defer db.Close()
+	err = addTestData(db)
+	require.NoError(t, err)
+
+	manager, err := New(ctx, testLog, db, &jobRunnerMock{}, 60)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "* * * * *", "2": "*/5 * * * *"}, manager.cron.Schedules())
+
+	// another server sharing the database changes the schedules
+	other := NewManager(&jobRunnerMock{}, db, testLog, 60)
+	require.NoError(t, other.Delete(ctx, "1"))
+	s2, err := other.Get(ctx, "2")
+	require.NoError(t, err)
+	s2.Schedule = "0 * * * *"
+	_, err = other.Update(ctx, "2", s2)
+	require.NoError(t, err)
+	s3, err := other.Create(ctx, &Schedule{
+		Base:    Base{Name: "schedule 3", Schedule: "*/10 * * * *", Type: TypeCommand},
+		Details: Details{ClientIDs: []string{"c3"}, Command: "/bin/true"},
+	}, "user1")
+	require.NoError(t, err)
+
+	err = NewSyncTask(manager).Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"2": "0 * * * *", s3.ID: "*/10 * * * *"}, manager.cron.Schedules())
+}
+
+func TestRunOnLeaderOnly(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", jobsmigration.AssetNames(), jobsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	defer db.Close()
+	err = addTestData(db)
+	require.NoError(t, err)
+
+	jobRunner := &jobRunnerMock{}
+	manager := NewManager(jobRunner, db, testLog, 60)
+	manager.SetLeader(leaderMock(false))
+
+	manager.run(ctx, "1")
+	assert.Len(t, jobRunner.requests, 0)
+
+	manager.SetLeader(leaderMock(true))
+	manager.run(ctx, "1")
+	require.Len(t, jobRunner.requests, 1)
+	assert.Equal(t, []string{"c1"}, jobRunner.requests[0].ClientIDs)
+}
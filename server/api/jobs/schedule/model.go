@@ -78,6 +78,8 @@ type Details struct {
 	ExecuteConcurrently bool                  `json:"execute_concurrently" db:"-"`
 	AbortOnError        *bool                 `json:"abort_on_error" db:"-"`
 	Overlaps            bool                  `json:"overlaps" db:"-"`
+	DeliverOnReconnect  bool                  `json:"deliver_on_reconnect,omitempty" db:"-"`
+	DeliverExpirySec    int                   `json:"deliver_expiry_sec,omitempty" db:"-"`
 }
 
 func (d *Details) Scan(value interface{}) error {
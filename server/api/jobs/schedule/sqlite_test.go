@@ -51,7 +51,7 @@ var testData = []*Schedule{
 		Details: Details{
 			ClientIDs:           []string{"c2"},
 			GroupIDs:            []string{"g2"},
-			Command:             "echo 'test'",
+			Script:              "ZWNobyAndGVzdCc=", // echo 'test'
 			Interpreter:         "/bin/sh",
 			Cwd:                 "/home/riport",
 			TimeoutSec:          3,
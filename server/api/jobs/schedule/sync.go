@@ -0,0 +1,18 @@
+package schedule
+
+import "context"
+
+type SyncTask struct {
+	manager *Manager
+}
+
+// NewSyncTask returns a task to run the schedules created, changed or deleted by other servers sharing the database
+func NewSyncTask(manager *Manager) *SyncTask {
+	return &SyncTask{
+		manager: manager,
+	}
+}
+
+func (t *SyncTask) Run(ctx context.Context) error {
+	return t.manager.Sync(ctx)
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"errors"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+// ValidateStrategy checks the given multi-client job execution strategy. Nil strategy is valid.
+func ValidateStrategy(s *models.MultiJobStrategy) error {
+	if s == nil {
+		return nil
+	}
+	if s.BatchSize < 0 {
+		return errors.New("batch_size cannot be negative")
+	}
+	if s.MaxFailureRate != nil && (*s.MaxFailureRate < 0 || *s.MaxFailureRate > 100) {
+		return errors.New("max_failure_rate should be between 0 and 100")
+	}
+	if s.CanarySize < 0 {
+		return errors.New("canary_size cannot be negative")
+	}
+	if s.CanaryWaitSec < 0 {
+		return errors.New("canary_wait_sec cannot be negative")
+	}
+	if s.CanarySize == 0 && s.CanaryWaitSec > 0 {
+		return errors.New("canary_wait_sec requires canary_size to be set")
+	}
+	return nil
+}
+
+// NewBatches splits given clients into batches according to the strategy. A canary batch if any goes first.
+func NewBatches(clientIDs []string, s *models.MultiJobStrategy) []*models.MultiJobBatch {
+	var batches []*models.MultiJobBatch
+	add := func(ids []string, canary bool) {
+		batches = append(batches, &models.MultiJobBatch{
+			Index:     len(batches),
+			Canary:    canary,
+			ClientIDs: ids,
+			Status:    models.BatchStatusPending,
+		})
+	}
+
+	rest := clientIDs
+	if s.CanarySize > 0 {
+		n := s.CanarySize
+		if n > len(rest) {
+			n = len(rest)
+		}
+		add(rest[:n], true)
+		rest = rest[n:]
+	}
+
+	size := s.BatchSize
+	if size <= 0 {
+		size = len(rest)
+	}
+	for len(rest) > 0 {
+		n := size
+		if n > len(rest) {
+			n = len(rest)
+		}
+		add(rest[:n], false)
+		rest = rest[n:]
+	}
+
+	return batches
+}
+
+// CanProceed returns true if the execution can continue after the given finished batch.
+// A canary batch is not allowed to have any failures unless max failure rate is set.
+func CanProceed(batch *models.MultiJobBatch, s *models.MultiJobStrategy) bool {
+	if s.MaxFailureRate != nil {
+		return batch.FailureRate() <= *s.MaxFailureRate
+	}
+	if batch.Canary {
+		return batch.Failed == 0
+	}
+	return true
+}
+
+// RemainingClientIDs returns IDs of clients from batches that are not started yet.
+func RemainingClientIDs(batches []*models.MultiJobBatch) []string {
+	var res []string
+	for _, b := range batches {
+		if b.Status == models.BatchStatusPending {
+			res = append(res, b.ClientIDs...)
+		}
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+func TestValidateStrategy(t *testing.T) {
+	negativeRate := -1.0
+	tooHighRate := 101.0
+	validRate := 20.0
+	testCases := []struct {
+		Name     string
+		Strategy *models.MultiJobStrategy
+		ExpError string
+	}{
+		{
+			Name: "nil",
+		},
+		{
+			Name:     "valid",
+			Strategy: &models.MultiJobStrategy{BatchSize: 5, MaxFailureRate: &validRate, CanarySize: 1, CanaryWaitSec: 60},
+		},
+		{
+			Name:     "negative batch size",
+			Strategy: &models.MultiJobStrategy{BatchSize: -1},
+			ExpError: "batch_size cannot be negative",
+		},
+		{
+			Name:     "negative failure rate",
+			Strategy: &models.MultiJobStrategy{MaxFailureRate: &negativeRate},
+			ExpError: "max_failure_rate should be between 0 and 100",
+		},
+		{
+			Name:     "too high failure rate",
+			Strategy: &models.MultiJobStrategy{MaxFailureRate: &tooHighRate},
+			ExpError: "max_failure_rate should be between 0 and 100",
+		},
+		{
+			Name:     "canary wait without canary",
+			Strategy: &models.MultiJobStrategy{CanaryWaitSec: 10},
+			ExpError: "canary_wait_sec requires canary_size to be set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := ValidateStrategy(tc.Strategy)
+			if tc.ExpError != "" {
+				require.EqualError(t, err, tc.ExpError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewBatches(t *testing.T) {
+	clientIDs := []string{"c1", "c2", "c3", "c4", "c5"}
+	testCases := []struct {
+		Name       string
+		Strategy   *models.MultiJobStrategy
+		ExpBatches [][]string
+		ExpCanary  bool
+	}{
+		{
+			Name:       "all in one batch",
+			Strategy:   &models.MultiJobStrategy{},
+			ExpBatches: [][]string{{"c1", "c2", "c3", "c4", "c5"}},
+		},
+		{
+			Name:       "batch size",
+			Strategy:   &models.MultiJobStrategy{BatchSize: 2},
+			ExpBatches: [][]string{{"c1", "c2"}, {"c3", "c4"}, {"c5"}},
+		},
+		{
+			Name:       "canary and rest",
+			Strategy:   &models.MultiJobStrategy{CanarySize: 1},
+			ExpBatches: [][]string{{"c1"}, {"c2", "c3", "c4", "c5"}},
+			ExpCanary:  true,
+		},
+		{
+			Name:       "canary and batches",
+			Strategy:   &models.MultiJobStrategy{CanarySize: 2, BatchSize: 2},
+			ExpBatches: [][]string{{"c1", "c2"}, {"c3", "c4"}, {"c5"}},
+			ExpCanary:  true,
+		},
+		{
+			Name:       "canary bigger than clients",
+			Strategy:   &models.MultiJobStrategy{CanarySize: 10},
+			ExpBatches: [][]string{{"c1", "c2", "c3", "c4", "c5"}},
+			ExpCanary:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			batches := NewBatches(clientIDs, tc.Strategy)
+
+			require.Len(t, batches, len(tc.ExpBatches))
+			for i, b := range batches {
+				assert.Equal(t, i, b.Index)
+				assert.Equal(t, tc.ExpBatches[i], b.ClientIDs)
+				assert.Equal(t, tc.ExpCanary && i == 0, b.Canary)
+				assert.Equal(t, models.BatchStatusPending, b.Status)
+			}
+			assert.Equal(t, clientIDs, RemainingClientIDs(batches))
+		})
+	}
+}
+
+func TestCanProceed(t *testing.T) {
+	maxRate := 25.0
+	testCases := []struct {
+		Name     string
+		Batch    *models.MultiJobBatch
+		Strategy *models.MultiJobStrategy
+		Expected bool
+	}{
+		{
+			Name:     "no max failure rate",
+			Batch:    &models.MultiJobBatch{ClientIDs: []string{"c1", "c2"}, Failed: 2},
+			Strategy: &models.MultiJobStrategy{},
+			Expected: true,
+		},
+		{
+			Name:     "failed canary",
+			Batch:    &models.MultiJobBatch{ClientIDs: []string{"c1", "c2"}, Failed: 1, Canary: true},
+			Strategy: &models.MultiJobStrategy{},
+			Expected: false,
+		},
+		{
+			Name:     "failure rate under threshold",
+			Batch:    &models.MultiJobBatch{ClientIDs: []string{"c1", "c2", "c3", "c4"}, Failed: 1, Successful: 3},
+			Strategy: &models.MultiJobStrategy{MaxFailureRate: &maxRate},
+			Expected: true,
+		},
+		{
+			Name:     "failure rate over threshold",
+			Batch:    &models.MultiJobBatch{ClientIDs: []string{"c1", "c2", "c3"}, Failed: 1, Successful: 2},
+			Strategy: &models.MultiJobStrategy{MaxFailureRate: &maxRate},
+			Expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, CanProceed(tc.Batch, tc.Strategy))
+		})
+	}
+}
@@ -23,6 +23,7 @@ type UserDatabase struct {
 
 	twoFAOn     bool
 	totPOn      bool
+	webAuthnOn  bool
 	plusEnabled bool
 	logger      *logger.Logger
 }
@@ -30,7 +31,7 @@ type UserDatabase struct {
 func NewUserDatabase(
 	DB *sqlx.DB,
 	usersTableName, groupsTableName, groupDetailsTableName string,
-	twoFAOn, totPOn bool,
+	twoFAOn, totPOn, webAuthnOn bool,
 	plusEnabled bool, logger *logger.Logger,
 ) (*UserDatabase, error) {
 	d := &UserDatabase{
@@ -42,6 +43,7 @@ func NewUserDatabase(
 
 		twoFAOn:     twoFAOn,
 		totPOn:      totPOn,
+		webAuthnOn:  webAuthnOn,
 		plusEnabled: plusEnabled,
 		logger:      logger,
 	}
@@ -59,6 +61,9 @@ func (d *UserDatabase) getSelectClause() string {
 	if d.totPOn {
 		s += ", totp_secret"
 	}
+	if d.webAuthnOn {
+		s += ", webauthn_credentials"
+	}
 	return s
 }
 
@@ -291,6 +296,11 @@ func (d *UserDatabase) Add(usr *User) error {
 		params = append(params, usr.TotP)
 	}
 
+	if d.webAuthnOn {
+		columns = append(columns, "`webauthn_credentials`")
+		params = append(params, usr.WebAuthnCredentials)
+	}
+
 	_, err = tx.Exec(
 		fmt.Sprintf(
 			"INSERT INTO `%s` (%s) VALUES (%s)",
@@ -354,6 +364,11 @@ func (d *UserDatabase) Update(usr *User, usernameToUpdate string) error {
 		params = append(params, usr.TotP)
 	}
 
+	if usr.WebAuthnCredentials != nil {
+		statements = append(statements, "`webauthn_credentials` = ?")
+		params = append(params, usr.WebAuthnCredentials)
+	}
+
 	if usr.Username != "" && usr.Username != usernameToUpdate {
 		statements = append(statements, "`username` = ?")
 		params = append(params, usr.Username)
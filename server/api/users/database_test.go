@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	extperm "github.com/riportdev/riport/plus/capabilities/extendedpermission"
+	"github.com/riportdev/riport/server/webauthn"
 	chshare "github.com/riportdev/riport/share/logger"
 
 	"github.com/riportdev/riport/share/test"
@@ -111,7 +112,7 @@ func TestNewUserDatabase(t *testing.T) {
 			_, err = db.Exec("CREATE TABLE `invalid_group_details` (name TEXT, other TEXT)")
 			require.NoError(t, err)
 
-			_, err = NewUserDatabase(db, tc.UsersTable, tc.GroupsTable, tc.GroupDetailsTable, tc.twoFAOn, tc.totPOn, false, testLog)
+			_, err = NewUserDatabase(db, tc.UsersTable, tc.GroupsTable, tc.GroupDetailsTable, tc.twoFAOn, tc.totPOn, false, false, testLog)
 			if tc.ExpectedError == "" {
 				require.NoError(t, err)
 			} else {
@@ -133,7 +134,7 @@ func TestGetByUsername(t *testing.T) {
 	err = prepareDummyData(db, true, false, false)
 	require.NoError(t, err)
 
-	d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, testLog)
+	d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, false, testLog)
 	require.NoError(t, err)
 
 	testCases := []struct {
@@ -196,7 +197,7 @@ func TestGetAll(t *testing.T) {
 	err = prepareDummyData(db, false, true, false)
 	require.NoError(t, err)
 
-	d, err := NewUserDatabase(db, "users", "groups", "", false, true, false, testLog)
+	d, err := NewUserDatabase(db, "users", "groups", "", false, true, false, false, testLog)
 	require.NoError(t, err)
 
 	actualUsers, err := d.GetAll()
@@ -268,7 +269,7 @@ func TestListGroups(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, false, testLog)
 			require.NoError(t, err)
 
 			actualGroups, err := d.ListGroups()
@@ -324,7 +325,7 @@ func TestListGroupsPlusEnabled(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, true, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, true, testLog)
 			require.NoError(t, err)
 
 			actualGroups, err := d.ListGroups()
@@ -374,7 +375,7 @@ func TestGetGroup(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, false, testLog)
 			require.NoError(t, err)
 
 			actual, err := d.GetGroup(tc.Group)
@@ -444,7 +445,7 @@ func TestGetGroupPlusEnabled(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, true, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", tc.DetailsTable, false, false, false, true, testLog)
 			require.NoError(t, err)
 
 			actual, err := d.GetGroup(tc.Group)
@@ -482,7 +483,7 @@ func TestUpdateGroup(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, false, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, false, false, testLog)
 			require.NoError(t, err)
 
 			err = d.UpdateGroup(tc.Group.Name, tc.Group)
@@ -589,7 +590,7 @@ func TestUpdateGroupPlusEnabled(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, true, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, false, true, testLog)
 			require.NoError(t, err)
 
 			err = d.UpdateGroup(tc.Group.Name, tc.Group)
@@ -617,7 +618,7 @@ func TestDeleteGroup(t *testing.T) {
 	err = prepareDummyData(db, false, false, false)
 	require.NoError(t, err)
 
-	d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, false, testLog)
+	d, err := NewUserDatabase(db, "users", "groups", "group_details", false, false, false, false, testLog)
 	require.NoError(t, err)
 
 	err = d.DeleteGroup("group1")
@@ -681,7 +682,7 @@ func TestAdd(t *testing.T) {
 			err = prepareTables(db, false, false, false)
 			require.NoError(t, err)
 
-			d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, false, testLog)
 			require.NoError(t, err)
 
 			err = d.Add(testCase.userToChange)
@@ -865,7 +866,7 @@ func TestUpdate(t *testing.T) {
 			err = prepareDummyData(db, false, false, false)
 			require.NoError(t, err)
 
-			d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, testLog)
+			d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, false, testLog)
 			require.NoError(t, err)
 
 			testCase := testCases[i]
@@ -879,6 +880,40 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestWebAuthnCredentials(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = prepareTables(db, false, false, false)
+	require.NoError(t, err)
+
+	_, err = NewUserDatabase(db, "users", "groups", "", false, false, true, false, testLog)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no such column: webauthn_credentials")
+
+	_, err = db.Exec("ALTER TABLE `users` ADD COLUMN webauthn_credentials TEXT NOT NULL DEFAULT ''")
+	require.NoError(t, err)
+
+	d, err := NewUserDatabase(db, "users", "groups", "", false, false, true, false, testLog)
+	require.NoError(t, err)
+
+	creds := webauthn.Credentials{{ID: "cred1", Name: "YubiKey", PublicKey: []byte{1, 2, 3}}}
+	err = d.Add(&User{Username: "user1", Password: "pass1", WebAuthnCredentials: creds})
+	require.NoError(t, err)
+
+	user, err := d.GetByUsername("user1")
+	require.NoError(t, err)
+	assert.Equal(t, creds, user.WebAuthnCredentials)
+
+	err = d.Update(&User{WebAuthnCredentials: webauthn.Credentials{}}, "user1")
+	require.NoError(t, err)
+
+	user, err = d.GetByUsername("user1")
+	require.NoError(t, err)
+	assert.Nil(t, user.WebAuthnCredentials)
+}
+
 func TestDelete(t *testing.T) {
 	db, err := sqlx.Connect("sqlite3", ":memory:")
 	require.NoError(t, err)
@@ -890,7 +925,7 @@ func TestDelete(t *testing.T) {
 	err = prepareDummyData(db, false, false, false)
 	require.NoError(t, err)
 
-	d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, testLog)
+	d, err := NewUserDatabase(db, "users", "groups", "", false, false, false, false, testLog)
 	require.NoError(t, err)
 
 	err = d.Delete("user1")
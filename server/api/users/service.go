@@ -271,7 +271,8 @@ func (as *APIService) validate(dataToChange *User, usernameToFind string) error
 			dataToChange.PasswordExpired == nil &&
 			dataToChange.Groups == nil &&
 			(!as.TwoFAOn || dataToChange.TwoFASendTo == "") &&
-			dataToChange.TotP == "" {
+			dataToChange.TotP == "" &&
+			dataToChange.WebAuthnCredentials == nil {
 			errs = append(errs, errors2.APIError{
 				Message:    "nothing to change",
 				HTTPStatus: http.StatusBadRequest,
@@ -417,6 +418,7 @@ func newAPIAuthDatabase(authDB *sqlx.DB, config *chconfig.Config, logger *logger
 		config.API.AuthGroupDetailsTable,
 		config.API.IsTwoFAOn(),
 		config.API.TotPEnabled,
+		config.API.WebAuthnEnabled,
 		rportplus.IsPlusEnabled(config.PlusConfig),
 		logger,
 	)
@@ -1,13 +1,16 @@
 package users
 
+import "github.com/riportdev/riport/server/webauthn"
+
 // User represents API user.
 type User struct {
-	Username        string   `json:"username" db:"username"`
-	Password        string   `json:"password" db:"password"`
-	PasswordExpired *bool    `json:"password_expired" db:"password_expired"`
-	Groups          []string `json:"groups" db:"-"`
-	TwoFASendTo     string   `json:"two_fa_send_to" db:"two_fa_send_to"`
-	TotP            string   `json:"totp_secret,omitempty" db:"totp_secret"`
+	Username            string               `json:"username" db:"username"`
+	Password            string               `json:"password" db:"password"`
+	PasswordExpired     *bool                `json:"password_expired" db:"password_expired"`
+	Groups              []string             `json:"groups" db:"-"`
+	TwoFASendTo         string               `json:"two_fa_send_to" db:"two_fa_send_to"`
+	TotP                string               `json:"totp_secret,omitempty" db:"totp_secret"`
+	WebAuthnCredentials webauthn.Credentials `json:"webauthn_credentials,omitempty" db:"webauthn_credentials"`
 }
 
 func (u User) GetGroups() []string {
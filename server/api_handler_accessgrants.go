@@ -0,0 +1,173 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/accessgrants"
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/query"
+	"github.com/riportdev/riport/share/random"
+)
+
+// handleListAccessGrants handles GET /access-grants
+// Non-admin users only see grants given to them.
+func (al *APIListener) handleListAccessGrants(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	options := query.NewOptions(req, accessgrants.ListDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, accessgrants.SupportedSorts, accessgrants.SupportedFilters, nil, &query.PaginationConfig{
+		MaxLimit:     100,
+		DefaultLimit: 20,
+	})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	pagination := options.Pagination
+	options.Pagination = nil
+	entries, err := al.accessGrants.List(ctx, options)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get access grants.", err)
+		return
+	}
+
+	visible := make([]*accessgrants.Grant, 0, len(entries))
+	for _, entry := range entries {
+		if canSeeAccessGrant(entry, curUser) {
+			visible = append(visible, entry)
+		}
+	}
+
+	totalCount := len(visible)
+	start, end := pagination.GetStartEnd(totalCount)
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: visible[start:end],
+		Meta: api.NewMeta(totalCount),
+	})
+}
+
+// handleGetAccessGrant handles GET /access-grants/{grant_id}
+func (al *APIListener) handleGetAccessGrant(w http.ResponseWriter, req *http.Request) {
+	grant, ok := al.getAccessGrantFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if !canSeeAccessGrant(grant, curUser) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Access denied.")
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(grant))
+}
+
+// handlePostAccessGrant handles POST /access-grants
+func (al *APIListener) handlePostAccessGrant(w http.ResponseWriter, req *http.Request) {
+	var grant accessgrants.Grant
+	err := parseRequestBody(req.Body, &grant)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	now := time.Now()
+	if grant.StartsAt.IsZero() {
+		grant.StartsAt = now
+	}
+	if err := grant.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid access grant.", err)
+		return
+	}
+	if !grant.ExpiresAt.After(now) {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Invalid access grant. expires_at must be in the future.")
+		return
+	}
+
+	grant.ID, err = random.UUID4()
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	grant.CreatedAt = now
+	grant.CreatedBy = api.GetUser(req.Context(), al.Logger)
+	grant.RevokedAt = nil
+	grant.RevokedBy = nil
+
+	if err := al.accessGrants.Create(req.Context(), &grant); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist a new access grant.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAccessGrant, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(grant).
+		WithID(grant.ID).
+		WithClientID(grant.ClientID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(grant))
+	al.Debugf("Access grant [id=%q] created.", grant.ID)
+}
+
+// handleDeleteAccessGrant handles DELETE /access-grants/{grant_id}
+// The grant is revoked and kept for the history.
+func (al *APIListener) handleDeleteAccessGrant(w http.ResponseWriter, req *http.Request) {
+	grant, ok := al.getAccessGrantFromRequest(w, req)
+	if !ok {
+		return
+	}
+	if grant.Status != accessgrants.StatusActive {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Access grant[id=%q] is already %s.", grant.ID, grant.Status))
+		return
+	}
+
+	if err := al.accessGrants.Revoke(req.Context(), grant, api.GetUser(req.Context(), al.Logger)); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke access grant[id=%q].", grant.ID), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAccessGrant, auditlog.ActionRevoke).
+		WithHTTPRequest(req).
+		WithID(grant.ID).
+		WithClientID(grant.ClientID).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Access grant [id=%q] revoked.", grant.ID)
+}
+
+func (al *APIListener) getAccessGrantFromRequest(w http.ResponseWriter, req *http.Request) (*accessgrants.Grant, bool) {
+	id := mux.Vars(req)[routes.ParamGrantID]
+	grant, err := al.accessGrants.Get(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find access grant[id=%q].", id), err)
+		return nil, false
+	}
+	if grant == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Access grant[id=%q] not found.", id))
+		return nil, false
+	}
+	return grant, true
+}
+
+func canSeeAccessGrant(grant *accessgrants.Grant, user *users.User) bool {
+	return user.IsAdmin() || grant.AppliesToUser(user.Username, user.Groups)
+}
@@ -0,0 +1,337 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/query"
+	"github.com/riportdev/riport/share/random"
+)
+
+// handleListApprovalPolicies handles GET /approval-policies
+func (al *APIListener) handleListApprovalPolicies(w http.ResponseWriter, req *http.Request) {
+	policies, err := al.approvalProvider.ListPolicies(req.Context())
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get approval policies.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(policies))
+}
+
+// handlePostApprovalPolicy handles POST /approval-policies
+func (al *APIListener) handlePostApprovalPolicy(w http.ResponseWriter, req *http.Request) {
+	var policy approvals.Policy
+	err := parseRequestBody(req.Body, &policy)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := policy.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid approval policy.", err)
+		return
+	}
+
+	policy.ID, err = random.UUID4()
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	policy.CreatedAt = time.Now()
+	policy.CreatedBy = api.GetUser(req.Context(), al.Logger)
+
+	if err := al.approvalProvider.SavePolicy(req.Context(), &policy); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist a new approval policy.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApprovalPolicy, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(policy).
+		WithID(policy.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(policy))
+	al.Debugf("Approval policy [id=%q] created.", policy.ID)
+}
+
+// handlePutApprovalPolicy handles PUT /approval-policies/{policy_id}
+func (al *APIListener) handlePutApprovalPolicy(w http.ResponseWriter, req *http.Request) {
+	existing, ok := al.getApprovalPolicyFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	var policy approvals.Policy
+	err := parseRequestBody(req.Body, &policy)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := policy.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid approval policy.", err)
+		return
+	}
+
+	policy.ID = existing.ID
+	policy.CreatedAt = existing.CreatedAt
+	policy.CreatedBy = existing.CreatedBy
+
+	if err := al.approvalProvider.SavePolicy(req.Context(), &policy); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist approval policy.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApprovalPolicy, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithRequest(policy).
+		WithID(policy.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(policy))
+	al.Debugf("Approval policy [id=%q] updated.", policy.ID)
+}
+
+// handleDeleteApprovalPolicy handles DELETE /approval-policies/{policy_id}
+func (al *APIListener) handleDeleteApprovalPolicy(w http.ResponseWriter, req *http.Request) {
+	policy, ok := al.getApprovalPolicyFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	if err := al.approvalProvider.DeletePolicy(req.Context(), policy.ID); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete approval policy[id=%q].", policy.ID), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApprovalPolicy, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(policy.ID).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Approval policy [id=%q] deleted.", policy.ID)
+}
+
+func (al *APIListener) getApprovalPolicyFromRequest(w http.ResponseWriter, req *http.Request) (*approvals.Policy, bool) {
+	id := mux.Vars(req)[routes.ParamPolicyID]
+	policy, err := al.approvalProvider.GetPolicy(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find approval policy[id=%q].", id), err)
+		return nil, false
+	}
+	if policy == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Approval policy[id=%q] not found.", id))
+		return nil, false
+	}
+	return policy, true
+}
+
+// handleListApprovals handles GET /approvals
+// Non-admin users only see requests they made or they can decide on.
+func (al *APIListener) handleListApprovals(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	options := query.NewOptions(req, approvals.RequestListDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, approvals.RequestSupportedSorts, approvals.RequestSupportedFilters, nil, &query.PaginationConfig{
+		MaxLimit:     100,
+		DefaultLimit: 20,
+	})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	pagination := options.Pagination
+	options.Pagination = nil
+	entries, err := al.approvalProvider.ListRequests(ctx, options)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get approval requests.", err)
+		return
+	}
+
+	policies, err := al.approvalPoliciesByID(req)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get approval policies.", err)
+		return
+	}
+
+	visible := make([]*approvals.Request, 0, len(entries))
+	for _, entry := range entries {
+		if curUser.IsAdmin() || entry.RequestedBy == curUser.Username || canDecideApproval(policies[entry.PolicyID], curUser) {
+			visible = append(visible, entry)
+		}
+	}
+
+	totalCount := len(visible)
+	start, end := pagination.GetStartEnd(totalCount)
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: visible[start:end],
+		Meta: api.NewMeta(totalCount),
+	})
+}
+
+// handleGetApproval handles GET /approvals/{approval_id}
+func (al *APIListener) handleGetApproval(w http.ResponseWriter, req *http.Request) {
+	approval, policy, ok := al.getApprovalFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if !curUser.IsAdmin() && approval.RequestedBy != curUser.Username && !canDecideApproval(policy, curUser) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Access denied.")
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(approval))
+}
+
+type approvalDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// handleApproveApproval handles POST /approvals/{approval_id}/approve
+func (al *APIListener) handleApproveApproval(w http.ResponseWriter, req *http.Request) {
+	al.decideApproval(w, req, true)
+}
+
+// handleRejectApproval handles POST /approvals/{approval_id}/reject
+func (al *APIListener) handleRejectApproval(w http.ResponseWriter, req *http.Request) {
+	al.decideApproval(w, req, false)
+}
+
+func (al *APIListener) decideApproval(w http.ResponseWriter, req *http.Request, approve bool) {
+	ctx := req.Context()
+
+	var decision approvalDecisionRequest
+	if req.ContentLength != 0 {
+		if err := parseRequestBody(req.Body, &decision); err != nil {
+			al.jsonError(w, err)
+			return
+		}
+	}
+
+	// decisions are serialized, so an approved operation is never executed twice
+	al.approvalsMu.Lock()
+	defer al.approvalsMu.Unlock()
+
+	approval, policy, ok := al.getApprovalFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if approval.RequestedBy == curUser.Username {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Approval request cannot be decided on by its requester.")
+		return
+	}
+	if !canDecideApproval(policy, curUser) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Access denied. User is not an approver of the approval request.")
+		return
+	}
+	if approval.Status != approvals.StatusPending {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Approval request[id=%q] is already %s.", approval.ID, approval.Status))
+		return
+	}
+
+	now := time.Now()
+	approval.DecidedAt = &now
+	approval.DecidedBy = &curUser.Username
+	approval.Reason = decision.Reason
+
+	action := auditlog.ActionReject
+	approval.Status = approvals.StatusRejected
+	if approve {
+		action = auditlog.ActionApprove
+		approval.Status = approvals.StatusApproved
+		result, err := al.executeApprovedRequest(ctx, approval)
+		if err != nil {
+			al.Errorf("Failed to execute approved request[id=%q]: %v", approval.ID, err)
+			approval.Status = approvals.StatusFailed
+			approval.Error = err.Error()
+		}
+		approval.Result = result
+	}
+
+	if err := al.approvalProvider.SaveRequest(ctx, approval); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist approval request.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApproval, action).
+		WithHTTPRequest(req).
+		WithRequest(decision).
+		WithResponse(approval).
+		WithID(approval.ID).
+		Save()
+
+	al.notifyRequester(ctx, approval)
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(approval))
+	al.Debugf("Approval request[id=%q] %s by %s.", approval.ID, approval.Status, curUser.Username)
+}
+
+func (al *APIListener) getApprovalFromRequest(w http.ResponseWriter, req *http.Request) (*approvals.Request, *approvals.Policy, bool) {
+	id := mux.Vars(req)[routes.ParamApprovalID]
+	approval, err := al.approvalProvider.GetRequest(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find approval request[id=%q].", id), err)
+		return nil, nil, false
+	}
+	if approval == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Approval request[id=%q] not found.", id))
+		return nil, nil, false
+	}
+
+	policy, err := al.approvalProvider.GetPolicy(req.Context(), approval.PolicyID)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find approval policy[id=%q].", approval.PolicyID), err)
+		return nil, nil, false
+	}
+
+	return approval, policy, true
+}
+
+func (al *APIListener) approvalPoliciesByID(req *http.Request) (map[string]*approvals.Policy, error) {
+	policies, err := al.approvalProvider.ListPolicies(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*approvals.Policy, len(policies))
+	for _, p := range policies {
+		res[p.ID] = p
+	}
+	return res, nil
+}
+
+// canDecideApproval returns whether a user is an approver of a policy. Requests of deleted policies can be decided on by admins only.
+func canDecideApproval(policy *approvals.Policy, user *users.User) bool {
+	if policy == nil {
+		return user.IsAdmin()
+	}
+	return policy.CanApprove(user.Groups)
+}
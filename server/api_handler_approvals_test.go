@@ -0,0 +1,168 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	approvalsmigration "github.com/riportdev/riport/db/migration/approvals"
+	jobsmigration "github.com/riportdev/riport/db/migration/jobs"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/test"
+)
+
+func TestHandleApprovals(t *testing.T) {
+	requester := &users.User{
+		Username: "requester",
+		Groups:   []string{users.Administrators},
+	}
+	approver := &users.User{
+		Username: "approver",
+		Groups:   []string{"approvers"},
+	}
+	other := &users.User{
+		Username: "other",
+		Groups:   []string{"others"},
+	}
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshRespBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Logger(testLog).Build()
+
+	approvalsDB, err := sqlite.New(":memory:", approvalsmigration.AssetNames(), approvalsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	ap := approvals.NewSqliteProvider(approvalsDB)
+	defer ap.Close()
+
+	jobsDB, err := sqlite.New(":memory:", jobsmigration.AssetNames(), jobsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	jp := jobs.NewSqliteProvider(jobsDB, testLog)
+	defer jp.Close()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1}, &hour, testLog), testLog, nil),
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{
+					RunRemoteCmdTimeoutSec: 60,
+				},
+				API: chconfig.APIConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			clientGroupProvider: mockClientGroupProvider{},
+			jobProvider:         jp,
+		},
+		userService:      users.NewAPIService(users.NewStaticProvider([]*users.User{requester, approver, other}), false, 0, -1),
+		approvalProvider: ap,
+		Logger:           testLog,
+	}
+	al.initRouter()
+
+	requesterCtx := api.WithUser(context.Background(), requester.Username)
+	approverCtx := api.WithUser(context.Background(), approver.Username)
+	otherCtx := api.WithUser(context.Background(), other.Username)
+
+	serve := func(ctx context.Context, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *approvals.Request {
+		resp := struct {
+			Data *approvals.Request `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	w := serve(requesterCtx, http.MethodPost, "/api/v1/approval-policies", `{"name": "sudo", "operation": "command", "sudo_only": true, "approver_groups": ["approvers"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	t.Run("not matching policy", func(t *testing.T) {
+		w := serve(requesterCtx, http.MethodPost, "/api/v1/clients/client-1/commands", `{"command": "/bin/date"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		w := serve(requesterCtx, http.MethodPost, "/api/v1/clients/client-1/commands", `{"command": "/bin/date", "is_sudo": true}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		approval := decode(w)
+		assert.Equal(t, approvals.StatusPending, approval.Status)
+		assert.Equal(t, approvals.TypeClientCommand, approval.Type)
+		assert.Equal(t, []string{"client-1"}, []string(approval.ClientIDs))
+		assert.Equal(t, requester.Username, approval.RequestedBy)
+
+		w = serve(otherCtx, http.MethodGet, "/api/v1/approvals/"+approval.ID, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(requesterCtx, http.MethodPost, "/api/v1/approvals/"+approval.ID+"/approve", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(approverCtx, http.MethodPost, "/api/v1/approvals/"+approval.ID+"/approve", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		approval = decode(w)
+		assert.Equal(t, approvals.StatusApproved, approval.Status)
+		assert.Equal(t, approver.Username, *approval.DecidedBy)
+		require.NotEmpty(t, approval.Result)
+
+		job, err := jp.GetByJID("client-1", approval.Result)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, requester.Username, job.CreatedBy)
+		assert.True(t, job.IsSudo)
+
+		w = serve(approverCtx, http.MethodPost, "/api/v1/approvals/"+approval.ID+"/reject", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		w := serve(requesterCtx, http.MethodPost, "/api/v1/clients/client-1/commands", `{"command": "/bin/date", "is_sudo": true}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		approval := decode(w)
+
+		w = serve(approverCtx, http.MethodPost, "/api/v1/approvals/"+approval.ID+"/reject", `{"reason": "not now"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		approval = decode(w)
+		assert.Equal(t, approvals.StatusRejected, approval.Status)
+		assert.Equal(t, "not now", approval.Reason)
+		assert.Empty(t, approval.Result)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := serve(approverCtx, http.MethodGet, "/api/v1/approvals?filter[status]=rejected", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		resp := struct {
+			Data []*approvals.Request `json:"data"`
+			Meta *api.Meta            `json:"meta"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Meta.Count)
+
+		w = serve(otherCtx, http.MethodGet, "/api/v1/approvals", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 0, resp.Meta.Count)
+	})
+}
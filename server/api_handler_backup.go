@@ -0,0 +1,39 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/backup"
+	chshare "github.com/riportdev/riport/share"
+)
+
+// handleGetBackup streams a backup of all databases of the running server
+func (al *APIListener) handleGetBackup(w http.ResponseWriter, req *http.Request) {
+	stores := backup.Stores(al.config)
+	for _, store := range stores {
+		if store.Name == backup.AlertsStoreName {
+			// the running server holds the lock of the bolt database
+			store.Bolt = al.Server.alertsDB
+		}
+	}
+
+	filename := fmt.Sprintf("riportd-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	manifest, err := backup.Backup(req.Context(), w, stores, chshare.BuildVersion)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationBackup, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(filename).
+		WithResponse(manifest).
+		Save()
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/cgroups"
 	"github.com/riportdev/riport/server/clients/clientdata"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/share/comm"
@@ -70,6 +73,9 @@ func (al *APIListener) handleUpdateClientAttributes(w http.ResponseWriter, req *
 		return
 	}
 
+	al.attributesLocks.Lock(client.GetID())
+	defer al.attributesLocks.Unlock(client.GetID())
+
 	sshResp := &Resp{}
 	err = comm.SendRequestAndGetResponse(client.GetConnection(), comm.RequestTypeUpdateClientAttributes, attributes, sshResp, al.Log())
 	if err != nil {
@@ -91,6 +97,197 @@ func (al *APIListener) handleUpdateClientAttributes(w http.ResponseWriter, req *
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload("ok"))
 }
 
+const (
+	clientAttributesStatusUpdated = "updated"
+	clientAttributesStatusQueued  = "queued"
+	clientAttributesStatusFailed  = "failed"
+)
+
+// bulkClientAttributesRequest changes the attributes of all targeted clients
+type bulkClientAttributesRequest struct {
+	ClientIDs  []string                     `json:"client_ids"`
+	GroupIDs   []string                     `json:"group_ids"`
+	ClientTags *models.JobClientTags        `json:"tags"`
+	Operations []models.AttributesOperation `json:"operations"`
+}
+
+func (r *bulkClientAttributesRequest) GetClientIDs() (ids []string) {
+	return r.ClientIDs
+}
+
+func (r *bulkClientAttributesRequest) GetGroupIDs() (ids []string) {
+	return r.GroupIDs
+}
+
+func (r *bulkClientAttributesRequest) GetClientTags() (clientTags *models.JobClientTags) {
+	return r.ClientTags
+}
+
+// clientAttributesResult is the result of changing the attributes of a single client
+type clientAttributesResult struct {
+	ClientID   string                       `json:"client_id"`
+	Status     string                       `json:"status"`
+	Attributes *models.Attributes           `json:"attributes,omitempty"`
+	Pending    []models.AttributesOperation `json:"pending,omitempty"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+// handlePostClientsAttributes applies attribute operations to all targeted clients. Connected clients are updated
+// right away, the operations for disconnected clients are queued until they reconnect. A failure on a client
+// doesn't stop the other clients, the result of each client is returned instead.
+func (al *APIListener) handlePostClientsAttributes(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	reqBody := &bulkClientAttributesRequest{}
+	err := parseRequestBody(req.Body, reqBody)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if len(reqBody.Operations) == 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "At least one operation should be specified.")
+		return
+	}
+	for i, o := range reqBody.Operations {
+		if err := o.Validate(); err != nil {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Invalid operation %d: %v.", i+1, err))
+			return
+		}
+	}
+
+	clients, _, err := al.getOrderedClientsWithValidation(ctx, reqBody)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	clientGroups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	results := make([]*clientAttributesResult, 0, len(clients))
+	for _, client := range clients {
+		result := al.updateClientAttributes(client, curUser, clientGroups, reqBody.Operations)
+		if result.Status != clientAttributesStatusFailed {
+			al.auditLog.Entry(auditlog.ApplicationClientAttributes, auditlog.ActionUpdate).
+				WithHTTPRequest(req).
+				WithClient(client).
+				WithRequest(reqBody.Operations).
+				WithResponse(result).
+				Save()
+		}
+		results = append(results, result)
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: results,
+		Meta: api.NewMeta(len(results)),
+	})
+}
+
+func (al *APIListener) updateClientAttributes(
+	client *clientdata.Client,
+	curUser *users.User,
+	clientGroups []*cgroups.ClientGroup,
+	operations []models.AttributesOperation,
+) *clientAttributesResult {
+	result := &clientAttributesResult{
+		ClientID: client.GetID(),
+		Status:   clientAttributesStatusFailed,
+	}
+
+	err := al.clientService.CheckClientsAccess([]*clientdata.Client{client}, curUser, clientGroups)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// the operations are applied to the current attributes, a concurrent change must not get lost
+	al.attributesLocks.Lock(client.GetID())
+	defer al.attributesLocks.Unlock(client.GetID())
+
+	if !client.IsConnected() {
+		client.QueueAttributes(operations)
+		if err := al.clientService.GetRepo().Save(client); err != nil {
+			result.Error = fmt.Sprintf("failed to queue attributes: %v", err)
+			return result
+		}
+		result.Status = clientAttributesStatusQueued
+		result.Pending = client.GetPendingAttributes()
+		return result
+	}
+
+	if client.IsPaused() {
+		result.Error = fmt.Sprintf("client is paused (reason = %s)", client.GetPausedReason())
+		return result
+	}
+
+	attributes := client.GetAttributes().Apply(operations)
+	err = al.sendClientAttributes(client, attributes)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = clientAttributesStatusUpdated
+	result.Attributes = &attributes
+	return result
+}
+
+// sendClientAttributes writes the attributes to the attributes file of a connected client and updates the client
+func (al *APIListener) sendClientAttributes(client *clientdata.Client, attributes models.Attributes) error {
+	sshResp := &Resp{}
+	err := comm.SendRequestAndGetResponse(client.GetConnection(), comm.RequestTypeUpdateClientAttributes, attributes, sshResp, al.Log())
+	if err != nil {
+		return err
+	}
+
+	client.SetAttributes(attributes)
+
+	err = al.clientService.GetRepo().Save(client)
+	if err != nil {
+		al.Errorf("Failed to save attributes of client %s: %v", client.GetID(), err)
+	}
+	return nil
+}
+
+// deliverPendingAttributes applies the attribute changes queued while the client was disconnected.
+// The changes are applied to the attributes the client reported on connect.
+func (al *APIListener) deliverPendingAttributes(client *clientdata.Client) {
+	al.attributesLocks.Lock(client.GetID())
+	defer al.attributesLocks.Unlock(client.GetID())
+
+	pending := client.GetPendingAttributes()
+	if len(pending) == 0 {
+		return
+	}
+
+	attributes := client.GetAttributes().Apply(pending)
+	err := al.sendClientAttributes(client, attributes)
+	if err != nil {
+		if _, ok := err.(*comm.ClientError); !ok {
+			al.Errorf("Failed to deliver pending attributes to client %s, retrying on next connect: %v", client.GetID(), err)
+			return
+		}
+		// the client rejected the changes, e.g. it has no attributes file, retrying won't help
+		al.Errorf("Client %s rejected pending attributes, discarding them: %v", client.GetID(), err)
+	} else {
+		al.Debugf("Delivered %d pending attribute changes to client %s.", len(pending), client.GetID())
+	}
+
+	client.RemovePendingAttributes(len(pending))
+	if err := al.clientService.GetRepo().Save(client); err != nil {
+		al.Errorf("Failed to save client %s: %v", client.GetID(), err)
+	}
+}
+
 func (al *APIListener) withActiveClient(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 
@@ -0,0 +1,242 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/test"
+)
+
+func TestHandlePostClientsAttributes(t *testing.T) {
+	testUser := "test-user"
+	curUser := &users.User{
+		Username: testUser,
+		Groups:   []string{users.Administrators},
+	}
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		connReturnOk   bool
+		wantStatusCode int
+		wantResults    []clientAttributesResult
+		wantErrTitle   string
+	}{
+		{
+			name: "connected and disconnected clients",
+			requestBody: `{
+				"client_ids": ["client-1", "client-2"],
+				"operations": [
+					{"op": "add_tag", "tag": "web"},
+					{"op": "remove_tag", "tag": "Datacenter 1"},
+					{"op": "set_label", "key": "env", "value": "prod"},
+					{"op": "delete_label", "key": "datacenter"}
+				]
+			}`,
+			connReturnOk:   true,
+			wantStatusCode: http.StatusOK,
+			wantResults: []clientAttributesResult{
+				{
+					ClientID: "client-1",
+					Status:   clientAttributesStatusUpdated,
+					Attributes: &models.Attributes{
+						Tags:   []string{"Linux", "web"},
+						Labels: map[string]string{"country": "Germany", "city": "Cologne", "env": "prod"},
+					},
+				},
+				{
+					ClientID: "client-2",
+					Status:   clientAttributesStatusQueued,
+					Pending: []models.AttributesOperation{
+						{Op: models.AttributesOperationAddTag, Tag: "web"},
+						{Op: models.AttributesOperationRemoveTag, Tag: "Datacenter 1"},
+						{Op: models.AttributesOperationSetLabel, Key: "env", Value: "prod"},
+						{Op: models.AttributesOperationDeleteLabel, Key: "datacenter"},
+					},
+				},
+			},
+		},
+		{
+			name: "client rejects update",
+			requestBody: `{
+				"client_ids": ["client-1"],
+				"operations": [{"op": "add_tag", "tag": "web"}]
+			}`,
+			connReturnOk:   false,
+			wantStatusCode: http.StatusOK,
+			wantResults: []clientAttributesResult{
+				{
+					ClientID: "client-1",
+					Status:   clientAttributesStatusFailed,
+					Error:    "client error: attributes file path not set",
+				},
+			},
+		},
+		{
+			name: "no operations",
+			requestBody: `{
+				"client_ids": ["client-1"]
+			}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "At least one operation should be specified.",
+		},
+		{
+			name: "invalid operation",
+			requestBody: `{
+				"client_ids": ["client-1"],
+				"operations": [{"op": "set_label", "value": "prod"}]
+			}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid operation 1: set_label requires a key.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			connMock := test.NewConnMock()
+			connMock.ReturnOk = tc.connReturnOk
+			if tc.connReturnOk {
+				connMock.ReturnResponsePayload = []byte(`{"status":"OK"}`)
+			} else {
+				connMock.ReturnResponsePayload = []byte("attributes file path not set")
+			}
+			c1 := clients.New(t).ID("client-1").Connection(connMock).Logger(testLog).Build()
+			c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Logger(testLog).Build()
+
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1, c2}, &hour, testLog), testLog, nil),
+					config: &chconfig.Config{
+						API: chconfig.APIConfig{
+							MaxRequestBytes: 1024 * 1024,
+						},
+					},
+					clientGroupProvider: mockClientGroupProvider{},
+				},
+				userService: users.NewAPIService(users.NewStaticProvider([]*users.User{curUser}), false, 0, -1),
+				Logger:      testLog,
+			}
+			al.initRouter()
+
+			ctx := api.WithUser(context.Background(), testUser)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/clients-attributes", strings.NewReader(tc.requestBody))
+			req = req.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantStatusCode != http.StatusOK {
+				wantResp := api.NewErrAPIPayloadFromMessage("", tc.wantErrTitle, "")
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+				return
+			}
+
+			var gotResp struct {
+				Data []clientAttributesResult `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+			assert.Equal(t, tc.wantResults, gotResp.Data)
+
+			if tc.connReturnOk {
+				name, _, payload := connMock.InputSendRequest()
+				assert.Equal(t, comm.RequestTypeUpdateClientAttributes, name)
+				assert.JSONEq(t, `{"tags":["Linux","web"],"labels":{"country":"Germany","city":"Cologne","env":"prod"}}`, string(payload))
+				assert.Equal(t, []string{"Linux", "web"}, c1.GetAttributes().Tags)
+				assert.Len(t, c2.GetPendingAttributes(), 4)
+			}
+		})
+	}
+}
+
+func TestDeliverPendingAttributes(t *testing.T) {
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	connMock.ReturnResponsePayload = []byte(`{"status":"OK"}`)
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Logger(testLog).Build()
+	c1.QueueAttributes([]models.AttributesOperation{
+		{Op: models.AttributesOperationAddTag, Tag: "web"},
+		{Op: models.AttributesOperationSetLabel, Key: "city", Value: "Berlin"},
+	})
+
+	al := APIListener{
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1}, &hour, testLog), testLog, nil),
+		},
+		Logger: testLog,
+	}
+
+	al.deliverPendingAttributes(c1)
+
+	_, _, payload := connMock.InputSendRequest()
+	assert.JSONEq(t, `{"tags":["Linux","Datacenter 1","web"],"labels":{"country":"Germany","city":"Berlin","datacenter":"NetCologne GmbH"}}`, string(payload))
+	assert.Equal(t, "Berlin", c1.GetAttributes().Labels["city"])
+	assert.Empty(t, c1.GetPendingAttributes())
+}
+
+// slowConnMock takes a while to answer, so concurrent requests overlap
+type slowConnMock struct {
+	*test.ConnMock
+}
+
+func (c slowConnMock) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	time.Sleep(10 * time.Millisecond)
+	return c.ConnMock.SendRequest(name, wantReply, payload)
+}
+
+func TestUpdateClientAttributesConcurrently(t *testing.T) {
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	connMock.ReturnResponsePayload = []byte(`{"status":"OK"}`)
+	c1 := clients.New(t).ID("client-1").Connection(slowConnMock{connMock}).Logger(testLog).Build()
+	curUser := &users.User{
+		Username: "test-user",
+		Groups:   []string{users.Administrators},
+	}
+
+	al := APIListener{
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1}, &hour, testLog), testLog, nil),
+		},
+		Logger: testLog,
+	}
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			operations := []models.AttributesOperation{{Op: models.AttributesOperationAddTag, Tag: fmt.Sprintf("tag-%d", i)}}
+			result := al.updateClientAttributes(c1, curUser, nil, operations)
+			assert.Equal(t, clientAttributesStatusUpdated, result.Status)
+		}(i)
+	}
+	wg.Wait()
+
+	// none of the concurrent changes got lost
+	tags := c1.GetAttributes().Tags
+	for i := 0; i < updates; i++ {
+		assert.Contains(t, tags, fmt.Sprintf("tag-%d", i))
+	}
+}
@@ -86,6 +86,43 @@ func (al *APIListener) handlePutClientGroup(w http.ResponseWriter, req *http.Req
 	al.Debugf("Client Group [id=%q] updated.", group.ID)
 }
 
+// handlePostClientGroupPreview handles POST /client-groups/preview,
+// it returns the clients that would belong to the given group without saving it
+func (al *APIListener) handlePostClientGroupPreview(w http.ResponseWriter, req *http.Request) {
+	var group cgroups.ClientGroup
+	err := parseRequestBody(req.Body, &group)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := validateClientGroupParams(group.Params); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid client group.", err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	group.ClientIDs = []string{}
+	al.clientService.PopulateGroupsWithUserClients([]*cgroups.ClientGroup{&group}, curUser)
+
+	payload, err := al.convertToClientGroupPayload(&group, map[string]bool{
+		"client_ids":            true,
+		"num_clients":           true,
+		"num_clients_connected": true,
+	})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(payload))
+}
+
 const groupIDMaxLength = 30
 const validGroupIDChars = "A-Za-z0-9_-*"
 
@@ -101,12 +138,22 @@ func validateInputClientGroup(group cgroups.ClientGroup) error {
 	if invalidGroupIDRegexp.MatchString(group.ID) {
 		return fmt.Errorf("invalid group ID %q: can contain only %q", group.ID, validGroupIDChars)
 	}
-	if group.Params != nil && group.Params.Tag != nil {
-		_, _, err := cgroups.ParseTag(group.Params.Tag)
+	return validateClientGroupParams(group.Params)
+}
+
+func validateClientGroupParams(params *cgroups.ClientParams) error {
+	if params == nil {
+		return nil
+	}
+	if params.Tag != nil {
+		_, _, err := cgroups.ParseTag(params.Tag)
 		if err != nil {
 			return err
 		}
 	}
+	if params.Expression != nil {
+		return params.Expression.Validate()
+	}
 	return nil
 }
 
@@ -171,6 +171,42 @@ func TestValidateInputClientGroupParamsTag(t *testing.T) {
 	}
 }
 
+func TestValidateInputClientGroupParamsExpression(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression *cgroups.Expression
+		wantErr    error
+	}{
+		{
+			name:       "valid expression",
+			expression: cgroups.NewExpression(`labels.env == "prod" && updates.security > 0`),
+			wantErr:    nil,
+		},
+		{
+			name:       "unknown variable",
+			expression: cgroups.NewExpression(`env == "prod"`),
+			wantErr:    errors.New("invalid expression: unknown variable env at position 1"),
+		},
+		{
+			name:       "no expression",
+			expression: nil,
+			wantErr:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			group := cgroups.ClientGroup{
+				ID:     "testg1",
+				Params: &cgroups.ClientParams{Expression: tc.expression},
+			}
+
+			gotErr := validateInputClientGroup(group)
+			assert.Equal(t, tc.wantErr, gotErr)
+		})
+	}
+}
+
 func jsonData(data string) *json.RawMessage {
 	bytes := []byte(data)
 	return (*json.RawMessage)(&bytes)
@@ -1,6 +1,7 @@
 package chserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/riportdev/riport/server/api"
 	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/clients"
 	"github.com/riportdev/riport/server/clients/clientdata"
@@ -19,6 +21,7 @@ import (
 	"github.com/riportdev/riport/server/ports"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/server/validation"
+	"github.com/riportdev/riport/server/vault"
 	"github.com/riportdev/riport/share/comm"
 	"github.com/riportdev/riport/share/models"
 	"github.com/riportdev/riport/share/query"
@@ -228,22 +231,9 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	localAddr := req.URL.Query().Get("local")
-	remoteAddr := req.URL.Query().Get("remote")
-
-	remoteStr := localAddr + ":" + remoteAddr
-	if localAddr == "" {
-		remoteStr = remoteAddr
-	}
-
-	protocol := req.URL.Query().Get("protocol")
-	if protocol != "" {
-		remoteStr += "/" + protocol
-	}
-
-	remote, err := models.NewRemote(remoteStr)
+	remote, err := getRemoteFromRequest(req)
 	if err != nil {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("failed to decode %q: %v", remoteStr, err))
+		al.jsonError(w, err)
 		return
 	}
 
@@ -263,19 +253,7 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		remote.Scheme = &schemeStr
 	}
 
-	err = al.setTunnelProxyOptionsForRemote(req, remote)
-	if err != nil {
-		al.jsonError(w, err)
-		return
-	}
-
-	err = al.setAuthOptionsForRemote(req, remote)
-	if err != nil {
-		al.jsonError(w, err)
-		return
-	}
-
-	err = al.setAutoCloseIdleOptionsForRemote(req, remote)
+	err = al.setOptionsForRemote(req, remote)
 	if err != nil {
 		al.jsonError(w, err)
 		return
@@ -290,14 +268,28 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		remote.ACL = &aclStr
 	}
 
-	allowed, err := clienttunnel.IsAllowed(remote.Remote(), client.GetConnection(), al.Log())
-	if err != nil {
-		al.jsonError(w, err)
-		return
+	// the destinations of socks5 tunnels are checked by the client on every connection
+	if !remote.IsSOCKS5() {
+		allowed, err := clienttunnel.IsAllowed(remote.Remote(), client.GetConnection(), al.Log())
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		if !allowed {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Tunnel destination is not allowed by client configuration.")
+			return
+		}
 	}
-	if !allowed {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Tunnel destination is not allowed by client configuration.")
-		return
+	for _, route := range remote.HTTPRoutes {
+		allowed, err := clienttunnel.IsAllowed(route.Target, client.GetConnection(), al.Log())
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		if !allowed {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Route target %s is not allowed by client configuration.", route.Target))
+			return
+		}
 	}
 
 	if existing := al.clientService.FindTunnelByRemote(client, remote); existing != nil {
@@ -306,7 +298,7 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	}
 
 	for _, t := range client.GetTunnels() {
-		if t.Remote.Remote() == remote.Remote() && t.Remote.IsProtocol(remote.Protocol) && t.EqualACL(remote.ACL) {
+		if !remote.IsSOCKS5() && t.Remote.Remote() == remote.Remote() && t.Remote.IsProtocol(remote.Protocol) && t.EqualACL(remote.ACL) {
 			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeTunnelToPortExist, fmt.Sprintf("Tunnel to port %s already exists.", remote.RemotePort))
 			return
 		}
@@ -321,7 +313,7 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	}
 
 	if remote.IsLocalSpecified() {
-		err = al.checkLocalPort(remote.LocalPort, remote.Protocol)
+		err = al.checkLocalPort(remote.LocalPort, remote.ListenProtocol())
 		if err != nil {
 			al.jsonError(w, err)
 			return
@@ -336,6 +328,16 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	}
 	remote.Owner = currUser.Username
 
+	err = al.setAuthHeaderFromVault(req.Context(), client, remote, currUser)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if al.handleIfApprovalRequired(w, req, approvals.TypeTunnel, false, []*clientdata.Client{client}, &tunnelApprovalRequest{ClientID: clientID, Remote: remote}) {
+		return
+	}
+
 	// start the new tunnel only
 	tunnels, err := al.clientService.StartClientTunnels(client, []*models.Remote{remote})
 	if err != nil {
@@ -355,6 +357,56 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 	al.writeJSONResponse(w, http.StatusOK, response)
 }
 
+func getRemoteFromRequest(req *http.Request) (*models.Remote, error) {
+	localAddr := req.URL.Query().Get("local")
+	remoteAddr := req.URL.Query().Get("remote")
+	protocol := req.URL.Query().Get("protocol")
+
+	if protocol == models.ProtocolSOCKS5 {
+		if remoteAddr != "" {
+			return nil, apierrors.NewAPIError(http.StatusBadRequest, "", "remote not allowed for socks5 tunnels, the destination is given by each connection", nil)
+		}
+		remote, err := models.NewSOCKS5Remote(localAddr)
+		if err != nil {
+			return nil, apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("failed to decode %q: %v", localAddr, err), nil)
+		}
+		return remote, nil
+	}
+
+	remoteStr := localAddr + ":" + remoteAddr
+	if localAddr == "" {
+		remoteStr = remoteAddr
+	}
+
+	if protocol != "" {
+		remoteStr += "/" + protocol
+	}
+
+	remote, err := models.NewRemote(remoteStr)
+	if err != nil {
+		return nil, apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("failed to decode %q: %v", remoteStr, err), nil)
+	}
+	return remote, nil
+}
+
+// setOptionsForRemote sets the tunnel options given as query params except name, scheme and acl
+func (al *APIListener) setOptionsForRemote(req *http.Request, remote *models.Remote) error {
+	setters := []func(*http.Request, *models.Remote) error{
+		al.setTunnelProxyOptionsForRemote,
+		al.setDirectOptionsForRemote,
+		al.setAuthOptionsForRemote,
+		al.setHTTPRoutingOptionsForRemote,
+		al.setAutoCloseIdleOptionsForRemote,
+		al.setTrafficLimitsForRemote,
+	}
+	for _, set := range setters {
+		if err := set(req, remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (al *APIListener) setTunnelProxyOptionsForRemote(req *http.Request, remote *models.Remote) (err error) {
 	httpProxy := req.URL.Query().Get("http_proxy")
 	if httpProxy == "" {
@@ -403,11 +455,35 @@ func (al *APIListener) setTunnelProxyOptionsForRemote(req *http.Request, remote
 	return err
 }
 
+func (al *APIListener) setDirectOptionsForRemote(req *http.Request, remote *models.Remote) (err error) {
+	directStr := req.URL.Query().Get("direct")
+	if directStr == "" {
+		return nil
+	}
+	remote.Direct, err = strconv.ParseBool(directStr)
+	if err != nil {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid direct value %q", directStr), err)
+	}
+
+	if remote.Direct && remote.HTTPProxy {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", "direct tunnels can't be used with http_proxy", nil)
+	}
+	if remote.Direct && remote.Protocol != models.ProtocolTCP {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("direct tunnels not allowed with protocol %s", remote.Protocol), nil)
+	}
+	// the client port is reachable by everyone who can reach the client, not only by the users of the server
+	if remote.Direct && req.URL.Query().Get("acl") == "" {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", "direct tunnels require an acl", nil)
+	}
+
+	return nil
+}
+
 func (al *APIListener) setAuthOptionsForRemote(req *http.Request, remote *models.Remote) (err error) {
 	authUser := req.URL.Query().Get("auth_user")
 	authPassword := req.URL.Query().Get("auth_password")
 	if authUser != "" || authPassword != "" {
-		if !remote.HTTPProxy {
+		if !remote.HTTPProxy && !remote.IsSOCKS5() {
 			return apierrors.NewAPIError(http.StatusBadRequest, "", "http basic authentication requires http_proxy to be activated on the requested tunnel", nil)
 		}
 		if authPassword != "" && authUser == "" {
@@ -420,9 +496,110 @@ func (al *APIListener) setAuthOptionsForRemote(req *http.Request, remote *models
 		remote.AuthPassword = authPassword
 	}
 
+	authMode := req.URL.Query().Get("auth_mode")
+	switch authMode {
+	case "":
+	case models.TunnelAuthModeSession:
+		if !remote.HTTPProxy {
+			return apierrors.NewAPIError(http.StatusBadRequest, "", "auth_mode session requires http_proxy to be activated on the requested tunnel", nil)
+		}
+		if remote.AuthUser != "" {
+			return apierrors.NewAPIError(http.StatusBadRequest, "", "auth_mode session can't be combined with auth_user", nil)
+		}
+		remote.AuthMode = authMode
+	default:
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid auth_mode %q", authMode), nil)
+	}
+
 	return err
 }
 
+func (al *APIListener) setHTTPRoutingOptionsForRemote(req *http.Request, remote *models.Remote) (err error) {
+	query := req.URL.Query()
+
+	for _, routeStr := range query["path_route"] {
+		route, err := models.ParseHTTPRoute(routeStr)
+		if err != nil {
+			return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid path_route %q: %v", routeStr, err), nil)
+		}
+		for _, existing := range remote.HTTPRoutes {
+			if existing.PathPrefix == route.PathPrefix {
+				return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("duplicate path_route prefix %q", route.PathPrefix), nil)
+			}
+		}
+		remote.HTTPRoutes = append(remote.HTTPRoutes, route)
+	}
+
+	remote.RequestHeaders, err = parseHeaderRules(query["request_header"], "request_header")
+	if err != nil {
+		return err
+	}
+	remote.ResponseHeaders, err = parseHeaderRules(query["response_header"], "response_header")
+	if err != nil {
+		return err
+	}
+
+	if vaultIDStr := query.Get("auth_header_vault_id"); vaultIDStr != "" {
+		remote.AuthHeaderVaultID, err = strconv.Atoi(vaultIDStr)
+		if err != nil || remote.AuthHeaderVaultID <= 0 {
+			return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid auth_header_vault_id %q", vaultIDStr), nil)
+		}
+		remote.AuthHeaderName = "Authorization"
+	}
+	if authHeaderName := query.Get("auth_header_name"); authHeaderName != "" {
+		if remote.AuthHeaderVaultID == 0 {
+			return apierrors.NewAPIError(http.StatusBadRequest, "", "auth_header_name requires auth_header_vault_id", nil)
+		}
+		remote.AuthHeaderName = authHeaderName
+	}
+
+	if len(remote.HTTPRoutes) == 0 && len(remote.RequestHeaders) == 0 && len(remote.ResponseHeaders) == 0 && remote.AuthHeaderVaultID == 0 {
+		return nil
+	}
+	if !remote.HTTPProxy {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", "path_route, request_header, response_header and auth_header_vault_id require http_proxy to be activated on the requested tunnel", nil)
+	}
+	if remote.Scheme != nil && *remote.Scheme != "http" && *remote.Scheme != "https" {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("path_route, request_header, response_header and auth_header_vault_id not allowed with scheme %s", *remote.Scheme), nil)
+	}
+
+	return nil
+}
+
+func parseHeaderRules(values []string, param string) ([]models.HeaderRule, error) {
+	var rules []models.HeaderRule
+	for _, value := range values {
+		rule, err := models.ParseHeaderRule(value)
+		if err != nil {
+			return nil, apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid %s %q: %v", param, value, err), nil)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// setAuthHeaderFromVault reads the value of the auth header injected by the tunnel proxy from the vault.
+// The value is never stored, so it's read again for the requester when an approved tunnel is started.
+func (al *APIListener) setAuthHeaderFromVault(ctx context.Context, client *clientdata.Client, remote *models.Remote, user vault.UserDataProvider) error {
+	if remote.AuthHeaderVaultID == 0 {
+		return nil
+	}
+
+	storedValue, found, err := al.vaultManager.GetOne(ctx, remote.AuthHeaderVaultID, user)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("vault value %d not found", remote.AuthHeaderVaultID), nil)
+	}
+	if storedValue.ClientID != "" && storedValue.ClientID != client.GetID() {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("vault value %d belongs to another client", remote.AuthHeaderVaultID), nil)
+	}
+
+	remote.AuthHeaderValue = storedValue.Value
+	return nil
+}
+
 func (al *APIListener) setAutoCloseIdleOptionsForRemote(req *http.Request, remote *models.Remote) (err error) {
 	idleTimeoutMinutesStr := req.URL.Query().Get(idleTimeoutMinutesQueryParam)
 	skipIdleTimeout, err := strconv.ParseBool(req.URL.Query().Get(skipIdleTimeoutQueryParam))
@@ -445,6 +622,27 @@ func (al *APIListener) setAutoCloseIdleOptionsForRemote(req *http.Request, remot
 	return err
 }
 
+func (al *APIListener) setTrafficLimitsForRemote(req *http.Request, remote *models.Remote) (err error) {
+	remote.MaxRate, err = parseTrafficLimit(req.URL.Query().Get("max_rate"), "max_rate")
+	if err != nil {
+		return err
+	}
+
+	remote.Quota, err = parseTrafficLimit(req.URL.Query().Get("quota"), "quota")
+	return err
+}
+
+func parseTrafficLimit(value, param string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Invalid %s: %q, must be a number of bytes.", param, value), err)
+	}
+	return limit, nil
+}
+
 // TODO: remove this check, do it in client srv in startClientTunnels when https://github.com/riportdev/riport/pull/252 will be in master.
 // APIError needs both httpStatusCode and errorCode. To avoid too many merge conflicts with PR252 temporarily use this check to avoid breaking UI
 func (al *APIListener) checkLocalPort(localPort, protocol string) (err error) {
@@ -560,6 +758,7 @@ func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http
 		WithRequest(map[string]interface{}{
 			"force": force,
 		}).
+		WithResponse(tunnel.TrafficStats()).
 		Save()
 
 	w.WriteHeader(http.StatusNoContent)
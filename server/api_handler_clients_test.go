@@ -839,6 +839,8 @@ func TestHandlePutClientTunnelACL(t *testing.T) {
 	mockTunnelProtocol := &MockTunnelProtocol{}
 	c1 := clients.New(t).ID("client-1").Build()
 	c1.Tunnels[0].TunnelProtocol = mockTunnelProtocol
+	c1.Tunnels[1].TunnelProtocol = &MockTunnelProtocol{}
+	c1.Tunnels[1].Direct = true
 	al := APIListener{
 		insecureForTests: true,
 		Server: &Server{
@@ -880,6 +882,11 @@ func TestHandlePutClientTunnelACL(t *testing.T) {
 			URL:            "/api/v1/clients/client-1/tunnels/1/acl",
 			Body:           `{"acl": "invalid"}`,
 			ExpectedStatus: http.StatusBadRequest,
+		}, {
+			Name:           "direct tunnel without acl",
+			URL:            "/api/v1/clients/client-1/tunnels/2/acl",
+			Body:           `{"acl": null}`,
+			ExpectedStatus: http.StatusBadRequest,
 		}, {
 			Name:           "unknown tunnel",
 			URL:            "/api/v1/clients/client-1/tunnels/unknown/acl",
@@ -0,0 +1,58 @@
+package chserver
+
+import (
+	"net/http"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientsearch"
+	"github.com/riportdev/riport/share/query"
+)
+
+// handleSearchClients handles GET /clients-search
+func (al *APIListener) handleSearchClients(w http.ResponseWriter, req *http.Request) {
+	options := query.NewOptions(req, nil, nil, nil)
+	// filter[*] is expanded to the client fields only
+	searchFilters, clientFilters := query.SplitFilters(options.Filters, clientsearch.FilterColumns)
+	if errs := query.ValidateFilterOptions(searchFilters, clientsearch.SupportedFilters); errs != nil {
+		al.jsonError(w, errs)
+		return
+	}
+	if errs := query.ValidateFilterOptions(clientFilters, clients.OptionsSupportedFilters); errs != nil {
+		al.jsonError(w, errs)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	groups, err := al.clientGroupProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get client groups.", err)
+		return
+	}
+
+	filteredClients, err := al.clientService.GetFilteredUserClients(curUser, clientFilters, groups)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	clientIDs := make([]string, 0, len(filteredClients))
+	for _, c := range filteredClients {
+		clientIDs = append(clientIDs, c.GetID())
+	}
+
+	clientIDs, err = al.clientSearcher.Search(req.Context(), clientIDs, searchFilters)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: clientIDs,
+		Meta: api.NewMeta(len(clientIDs)),
+	})
+}
@@ -0,0 +1,63 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/cluster"
+	"github.com/riportdev/riport/server/routes"
+)
+
+// wrapClusterForwardMiddleware forwards requests for a client connected to another node of the cluster to that node
+func (al *APIListener) wrapClusterForwardMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if al.Server.cluster == nil || cluster.IsForwarded(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		clientID := mux.Vars(r)[routes.ParamClientID]
+		client, err := al.clientService.GetActiveByID(clientID)
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		if client != nil {
+			// connected to this node
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		owner, err := al.Server.cluster.GetRemoteOwner(r.Context(), clientID)
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		if owner == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		username := api.GetUser(r.Context(), al.Logger)
+		al.Debugf("Forwarding %s %s to node %s of client %s", r.Method, r.URL.Path, owner.ID, clientID)
+		al.Server.cluster.Forward(w, r, owner, username, func(w http.ResponseWriter, r *http.Request, err error) {
+			al.jsonErrorResponseWithError(w, http.StatusBadGateway, fmt.Sprintf("Failed to forward the request to cluster node %s.", owner.ID), err)
+		})
+	})
+}
+
+func (al *APIListener) handleGetClusterNodes(w http.ResponseWriter, req *http.Request) {
+	nodes, err := al.Server.cluster.Nodes(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if nodes == nil {
+		nodes = []*cluster.NodeStatus{}
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(nodes))
+}
@@ -10,8 +10,11 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/cluster"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/server/validation"
 	"github.com/riportdev/riport/share/comm"
@@ -152,6 +155,14 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 	execCmdInput.ClientID = cid
 	execCmdInput.IsScript = false
 
+	if err := validateExecuteInput(execCmdInput); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if al.handleIfClientApprovalRequired(w, req, approvals.TypeClientCommand, execCmdInput.IsSudo, cid, execCmdInput) {
+		return
+	}
+
 	resp := al.handleExecuteCommand(req.Context(), w, execCmdInput)
 
 	if resp != nil {
@@ -319,6 +330,10 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 
 	reqBody.Username = curUser.Username
 
+	if al.handleIfApprovalRequired(w, req, approvals.TypeMultiCommand, reqBody.IsSudo, reqBody.OrderedClients, reqBody) {
+		return
+	}
+
 	multiJob, err := al.StartMultiClientJob(ctx, &reqBody)
 	if err != nil {
 		al.jsonError(w, err)
@@ -342,13 +357,21 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 }
 
 func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.ResponseWriter, executeInput *api.ExecuteInput) *newJobResponse {
-	if executeInput.Command == "" {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Command cannot be empty.")
+	resp, err := al.executeCommand(ctx, executeInput, api.GetUser(ctx, al.Logger))
+	if err != nil {
+		al.jsonError(w, err)
 		return nil
 	}
-	if err := validation.ValidateInterpreter(executeInput.Interpreter, executeInput.IsScript); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid interpreter.", err)
-		return nil
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
+
+	return resp
+}
+
+// executeCommand sends a command or a script to a single client on behalf of a given user.
+func (al *APIListener) executeCommand(ctx context.Context, executeInput *api.ExecuteInput, username string) (*newJobResponse, error) {
+	if err := validateExecuteInput(executeInput); err != nil {
+		return nil, err
 	}
 
 	if executeInput.TimeoutSec <= 0 {
@@ -357,17 +380,24 @@ func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.Response
 
 	client, err := al.clientService.GetActiveByID(executeInput.ClientID)
 	if err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find an active client with id=%q.", executeInput.ClientID), err)
-		return nil
+		return nil, errors2.APIError{
+			Message:    fmt.Sprintf("Failed to find an active client with id=%q.", executeInput.ClientID),
+			Err:        err,
+			HTTPStatus: http.StatusInternalServerError,
+		}
 	}
 	if client == nil {
-		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Active client with id=%q not found.", executeInput.ClientID))
-		return nil
+		return nil, errors2.APIError{
+			Message:    fmt.Sprintf("Active client with id=%q not found.", executeInput.ClientID),
+			HTTPStatus: http.StatusNotFound,
+		}
 	}
 
 	if client.IsPaused() {
-		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("failed to execute command/script for client with id %s due to client being paused (reason = %s)", client.GetID(), client.GetPausedReason()))
-		return nil
+		return nil, errors2.APIError{
+			Message:    fmt.Sprintf("failed to execute command/script for client with id %s due to client being paused (reason = %s)", client.GetID(), client.GetPausedReason()),
+			HTTPStatus: http.StatusNotFound,
+		}
 	}
 
 	// send the command to the client
@@ -375,8 +405,7 @@ func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.Response
 	// Needed when server restarts to get all job data from client. Because on server restart job running info is lost.
 	jid, err := generateNewJobID()
 	if err != nil {
-		al.jsonError(w, err)
-		return nil
+		return nil, err
 	}
 	curJob := models.Job{
 		JID:         jid,
@@ -385,7 +414,7 @@ func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.Response
 		ClientName:  client.GetName(),
 		Command:     executeInput.Command,
 		Interpreter: executeInput.Interpreter,
-		CreatedBy:   api.GetUser(ctx, al.Logger),
+		CreatedBy:   username,
 		TimeoutSec:  executeInput.TimeoutSec,
 		Result:      nil,
 		Cwd:         executeInput.Cwd,
@@ -396,11 +425,16 @@ func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.Response
 	err = comm.SendRequestAndGetResponse(client.GetConnection(), comm.RequestTypeRunCmd, curJob, sshResp, al.Log())
 	if err != nil {
 		if _, ok := err.(*comm.ClientError); ok {
-			al.jsonErrorResponseWithTitle(w, http.StatusConflict, err.Error())
-		} else {
-			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to execute remote command.", err)
+			return nil, errors2.APIError{
+				Message:    err.Error(),
+				HTTPStatus: http.StatusConflict,
+			}
+		}
+		return nil, errors2.APIError{
+			Message:    "Failed to execute remote command.",
+			Err:        err,
+			HTTPStatus: http.StatusInternalServerError,
 		}
-		return nil
 	}
 
 	// set fields received in response
@@ -409,19 +443,35 @@ func (al *APIListener) handleExecuteCommand(ctx context.Context, w http.Response
 	curJob.Status = models.JobStatusRunning
 
 	if err := al.jobProvider.CreateJob(&curJob); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist a new job.", err)
-		return nil
-	}
-
-	resp := &newJobResponse{
-		JID: curJob.JID,
+		return nil, errors2.APIError{
+			Message:    "Failed to persist a new job.",
+			Err:        err,
+			HTTPStatus: http.StatusInternalServerError,
+		}
 	}
 
-	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
-
 	al.Debugf("Job[id=%q] created to execute remote command on client with id=%q: %q.", curJob.JID, executeInput.ClientID, executeInput.Command)
 
-	return resp
+	return &newJobResponse{
+		JID: curJob.JID,
+	}, nil
+}
+
+func validateExecuteInput(executeInput *api.ExecuteInput) error {
+	if executeInput.Command == "" {
+		return errors2.APIError{
+			Message:    "Command cannot be empty.",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+	if err := validation.ValidateInterpreter(executeInput.Interpreter, executeInput.IsScript); err != nil {
+		return errors2.APIError{
+			Message:    "Invalid interpreter.",
+			Err:        err,
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+	return nil
 }
 
 // handleCommandsWS handles GET /ws/commands
@@ -519,3 +569,89 @@ func (al *APIListener) handleGetMultiClientCommands(w http.ResponseWriter, req *
 	}
 	al.writeJSONResponse(w, http.StatusOK, payload)
 }
+
+// handlePauseMultiClientJob handles POST /commands/{job_id}/pause
+func (al *APIListener) handlePauseMultiClientJob(w http.ResponseWriter, req *http.Request) {
+	control, ok := al.getMultiJobControl(w, req)
+	if !ok {
+		return
+	}
+
+	control.Pause()
+
+	al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionPause).
+		WithHTTPRequest(req).
+		WithID(mux.Vars(req)[routes.ParamJobID]).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResumeMultiClientJob handles POST /commands/{job_id}/resume, it also approves a job waiting after a canary batch
+func (al *APIListener) handleResumeMultiClientJob(w http.ResponseWriter, req *http.Request) {
+	control, ok := al.getMultiJobControl(w, req)
+	if !ok {
+		return
+	}
+
+	if !control.Resume() {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, "Multi-client job is neither paused nor waiting for approval.")
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionResume).
+		WithHTTPRequest(req).
+		WithID(mux.Vars(req)[routes.ParamJobID]).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (al *APIListener) getMultiJobControl(w http.ResponseWriter, req *http.Request) (*multiJobControl, bool) {
+	jid := mux.Vars(req)[routes.ParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routes.ParamJobID))
+		return nil, false
+	}
+
+	job, err := al.jobProvider.GetMultiJob(req.Context(), jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find a multi-client job[id=%q].", jid), err)
+		return nil, false
+	}
+	if job == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
+		return nil, false
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return nil, false
+	}
+	if !curUser.IsAdmin() && job.CreatedBy != curUser.Username {
+		al.jsonErrorResponseWithError(w, http.StatusForbidden, "forbidden", fmt.Errorf("you are not allowed to access items created by another user"))
+		return nil, false
+	}
+
+	control := al.multiJobControls.Get(jid)
+	if control == nil && job.NodeID != "" && !cluster.IsForwarded(req) {
+		// the job runs on another node of the cluster
+		node, err := al.Server.cluster.GetRemoteNode(req.Context(), job.NodeID)
+		if err != nil {
+			al.jsonError(w, err)
+			return nil, false
+		}
+		if node != nil {
+			al.Server.cluster.Forward(w, req, node, curUser.Username, func(w http.ResponseWriter, r *http.Request, err error) {
+				al.jsonErrorResponseWithError(w, http.StatusBadGateway, fmt.Sprintf("Failed to forward the request to cluster node %s.", node.ID), err)
+			})
+			return nil, false
+		}
+	}
+	if control == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Multi-client Job[id=%q] is not in progress or runs without an execution strategy.", jid))
+		return nil, false
+	}
+	return control, true
+}
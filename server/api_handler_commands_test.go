@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -740,6 +741,330 @@ func TestHandlePostMultiClientCommand(t *testing.T) {
 	}
 }
 
+func TestHandlePostMultiClientCommandWithStrategy(t *testing.T) {
+	testUser := "test-user"
+	curUser := &users.User{
+		Username: testUser,
+		Groups:   []string{users.Administrators},
+	}
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshRespBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Logger(testLog).Build()
+	c2 := clients.New(t).ID("client-2").Connection(connMock).Logger(testLog).Build()
+	c3 := clients.New(t).ID("client-3").Connection(connMock).Logger(testLog).Build()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1, c2, c3}, &hour, testLog), testLog, nil),
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{
+					RunRemoteCmdTimeoutSec: 60,
+				},
+				API: chconfig.APIConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+			multiJobControls: multiJobControlMap{
+				m: make(map[string]*multiJobControl),
+			},
+			clientGroupProvider: mockClientGroupProvider{},
+		},
+		userService: users.NewAPIService(users.NewStaticProvider([]*users.User{curUser}), false, 0, -1),
+		Logger:      testLog,
+	}
+	done := make(chan bool)
+	al.testDone = done
+	al.initRouter()
+
+	jobsDB, err := sqlite.New(":memory:", jobsmigration.AssetNames(), jobsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	jp := jobs.NewSqliteProvider(jobsDB, testLog)
+	defer jp.Close()
+	al.jobProvider = jp
+
+	ctx := api.WithUser(context.Background(), testUser)
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		reqBody := `{"command": "/bin/date", "client_ids": ["client-1", "client-2"], "strategy": {"batch_size": -1}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/commands", strings.NewReader(reqBody)).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		al.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "batch_size cannot be negative")
+	})
+
+	postJob := func(t *testing.T, reqBody string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/commands", strings.NewReader(reqBody)).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		al.router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		gotResp := struct {
+			Data newJobResponse `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+		return gotResp.Data.JID
+	}
+
+	t.Run("canary with approval", func(t *testing.T) {
+		jid := postJob(t, `{"command": "/bin/date", "client_ids": ["client-1", "client-2", "client-3"], "strategy": {"canary_size": 1, "batch_size": 1}}`)
+		stop := sendMultiJobResults(t, &al, jp, jid, nil)
+		defer close(stop)
+
+		var gotMultiJob *models.MultiJob
+		require.Eventually(t, func() bool {
+			gotMultiJob, err = jp.GetMultiJob(ctx, jid)
+			require.NoError(t, err)
+			return gotMultiJob.Status == models.MultiJobStatusWaitingApproval
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"client-2", "client-3"}, gotMultiJob.RemainingClientIDs)
+		require.Len(t, gotMultiJob.Batches, 3)
+		assert.Equal(t, models.BatchStatusSuccessful, gotMultiJob.Batches[0].Status)
+		assert.Equal(t, 1, gotMultiJob.Batches[0].Successful)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/commands/"+jid+"/resume", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		<-done
+
+		gotMultiJob, err = jp.GetMultiJob(ctx, jid)
+		require.NoError(t, err)
+		assert.Equal(t, models.MultiJobStatusFinished, gotMultiJob.Status)
+		assert.Empty(t, gotMultiJob.RemainingClientIDs)
+		for _, b := range gotMultiJob.Batches {
+			assert.Equal(t, models.BatchStatusSuccessful, b.Status)
+		}
+		assert.Len(t, gotMultiJob.Jobs, 3)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/commands/"+jid+"/pause", nil).WithContext(ctx)
+		w = httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("failed canary", func(t *testing.T) {
+		jid := postJob(t, `{"command": "/bin/date", "client_ids": ["client-1", "client-2", "client-3"], "strategy": {"canary_size": 1, "batch_size": 1}}`)
+		stop := sendMultiJobResults(t, &al, jp, jid, map[string]string{"client-1": models.JobStatusFailed})
+		defer close(stop)
+
+		<-done
+
+		gotMultiJob, err := jp.GetMultiJob(ctx, jid)
+		require.NoError(t, err)
+		assert.Equal(t, models.MultiJobStatusAborted, gotMultiJob.Status)
+		assert.Equal(t, []string{"client-2", "client-3"}, gotMultiJob.RemainingClientIDs)
+		require.Len(t, gotMultiJob.Batches, 3)
+		assert.Equal(t, models.BatchStatusFailed, gotMultiJob.Batches[0].Status)
+		assert.Equal(t, 1, gotMultiJob.Batches[0].Failed)
+		assert.Equal(t, models.BatchStatusPending, gotMultiJob.Batches[1].Status)
+		assert.Equal(t, models.BatchStatusPending, gotMultiJob.Batches[2].Status)
+		assert.Len(t, gotMultiJob.Jobs, 1)
+	})
+
+	t.Run("rolling with max failure rate", func(t *testing.T) {
+		jid := postJob(t, `{"command": "/bin/date", "client_ids": ["client-1", "client-2", "client-3"], "execute_concurrently": true, "strategy": {"batch_size": 1, "max_failure_rate": 0}}`)
+		stop := sendMultiJobResults(t, &al, jp, jid, map[string]string{"client-2": models.JobStatusFailed})
+		defer close(stop)
+
+		<-done
+
+		gotMultiJob, err := jp.GetMultiJob(ctx, jid)
+		require.NoError(t, err)
+		assert.Equal(t, models.MultiJobStatusAborted, gotMultiJob.Status)
+		assert.Equal(t, []string{"client-3"}, gotMultiJob.RemainingClientIDs)
+		require.Len(t, gotMultiJob.Batches, 3)
+		assert.Equal(t, models.BatchStatusSuccessful, gotMultiJob.Batches[0].Status)
+		assert.Equal(t, models.BatchStatusFailed, gotMultiJob.Batches[1].Status)
+		assert.Equal(t, 1, gotMultiJob.Batches[1].Failed)
+		assert.Equal(t, models.BatchStatusPending, gotMultiJob.Batches[2].Status)
+		assert.Len(t, gotMultiJob.Jobs, 2)
+	})
+}
+
+func TestHandlePostMultiClientCommandDeliverOnReconnect(t *testing.T) {
+	testUser := "test-user"
+	curUser := &users.User{
+		Username: testUser,
+		Groups:   []string{users.Administrators},
+	}
+
+	c1 := clients.New(t).ID("client-1").Connection(makeConnMock(t, 1, time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC))).Logger(testLog).Build()
+	// not connected
+	c2 := clients.New(t).ID("client-2").Logger(testLog).Build()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1, c2}, &hour, testLog), testLog, nil),
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{
+					RunRemoteCmdTimeoutSec: 60,
+				},
+				API: chconfig.APIConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+			clientGroupProvider: mockClientGroupProvider{},
+		},
+		userService: users.NewAPIService(users.NewStaticProvider([]*users.User{curUser}), false, 0, -1),
+		Logger:      testLog,
+	}
+	done := make(chan bool)
+	al.testDone = done
+	al.initRouter()
+
+	jp := makeJobsProvider(t, DataSourceOptions, testLog)
+	defer jp.Close()
+	al.jobProvider = jp
+
+	ctx := api.WithUser(context.Background(), testUser)
+
+	postJob := func(t *testing.T, reqBody string) *models.MultiJob {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/commands", strings.NewReader(reqBody)).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		al.router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		gotResp := struct {
+			Data newJobResponse `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+
+		<-done
+
+		multiJob, err := jp.GetMultiJob(ctx, gotResp.Data.JID)
+		require.NoError(t, err)
+		require.Len(t, multiJob.Jobs, 2)
+		return multiJob
+	}
+	clientJob := func(multiJob *models.MultiJob, clientID string) *models.Job {
+		for _, job := range multiJob.Jobs {
+			if job.ClientID == clientID {
+				return job
+			}
+		}
+		return nil
+	}
+
+	t.Run("without delivery on reconnect", func(t *testing.T) {
+		multiJob := postJob(t, `{"command": "/bin/date", "client_ids": ["client-1", "client-2"], "abort_on_error": false}`)
+
+		assert.Equal(t, models.JobStatusRunning, clientJob(multiJob, "client-1").Status)
+		job := clientJob(multiJob, "client-2")
+		assert.Equal(t, models.JobStatusFailed, job.Status)
+		assert.Nil(t, job.ExpiresAt)
+	})
+
+	multiJob := postJob(t, `{"command": "/bin/date", "client_ids": ["client-1", "client-2"], "deliver_on_reconnect": true, "deliver_expiry_sec": 60}`)
+	deferred := clientJob(multiJob, "client-2")
+
+	t.Run("deferred", func(t *testing.T) {
+		assert.True(t, multiJob.DeliverOnReconnect)
+		assert.Equal(t, models.JobStatusRunning, clientJob(multiJob, "client-1").Status)
+		assert.Equal(t, models.JobStatusPending, deferred.Status)
+		require.NotNil(t, deferred.ExpiresAt)
+		assert.WithinDuration(t, multiJob.StartedAt.Add(60*time.Second), *deferred.ExpiresAt, time.Second)
+		assert.Nil(t, deferred.PID)
+	})
+
+	t.Run("delivered on reconnect", func(t *testing.T) {
+		expiredAt := time.Now().Add(-time.Minute)
+		expired := jb.New(t).ClientID("client-2").Status(models.JobStatusPending).StartedAt(expiredAt.Add(-time.Hour)).ExpiresAt(expiredAt).Build()
+		require.NoError(t, jp.CreateJob(expired))
+
+		connMock := makeConnMock(t, 2, time.Date(2020, 10, 10, 10, 10, 2, 0, time.UTC))
+		connMock.DoneChannel = make(chan bool, 10)
+		c2.SetConnection(connMock)
+
+		// concurrent reconnects must deliver a job only once
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				al.deliverPendingJobs(ctx, c2)
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, connMock.DoneChannel, 1)
+		name, _, payload := connMock.InputSendRequest()
+		assert.Equal(t, comm.RequestTypeRunCmd, name)
+		sentJob := &models.Job{}
+		require.NoError(t, json.Unmarshal(payload, sentJob))
+		assert.Equal(t, deferred.JID, sentJob.JID)
+
+		gotJob, err := jp.GetByJID("client-2", deferred.JID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobStatusRunning, gotJob.Status)
+		require.NotNil(t, gotJob.PID)
+		assert.Equal(t, 2, *gotJob.PID)
+
+		gotExpired, err := jp.GetByJID("client-2", expired.JID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobStatusFailed, gotExpired.Status)
+		assert.Equal(t, jobs.ErrMsgPendingJobExpired, gotExpired.Error)
+		assert.NotNil(t, gotExpired.FinishedAt)
+	})
+}
+
+// sendMultiJobResults sends results of started jobs of a multi-client job like the client listener does.
+// Jobs are successful unless another status is given for the client. Results are sent until the returned channel is closed.
+func sendMultiJobResults(t *testing.T, al *APIListener, jp *jobs.SqliteProvider, multiJobID string, statuses map[string]string) chan struct{} {
+	stop := make(chan struct{})
+	sent := make(map[string]bool)
+	go func() {
+		tick := time.NewTicker(5 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-tick.C:
+			}
+			multiJob, err := jp.GetMultiJob(context.Background(), multiJobID)
+			if err != nil || multiJob == nil {
+				continue
+			}
+			done := al.jobsDoneChannel.Get(multiJobID)
+			if done == nil {
+				continue
+			}
+			for _, job := range multiJob.Jobs {
+				if sent[job.JID] {
+					continue
+				}
+				sent[job.JID] = true
+				result := *job
+				result.Status = models.JobStatusSuccessful
+				if status, ok := statuses[job.ClientID]; ok {
+					result.Status = status
+				}
+				done <- &result
+			}
+		}
+	}()
+	return stop
+}
+
 func TestHandlePostMultiClientCommandWithPausedClient(t *testing.T) {
 	testUser := "test-user"
 	curUser := &users.User{
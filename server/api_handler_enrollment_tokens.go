@@ -0,0 +1,125 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/clients/enrollment"
+	"github.com/riportdev/riport/server/routes"
+)
+
+func (al *APIListener) handleListEnrollmentTokens(w http.ResponseWriter, req *http.Request) {
+	result, err := al.enrollmentTokens.List(req.Context(), req)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (al *APIListener) handleGetEnrollmentToken(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamEnrollmentToken]
+	t, err := al.enrollmentTokens.Get(req.Context(), id)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if t == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Enrollment token with id %q not found.", id))
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(t))
+}
+
+// handlePostEnrollmentTokens creates a token, the value to configure on the clients is only returned once
+func (al *APIListener) handlePostEnrollmentTokens(w http.ResponseWriter, req *http.Request) {
+	if !al.allowClientAuthWrite(w) {
+		return
+	}
+
+	ctx := req.Context()
+	t := &enrollment.Token{}
+	err := parseRequestBody(req.Body, t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	err = al.validateEnrollmentTokenGroup(ctx, t.GroupID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	result, err := al.enrollmentTokens.Create(ctx, t, curUser.Username)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationEnrollmentToken, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(map[string]interface{}{
+			"description": result.Description,
+			"expires_at":  result.ExpiresAt,
+			"max_uses":    result.MaxUses,
+			"tags":        result.Tags,
+			"labels":      result.Labels,
+			"group_id":    result.GroupID,
+		}).
+		WithID(result.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(result))
+}
+
+// handleDeleteEnrollmentToken revokes a token, the clients that enrolled with it are not affected
+func (al *APIListener) handleDeleteEnrollmentToken(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamEnrollmentToken]
+	err := al.enrollmentTokens.Revoke(req.Context(), id)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationEnrollmentToken, auditlog.ActionRevoke).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateEnrollmentTokenGroup checks that enrolled clients can be added to the group of a token by their id
+func (al *APIListener) validateEnrollmentTokenGroup(ctx context.Context, groupID string) error {
+	if groupID == "" {
+		return nil
+	}
+
+	group, err := al.clientGroupProvider.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Client group with id %q not found.", groupID), nil)
+	}
+	if group.Params == nil || group.Params.ClientID == nil || len(*group.Params.ClientID) == 0 {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Client group %q must have a client_id param to add enrolled clients to it.", groupID), nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/inventory"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/query"
+)
+
+var inventoryPaginationConfig = &query.PaginationConfig{
+	MaxLimit:     1000,
+	DefaultLimit: 100,
+}
+
+// handleGetClientInventory handles GET /clients/{client_id}/inventory
+func (al *APIListener) handleGetClientInventory(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routes.ParamClientID]
+	options := query.NewOptions(req, inventory.ItemsDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, inventory.SupportedItemSorts, inventory.SupportedItemFilters, nil, inventoryPaginationConfig)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	items, count, err := al.inventory.ListItems(req.Context(), clientID, options)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get inventory.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: items,
+		Meta: api.NewMeta(count),
+	})
+}
+
+// handleGetClientInventoryStatus handles GET /clients/{client_id}/inventory/status
+func (al *APIListener) handleGetClientInventoryStatus(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routes.ParamClientID]
+	status, err := al.inventory.GetStatus(req.Context(), clientID)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get inventory status.", err)
+		return
+	}
+	if status == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s has not sent an inventory", clientID))
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(status))
+}
+
+// handleGetClientInventoryChanges handles GET /clients/{client_id}/inventory/changes
+func (al *APIListener) handleGetClientInventoryChanges(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routes.ParamClientID]
+	options := query.NewOptions(req, inventory.ChangesDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, inventory.SupportedChangeSorts, inventory.SupportedChangeFilters, nil, inventoryPaginationConfig)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	changes, count, err := al.inventory.ListChanges(req.Context(), clientID, options)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get inventory changes.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: changes,
+		Meta: api.NewMeta(count),
+	})
+}
+
+// handleRefreshClientInventory handles POST /clients/{client_id}/inventory
+func (al *APIListener) handleRefreshClientInventory(w http.ResponseWriter, req *http.Request) {
+	clientID := mux.Vars(req)[routes.ParamClientID]
+	client, err := al.clientService.GetActiveByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
+		return
+	}
+
+	err = comm.SendRequestAndGetResponse(client.GetConnection(), comm.RequestTypeRefreshInventory, nil, nil, al.Log())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
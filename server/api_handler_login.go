@@ -12,14 +12,17 @@ import (
 	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/webauthn"
 	chshare "github.com/riportdev/riport/share"
 	"github.com/riportdev/riport/share/logger"
 )
 
 type twoFAResponse struct {
-	SendTo         string `json:"send_to"`
-	DeliveryMethod string `json:"delivery_method"`
-	TotPKeyStatus  string `json:"totp_key_status"`
+	SendTo            string                   `json:"send_to"`
+	DeliveryMethod    string                   `json:"delivery_method"`
+	TotPKeyStatus     string                   `json:"totp_key_status"`
+	WebAuthnKeyStatus string                   `json:"webauthn_key_status,omitempty"`
+	WebAuthn          *webauthn.RequestOptions `json:"webauthn,omitempty"`
 }
 
 type loginResponse struct {
@@ -103,6 +106,11 @@ func (al *APIListener) handleLogin(username, pwd string, newpwd string, skipPass
 		return
 	}
 
+	// security keys take precedence over other second factors
+	if al.handleWebAuthnLogin(w, req, user, lifetime) {
+		return
+	}
+
 	if al.config.API.IsTwoFAOn() {
 		sendTo, err := al.twoFASrv.SendToken(req.Context(), username, req.UserAgent(), chshare.RemoteIP(req))
 		if err != nil {
@@ -399,6 +399,7 @@ func TestWrapWithAuthMiddleware(t *testing.T) {
 		Username       string
 		Password       string
 		EnableTwoFA    bool
+		EnableWebAuthn bool
 		Bearer         string
 		ExpectedStatus int
 	}{
@@ -431,12 +432,26 @@ func TestWrapWithAuthMiddleware(t *testing.T) {
 			EnableTwoFA:    true,
 			ExpectedStatus: http.StatusUnauthorized,
 		},
+		{
+			Name:           "basic auth with password, webauthn enabled",
+			Username:       user.Username,
+			Password:       "pwd",
+			EnableWebAuthn: true,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
 		{
 			Name:           "basic auth with token",
 			Username:       user.Username,
 			Password:       "theprefi_mynicefi-xedl-enth-long-livedpasswor",
 			ExpectedStatus: http.StatusOK,
 		},
+		{
+			Name:           "basic auth with token, webauthn enabled",
+			Username:       user.Username,
+			Password:       "theprefi_mynicefi-xedl-enth-long-livedpasswor",
+			EnableWebAuthn: true,
+			ExpectedStatus: http.StatusOK,
+		},
 		{
 			Name:           "basic auth with an expired token",
 			Username:       user.Username,
@@ -477,6 +492,7 @@ func TestWrapWithAuthMiddleware(t *testing.T) {
 				twoFATokenDelivery = "smtp"
 			}
 			al.config.API.TwoFATokenDelivery = twoFATokenDelivery
+			al.config.API.WebAuthnEnabled = tc.EnableWebAuthn
 
 			handler := al.wrapWithAuthMiddleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, user.Username, api.GetUser(r.Context(), nil))
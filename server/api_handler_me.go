@@ -298,7 +298,7 @@ func (al *APIListener) handlePostToken(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	if r.Scope == authorization.APITokenClientsAuth && !user.IsAdmin() {
+	if (r.Scope == authorization.APITokenClientsAuth || r.Scope == authorization.APITokenSCIM) && !user.IsAdmin() {
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "current user should belong to Administrators group to create a token with this scope")
 		return
 	}
@@ -106,6 +106,7 @@ func TestHandleMeDBAuth(t *testing.T) {
 		false,
 		false,
 		false,
+		false,
 		logger)
 	require.NoError(t, err)
 
@@ -0,0 +1,251 @@
+package chserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/rbac"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/random"
+)
+
+// handleListRoles handles GET /roles
+func (al *APIListener) handleListRoles(w http.ResponseWriter, req *http.Request) {
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(al.roles.ListRoles()))
+}
+
+// handleGetRole handles GET /roles/{role_name}
+func (al *APIListener) handleGetRole(w http.ResponseWriter, req *http.Request) {
+	role, ok := al.getRoleFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(role))
+}
+
+// handlePostRole handles POST /roles
+func (al *APIListener) handlePostRole(w http.ResponseWriter, req *http.Request) {
+	var role rbac.Role
+	err := parseRequestBody(req.Body, &role)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := role.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid role.", err)
+		return
+	}
+	if al.roles.GetRole(role.Name) != nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Role %q already exists.", role.Name))
+		return
+	}
+
+	role.CreatedAt = time.Now()
+	role.CreatedBy = api.GetUser(req.Context(), al.Logger)
+
+	if err := al.roles.SaveRole(req.Context(), &role); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist a new role.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthRole, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(role).
+		WithID(role.Name).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(role))
+	al.Debugf("Role %q created.", role.Name)
+}
+
+// handlePutRole handles PUT /roles/{role_name}
+func (al *APIListener) handlePutRole(w http.ResponseWriter, req *http.Request) {
+	existing, ok := al.getRoleFromRequest(w, req)
+	if !ok {
+		return
+	}
+	if existing.BuiltIn {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Built-in role %q cannot be changed.", existing.Name))
+		return
+	}
+
+	var role rbac.Role
+	err := parseRequestBody(req.Body, &role)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	role.Name = existing.Name
+	if err := role.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid role.", err)
+		return
+	}
+	role.CreatedAt = existing.CreatedAt
+	role.CreatedBy = existing.CreatedBy
+
+	if err := al.roles.SaveRole(req.Context(), &role); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist role.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthRole, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithRequest(role).
+		WithID(role.Name).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(role))
+	al.Debugf("Role %q updated.", role.Name)
+}
+
+// handleDeleteRole handles DELETE /roles/{role_name}
+func (al *APIListener) handleDeleteRole(w http.ResponseWriter, req *http.Request) {
+	role, ok := al.getRoleFromRequest(w, req)
+	if !ok {
+		return
+	}
+	if role.BuiltIn {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Built-in role %q cannot be deleted.", role.Name))
+		return
+	}
+
+	err := al.roles.DeleteRole(req.Context(), role.Name)
+	if errors.Is(err, rbac.ErrRoleInUse) {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, fmt.Sprintf("Role %q is assigned to user groups, delete its role bindings first.", role.Name))
+		return
+	}
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete role %q.", role.Name), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthRole, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(role.Name).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Role %q deleted.", role.Name)
+}
+
+func (al *APIListener) getRoleFromRequest(w http.ResponseWriter, req *http.Request) (*rbac.Role, bool) {
+	name := mux.Vars(req)[routes.ParamRoleName]
+	role := al.roles.GetRole(name)
+	if role == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Role %q not found.", name))
+		return nil, false
+	}
+	return role, true
+}
+
+// handleListRoleBindings handles GET /role-bindings
+func (al *APIListener) handleListRoleBindings(w http.ResponseWriter, req *http.Request) {
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(al.roles.ListBindings()))
+}
+
+// handlePostRoleBinding handles POST /role-bindings
+func (al *APIListener) handlePostRoleBinding(w http.ResponseWriter, req *http.Request) {
+	var binding rbac.Binding
+	err := parseRequestBody(req.Body, &binding)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := binding.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "Invalid role binding.", err)
+		return
+	}
+	if al.roles.GetRole(binding.Role) == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Role %q not found.", binding.Role))
+		return
+	}
+
+	binding.ID, err = random.UUID4()
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	binding.CreatedAt = time.Now()
+	binding.CreatedBy = api.GetUser(req.Context(), al.Logger)
+
+	if err := al.roles.AddBinding(req.Context(), &binding); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to persist a new role binding.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthRoleBinding, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(binding).
+		WithID(binding.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(binding))
+	al.Debugf("Role binding [id=%q] created.", binding.ID)
+}
+
+// handleDeleteRoleBinding handles DELETE /role-bindings/{binding_id}
+func (al *APIListener) handleDeleteRoleBinding(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamBindingID]
+	if al.roles.GetBinding(id) == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Role binding[id=%q] not found.", id))
+		return
+	}
+
+	if err := al.roles.DeleteBinding(req.Context(), id); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete role binding[id=%q].", id), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthRoleBinding, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Role binding [id=%q] deleted.", id)
+}
+
+// handleGetMeAccess handles GET /me/access
+// It explains whether the current user may perform an action on a resource type and why.
+func (al *APIListener) handleGetMeAccess(w http.ResponseWriter, req *http.Request) {
+	resource := req.URL.Query().Get("resource")
+	action := req.URL.Query().Get("action")
+	if resource == "" || action == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Query params resource and action are required.")
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if !al.userService.SupportsGroupPermissions() {
+		al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(&rbac.Decision{
+			Allowed:  true,
+			Resource: resource,
+			Action:   action,
+			Reasons:  []string{"group permissions are not supported by the user provider, all actions are allowed"},
+		}))
+		return
+	}
+
+	decision, err := al.authorizeByRoles(req.Context(), curUser, resource, action, req.URL.Query().Get("client_id"))
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(decision))
+}
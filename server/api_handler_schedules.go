@@ -10,6 +10,7 @@ import (
 	"github.com/riportdev/riport/server/api"
 	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/jobs/schedule"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/clients/clientdata"
 )
@@ -53,6 +54,17 @@ func (al *APIListener) prepareHandleSchedules(req *http.Request) (schedule.Sched
 	if err != nil {
 		return scheduleInput, username, orderedClients, err
 	}
+
+	policy, err := al.getApprovalPolicy(ctx, approvals.Operation{Name: approvals.OperationCommand, IsSudo: scheduleInput.IsSudo}, orderedClients)
+	if err != nil {
+		return scheduleInput, username, orderedClients, err
+	}
+	if policy != nil {
+		return scheduleInput, username, orderedClients, errors2.APIError{
+			Message:    fmt.Sprintf("Schedule cannot be created for commands that require an approval by policy %q.", policy.Name),
+			HTTPStatus: http.StatusForbidden,
+		}
+	}
 	return scheduleInput, username, orderedClients, nil
 }
 
@@ -0,0 +1,424 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/authorization"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/server/scim"
+)
+
+// isUserDeactivated returns true if the user was deactivated by the identity provider via SCIM.
+func (al *APIListener) isUserDeactivated(username string) bool {
+	return al.scimService != nil && al.scimService.IsDeactivated(username)
+}
+
+// wrapSCIMAuthMiddleware authenticates SCIM requests. Only API tokens of scope "scim" owned by admins are accepted,
+// either as password of HTTP basic auth or as bearer token in the format <username>:<token>.
+func (al *APIListener) wrapSCIMAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, token, ok := scimCredentials(r)
+		if !ok {
+			al.writeSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "an API token of scope %q is required", authorization.APITokenSCIM))
+			return
+		}
+		if al.bannedUsers.IsBanned(username) {
+			al.writeSCIMError(w, scim.NewError(http.StatusTooManyRequests, "", "%s", ErrTooManyRequests.Error()))
+			return
+		}
+
+		authorized, err := al.checkSCIMToken(r.Context(), username, token)
+		if err != nil {
+			al.writeSCIMError(w, err)
+			return
+		}
+		if !al.handleBannedIPs(r, authorized) {
+			return
+		}
+		if !authorized {
+			al.bannedUsers.Add(username)
+			al.writeSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "unauthorized"))
+			return
+		}
+
+		if err := al.scimService.CheckSupported(); err != nil {
+			al.writeSCIMError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(api.WithUser(r.Context(), username)))
+	})
+}
+
+func scimCredentials(r *http.Request) (username, token string, ok bool) {
+	if username, token, ok := r.BasicAuth(); ok {
+		return username, token, username != ""
+	}
+
+	bearerToken, ok := bearer.GetBearerToken(r)
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(bearerToken, ":")
+	if i < 1 {
+		return "", "", false
+	}
+	return bearerToken[:i], bearerToken[i+1:], true
+}
+
+func (al *APIListener) checkSCIMToken(ctx context.Context, username, token string) (bool, error) {
+	user, err := al.userService.GetByUsername(username)
+	if err != nil {
+		return false, err
+	}
+	if user == nil || !user.IsAdmin() || al.isUserDeactivated(username) {
+		return false, nil
+	}
+
+	prefix, secret, err := authorization.Extract(token)
+	if err != nil {
+		return false, nil
+	}
+	apiToken, err := al.tokenManager.Get(ctx, username, prefix)
+	if err != nil {
+		return false, err
+	}
+	if apiToken == nil || apiToken.Scope != authorization.APITokenSCIM {
+		return false, nil
+	}
+	if apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(time.Now()) {
+		return false, nil
+	}
+	return verifyPassword(apiToken.Token, secret), nil
+}
+
+func (al *APIListener) writeSCIMResponse(w http.ResponseWriter, statusCode int, response interface{}) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(b); err != nil {
+		al.Errorf("error writing response: %s", err)
+	}
+}
+
+func (al *APIListener) writeSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		al.Errorf("SCIM request failed: %v", err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "%s", err.Error())
+	}
+	al.writeSCIMResponse(w, scimErr.HTTPStatus, scimErr)
+}
+
+// parseSCIMBody decodes the request body. Unlike parseRequestBody unknown fields are allowed, identity providers send
+// more attributes than the server stores.
+func parseSCIMBody(req *http.Request, dest interface{}) error {
+	if err := json.NewDecoder(req.Body).Decode(dest); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid JSON data: %v", err)
+	}
+	return nil
+}
+
+func parseSCIMListParams(req *http.Request) (f *scim.Filter, startIndex, count int, err error) {
+	f, err = scim.ParseFilter(req.URL.Query().Get("filter"))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	startIndex, err = parseSCIMIntParam(req, "startIndex", 1)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	count, err = parseSCIMIntParam(req, "count", scim.DefaultCount)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if count > scim.MaxCount {
+		count = scim.MaxCount
+	}
+	return f, startIndex, count, nil
+}
+
+func parseSCIMIntParam(req *http.Request, name string, defaultValue int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, "invalid %s %q", name, value)
+	}
+	return i, nil
+}
+
+// handleGetSCIMServiceProviderConfig handles GET /scim/v2/ServiceProviderConfig
+func (al *APIListener) handleGetSCIMServiceProviderConfig(w http.ResponseWriter, req *http.Request) {
+	al.writeSCIMResponse(w, http.StatusOK, scim.NewServiceProviderConfig())
+}
+
+// handleListSCIMUsers handles GET /scim/v2/Users
+func (al *APIListener) handleListSCIMUsers(w http.ResponseWriter, req *http.Request) {
+	f, startIndex, count, err := parseSCIMListParams(req)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	res, err := al.scimService.ListUsers(req.Context(), f, startIndex, count)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handleGetSCIMUser handles GET /scim/v2/Users/{scim_id}
+func (al *APIListener) handleGetSCIMUser(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.GetUser(req.Context(), id)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handlePostSCIMUser handles POST /scim/v2/Users
+func (al *APIListener) handlePostSCIMUser(w http.ResponseWriter, req *http.Request) {
+	var in scim.User
+	if err := parseSCIMBody(req, &in); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	res, err := al.scimService.CreateUser(req.Context(), &in)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSCIMUser, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(res.UserName).
+		Save()
+
+	al.Debugf("SCIM user [%s] created.", res.UserName)
+	al.writeSCIMResponse(w, http.StatusCreated, res)
+}
+
+// handlePutSCIMUser handles PUT /scim/v2/Users/{scim_id}
+func (al *APIListener) handlePutSCIMUser(w http.ResponseWriter, req *http.Request) {
+	var in scim.User
+	if err := parseSCIMBody(req, &in); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.ReplaceUser(req.Context(), id, &in)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.handleSCIMUserUpdated(w, req, res)
+}
+
+// handlePatchSCIMUser handles PATCH /scim/v2/Users/{scim_id}
+func (al *APIListener) handlePatchSCIMUser(w http.ResponseWriter, req *http.Request) {
+	var patch scim.PatchRequest
+	if err := parseSCIMBody(req, &patch); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	if err := patch.Validate(); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.PatchUser(req.Context(), id, &patch)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.handleSCIMUserUpdated(w, req, res)
+}
+
+// handleSCIMUserUpdated logs the change and terminates the sessions of deactivated users.
+func (al *APIListener) handleSCIMUserUpdated(w http.ResponseWriter, req *http.Request, res *scim.User) {
+	if !res.IsActive() {
+		if err := al.apiSessions.DeleteAllByUser(req.Context(), res.UserName); err != nil {
+			al.writeSCIMError(w, err)
+			return
+		}
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSCIMUser, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithID(res.UserName).
+		WithRequest(map[string]interface{}{"active": res.IsActive()}).
+		Save()
+
+	al.Debugf("SCIM user [%s] updated.", res.UserName)
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handleDeleteSCIMUser handles DELETE /scim/v2/Users/{scim_id}
+func (al *APIListener) handleDeleteSCIMUser(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	user, err := al.scimService.GetUser(ctx, id)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	if err := al.scimService.DeleteUser(ctx, id); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	if err := al.apiSessions.DeleteAllByUser(ctx, user.UserName); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSCIMUser, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(user.UserName).
+		Save()
+
+	al.Debugf("SCIM user [%s] deleted.", user.UserName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListSCIMGroups handles GET /scim/v2/Groups
+func (al *APIListener) handleListSCIMGroups(w http.ResponseWriter, req *http.Request) {
+	f, startIndex, count, err := parseSCIMListParams(req)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	res, err := al.scimService.ListGroups(req.Context(), f, startIndex, count)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handleGetSCIMGroup handles GET /scim/v2/Groups/{scim_id}
+func (al *APIListener) handleGetSCIMGroup(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.GetGroup(req.Context(), id)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handlePostSCIMGroup handles POST /scim/v2/Groups
+func (al *APIListener) handlePostSCIMGroup(w http.ResponseWriter, req *http.Request) {
+	var in scim.Group
+	if err := parseSCIMBody(req, &in); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	res, err := al.scimService.CreateGroup(req.Context(), &in)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSCIMGroup, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(res).
+		WithID(res.DisplayName).
+		Save()
+
+	al.Debugf("SCIM group [%s] created.", res.DisplayName)
+	al.writeSCIMResponse(w, http.StatusCreated, res)
+}
+
+// handlePutSCIMGroup handles PUT /scim/v2/Groups/{scim_id}
+func (al *APIListener) handlePutSCIMGroup(w http.ResponseWriter, req *http.Request) {
+	var in scim.Group
+	if err := parseSCIMBody(req, &in); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.ReplaceGroup(req.Context(), id, &in)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.handleSCIMGroupUpdated(w, req, res)
+}
+
+// handlePatchSCIMGroup handles PATCH /scim/v2/Groups/{scim_id}
+func (al *APIListener) handlePatchSCIMGroup(w http.ResponseWriter, req *http.Request) {
+	var patch scim.PatchRequest
+	if err := parseSCIMBody(req, &patch); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	if err := patch.Validate(); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	res, err := al.scimService.PatchGroup(req.Context(), id, &patch)
+	if err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+	al.handleSCIMGroupUpdated(w, req, res)
+}
+
+func (al *APIListener) handleSCIMGroupUpdated(w http.ResponseWriter, req *http.Request, res *scim.Group) {
+	al.auditLog.Entry(auditlog.ApplicationSCIMGroup, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithRequest(res).
+		WithID(res.DisplayName).
+		Save()
+
+	al.Debugf("SCIM group [%s] updated.", res.DisplayName)
+	al.writeSCIMResponse(w, http.StatusOK, res)
+}
+
+// handleDeleteSCIMGroup handles DELETE /scim/v2/Groups/{scim_id}
+func (al *APIListener) handleDeleteSCIMGroup(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamSCIMID]
+	if err := al.scimService.DeleteGroup(req.Context(), id); err != nil {
+		al.writeSCIMError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSCIMGroup, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
+	al.Debugf("SCIM group [%s] deleted.", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/riportdev/riport/server/api"
 	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/share/ws"
@@ -46,6 +47,14 @@ func (al *APIListener) handleExecuteScript(w http.ResponseWriter, req *http.Requ
 	execCmdInput.ClientID = cid
 	execCmdInput.IsScript = true
 
+	if err := validateExecuteInput(execCmdInput); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if al.handleIfClientApprovalRequired(w, req, approvals.TypeClientScript, execCmdInput.IsSudo, cid, execCmdInput) {
+		return
+	}
+
 	resp := al.handleExecuteCommand(req.Context(), w, execCmdInput)
 
 	if resp != nil {
@@ -100,6 +109,10 @@ func (al *APIListener) handlePostMultiClientScript(w http.ResponseWriter, req *h
 
 	inboundMsg.Username = curUser.Username
 
+	if al.handleIfApprovalRequired(w, req, approvals.TypeMultiScript, inboundMsg.IsSudo, inboundMsg.OrderedClients, inboundMsg) {
+		return
+	}
+
 	multiJob, err := al.StartMultiClientJob(ctx, inboundMsg)
 	if err != nil {
 		al.jsonError(w, err)
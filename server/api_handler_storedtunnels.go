@@ -1,12 +1,16 @@
 package chserver
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 
 	"github.com/riportdev/riport/server/api"
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
 	"github.com/riportdev/riport/server/clients/storedtunnels"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/share/query"
@@ -33,6 +37,7 @@ func (al *APIListener) handleGetStoredTunnels(w http.ResponseWriter, req *http.R
 		al.jsonError(w, err)
 		return
 	}
+	al.refreshPersistentTunnelHealth(result.Data.([]*storedtunnels.StoredTunnel))
 
 	al.writeJSONResponse(w, http.StatusOK, result)
 }
@@ -59,12 +64,23 @@ func (al *APIListener) handlePostStoredTunnels(w http.ResponseWriter, req *http.
 		return
 	}
 
+	err = al.prepareStoredTunnel(ctx, storedTunnel, []*clientdata.Client{client})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
 	result, err := al.storedTunnels.Create(ctx, client.GetID(), storedTunnel)
 	if err != nil {
 		al.jsonError(w, err)
 		return
 	}
 
+	if result.Persistent && client.IsConnected() && !client.IsPaused() {
+		al.startPersistentTunnel(ctx, client, result)
+		result.Health = al.storedTunnels.GetHealth(result.ID)
+	}
+
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
 }
 
@@ -85,11 +101,17 @@ func (al *APIListener) handleDeleteStoredTunnel(w http.ResponseWriter, req *http
 	}
 
 	// ED TODO: need to check if its the same user that owned this, but need to fetch the tunnel first
+	health, err := al.getStoredTunnelHealth(ctx, tunnelID, client.GetID(), "")
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
 	err = al.storedTunnels.Delete(ctx, client.GetID(), tunnelID)
 	if err != nil {
 		al.jsonError(w, err)
 		return
 	}
+	al.stopPersistentTunnel(tunnelID, health)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -118,11 +140,216 @@ func (al *APIListener) handlePutStoredTunnel(w http.ResponseWriter, req *http.Re
 	}
 	storedTunnel.ID = tunnelID
 
+	err = al.prepareStoredTunnel(ctx, storedTunnel, []*clientdata.Client{client})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	// a running persistent tunnel is restarted with the new options
+	health, err := al.getStoredTunnelHealth(ctx, tunnelID, client.GetID(), "")
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
 	result, err := al.storedTunnels.Update(ctx, client.GetID(), storedTunnel)
 	if err != nil {
 		al.jsonError(w, err)
 		return
 	}
+	al.stopPersistentTunnel(tunnelID, health)
+
+	if result.Persistent && client.IsConnected() && !client.IsPaused() {
+		al.startPersistentTunnel(ctx, client, result)
+		result.Health = al.storedTunnels.GetHealth(result.ID)
+	}
 
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
 }
+
+// prepareStoredTunnel makes the current user the owner of a stored tunnel and checks the options of a persistent
+// tunnel on the given clients as it's started without user interaction
+func (al *APIListener) prepareStoredTunnel(ctx context.Context, t *storedtunnels.StoredTunnel, clients []*clientdata.Client) error {
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		return err
+	}
+	t.Owner = curUser.Username
+
+	if !t.Persistent {
+		return nil
+	}
+
+	_, err = al.getRemoteFromStoredTunnel(t, 0)
+	if err != nil {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Invalid persistent tunnel: %v", err), err)
+	}
+	return al.checkPersistentTunnelApproval(ctx, clients)
+}
+
+func (al *APIListener) handleGetClientGroupStoredTunnels(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	group, ok := al.getClientGroupForStoredTunnels(w, req)
+	if !ok {
+		return
+	}
+
+	options := query.GetListOptions(req)
+	result, err := al.storedTunnels.ListForGroup(ctx, options, group.ID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	al.refreshPersistentTunnelHealth(result.Data.([]*storedtunnels.StoredTunnel))
+
+	al.writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (al *APIListener) handlePostClientGroupStoredTunnels(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	group, ok := al.getClientGroupForStoredTunnels(w, req)
+	if !ok {
+		return
+	}
+
+	groupClients, err := al.clientService.GetByGroups([]*cgroups.ClientGroup{group})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	storedTunnel := &storedtunnels.StoredTunnel{}
+	err = parseRequestBody(req.Body, storedTunnel)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	err = al.prepareStoredTunnel(ctx, storedTunnel, groupClients)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	result, err := al.storedTunnels.CreateForGroup(ctx, group.ID, storedTunnel)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if result.Persistent {
+		err = al.startPersistentTunnelOnGroup(ctx, group, result)
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		result.Health = al.storedTunnels.GetHealth(result.ID)
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
+}
+
+func (al *APIListener) handleDeleteClientGroupStoredTunnel(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	tunnelID := mux.Vars(req)["tunnel_id"]
+	group, ok := al.getClientGroupForStoredTunnels(w, req)
+	if !ok {
+		return
+	}
+
+	health, err := al.getStoredTunnelHealth(ctx, tunnelID, "", group.ID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	err = al.storedTunnels.DeleteForGroup(ctx, group.ID, tunnelID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	al.stopPersistentTunnel(tunnelID, health)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (al *APIListener) handlePutClientGroupStoredTunnel(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	tunnelID := mux.Vars(req)["tunnel_id"]
+	group, ok := al.getClientGroupForStoredTunnels(w, req)
+	if !ok {
+		return
+	}
+
+	groupClients, err := al.clientService.GetByGroups([]*cgroups.ClientGroup{group})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	storedTunnel := &storedtunnels.StoredTunnel{}
+	err = parseRequestBody(req.Body, storedTunnel)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	storedTunnel.ID = tunnelID
+
+	err = al.prepareStoredTunnel(ctx, storedTunnel, groupClients)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	health, err := al.getStoredTunnelHealth(ctx, tunnelID, "", group.ID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	result, err := al.storedTunnels.UpdateForGroup(ctx, group.ID, storedTunnel)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	al.stopPersistentTunnel(tunnelID, health)
+
+	if result.Persistent {
+		err = al.startPersistentTunnelOnGroup(ctx, group, result)
+		if err != nil {
+			al.jsonError(w, err)
+			return
+		}
+		result.Health = al.storedTunnels.GetHealth(result.ID)
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
+}
+
+func (al *APIListener) getClientGroupForStoredTunnels(w http.ResponseWriter, req *http.Request) (*cgroups.ClientGroup, bool) {
+	id := mux.Vars(req)[routes.ParamGroupID]
+	group, err := al.clientGroupProvider.Get(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to find client group[id=%q].", id), err)
+		return nil, false
+	}
+	if group == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client Group[id=%q] not found.", id))
+		return nil, false
+	}
+	return group, true
+}
+
+// getStoredTunnelHealth returns the health of a stored tunnel of the given client or client group, the tunnels of other
+// clients and groups are not touched
+func (al *APIListener) getStoredTunnelHealth(ctx context.Context, id, clientID, groupID string) ([]*storedtunnels.Health, error) {
+	existing, err := al.storedTunnels.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.ClientID != clientID || existing.ClientGroupID != groupID {
+		if groupID != "" {
+			return nil, apierrors.NewAPIError(http.StatusNotFound, "", fmt.Sprintf("stored tunnel with id %q not found", id), nil)
+		}
+		return nil, nil
+	}
+	return al.storedTunnels.GetHealth(id), nil
+}
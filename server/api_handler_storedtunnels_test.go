@@ -0,0 +1,133 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	approvalsmigration "github.com/riportdev/riport/db/migration/approvals"
+	clientsmigration "github.com/riportdev/riport/db/migration/clients"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/clients/storedtunnels"
+	"github.com/riportdev/riport/server/vault"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/test"
+)
+
+func TestHandlePostPersistentStoredTunnels(t *testing.T) {
+	admin := &users.User{
+		Username: "admin",
+		Groups:   []string{users.Administrators},
+	}
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	allowedResp, err := json.Marshal(comm.CheckTunnelAllowedResponse{IsAllowed: true})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = allowedResp
+
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Logger(testLog).Build()
+	initialTunnels := c1.GetTunnels()
+
+	clientsDB, err := sqlite.New(":memory:", clientsmigration.AssetNames(), clientsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	defer clientsDB.Close()
+
+	approvalsDB, err := sqlite.New(":memory:", approvalsmigration.AssetNames(), approvalsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	ap := approvals.NewSqliteProvider(approvalsDB)
+	defer ap.Close()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{c1}, &hour, testLog), testLog, nil),
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{
+					InternalTunnelProxyConfig: clienttunnel.InternalTunnelProxyConfig{
+						Enabled: true,
+					},
+				},
+				API: chconfig.APIConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			clientGroupProvider: mockClientGroupProvider{},
+		},
+		userService:      users.NewAPIService(users.NewStaticProvider([]*users.User{admin}), false, 0, -1),
+		approvalProvider: ap,
+		// the vault is locked until it's unlocked with its password
+		vaultManager:  vault.NewManager(nil, &vault.Aes256PassManager{}, testLog),
+		storedTunnels: storedtunnels.New(clientsDB),
+		Logger:        testLog,
+	}
+	al.initRouter()
+
+	ctx := api.WithUser(context.Background(), admin.Username)
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) *storedtunnels.StoredTunnel {
+		resp := struct {
+			Data *storedtunnels.StoredTunnel `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	var withAuthHeader *storedtunnels.StoredTunnel
+	t.Run("vault locked", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/v1/clients/client-1/stored-tunnels", `{
+			"name": "web",
+			"remote_port": 80,
+			"persistent": true,
+			"further_options": {"http_proxy": true, "auth_header_vault_id": 1}
+		}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		withAuthHeader = decode(w)
+		assert.Equal(t, admin.Username, withAuthHeader.Owner)
+		require.Len(t, withAuthHeader.Health, 1)
+		assert.Equal(t, storedtunnels.HealthFailed, withAuthHeader.Health[0].Status)
+		assert.Equal(t, "vault is locked", withAuthHeader.Health[0].Error)
+		assert.Equal(t, initialTunnels, c1.GetTunnels())
+	})
+
+	w := serve(http.MethodPost, "/api/v1/approval-policies", `{"name": "tunnels", "operation": "tunnel", "approver_groups": ["approvers"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	t.Run("approval required", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/v1/clients/client-1/stored-tunnels", `{"name": "ssh", "remote_port": 22, "persistent": true}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `tunnels require an approval by policy \"tunnels\"`)
+
+		// stored tunnels which are not started by the server are started through the approval of the tunnel
+		w = serve(http.MethodPost, "/api/v1/clients/client-1/stored-tunnels", `{"name": "ssh", "remote_port": 22}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("approval required on connect", func(t *testing.T) {
+		al.startPersistentTunnels(ctx, c1)
+
+		health := al.storedTunnels.GetHealth(withAuthHeader.ID)
+		require.Len(t, health, 1)
+		assert.Equal(t, storedtunnels.HealthFailed, health[0].Status)
+		assert.Equal(t, `tunnels require an approval by policy "tunnels", persistent tunnels are not allowed`, health[0].Error)
+		assert.Equal(t, initialTunnels, c1.GetTunnels())
+	})
+}
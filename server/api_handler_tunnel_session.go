@@ -0,0 +1,157 @@
+package chserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/rbac"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/models"
+)
+
+type TunnelSessionPayload struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handlePostTunnelSession issues a short-lived token to open a tunnel requiring a riport session
+func (al *APIListener) handlePostTunnelSession(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	clientID := vars[routes.ParamClientID]
+
+	client, err := al.clientService.GetActiveByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
+		return
+	}
+
+	tunnel := al.clientService.FindTunnel(client, vars["tunnel_id"])
+	if tunnel == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "tunnel not found")
+		return
+	}
+	if tunnel.AuthMode != models.TunnelAuthModeSession {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "tunnel does not require a riport session")
+		return
+	}
+
+	tokenStr, ok := bearer.GetBearerToken(req)
+	if !ok || tokenStr == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "a riport session is required, authenticate with a bearer token")
+		return
+	}
+	tokenCtx, err := bearer.ParseToken(tokenStr, al.config.API.JWTSecret)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusBadRequest, fmt.Errorf("token is invalid: %v", err))
+		return
+	}
+
+	token, expiresAt, err := al.tunnelSessionAuth().NewToken(tokenCtx.AppClaims.Username, tokenCtx.AppClaims.SessionID, clientID, tunnel.ID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	tunnelURL := url.URL{
+		Scheme:   "https",
+		Host:     al.tunnelProxyHostPort(req, tunnel),
+		Path:     clienttunnel.TunnelSessionAuthPath,
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	if tunnel.TunnelURL != "" {
+		if u, err := url.Parse(tunnel.TunnelURL); err == nil {
+			tunnelURL.Host = u.Host
+		}
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(TunnelSessionPayload{
+		Token:     token,
+		URL:       tunnelURL.String(),
+		ExpiresAt: expiresAt,
+	}))
+}
+
+func (al *APIListener) tunnelSessionAuth() *clienttunnel.TunnelSessionAuth {
+	return clienttunnel.NewTunnelSessionAuth(al.config.API.JWTSecret, al)
+}
+
+// tunnelProxyHostPort returns the address users reach the tunnel proxy at, the host of the API is used if tunnel_host is not set
+func (al *APIListener) tunnelProxyHostPort(req *http.Request, tunnel *clienttunnel.Tunnel) string {
+	host := al.config.Server.InternalTunnelProxyConfig.Host
+	if host == "" {
+		host = req.Host
+		if h, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = h
+		}
+	}
+	return net.JoinHostPort(host, tunnel.LocalPort)
+}
+
+// AuthorizeTunnelSession is checked on every request of a tunnel requiring a riport session. The API session must be
+// valid and the user must still have access to the client and the tunnels permission.
+func (al *APIListener) AuthorizeTunnelSession(ctx context.Context, username string, sessionID int64, clientID string) error {
+	found, apiSession, err := al.apiSessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !found || apiSession.Username != username || apiSession.ExpiresAt.Before(time.Now()) {
+		return errors.New("riport session expired")
+	}
+	if al.bannedUsers.IsBanned(username) {
+		return ErrTooManyRequests
+	}
+
+	user, err := al.userService.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", username)
+	}
+
+	clientGroups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	if err := al.clientService.CheckClientAccess(clientID, user, clientGroups); err != nil {
+		return err
+	}
+
+	if !al.userService.SupportsGroupPermissions() {
+		return nil
+	}
+	permErr := al.userService.CheckPermission(user, users.PermissionTunnels)
+	if permErr == nil {
+		return nil
+	}
+	if al.accessGrants != nil {
+		client, err := al.clientService.GetByID(clientID)
+		if err == nil && client != nil && al.accessGrants.HasClientPermission(user.Username, user.Groups, client, clientGroups, users.PermissionTunnels) {
+			return nil
+		}
+	}
+	if al.roles != nil {
+		decision, err := al.authorizeByRoles(ctx, user, rbac.ResourceTunnels, rbac.ActionRead, clientID)
+		if err == nil && decision.Allowed {
+			return nil
+		}
+	}
+	return apierrors.NewAPIError(http.StatusForbidden, "", permErr.Error(), nil)
+}
@@ -4,9 +4,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
+
 	"github.com/riportdev/riport/server/api"
 	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/server/tunnellog"
 	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
 )
 
 type TunnelPayload struct {
@@ -14,14 +19,24 @@ type TunnelPayload struct {
 	ID        string    `json:"id"`
 	ClientID  string    `json:"client_id"`
 	CreatedAt time.Time `json:"created_at"`
+	// Mode is either direct or relayed
+	Mode            string   `json:"mode"`
+	DirectAddresses []string `json:"direct_addresses,omitempty"`
+	// Connections lists the active tcp and socks5 connections
+	Connections []clienttunnel.TunnelConnection `json:"connections,omitempty"`
+	clienttunnel.TrafficStats
 }
 
 func convertToTunnelPayload(t *clienttunnel.Tunnel, clientID string) TunnelPayload {
 	return TunnelPayload{
-		Remote:    t.Remote,
-		ID:        t.ID,
-		ClientID:  clientID,
-		CreatedAt: t.CreatedAt,
+		Remote:          t.Remote,
+		ID:              t.ID,
+		ClientID:        clientID,
+		CreatedAt:       t.CreatedAt,
+		Mode:            t.Mode,
+		DirectAddresses: t.DirectAddresses,
+		Connections:     t.Connections(),
+		TrafficStats:    t.TrafficStats(),
 	}
 }
 
@@ -53,3 +68,38 @@ func (al *APIListener) handleGetTunnels(w http.ResponseWriter, req *http.Request
 
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(tunnels))
 }
+
+// handleGetTunnelConnections handles GET /clients/{client_id}/tunnels/{tunnel_id}/connections
+// The connections of closed tunnels are listed as well until the retention period is over.
+func (al *APIListener) handleGetTunnelConnections(w http.ResponseWriter, req *http.Request) {
+	if al.tunnelConnLog == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "tunnel connection log is disabled")
+		return
+	}
+
+	vars := mux.Vars(req)
+	options := query.NewOptions(req, tunnellog.ListDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, tunnellog.SupportedSorts, tunnellog.SupportedFilters, nil, &query.PaginationConfig{
+		MaxLimit:     100,
+		DefaultLimit: 20,
+	})
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	options.Filters = append(options.Filters,
+		query.FilterOption{Column: []string{"client_id"}, Values: []string{vars[routes.ParamClientID]}},
+		query.FilterOption{Column: []string{"tunnel_id"}, Values: []string{vars["tunnel_id"]}},
+	)
+
+	records, count, err := al.tunnelConnLog.List(req.Context(), options)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to get tunnel connections.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: records,
+		Meta: api.NewMeta(count),
+	})
+}
@@ -0,0 +1,370 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/clients/tunneltemplates"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/share/models"
+)
+
+// tunnelTemplateTargets are the clients a tunnel template is started on
+type tunnelTemplateTargets struct {
+	ClientIDs  []string              `json:"client_ids"`
+	GroupIDs   []string              `json:"group_ids"`
+	ClientTags *models.JobClientTags `json:"tags"`
+}
+
+func (t *tunnelTemplateTargets) GetClientIDs() (ids []string) {
+	return t.ClientIDs
+}
+
+func (t *tunnelTemplateTargets) GetGroupIDs() (ids []string) {
+	return t.GroupIDs
+}
+
+func (t *tunnelTemplateTargets) GetClientTags() (clientTags *models.JobClientTags) {
+	return t.ClientTags
+}
+
+// tunnelTemplateResult is the result of starting a tunnel template on a single client
+type tunnelTemplateResult struct {
+	ClientID   string               `json:"client_id"`
+	Success    bool                 `json:"success"`
+	Tunnel     *clienttunnel.Tunnel `json:"tunnel,omitempty"`
+	ApprovalID string               `json:"approval_id,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+func (al *APIListener) handleListTunnelTemplates(w http.ResponseWriter, req *http.Request) {
+	result, err := al.tunnelTemplates.List(req.Context(), req)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (al *APIListener) handleGetTunnelTemplate(w http.ResponseWriter, req *http.Request) {
+	t, err := al.getTunnelTemplate(req)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(t))
+}
+
+func (al *APIListener) handlePostTunnelTemplates(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	t := &tunneltemplates.TunnelTemplate{}
+	err := parseRequestBody(req.Body, t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	err = al.validateTunnelTemplate(t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	result, err := al.tunnelTemplates.Create(ctx, t, curUser.Username)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationTunnelTemplate, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(t).
+		WithID(result.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
+}
+
+func (al *APIListener) handlePutTunnelTemplate(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	id := mux.Vars(req)[routes.ParamTemplateID]
+	t := &tunneltemplates.TunnelTemplate{}
+	err := parseRequestBody(req.Body, t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	err = al.validateTunnelTemplate(t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	result, err := al.tunnelTemplates.Update(ctx, id, t)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationTunnelTemplate, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithRequest(t).
+		WithID(id).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(result))
+}
+
+func (al *APIListener) handleDeleteTunnelTemplate(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamTemplateID]
+	err := al.tunnelTemplates.Delete(req.Context(), id)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationTunnelTemplate, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostTunnelTemplateTunnels starts the tunnel of a template on all targeted clients. A failure on a client
+// doesn't stop the other clients, the result of each client is returned instead.
+func (al *APIListener) handlePostTunnelTemplateTunnels(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	t, err := al.getTunnelTemplate(req)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	targets := &tunnelTemplateTargets{}
+	err = parseRequestBody(req.Body, targets)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	clients, _, err := al.getOrderedClientsWithValidation(ctx, targets)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	clientGroups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	results := make([]*tunnelTemplateResult, 0, len(clients))
+	for _, client := range clients {
+		result := &tunnelTemplateResult{
+			ClientID: client.GetID(),
+		}
+		tunnel, approval, err := al.startTunnelFromTemplate(req, client, clientGroups, t)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.Tunnel = tunnel
+			if approval != nil {
+				result.ApprovalID = approval.ID
+			}
+		}
+		results = append(results, result)
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, &api.SuccessPayload{
+		Data: results,
+		Meta: api.NewMeta(len(results)),
+	})
+}
+
+func (al *APIListener) getTunnelTemplate(req *http.Request) (*tunneltemplates.TunnelTemplate, error) {
+	id := mux.Vars(req)[routes.ParamTemplateID]
+	t, err := al.tunnelTemplates.Get(req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, apierrors.NewAPIError(http.StatusNotFound, "", fmt.Sprintf("tunnel template with id %q not found", id), nil)
+	}
+	return t, nil
+}
+
+// validateTunnelTemplate checks the tunnel options of a template the same way as when a tunnel is created
+func (al *APIListener) validateTunnelTemplate(t *tunneltemplates.TunnelTemplate) error {
+	_, err := al.getRemoteFromTunnelTemplate(t)
+	if err != nil {
+		return apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Invalid tunnel template: %v", err), err)
+	}
+	return nil
+}
+
+// getRemoteFromTunnelTemplate returns a new remote for a template, it's created for each client as http proxies get
+// their own subdomain
+func (al *APIListener) getRemoteFromTunnelTemplate(t *tunneltemplates.TunnelTemplate) (*models.Remote, error) {
+	values, err := furtherOptionsToValues(t.FurtherOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.RemotePort != nil {
+		values.Set("remote", remoteAddr(t.RemoteIP, *t.RemotePort))
+	}
+	if t.Protocol != "" {
+		values.Set("protocol", t.Protocol)
+	}
+	if t.IdleTimeoutMinutes != nil {
+		values.Set(idleTimeoutMinutesQueryParam, strconv.Itoa(*t.IdleTimeoutMinutes))
+	}
+	if t.HTTPProxy {
+		values.Set("http_proxy", "true")
+	}
+	if t.HostHeader != "" {
+		values.Set("host_header", t.HostHeader)
+	}
+	values.Set("name", t.Name)
+	setValueIfNotEmpty(values, "scheme", t.Scheme)
+	setValueIfNotEmpty(values, "acl", t.ACL)
+
+	return al.getRemoteFromValues(values)
+}
+
+// startTunnelFromTemplate starts the tunnel of a template on a client or creates an approval request if a policy
+// requires it
+func (al *APIListener) startTunnelFromTemplate(
+	req *http.Request,
+	client *clientdata.Client,
+	clientGroups []*cgroups.ClientGroup,
+	t *tunneltemplates.TunnelTemplate,
+) (*clienttunnel.Tunnel, *approvals.Request, error) {
+	ctx := req.Context()
+	curUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = al.clientService.CheckClientsAccess([]*clientdata.Client{client}, curUser, clientGroups)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !client.IsConnected() {
+		return nil, nil, fmt.Errorf("client is disconnected")
+	}
+	if client.IsPaused() {
+		return nil, nil, fmt.Errorf("client is paused (reason = %s)", client.GetPausedReason())
+	}
+
+	remote, err := al.getRemoteFromTunnelTemplate(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = al.checkTunnelFromTemplateAllowed(client, remote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remote.Owner = curUser.Username
+	err = al.setAuthHeaderFromVault(ctx, client, remote, curUser)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	approval, err := al.createTunnelApprovalIfRequired(req, client, remote)
+	if err != nil || approval != nil {
+		return nil, approval, err
+	}
+
+	tunnels, err := al.clientService.StartClientTunnels(client, []*models.Remote{remote})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationClientTunnel, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithClient(client).
+		WithRequest(remote).
+		WithResponse(tunnels[0]).
+		WithID(tunnels[0].ID).
+		Save()
+
+	return tunnels[0], nil, nil
+}
+
+func (al *APIListener) checkTunnelFromTemplateAllowed(client *clientdata.Client, remote *models.Remote) error {
+	// the destinations of socks5 tunnels are checked by the client on every connection
+	if !remote.IsSOCKS5() {
+		allowed, err := clienttunnel.IsAllowed(remote.Remote(), client.GetConnection(), al.Log())
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("tunnel destination %s is not allowed by client configuration", remote.Remote())
+		}
+	}
+	for _, route := range remote.HTTPRoutes {
+		allowed, err := clienttunnel.IsAllowed(route.Target, client.GetConnection(), al.Log())
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("route target %s is not allowed by client configuration", route.Target)
+		}
+	}
+
+	if existing := al.clientService.FindTunnelByRemote(client, remote); existing != nil {
+		return apierrors.NewAPIError(http.StatusBadRequest, ErrCodeTunnelExist, "Tunnel already exist.", nil)
+	}
+	return nil
+}
+
+// createTunnelApprovalIfRequired creates an approval request for a tunnel on a client if a policy requires it
+func (al *APIListener) createTunnelApprovalIfRequired(req *http.Request, client *clientdata.Client, remote *models.Remote) (*approvals.Request, error) {
+	ctx := req.Context()
+	policy, err := al.getApprovalPolicy(ctx, approvals.Operation{Name: approvals.OperationTunnel}, []*clientdata.Client{client})
+	if err != nil || policy == nil {
+		return nil, err
+	}
+
+	request := &tunnelApprovalRequest{ClientID: client.GetID(), Remote: remote}
+	approval, err := al.createApprovalRequest(ctx, policy, approvals.TypeTunnel, []string{client.GetID()}, request)
+	if err != nil {
+		return nil, err
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApproval, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(request).
+		WithResponse(approval).
+		WithID(approval.ID).
+		Save()
+
+	al.notifyApprovers(ctx, policy, approval)
+
+	return approval, nil
+}
@@ -1,6 +1,7 @@
 package chserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	rportplus "github.com/riportdev/riport/plus"
 	extperm "github.com/riportdev/riport/plus/capabilities/extendedpermission"
 	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/routes"
@@ -81,6 +83,13 @@ func (al *APIListener) handleChangeUser(w http.ResponseWriter, req *http.Request
 		al.jsonError(w, err)
 		return
 	}
+	// security keys can be registered by their owners only
+	user.WebAuthnCredentials = nil
+
+	if err := al.checkAdministratorsChange(req.Context(), userID, user.Groups); err != nil {
+		al.jsonError(w, err)
+		return
+	}
 
 	if err := al.userService.Change(&user, userID); err != nil {
 		al.jsonError(w, err)
@@ -116,6 +125,41 @@ func (al *APIListener) handleChangeUser(w http.ResponseWriter, req *http.Request
 	}
 }
 
+// checkAdministratorsChange allows only admins to change administrator accounts and to add users to the
+// Administrators group. Otherwise, a role that allows to manage users would allow to become an admin.
+func (al *APIListener) checkAdministratorsChange(ctx context.Context, userID string, groups []string) error {
+	currUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if currUser.IsAdmin() {
+		return nil
+	}
+
+	if userID != "" {
+		existing, err := al.userService.GetByUsername(userID)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.IsAdmin() {
+			return errors2.APIError{
+				Message:    fmt.Sprintf("only members of %s group can change administrators", users.Administrators),
+				HTTPStatus: http.StatusForbidden,
+			}
+		}
+	}
+
+	for _, group := range groups {
+		if group == users.Administrators {
+			return errors2.APIError{
+				Message:    fmt.Sprintf("only members of %s group can add users to it", users.Administrators),
+				HTTPStatus: http.StatusForbidden,
+			}
+		}
+	}
+	return nil
+}
+
 func (al *APIListener) checkUserCount() (err error) {
 	maxUsers := al.getMaxUsers()
 
@@ -148,6 +192,11 @@ func (al *APIListener) handleDeleteUser(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	if err := al.checkAdministratorsChange(req.Context(), userID, nil); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
 	if err := al.userService.Delete(userID); err != nil {
 		al.jsonError(w, err)
 		return
@@ -179,6 +228,10 @@ func (al *APIListener) handleDeleteUsersTotP(w http.ResponseWriter, req *http.Re
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "user not found")
 		return
 	}
+	if err := al.checkAdministratorsChange(req.Context(), userID, nil); err != nil {
+		al.jsonError(w, err)
+		return
+	}
 
 	al.auditLog.Entry(auditlog.ApplicationAuthUserTotP, auditlog.ActionDelete).
 		WithHTTPRequest(req).
@@ -7,18 +7,23 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	rbacmigration "github.com/riportdev/riport/db/migration/rbac"
+	"github.com/riportdev/riport/db/sqlite"
 	"github.com/riportdev/riport/server/api"
 	"github.com/riportdev/riport/server/api/session"
 	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/bearer"
 	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/rbac"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/security"
 )
@@ -195,6 +200,140 @@ func TestShouldErrorWhenNonAdminUser(t *testing.T) {
 	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
 }
 
+func TestShouldAllowOnlyAdminsToChangeAdministrators(t *testing.T) {
+	ctx := context.Background()
+	al, _ := setupTestAPIListenerUserAPISessions(t, nil)
+	al.config.API.MaxRequestBytes = 1024 * 1024
+
+	authDB, err := sqlx.Connect("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer authDB.Close()
+	sqlExecs := []string{
+		`CREATE TABLE "users" ("username" TEXT PRIMARY KEY, "password" TEXT, "password_expired" BOOLEAN NOT NULL CHECK (password_expired IN (0, 1)) DEFAULT 0)`,
+		`INSERT INTO "users" VALUES("admin","foobaz", false)`,
+		`INSERT INTO "users" VALUES("manager","pa55word", false)`,
+		`INSERT INTO "users" VALUES("user1","pa55word", false)`,
+		`CREATE TABLE "groups" ("username" TEXT, "group" TEXT)`,
+		`INSERT INTO "groups" VALUES("admin","Administrators")`,
+		`INSERT INTO "groups" VALUES("manager","user-managers")`,
+		`INSERT INTO "groups" VALUES("user1","group1")`,
+		`CREATE TABLE "group_details" ("name" TEXT, "permissions" TEXT)`,
+		`CREATE UNIQUE INDEX "main"."username_group_name" ON "group_details" ("name" ASC)`,
+	}
+	for _, sqlExec := range sqlExecs {
+		_, err = authDB.Exec(sqlExec)
+		require.NoError(t, err)
+	}
+	userProvider, err := users.NewUserDatabase(authDB, "users", "groups", "group_details", false, false, false, false, al.Logger)
+	require.NoError(t, err)
+	al.userService = users.NewAPIService(userProvider, false, 0, -1)
+
+	rbacDB, err := sqlite.New(":memory:", rbacmigration.AssetNames(), rbacmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	al.roles, err = rbac.NewManager(ctx, rbac.NewSqliteProvider(rbacDB), userProvider)
+	require.NoError(t, err)
+	defer al.roles.Close()
+	require.NoError(t, al.roles.SaveRole(ctx, &rbac.Role{
+		Name:  "user-manager",
+		Rules: rbac.Rules{{Resource: rbac.ResourceUsers, Actions: []string{rbac.ActionRead, rbac.ActionCreate, rbac.ActionDelete}}},
+	}))
+	require.NoError(t, al.roles.AddBinding(ctx, &rbac.Binding{ID: "b1", UserGroup: "user-managers", Role: "user-manager"}))
+	al.initRouter()
+
+	cases := []struct {
+		name       string
+		username   string
+		method     string
+		path       string
+		body       string
+		statusCode int
+	}{
+		{
+			name:       "add a user to Administrators",
+			username:   "manager",
+			method:     http.MethodPut,
+			path:       "users/user1",
+			body:       `{"groups": ["group1", "Administrators"]}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "create an admin",
+			username:   "manager",
+			method:     http.MethodPost,
+			path:       "users",
+			body:       `{"username": "user2", "password": "pa55word", "groups": ["Administrators"]}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "remove an admin from Administrators",
+			username:   "manager",
+			method:     http.MethodPut,
+			path:       "users/admin",
+			body:       `{"groups": ["group1"]}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "change the password of an admin",
+			username:   "manager",
+			method:     http.MethodPut,
+			path:       "users/admin",
+			body:       `{"password": "new-pa55word"}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "delete an admin",
+			username:   "manager",
+			method:     http.MethodDelete,
+			path:       "users/admin",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "change the Administrators group",
+			username:   "manager",
+			method:     http.MethodPut,
+			path:       "user-groups/Administrators",
+			body:       `{"permissions": {"commands": true}}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "change groups of a user",
+			username:   "manager",
+			method:     http.MethodPut,
+			path:       "users/user1",
+			body:       `{"groups": ["group1", "group2"]}`,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "admin adds a user to Administrators",
+			username:   "admin",
+			method:     http.MethodPut,
+			path:       "users/user1",
+			body:       `{"groups": ["group1", "Administrators"]}`,
+			statusCode: http.StatusNoContent,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/v1/"+tc.path, strings.NewReader(tc.body))
+			password := "pa55word"
+			if tc.username == "admin" {
+				password = "foobaz"
+			}
+			req.SetBasicAuth(tc.username, password)
+
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.statusCode, w.Result().StatusCode, w.Body.String())
+		})
+	}
+
+	admin, err := userProvider.GetByUsername("admin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{users.Administrators}, admin.Groups)
+	assert.Equal(t, "foobaz", admin.Password)
+}
+
 type MockAPISessionStorageProvider struct {
 	*session.SqliteProvider
 
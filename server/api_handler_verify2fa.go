@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/webauthn"
 )
 
 func (al *APIListener) handlePostVerify2FAToken() http.Handler {
@@ -24,7 +26,7 @@ func (al *APIListener) handlePostVerify2FAToken() http.Handler {
 }
 
 func (al *APIListener) parseAndValidate2FATokenRequest(req *http.Request) (username string, err error) {
-	if !al.config.API.IsTwoFAOn() && !al.config.API.TotPEnabled {
+	if !al.config.API.IsTwoFAOn() && !al.config.API.TotPEnabled && !al.config.API.WebAuthnEnabled {
 		return "", errors2.APIError{
 			HTTPStatus: http.StatusConflict,
 			Message:    "2fa is disabled",
@@ -32,8 +34,9 @@ func (al *APIListener) parseAndValidate2FATokenRequest(req *http.Request) (usern
 	}
 
 	var reqBody struct {
-		Username string `json:"username"`
-		Token    string `json:"token"`
+		Username string                      `json:"username"`
+		Token    string                      `json:"token"`
+		WebAuthn *webauthn.AssertionResponse `json:"webauthn"`
 	}
 	err = parseRequestBody(req.Body, &reqBody)
 	if err != nil {
@@ -54,6 +57,14 @@ func (al *APIListener) parseAndValidate2FATokenRequest(req *http.Request) (usern
 		}
 	}
 
+	if reqBody.WebAuthn != nil {
+		user, err := al.get2FALoginUser(req)
+		if err != nil {
+			return reqBody.Username, err
+		}
+		return user.Username, al.validateWebAuthnAssertion(user, reqBody.WebAuthn)
+	}
+
 	if reqBody.Token == "" {
 		return reqBody.Username, errors2.APIError{
 			HTTPStatus: http.StatusUnauthorized,
@@ -61,34 +72,69 @@ func (al *APIListener) parseAndValidate2FATokenRequest(req *http.Request) (usern
 		}
 	}
 
-	if al.config.API.TotPEnabled {
-		bearerToken, bearerAuthProvided := bearer.GetBearerToken(req)
-
-		if !bearerAuthProvided {
+	if al.config.API.WebAuthnEnabled {
+		user, err := al.userService.GetByUsername(reqBody.Username)
+		if err != nil {
+			return reqBody.Username, err
+		}
+		// users with security keys must not fall back to weaker second factors
+		if user != nil && (len(user.WebAuthnCredentials) > 0 || al.isWebAuthnRequired(user)) {
 			return reqBody.Username, errors2.APIError{
-				HTTPStatus: http.StatusBadRequest,
-				Message:    "token is required",
+				HTTPStatus: http.StatusUnauthorized,
+				Message:    "login with a security key is required",
 			}
 		}
+	}
 
-		isAuthorized, token, err := al.checkBearerToken(req.Context(), bearerToken, req.URL.Path, req.Method)
+	if al.config.API.TotPEnabled {
+		user, err := al.get2FALoginUser(req)
 		if err != nil {
 			return reqBody.Username, err
 		}
+		return user.Username, al.twoFASrv.ValidateTotPCode(user, reqBody.Token)
+	}
 
-		if !isAuthorized {
-			return reqBody.Username, errors2.APIError{
-				HTTPStatus: http.StatusForbidden,
-				Message:    "access denied",
-			}
+	if !al.config.API.IsTwoFAOn() {
+		return reqBody.Username, errors2.APIError{
+			HTTPStatus: http.StatusConflict,
+			Message:    "2fa is disabled",
 		}
+	}
 
-		user, err := al.userService.GetByUsername(token.AppClaims.Username)
-		if err != nil {
-			return "", err
+	return reqBody.Username, al.twoFASrv.ValidateToken(reqBody.Username, reqBody.Token)
+}
+
+// get2FALoginUser returns the user of the bearer token issued by the password login.
+func (al *APIListener) get2FALoginUser(req *http.Request) (*users.User, error) {
+	bearerToken, bearerAuthProvided := bearer.GetBearerToken(req)
+	if !bearerAuthProvided {
+		return nil, errors2.APIError{
+			HTTPStatus: http.StatusBadRequest,
+			Message:    "token is required",
 		}
-		return token.AppClaims.Username, al.twoFASrv.ValidateTotPCode(user, reqBody.Token)
 	}
 
-	return reqBody.Username, al.twoFASrv.ValidateToken(reqBody.Username, reqBody.Token)
+	isAuthorized, token, err := al.checkBearerToken(req.Context(), bearerToken, req.URL.Path, req.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAuthorized {
+		return nil, errors2.APIError{
+			HTTPStatus: http.StatusForbidden,
+			Message:    "access denied",
+		}
+	}
+
+	user, err := al.userService.GetByUsername(token.AppClaims.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors2.APIError{
+			HTTPStatus: http.StatusUnauthorized,
+			Message:    "unauthorized",
+		}
+	}
+	return user, nil
 }
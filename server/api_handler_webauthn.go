@@ -0,0 +1,296 @@
+package chserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/routes"
+	"github.com/riportdev/riport/server/webauthn"
+	chshare "github.com/riportdev/riport/share"
+)
+
+const (
+	webAuthnDeliveryMethod      = "webauthn"
+	webAuthnLoginSession        = "login:"
+	webAuthnRegistrationSession = "registration:"
+)
+
+type WebAuthnCredentialPayload struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type webAuthnRegistrationRequest struct {
+	Name       string                         `json:"name"`
+	Credential *webauthn.RegistrationResponse `json:"credential"`
+}
+
+func newWebAuthnCredentialPayload(cred webauthn.Credential) WebAuthnCredentialPayload {
+	return WebAuthnCredentialPayload{
+		ID:         cred.ID,
+		Name:       cred.Name,
+		CreatedAt:  cred.CreatedAt,
+		LastUsedAt: cred.LastUsedAt,
+	}
+}
+
+func (al *APIListener) isWebAuthnRequired(user *users.User) bool {
+	return al.config.API.WebAuthnRequiredForAdmins && user.IsAdmin()
+}
+
+// handleWebAuthnLogin finishes a password login of a user who has to confirm it with a security key.
+// It returns false if security keys are not involved in the login of the user.
+func (al *APIListener) handleWebAuthnLogin(w http.ResponseWriter, req *http.Request, user *users.User, lifetime time.Duration) bool {
+	if !al.config.API.WebAuthnEnabled {
+		return false
+	}
+
+	loginResp := loginResponse{
+		TwoFA: &twoFAResponse{
+			DeliveryMethod: webAuthnDeliveryMethod,
+		},
+	}
+	scopes := bearer.Scopes2FaCheckOnly
+
+	if len(user.WebAuthnCredentials) > 0 {
+		challenge, err := webauthn.NewChallenge()
+		if err != nil {
+			al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+			return true
+		}
+		al.webAuthnSessions.Save(webAuthnLoginSession+user.Username, challenge)
+
+		loginResp.TwoFA.WebAuthn = al.webAuthnRP.RequestOptions(challenge, user.WebAuthnCredentials)
+		loginResp.TwoFA.WebAuthnKeyStatus = TotPKeyExists.String()
+	} else if al.isWebAuthnRequired(user) {
+		// the user can only register a security key and has to log in again afterwards
+		scopes = bearer.ScopesWebAuthnRegisterOnly
+		loginResp.TwoFA.WebAuthnKeyStatus = TotPKeyPending.String()
+	} else {
+		return false
+	}
+
+	tokenStr, err := bearer.CreateAuthToken(
+		req.Context(),
+		al.apiSessions,
+		al.config.API.JWTSecret,
+		lifetime,
+		user.Username,
+		scopes,
+		req.UserAgent(),
+		chshare.RemoteIP(req),
+	)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return true
+	}
+
+	loginResp.Token = &tokenStr
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(loginResp))
+	return true
+}
+
+func (al *APIListener) validateWebAuthnAssertion(user *users.User, assertion *webauthn.AssertionResponse) error {
+	if !al.config.API.WebAuthnEnabled {
+		return errors2.APIError{
+			Message:    "webauthn is disabled",
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+
+	challenge, ok := al.webAuthnSessions.Take(webAuthnLoginSession + user.Username)
+	if !ok {
+		return errors2.APIError{
+			Message:    "login request not found or expired",
+			HTTPStatus: http.StatusUnauthorized,
+		}
+	}
+
+	creds := append(webauthn.Credentials{}, user.WebAuthnCredentials...)
+	cred, err := al.webAuthnRP.VerifyAssertion(challenge, creds, assertion)
+	if err != nil {
+		return errors2.APIError{
+			Message:    "invalid security key assertion",
+			Err:        err,
+			HTTPStatus: http.StatusUnauthorized,
+		}
+	}
+
+	// store the new signature counter to detect cloned keys
+	if err := al.userService.Change(&users.User{WebAuthnCredentials: creds}, user.Username); err != nil {
+		return err
+	}
+
+	al.Debugf("User [%s] logged in with security key %q.", user.Username, cred.Name)
+	return nil
+}
+
+// handleGetWebAuthnCredentials handles GET /me/webauthn/credentials
+func (al *APIListener) handleGetWebAuthnCredentials(w http.ResponseWriter, req *http.Request) {
+	user, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	res := make([]WebAuthnCredentialPayload, 0, len(user.WebAuthnCredentials))
+	for _, cred := range user.WebAuthnCredentials {
+		res = append(res, newWebAuthnCredentialPayload(cred))
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+// handlePostWebAuthnRegistration handles POST /me/webauthn/registration
+func (al *APIListener) handlePostWebAuthnRegistration(w http.ResponseWriter, req *http.Request) {
+	user, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	al.webAuthnSessions.Save(webAuthnRegistrationSession+user.Username, challenge)
+
+	options := al.webAuthnRP.CreationOptions(user.Username, challenge, user.WebAuthnCredentials)
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(options))
+}
+
+// handlePostWebAuthnCredential handles POST /me/webauthn/credentials
+func (al *APIListener) handlePostWebAuthnCredential(w http.ResponseWriter, req *http.Request) {
+	var r webAuthnRegistrationRequest
+	err := parseRequestBody(req.Body, &r)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if r.Credential == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "credential is required")
+		return
+	}
+
+	user, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	challenge, ok := al.webAuthnSessions.Take(webAuthnRegistrationSession + user.Username)
+	if !ok {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "registration not found or expired, please start a new registration")
+		return
+	}
+
+	cred, err := al.webAuthnRP.VerifyRegistration(challenge, r.Credential)
+	if err != nil {
+		al.jsonErrorResponseWithDetail(w, http.StatusBadRequest, "", "invalid security key registration", err.Error())
+		return
+	}
+	if user.WebAuthnCredentials.Find(cred.ID) >= 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, "security key is already registered")
+		return
+	}
+	cred.Name = r.Name
+
+	creds := append(webauthn.Credentials{}, user.WebAuthnCredentials...)
+	creds = append(creds, *cred)
+	if err := al.userService.Change(&users.User{WebAuthnCredentials: creds}, user.Username); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthUserWebAuthn, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(user.Username).
+		WithRequest(map[string]string{"id": cred.ID, "name": cred.Name}).
+		Save()
+
+	al.Debugf("Security key %q registered for user [%s].", cred.Name, user.Username)
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(newWebAuthnCredentialPayload(*cred)))
+}
+
+// handleDeleteWebAuthnCredential handles DELETE /me/webauthn/credentials/{credential_id}
+func (al *APIListener) handleDeleteWebAuthnCredential(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routes.ParamCredentialID]
+
+	user, err := al.getUserModelForAuth(req.Context())
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if user.WebAuthnCredentials.Find(id) < 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "security key not found")
+		return
+	}
+	if len(user.WebAuthnCredentials) == 1 && al.isWebAuthnRequired(user) {
+		al.jsonErrorResponseWithTitle(w, http.StatusConflict, "administrators must log in with a security key, register another one before deleting the last one")
+		return
+	}
+
+	if err := al.userService.Change(&users.User{WebAuthnCredentials: user.WebAuthnCredentials.Remove(id)}, user.Username); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthUserWebAuthn, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(user.Username).
+		WithRequest(map[string]string{"id": id}).
+		Save()
+
+	al.Debugf("Security key %q deleted for user [%s].", id, user.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteUsersWebAuthnCredentials handles DELETE /users/{user_id}/webauthn-credentials
+func (al *APIListener) handleDeleteUsersWebAuthnCredentials(w http.ResponseWriter, req *http.Request) {
+	userID := mux.Vars(req)[routes.ParamUserID]
+
+	user, err := al.userService.GetByUsername(userID)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+	if user == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := al.checkAdministratorsChange(req.Context(), userID, nil); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	if err := al.userService.Change(&users.User{WebAuthnCredentials: webauthn.Credentials{}}, user.Username); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationAuthUserWebAuthn, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(userID).
+		Save()
+
+	al.Debugf("All security keys deleted for user [%s].", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
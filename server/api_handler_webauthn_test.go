@@ -0,0 +1,153 @@
+package chserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/webauthn"
+	"github.com/riportdev/riport/share/security"
+)
+
+func newWebAuthnTestListener(t *testing.T, usrs ...*users.User) *APIListener {
+	al := &APIListener{
+		Logger:      testLog,
+		bannedUsers: security.NewBanList(0),
+		apiSessions: newEmptyAPISessionCache(t),
+		Server: &Server{
+			config: &chconfig.Config{
+				API: chconfig.APIConfig{
+					MaxRequestBytes:           1024 * 1024,
+					WebAuthnEnabled:           true,
+					WebAuthnRequiredForAdmins: true,
+					WebAuthnRPID:              "rport.example.com",
+					WebAuthnRPOrigins:         []string{"https://rport.example.com"},
+				},
+			},
+		},
+		userService:      users.NewAPIService(users.NewStaticProvider(usrs), false, 0, -1),
+		webAuthnRP:       webauthn.NewRelyingParty("rport.example.com", "RPort", []string{"https://rport.example.com"}),
+		webAuthnSessions: webauthn.NewSessionStore(time.Minute),
+	}
+	al.initRouter()
+	return al
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	pwdHash := "$2y$05$ep2DdPDeLDDhwRrED9q/vuVEzRpZtB5WHCFT7YbcmH9r9oNmlsZOm"
+	al := newWebAuthnTestListener(t,
+		&users.User{Username: "admin", Password: pwdHash, Groups: []string{users.Administrators}},
+		&users.User{Username: "user1", Password: pwdHash, WebAuthnCredentials: webauthn.Credentials{{ID: "cred1", Name: "YubiKey"}}},
+		&users.User{Username: "user2", Password: pwdHash},
+	)
+
+	testCases := []struct {
+		Name             string
+		Username         string
+		ExpectedContains []string
+	}{
+		{
+			Name:     "admin without security key",
+			Username: "admin",
+			ExpectedContains: []string{
+				`"delivery_method":"webauthn"`,
+				`"webauthn_key_status":"pending"`,
+			},
+		},
+		{
+			Name:     "user with security key",
+			Username: "user1",
+			ExpectedContains: []string{
+				`"delivery_method":"webauthn"`,
+				`"webauthn_key_status":"exists"`,
+				`"rpId":"rport.example.com"`,
+				`"allowCredentials":[{"type":"public-key","id":"cred1"}]`,
+			},
+		},
+		{
+			Name:             "user without security key",
+			Username:         "user2",
+			ExpectedContains: []string{`"two_fa":null`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"username":"`+tc.Username+`","password":"pwd"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			for _, expected := range tc.ExpectedContains {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+		})
+	}
+}
+
+func TestWebAuthnVerify2FA(t *testing.T) {
+	pwdHash := "$2y$05$ep2DdPDeLDDhwRrED9q/vuVEzRpZtB5WHCFT7YbcmH9r9oNmlsZOm"
+	al := newWebAuthnTestListener(t,
+		&users.User{Username: "user1", Password: pwdHash, WebAuthnCredentials: webauthn.Credentials{{ID: "cred1", Name: "YubiKey"}}},
+	)
+
+	login := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"username":"user1","password":"pwd"}`))
+		req.Header.Set("Content-Type", "application/json")
+		al.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data loginResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Data.Token)
+		return *resp.Data.Token
+	}
+
+	testCases := []struct {
+		Name            string
+		Body            string
+		ExpectedStatus  int
+		ExpectedMessage string
+	}{
+		{
+			Name:            "token instead of security key",
+			Body:            `{"username":"user1","token":"123456"}`,
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedMessage: "login with a security key is required",
+		},
+		{
+			Name:            "unknown security key",
+			Body:            `{"username":"user1","webauthn":{"id":"cred2","type":"public-key","response":{}}}`,
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedMessage: "invalid security key assertion",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			token := login()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/verify-2fa", strings.NewReader(tc.Body))
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.ExpectedMessage)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/notifications"
+	"github.com/riportdev/riport/share/email"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/random"
+	"github.com/riportdev/riport/share/refs"
+)
+
+const approvalNotificationType refs.IdentifiableType = "approval"
+
+// tunnelApprovalRequest is the stored request of a tunnel waiting for an approval
+type tunnelApprovalRequest struct {
+	ClientID string         `json:"client_id"`
+	Remote   *models.Remote `json:"remote"`
+}
+
+// getApprovalPolicy returns a policy that requires an approval of a given operation on given clients or nil.
+func (al *APIListener) getApprovalPolicy(ctx context.Context, op approvals.Operation, clients []*clientdata.Client) (*approvals.Policy, error) {
+	if al.approvalProvider == nil {
+		return nil, nil
+	}
+
+	policies, err := al.approvalProvider.ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	clientGroups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range clientGroups {
+		for _, client := range clients {
+			if client.BelongsTo(group) {
+				op.ClientGroupIDs = append(op.ClientGroupIDs, group.ID)
+				break
+			}
+		}
+	}
+
+	return approvals.FindPolicy(policies, op), nil
+}
+
+// handleIfApprovalRequired creates an approval request instead of executing an operation if a policy requires it.
+// It returns true if the response was written.
+func (al *APIListener) handleIfApprovalRequired(
+	w http.ResponseWriter,
+	req *http.Request,
+	requestType string,
+	isSudo bool,
+	clients []*clientdata.Client,
+	request interface{},
+) bool {
+	operation := approvals.OperationCommand
+	if requestType == approvals.TypeTunnel {
+		operation = approvals.OperationTunnel
+	}
+
+	policy, err := al.getApprovalPolicy(req.Context(), approvals.Operation{Name: operation, IsSudo: isSudo}, clients)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "Failed to check approval policies.", err)
+		return true
+	}
+	if policy == nil {
+		return false
+	}
+
+	clientIDs := make([]string, 0, len(clients))
+	for _, client := range clients {
+		clientIDs = append(clientIDs, client.GetID())
+	}
+	al.handleApprovalRequired(w, req, policy, requestType, clientIDs, request)
+	return true
+}
+
+// handleIfClientApprovalRequired is the same as handleIfApprovalRequired for an operation on a single client.
+// Unknown clients are left to the operation itself to report.
+func (al *APIListener) handleIfClientApprovalRequired(
+	w http.ResponseWriter,
+	req *http.Request,
+	requestType string,
+	isSudo bool,
+	clientID string,
+	request interface{},
+) bool {
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil || client == nil {
+		return false
+	}
+	return al.handleIfApprovalRequired(w, req, requestType, isSudo, []*clientdata.Client{client}, request)
+}
+
+// handleApprovalRequired stores an operation as a pending approval request instead of executing it.
+func (al *APIListener) handleApprovalRequired(
+	w http.ResponseWriter,
+	req *http.Request,
+	policy *approvals.Policy,
+	requestType string,
+	clientIDs []string,
+	request interface{},
+) {
+	ctx := req.Context()
+	approval, err := al.createApprovalRequest(ctx, policy, requestType, clientIDs, request)
+	if err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationApproval, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(request).
+		WithResponse(approval).
+		WithID(approval.ID).
+		Save()
+
+	al.notifyApprovers(ctx, policy, approval)
+
+	al.writeJSONResponse(w, http.StatusAccepted, api.NewSuccessPayload(approval))
+
+	al.Debugf("Approval request[id=%q] created for %s by policy %q.", approval.ID, requestType, policy.Name)
+}
+
+func (al *APIListener) createApprovalRequest(
+	ctx context.Context,
+	policy *approvals.Policy,
+	requestType string,
+	clientIDs []string,
+	request interface{},
+) (*approvals.Request, error) {
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &approvals.Request{
+		ID:          id,
+		PolicyID:    policy.ID,
+		Type:        requestType,
+		Status:      approvals.StatusPending,
+		ClientIDs:   clientIDs,
+		Request:     raw,
+		RequestedAt: time.Now(),
+		RequestedBy: api.GetUser(ctx, al.Logger),
+	}
+	if err := al.approvalProvider.SaveRequest(ctx, approval); err != nil {
+		return nil, errors2.APIError{
+			Message:    "Failed to persist an approval request.",
+			Err:        err,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+	}
+
+	return approval, nil
+}
+
+// executeApprovedRequest runs an approved operation on behalf of the user who requested it and returns the ID of the created job or tunnel.
+func (al *APIListener) executeApprovedRequest(ctx context.Context, approval *approvals.Request) (string, error) {
+	switch approval.Type {
+	case approvals.TypeClientCommand, approvals.TypeClientScript:
+		executeInput := &api.ExecuteInput{}
+		if err := json.Unmarshal(approval.Request, executeInput); err != nil {
+			return "", err
+		}
+		resp, err := al.executeCommand(ctx, executeInput, approval.RequestedBy)
+		if err != nil {
+			return "", err
+		}
+		return resp.JID, nil
+	case approvals.TypeMultiCommand, approvals.TypeMultiScript:
+		multiJobRequest := &jobs.MultiJobRequest{}
+		if err := json.Unmarshal(approval.Request, multiJobRequest); err != nil {
+			return "", err
+		}
+		// run on clients that were approved, even if groups or tags match other clients by now
+		multiJobRequest.ClientIDs = approval.ClientIDs
+		multiJobRequest.GroupIDs = nil
+		multiJobRequest.ClientTags = nil
+		orderedClients, _, err := al.getOrderedClientsWithValidation(ctx, multiJobRequest)
+		if err != nil {
+			return "", err
+		}
+		multiJobRequest.OrderedClients = orderedClients
+		multiJobRequest.Username = approval.RequestedBy
+		multiJobRequest.IsScript = approval.Type == approvals.TypeMultiScript
+		multiJob, err := al.StartMultiClientJob(ctx, multiJobRequest)
+		if err != nil {
+			return "", err
+		}
+		return multiJob.JID, nil
+	case approvals.TypeTunnel:
+		tunnelRequest := &tunnelApprovalRequest{}
+		if err := json.Unmarshal(approval.Request, tunnelRequest); err != nil {
+			return "", err
+		}
+		client, err := al.clientService.GetActiveByID(tunnelRequest.ClientID)
+		if err != nil {
+			return "", err
+		}
+		if client == nil {
+			return "", errors2.APIError{
+				Message:    fmt.Sprintf("Active client with id=%q not found.", tunnelRequest.ClientID),
+				HTTPStatus: http.StatusNotFound,
+			}
+		}
+		if tunnelRequest.Remote.AuthHeaderVaultID != 0 {
+			requestedBy, err := al.userService.GetByUsername(approval.RequestedBy)
+			if err != nil {
+				return "", err
+			}
+			if requestedBy == nil {
+				return "", fmt.Errorf("user %q requesting the tunnel not found", approval.RequestedBy)
+			}
+			if err := al.setAuthHeaderFromVault(ctx, client, tunnelRequest.Remote, requestedBy); err != nil {
+				return "", err
+			}
+		}
+		tunnels, err := al.clientService.StartClientTunnels(client, []*models.Remote{tunnelRequest.Remote})
+		if err != nil {
+			return "", err
+		}
+		return tunnels[0].ID, nil
+	}
+
+	return "", fmt.Errorf("unknown approval request type %q", approval.Type)
+}
+
+func (al *APIListener) notifyApprovers(ctx context.Context, policy *approvals.Policy, approval *approvals.Request) {
+	var recipients []string
+	allUsers, err := al.userService.GetAll()
+	if err != nil {
+		al.Errorf("Failed to get approvers of approval request[id=%q]: %v", approval.ID, err)
+		return
+	}
+	for _, user := range allUsers {
+		if user.Username != approval.RequestedBy && policy.CanApprove(user.Groups) && isEmail(user.TwoFASendTo) {
+			recipients = append(recipients, user.TwoFASendTo)
+		}
+	}
+
+	al.sendApprovalNotification(ctx, approval, recipients,
+		fmt.Sprintf("Approval required: %s requested by %s", approval.Type, approval.RequestedBy),
+		fmt.Sprintf("User %s requested %s on clients %s.\nApproval request ID: %s\nPolicy: %s",
+			approval.RequestedBy, approval.Type, strings.Join(approval.ClientIDs, ", "), approval.ID, policy.Name),
+	)
+}
+
+func (al *APIListener) notifyRequester(ctx context.Context, approval *approvals.Request) {
+	requester, err := al.userService.GetByUsername(approval.RequestedBy)
+	if err != nil || requester == nil || !isEmail(requester.TwoFASendTo) {
+		return
+	}
+
+	content := fmt.Sprintf("Your request of %s on clients %s was %s by %s.\nApproval request ID: %s",
+		approval.Type, strings.Join(approval.ClientIDs, ", "), approval.Status, *approval.DecidedBy, approval.ID)
+	if approval.Reason != "" {
+		content += "\nReason: " + approval.Reason
+	}
+	if approval.Error != "" {
+		content += "\nError: " + approval.Error
+	}
+	al.sendApprovalNotification(ctx, approval, []string{requester.TwoFASendTo},
+		fmt.Sprintf("Approval request %s", approval.Status),
+		content,
+	)
+}
+
+func (al *APIListener) sendApprovalNotification(ctx context.Context, approval *approvals.Request, recipients []string, subject, content string) {
+	if al.notificationsDispatcher == nil || len(recipients) == 0 {
+		return
+	}
+
+	_, err := al.notificationsDispatcher.Dispatch(ctx, refs.NewIdentifiable(approvalNotificationType, approval.ID), notifications.NotificationData{
+		Target:      "smtp",
+		Recipients:  recipients,
+		Subject:     subject,
+		Content:     content,
+		ContentType: notifications.ContentTypeTextPlain,
+	})
+	if err != nil {
+		al.Errorf("Failed to send notification of approval request[id=%q]: %v", approval.ID, err)
+	}
+}
+
+func isEmail(s string) bool {
+	return s != "" && email.Validate(s) == nil
+}
@@ -3,11 +3,13 @@ package chserver
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/validation"
 	"github.com/riportdev/riport/share/models"
@@ -24,6 +26,14 @@ func (al *APIListener) handleCommandsExecutionWS(
 		uiConnTS.WriteError("Command cannot be empty.", nil)
 		return
 	}
+	if inboundMsg.Strategy != nil {
+		uiConnTS.WriteError("Execution strategies are not supported via websocket, please use the REST API.", nil)
+		return
+	}
+	if inboundMsg.DeliverOnReconnect {
+		uiConnTS.WriteError("Delivery on reconnect is not supported via websocket, please use the REST API.", nil)
+		return
+	}
 	if err := validation.ValidateInterpreter(inboundMsg.Interpreter, inboundMsg.IsScript); err != nil {
 		uiConnTS.WriteError("Invalid interpreter", err)
 		return
@@ -56,6 +66,16 @@ func (al *APIListener) handleCommandsExecutionWS(
 		return
 	}
 
+	policy, err := al.getApprovalPolicy(ctx, approvals.Operation{Name: approvals.OperationCommand, IsSudo: inboundMsg.IsSudo}, inboundMsg.OrderedClients)
+	if err != nil {
+		uiConnTS.WriteError("Could not check approval policies", err)
+		return
+	}
+	if policy != nil {
+		uiConnTS.WriteError(fmt.Sprintf("Execution requires an approval by policy %q, please use the REST API.", policy.Name), nil)
+		return
+	}
+
 	jid, err := generateNewJobID()
 	if err != nil {
 		uiConnTS.WriteError("Could not generate job id.", err)
@@ -135,6 +155,7 @@ func (al *APIListener) handleCommandsExecutionWS(
 					multiJob.IsSudo,
 					multiJob.IsScript,
 					client,
+					nil,
 				)
 			} else {
 				err := al.createAndRunJob(
@@ -149,6 +170,7 @@ func (al *APIListener) handleCommandsExecutionWS(
 					multiJob.IsSudo,
 					multiJob.IsScript,
 					client,
+					nil,
 				)
 
 				if err != nil {
@@ -187,6 +209,7 @@ func (al *APIListener) handleCommandsExecutionWS(
 			inboundMsg.IsSudo,
 			inboundMsg.IsScript,
 			client,
+			nil,
 		)
 	}
 
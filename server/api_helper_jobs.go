@@ -5,8 +5,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
+	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/jobs"
 	"github.com/riportdev/riport/server/clients/clientdata"
 	"github.com/riportdev/riport/share/comm"
@@ -18,6 +21,9 @@ import (
 
 var ErrClientNotConnected = errors.New("client is not connected")
 
+// ErrJobDeferred is returned when a job is queued to be delivered when the client reconnects
+var ErrJobDeferred = fmt.Errorf("%w, job will be delivered on reconnect", ErrClientNotConnected)
+
 var generateNewJobID = func() (string, error) {
 	return random.UUID4()
 }
@@ -35,6 +41,9 @@ type JobProvider interface {
 	CountMultiJobs(ctx context.Context, options *query.ListOptions) (int, error)
 	SaveMultiJob(multiJob *models.MultiJob) error
 	CleanupJobsMultiJobs(context.Context, int) error
+	ListPendingJobs(ctx context.Context, clientID string) ([]*models.Job, error)
+	ExpirePendingJobs(ctx context.Context, now time.Time) error
+	InterruptMultiJobs(ctx context.Context, nodeID string) (int, error)
 	Close() error
 }
 
@@ -45,6 +54,7 @@ func (al *APIListener) createAndRunJob(
 	timeoutSec int,
 	isSudo, isScript bool,
 	client *clientdata.Client,
+	deliverUntil *time.Time, // if set, a job for a disconnected client is queued until the client reconnects
 ) error {
 	curJob := models.Job{
 		JID:          jid,
@@ -77,6 +87,17 @@ func (al *APIListener) createAndRunJob(
 		err = fmt.Errorf("client is paused (reason = %s)", client.PausedReason)
 	}
 
+	if errors.Is(err, ErrClientNotConnected) && deliverUntil != nil {
+		al.Debugf("%s, Client is not connected, job will be delivered on reconnect until %s.", logPrefix, deliverUntil)
+		curJob.Status = models.JobStatusPending
+		curJob.ExpiresAt = deliverUntil
+		if dbErr := al.jobProvider.CreateJob(&curJob); dbErr != nil {
+			al.Errorf("%s, Failed to persist pending job: %v", logPrefix, dbErr)
+			return dbErr
+		}
+		return ErrJobDeferred
+	}
+
 	if err != nil {
 		al.Errorf("%s, Error on execute remote command: %v", logPrefix, err)
 
@@ -125,6 +146,23 @@ func (al *APIListener) StartMultiClientJob(ctx context.Context, multiJobRequest
 	if multiJobRequest.TimeoutSec <= 0 {
 		multiJobRequest.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
+	if multiJobRequest.DeliverExpirySec < 0 {
+		return nil, errors2.APIError{
+			Message:    "Invalid delivery expiry.",
+			Err:        errors.New("deliver_expiry_sec cannot be negative"),
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+	if multiJobRequest.DeliverOnReconnect && multiJobRequest.DeliverExpirySec == 0 {
+		multiJobRequest.DeliverExpirySec = jobs.DefaultDeliverExpirySec
+	}
+	if err := jobs.ValidateStrategy(multiJobRequest.Strategy); err != nil {
+		return nil, errors2.APIError{
+			Message:    "Invalid execution strategy.",
+			Err:        err,
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
 
 	if multiJobRequest.OrderedClients == nil {
 		// try to rebuild the ordered client list
@@ -172,12 +210,30 @@ func (al *APIListener) StartMultiClientJob(ctx context.Context, multiJobRequest
 		TimeoutSec:  multiJobRequest.TimeoutSec,
 		Concurrent:  multiJobRequest.ExecuteConcurrently,
 		AbortOnErr:  abortOnErr,
+
+		DeliverOnReconnect: multiJobRequest.DeliverOnReconnect,
+		DeliverExpirySec:   multiJobRequest.DeliverExpirySec,
+	}
+	if multiJobRequest.Strategy != nil {
+		clientIDs := make([]string, 0, len(multiJobRequest.OrderedClients))
+		for _, client := range multiJobRequest.OrderedClients {
+			clientIDs = append(clientIDs, client.GetID())
+		}
+		multiJob.Strategy = multiJobRequest.Strategy
+		multiJob.Status = models.MultiJobStatusRunning
+		multiJob.Batches = jobs.NewBatches(clientIDs, multiJobRequest.Strategy)
+		multiJob.RemainingClientIDs = clientIDs
+		multiJob.NodeID = al.cluster.ID()
 	}
 	if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
 		return nil, err
 	}
 
-	go al.executeMultiClientJob(multiJob, multiJobRequest.OrderedClients)
+	if multiJob.Strategy != nil {
+		go al.executeMultiClientJobWithStrategy(multiJob, multiJobRequest.OrderedClients)
+	} else {
+		go al.executeMultiClientJob(multiJob, multiJobRequest.OrderedClients)
+	}
 
 	return multiJob, nil
 }
@@ -214,6 +270,7 @@ func (al *APIListener) executeMultiClientJob(
 				job.IsSudo,
 				job.IsScript,
 				client,
+				job.DeliverUntil(),
 			)
 		} else {
 			err := al.createAndRunJob(
@@ -228,6 +285,7 @@ func (al *APIListener) executeMultiClientJob(
 				job.IsSudo,
 				job.IsScript,
 				client,
+				job.DeliverUntil(),
 			)
 			if err != nil {
 				if job.AbortOnErr && !errors.Is(err, ErrClientNotConnected) {
@@ -242,8 +300,11 @@ func (al *APIListener) executeMultiClientJob(
 				continue
 			}
 
-			// wait until command is finished
+			// wait until command is finished, skip results of jobs of the same multi-job delivered on reconnect
 			jobResult := <-curJobDoneChannel
+			for jobResult.JID != curJID {
+				jobResult = <-curJobDoneChannel
+			}
 			if job.AbortOnErr && jobResult.Status == models.JobStatusFailed {
 				break
 			}
@@ -253,3 +314,80 @@ func (al *APIListener) executeMultiClientJob(
 		al.testDone <- true
 	}
 }
+
+// deliverPendingJobs sends jobs that were queued while the client was disconnected.
+func (al *APIListener) deliverPendingJobs(ctx context.Context, client *clientdata.Client) {
+	// a slow client must not delay the delivery to other clients
+	al.pendingJobsLocks.Lock(client.GetID())
+	defer al.pendingJobsLocks.Unlock(client.GetID())
+
+	pending, err := al.jobProvider.ListPendingJobs(ctx, client.GetID())
+	if err != nil {
+		al.Errorf("Failed to get pending jobs of client %s: %v", client.GetID(), err)
+		return
+	}
+
+	for _, job := range pending {
+		logPrefix := job.LogPrefix()
+		now := time.Now()
+		if jobs.IsPendingJobExpired(job, now) {
+			jobs.ExpirePendingJob(job, now)
+		} else {
+			sshResp := &comm.RunCmdResponse{}
+			err := comm.SendRequestAndGetResponse(client.GetConnection(), comm.RequestTypeRunCmd, job, sshResp, al.Log())
+			if err != nil {
+				al.Errorf("%s, Error on delivering pending job: %v", logPrefix, err)
+				job.Status = models.JobStatusFailed
+				job.FinishedAt = &now
+				job.Error = err.Error()
+			} else {
+				al.Debugf("%s, Pending job was delivered to execute remote command: %q.", logPrefix, job.Command)
+				job.PID = &sshResp.Pid
+				job.StartedAt = sshResp.StartedAt
+				job.Status = models.JobStatusRunning
+			}
+		}
+
+		if err := al.jobProvider.SaveJob(job); err != nil {
+			al.Errorf("%s, Failed to persist job: %v", logPrefix, err)
+		}
+	}
+}
+
+// clientLockMap holds a lock per client, a lock is removed when nobody holds or waits for it
+type clientLockMap struct {
+	m  map[string]*clientLock
+	mu sync.Mutex
+}
+
+type clientLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (m *clientLockMap) Lock(clientID string) {
+	m.mu.Lock()
+	if m.m == nil {
+		m.m = make(map[string]*clientLock)
+	}
+	l := m.m[clientID]
+	if l == nil {
+		l = &clientLock{}
+		m.m[clientID] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+}
+
+func (m *clientLockMap) Unlock(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.m[clientID]
+	l.refs--
+	if l.refs == 0 {
+		delete(m.m, clientID)
+	}
+	l.Unlock()
+}
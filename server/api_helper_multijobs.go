@@ -0,0 +1,294 @@
+package chserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/riportdev/riport/server/api/jobs"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/models"
+)
+
+// multiJobResultWaitGrace is added to a job timeout while waiting for job results of a batch
+const multiJobResultWaitGrace = time.Minute
+
+// multiJobControl is used to pause, resume and approve a multi-client job that runs with an execution strategy.
+type multiJobControl struct {
+	mu      sync.Mutex
+	paused  bool
+	waiting chan struct{} // not nil while the job waits to be resumed or approved
+}
+
+func (c *multiJobControl) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+// Resume unpauses the job and releases it if it waits for a resume or an approval.
+// Returns false if there was nothing to resume.
+func (c *multiJobControl) Resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resumed := c.paused
+	c.paused = false
+	if c.waiting != nil {
+		close(c.waiting)
+		c.waiting = nil
+		resumed = true
+	}
+	return resumed
+}
+
+// waitIfPaused returns a channel that is closed when the paused job is resumed, nil if the job isn't paused.
+// The pause is checked under the same lock, a concurrent resume either happens before or closes the channel.
+func (c *multiJobControl) waitIfPaused() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return nil
+	}
+	if c.waiting == nil {
+		c.waiting = make(chan struct{})
+	}
+	return c.waiting
+}
+
+// wait returns a channel that is closed when the job is resumed.
+func (c *multiJobControl) wait() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiting == nil {
+		c.waiting = make(chan struct{})
+	}
+	return c.waiting
+}
+
+func (c *multiJobControl) stopWaiting() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiting = nil
+}
+
+type multiJobControlMap struct {
+	m  map[string]*multiJobControl
+	mu sync.RWMutex
+}
+
+func (m *multiJobControlMap) Set(jobID string, c *multiJobControl) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[jobID] = c
+}
+
+func (m *multiJobControlMap) Del(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, jobID)
+}
+
+func (m *multiJobControlMap) Get(jobID string) *multiJobControl {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m[jobID]
+}
+
+// executeMultiClientJobWithStrategy runs a multi-client job batch by batch as it's defined by the job strategy.
+func (al *APIListener) executeMultiClientJobWithStrategy(
+	job *models.MultiJob,
+	orderedClients []*clientdata.Client,
+) {
+	control := &multiJobControl{}
+	al.multiJobControls.Set(job.JID, control)
+	defer al.multiJobControls.Del(job.JID)
+
+	// buffered to never block a sender in client listener, even after the job is finished
+	done := make(chan *models.Job, len(orderedClients))
+	al.jobsDoneChannel.Set(job.JID, done)
+	defer al.jobsDoneChannel.Del(job.JID)
+
+	clientsByID := make(map[string]*clientdata.Client, len(orderedClients))
+	for _, client := range orderedClients {
+		clientsByID[client.GetID()] = client
+	}
+
+	status := models.MultiJobStatusFinished
+	for i, batch := range job.Batches {
+		if i > 0 {
+			prev := job.Batches[i-1]
+			if prev.Canary {
+				al.waitForCanaryApproval(job, control)
+			}
+			if resumed := control.waitIfPaused(); resumed != nil {
+				job.Status = models.MultiJobStatusPaused
+				al.saveMultiJobState(job)
+				<-resumed
+			}
+		}
+
+		clients := make([]*clientdata.Client, 0, len(batch.ClientIDs))
+		for _, id := range batch.ClientIDs {
+			clients = append(clients, clientsByID[id])
+		}
+
+		aborted := al.runMultiJobBatch(job, batch, clients, done)
+		if aborted || !jobs.CanProceed(batch, job.Strategy) {
+			al.Infof("Multi-client Job[id=%q] aborted after batch %d: %d successful, %d failed.", job.JID, batch.Index, batch.Successful, batch.Failed)
+			status = models.MultiJobStatusAborted
+			break
+		}
+	}
+
+	job.Status = status
+	job.RemainingClientIDs = jobs.RemainingClientIDs(job.Batches)
+	al.saveMultiJobState(job)
+
+	if al.testDone != nil {
+		al.testDone <- true
+	}
+}
+
+func (al *APIListener) waitForCanaryApproval(job *models.MultiJob, control *multiJobControl) {
+	job.Status = models.MultiJobStatusWaitingApproval
+	al.saveMultiJobState(job)
+
+	if job.Strategy.CanaryWaitSec > 0 {
+		timer := time.NewTimer(time.Duration(job.Strategy.CanaryWaitSec) * time.Second)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			control.stopWaiting()
+		case <-control.wait():
+		}
+		return
+	}
+
+	al.Infof("Multi-client Job[id=%q] waits for approval to proceed after the canary batch.", job.JID)
+	<-control.wait()
+}
+
+// runMultiJobBatch runs the job on all batch clients and waits for the results. Returns true if the job should be aborted.
+func (al *APIListener) runMultiJobBatch(
+	job *models.MultiJob,
+	batch *models.MultiJobBatch,
+	clients []*clientdata.Client,
+	done <-chan *models.Job,
+) (aborted bool) {
+	now := time.Now()
+	batch.StartedAt = &now
+	batch.Status = models.BatchStatusRunning
+	job.Status = models.MultiJobStatusRunning
+	job.RemainingClientIDs = jobs.RemainingClientIDs(job.Batches)
+	al.saveMultiJobState(job)
+
+	defer func() {
+		finishedAt := time.Now()
+		batch.FinishedAt = &finishedAt
+		batch.Status = models.BatchStatusSuccessful
+		if aborted || !jobs.CanProceed(batch, job.Strategy) {
+			batch.Status = models.BatchStatusFailed
+		}
+		al.saveMultiJobState(job)
+	}()
+
+	resultTimeout := time.Duration(job.TimeoutSec)*time.Second + multiJobResultWaitGrace
+	if job.Concurrent {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		pending := make(map[string]bool, len(clients))
+		for _, client := range clients {
+			wg.Add(1)
+			go func(client *clientdata.Client) {
+				defer wg.Done()
+				jid, err := al.runMultiJobOnClient(job, client)
+				mu.Lock()
+				defer mu.Unlock()
+				if errors.Is(err, ErrJobDeferred) {
+					batch.Pending++
+					return
+				}
+				if err != nil {
+					batch.Failed++
+					return
+				}
+				pending[jid] = true
+			}(client)
+		}
+		wg.Wait()
+		al.waitForJobResults(batch, done, pending, resultTimeout)
+		return false
+	}
+
+	for _, client := range clients {
+		jid, err := al.runMultiJobOnClient(job, client)
+		if errors.Is(err, ErrJobDeferred) {
+			batch.Pending++
+			continue
+		}
+		if err != nil {
+			batch.Failed++
+			if job.AbortOnErr && !errors.Is(err, ErrClientNotConnected) {
+				return true
+			}
+			continue
+		}
+		failedBefore := batch.Failed
+		al.waitForJobResults(batch, done, map[string]bool{jid: true}, resultTimeout)
+		if job.AbortOnErr && batch.Failed > failedBefore {
+			return true
+		}
+	}
+	return false
+}
+
+func (al *APIListener) runMultiJobOnClient(job *models.MultiJob, client *clientdata.Client) (string, error) {
+	jid, err := generateNewJobID()
+	if err != nil {
+		al.Errorf("multiJobID=%q, Could not generate job id: %v", job.JID, err)
+		return "", err
+	}
+	return jid, al.createAndRunJob(
+		nil,
+		&job.JID,
+		jid,
+		job.Command,
+		job.Interpreter,
+		job.CreatedBy,
+		job.Cwd,
+		job.TimeoutSec,
+		job.IsSudo,
+		job.IsScript,
+		client,
+		job.DeliverUntil(),
+	)
+}
+
+// waitForJobResults counts results of pending jobs in the batch. Jobs without a result within a given timeout are counted as failed.
+func (al *APIListener) waitForJobResults(batch *models.MultiJobBatch, done <-chan *models.Job, pending map[string]bool, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(pending) > 0 {
+		select {
+		case result := <-done:
+			if !pending[result.JID] {
+				continue
+			}
+			delete(pending, result.JID)
+			if result.Status == models.JobStatusSuccessful {
+				batch.Successful++
+			} else {
+				batch.Failed++
+			}
+		case <-timer.C:
+			batch.Failed += len(pending)
+			return
+		}
+	}
+}
+
+func (al *APIListener) saveMultiJobState(job *models.MultiJob) {
+	if err := al.jobProvider.SaveMultiJob(job); err != nil {
+		al.Errorf("multiJobID=%q, Failed to persist multi-client job state: %v", job.JID, err)
+	}
+}
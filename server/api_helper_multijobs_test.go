@@ -0,0 +1,33 @@
+package chserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiJobControlWaitIfPaused(t *testing.T) {
+	control := &multiJobControl{}
+	assert.Nil(t, control.waitIfPaused())
+
+	// a resume racing with the check of the pause never leaves the job waiting
+	for i := 0; i < 1000; i++ {
+		control.Pause()
+		resumeDone := make(chan struct{})
+		go func() {
+			control.Resume()
+			close(resumeDone)
+		}()
+
+		if resumed := control.waitIfPaused(); resumed != nil {
+			select {
+			case <-resumed:
+			case <-time.After(time.Second):
+				t.Fatal("paused job was not resumed")
+			}
+		}
+		<-resumeDone
+	}
+	assert.Nil(t, control.waitIfPaused())
+}
@@ -0,0 +1,281 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/approvals"
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/clients/storedtunnels"
+	"github.com/riportdev/riport/share/models"
+)
+
+// startPersistentTunnels starts the persistent stored tunnels of a connected client and of its client groups
+func (al *APIListener) startPersistentTunnels(ctx context.Context, client *clientdata.Client) {
+	if client.IsPaused() {
+		return
+	}
+
+	groups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		al.Errorf("Failed to get client groups to start persistent tunnels of client %s: %v", client.GetID(), err)
+		return
+	}
+	var groupIDs []string
+	for _, group := range groups {
+		if client.BelongsTo(group) {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+
+	storedTunnels, err := al.storedTunnels.ListPersistent(ctx, client.GetID(), groupIDs)
+	if err != nil {
+		al.Errorf("Failed to get persistent tunnels of client %s: %v", client.GetID(), err)
+		return
+	}
+
+	for _, st := range storedTunnels {
+		al.startPersistentTunnel(ctx, client, st)
+	}
+}
+
+// startPersistentTunnelOnGroup starts a persistent tunnel on all connected clients of a client group
+func (al *APIListener) startPersistentTunnelOnGroup(ctx context.Context, group *cgroups.ClientGroup, st *storedtunnels.StoredTunnel) error {
+	clients, err := al.clientService.GetByGroups([]*cgroups.ClientGroup{group})
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		if client.IsConnected() && !client.IsPaused() {
+			al.startPersistentTunnel(ctx, client, st)
+		}
+	}
+	return nil
+}
+
+// startPersistentTunnel starts a persistent tunnel and updates its health, errors are only shown in the health
+func (al *APIListener) startPersistentTunnel(ctx context.Context, client *clientdata.Client, st *storedtunnels.StoredTunnel) {
+	h := &storedtunnels.Health{
+		ClientID: client.GetID(),
+	}
+
+	tunnel, err := al.doStartPersistentTunnel(ctx, client, st)
+	if err != nil {
+		client.Log().Errorf("Failed to start persistent tunnel %s: %v", st.ID, err)
+		h.Status = storedtunnels.HealthFailed
+		h.Error = err.Error()
+	} else {
+		h.Status = storedtunnels.HealthRunning
+		h.TunnelID = tunnel.ID
+		h.PublicPort, _ = strconv.Atoi(tunnel.Remote.LocalPort)
+	}
+	al.storedTunnels.SetHealth(st.ID, h)
+}
+
+func (al *APIListener) doStartPersistentTunnel(ctx context.Context, client *clientdata.Client, st *storedtunnels.StoredTunnel) (*clienttunnel.Tunnel, error) {
+	publicPort, err := al.storedTunnels.GetPublicPort(ctx, st, client.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := al.getRemoteFromStoredTunnel(st, publicPort)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tunnel is re-established if the client resumed its session
+	for _, t := range client.GetTunnels() {
+		if t.Remote.StoredTunnelID == st.ID {
+			al.savePersistentTunnelPort(ctx, client, st, t)
+			return t, nil
+		}
+	}
+
+	if !remote.IsSOCKS5() {
+		allowed, err := clienttunnel.IsAllowed(remote.Remote(), client.GetConnection(), al.Log())
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, fmt.Errorf("tunnel destination %s is not allowed by client configuration", remote.Remote())
+		}
+	}
+
+	// approval policies are checked again as the client may have joined a client group since the tunnel was stored
+	err = al.checkPersistentTunnelApproval(ctx, []*clientdata.Client{client})
+	if err != nil {
+		return nil, err
+	}
+
+	remote.Owner = st.Owner
+	err = al.setPersistentTunnelAuthHeader(ctx, client, st, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicPort != 0 {
+		err = al.checkLocalPort(remote.LocalPort, remote.ListenProtocol())
+		if err != nil && st.PublicPort != nil {
+			return nil, err
+		}
+		if err != nil {
+			// the port allocated before was taken by another tunnel meanwhile, a new one is allocated
+			client.Log().Infof("Port %s of persistent tunnel %s is in use, allocating a new port", remote.LocalPort, st.ID)
+			remote.LocalHost = ""
+			remote.LocalPort = ""
+		}
+	}
+
+	tunnels, err := al.clientService.StartClientTunnels(client, []*models.Remote{remote})
+	if err != nil {
+		return nil, err
+	}
+	tunnel := tunnels[0]
+	// keeps the allocated port when the tunnel is re-established after the client resumed its session
+	tunnel.Remote.LocalPortRandom = false
+	al.savePersistentTunnelPort(ctx, client, st, tunnel)
+
+	al.auditLog.Entry(auditlog.ApplicationClientTunnel, auditlog.ActionCreate).
+		WithClient(client).
+		WithRequest(st).
+		WithResponse(tunnel).
+		WithID(tunnel.ID).
+		Save()
+
+	return tunnel, nil
+}
+
+// checkPersistentTunnelApproval rejects persistent tunnels on clients which require an approval of tunnels, there is
+// nobody to wait for the approval when the tunnel is started on connect
+func (al *APIListener) checkPersistentTunnelApproval(ctx context.Context, clients []*clientdata.Client) error {
+	policy, err := al.getApprovalPolicy(ctx, approvals.Operation{Name: approvals.OperationTunnel}, clients)
+	if err != nil {
+		return err
+	}
+	if policy != nil {
+		return apierrors.NewAPIError(http.StatusForbidden, "", fmt.Sprintf("tunnels require an approval by policy %q, persistent tunnels are not allowed", policy.Name), nil)
+	}
+	return nil
+}
+
+// setPersistentTunnelAuthHeader reads the auth header from the vault with the access rights of the owner of a
+// persistent tunnel, the start fails while the vault is locked
+func (al *APIListener) setPersistentTunnelAuthHeader(ctx context.Context, client *clientdata.Client, st *storedtunnels.StoredTunnel, remote *models.Remote) error {
+	if remote.AuthHeaderVaultID == 0 {
+		return nil
+	}
+
+	owner, err := al.userService.GetByUsername(st.Owner)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return fmt.Errorf("user %q owning the persistent tunnel not found", st.Owner)
+	}
+	return al.setAuthHeaderFromVault(ctx, client, remote, owner)
+}
+
+// resolveTunnelAuthHeader reads the auth header of a tunnel re-established on reconnect of the client from the vault
+// with the access rights of the owner of the tunnel
+func (al *APIListener) resolveTunnelAuthHeader(ctx context.Context, client *clientdata.Client, remote *models.Remote) error {
+	owner, err := al.userService.GetByUsername(remote.Owner)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return fmt.Errorf("user %q owning the tunnel not found", remote.Owner)
+	}
+	return al.setAuthHeaderFromVault(ctx, client, remote, owner)
+}
+
+func (al *APIListener) savePersistentTunnelPort(ctx context.Context, client *clientdata.Client, st *storedtunnels.StoredTunnel, t *clienttunnel.Tunnel) {
+	port, _ := strconv.Atoi(t.Remote.LocalPort)
+	err := al.storedTunnels.SavePublicPort(ctx, st, client.GetID(), port)
+	if err != nil {
+		client.Log().Errorf("Failed to save port of persistent tunnel %s: %v", st.ID, err)
+	}
+}
+
+// stopPersistentTunnel terminates a persistent tunnel on all clients it's running on
+func (al *APIListener) stopPersistentTunnel(storedTunnelID string, health []*storedtunnels.Health) {
+	if len(health) == 0 {
+		return
+	}
+	al.storedTunnels.DeleteHealth(storedTunnelID)
+
+	for _, h := range health {
+		if h.Status != storedtunnels.HealthRunning {
+			continue
+		}
+
+		client, err := al.clientService.GetActiveByID(h.ClientID)
+		if err != nil || client == nil {
+			continue
+		}
+		tunnel := al.clientService.FindTunnel(client, h.TunnelID)
+		if tunnel == nil {
+			continue
+		}
+		err = al.clientService.TerminateTunnel(client, tunnel, true)
+		if err != nil {
+			client.Log().Errorf("Failed to terminate persistent tunnel %s: %v", tunnel.ID, err)
+		}
+	}
+}
+
+// refreshPersistentTunnelHealth marks running tunnels as stopped if they were deleted meanwhile, they are started
+// again when the client connects the next time
+func (al *APIListener) refreshPersistentTunnelHealth(storedTunnels []*storedtunnels.StoredTunnel) {
+	for _, st := range storedTunnels {
+		for _, h := range st.Health {
+			if h.Status != storedtunnels.HealthRunning {
+				continue
+			}
+			client, err := al.clientService.GetActiveByID(h.ClientID)
+			if err == nil && client != nil && al.clientService.FindTunnel(client, h.TunnelID) != nil {
+				continue
+			}
+			h.Status = storedtunnels.HealthStopped
+			h.TunnelID = ""
+		}
+	}
+}
+
+// getRemoteFromStoredTunnel returns the remote of a stored tunnel, the further options are the query params of
+// PUT /clients/{client_id}/tunnels
+func (al *APIListener) getRemoteFromStoredTunnel(st *storedtunnels.StoredTunnel, publicPort int) (*models.Remote, error) {
+	values, err := furtherOptionsToValues(st.FurtherOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// persistent tunnels aren't closed when idle unless requested
+	if !values.Has(idleTimeoutMinutesQueryParam) && !values.Has(skipIdleTimeoutQueryParam) {
+		values.Set(skipIdleTimeoutQueryParam, "true")
+	}
+
+	if st.RemotePort != nil {
+		values.Set("remote", remoteAddr(st.RemoteIP, *st.RemotePort))
+	}
+	if publicPort != 0 {
+		values.Set("local", strconv.Itoa(publicPort))
+	}
+	values.Set("name", st.Name)
+	setValueIfNotEmpty(values, "scheme", st.Scheme)
+	setValueIfNotEmpty(values, "acl", st.ACL)
+
+	remote, err := al.getRemoteFromValues(values)
+	if err != nil {
+		return nil, err
+	}
+	remote.StoredTunnelID = st.ID
+
+	return remote, nil
+}
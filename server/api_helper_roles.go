@@ -0,0 +1,134 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/rbac"
+	"github.com/riportdev/riport/server/routes"
+)
+
+// clientAccessCheckers gives access to a client if one of the checkers does.
+type clientAccessCheckers []clients.AccessGrantChecker
+
+func (c clientAccessCheckers) HasClientAccess(username string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool {
+	for _, checker := range c {
+		if checker.HasClientAccess(username, userGroups, client, clientGroups) {
+			return true
+		}
+	}
+	return false
+}
+
+var permissionResources = map[string]string{
+	users.PermissionTunnels:    rbac.ResourceTunnels,
+	users.PermissionCommands:   rbac.ResourceCommands,
+	users.PermissionScripts:    rbac.ResourceScripts,
+	users.PermissionVault:      rbac.ResourceVault,
+	users.PermissionScheduler:  rbac.ResourceSchedules,
+	users.PermissionMonitoring: rbac.ResourceMonitoring,
+	users.PermissionUploads:    rbac.ResourceUploads,
+	users.PermissionsAuditLog:  rbac.ResourceAuditLog,
+}
+
+// requestedAccess returns the resource type and the action of a request to a route guarded by a given permission.
+func requestedAccess(permission string, r *http.Request) (resource, action string) {
+	resource = permissionResources[permission]
+	if strings.Contains(r.URL.Path, "/library/") {
+		resource = rbac.ResourceLibrary
+	}
+
+	switch {
+	case strings.Contains(r.URL.Path, "/ws/"):
+		// web sockets are opened with GET but execute commands, scripts and uploads
+		if resource == rbac.ResourceUploads {
+			return resource, rbac.ActionCreate
+		}
+		return resource, rbac.ActionExecute
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return resource, rbac.ActionRead
+	case r.Method == http.MethodDelete:
+		return resource, rbac.ActionDelete
+	case r.Method == http.MethodPost && (resource == rbac.ResourceCommands || resource == rbac.ResourceScripts):
+		return resource, rbac.ActionExecute
+	default:
+		return resource, rbac.ActionCreate
+	}
+}
+
+// authorizeByRoles decides on an action using the roles of the user.
+// A client, if given, is passed on, so roles scoped to client groups can apply.
+func (al *APIListener) authorizeByRoles(ctx context.Context, currUser *users.User, resource, action, clientID string) (*rbac.Decision, error) {
+	req := rbac.Request{
+		Resource: resource,
+		Action:   action,
+	}
+
+	if clientID != "" {
+		client, err := al.clientService.GetByID(clientID)
+		if err != nil {
+			return nil, err
+		}
+		if client != nil {
+			clientGroups, err := al.clientGroupProvider.GetAll(ctx)
+			if err != nil {
+				return nil, err
+			}
+			req.Client = client
+			req.ClientGroups = clientGroups
+		}
+	}
+
+	return al.roles.Authorize(currUser, req)
+}
+
+func accessDeniedTitle(resource string) string {
+	return fmt.Sprintf("current user should belong to %s group or have a role that allows to access %s", users.Administrators, resource)
+}
+
+// rolesMiddleware guards routes of a resource type that are available to admins only unless a role allows the action.
+func (al *APIListener) rolesMiddleware(resource string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if al.insecureForTests {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			currUser, err := al.getUserModelForAuth(r.Context())
+			if err != nil {
+				al.jsonError(w, err)
+				return
+			}
+			if currUser.IsAdmin() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if al.roles != nil {
+				_, action := requestedAccess("", r)
+				decision, err := al.authorizeByRoles(r.Context(), currUser, resource, action, mux.Vars(r)[routes.ParamClientID])
+				if err != nil {
+					al.jsonError(w, err)
+					return
+				}
+				if decision.Allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+				al.jsonErrorResponseWithDetail(w, http.StatusForbidden, "", accessDeniedTitle(resource), decision.String())
+				return
+			}
+
+			al.jsonErrorResponseWithTitle(w, http.StatusForbidden, accessDeniedTitle(resource))
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package chserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/rbac"
+)
+
+func TestRequestedAccess(t *testing.T) {
+	testCases := []struct {
+		Method           string
+		URL              string
+		Permission       string
+		ExpectedResource string
+		ExpectedAction   string
+	}{
+		{"GET", "/api/v1/clients/c1/tunnels", users.PermissionTunnels, rbac.ResourceTunnels, rbac.ActionRead},
+		{"PUT", "/api/v1/clients/c1/tunnels", users.PermissionTunnels, rbac.ResourceTunnels, rbac.ActionCreate},
+		{"DELETE", "/api/v1/clients/c1/tunnels/t1", users.PermissionTunnels, rbac.ResourceTunnels, rbac.ActionDelete},
+		{"POST", "/api/v1/commands", users.PermissionCommands, rbac.ResourceCommands, rbac.ActionExecute},
+		{"POST", "/api/v1/library/commands", users.PermissionCommands, rbac.ResourceLibrary, rbac.ActionCreate},
+		{"GET", "/api/v1/ws/scripts", users.PermissionScripts, rbac.ResourceScripts, rbac.ActionExecute},
+		{"GET", "/api/v1/ws/uploads", users.PermissionUploads, rbac.ResourceUploads, rbac.ActionCreate},
+		{"PUT", "/api/v1/schedules/s1", users.PermissionScheduler, rbac.ResourceSchedules, rbac.ActionCreate},
+		{"GET", "/api/v1/auditlog", users.PermissionsAuditLog, rbac.ResourceAuditLog, rbac.ActionRead},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.Method, tc.URL, nil)
+
+		resource, action := requestedAccess(tc.Permission, req)
+
+		assert.Equal(t, tc.ExpectedResource, resource, tc.URL)
+		assert.Equal(t, tc.ExpectedAction, action, tc.URL)
+	}
+}
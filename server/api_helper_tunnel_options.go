@@ -0,0 +1,90 @@
+package chserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	apierrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/types"
+)
+
+// getRemoteFromValues returns a remote from the query params of PUT /clients/{client_id}/tunnels, it's used to start
+// tunnels that are defined on the server instead of being requested by a user
+func (al *APIListener) getRemoteFromValues(values url.Values) (*models.Remote, error) {
+	scheme := values.Get("scheme")
+	if len(scheme) > URISchemeMaxLength {
+		return nil, apierrors.NewAPIError(http.StatusBadRequest, ErrCodeURISchemeLengthExceed, "Invalid URI scheme.", nil)
+	}
+	req := &http.Request{URL: &url.URL{RawQuery: values.Encode()}}
+
+	remote, err := getRemoteFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	remote.Name = values.Get("name")
+	if scheme != "" {
+		remote.Scheme = &scheme
+	}
+
+	err = al.setOptionsForRemote(req, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	acl := values.Get("acl")
+	if acl != "" {
+		if _, err = clienttunnel.ParseTunnelACL(acl); err != nil {
+			return nil, apierrors.NewAPIError(http.StatusBadRequest, ErrCodeInvalidACL, fmt.Sprintf("Invalid ACL: %s", err), nil)
+		}
+		remote.ACL = &acl
+	}
+
+	return remote, nil
+}
+
+// furtherOptionsToValues converts further options given as a JSON object to query params
+func furtherOptionsToValues(furtherOptions *types.JSONString) (url.Values, error) {
+	values := url.Values{}
+	if furtherOptions == nil || *furtherOptions == "" {
+		return values, nil
+	}
+
+	options := make(map[string]interface{})
+	err := json.Unmarshal([]byte(*furtherOptions), &options)
+	if err != nil {
+		return nil, apierrors.NewAPIError(http.StatusBadRequest, "", "further_options must be an object", err)
+	}
+	for name, v := range options {
+		switch value := v.(type) {
+		case nil:
+		case string:
+			values.Set(name, value)
+		case bool:
+			values.Set(name, strconv.FormatBool(value))
+		case float64:
+			values.Set(name, strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			return nil, apierrors.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid value of further option %q", name), nil)
+		}
+	}
+	return values, nil
+}
+
+func remoteAddr(ip *string, port int) string {
+	addr := strconv.Itoa(port)
+	if ip != nil && *ip != "" {
+		addr = *ip + ":" + addr
+	}
+	return addr
+}
+
+func setValueIfNotEmpty(values url.Values, name string, value *string) {
+	if value != nil && *value != "" {
+		values.Set(name, *value)
+	}
+}
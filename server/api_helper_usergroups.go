@@ -1,11 +1,14 @@
 package chserver
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 
 	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
 	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/auditlog"
 )
@@ -37,6 +40,11 @@ func (al *APIListener) handleUpdateUserGroup(w http.ResponseWriter, req *http.Re
 	vars := mux.Vars(req)
 	name := vars["group_name"]
 
+	if err := al.checkAdministratorsGroupChange(req.Context(), name); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
 	var input users.Group
 	err := parseRequestBody(req.Body, &input)
 	if err != nil {
@@ -64,6 +72,11 @@ func (al *APIListener) handleDeleteUserGroup(w http.ResponseWriter, req *http.Re
 	vars := mux.Vars(req)
 	name := vars["group_name"]
 
+	if err := al.checkAdministratorsGroupChange(req.Context(), name); err != nil {
+		al.jsonError(w, err)
+		return
+	}
+
 	err := al.userService.DeleteGroup(name)
 	if err != nil {
 		al.jsonError(w, err)
@@ -77,3 +90,21 @@ func (al *APIListener) handleDeleteUserGroup(w http.ResponseWriter, req *http.Re
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// checkAdministratorsGroupChange allows only admins to change the Administrators group
+func (al *APIListener) checkAdministratorsGroupChange(ctx context.Context, name string) error {
+	if name != users.Administrators {
+		return nil
+	}
+	currUser, err := al.getUserModelForAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if !currUser.IsAdmin() {
+		return errors2.APIError{
+			Message:    fmt.Sprintf("only members of %s group can change it", users.Administrators),
+			HTTPStatus: http.StatusForbidden,
+		}
+	}
+	return nil
+}
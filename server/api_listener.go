@@ -21,8 +21,12 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jpillora/requestlog"
 
+	accessgrantsmigration "github.com/riportdev/riport/db/migration/access_grants"
 	"github.com/riportdev/riport/db/migration/api_token"
+	approvalsmigration "github.com/riportdev/riport/db/migration/approvals"
 	"github.com/riportdev/riport/db/migration/library"
+	rbacmigration "github.com/riportdev/riport/db/migration/rbac"
+	scimmigration "github.com/riportdev/riport/db/migration/scim"
 	"github.com/riportdev/riport/db/sqlite"
 	rportplus "github.com/riportdev/riport/plus"
 	"github.com/riportdev/riport/server/notifications"
@@ -34,14 +38,20 @@ import (
 	"github.com/riportdev/riport/server/api/authorization"
 	"github.com/riportdev/riport/server/api/session"
 	"github.com/riportdev/riport/server/clients/storedtunnels"
+	"github.com/riportdev/riport/server/clients/tunneltemplates"
 	"github.com/riportdev/riport/server/script"
 
+	"github.com/riportdev/riport/server/accessgrants"
 	"github.com/riportdev/riport/server/api"
 	"github.com/riportdev/riport/server/api/command"
 	"github.com/riportdev/riport/server/api/message"
 	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/approvals"
 	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/rbac"
+	"github.com/riportdev/riport/server/scim"
 	"github.com/riportdev/riport/server/vault"
+	"github.com/riportdev/riport/server/webauthn"
 
 	extperm "github.com/riportdev/riport/plus/capabilities/extendedpermission"
 	chshare "github.com/riportdev/riport/share"
@@ -75,17 +85,26 @@ type APIListener struct {
 
 	testDone chan bool // is used only in tests to be able to wait until async task is done
 
-	userService    UserService
-	vaultManager   *vault.Manager
-	scriptManager  *script.Manager
-	tokenManager   *authorization.Manager
-	commandManager *command.Manager
-	storedTunnels  *storedtunnels.Manager
-
-	notificationsStorage   notificationsSQLite.Repository
-	notificationsProcessor notifications.Processor
-	notificationsDB        *sqlx.DB
-	notificationsCleaner   notificationsSQLite.Closeable
+	userService     UserService
+	vaultManager    *vault.Manager
+	scriptManager   *script.Manager
+	tokenManager    *authorization.Manager
+	commandManager  *command.Manager
+	storedTunnels   *storedtunnels.Manager
+	tunnelTemplates *tunneltemplates.Manager
+
+	approvalProvider approvals.Provider
+	accessGrants     *accessgrants.Manager
+	roles            *rbac.Manager
+	scimService      *scim.Service
+	webAuthnRP       *webauthn.RelyingParty
+	webAuthnSessions *webauthn.SessionStore
+
+	notificationsStorage    notificationsSQLite.Repository
+	notificationsProcessor  notifications.Processor
+	notificationsDispatcher notifications.Dispatcher
+	notificationsDB         *sqlx.DB
+	notificationsCleaner    notificationsSQLite.Closeable
 
 	mu sync.RWMutex
 }
@@ -201,6 +220,30 @@ func NewAPIListener(
 		return nil, fmt.Errorf("failed init api_token DB instance: %w", err)
 	}
 
+	approvalsDb, err := sqlite.New(
+		path.Join(config.Server.DataDir, "approvals.db"),
+		approvalsmigration.AssetNames(),
+		approvalsmigration.Asset,
+		config.Server.GetSQLiteDataSourceOptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed init approvals DB instance: %w", err)
+	}
+
+	accessGrantsDb, err := sqlite.New(
+		path.Join(config.Server.DataDir, "access_grants.db"),
+		accessgrantsmigration.AssetNames(),
+		accessgrantsmigration.Asset,
+		config.Server.GetSQLiteDataSourceOptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed init access grants DB instance: %w", err)
+	}
+	accessGrants, err := accessgrants.NewManager(ctx, accessgrants.NewSqliteProvider(accessGrantsDb))
+	if err != nil {
+		return nil, err
+	}
+
 	scriptLogger := logger.NewLogger("scripts", config.Logging.LogOutput, config.Logging.LogLevel)
 	scriptProvider := script.NewSqliteProvider(libraryDb)
 	scriptManager := script.NewManager(scriptProvider, scriptLogger)
@@ -216,6 +259,35 @@ func NewAPIListener(
 		return nil, fmt.Errorf("failed init api users service: %w", err)
 	}
 
+	rbacDb, err := sqlite.New(
+		path.Join(config.Server.DataDir, "rbac.db"),
+		rbacmigration.AssetNames(),
+		rbacmigration.Asset,
+		config.Server.GetSQLiteDataSourceOptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed init rbac DB instance: %w", err)
+	}
+	roles, err := rbac.NewManager(ctx, rbac.NewSqliteProvider(rbacDb), userService)
+	if err != nil {
+		return nil, err
+	}
+	server.clientService.GetRepo().SetAccessGrantChecker(clientAccessCheckers{accessGrants, roles})
+
+	scimDb, err := sqlite.New(
+		path.Join(config.Server.DataDir, "scim.db"),
+		scimmigration.AssetNames(),
+		scimmigration.Asset,
+		config.Server.GetSQLiteDataSourceOptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed init scim DB instance: %w", err)
+	}
+	scimService, err := scim.NewService(ctx, scim.NewSqliteProvider(scimDb), userService, config.API.IsTwoFAOn())
+	if err != nil {
+		return nil, err
+	}
+
 	var HTTPServerOptions []chshare.ServerOption
 	if config.API.CertFile != "" && config.API.KeyFile != "" {
 		HTTPServerOptions = []chshare.ServerOption{chshare.WithTLS(config.API.CertFile, config.API.KeyFile, security.TLSConfig(config.API.TLSMin))}
@@ -235,22 +307,28 @@ func NewAPIListener(
 
 	allog := logger.NewLogger("api-listener", config.Logging.LogOutput, config.Logging.LogLevel)
 	a := &APIListener{
-		Server:                 server,
-		Logger:                 allog,
-		fingerprint:            fingerprint,
-		httpServer:             chshare.NewHTTPServer(int(config.API.MaxRequestBytes), allog, HTTPServerOptions...),
-		requestLogOptions:      config.InitRequestLogOptions(),
-		bannedUsers:            security.NewBanList(time.Duration(config.API.UserLoginWait) * time.Second),
-		userService:            userService,
-		vaultManager:           vault.NewManager(vaultDBProviderFactory, &vault.Aes256PassManager{}, vaultLogger),
-		scriptManager:          scriptManager,
-		commandManager:         commandManager,
-		tokenManager:           tokenManager,
-		storedTunnels:          storedtunnels.New(server.clientDB),
-		notificationsStorage:   store,
-		notificationsProcessor: notificationProcessor,
-		notificationsDB:        db,
-		notificationsCleaner:   notificationsCleaner,
+		Server:                  server,
+		Logger:                  allog,
+		fingerprint:             fingerprint,
+		httpServer:              chshare.NewHTTPServer(int(config.API.MaxRequestBytes), allog, HTTPServerOptions...),
+		requestLogOptions:       config.InitRequestLogOptions(),
+		bannedUsers:             security.NewBanList(time.Duration(config.API.UserLoginWait) * time.Second),
+		userService:             userService,
+		vaultManager:            vault.NewManager(vaultDBProviderFactory, &vault.Aes256PassManager{}, vaultLogger),
+		scriptManager:           scriptManager,
+		commandManager:          commandManager,
+		tokenManager:            tokenManager,
+		storedTunnels:           storedtunnels.New(server.clientDB),
+		tunnelTemplates:         tunneltemplates.New(server.clientDB),
+		approvalProvider:        approvals.NewSqliteProvider(approvalsDb),
+		accessGrants:            accessGrants,
+		roles:                   roles,
+		scimService:             scimService,
+		notificationsStorage:    store,
+		notificationsProcessor:  notificationProcessor,
+		notificationsDispatcher: notifications.NewDispatcher(store),
+		notificationsDB:         db,
+		notificationsCleaner:    notificationsCleaner,
 	}
 
 	a.errResponseLogger = allog.Fork("error-response")
@@ -303,6 +381,12 @@ func NewAPIListener(
 		a.Logger.Infof("2FA is enabled via an Authenticator app")
 	}
 
+	if config.API.WebAuthnEnabled {
+		a.webAuthnRP = webauthn.NewRelyingParty(config.API.WebAuthnRPID, config.API.WebAuthnRPName, config.API.WebAuthnRPOrigins)
+		a.webAuthnSessions = webauthn.NewSessionStore(webauthn.DefaultTimeout)
+		a.Logger.Infof("2FA is enabled via security keys for %s", config.API.WebAuthnRPID)
+	}
+
 	if config.API.MaxFailedLogin > 0 && config.API.BanTime > 0 {
 		a.bannedIPs = security.NewMaxBadAttemptsBanList(
 			config.API.MaxFailedLogin,
@@ -376,6 +460,18 @@ func (al *APIListener) Close() error {
 	if al.apiSessions != nil {
 		g.Go(al.apiSessions.Close)
 	}
+	if al.approvalProvider != nil {
+		g.Go(al.approvalProvider.Close)
+	}
+	if al.accessGrants != nil {
+		g.Go(al.accessGrants.Close)
+	}
+	if al.roles != nil {
+		g.Go(al.roles.Close)
+	}
+	if al.scimService != nil {
+		g.Go(al.scimService.Close)
+	}
 
 	g.Go(al.notificationsStorage.Close)
 	g.Go(al.notificationsProcessor.Close)
@@ -396,6 +492,11 @@ var ErrThatTokenHasExpired = errors.New("the provided token has expired")
 
 // lookupUser is used to get the user on every request in auth middleware
 func (al *APIListener) lookupUser(r *http.Request, isBearerOnly bool) (authorized bool, username string, err error) {
+	// requests forwarded by another node of the cluster were authorized there already
+	if forwardedUser, ok := al.Server.cluster.ForwardedUser(r); ok {
+		return true, forwardedUser, nil
+	}
+
 	if !isBearerOnly {
 		if basicUser, basicPwd, basicAuthProvided := r.BasicAuth(); basicAuthProvided {
 			return al.handleBasicAuth(r.Context(), r.Method, r.URL.Path, basicUser, basicPwd)
@@ -433,7 +534,7 @@ func (al *APIListener) handleBasicAuth(ctx context.Context, httpverb, urlpath, u
 	if err != nil {
 		return false, username, fmt.Errorf("failed to get user: %v", err)
 	}
-	if user == nil {
+	if user == nil || al.isUserDeactivated(username) {
 		return false, username, nil
 	}
 
@@ -441,8 +542,8 @@ func (al *APIListener) handleBasicAuth(ctx context.Context, httpverb, urlpath, u
 		return false, username, ErrThatPasswordHasExpired
 	}
 
-	// skip basic auth with password when 2fa is enabled
-	if !al.config.API.IsTwoFAOn() && !al.config.API.TotPEnabled {
+	// skip basic auth with password when 2fa or security keys are enabled
+	if !al.config.API.IsTwoFAOn() && !al.config.API.TotPEnabled && !al.config.API.WebAuthnEnabled {
 		passwordOk := verifyPassword(user.Password, password)
 		if passwordOk {
 			return true, username, nil
@@ -552,7 +653,7 @@ func (al *APIListener) validateCredentials(username, password string, skipPasswo
 		}
 	}
 
-	if user == nil {
+	if user == nil || al.isUserDeactivated(username) {
 		return false, user, nil
 	}
 
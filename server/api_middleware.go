@@ -72,6 +72,17 @@ func (al *APIListener) wrapTotPEnabledMiddleware(next http.HandlerFunc) http.Han
 	}
 }
 
+func (al *APIListener) wrapWebAuthnEnabledMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !al.config.API.WebAuthnEnabled {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "WebAuthn is disabled")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
 func (al *APIListener) wrapWithAuthMiddleware(isBearerOnly bool) mux.MiddlewareFunc {
 	return func(f http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -192,10 +203,10 @@ func (al *APIListener) permissionsMiddleware(permission string) mux.MiddlewareFu
 
 			if al.userService.SupportsGroupPermissions() {
 				// Check group permissions only if supported otherwise let pass.
-				err = al.userService.CheckPermission(currUser, permission)
-				if err != nil {
-					al.jsonError(w, err)
-					return
+				if err := al.userService.CheckPermission(currUser, permission); err != nil && !al.hasAccessGrantPermission(r, currUser, permission) {
+					if !al.checkRolesForPermission(w, r, currUser, permission, err) {
+						return
+					}
 				}
 				if rportplus.IsPlusEnabled(al.config.PlusConfig) &&
 					(permission == users.PermissionTunnels ||
@@ -225,6 +236,48 @@ func (al *APIListener) permissionsMiddleware(permission string) mux.MiddlewareFu
 	}
 }
 
+// checkRolesForPermission returns true if a role of the user allows a request the user lacks a permission for.
+// Otherwise, it responds with the reasons why the access was denied.
+func (al *APIListener) checkRolesForPermission(w http.ResponseWriter, r *http.Request, currUser *users.User, permission string, permErr error) bool {
+	if al.roles == nil {
+		al.jsonError(w, permErr)
+		return false
+	}
+
+	resource, action := requestedAccess(permission, r)
+	decision, err := al.authorizeByRoles(r.Context(), currUser, resource, action, mux.Vars(r)[routes.ParamClientID])
+	if err != nil {
+		al.jsonError(w, err)
+		return false
+	}
+	if !decision.Allowed {
+		al.jsonErrorResponseWithDetail(w, http.StatusForbidden, "", permErr.Error(), decision.String())
+		return false
+	}
+	return true
+}
+
+// hasAccessGrantPermission returns whether an access grant gives a user a permission on the client of a given request.
+// Access grants extend permissions only on client routes.
+func (al *APIListener) hasAccessGrantPermission(r *http.Request, currUser *users.User, permission string) bool {
+	clientID := mux.Vars(r)[routes.ParamClientID]
+	if clientID == "" || al.accessGrants == nil {
+		return false
+	}
+
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil || client == nil {
+		return false
+	}
+	clientGroups, err := al.clientGroupProvider.GetAll(r.Context())
+	if err != nil {
+		al.Errorf("Failed to get client groups: %v", err)
+		return false
+	}
+
+	return al.accessGrants.HasClientPermission(currUser.Username, currUser.Groups, client, clientGroups, permission)
+}
+
 func (al *APIListener) updateTokenAccess(ctx context.Context, token string, accessTime time.Time, userAgent string, remoteAddress string) (err error) {
 	tokenCtx, err := bearer.ParseToken(token, al.config.API.JWTSecret)
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/riportdev/riport/plus/capabilities/oauth"
 	"github.com/riportdev/riport/server/api/middleware"
 	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/rbac"
 	"github.com/riportdev/riport/server/routes"
 	"github.com/riportdev/riport/share/security"
 )
@@ -41,6 +42,7 @@ func (al *APIListener) initRouter() {
 	secureAPI.HandleFunc("/me", al.handleGetMe).Methods(http.MethodGet)
 	secureAPI.HandleFunc("/me", al.handleChangeMe).Methods(http.MethodPut)
 	secureAPI.HandleFunc("/me/ip", al.handleGetIP).Methods(http.MethodGet)
+	secureAPI.HandleFunc("/me/access", al.handleGetMeAccess).Methods(http.MethodGet)
 
 	secureAPI.HandleFunc("/me/token", al.handleTokenGone).Methods(http.MethodGet)
 	secureAPI.HandleFunc("/me/token", al.handleTokenGone).Methods(http.MethodPost)
@@ -53,7 +55,10 @@ func (al *APIListener) initRouter() {
 	secureAPI.HandleFunc("/me/tokens/{prefix}", al.handleDeleteToken).Methods(http.MethodDelete)
 
 	secureAPI.HandleFunc("/clients", al.handleGetClients).Methods(http.MethodGet)
+	secureAPI.Handle("/clients-search", al.permissionsMiddleware(users.PermissionMonitoring)(http.HandlerFunc(al.handleSearchClients))).Methods(http.MethodGet)
+	secureAPI.HandleFunc("/clients-attributes", al.handlePostClientsAttributes).Methods(http.MethodPost)
 	clientDetails := secureAPI.PathPrefix("/clients/{client_id}").Subrouter()
+	clientDetails.Use(al.wrapClusterForwardMiddleware)
 	clientDetails.Use(al.wrapClientAccessMiddleware)
 	clientDetails.HandleFunc("", al.handleGetClient).Methods(http.MethodGet)
 	clientDetails.HandleFunc("", al.handleDeleteClient).Methods(http.MethodDelete)
@@ -76,6 +81,8 @@ func (al *APIListener) initRouter() {
 	clientTunnels.HandleFunc("/tunnels", al.handlePutClientTunnel).Methods(http.MethodPut)
 	clientTunnels.HandleFunc("/tunnels/{tunnel_id}", al.handleDeleteClientTunnel).Methods(http.MethodDelete)
 	clientTunnels.HandleFunc("/tunnels/{tunnel_id}/acl", al.handlePutClientTunnelACL).Methods(http.MethodPut)
+	clientTunnels.HandleFunc("/tunnels/{tunnel_id}/session", al.handlePostTunnelSession).Methods(http.MethodPost)
+	clientTunnels.HandleFunc("/tunnels/{tunnel_id}/connections", al.handleGetTunnelConnections).Methods(http.MethodGet)
 	clientTunnels.HandleFunc("/stored-tunnels", al.handleGetStoredTunnels).Methods(http.MethodGet)
 	clientTunnels.HandleFunc("/stored-tunnels", al.handlePostStoredTunnels).Methods(http.MethodPost)
 	clientTunnels.HandleFunc("/stored-tunnels/{tunnel_id}", al.handleDeleteStoredTunnel).Methods(http.MethodDelete)
@@ -84,6 +91,10 @@ func (al *APIListener) initRouter() {
 	clientMonitoring := clientDetails.NewRoute().Subrouter()
 	clientMonitoring.Use(al.permissionsMiddleware(users.PermissionMonitoring))
 	clientMonitoring.HandleFunc("/updates-status", al.handleRefreshUpdatesStatus).Methods(http.MethodPost)
+	clientMonitoring.HandleFunc("/inventory", al.handleGetClientInventory).Methods(http.MethodGet)
+	clientMonitoring.HandleFunc("/inventory", al.handleRefreshClientInventory).Methods(http.MethodPost)
+	clientMonitoring.HandleFunc("/inventory/status", al.handleGetClientInventoryStatus).Methods(http.MethodGet)
+	clientMonitoring.HandleFunc("/inventory/changes", al.handleGetClientInventoryChanges).Methods(http.MethodGet)
 	if al.Server.config.Monitoring.Enabled {
 		clientMonitoring.HandleFunc("/graph-metrics", al.handleGetClientGraphMetrics).Methods(http.MethodGet)
 		clientMonitoring.HandleFunc("/graph-metrics/{"+routes.ParamGraphName+"}", al.handleGetClientGraphMetricsGraph).Methods(http.MethodGet)
@@ -101,48 +112,99 @@ func (al *APIListener) initRouter() {
 	secureAPI.HandleFunc("/client-tags", al.handleGetClientTags).Methods(http.MethodGet)
 
 	secureAPI.Handle("/tunnels", al.permissionsMiddleware(users.PermissionTunnels)(http.HandlerFunc(al.handleGetTunnels))).Methods(http.MethodGet)
+	secureAPI.Handle("/tunnel-templates", al.permissionsMiddleware(users.PermissionTunnels)(http.HandlerFunc(al.handleListTunnelTemplates))).Methods(http.MethodGet)
+	secureAPI.Handle("/tunnel-templates/{"+routes.ParamTemplateID+"}", al.permissionsMiddleware(users.PermissionTunnels)(http.HandlerFunc(al.handleGetTunnelTemplate))).Methods(http.MethodGet)
+	secureAPI.Handle("/tunnel-templates/{"+routes.ParamTemplateID+"}/tunnels", al.permissionsMiddleware(users.PermissionTunnels)(http.HandlerFunc(al.handlePostTunnelTemplateTunnels))).Methods(http.MethodPost)
 	secureAPI.Handle("/auditlog", al.permissionsMiddleware(users.PermissionsAuditLog)(http.HandlerFunc(al.handleListAuditLog))).Methods(http.MethodGet)
 	secureAPI.Handle("/files", al.permissionsMiddleware(users.PermissionUploads)(http.HandlerFunc(al.handleFileUploads))).Methods(http.MethodPost).Name(routes.FilesUploadRouteName)
 
 	secureAPI.HandleFunc("/client-groups", al.handleGetClientGroups).Methods(http.MethodGet)
 	secureAPI.HandleFunc("/client-groups/{group_id}", al.handleGetClientGroup).Methods(http.MethodGet)
 
+	secureAPI.HandleFunc("/approvals", al.handleListApprovals).Methods(http.MethodGet)
+	secureAPI.HandleFunc("/approvals/{"+routes.ParamApprovalID+"}", al.handleGetApproval).Methods(http.MethodGet)
+	secureAPI.HandleFunc("/approvals/{"+routes.ParamApprovalID+"}/approve", al.handleApproveApproval).Methods(http.MethodPost)
+	secureAPI.HandleFunc("/approvals/{"+routes.ParamApprovalID+"}/reject", al.handleRejectApproval).Methods(http.MethodPost)
+
+	secureAPI.HandleFunc("/access-grants", al.handleListAccessGrants).Methods(http.MethodGet)
+	secureAPI.HandleFunc("/access-grants/{"+routes.ParamGrantID+"}", al.handleGetAccessGrant).Methods(http.MethodGet)
+
+	userManagement := secureAPI.NewRoute().Subrouter()
+	userManagement.Use(al.rolesMiddleware(rbac.ResourceUsers))
+	userManagement.HandleFunc("/users", al.wrapStaticPassModeMiddleware(al.handleGetUsers)).Methods(http.MethodGet)
+	userManagement.HandleFunc("/users", al.wrapStaticPassModeMiddleware(al.handleChangeUser)).Methods(http.MethodPost)
+	userManagement.HandleFunc("/users/{user_id}", al.wrapStaticPassModeMiddleware(al.handleChangeUser)).Methods(http.MethodPut)
+	userManagement.HandleFunc("/users/{user_id}", al.wrapStaticPassModeMiddleware(al.handleDeleteUser)).Methods(http.MethodDelete)
+	userManagement.HandleFunc("/users/{user_id}/totp-secret", al.wrapStaticPassModeMiddleware(
+		al.wrapTotPEnabledMiddleware(al.handleDeleteUsersTotP),
+	)).Methods(http.MethodDelete)
+	userManagement.HandleFunc("/users/{user_id}/webauthn-credentials", al.wrapStaticPassModeMiddleware(
+		al.wrapWebAuthnEnabledMiddleware(al.handleDeleteUsersWebAuthnCredentials),
+	)).Methods(http.MethodDelete)
+
+	userManagement.HandleFunc("/users/{user_id}/sessions", al.handleGetUserAPISessions).Methods(http.MethodGet)
+	userManagement.HandleFunc("/users/{user_id}/sessions", al.handleDeleteAllUserAPISessions).Methods(http.MethodDelete)
+	userManagement.HandleFunc("/users/{user_id}/sessions/{session_id}", al.handleDeleteUserAPISession).Methods(http.MethodDelete)
+
+	userManagement.HandleFunc("/user-groups", al.handleListUserGroups).Methods(http.MethodGet)
+	userManagement.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleGetUserGroup)).Methods(http.MethodGet)
+	userManagement.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleUpdateUserGroup)).Methods(http.MethodPut)
+	userManagement.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleDeleteUserGroup)).Methods(http.MethodDelete)
+
 	adminOnly := secureAPI.NewRoute().Subrouter()
 	adminOnly.Use(al.wrapAdminAccessMiddleware)
 	adminOnly.HandleFunc("/client-groups", al.handlePostClientGroups).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/client-groups/preview", al.handlePostClientGroupPreview).Methods(http.MethodPost)
 	adminOnly.HandleFunc("/client-groups/{group_id}", al.handlePutClientGroup).Methods(http.MethodPut)
 	adminOnly.HandleFunc("/client-groups/{group_id}", al.handleDeleteClientGroup).Methods(http.MethodDelete)
-	adminOnly.HandleFunc("/users", al.wrapStaticPassModeMiddleware(al.handleGetUsers)).Methods(http.MethodGet)
-	adminOnly.HandleFunc("/users", al.wrapStaticPassModeMiddleware(al.handleChangeUser)).Methods(http.MethodPost)
-	adminOnly.HandleFunc("/users/{user_id}", al.wrapStaticPassModeMiddleware(al.handleChangeUser)).Methods(http.MethodPut)
-	adminOnly.HandleFunc("/users/{user_id}", al.wrapStaticPassModeMiddleware(al.handleDeleteUser)).Methods(http.MethodDelete)
-	adminOnly.HandleFunc("/users/{user_id}/totp-secret", al.wrapStaticPassModeMiddleware(
-		al.wrapTotPEnabledMiddleware(al.handleDeleteUsersTotP),
-	)).Methods(http.MethodDelete)
-
-	adminOnly.HandleFunc("/users/{user_id}/sessions", al.handleGetUserAPISessions).Methods(http.MethodGet)
-	adminOnly.HandleFunc("/users/{user_id}/sessions", al.handleDeleteAllUserAPISessions).Methods(http.MethodDelete)
-	adminOnly.HandleFunc("/users/{user_id}/sessions/{session_id}", al.handleDeleteUserAPISession).Methods(http.MethodDelete)
-
-	adminOnly.HandleFunc("/user-groups", al.handleListUserGroups).Methods(http.MethodGet)
-	adminOnly.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleGetUserGroup)).Methods(http.MethodGet)
-	adminOnly.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleUpdateUserGroup)).Methods(http.MethodPut)
-	adminOnly.HandleFunc("/user-groups/{group_name}", al.wrapStaticPassModeMiddleware(al.handleDeleteUserGroup)).Methods(http.MethodDelete)
+	adminOnly.HandleFunc("/client-groups/{group_id}/stored-tunnels", al.handleGetClientGroupStoredTunnels).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/client-groups/{group_id}/stored-tunnels", al.handlePostClientGroupStoredTunnels).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/client-groups/{group_id}/stored-tunnels/{tunnel_id}", al.handleDeleteClientGroupStoredTunnel).Methods(http.MethodDelete)
+	adminOnly.HandleFunc("/client-groups/{group_id}/stored-tunnels/{tunnel_id}", al.handlePutClientGroupStoredTunnel).Methods(http.MethodPut)
+	adminOnly.HandleFunc("/tunnel-templates", al.handlePostTunnelTemplates).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/tunnel-templates/{"+routes.ParamTemplateID+"}", al.handlePutTunnelTemplate).Methods(http.MethodPut)
+	adminOnly.HandleFunc("/tunnel-templates/{"+routes.ParamTemplateID+"}", al.handleDeleteTunnelTemplate).Methods(http.MethodDelete)
+	adminOnly.HandleFunc("/cluster/nodes", al.handleGetClusterNodes).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/backup", al.handleGetBackup).Methods(http.MethodGet)
+
+	adminOnly.HandleFunc("/roles", al.handleListRoles).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/roles", al.handlePostRole).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/roles/{"+routes.ParamRoleName+"}", al.handleGetRole).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/roles/{"+routes.ParamRoleName+"}", al.handlePutRole).Methods(http.MethodPut)
+	adminOnly.HandleFunc("/roles/{"+routes.ParamRoleName+"}", al.handleDeleteRole).Methods(http.MethodDelete)
+	adminOnly.HandleFunc("/role-bindings", al.handleListRoleBindings).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/role-bindings", al.handlePostRoleBinding).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/role-bindings/{"+routes.ParamBindingID+"}", al.handleDeleteRoleBinding).Methods(http.MethodDelete)
 
 	adminOnly.HandleFunc("/clients-auth", al.handleGetClientsAuth).Methods(http.MethodGet)
 	adminOnly.HandleFunc("/clients-auth/{client_auth_id}", al.handleGetClientAuth).Methods(http.MethodGet)
 	adminOnly.HandleFunc("/clients-auth", al.handlePostClientsAuth).Methods(http.MethodPost)
 	adminOnly.HandleFunc("/clients-auth/{client_auth_id}", al.handleDeleteClientAuth).Methods(http.MethodDelete)
 
+	adminOnly.HandleFunc("/enrollment-tokens", al.handleListEnrollmentTokens).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/enrollment-tokens", al.handlePostEnrollmentTokens).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/enrollment-tokens/{"+routes.ParamEnrollmentToken+"}", al.handleGetEnrollmentToken).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/enrollment-tokens/{"+routes.ParamEnrollmentToken+"}", al.handleDeleteEnrollmentToken).Methods(http.MethodDelete)
+
 	adminOnly.HandleFunc("/notification-logs", al.handleGetNotifications).Methods(http.MethodGet)
 	adminOnly.HandleFunc("/notification-logs/{notification_id}", al.handleGetNotificationDetails).Methods(http.MethodGet)
 
+	adminOnly.HandleFunc("/approval-policies", al.handleListApprovalPolicies).Methods(http.MethodGet)
+	adminOnly.HandleFunc("/approval-policies", al.handlePostApprovalPolicy).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/approval-policies/{"+routes.ParamPolicyID+"}", al.handlePutApprovalPolicy).Methods(http.MethodPut)
+	adminOnly.HandleFunc("/approval-policies/{"+routes.ParamPolicyID+"}", al.handleDeleteApprovalPolicy).Methods(http.MethodDelete)
+
+	adminOnly.HandleFunc("/access-grants", al.handlePostAccessGrant).Methods(http.MethodPost)
+	adminOnly.HandleFunc("/access-grants/{"+routes.ParamGrantID+"}", al.handleDeleteAccessGrant).Methods(http.MethodDelete)
+
 	commands := secureAPI.NewRoute().Subrouter()
 	commands.Use(al.permissionsMiddleware(users.PermissionCommands))
 	commands.HandleFunc("/commands", al.handlePostMultiClientCommand).Methods(http.MethodPost)
 	commands.HandleFunc("/commands", al.handleGetMultiClientCommands).Methods(http.MethodGet)
 	commands.HandleFunc("/commands/{job_id}", al.handleGetMultiClientCommand).Methods(http.MethodGet)
 	commands.HandleFunc("/commands/{job_id}/jobs", al.handleGetMultiClientCommandJobs).Methods(http.MethodGet)
+	commands.HandleFunc("/commands/{job_id}/pause", al.handlePauseMultiClientJob).Methods(http.MethodPost)
+	commands.HandleFunc("/commands/{job_id}/resume", al.handleResumeMultiClientJob).Methods(http.MethodPost)
 	commands.HandleFunc("/library/commands", al.handleListCommands).Methods(http.MethodGet)
 	commands.HandleFunc("/library/commands", al.handleCommandCreate).Methods(http.MethodPost)
 	commands.HandleFunc("/library/commands/{"+routes.ParamCommandValueID+"}", al.handleCommandUpdate).Methods(http.MethodPut)
@@ -181,6 +243,10 @@ func (al *APIListener) initRouter() {
 	secureAPI.HandleFunc(routes.TotPRoutes, al.wrapTotPEnabledMiddleware(al.handleGetTotP)).Methods(http.MethodGet)
 	secureAPI.HandleFunc(routes.TotPRoutes, al.wrapTotPEnabledMiddleware(al.handlePostTotP)).Methods(http.MethodPost)
 	secureAPI.HandleFunc(routes.TotPRoutes, al.wrapTotPEnabledMiddleware(al.handleDeleteTotP)).Methods(http.MethodDelete)
+	secureAPI.HandleFunc(routes.WebAuthnRegistrationRoute, al.wrapWebAuthnEnabledMiddleware(al.handlePostWebAuthnRegistration)).Methods(http.MethodPost)
+	secureAPI.HandleFunc(routes.WebAuthnCredentialsRoute, al.wrapWebAuthnEnabledMiddleware(al.handleGetWebAuthnCredentials)).Methods(http.MethodGet)
+	secureAPI.HandleFunc(routes.WebAuthnCredentialsRoute, al.wrapWebAuthnEnabledMiddleware(al.handlePostWebAuthnCredential)).Methods(http.MethodPost)
+	secureAPI.HandleFunc(routes.WebAuthnCredentialsRoute+"/{"+routes.ParamCredentialID+"}", al.wrapWebAuthnEnabledMiddleware(al.handleDeleteWebAuthnCredential)).Methods(http.MethodDelete)
 
 	// all routes defined below do not have authorization middleware, auth is done in each handler separately
 	api.HandleFunc("/login", al.handleGetLogin).Methods(http.MethodGet)
@@ -200,6 +266,22 @@ func (al *APIListener) initRouter() {
 		api.HandleFunc("/test/uploads/ui", al.wsUploads)
 	}
 
+	scimRouter := api.PathPrefix("/scim/v2").Subrouter()
+	scimRouter.Use(al.wrapSCIMAuthMiddleware)
+	scimRouter.HandleFunc("/ServiceProviderConfig", al.handleGetSCIMServiceProviderConfig).Methods(http.MethodGet)
+	scimRouter.HandleFunc("/Users", al.handleListSCIMUsers).Methods(http.MethodGet)
+	scimRouter.HandleFunc("/Users", al.handlePostSCIMUser).Methods(http.MethodPost)
+	scimRouter.HandleFunc("/Users/{"+routes.ParamSCIMID+"}", al.handleGetSCIMUser).Methods(http.MethodGet)
+	scimRouter.HandleFunc("/Users/{"+routes.ParamSCIMID+"}", al.handlePutSCIMUser).Methods(http.MethodPut)
+	scimRouter.HandleFunc("/Users/{"+routes.ParamSCIMID+"}", al.handlePatchSCIMUser).Methods(http.MethodPatch)
+	scimRouter.HandleFunc("/Users/{"+routes.ParamSCIMID+"}", al.handleDeleteSCIMUser).Methods(http.MethodDelete)
+	scimRouter.HandleFunc("/Groups", al.handleListSCIMGroups).Methods(http.MethodGet)
+	scimRouter.HandleFunc("/Groups", al.handlePostSCIMGroup).Methods(http.MethodPost)
+	scimRouter.HandleFunc("/Groups/{"+routes.ParamSCIMID+"}", al.handleGetSCIMGroup).Methods(http.MethodGet)
+	scimRouter.HandleFunc("/Groups/{"+routes.ParamSCIMID+"}", al.handlePutSCIMGroup).Methods(http.MethodPut)
+	scimRouter.HandleFunc("/Groups/{"+routes.ParamSCIMID+"}", al.handlePatchSCIMGroup).Methods(http.MethodPatch)
+	scimRouter.HandleFunc("/Groups/{"+routes.ParamSCIMID+"}", al.handleDeleteSCIMGroup).Methods(http.MethodDelete)
+
 	if al.bannedIPs != nil {
 		api.Use(security.RejectBannedIPs(al.bannedIPs))
 	}
@@ -0,0 +1,176 @@
+package approvals
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riportdev/riport/share/types"
+)
+
+const (
+	// OperationCommand covers commands and scripts, both on a single client and on multiple clients.
+	OperationCommand = "command"
+	OperationTunnel  = "tunnel"
+)
+
+const (
+	TypeClientCommand = "client_command"
+	TypeClientScript  = "client_script"
+	TypeMultiCommand  = "multi_command"
+	TypeMultiScript   = "multi_script"
+	TypeTunnel        = "tunnel"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusFailed   = "failed"
+)
+
+var RequestSupportedFilters = map[string]bool{
+	"status":       true,
+	"type":         true,
+	"requested_by": true,
+	"policy_id":    true,
+}
+
+var RequestSupportedSorts = map[string]bool{
+	"requested_at": true,
+	"decided_at":   true,
+}
+
+var RequestListDefaultSort = map[string][]string{
+	"sort": {"-requested_at"},
+}
+
+// Policy defines which operations require an approval and who may give it.
+type Policy struct {
+	ID        string `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Operation string `json:"operation" db:"operation"`
+	// SudoOnly restricts a command policy to commands and scripts executed with sudo.
+	SudoOnly bool `json:"sudo_only" db:"sudo_only"`
+	// ClientGroupIDs restricts a policy to clients of given client groups. Empty means all clients.
+	ClientGroupIDs types.StringSlice `json:"client_group_ids" db:"client_group_ids"`
+	ApproverGroups types.StringSlice `json:"approver_groups" db:"approver_groups"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	CreatedBy      string            `json:"created_by" db:"created_by"`
+}
+
+func (p *Policy) Validate() error {
+	if p.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if p.Operation != OperationCommand && p.Operation != OperationTunnel {
+		return fmt.Errorf("operation must be %q or %q", OperationCommand, OperationTunnel)
+	}
+	if p.SudoOnly && p.Operation != OperationCommand {
+		return fmt.Errorf("sudo_only is applicable only to %q operation", OperationCommand)
+	}
+	if len(p.ApproverGroups) == 0 {
+		return errors.New("approver_groups cannot be empty")
+	}
+	return nil
+}
+
+// Operation describes a requested operation that might be subject to an approval.
+type Operation struct {
+	Name   string
+	IsSudo bool
+	// ClientGroupIDs are IDs of client groups the affected clients belong to.
+	ClientGroupIDs []string
+}
+
+func (p *Policy) Matches(op Operation) bool {
+	if p.Operation != op.Name {
+		return false
+	}
+	if p.SudoOnly && !op.IsSudo {
+		return false
+	}
+	if len(p.ClientGroupIDs) == 0 {
+		return true
+	}
+	for _, id := range p.ClientGroupIDs {
+		for _, opID := range op.ClientGroupIDs {
+			if id == opID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindPolicy returns the first policy that matches a given operation or nil if no approval is required.
+func FindPolicy(policies []*Policy, op Operation) *Policy {
+	for _, p := range policies {
+		if p.Matches(op) {
+			return p
+		}
+	}
+	return nil
+}
+
+// CanApprove returns whether a user with given groups may decide on a request under a given policy.
+func (p *Policy) CanApprove(userGroups []string) bool {
+	for _, g := range p.ApproverGroups {
+		for _, ug := range userGroups {
+			if g == ug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Request is an operation waiting for an approval.
+type Request struct {
+	ID          string            `json:"id" db:"id"`
+	PolicyID    string            `json:"policy_id" db:"policy_id"`
+	Type        string            `json:"type" db:"type"`
+	Status      string            `json:"status" db:"status"`
+	ClientIDs   types.StringSlice `json:"client_ids" db:"client_ids"`
+	Request     RawJSON           `json:"request" db:"request"`
+	RequestedAt time.Time         `json:"requested_at" db:"requested_at"`
+	RequestedBy string            `json:"requested_by" db:"requested_by"`
+	DecidedAt   *time.Time        `json:"decided_at" db:"decided_at"`
+	DecidedBy   *string           `json:"decided_by" db:"decided_by"`
+	Reason      string            `json:"reason" db:"reason"`
+	// Result is the ID of the job or tunnel created after the approval.
+	Result string `json:"result" db:"result"`
+	Error  string `json:"error" db:"error"`
+}
+
+// RawJSON stores the original request of an operation as a json db column.
+type RawJSON []byte
+
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+func (r *RawJSON) UnmarshalJSON(data []byte) error {
+	*r = append((*r)[0:0], data...)
+	return nil
+}
+
+func (r *RawJSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*r = RawJSON(v)
+	case []byte:
+		*r = append(RawJSON{}, v...)
+	default:
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	return nil
+}
+
+func (r RawJSON) Value() (driver.Value, error) {
+	return string(r), nil
+}
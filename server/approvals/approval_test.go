@@ -0,0 +1,88 @@
+package approvals
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPolicy(t *testing.T) {
+	sudoOnGroupX := &Policy{ID: "1", Operation: OperationCommand, SudoOnly: true, ClientGroupIDs: []string{"x"}, ApproverGroups: []string{"y"}}
+	anyTunnel := &Policy{ID: "2", Operation: OperationTunnel, ApproverGroups: []string{"y"}}
+	policies := []*Policy{sudoOnGroupX, anyTunnel}
+
+	testCases := []struct {
+		Name      string
+		Operation Operation
+		Expected  *Policy
+	}{
+		{
+			Name:      "sudo command on group x",
+			Operation: Operation{Name: OperationCommand, IsSudo: true, ClientGroupIDs: []string{"z", "x"}},
+			Expected:  sudoOnGroupX,
+		},
+		{
+			Name:      "non sudo command on group x",
+			Operation: Operation{Name: OperationCommand, ClientGroupIDs: []string{"x"}},
+		},
+		{
+			Name:      "sudo command on other group",
+			Operation: Operation{Name: OperationCommand, IsSudo: true, ClientGroupIDs: []string{"z"}},
+		},
+		{
+			Name:      "tunnel on any client",
+			Operation: Operation{Name: OperationTunnel},
+			Expected:  anyTunnel,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, FindPolicy(policies, tc.Operation))
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Policy   Policy
+		ExpError string
+	}{
+		{
+			Name:   "valid",
+			Policy: Policy{Name: "sudo", Operation: OperationCommand, SudoOnly: true, ApproverGroups: []string{"y"}},
+		},
+		{
+			Name:     "empty name",
+			Policy:   Policy{Operation: OperationCommand, ApproverGroups: []string{"y"}},
+			ExpError: "name cannot be empty",
+		},
+		{
+			Name:     "invalid operation",
+			Policy:   Policy{Name: "p", Operation: "upload", ApproverGroups: []string{"y"}},
+			ExpError: `operation must be "command" or "tunnel"`,
+		},
+		{
+			Name:     "sudo only on tunnel",
+			Policy:   Policy{Name: "p", Operation: OperationTunnel, SudoOnly: true, ApproverGroups: []string{"y"}},
+			ExpError: `sudo_only is applicable only to "command" operation`,
+		},
+		{
+			Name:     "no approvers",
+			Policy:   Policy{Name: "p", Operation: OperationTunnel},
+			ExpError: "approver_groups cannot be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Policy.Validate()
+			if tc.ExpError != "" {
+				assert.EqualError(t, err, tc.ExpError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
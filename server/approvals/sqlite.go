@@ -0,0 +1,109 @@
+package approvals
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/query"
+)
+
+type Provider interface {
+	ListPolicies(ctx context.Context) ([]*Policy, error)
+	GetPolicy(ctx context.Context, id string) (*Policy, error)
+	SavePolicy(ctx context.Context, p *Policy) error
+	DeletePolicy(ctx context.Context, id string) error
+	ListRequests(ctx context.Context, options *query.ListOptions) ([]*Request, error)
+	GetRequest(ctx context.Context, id string) (*Request, error)
+	SaveRequest(ctx context.Context, r *Request) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+	}
+}
+
+func (p *SqliteProvider) ListPolicies(ctx context.Context) ([]*Policy, error) {
+	res := []*Policy{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM approval_policies ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) GetPolicy(ctx context.Context, id string) (*Policy, error) {
+	res := &Policy{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM approval_policies WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SavePolicy(ctx context.Context, policy *Policy) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO approval_policies (id, name, operation, sudo_only, client_group_ids, approver_groups, created_at, created_by)
+		VALUES (:id, :name, :operation, :sudo_only, :client_group_ids, :approver_groups, :created_at, :created_by)`,
+		policy,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeletePolicy(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM approval_policies WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) ListRequests(ctx context.Context, options *query.ListOptions) ([]*Request, error) {
+	q, params := p.converter.ConvertListOptionsToQuery(options, "SELECT * FROM approval_requests")
+
+	res := []*Request{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) GetRequest(ctx context.Context, id string) (*Request, error) {
+	res := &Request{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM approval_requests WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SaveRequest(ctx context.Context, r *Request) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO approval_requests (
+			id, policy_id, type, status, client_ids, request, requested_at, requested_by, decided_at, decided_by, reason, result, error
+		) VALUES (
+			:id, :policy_id, :type, :status, :client_ids, :request, :requested_at, :requested_by, :decided_at, :decided_by, :reason, :result, :error
+		)`,
+		r,
+	)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
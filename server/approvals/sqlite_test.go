@@ -0,0 +1,75 @@
+package approvals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/db/migration/approvals"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/share/query"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func TestSqliteProvider(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", approvals.AssetNames(), approvals.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(db)
+	defer p.Close()
+
+	policy := &Policy{
+		ID:             "p1",
+		Name:           "sudo on servers",
+		Operation:      OperationCommand,
+		SudoOnly:       true,
+		ClientGroupIDs: []string{"servers"},
+		ApproverGroups: []string{"approvers"},
+		CreatedAt:      time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC),
+		CreatedBy:      "admin",
+	}
+	require.NoError(t, p.SavePolicy(ctx, policy))
+
+	policies, err := p.ListPolicies(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Policy{policy}, policies)
+
+	request := &Request{
+		ID:          "r1",
+		PolicyID:    policy.ID,
+		Type:        TypeClientCommand,
+		Status:      StatusPending,
+		ClientIDs:   []string{"c1"},
+		Request:     RawJSON(`{"command":"/bin/true"}`),
+		RequestedAt: time.Date(2022, 1, 1, 2, 0, 0, 0, time.UTC),
+		RequestedBy: "user1",
+	}
+	require.NoError(t, p.SaveRequest(ctx, request))
+
+	decidedAt := time.Date(2022, 1, 1, 3, 0, 0, 0, time.UTC)
+	decidedBy := "user2"
+	request.Status = StatusApproved
+	request.DecidedAt = &decidedAt
+	request.DecidedBy = &decidedBy
+	request.Result = "job-1"
+	require.NoError(t, p.SaveRequest(ctx, request))
+
+	got, err := p.GetRequest(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, request, got)
+
+	list, err := p.ListRequests(ctx, &query.ListOptions{
+		Filters: []query.FilterOption{{Column: []string{"status"}, Values: []string{StatusPending}}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	require.NoError(t, p.DeletePolicy(ctx, policy.ID))
+	got2, err := p.GetPolicy(ctx, policy.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got2)
+}
@@ -8,26 +8,47 @@ const (
 	ActionExecuteDone  = "execute.done"
 	ActionSuccess      = "success"
 	ActionFailed       = "failed"
+	ActionPause        = "pause"
+	ActionResume       = "resume"
+	ActionApprove      = "approve"
+	ActionReject       = "reject"
+	ActionRevoke       = "revoke"
+	ActionExpire       = "expire"
+	ActionClose        = "close"
 )
 
 const (
-	ApplicationAuthUser        = "auth.user"
-	ApplicationAuthUserMe      = "auth.user.me"
-	ApplicationAuthUserMeToken = "auth.user.me.token" //nolint:gosec
-	ApplicationAuthUserTotP    = "auth.user.totp"
-	ApplicationAuthUserGroup   = "auth.user.group"
-	ApplicationAuthAPISession  = "auth.api.session"
-	ApplicationAuthAPISessions = "auth.api.sessions"
-	ApplicationClient          = "client"
-	ApplicationClientACL       = "client.acl"
-	ApplicationClientAuth      = "client.auth"
-	ApplicationClientGroup     = "client.group"
-	ApplicationClientTunnel    = "client.tunnel"
-	ApplicationClientCommand   = "client.command"
-	ApplicationClientScript    = "client.script"
-	ApplicationLibraryCommand  = "library.command"
-	ApplicationLibraryScript   = "library.script"
-	ApplicationVault           = "vault"
-	ApplicationSchedule        = "schedule"
-	ApplicationUploads         = "uploads"
+	ApplicationAuthUser         = "auth.user"
+	ApplicationAuthUserMe       = "auth.user.me"
+	ApplicationAuthUserMeToken  = "auth.user.me.token" //nolint:gosec
+	ApplicationAuthUserTotP     = "auth.user.totp"
+	ApplicationAuthUserWebAuthn = "auth.user.webauthn"
+	ApplicationAuthUserGroup    = "auth.user.group"
+	ApplicationAuthRole         = "auth.role"
+	ApplicationAuthRoleBinding  = "auth.role.binding"
+	ApplicationAuthAPISession   = "auth.api.session"
+	ApplicationAuthAPISessions  = "auth.api.sessions"
+	ApplicationClient           = "client"
+	ApplicationClientACL        = "client.acl"
+	ApplicationClientAttributes = "client.attributes"
+	ApplicationClientAuth       = "client.auth"
+	ApplicationClientCert       = "client.certificate"
+	ApplicationClientEnrollment = "client.enrollment"
+	ApplicationClientGroup      = "client.group"
+	ApplicationClientTunnel     = "client.tunnel"
+	ApplicationClientCommand    = "client.command"
+	ApplicationClientScript     = "client.script"
+	ApplicationLibraryCommand   = "library.command"
+	ApplicationLibraryScript    = "library.script"
+	ApplicationVault            = "vault"
+	ApplicationSchedule         = "schedule"
+	ApplicationUploads          = "uploads"
+	ApplicationApproval         = "approval"
+	ApplicationApprovalPolicy   = "approval.policy"
+	ApplicationAccessGrant      = "access.grant"
+	ApplicationSCIMUser         = "scim.user"
+	ApplicationSCIMGroup        = "scim.group"
+	ApplicationTunnelTemplate   = "tunnel.template"
+	ApplicationBackup           = "backup"
+	ApplicationEnrollmentToken  = "enrollment.token"
 )
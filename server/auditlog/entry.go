@@ -46,6 +46,16 @@ func (e *Entry) WithHTTPRequest(req *http.Request) *Entry {
 	return e
 }
 
+// WithRemoteIP is used for entries that are not caused by an API request, e.g. a client connecting
+func (e *Entry) WithRemoteIP(ip string) *Entry {
+	if e == nil {
+		return e
+	}
+
+	e.RemoteIP = ip
+	return e
+}
+
 func (e *Entry) WithRequest(request interface{}) *Entry {
 	if e == nil {
 		return e
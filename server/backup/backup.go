@@ -0,0 +1,363 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	ManifestName = "manifest.json"
+	dataPrefix   = "data/"
+
+	// manifestFormat is increased on incompatible changes of the archive layout
+	manifestFormat = 1
+
+	boltOpenTimeout = time.Second
+	maxBusyAttempts = 10
+	busyDelay       = 100 * time.Millisecond
+)
+
+type StoreType string
+
+const (
+	StoreSQLite StoreType = "sqlite"
+	StoreBolt   StoreType = "bolt"
+	StoreFile   StoreType = "file"
+)
+
+// Store is a database or a file which is part of a backup
+type Store struct {
+	// Name identifies the store in the archive, it's the file name for stores in the data dir
+	Name string
+	Path string
+	Type StoreType
+	// Migrations returns the migrations of a sqlite database known to this version, nil for databases without migrations
+	Migrations func() []string
+	// Bolt is the database opened by the running server, nil to open the file
+	Bolt *bbolt.DB
+}
+
+type Manifest struct {
+	Format    int              `json:"format"`
+	Version   string           `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Stores    []*ManifestStore `json:"stores"`
+}
+
+type ManifestStore struct {
+	Name             string    `json:"name"`
+	Type             StoreType `json:"type"`
+	MigrationVersion *uint     `json:"migration_version,omitempty"`
+	Size             int64     `json:"size"`
+	SHA256           string    `json:"sha256"`
+}
+
+// Backup takes a consistent snapshot of all existing stores and writes it as a gzipped tar archive with a manifest.
+// Nothing is written if taking a snapshot fails, so callers can still report the error.
+func Backup(ctx context.Context, w io.Writer, stores []*Store, version string) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "riportd-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest := &Manifest{
+		Format:    manifestFormat,
+		Version:   version,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, store := range stores {
+		entry, err := snapshot(ctx, store, filepath.Join(tmpDir, store.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to backup %s: %v", store.Name, err)
+		}
+		if entry != nil {
+			manifest.Stores = append(manifest.Stores, entry)
+		}
+	}
+
+	err = writeArchive(w, manifest, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// snapshot copies a store to dst, it returns nil if the store doesn't exist
+func snapshot(ctx context.Context, store *Store, dst string) (*ManifestStore, error) {
+	if store.Bolt == nil {
+		if _, err := os.Stat(store.Path); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(dst), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &ManifestStore{
+		Name: store.Name,
+		Type: store.Type,
+	}
+	switch store.Type {
+	case StoreSQLite:
+		err = backupSQLite(ctx, store.Path, dst)
+		if err != nil {
+			return nil, err
+		}
+		if store.Migrations != nil {
+			version, err := readMigrationVersion(ctx, dst)
+			if err != nil {
+				return nil, err
+			}
+			entry.MigrationVersion = &version
+		}
+	case StoreBolt:
+		err = backupBolt(store, dst)
+	case StoreFile:
+		err = copyFile(store.Path, dst)
+	default:
+		err = fmt.Errorf("unknown store type %q", store.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Size, entry.SHA256, err = checksum(dst)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// backupSQLite uses the sqlite online backup api to copy a database while it's in use by a running server
+func backupSQLite(ctx context.Context, src, dst string) error {
+	srcDB, err := sql.Open("sqlite3", src)
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+	dstDB, err := sql.Open("sqlite3", dst)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	dstConn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dstDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			dstSQLiteConn, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected connection type %T", dstDriverConn)
+			}
+			srcSQLiteConn, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected connection type %T", srcDriverConn)
+			}
+
+			b, err := dstSQLiteConn.Backup("main", srcSQLiteConn, "main")
+			if err != nil {
+				return err
+			}
+			err = stepAll(b)
+			if err != nil {
+				_ = b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+}
+
+// stepAll copies all pages, it retries while the source database is locked by a writer
+func stepAll(b *sqlite3.SQLiteBackup) error {
+	for attempt := 1; ; attempt++ {
+		done, err := b.Step(-1)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if attempt == maxBusyAttempts {
+			return fmt.Errorf("database still busy after %d attempts", maxBusyAttempts)
+		}
+		time.Sleep(busyDelay)
+	}
+}
+
+func backupBolt(store *Store, dst string) error {
+	db := store.Bolt
+	if db == nil {
+		var err error
+		db, err = bbolt.Open(store.Path, 0600, &bbolt.Options{ReadOnly: true, Timeout: boltOpenTimeout})
+		if err != nil {
+			if errors.Is(err, bbolt.ErrTimeout) {
+				return errors.New("database is locked by a running riportd, use the api to backup a running server")
+			}
+			return err
+		}
+		defer db.Close()
+	}
+
+	return db.View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(dst, 0600)
+	})
+}
+
+// readMigrationVersion returns the version of the latest migration applied to a database
+func readMigrationVersion(ctx context.Context, dbPath string) (uint, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var version uint
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %v", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("migration %d didn't complete", version)
+	}
+	return version, nil
+}
+
+// latestMigrationVersion returns the highest version of migration files named like 001_init.up.sql
+func latestMigrationVersion(names []string) (uint, error) {
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(path.Base(name), "_")
+		version, err := strconv.ParseUint(prefix, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration name %q", name)
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+func writeArchive(w io.Writer, manifest *Manifest, dir string) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the manifest goes first, so restore can validate it before extracting the data
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0600,
+		Size:    int64(len(manifestJSON)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Stores {
+		err := writeArchiveFile(tw, filepath.Join(dir, entry.Name), dataPrefix+entry.Name, entry.Size, manifest.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+func writeArchiveFile(tw *tar.Writer, src, name string, size int64, modTime time.Time) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func checksum(file string) (int64, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	"github.com/riportdev/riport/db/migration/dummy"
+	"github.com/riportdev/riport/db/sqlite"
+)
+
+var testBucket = []byte("rules")
+
+type testData struct {
+	dir    string
+	db     *sqlx.DB
+	bolt   *bbolt.DB
+	stores []*Store
+}
+
+func newTestData(t *testing.T) *testData {
+	dir := t.TempDir()
+	db, err := sqlite.New(filepath.Join(dir, "dummy.db"), dummy.AssetNames(), dummy.Asset, sqlite.DataSourceOptions{WALEnabled: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	bolt, err := bbolt.Open(filepath.Join(dir, AlertsStoreName), 0600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { bolt.Close() })
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "auth.json"), []byte(`{"client-1":"secret"}`), 0600))
+
+	return &testData{
+		dir:  dir,
+		db:   db,
+		bolt: bolt,
+		stores: []*Store{
+			{Name: "dummy.db", Path: filepath.Join(dir, "dummy.db"), Type: StoreSQLite, Migrations: dummy.AssetNames},
+			{Name: "missing.db", Path: filepath.Join(dir, "missing.db"), Type: StoreSQLite, Migrations: dummy.AssetNames},
+			{Name: AlertsStoreName, Path: filepath.Join(dir, AlertsStoreName), Type: StoreBolt, Bolt: bolt},
+			{Name: "auth/clients-auth.json", Path: filepath.Join(dir, "auth.json"), Type: StoreFile},
+		},
+	}
+}
+
+func (d *testData) insert(t *testing.T, id string) {
+	_, err := d.db.Exec("INSERT INTO clients (id, client_auth_id, details) VALUES (?, ?, ?)", id, "auth", "{}")
+	require.NoError(t, err)
+	err = d.bolt.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(testBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), []byte("rule"))
+	})
+	require.NoError(t, err)
+}
+
+// stop closes the databases as on shutdown of the server
+func (d *testData) stop(t *testing.T) {
+	require.NoError(t, d.db.Close())
+	require.NoError(t, d.bolt.Close())
+	d.stores[2].Bolt = nil
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	data := newTestData(t)
+	data.insert(t, "1")
+
+	archive := &bytes.Buffer{}
+	manifest, err := Backup(ctx, archive, data.stores, "1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", manifest.Version)
+	require.Len(t, manifest.Stores, 3)
+	assert.Equal(t, "dummy.db", manifest.Stores[0].Name)
+	require.NotNil(t, manifest.Stores[0].MigrationVersion)
+	assert.Equal(t, uint(1), *manifest.Stores[0].MigrationVersion)
+	assert.Equal(t, AlertsStoreName, manifest.Stores[1].Name)
+	assert.Equal(t, "auth/clients-auth.json", manifest.Stores[2].Name)
+	assert.Nil(t, manifest.Stores[2].MigrationVersion)
+
+	// changes after the backup are reverted by the restore
+	data.insert(t, "2")
+	require.NoError(t, os.WriteFile(filepath.Join(data.dir, "auth.json"), []byte(`{}`), 0600))
+
+	_, err = Restore(ctx, bytes.NewReader(archive.Bytes()), data.stores)
+	require.EqualError(t, err, filepath.Join(data.dir, AlertsStoreName)+" is in use, stop riportd before restoring")
+
+	data.stop(t)
+	restored, err := Restore(ctx, bytes.NewReader(archive.Bytes()), data.stores)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.CreatedAt.Unix(), restored.CreatedAt.Unix())
+
+	db, err := sqlite.New(filepath.Join(data.dir, "dummy.db"), dummy.AssetNames(), dummy.Asset, sqlite.DataSourceOptions{WALEnabled: true})
+	require.NoError(t, err)
+	defer db.Close()
+	var ids []string
+	require.NoError(t, db.Select(&ids, "SELECT id FROM clients"))
+	assert.Equal(t, []string{"1"}, ids)
+
+	bolt, err := bbolt.Open(filepath.Join(data.dir, AlertsStoreName), 0600, nil)
+	require.NoError(t, err)
+	defer bolt.Close()
+	err = bolt.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(testBucket)
+		require.NotNil(t, b)
+		assert.NotNil(t, b.Get([]byte("1")))
+		assert.Nil(t, b.Get([]byte("2")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	auth, err := os.ReadFile(filepath.Join(data.dir, "auth.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"client-1":"secret"}`, string(auth))
+}
+
+func TestRestoreValidation(t *testing.T) {
+	ctx := context.Background()
+	data := newTestData(t)
+	data.insert(t, "1")
+
+	archive := &bytes.Buffer{}
+	_, err := Backup(ctx, archive, data.stores, "1.2.3")
+	require.NoError(t, err)
+	data.stop(t)
+
+	testCases := []struct {
+		Name          string
+		Archive       []byte
+		Stores        func() []*Store
+		ExpectedError string
+	}{
+		{
+			Name:          "not an archive",
+			Archive:       []byte("backup"),
+			Stores:        func() []*Store { return data.stores },
+			ExpectedError: "invalid backup archive: unexpected EOF",
+		},
+		{
+			Name:    "newer migration",
+			Archive: archive.Bytes(),
+			Stores: func() []*Store {
+				stores := []*Store{{Name: "dummy.db", Path: filepath.Join(data.dir, "dummy.db"), Type: StoreSQLite, Migrations: func() []string { return nil }}}
+				return append(stores, data.stores[1:]...)
+			},
+			ExpectedError: "invalid backup of dummy.db: migration version 1 is newer than 0 supported by this riportd, upgrade riportd before restoring",
+		},
+		{
+			Name:          "unknown store",
+			Archive:       archive.Bytes(),
+			Stores:        func() []*Store { return data.stores[:3] },
+			ExpectedError: "invalid backup of auth/clients-auth.json: not a store of this riportd or not enabled in the config",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := Restore(ctx, bytes.NewReader(tc.Archive), tc.Stores())
+			assert.EqualError(t, err, tc.ExpectedError)
+		})
+	}
+
+	// nothing was replaced by the failed restores
+	auth, err := os.ReadFile(filepath.Join(data.dir, "auth.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"client-1":"secret"}`, string(auth))
+}
+
+func TestLatestMigrationVersion(t *testing.T) {
+	version, err := latestMigrationVersion([]string{"001_init.up.sql", "003_add.down.sql", "002_index.up.sql"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+
+	_, err = latestMigrationVersion([]string{"init.up.sql"})
+	assert.EqualError(t, err, `invalid migration name "init.up.sql"`)
+}
@@ -0,0 +1,247 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.etcd.io/bbolt"
+)
+
+const restoreSuffix = ".restore"
+
+// Restore replaces the stores with the data of a backup archive. The archive is validated completely before any store
+// is replaced. Stores which are not part of the backup are kept. The server must not be running.
+func Restore(ctx context.Context, r io.Reader, stores []*Store) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "riportd-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest, err := extractArchive(r, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	storesByName := make(map[string]*Store, len(stores))
+	for _, store := range stores {
+		storesByName[store.Name] = store
+	}
+	for _, entry := range manifest.Stores {
+		err := validate(ctx, entry, storesByName[entry.Name], filepath.Join(tmpDir, entry.Name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup of %s: %v", entry.Name, err)
+		}
+	}
+
+	for _, store := range stores {
+		if err := checkNotInUse(store); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range manifest.Stores {
+		err := replace(filepath.Join(tmpDir, entry.Name), storesByName[entry.Name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %v", entry.Name, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+func extractArchive(r io.Reader, dir string) (*Manifest, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	if header.Name != ManifestName {
+		return nil, fmt.Errorf("invalid backup archive: expected %s first, got %s", ManifestName, header.Name)
+	}
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", ManifestName, err)
+	}
+	if manifest.Format < 1 || manifest.Format > manifestFormat {
+		return nil, fmt.Errorf("backup format %d created by riportd %s is not supported by this version", manifest.Format, manifest.Version)
+	}
+
+	expected := make(map[string]bool, len(manifest.Stores))
+	for _, entry := range manifest.Stores {
+		if !isValidName(entry.Name) {
+			return nil, fmt.Errorf("invalid store name %q in %s", entry.Name, ManifestName)
+		}
+		expected[dataPrefix+entry.Name] = true
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %v", err)
+		}
+		if !expected[header.Name] {
+			return nil, fmt.Errorf("unexpected file %s in backup archive", header.Name)
+		}
+
+		err = extractFile(tr, filepath.Join(dir, strings.TrimPrefix(header.Name, dataPrefix)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+func isValidName(name string) bool {
+	return name != "" && !path.IsAbs(name) && path.Clean(name) == name && !strings.HasPrefix(name, "..")
+}
+
+func extractFile(r io.Reader, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// validate checks the extracted file of a store can be restored by this version
+func validate(ctx context.Context, entry *ManifestStore, store *Store, file string) error {
+	if store == nil {
+		return errors.New("not a store of this riportd or not enabled in the config")
+	}
+	if store.Type != entry.Type {
+		return fmt.Errorf("expected type %s, got %s", store.Type, entry.Type)
+	}
+
+	size, sum, err := checksum(file)
+	if err != nil {
+		return err
+	}
+	if size != entry.Size || sum != entry.SHA256 {
+		return errors.New("checksum mismatch")
+	}
+
+	switch entry.Type {
+	case StoreSQLite:
+		return validateSQLite(ctx, entry, store, file)
+	case StoreBolt:
+		db, err := bbolt.Open(file, 0600, &bbolt.Options{ReadOnly: true, Timeout: boltOpenTimeout})
+		if err != nil {
+			return err
+		}
+		return db.Close()
+	}
+	return nil
+}
+
+func validateSQLite(ctx context.Context, entry *ManifestStore, store *Store, file string) error {
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	err = db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	if store.Migrations == nil {
+		return nil
+	}
+	if entry.MigrationVersion == nil {
+		return errors.New("migration version missing")
+	}
+	version, err := readMigrationVersion(ctx, file)
+	if err != nil {
+		return err
+	}
+	if version != *entry.MigrationVersion {
+		return fmt.Errorf("migration version %d doesn't match %d of %s", version, *entry.MigrationVersion, ManifestName)
+	}
+	latest, err := latestMigrationVersion(store.Migrations())
+	if err != nil {
+		return err
+	}
+	if version > latest {
+		// older versions are migrated on start, newer ones are unknown to this version
+		return fmt.Errorf("migration version %d is newer than %d supported by this riportd, upgrade riportd before restoring", version, latest)
+	}
+	return nil
+}
+
+// checkNotInUse fails if a running server holds the lock of a bolt database
+func checkNotInUse(store *Store) error {
+	if store.Type != StoreBolt {
+		return nil
+	}
+	if _, err := os.Stat(store.Path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	db, err := bbolt.Open(store.Path, 0600, &bbolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		if errors.Is(err, bbolt.ErrTimeout) {
+			return fmt.Errorf("%s is in use, stop riportd before restoring", store.Path)
+		}
+		return err
+	}
+	return db.Close()
+}
+
+func replace(src string, store *Store) error {
+	err := os.MkdirAll(filepath.Dir(store.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp := store.Path + restoreSuffix
+	err = copyFile(src, tmp)
+	if err != nil {
+		return err
+	}
+
+	if store.Type == StoreSQLite {
+		// the journal of the replaced database must not be applied to the restored one
+		for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+			if err := os.Remove(store.Path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return os.Rename(tmp, store.Path)
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"path/filepath"
+
+	accessgrantsmigration "github.com/riportdev/riport/db/migration/access_grants"
+	"github.com/riportdev/riport/db/migration/api_sessions"
+	"github.com/riportdev/riport/db/migration/api_token"
+	approvalsmigration "github.com/riportdev/riport/db/migration/approvals"
+	auditlogmigration "github.com/riportdev/riport/db/migration/auditlog"
+	"github.com/riportdev/riport/db/migration/client_groups"
+	clientsmigration "github.com/riportdev/riport/db/migration/clients"
+	inventorymigration "github.com/riportdev/riport/db/migration/inventory"
+	jobsmigration "github.com/riportdev/riport/db/migration/jobs"
+	"github.com/riportdev/riport/db/migration/library"
+	monitoringmigration "github.com/riportdev/riport/db/migration/monitoring"
+	rbacmigration "github.com/riportdev/riport/db/migration/rbac"
+	scimmigration "github.com/riportdev/riport/db/migration/scim"
+	tunnelconnsmigration "github.com/riportdev/riport/db/migration/tunnel_connections"
+	"github.com/riportdev/riport/db/migration/vaults"
+	"github.com/riportdev/riport/server/chconfig"
+	notificationsSQLite "github.com/riportdev/riport/server/notifications/repository/sqlite"
+)
+
+const AlertsStoreName = "alerts.boltdb"
+
+var dataDirDatabases = []struct {
+	name       string
+	migrations func() []string
+}{
+	{"clients.db", clientsmigration.AssetNames},
+	{"client_groups.db", client_groups.AssetNames},
+	{"jobs.db", jobsmigration.AssetNames},
+	{"library.db", library.AssetNames},
+	{chconfig.DefaultVaultDBName, vaults.AssetNames},
+	{"monitoring.db", monitoringmigration.AssetNames},
+	{"notifications.db", notificationsSQLite.AssetNames},
+	{"api_sessions.db", api_sessions.AssetNames},
+	{"api_token.db", api_token.AssetNames},
+	{"approvals.db", approvalsmigration.AssetNames},
+	{"access_grants.db", accessgrantsmigration.AssetNames},
+	{"rbac.db", rbacmigration.AssetNames},
+	{"scim.db", scimmigration.AssetNames},
+	{"tunnel_connections.db", tunnelconnsmigration.AssetNames},
+	{"inventory.db", inventorymigration.AssetNames},
+	{"auditlog.db", auditlogmigration.AssetNames},
+}
+
+// Stores returns the databases and files holding the data of a server with the given config.
+// The cluster database isn't included, it only holds the state of running nodes.
+func Stores(config *chconfig.Config) []*Store {
+	dataDir := config.Server.DataDir
+	stores := make([]*Store, 0, len(dataDirDatabases)+4)
+	for _, db := range dataDirDatabases {
+		stores = append(stores, &Store{
+			Name:       db.name,
+			Path:       filepath.Join(dataDir, db.name),
+			Type:       StoreSQLite,
+			Migrations: db.migrations,
+		})
+	}
+
+	stores = append(stores, &Store{
+		Name: AlertsStoreName,
+		Path: filepath.Join(dataDir, AlertsStoreName),
+		Type: StoreBolt,
+	})
+
+	if config.Server.AuthFile != "" {
+		stores = append(stores, &Store{
+			Name: "auth/clients-auth.json",
+			Path: config.Server.AuthFile,
+			Type: StoreFile,
+		})
+	}
+	if config.API.AuthFile != "" {
+		stores = append(stores, &Store{
+			Name: "auth/users.json",
+			Path: config.API.AuthFile,
+			Type: StoreFile,
+		})
+	}
+	if config.Database.Type == "sqlite" && config.Database.Name != "" {
+		// user and client auth tables, they are created by the admin without migrations
+		stores = append(stores, &Store{
+			Name: "auth/database.db",
+			Path: config.Database.Name,
+			Type: StoreSQLite,
+		})
+	}
+
+	return stores
+}
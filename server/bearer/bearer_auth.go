@@ -53,6 +53,17 @@ var ScopesTotPCreateOnly = []Scope{
 	},
 }
 
+var ScopesWebAuthnRegisterOnly = []Scope{
+	{
+		URI:    routes.AllRoutesPrefix + routes.WebAuthnRegistrationRoute,
+		Method: http.MethodPost,
+	},
+	{
+		URI:    routes.AllRoutesPrefix + routes.WebAuthnCredentialsRoute,
+		Method: http.MethodPost,
+	},
+}
+
 var Scopes2FaCheckOnly = []Scope{
 	{
 		URI:    routes.AllRoutesPrefix + routes.Verify2FaRoute,
@@ -0,0 +1,527 @@
+package cgroups
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/go-version"
+)
+
+type ExpressionType string
+
+const (
+	ExpressionTypeString   ExpressionType = "string"
+	ExpressionTypeStrings  ExpressionType = "strings"
+	ExpressionTypeNumber   ExpressionType = "number"
+	ExpressionTypeBool     ExpressionType = "bool"
+	ExpressionTypeDuration ExpressionType = "duration"
+)
+
+// ExpressionLabelsPrefix is the prefix of the variables of the client labels, e.g. labels.env
+const ExpressionLabelsPrefix = "labels."
+
+// ExpressionVariableTypes are the variables that can be used in expressions besides the labels
+var ExpressionVariableTypes = map[string]ExpressionType{
+	"id":                       ExpressionTypeString,
+	"name":                     ExpressionTypeString,
+	"os":                       ExpressionTypeString,
+	"os_arch":                  ExpressionTypeString,
+	"os_family":                ExpressionTypeString,
+	"os_kernel":                ExpressionTypeString,
+	"os_full_name":             ExpressionTypeString,
+	"os_version":               ExpressionTypeString,
+	"os_virtualization_system": ExpressionTypeString,
+	"os_virtualization_role":   ExpressionTypeString,
+	"cpu_vendor":               ExpressionTypeString,
+	"cpu_model_name":           ExpressionTypeString,
+	"timezone":                 ExpressionTypeString,
+	"hostname":                 ExpressionTypeString,
+	"version":                  ExpressionTypeString,
+	"address":                  ExpressionTypeString,
+	"client_auth_id":           ExpressionTypeString,
+	"connection_state":         ExpressionTypeString,
+	"tags":                     ExpressionTypeStrings,
+	"ipv4":                     ExpressionTypeStrings,
+	"ipv6":                     ExpressionTypeStrings,
+	"num_cpus":                 ExpressionTypeNumber,
+	"mem_total":                ExpressionTypeNumber,
+	"updates.available":        ExpressionTypeNumber,
+	"updates.security":         ExpressionTypeNumber,
+	"updates.reboot_pending":   ExpressionTypeBool,
+	"connection_age":           ExpressionTypeDuration,
+}
+
+// ExpressionVariables returns the value of a variable for a client. Values are string, []string, float64, bool and
+// time.Duration according to the type of the variable or nil if the value is unknown.
+type ExpressionVariables func(name string) interface{}
+
+// Expression is a rule clients must match to belong to a group, e.g.
+//
+//	labels.env == "prod" && (updates.security > 0 || updates.reboot_pending) && version >= "0.9.0"
+//
+// Strings are compared case-insensitive with * wildcards like the other params, ordered comparisons of strings
+// compare versions. It's parsed on first use.
+type Expression struct {
+	source string
+
+	once sync.Once
+	root exprNode
+	err  error
+}
+
+func NewExpression(source string) *Expression {
+	return &Expression{source: source}
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func (e *Expression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.source)
+}
+
+func (e *Expression) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &e.source)
+}
+
+// Validate parses the expression and returns syntax errors and unknown variables or wrong types
+func (e *Expression) Validate() error {
+	e.once.Do(func() {
+		e.root, e.err = parseExpression(e.source)
+	})
+	return e.err
+}
+
+// Matches returns true if there's no expression, invalid expressions never match
+func (e *Expression) Matches(vars ExpressionVariables) bool {
+	if e == nil {
+		return true
+	}
+	if e.Validate() != nil {
+		return false
+	}
+	return e.root.eval(vars)
+}
+
+type exprNode interface {
+	eval(vars ExpressionVariables) bool
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n *orNode) eval(vars ExpressionVariables) bool {
+	return n.left.eval(vars) || n.right.eval(vars)
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n *andNode) eval(vars ExpressionVariables) bool {
+	return n.left.eval(vars) && n.right.eval(vars)
+}
+
+type notNode struct {
+	node exprNode
+}
+
+func (n *notNode) eval(vars ExpressionVariables) bool {
+	return !n.node.eval(vars)
+}
+
+type boolVarNode struct {
+	name string
+}
+
+func (n *boolVarNode) eval(vars ExpressionVariables) bool {
+	v, _ := vars(n.name).(bool)
+	return v
+}
+
+type compareNode struct {
+	name     string
+	operator string
+	values   []interface{}
+}
+
+// eval compares the variable with the values, comparisons with unknown values never match
+func (n *compareNode) eval(vars ExpressionVariables) bool {
+	v := vars(n.name)
+	if v == nil {
+		return false
+	}
+	switch n.operator {
+	case "in":
+		for _, value := range n.values {
+			if equals(v, value) {
+				return true
+			}
+		}
+		return false
+	case "==":
+		return equals(v, n.values[0])
+	case "!=":
+		return !equals(v, n.values[0])
+	}
+
+	c, ok := compare(v, n.values[0])
+	if !ok {
+		return false
+	}
+	switch n.operator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func equals(v, value interface{}) bool {
+	switch actual := v.(type) {
+	case string:
+		return Param(value.(string)).matches(actual)
+	case []string:
+		for _, cur := range actual {
+			if Param(value.(string)).matches(cur) {
+				return true
+			}
+		}
+		return false
+	}
+	return v == value
+}
+
+func compare(v, value interface{}) (int, bool) {
+	switch actual := v.(type) {
+	case string:
+		return compareVersions(actual, value.(string)), true
+	case float64:
+		return compareOrdered(actual, value.(float64)), true
+	case time.Duration:
+		return compareOrdered(actual, value.(time.Duration)), true
+	}
+	return 0, false
+}
+
+func compareVersions(a, b string) int {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
+
+func compareOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '"' || r == '\'':
+			i++
+			var sb strings.Builder
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:i]), value: sb.String(), pos: start + 1})
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			number, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", string(runes[start:i]), start+1)
+			}
+			unitStart := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			if unitStart == i {
+				tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), value: number, pos: start + 1})
+				break
+			}
+			unit, ok := durationUnits[string(runes[unitStart:i])]
+			if !ok {
+				return nil, fmt.Errorf("invalid duration %q at position %d, use one of the units s, m, h, d and w", string(runes[start:i]), start+1)
+			}
+			tokens = append(tokens, token{kind: tokenDuration, text: string(runes[start:i]), value: time.Duration(number * float64(unit)), pos: start + 1})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_.-", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start + 1})
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start+1)
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start + 1})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(runes) + 1}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseExpression(source string) (exprNode, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.text == op
+}
+
+func (p *parser) expectOperator(op string) error {
+	if !p.isOperator(op) {
+		return fmt.Errorf("expected %q at position %d, got %s", op, p.peek().pos, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	return fmt.Errorf("unexpected %s at position %d", t.text, t.pos)
+}
+
+func (p *parser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (exprNode, error) {
+	if p.isOperator("!") {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	}
+	if p.isOperator("(") {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expectOperator(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (exprNode, error) {
+	name, typ, err := p.parseVariable()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokenIdent && t.text == "in":
+		p.next()
+		values, err := p.parseList(name, typ)
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{name: name, operator: "in", values: values}, nil
+	case t.kind == tokenOperator && (t.text == "==" || t.text == "!="):
+	case t.kind == tokenOperator && (t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		if typ == ExpressionTypeStrings || typ == ExpressionTypeBool {
+			return nil, fmt.Errorf("%s can't be compared with %s at position %d", name, t.text, t.pos)
+		}
+	default:
+		if typ == ExpressionTypeBool {
+			return &boolVarNode{name: name}, nil
+		}
+		return nil, fmt.Errorf("expected an operator after %s at position %d, got %s", name, t.pos, t.text)
+	}
+	p.next()
+
+	value, err := p.parseLiteral(name, typ)
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{name: name, operator: t.text, values: []interface{}{value}}, nil
+}
+
+// parseVariable parses a variable like os_family, labels.env or labels["app name"]
+func (p *parser) parseVariable() (string, ExpressionType, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return "", "", fmt.Errorf("expected a variable at position %d, got %s", t.pos, t.text)
+	}
+	if t.text == "labels" && p.isOperator("[") {
+		p.next()
+		key := p.next()
+		if key.kind != tokenString {
+			return "", "", fmt.Errorf("expected a label name at position %d, got %s", key.pos, key.text)
+		}
+		if err := p.expectOperator("]"); err != nil {
+			return "", "", err
+		}
+		return ExpressionLabelsPrefix + key.value.(string), ExpressionTypeString, nil
+	}
+	if strings.HasPrefix(t.text, ExpressionLabelsPrefix) && len(t.text) > len(ExpressionLabelsPrefix) {
+		return t.text, ExpressionTypeString, nil
+	}
+	typ, ok := ExpressionVariableTypes[t.text]
+	if !ok {
+		return "", "", fmt.Errorf("unknown variable %s at position %d", t.text, t.pos)
+	}
+	return t.text, typ, nil
+}
+
+func (p *parser) parseList(name string, typ ExpressionType) ([]interface{}, error) {
+	if err := p.expectOperator("["); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseLiteral(name, typ)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if !p.isOperator(",") {
+			break
+		}
+		p.next()
+	}
+	return values, p.expectOperator("]")
+}
+
+func (p *parser) parseLiteral(name string, typ ExpressionType) (interface{}, error) {
+	t := p.next()
+	switch {
+	case (typ == ExpressionTypeString || typ == ExpressionTypeStrings) && t.kind == tokenString,
+		typ == ExpressionTypeNumber && t.kind == tokenNumber,
+		typ == ExpressionTypeDuration && t.kind == tokenDuration:
+		return t.value, nil
+	case typ == ExpressionTypeBool && t.kind == tokenIdent && (t.text == "true" || t.text == "false"):
+		return t.text == "true", nil
+	}
+	return nil, fmt.Errorf("expected a %s value for %s at position %d, got %s", typ, name, t.pos, t.text)
+}
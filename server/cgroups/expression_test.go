@@ -0,0 +1,112 @@
+package cgroups
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionMatches(t *testing.T) {
+	values := map[string]interface{}{
+		"labels.env":             "prod",
+		"labels.app name":        "Web Shop",
+		"labels.team":            "",
+		"os_virtualization_role": "guest",
+		"version":                "0.9.12",
+		"tags":                   []string{"Linux", "Datacenter 3"},
+		"num_cpus":               float64(4),
+		"updates.security":       float64(2),
+		"updates.reboot_pending": true,
+		"connection_age":         36 * time.Hour,
+	}
+	vars := func(name string) interface{} {
+		return values[name]
+	}
+
+	testCases := []struct {
+		expression string
+		wantRes    bool
+	}{
+		{expression: `labels.env == "prod"`, wantRes: true},
+		{expression: `labels.env == "PR*"`, wantRes: true},
+		{expression: `labels.env != "prod"`, wantRes: false},
+		{expression: `labels["app name"] == 'web shop'`, wantRes: true},
+		{expression: `labels.team == ""`, wantRes: true},
+		{expression: `updates.security > 0`, wantRes: true},
+		{expression: `updates.security >= 3`, wantRes: false},
+		{expression: `updates.reboot_pending`, wantRes: true},
+		{expression: `!updates.reboot_pending`, wantRes: false},
+		{expression: `updates.reboot_pending == false`, wantRes: false},
+		{expression: `os_virtualization_role in ["host", "guest"]`, wantRes: true},
+		{expression: `version >= "0.9.2" && version < "1.0.0"`, wantRes: true},
+		{expression: `version < "0.9.2"`, wantRes: false},
+		{expression: `tags == "datacenter*"`, wantRes: true},
+		{expression: `tags != "Linux"`, wantRes: false},
+		{expression: `connection_age > 1d`, wantRes: true},
+		{expression: `connection_age > 1.5d`, wantRes: false},
+		{expression: `num_cpus >= 4 && (labels.env == "test" || updates.security > 1)`, wantRes: true},
+		{expression: `labels.env == "test" || num_cpus < 2 && updates.reboot_pending`, wantRes: false},
+		{expression: `!(labels.env == "test")`, wantRes: true},
+		// unknown values never match
+		{expression: `updates.available > 0`, wantRes: false},
+		{expression: `updates.available == 0`, wantRes: false},
+		{expression: `updates.available != 0`, wantRes: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			e := NewExpression(tc.expression)
+			require.NoError(t, e.Validate())
+			assert.Equal(t, tc.wantRes, e.Matches(vars))
+		})
+	}
+
+	var noExpression *Expression
+	assert.True(t, noExpression.Matches(vars))
+}
+
+func TestExpressionValidate(t *testing.T) {
+	testCases := []struct {
+		expression string
+		wantErr    string
+	}{
+		{expression: ``, wantErr: "invalid expression: expected a variable at position 1, got end of expression"},
+		{expression: `foo == "bar"`, wantErr: "invalid expression: unknown variable foo at position 1"},
+		{expression: `labels.env = "prod"`, wantErr: `invalid expression: unexpected character '=' at position 12`},
+		{expression: `labels.env == "prod`, wantErr: "invalid expression: unterminated string at position 15"},
+		{expression: `labels.env == prod`, wantErr: "invalid expression: expected a string value for labels.env at position 15, got prod"},
+		{expression: `updates.security > "1"`, wantErr: `invalid expression: expected a number value for updates.security at position 20, got "1"`},
+		{expression: `connection_age > 2y`, wantErr: `invalid expression: invalid duration "2y" at position 18, use one of the units s, m, h, d and w`},
+		{expression: `tags < "b"`, wantErr: "invalid expression: tags can't be compared with < at position 6"},
+		{expression: `labels.env`, wantErr: "invalid expression: expected an operator after labels.env at position 11, got end of expression"},
+		{expression: `(num_cpus > 1`, wantErr: `invalid expression: expected ")" at position 14, got end of expression`},
+		{expression: `num_cpus > 1 num_cpus < 8`, wantErr: "invalid expression: unexpected num_cpus at position 14"},
+		{expression: `os in ["linux" "windows"]`, wantErr: `invalid expression: expected "]" at position 16, got "windows"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			err := NewExpression(tc.expression).Validate()
+			require.Error(t, err)
+			assert.Equal(t, tc.wantErr, err.Error())
+			// invalid expressions never match
+			assert.False(t, NewExpression(tc.expression).Matches(func(string) interface{} { return "" }))
+		})
+	}
+}
+
+func TestExpressionJSON(t *testing.T) {
+	var params ClientParams
+	err := json.Unmarshal([]byte(`{"expression": "labels.env == \"prod\""}`), &params)
+	require.NoError(t, err)
+	require.NotNil(t, params.Expression)
+	assert.Equal(t, `labels.env == "prod"`, params.Expression.String())
+	assert.False(t, params.HasNoParams())
+
+	b, err := json.Marshal(params.Expression)
+	require.NoError(t, err)
+	assert.Equal(t, `"labels.env == \"prod\""`, string(b))
+}
@@ -65,6 +65,12 @@ type ClientParams struct {
 	Address         *ParamValues     `json:"address"`
 	ClientAuthID    *ParamValues     `json:"client_auth_id"`
 	ConnectionState *ParamValues     `json:"connection_state"`
+	Package         *ParamValues     `json:"package"`
+	ListeningPort   *ParamValues     `json:"listening_port"`
+	LocalUser       *ParamValues     `json:"local_user"`
+	SystemVendor    *ParamValues     `json:"system_vendor"`
+	SystemModel     *ParamValues     `json:"system_model"`
+	Expression      *Expression      `json:"expression"`
 }
 
 type Param string
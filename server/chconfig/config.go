@@ -25,6 +25,7 @@ import (
 	"github.com/riportdev/riport/db/sqlite"
 	rportplus "github.com/riportdev/riport/plus"
 	"github.com/riportdev/riport/server/caddy"
+	"github.com/riportdev/riport/server/cluster"
 
 	"github.com/riportdev/riport/share/files"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/riportdev/riport/server/api/message"
 	auditlog "github.com/riportdev/riport/server/auditlog/config"
 	"github.com/riportdev/riport/server/bearer"
+	"github.com/riportdev/riport/server/clientcerts"
 	"github.com/riportdev/riport/server/clients/clienttunnel"
 	"github.com/riportdev/riport/server/ports"
 	chshare "github.com/riportdev/riport/share"
@@ -72,6 +74,8 @@ type APIConfig struct {
 	MaxFilePushSize        int64    `mapstructure:"max_filepush_size"`
 	CORS                   []string `mapstructure:"cors"`
 
+	FilePushResumeTimeout time.Duration `mapstructure:"filepush_resume_timeout"`
+
 	TwoFATokenDelivery       string                 `mapstructure:"two_fa_token_delivery"`
 	TwoFATokenTTLSeconds     int                    `mapstructure:"two_fa_token_ttl_seconds"`
 	TwoFASendTimeout         time.Duration          `mapstructure:"two_fa_send_timeout"`
@@ -83,6 +87,12 @@ type APIConfig struct {
 	TotPEnabled             bool            `mapstructure:"totp_enabled"`
 	TotPLoginSessionTimeout time.Duration   `mapstructure:"totp_login_session_ttl"`
 	TotPAccountName         string          `mapstructure:"totp_account_name"`
+
+	WebAuthnEnabled           bool     `mapstructure:"webauthn_enabled"`
+	WebAuthnRPID              string   `mapstructure:"webauthn_rp_id"`
+	WebAuthnRPName            string   `mapstructure:"webauthn_rp_name"`
+	WebAuthnRPOrigins         []string `mapstructure:"webauthn_rp_origins"`
+	WebAuthnRequiredForAdmins bool     `mapstructure:"webauthn_required_for_admins"`
 }
 
 func (c *APIConfig) IsTwoFAOn() bool {
@@ -156,7 +166,18 @@ type ServerConfig struct {
 	BanTime                              int                                    `mapstructure:"ban_time"`
 	InternalTunnelProxyConfig            clienttunnel.InternalTunnelProxyConfig `mapstructure:",squash"`
 	JobsMaxResults                       int                                    `mapstructure:"jobs_max_results"`
+	TunnelConnectionsRetention           string                                 `mapstructure:"tunnel_connections_retention"`
 	AcmeHTTPPort                         int                                    `mapstructure:"acme_http_port"`
+	TLSCertFile                          string                                 `mapstructure:"tls_cert_file"`
+	TLSKeyFile                           string                                 `mapstructure:"tls_key_file"`
+	ClientTLSMin                         string                                 `mapstructure:"client_tls_min"`
+	ClientCAFile                         string                                 `mapstructure:"client_ca_file"`
+	ClientCAKeyFile                      string                                 `mapstructure:"client_ca_key_file"`
+	ClientCRLFile                        string                                 `mapstructure:"client_crl_file"`
+	ClientOCSPFile                       string                                 `mapstructure:"client_ocsp_file"`
+	ClientCertIdentity                   string                                 `mapstructure:"client_cert_identity"`
+	ClientCertRequired                   bool                                   `mapstructure:"client_cert_required"`
+	ClientCertValidity                   time.Duration                          `mapstructure:"client_cert_validity"`
 
 	// DEPRECATED, only here for backwards compatibility
 	MaxRequestBytes       int64 `mapstructure:"max_request_bytes"`
@@ -166,6 +187,9 @@ type ServerConfig struct {
 	allowedPorts mapset.Set
 	AuthID       string
 	AuthPassword string
+
+	// cached version of TunnelConnectionsRetention as real time.Duration, 0 disables the tunnel connection log
+	tunnelConnectionsRetention time.Duration
 }
 
 type DatabaseConfig struct {
@@ -330,6 +354,7 @@ func (n *NotificationsConfig) parseAndValidateCleanup() error {
 type Config struct {
 	Server        ServerConfig         `mapstructure:"server"`
 	Caddy         caddy.Config         `mapstructure:"caddy-integration"`
+	Cluster       cluster.Config       `mapstructure:"cluster"`
 	Logging       LogConfig            `mapstructure:"logging"`
 	API           APIConfig            `mapstructure:"api"`
 	Database      DatabaseConfig       `mapstructure:"database"`
@@ -352,6 +377,10 @@ func (c *Config) GetUploadDir() string {
 	return filepath.Join(c.Server.DataDir, files.DefaultUploadTempFolder)
 }
 
+func (s *ServerConfig) GetTunnelConnectionsRetention() time.Duration {
+	return s.tunnelConnectionsRetention
+}
+
 func (s *ServerConfig) GetSQLiteDataSourceOptions() sqlite.DataSourceOptions {
 	return sqlite.DataSourceOptions{WALEnabled: s.SqliteWAL}
 }
@@ -402,6 +431,14 @@ func (c *Config) ParseAndValidate(mLog *logger.MemLogger) error {
 	if err := c.Server.InternalTunnelProxyConfig.ParseAndValidate(); err != nil {
 		return err
 	}
+
+	if c.Server.TunnelConnectionsRetention != "" {
+		// we need to do this conversion as time.Duration doesn't support days
+		c.Server.tunnelConnectionsRetention, err = convertHourOrDayStringToDuration("tunnel_connections_retention", c.Server.TunnelConnectionsRetention)
+		if err != nil {
+			return err
+		}
+	}
 	c.Server.InternalTunnelProxyConfig.CORS = parseAndValidateCORS(mLog, c.Server.InternalTunnelProxyConfig.CORS)
 
 	filesAPI := files.NewFileSystem()
@@ -425,6 +462,10 @@ func (c *Config) ParseAndValidate(mLog *logger.MemLogger) error {
 		return err
 	}
 
+	if err := c.Server.parseAndValidateClientTLS(); err != nil {
+		return err
+	}
+
 	if err := c.parseAndValidateAPI(mLog); err != nil {
 		return fmt.Errorf("API: %v", err)
 	}
@@ -454,6 +495,10 @@ func (c *Config) ParseAndValidate(mLog *logger.MemLogger) error {
 		return err
 	}
 
+	if err := c.Cluster.ParseAndValidate(c.Server.DataDir, c.API.Address); err != nil {
+		return fmt.Errorf("cluster: %v", err)
+	}
+
 	return nil
 }
 
@@ -486,6 +531,44 @@ func (c *Config) parseAndValidateClientAuth() error {
 	return nil
 }
 
+func (s *ServerConfig) parseAndValidateClientTLS() error {
+	if s.TLSCertFile != "" && s.TLSKeyFile == "" {
+		return errors.New("when 'tls_cert_file' is set, 'tls_key_file' must be set as well")
+	}
+	if s.TLSCertFile == "" && s.TLSKeyFile != "" {
+		return errors.New("when 'tls_key_file' is set, 'tls_cert_file' must be set as well")
+	}
+	if s.ClientTLSMin != "" && s.ClientTLSMin != "1.2" && s.ClientTLSMin != "1.3" {
+		return errors.New("'client_tls_min' must be either 1.2 or 1.3")
+	}
+
+	if s.ClientCAFile == "" {
+		if s.ClientCAKeyFile != "" || s.ClientCRLFile != "" || s.ClientOCSPFile != "" || s.ClientCertRequired {
+			return errors.New("'client_ca_key_file', 'client_crl_file', 'client_ocsp_file' and 'client_cert_required' require 'client_ca_file'")
+		}
+		return nil
+	}
+	if s.TLSCertFile == "" {
+		return errors.New("'client_ca_file' requires 'tls_cert_file' and 'tls_key_file'")
+	}
+	if err := clientcerts.ValidateIdentity(s.ClientCertIdentity); err != nil {
+		return fmt.Errorf("'client_cert_identity': %v", err)
+	}
+	if s.ClientCAKeyFile != "" && s.ClientCertValidity <= 0 {
+		return errors.New("'client_cert_validity' must be positive when 'client_ca_key_file' is set")
+	}
+
+	return nil
+}
+
+// GetClientTLSMin returns the minimal TLS version of the client connections, it defaults to {tls_min}
+func (s *ServerConfig) GetClientTLSMin() string {
+	if s.ClientTLSMin != "" {
+		return s.ClientTLSMin
+	}
+	return s.InternalTunnelProxyConfig.TLSMin
+}
+
 func (c *Config) parseAndValidateAPI(mLog *logger.MemLogger) error {
 	if c.API.Address != "" {
 		// API enabled
@@ -517,6 +600,11 @@ func (c *Config) parseAndValidateAPI(mLog *logger.MemLogger) error {
 			return err
 		}
 
+		err = c.parseAndValidateWebAuthn()
+		if err != nil {
+			return err
+		}
+
 		if c.API.TLSMin != "" && c.API.TLSMin != "1.2" && c.API.TLSMin != "1.3" {
 			return errors.New("TLS must be either 1.2 or 1.3")
 		}
@@ -695,6 +783,49 @@ func (c *Config) parseAndValidateTotPSecret() error {
 	return nil
 }
 
+func (c *Config) parseAndValidateWebAuthn() error {
+	if !c.API.WebAuthnEnabled {
+		if c.API.WebAuthnRequiredForAdmins {
+			return errors.New("webauthn_required_for_admins requires webauthn_enabled")
+		}
+		return nil
+	}
+
+	if c.API.Auth != "" {
+		return errors.New("WebAuthn is not available if you use a single static user-password pair")
+	}
+
+	if len(c.API.WebAuthnRPOrigins) == 0 {
+		return errors.New("webauthn_rp_origins is required if webauthn is enabled")
+	}
+
+	hosts := make([]string, 0, len(c.API.WebAuthnRPOrigins))
+	for i, origin := range c.API.WebAuthnRPOrigins {
+		if err := validateHTTPorHTTPSURL(origin); err != nil {
+			return fmt.Errorf("invalid webauthn_rp_origins: %w", err)
+		}
+		u, _ := url.Parse(origin)
+		if u.Path != "" && u.Path != "/" {
+			return fmt.Errorf("invalid webauthn_rp_origins: origin %s must not contain a path", origin)
+		}
+		// browsers send the origin without a trailing slash
+		c.API.WebAuthnRPOrigins[i] = strings.TrimSuffix(origin, "/")
+		hosts = append(hosts, u.Hostname())
+	}
+
+	if c.API.WebAuthnRPID == "" {
+		c.API.WebAuthnRPID = hosts[0]
+	}
+	// the relying party ID must be the host of every origin or a registrable suffix of it
+	for i, host := range hosts {
+		if host != c.API.WebAuthnRPID && !strings.HasSuffix(host, "."+c.API.WebAuthnRPID) {
+			return fmt.Errorf("webauthn_rp_id %q doesn't match the origin %s", c.API.WebAuthnRPID, c.API.WebAuthnRPOrigins[i])
+		}
+	}
+
+	return nil
+}
+
 func (c *Config) parseAndValidate2FA() error {
 	if c.API.TwoFATokenDelivery == "" {
 		return nil
@@ -808,7 +939,11 @@ func (s *ServerConfig) parseAndValidatePorts() error {
 
 func (s *ServerConfig) parseAndValidateURLs() error {
 	if len(s.URL) == 0 {
-		s.URL = []string{"http://" + s.ListenAddress}
+		scheme := "http://"
+		if s.TLSCertFile != "" {
+			scheme = "https://"
+		}
+		s.URL = []string{scheme + s.ListenAddress}
 	}
 
 	for _, v := range s.URL {
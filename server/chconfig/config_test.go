@@ -285,6 +285,110 @@ func TestParseAndValidateClientAuth(t *testing.T) {
 	}
 }
 
+func TestParseAndValidateClientTLS(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Config        ServerConfig
+		ExpectedError string
+	}{
+		{
+			Name:   "no tls",
+			Config: ServerConfig{},
+		}, {
+			Name: "cert without key",
+			Config: ServerConfig{
+				TLSCertFile: "server.crt",
+			},
+			ExpectedError: "when 'tls_cert_file' is set, 'tls_key_file' must be set as well",
+		}, {
+			Name: "client ca without tls",
+			Config: ServerConfig{
+				ClientCAFile:       "ca.crt",
+				ClientCertIdentity: "common_name",
+			},
+			ExpectedError: "'client_ca_file' requires 'tls_cert_file' and 'tls_key_file'",
+		}, {
+			Name: "crl without client ca",
+			Config: ServerConfig{
+				TLSCertFile:   "server.crt",
+				TLSKeyFile:    "server.key",
+				ClientCRLFile: "ca.crl",
+			},
+			ExpectedError: "'client_ca_key_file', 'client_crl_file', 'client_ocsp_file' and 'client_cert_required' require 'client_ca_file'",
+		}, {
+			Name: "ocsp without client ca",
+			Config: ServerConfig{
+				TLSCertFile:    "server.crt",
+				TLSKeyFile:     "server.key",
+				ClientOCSPFile: "ca.ocsp",
+			},
+			ExpectedError: "'client_ca_key_file', 'client_crl_file', 'client_ocsp_file' and 'client_cert_required' require 'client_ca_file'",
+		}, {
+			Name: "invalid client tls min",
+			Config: ServerConfig{
+				TLSCertFile:  "server.crt",
+				TLSKeyFile:   "server.key",
+				ClientTLSMin: "1.1",
+			},
+			ExpectedError: "'client_tls_min' must be either 1.2 or 1.3",
+		}, {
+			Name: "invalid identity",
+			Config: ServerConfig{
+				TLSCertFile:        "server.crt",
+				TLSKeyFile:         "server.key",
+				ClientCAFile:       "ca.crt",
+				ClientCertIdentity: "serial",
+			},
+			ExpectedError: `'client_cert_identity': invalid client certificate identity "serial", expected one of "common_name", "dns_san", "uri_san", "email_san"`,
+		}, {
+			Name: "built-in ca without validity",
+			Config: ServerConfig{
+				TLSCertFile:        "server.crt",
+				TLSKeyFile:         "server.key",
+				ClientCAFile:       "ca.crt",
+				ClientCAKeyFile:    "ca.key",
+				ClientCertIdentity: "common_name",
+			},
+			ExpectedError: "'client_cert_validity' must be positive when 'client_ca_key_file' is set",
+		}, {
+			Name: "valid",
+			Config: ServerConfig{
+				TLSCertFile:        "server.crt",
+				TLSKeyFile:         "server.key",
+				ClientCAFile:       "ca.crt",
+				ClientCAKeyFile:    "ca.key",
+				ClientCRLFile:      "ca.crl",
+				ClientOCSPFile:     "ca.ocsp",
+				ClientTLSMin:       "1.2",
+				ClientCertIdentity: "dns_san",
+				ClientCertRequired: true,
+				ClientCertValidity: 24 * time.Hour,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Config.parseAndValidateClientTLS()
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetClientTLSMin(t *testing.T) {
+	config := ServerConfig{
+		InternalTunnelProxyConfig: clienttunnel.InternalTunnelProxyConfig{TLSMin: "1.3"},
+	}
+	assert.Equal(t, "1.3", config.GetClientTLSMin())
+
+	config.ClientTLSMin = "1.2"
+	assert.Equal(t, "1.2", config.GetClientTLSMin())
+}
+
 func TestParseAndValidateAPI(t *testing.T) {
 	testCases := []struct {
 		Name                 string
@@ -600,6 +704,65 @@ func TestParseAndValidateAPI(t *testing.T) {
 			},
 			ExpectedError: "API: conflicting 2FA configuration, two factor auth and totp_enabled options cannot be both enabled",
 		},
+		{
+			Name: "api enabled, webauthn enabled ok",
+			Config: Config{
+				API: APIConfig{
+					Address:           "0.0.0.0:3000",
+					AuthFile:          "test.json",
+					WebAuthnEnabled:   true,
+					WebAuthnRPOrigins: []string{"https://rport.example.com/", "https://eu.rport.example.com:3000"},
+					WebAuthnRPID:      "rport.example.com",
+				},
+			},
+		},
+		{
+			Name: "api enabled, webauthn enabled, single user auth",
+			Config: Config{
+				API: APIConfig{
+					Address:           "0.0.0.0:3000",
+					Auth:              "abc:def",
+					WebAuthnEnabled:   true,
+					WebAuthnRPOrigins: []string{"https://rport.example.com"},
+				},
+			},
+			ExpectedError: "API: WebAuthn is not available if you use a single static user-password pair",
+		},
+		{
+			Name: "api enabled, webauthn enabled, no origins",
+			Config: Config{
+				API: APIConfig{
+					Address:         "0.0.0.0:3000",
+					AuthFile:        "test.json",
+					WebAuthnEnabled: true,
+				},
+			},
+			ExpectedError: "API: webauthn_rp_origins is required if webauthn is enabled",
+		},
+		{
+			Name: "api enabled, webauthn enabled, rp id doesn't match origin",
+			Config: Config{
+				API: APIConfig{
+					Address:           "0.0.0.0:3000",
+					AuthFile:          "test.json",
+					WebAuthnEnabled:   true,
+					WebAuthnRPOrigins: []string{"https://rport.example.com"},
+					WebAuthnRPID:      "example.org",
+				},
+			},
+			ExpectedError: "API: webauthn_rp_id \"example.org\" doesn't match the origin https://rport.example.com",
+		},
+		{
+			Name: "api enabled, webauthn required for admins but disabled",
+			Config: Config{
+				API: APIConfig{
+					Address:                   "0.0.0.0:3000",
+					AuthFile:                  "test.json",
+					WebAuthnRequiredForAdmins: true,
+				},
+			},
+			ExpectedError: "API: webauthn_required_for_admins requires webauthn_enabled",
+		},
 		{
 			Name: "api enabled, max token lifetime outside allowed range, negative",
 			Config: Config{
@@ -0,0 +1,40 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+// saveInventory stores the inventory received from a client and updates the summary client groups match on
+func (s *Server) saveInventory(clientID string, inventory *models.Inventory) error {
+	client, err := s.clientService.GetByID(clientID)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return fmt.Errorf("client %s not found", clientID)
+	}
+
+	summary, err := s.inventory.Save(context.Background(), clientID, inventory)
+	if err != nil {
+		return err
+	}
+	client.SetInventorySummary(summary)
+	return nil
+}
+
+// loadInventorySummaries sets the summaries of the stored inventories on the clients as they aren't persisted with them
+func (s *Server) loadInventorySummaries(ctx context.Context) error {
+	summaries, err := s.inventory.Summaries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load inventory summaries: %w", err)
+	}
+	for _, client := range s.clientService.GetRepo().GetAllClients() {
+		if summary, ok := summaries[client.GetID()]; ok {
+			client.SetInventorySummary(summary)
+		}
+	}
+	return nil
+}
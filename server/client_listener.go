@@ -28,6 +28,7 @@ import (
 	"github.com/riportdev/riport/server/api/middleware"
 	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clientcerts"
 	"github.com/riportdev/riport/server/clients"
 	"github.com/riportdev/riport/server/clients/clientdata"
 	chshare "github.com/riportdev/riport/share"
@@ -70,6 +71,11 @@ type ClientListener struct {
 	bannedClientAuths *security.BanList
 	bannedIPs         *security.MaxBadAttemptsBanList
 
+	// clientCertVerifier is nil unless clients may authenticate with certificates
+	clientCertVerifier *clientcerts.Verifier
+	// clientCA is nil unless the built-in CA issues client certificates
+	clientCA *clientcerts.CA
+
 	clientIndexAutoIncrement int32
 
 	// semaphore used to limit concurrent pending SSH connections
@@ -105,7 +111,6 @@ func NewClientListener(server *Server, privateKey ssh.Signer) (*ClientListener,
 	clog := logger.NewLogger("client-listener", config.Logging.LogOutput, config.Logging.LogLevel)
 	cl := &ClientListener{
 		server:                  server,
-		httpServer:              chshare.NewHTTPServer(int(config.Server.MaxRequestBytesClient), clog),
 		requestLogOptions:       config.InitRequestLogOptions(),
 		bannedClientAuths:       security.NewBanList(time.Duration(config.Server.ClientLoginWait) * time.Second),
 		inprogressSSHHandshakes: inprogressSSHHandshakes,
@@ -120,6 +125,16 @@ func NewClientListener(server *Server, privateKey ssh.Signer) (*ClientListener,
 		)
 	}
 
+	var httpOptions []chshare.ServerOption
+	if config.Server.TLSCertFile != "" {
+		tlsConfig, err := cl.setupTLS(&config.Server)
+		if err != nil {
+			return nil, err
+		}
+		httpOptions = append(httpOptions, chshare.WithTLS(config.Server.TLSCertFile, config.Server.TLSKeyFile, tlsConfig))
+	}
+	cl.httpServer = chshare.NewHTTPServer(int(config.Server.MaxRequestBytesClient), clog, httpOptions...)
+
 	// create ssh config
 	cl.sshConfig = &ssh.ServerConfig{
 		ServerVersion:    "SSH-" + chshare.ProtocolVersion + "-server",
@@ -162,6 +177,10 @@ func (cl *ClientListener) authUser(c ssh.ConnMetadata, password []byte) (*ssh.Pe
 		return nil, ErrTooManyRequests
 	}
 
+	if strings.HasPrefix(clientAuthID, models.EnrollmentTokenUserPrefix) {
+		return cl.authEnrollmentToken(c, password)
+	}
+
 	clientAuth, err := cl.server.clientAuthProvider.Get(clientAuthID)
 	if err != nil {
 		return nil, err
@@ -278,6 +297,12 @@ func (cl *ClientListener) acceptSSHConnection(w http.ResponseWriter, req *http.R
 	clog.Debugf("Handling inbound web socket connection...")
 	ts := time.Now()
 
+	sshConfig, err := cl.sshConfigFor(req)
+	if err != nil {
+		http.Error(w, "client certificate rejected", http.StatusForbidden)
+		return nil, nil, nil, nil, err
+	}
+
 	wsConn, err := upgrader.Upgrade(w, req, nil)
 	if err != nil {
 		clog.Debugf("Failed to upgrade (%s)", err)
@@ -286,7 +311,7 @@ func (cl *ClientListener) acceptSSHConnection(w http.ResponseWriter, req *http.R
 	conn := chshare.NewWebSocketConn(wsConn)
 	// perform SSH handshake on net.Conn
 	clog.Debugf("SSH Handshaking...")
-	sshConn, chans, reqs, err = ssh.NewServerConn(conn, cl.sshConfig)
+	sshConn, chans, reqs, err = ssh.NewServerConn(conn, sshConfig)
 	if err != nil {
 		if strings.Contains(err.Error(), "unexpected EOF") {
 			clog.Debugf("Failed to handshake (client closed connection? - %s) from %s", err, conn.RemoteAddr().String())
@@ -368,11 +393,34 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 
 	// get the current client auth id
 	clientAuthID := sshConn.User()
+	if certClientAuthID := getCertClientAuthID(sshConn); certClientAuthID != "" {
+		clientAuthID = certClientAuthID
+	}
 
-	clientID, err := cl.getClientID(connRequest.ID, cl.server.config, clientAuthID)
-	if err != nil {
-		cl.replyConnectionError(r, fmt.Errorf("could not get clientID: %s", err))
-		return
+	var clientID string
+	// enrolled is nil unless the client connected with an enrollment token
+	var enrolled *enrolledClient
+	if tokenID := getEnrollmentTokenID(sshConn); tokenID != "" {
+		remoteIP := cl.getIP(sshConn.RemoteAddr())
+		enrolled, err = cl.enrollClient(cl.getCtx(), tokenID, connRequest.ID, remoteIP)
+		if err != nil {
+			cl.server.auditLog.Entry(auditlog.ApplicationClientEnrollment, auditlog.ActionFailed).
+				WithRemoteIP(remoteIP).
+				WithID(tokenID).
+				WithResponse(err.Error()).
+				Save()
+			cl.replyConnectionError(r, fmt.Errorf("could not enroll client: %s", err))
+			return
+		}
+		clientAuthID = enrolled.Credentials.ClientAuthID
+		clientID = enrolled.ClientID
+		clientLog.Infof("Client %s enrolled with token %s", clientID, tokenID)
+	} else {
+		clientID, err = cl.getClientID(connRequest.ID, cl.server.config, clientAuthID)
+		if err != nil {
+			cl.replyConnectionError(r, fmt.Errorf("could not get clientID: %s", err))
+			return
+		}
 	}
 
 	ctx, cancel := context.WithCancel(cl.getCtx())
@@ -391,6 +439,18 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 	cl.sendCapabilities(sshConn)
 	// Now the client is fully connected and ready to create tunnels and execute command and scripts
 
+	if enrolled != nil {
+		cl.completeEnrollment(ctx, sshConn, client, enrolled, clientLog.GetLogger())
+	}
+	if chain := verifiedClientChain(req); chain != nil {
+		cl.renewClientCertificate(sshConn, client, clientAuthID, chain[0], clientLog.GetLogger())
+	}
+
+	cl.server.cluster.ClientConnected(ctx, client.GetID())
+	go cl.server.apiListener.deliverPendingJobs(ctx, client)
+	go cl.server.apiListener.deliverPendingAttributes(client)
+	go cl.server.apiListener.startPersistentTunnels(ctx, client)
+
 	clientBanner := client.Banner()
 	clientLog.Debugf("opened %s within %s", clientBanner, time.Since(ts2))
 
@@ -408,6 +468,8 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 	if err != nil {
 		cl.log().Errorf("could not terminate client: %s", err)
 	}
+	cl.server.apiListener.storedTunnels.SetClientDisconnected(client.GetID())
+	cl.server.cluster.ClientDisconnected(context.Background(), client.GetID())
 }
 
 // checkVersions print if client and server versions dont match.
@@ -631,6 +693,27 @@ func (cl *ClientListener) handleSSHRequests(clientLog *logger.DynamicLogger, cli
 				clientLog.Errorf("Failed to save IPAddresses status: %s", err)
 				continue
 			}
+		case comm.RequestTypeInventory:
+			clientLog.Debugf("inventory received from: %s", clientID)
+			inventory := &models.Inventory{}
+			err := json.Unmarshal(r.Payload, inventory)
+			if err != nil {
+				clientLog.Errorf("Failed to unmarshal inventory: %s", err)
+				continue
+			}
+			err = cl.server.saveInventory(clientID, inventory)
+			if err != nil {
+				clientLog.Errorf("Failed to save inventory: %s", err)
+				continue
+			}
+		case comm.RequestTypeUploadProgress:
+			progress := &models.UploadProgress{}
+			err := json.Unmarshal(r.Payload, progress)
+			if err != nil {
+				clientLog.Errorf("Failed to unmarshal upload progress: %s", err)
+				continue
+			}
+			cl.server.apiListener.notifyUploadEventListeners(newUploadProgressOutput(clientID, progress))
 		default:
 			clientLog.Debugf("Unknown request: %s", r.Type)
 		}
@@ -0,0 +1,186 @@
+package chserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clientcerts"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/security"
+)
+
+// certClientAuthIDExtension is set on the ssh permissions of clients that authenticated with a client certificate
+const certClientAuthIDExtension = "client_auth_id"
+
+var ErrClientCertRequired = errors.New("client certificate required")
+
+// setupTLS returns the TLS config of the client listener. If a client CA is configured, clients may authenticate
+// with certificates issued by it.
+func (cl *ClientListener) setupTLS(config *chconfig.ServerConfig) (*tls.Config, error) {
+	_, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'tls_cert_file', 'tls_key_file': %v", err)
+	}
+	tlsConfig := security.TLSConfig(config.GetClientTLSMin())
+	if config.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caCerts, err := clientcerts.LoadCertificates(config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'client_ca_file': %v", err)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range caCerts {
+		pool.AddCert(cert)
+	}
+	tlsConfig.ClientCAs = pool
+	// clients without certificate are still accepted, they authenticate with a password or an enrollment token
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	cl.clientCertVerifier, err = clientcerts.NewVerifier(caCerts, config.ClientCertIdentity, config.ClientCRLFile, config.ClientOCSPFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'client_crl_file' or 'client_ocsp_file': %v", err)
+	}
+
+	if config.ClientCAKeyFile != "" {
+		// the built-in CA signs with the first certificate of the bundle
+		cl.clientCA, err = clientcerts.NewCA(caCerts[0], config.ClientCAKeyFile, config.ClientCertIdentity, config.ClientCertValidity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'client_ca_key_file': %v", err)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// sshConfigFor returns the ssh config used to authenticate a client connection. Clients with a verified certificate
+// are authenticated by the certificate, the ssh password is ignored.
+func (cl *ClientListener) sshConfigFor(req *http.Request) (*ssh.ServerConfig, error) {
+	chain := verifiedClientChain(req)
+	if chain == nil {
+		if !cl.server.config.Server.ClientCertRequired {
+			return cl.sshConfig, nil
+		}
+		sshConfig := *cl.sshConfig
+		sshConfig.PasswordCallback = cl.authWithoutCertificate
+		return &sshConfig, nil
+	}
+
+	clientAuthID, err := cl.authCertificate(chain, cl.getRequestIP(req))
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfig := *cl.sshConfig
+	sshConfig.PasswordCallback = func(c ssh.ConnMetadata, _ []byte) (*ssh.Permissions, error) {
+		if c.User() != "" && c.User() != clientAuthID {
+			return nil, fmt.Errorf("client auth id %q doesn't match the client certificate of %q", c.User(), clientAuthID)
+		}
+		return &ssh.Permissions{
+			Extensions: map[string]string{
+				certClientAuthIDExtension: clientAuthID,
+			},
+		}, nil
+	}
+	return &sshConfig, nil
+}
+
+// authCertificate returns the client auth id of a verified client certificate, the client auth must exist
+func (cl *ClientListener) authCertificate(chain []*x509.Certificate, ip string) (string, error) {
+	clientAuthID, err := cl.clientCertVerifier.ClientAuthID(chain)
+	if err == nil {
+		clientAuth, getErr := cl.server.clientAuthProvider.Get(clientAuthID)
+		if getErr != nil {
+			return "", getErr
+		}
+		if clientAuth == nil {
+			err = fmt.Errorf("unknown client auth id %q", clientAuthID)
+		}
+	}
+	if err != nil {
+		cl.log().Infof("Client certificate %q rejected: %v (%s)", chain[0].Subject, err, ip)
+		if cl.bannedIPs != nil {
+			cl.bannedIPs.AddBadAttempt(ip)
+		}
+		return "", err
+	}
+
+	if cl.bannedIPs != nil {
+		cl.bannedIPs.AddSuccessAttempt(ip)
+	}
+	return clientAuthID, nil
+}
+
+// authWithoutCertificate is used when client certificates are required. Only enrollment tokens are accepted and only
+// if the built-in CA issues certificates to the enrolled clients.
+func (cl *ClientListener) authWithoutCertificate(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	if cl.clientCA != nil && strings.HasPrefix(c.User(), models.EnrollmentTokenUserPrefix) {
+		return cl.authUser(c, password)
+	}
+
+	cl.log().Infof("Login of client auth id %q without client certificate rejected (%s)", c.User(), cl.getIP(c.RemoteAddr()))
+	return nil, ErrClientCertRequired
+}
+
+func getCertClientAuthID(sshConn *ssh.ServerConn) string {
+	if sshConn.Permissions == nil {
+		return ""
+	}
+	return sshConn.Permissions.Extensions[certClientAuthIDExtension]
+}
+
+// verifiedClientChain returns the verified certificate chain of the client, the leaf certificate comes first
+func verifiedClientChain(req *http.Request) []*x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return req.TLS.VerifiedChains[0]
+}
+
+func (cl *ClientListener) getRequestIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// renewClientCertificate sends a new certificate to a client if its certificate was issued by the built-in CA and
+// expires soon
+func (cl *ClientListener) renewClientCertificate(conn ssh.Conn, client *clientdata.Client, clientAuthID string, cert *x509.Certificate, clientLog *logger.Logger) {
+	if cl.clientCA == nil || !cl.clientCA.NeedsRenewal(cert) {
+		return
+	}
+
+	renewed, err := cl.clientCA.Issue(clientAuthID)
+	if err != nil {
+		clientLog.Errorf("could not renew client certificate: %v", err)
+		return
+	}
+
+	err = comm.SendRequestAndGetResponse(conn, comm.RequestTypeRenewCertificate, renewed, nil, clientLog)
+	if err != nil {
+		clientLog.Errorf("could not send renewed client certificate: %v", err)
+		return
+	}
+	clientLog.Infof("Client certificate renewed, the previous one expires at %s", cert.NotAfter)
+
+	cl.server.auditLog.Entry(auditlog.ApplicationClientCert, auditlog.ActionUpdate).
+		WithRemoteIP(cl.getIP(conn.RemoteAddr())).
+		WithID(clientAuthID).
+		WithClient(client).
+		Save()
+}
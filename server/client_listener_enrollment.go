@@ -0,0 +1,227 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/server/auditlog"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/clients/enrollment"
+	"github.com/riportdev/riport/server/clientsauth"
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/random"
+)
+
+// enrollmentTokenExtension is set on the ssh permissions of clients that authenticated with an enrollment token
+const enrollmentTokenExtension = "enrollment_token"
+
+type enrolledClient struct {
+	Token       *enrollment.Token
+	ClientID    string
+	RemoteIP    string
+	Credentials models.EnrollmentCredentials
+}
+
+// authEnrollmentToken is responsible for validating the token of a client that connects for the first time
+func (cl *ClientListener) authEnrollmentToken(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	tokenID := strings.TrimPrefix(c.User(), models.EnrollmentTokenUserPrefix)
+	ip := cl.getIP(c.RemoteAddr())
+
+	if !cl.server.clientAuthProvider.IsWriteable() || !cl.server.config.Server.AuthWrite {
+		cl.log().Infof("Enrollment with token %q rejected, client auth credentials are read-only (%s)", tokenID, ip)
+		return nil, fmt.Errorf("client enrollment is not possible with read-only client auth credentials")
+	}
+
+	token, err := cl.server.enrollmentTokens.Authenticate(cl.getCtx(), tokenID, string(password))
+	if err != nil {
+		cl.log().Infof("Enrollment with token %q failed: %v (%s)", tokenID, err, ip)
+		cl.bannedClientAuths.Add(c.User())
+		if cl.bannedIPs != nil {
+			cl.bannedIPs.AddBadAttempt(ip)
+		}
+		cl.server.auditLog.Entry(auditlog.ApplicationClientEnrollment, auditlog.ActionFailed).
+			WithRemoteIP(ip).
+			WithID(tokenID).
+			WithResponse(err.Error()).
+			Save()
+		return nil, fmt.Errorf("invalid enrollment token: %s", tokenID)
+	}
+
+	if cl.bannedIPs != nil {
+		cl.bannedIPs.AddSuccessAttempt(ip)
+	}
+	return &ssh.Permissions{
+		Extensions: map[string]string{
+			enrollmentTokenExtension: token.ID,
+		},
+	}, nil
+}
+
+func getEnrollmentTokenID(sshConn *ssh.ServerConn) string {
+	if sshConn.Permissions == nil {
+		return ""
+	}
+	return sshConn.Permissions.Extensions[enrollmentTokenExtension]
+}
+
+// enrollClient uses the token once and creates the client auth credentials of the new client
+func (cl *ClientListener) enrollClient(ctx context.Context, tokenID, reqClientID, remoteIP string) (*enrolledClient, error) {
+	token, err := cl.server.enrollmentTokens.Get(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, enrollment.ErrInvalidToken
+	}
+
+	// a token must not give access to the connection, jobs and tunnels of an existing client
+	if reqClientID != "" {
+		existing, err := cl.getClientService().GetByID(reqClientID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("client with id %q already exists", reqClientID)
+		}
+	}
+
+	clientAuthID, err := cl.newEnrolledClientAuthID(reqClientID)
+	if err != nil {
+		return nil, err
+	}
+	password, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	credentials := models.EnrollmentCredentials{
+		ClientAuthID: clientAuthID,
+		Password:     password,
+	}
+	if cl.clientCA != nil {
+		credentials.Certificate, err = cl.clientCA.Issue(clientAuthID)
+		if err != nil {
+			return nil, fmt.Errorf("could not issue client certificate: %v", err)
+		}
+	}
+
+	clientID, err := cl.getClientID(reqClientID, cl.server.config, clientAuthID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get clientID: %s", err)
+	}
+
+	added, err := cl.server.clientAuthProvider.Add(&clientsauth.ClientAuth{ID: clientAuthID, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, fmt.Errorf("client auth with id %q already exists", clientAuthID)
+	}
+
+	err = cl.server.enrollmentTokens.Enroll(ctx, tokenID, clientID, clientAuthID, remoteIP)
+	if err != nil {
+		if delErr := cl.server.clientAuthProvider.Delete(clientAuthID); delErr != nil {
+			cl.log().Errorf("could not delete client auth %q of failed enrollment: %v", clientAuthID, delErr)
+		}
+		return nil, err
+	}
+
+	return &enrolledClient{
+		Token:       token,
+		ClientID:    clientID,
+		RemoteIP:    remoteIP,
+		Credentials: credentials,
+	}, nil
+}
+
+// newEnrolledClientAuthID uses the client id as client auth id if possible, so the credentials are easy to recognize
+func (cl *ClientListener) newEnrolledClientAuthID(reqClientID string) (string, error) {
+	if reqClientID == "" {
+		return random.UUID4()
+	}
+
+	existing, err := cl.server.clientAuthProvider.Get(reqClientID)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return reqClientID, nil
+	}
+
+	return reqClientID + "-" + random.AlphaNum(6), nil
+}
+
+// completeEnrollment sends the new credentials to the client and applies the defaults of the token.
+// It must run before pending attributes are delivered to the client.
+func (cl *ClientListener) completeEnrollment(ctx context.Context, conn ssh.Conn, client *clientdata.Client, enrolled *enrolledClient, clientLog *logger.Logger) {
+	err := comm.SendRequestAndGetResponse(conn, comm.RequestTypeEnrollment, enrolled.Credentials, nil, clientLog)
+	if err != nil {
+		clientLog.Errorf("could not send enrollment credentials, the client won't be able to reconnect: %v", err)
+	}
+
+	operations := enrollmentAttributesOperations(enrolled.Token)
+	if len(operations) > 0 {
+		client.QueueAttributes(operations)
+		if err := cl.getClientService().GetRepo().Save(client); err != nil {
+			clientLog.Errorf("could not save attributes of enrollment token %s: %v", enrolled.Token.ID, err)
+		}
+	}
+
+	if enrolled.Token.GroupID != "" {
+		if err := cl.addClientToGroup(ctx, enrolled.Token.GroupID, client.GetID()); err != nil {
+			clientLog.Errorf("could not add client to group of enrollment token %s: %v", enrolled.Token.ID, err)
+		}
+	}
+
+	cl.server.auditLog.Entry(auditlog.ApplicationClientEnrollment, auditlog.ActionCreate).
+		WithRemoteIP(enrolled.RemoteIP).
+		WithID(enrolled.Token.ID).
+		WithClient(client).
+		WithResponse(map[string]string{"client_auth_id": enrolled.Credentials.ClientAuthID}).
+		Save()
+}
+
+func enrollmentAttributesOperations(token *enrollment.Token) []models.AttributesOperation {
+	var operations []models.AttributesOperation
+	for _, tag := range token.Tags {
+		operations = append(operations, models.AttributesOperation{Op: models.AttributesOperationAddTag, Tag: tag})
+	}
+	keys := make([]string, 0, len(token.Labels))
+	for key := range token.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		operations = append(operations, models.AttributesOperation{Op: models.AttributesOperationSetLabel, Key: key, Value: token.Labels[key]})
+	}
+	return operations
+}
+
+func (cl *ClientListener) addClientToGroup(ctx context.Context, groupID, clientID string) error {
+	cl.server.enrollmentGroupsMu.Lock()
+	defer cl.server.enrollmentGroupsMu.Unlock()
+
+	group, err := cl.server.clientGroupProvider.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("client group %q not found", groupID)
+	}
+	if group.Params == nil || group.Params.ClientID == nil {
+		return fmt.Errorf("client group %q has no client_id param", groupID)
+	}
+
+	if group.Params.ClientID.MatchesOneOf(clientID) {
+		return nil
+	}
+	*group.Params.ClientID = append(*group.Params.ClientID, cgroups.Param(clientID))
+
+	return cl.server.clientGroupProvider.Update(ctx, group)
+}
@@ -0,0 +1,109 @@
+package chserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientsmigration "github.com/riportdev/riport/db/migration/clients"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/chconfig"
+	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientdata"
+	"github.com/riportdev/riport/server/clients/enrollment"
+	"github.com/riportdev/riport/server/clientsauth"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+func TestEnrollClient(t *testing.T) {
+	ctx := context.Background()
+	clientsDB, err := sqlite.New(":memory:", clientsmigration.AssetNames(), clientsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	authProvider := clientsauth.NewDatabaseMockProvider([]*clientsauth.ClientAuth{{ID: "existing", Password: "pswd"}}, t)
+	groupsProvider := makeGroupsProvider(t, DataSourceOptions)
+	err = groupsProvider.Create(ctx, &cgroups.ClientGroup{
+		ID:     "enrolled",
+		Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-0"}},
+	})
+	require.NoError(t, err)
+	taken := clients.New(t).ID("taken").Logger(testLog).Build()
+
+	cl := &ClientListener{
+		logger: testLog,
+		ctx:    ctx,
+		server: &Server{
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{AuthWrite: true},
+			},
+			clientAuthProvider:  authProvider,
+			clientGroupProvider: groupsProvider,
+			enrollmentTokens:    enrollment.New(clientsDB),
+			clientService:       clients.NewClientService(nil, nil, clients.NewClientRepository([]*clientdata.Client{taken}, &hour, testLog), testLog, nil),
+		},
+	}
+
+	token, err := cl.server.enrollmentTokens.Create(ctx, &enrollment.Token{
+		MaxUses:   2,
+		ExpiresAt: time.Now().Add(time.Hour),
+		Tags:      []string{"web"},
+		Labels:    enrollment.Labels{"env": "prod", "city": "Cologne"},
+		GroupID:   "enrolled",
+	}, "admin")
+	require.NoError(t, err)
+
+	// the client id is used as client auth id unless it's taken
+	enrolled, err := cl.enrollClient(ctx, token.ID, "existing", "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "existing", enrolled.ClientID)
+	assert.True(t, strings.HasPrefix(enrolled.Credentials.ClientAuthID, "existing-"))
+	stored, err := authProvider.Get(enrolled.Credentials.ClientAuthID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, enrolled.Credentials.Password, stored.Password)
+
+	enrolled2, err := cl.enrollClient(ctx, token.ID, "", "192.0.2.2")
+	require.NoError(t, err)
+	assert.NotEmpty(t, enrolled2.ClientID)
+	assert.NotEqual(t, enrolled.Credentials.ClientAuthID, enrolled2.Credentials.ClientAuthID)
+
+	// the id of an existing client can't be taken over, the token isn't used
+	_, err = cl.enrollClient(ctx, token.ID, "taken", "192.0.2.4")
+	assert.EqualError(t, err, `client with id "taken" already exists`)
+	stored, err = authProvider.Get("taken")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// the token is used up, no further credentials are created
+	_, err = cl.enrollClient(ctx, token.ID, "new-client", "192.0.2.3")
+	assert.Error(t, err)
+	stored, err = authProvider.Get("new-client")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	_, count, err := authProvider.GetFiltered(&query.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	withEnrollments, err := cl.server.enrollmentTokens.Get(ctx, token.ID)
+	require.NoError(t, err)
+	require.Len(t, withEnrollments.Enrollments, 2)
+	assert.Equal(t, enrolled.Credentials.ClientAuthID, withEnrollments.Enrollments[0].ClientAuthID)
+
+	assert.Equal(t, []models.AttributesOperation{
+		{Op: models.AttributesOperationAddTag, Tag: "web"},
+		{Op: models.AttributesOperationSetLabel, Key: "city", Value: "Cologne"},
+		{Op: models.AttributesOperationSetLabel, Key: "env", Value: "prod"},
+	}, enrollmentAttributesOperations(enrolled.Token))
+
+	// clients are added to the group only once
+	require.NoError(t, cl.addClientToGroup(ctx, "enrolled", enrolled.ClientID))
+	require.NoError(t, cl.addClientToGroup(ctx, "enrolled", enrolled.ClientID))
+	group, err := groupsProvider.Get(ctx, "enrolled")
+	require.NoError(t, err)
+	assert.Equal(t, &cgroups.ParamValues{"client-0", "existing"}, group.Params.ClientID)
+}
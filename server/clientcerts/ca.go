@@ -0,0 +1,154 @@
+package clientcerts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/riportdev/riport/share/models"
+)
+
+// clockSkew is subtracted from the start of the validity of issued certificates
+const clockSkew = 5 * time.Minute
+
+// CA is a small built-in certificate authority that issues the client certificates of enrolled clients
+type CA struct {
+	cert     *x509.Certificate
+	key      crypto.Signer
+	identity string
+	validity time.Duration
+	now      func() time.Time
+}
+
+func NewCA(cert *x509.Certificate, keyFile, identity string, validity time.Duration) (*CA, error) {
+	err := ValidateIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("client CA certificate %q is not a CA", cert.Subject)
+	}
+	if validity <= 0 {
+		return nil, errors.New("client certificate validity must be positive")
+	}
+
+	key, err := loadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeysEqual(key.Public(), cert.PublicKey) {
+		return nil, fmt.Errorf("client CA key %q doesn't match the certificate %q", keyFile, cert.Subject)
+	}
+
+	return &CA{
+		cert:     cert,
+		key:      key,
+		identity: identity,
+		validity: validity,
+		now:      time.Now,
+	}, nil
+}
+
+// Issue creates a new key and certificate for a client auth id. The client auth id is put into the field
+// used as identity.
+func (ca *CA) Issue(clientAuthID string) (*models.ClientCertificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := ca.now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: clientAuthID},
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(ca.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	switch ca.identity {
+	case IdentityDNSSAN:
+		template.DNSNames = []string{clientAuthID}
+	case IdentityEmailSAN:
+		template.EmailAddresses = []string{clientAuthID}
+	case IdentityURISAN:
+		u, err := url.Parse(clientAuthID)
+		if err != nil || u.Scheme == "" {
+			return nil, fmt.Errorf("client auth id %q can't be used as URI SAN", clientAuthID)
+		}
+		template.URIs = []*url.URL{u}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ClientCertificate{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Key:         string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}, nil
+}
+
+// NeedsRenewal returns true if a certificate was issued by the CA and less than a third of its validity is left
+func (ca *CA) NeedsRenewal(cert *x509.Certificate) bool {
+	if cert.CheckSignatureFrom(ca.cert) != nil {
+		return false
+	}
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Sub(ca.now()) < validity/3
+}
+
+func loadKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key found in %q", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %q: %v", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T in %q", key, path)
+	}
+	return signer, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
@@ -0,0 +1,257 @@
+package clientcerts
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestCAIssue(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKeyFile := makeTestCA(t, dir, "Test CA")
+
+	_, err := NewCA(caCert, caKeyFile, "unknown", time.Hour)
+	assert.EqualError(t, err, `invalid client certificate identity "unknown", expected one of "common_name", "dns_san", "uri_san", "email_san"`)
+	_, otherKeyFile := makeTestCA(t, t.TempDir(), "Other CA")
+	_, err = NewCA(caCert, otherKeyFile, IdentityCommonName, time.Hour)
+	assert.Error(t, err)
+
+	ca, err := NewCA(caCert, caKeyFile, IdentityDNSSAN, 90*24*time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	ca.now = func() time.Time { return now }
+
+	issued, err := ca.Issue("client-1.example.com")
+	require.NoError(t, err)
+
+	cert := parseCertificate(t, issued.Certificate)
+	assert.Equal(t, "client-1.example.com", cert.Subject.CommonName)
+	assert.Equal(t, []string{"client-1.example.com"}, cert.DNSNames)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+	assert.Equal(t, now.Add(90*24*time.Hour), cert.NotAfter)
+	require.NoError(t, cert.CheckSignatureFrom(caCert))
+
+	block, _ := pem.Decode([]byte(issued.Key))
+	require.NotNil(t, block)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	assert.True(t, key.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey))
+
+	id, err := Identity(cert, IdentityDNSSAN)
+	require.NoError(t, err)
+	assert.Equal(t, "client-1.example.com", id)
+	_, err = Identity(cert, IdentityEmailSAN)
+	assert.Error(t, err)
+
+	assert.False(t, ca.NeedsRenewal(cert))
+	now = now.Add(61 * 24 * time.Hour)
+	assert.True(t, ca.NeedsRenewal(cert))
+
+	// certificates of other CAs are never renewed
+	otherCA, otherKey := makeTestCACert(t, "Other CA")
+	otherCert := makeTestCert(t, "client-2", big.NewInt(2), otherCA, otherKey)
+	assert.False(t, ca.NeedsRenewal(otherCert))
+}
+
+func TestVerifier(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := makeTestCACert(t, "Test CA")
+	cert1 := makeTestCert(t, "client-1", big.NewInt(11), caCert, caKey)
+	cert2 := makeTestCert(t, "client-2", big.NewInt(12), caCert, caKey)
+	crlFile := filepath.Join(dir, "crl.pem")
+
+	_, err := NewVerifier([]*x509.Certificate{caCert}, IdentityCommonName, crlFile, "")
+	assert.Error(t, err, "missing CRL file")
+
+	writeTestCRL(t, crlFile, caCert, caKey, big.NewInt(1), big.NewInt(12))
+	v, err := NewVerifier([]*x509.Certificate{caCert}, IdentityCommonName, crlFile, "")
+	require.NoError(t, err)
+
+	_, err = v.ClientAuthID([]*x509.Certificate{cert2, caCert})
+	assert.Equal(t, ErrRevoked, err)
+
+	// the CRL is reloaded when it changes
+	writeTestCRL(t, crlFile, caCert, caKey, big.NewInt(2), big.NewInt(11))
+	require.NoError(t, os.Chtimes(crlFile, time.Now(), time.Now().Add(time.Minute)))
+	_, err = v.ClientAuthID([]*x509.Certificate{cert1, caCert})
+	assert.Equal(t, ErrRevoked, err)
+	id, err := v.ClientAuthID([]*x509.Certificate{cert2, caCert})
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", id)
+
+	// CRLs of other CAs are rejected
+	otherCA, otherKey := makeTestCACert(t, "Other CA")
+	writeTestCRL(t, crlFile, otherCA, otherKey, big.NewInt(3), big.NewInt(12))
+	require.NoError(t, os.Chtimes(crlFile, time.Now(), time.Now().Add(2*time.Minute)))
+	_, err = v.ClientAuthID([]*x509.Certificate{cert2, caCert})
+	assert.EqualError(t, err, `client CRL "`+crlFile+`" is not signed by a client CA`)
+
+	noCRL, err := NewVerifier([]*x509.Certificate{caCert}, IdentityCommonName, "", "")
+	require.NoError(t, err)
+	id, err = noCRL.ClientAuthID([]*x509.Certificate{cert1, caCert})
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", id)
+	_, err = noCRL.ClientAuthID(nil)
+	assert.Error(t, err)
+}
+
+func TestVerifierOCSP(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := makeTestCACert(t, "Test CA")
+	cert1 := makeTestCert(t, "client-1", big.NewInt(11), caCert, caKey)
+	cert2 := makeTestCert(t, "client-2", big.NewInt(12), caCert, caKey)
+	cert3 := makeTestCert(t, "client-3", big.NewInt(13), caCert, caKey)
+	ocspFile := filepath.Join(dir, "ocsp.der")
+
+	_, err := NewVerifier([]*x509.Certificate{caCert}, IdentityCommonName, "", ocspFile)
+	assert.Error(t, err, "missing OCSP response file")
+
+	writeTestOCSPResponse(t, ocspFile, caCert, caKey, cert2, ocsp.Revoked)
+	v, err := NewVerifier([]*x509.Certificate{caCert}, IdentityCommonName, "", ocspFile)
+	require.NoError(t, err)
+
+	_, err = v.ClientAuthID([]*x509.Certificate{cert2, caCert})
+	assert.Equal(t, ErrRevoked, err)
+	// certificates not listed in the response are accepted
+	id, err := v.ClientAuthID([]*x509.Certificate{cert3, caCert})
+	require.NoError(t, err)
+	assert.Equal(t, "client-3", id)
+
+	// the response is reloaded when it changes
+	writeTestOCSPResponse(t, ocspFile, caCert, caKey, cert2, ocsp.Good)
+	require.NoError(t, os.Chtimes(ocspFile, time.Now(), time.Now().Add(time.Minute)))
+	id, err = v.ClientAuthID([]*x509.Certificate{cert2, caCert})
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", id)
+
+	// responses of other CAs are rejected
+	otherCA, otherKey := makeTestCACert(t, "Other CA")
+	writeTestOCSPResponse(t, ocspFile, otherCA, otherKey, cert1, ocsp.Good)
+	require.NoError(t, os.Chtimes(ocspFile, time.Now(), time.Now().Add(2*time.Minute)))
+	_, err = v.ClientAuthID([]*x509.Certificate{cert1, caCert})
+	assert.ErrorContains(t, err, `invalid client OCSP response "`+ocspFile+`"`)
+}
+
+func TestLoadCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca1, _ := makeTestCA(t, dir, "CA 1")
+	ca2, _ := makeTestCACert(t, "CA 2")
+	bundle := filepath.Join(dir, "bundle.pem")
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca1.Raw}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca2.Raw})...)
+	require.NoError(t, os.WriteFile(bundle, data, 0600))
+
+	certs, err := LoadCertificates(bundle)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	assert.Equal(t, "CA 1", certs[0].Subject.CommonName)
+	assert.Equal(t, "CA 2", certs[1].Subject.CommonName)
+
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("no certs"), 0600))
+	_, err = LoadCertificates(empty)
+	assert.Error(t, err)
+}
+
+func makeTestCACert(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// makeTestCA creates a CA and writes its key to a file
+func makeTestCA(t *testing.T, dir, name string) (*x509.Certificate, string) {
+	t.Helper()
+	cert, key := makeTestCACert(t, name)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "ca.key")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return cert, keyFile
+}
+
+func makeTestCert(t *testing.T, cn string, serial *big.Int, ca *x509.Certificate, caKey interface{}) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func writeTestCRL(t *testing.T, path string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, number *big.Int, revoked ...*big.Int) {
+	t.Helper()
+	template := &x509.RevocationList{
+		Number:     number,
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+	}
+	for _, serial := range revoked {
+		template.RevokedCertificates = append(template.RevokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0600))
+}
+
+func parseCertificate(t *testing.T, data string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(data))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func writeTestOCSPResponse(t *testing.T, path string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, cert *x509.Certificate, status int) {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   time.Now(),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now()
+	}
+	der, err := ocsp.CreateResponse(ca, ca, template, caKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, der, 0600))
+}
@@ -0,0 +1,275 @@
+package clientcerts
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	IdentityCommonName = "common_name"
+	IdentityDNSSAN     = "dns_san"
+	IdentityURISAN     = "uri_san"
+	IdentityEmailSAN   = "email_san"
+)
+
+var ErrRevoked = errors.New("client certificate is revoked")
+
+// ValidateIdentity returns an error if the certificate field used as client auth id is not supported
+func ValidateIdentity(identity string) error {
+	switch identity {
+	case IdentityCommonName, IdentityDNSSAN, IdentityURISAN, IdentityEmailSAN:
+		return nil
+	}
+	return fmt.Errorf("invalid client certificate identity %q, expected one of %q, %q, %q, %q",
+		identity, IdentityCommonName, IdentityDNSSAN, IdentityURISAN, IdentityEmailSAN)
+}
+
+// Identity returns the client auth id of a certificate
+func Identity(cert *x509.Certificate, identity string) (string, error) {
+	var id string
+	switch identity {
+	case IdentityCommonName:
+		id = cert.Subject.CommonName
+	case IdentityDNSSAN:
+		if len(cert.DNSNames) > 0 {
+			id = cert.DNSNames[0]
+		}
+	case IdentityURISAN:
+		if len(cert.URIs) > 0 {
+			id = cert.URIs[0].String()
+		}
+	case IdentityEmailSAN:
+		if len(cert.EmailAddresses) > 0 {
+			id = cert.EmailAddresses[0]
+		}
+	default:
+		return "", ValidateIdentity(identity)
+	}
+
+	if id == "" {
+		return "", fmt.Errorf("client certificate %q has no %s", cert.Subject, identity)
+	}
+	return id, nil
+}
+
+// LoadCertificates reads all certificates of a PEM encoded bundle
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate in %q: %v", path, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in %q", path)
+	}
+	return certs, nil
+}
+
+// errNoOCSPStatus is returned by the ocsp package if the response doesn't list a certificate
+var errNoOCSPStatus = ocsp.ParseError("no response matching the supplied certificate")
+
+// Verifier maps client certificates to client auth ids. The certificates must have been verified against the
+// client CA bundle by the TLS handshake already. If a CRL or OCSP response file is set, it's reloaded whenever
+// it changes.
+type Verifier struct {
+	caCerts  []*x509.Certificate
+	identity string
+	crlFile  string
+	ocspFile string
+
+	crl         *x509.RevocationList
+	crlModTime  time.Time
+	ocspResp    []byte
+	ocspModTime time.Time
+	mu          sync.Mutex
+}
+
+func NewVerifier(caCerts []*x509.Certificate, identity, crlFile, ocspFile string) (*Verifier, error) {
+	err := ValidateIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Verifier{
+		caCerts:  caCerts,
+		identity: identity,
+		crlFile:  crlFile,
+		ocspFile: ocspFile,
+	}
+	if crlFile != "" {
+		_, err = v.getCRL()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ocspFile != "" {
+		_, err = v.getOCSPResponse()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+// ClientAuthID returns the client auth id of a verified certificate chain, the leaf certificate comes first
+func (v *Verifier) ClientAuthID(chain []*x509.Certificate) (string, error) {
+	if len(chain) == 0 {
+		return "", errors.New("no client certificate")
+	}
+
+	if v.crlFile != "" {
+		crl, err := v.getCRL()
+		if err != nil {
+			return "", err
+		}
+		if isRevoked(crl, chain) {
+			return "", ErrRevoked
+		}
+	}
+
+	if v.ocspFile != "" {
+		resp, err := v.getOCSPResponse()
+		if err != nil {
+			return "", err
+		}
+		revoked, err := isRevokedByOCSP(resp, chain)
+		if err != nil {
+			return "", fmt.Errorf("invalid client OCSP response %q: %v", v.ocspFile, err)
+		}
+		if revoked {
+			return "", ErrRevoked
+		}
+	}
+
+	return Identity(chain[0], v.identity)
+}
+
+func (v *Verifier) getCRL() (*x509.RevocationList, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	info, err := os.Stat(v.crlFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client CRL: %v", err)
+	}
+	if v.crl != nil && info.ModTime().Equal(v.crlModTime) {
+		return v.crl, nil
+	}
+
+	crl, err := v.loadCRL()
+	if err != nil {
+		return nil, err
+	}
+	v.crl = crl
+	v.crlModTime = info.ModTime()
+
+	return crl, nil
+}
+
+// loadCRL reads a PEM or DER encoded CRL and checks it's signed by one of the CA certificates
+func (v *Verifier) loadCRL() (*x509.RevocationList, error) {
+	data, err := os.ReadFile(v.crlFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client CRL: %v", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client CRL %q: %v", v.crlFile, err)
+	}
+
+	for _, ca := range v.caCerts {
+		if crl.CheckSignatureFrom(ca) == nil {
+			return crl, nil
+		}
+	}
+	return nil, fmt.Errorf("client CRL %q is not signed by a client CA", v.crlFile)
+}
+
+// isRevoked returns true if any certificate of the chain issued by the issuer of the CRL is listed on it
+func isRevoked(crl *x509.RevocationList, chain []*x509.Certificate) bool {
+	for _, cert := range chain {
+		if !bytes.Equal(cert.RawIssuer, crl.RawIssuer) {
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *Verifier) getOCSPResponse() ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	info, err := os.Stat(v.ocspFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client OCSP response: %v", err)
+	}
+	if v.ocspResp != nil && info.ModTime().Equal(v.ocspModTime) {
+		return v.ocspResp, nil
+	}
+
+	data, err := os.ReadFile(v.ocspFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client OCSP response: %v", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	v.ocspResp = data
+	v.ocspModTime = info.ModTime()
+
+	return data, nil
+}
+
+// isRevokedByOCSP returns true if any certificate of the chain is revoked according to the OCSP response.
+// The response must be signed by the issuer of the certificate, certificates it doesn't list aren't revoked.
+func isRevokedByOCSP(resp []byte, chain []*x509.Certificate) (bool, error) {
+	// the last certificate of a verified chain is the CA, it has no issuer in the chain
+	for i := 0; i < len(chain)-1; i++ {
+		status, err := ocsp.ParseResponseForCert(resp, chain[i], chain[i+1])
+		if err == errNoOCSPStatus {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if status.Status == ocsp.Revoked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
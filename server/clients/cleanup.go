@@ -9,8 +9,9 @@ import (
 )
 
 type CleanupTask struct {
-	log *logger.Logger
-	cr  *ClientRepository
+	log          *logger.Logger
+	cr           *ClientRepository
+	inMemoryOnly bool
 }
 
 // NewCleanupTask returns a task to cleanup Client Repository from obsolete clients.
@@ -21,7 +22,24 @@ func NewCleanupTask(log *logger.Logger, cr *ClientRepository) *CleanupTask {
 	}
 }
 
+// NewInMemoryCleanupTask returns a task to cleanup Client Repository from obsolete clients without deleting them
+// from the store, so the store shared by the nodes of a cluster is cleaned up by the leader only.
+func NewInMemoryCleanupTask(log *logger.Logger, cr *ClientRepository) *CleanupTask {
+	return &CleanupTask{
+		log:          log,
+		cr:           cr,
+		inMemoryOnly: true,
+	}
+}
+
 func (t *CleanupTask) Run(ctx context.Context) error {
+	if t.inMemoryOnly {
+		if deleted := t.cr.DeleteObsoleteInMemory(); len(deleted) > 0 {
+			t.log.Debugf("Deleted %d obsolete client(s) from memory.", len(deleted))
+		}
+		return nil
+	}
+
 	deleted, err := t.cr.DeleteObsolete()
 	if err != nil {
 		return fmt.Errorf("failed to delete obsolete clients: %v", err)
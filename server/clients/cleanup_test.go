@@ -65,6 +65,29 @@ func TestCleanupDisabled(t *testing.T) {
 	assert.ElementsMatch(t, getValues(clientsRepo.clientState), []*clientdata.Client{c1, c2, c3})
 }
 
+func TestCleanupInMemory(t *testing.T) {
+	// given
+	ctx := context.Background()
+	c1 := New(t).ID("client-1").Logger(testLog).Build()                                               // active
+	c3 := New(t).ID("client-3").DisconnectedDuration(time.Hour + time.Minute).Logger(testLog).Build() // obsolete
+	p := NewFakeClientProvider(t, &hour, c1, c3)
+	defer p.Close()
+	clientsRepo := NewClientRepositoryWithDB([]*clientdata.Client{c1, c3}, &hour, p, testLog)
+
+	task := NewInMemoryCleanupTask(testLog, clientsRepo)
+
+	// when
+	err := task.Run(ctx)
+
+	// then
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, getValues(clientsRepo.clientState), []*clientdata.Client{c1})
+	// the store is left to the cluster leader
+	gotObsolete, err := p.get(ctx, c3.GetID(), testLog)
+	require.NoError(t, err)
+	assert.NotNil(t, gotObsolete)
+}
+
 func getValues(clients map[string]*clientdata.Client) []*clientdata.Client {
 	var r []*clientdata.Client
 	for _, v := range clients {
@@ -22,17 +22,34 @@ import (
 
 	"github.com/riportdev/riport/server/acme"
 	apiErrors "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/auditlog"
 	"github.com/riportdev/riport/server/caddy"
 	"github.com/riportdev/riport/server/cgroups"
 	"github.com/riportdev/riport/server/clients/clientdata"
 	"github.com/riportdev/riport/server/clients/clienttunnel"
 	"github.com/riportdev/riport/server/ports"
+	"github.com/riportdev/riport/server/tunnellog"
 	chshare "github.com/riportdev/riport/share"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/models"
 	"github.com/riportdev/riport/share/query"
 )
 
+const (
+	TunnelCloseReasonAutoClose     = "auto close"
+	TunnelCloseReasonIdleTimeout   = "idle timeout"
+	TunnelCloseReasonQuotaExceeded = "quota exceeded"
+	// TunnelCloseReasonClientDisconnected is used for tunnels ending with the connection of the client, they are
+	// re-established when the client reconnects
+	TunnelCloseReasonClientDisconnected = "client disconnected"
+)
+
+// TunnelCloseResponse is stored in the audit log when a tunnel is closed by the server
+type TunnelCloseResponse struct {
+	Reason string `json:"reason"`
+	clienttunnel.TrafficStats
+}
+
 type ClientService interface {
 	SetPlusLicenseInfoCap(licensecap licensecap.CapabilityEx)
 	SetPlusAlertingServiceCap(as alertingcap.Service)
@@ -71,6 +88,10 @@ type ClientService interface {
 	GetRepo() *ClientRepository
 
 	SetCaddyAPI(capi caddy.API)
+	SetTunnelSessionAuth(auth *clienttunnel.TunnelSessionAuth)
+	SetAuditLog(auditLog *auditlog.AuditLog)
+	SetTunnelConnectionLog(connLog *tunnellog.Log)
+	SetAuthHeaderResolver(resolver AuthHeaderResolver)
 	StartClientTunnels(client *clientdata.Client, remotes []*models.Remote) ([]*clienttunnel.Tunnel, error)
 	StartTunnel(c *clientdata.Client, r *models.Remote, acl *clienttunnel.TunnelACL) (*clienttunnel.Tunnel, error)
 	FindTunnel(c *clientdata.Client, id string) *clienttunnel.Tunnel
@@ -79,14 +100,22 @@ type ClientService interface {
 	SetTunnelACL(c *clientdata.Client, t *clienttunnel.Tunnel, aclStr *string) error
 }
 
+// AuthHeaderResolver reads the value of the auth header of a tunnel from the vault. The value is neither stored nor
+// sent to the client, so it's read again whenever the tunnel is re-established.
+type AuthHeaderResolver func(ctx context.Context, client *clientdata.Client, remote *models.Remote) error
+
 type ClientServiceProvider struct {
-	repo              *ClientRepository
-	portDistributor   *ports.PortDistributor
-	tunnelProxyConfig *clienttunnel.InternalTunnelProxyConfig
-	caddyAPI          caddy.API
-	logger            *logger.Logger
-	acme              *acme.Acme
-	alertingService   alertingcap.Service
+	repo               *ClientRepository
+	portDistributor    *ports.PortDistributor
+	tunnelProxyConfig  *clienttunnel.InternalTunnelProxyConfig
+	caddyAPI           caddy.API
+	logger             *logger.Logger
+	acme               *acme.Acme
+	alertingService    alertingcap.Service
+	tunnelSessionAuth  *clienttunnel.TunnelSessionAuth
+	auditLog           *auditlog.AuditLog
+	tunnelConnLog      *tunnellog.Log
+	authHeaderResolver AuthHeaderResolver
 
 	licensecap licensecap.CapabilityEx
 
@@ -203,10 +232,11 @@ func InitClientService(
 	portDistributor *ports.PortDistributor,
 	db *sqlx.DB,
 	keepDisconnectedClients *time.Duration,
+	remoteClients RemoteClientChecker,
 	logger *logger.Logger,
 	acme *acme.Acme,
 ) (*ClientServiceProvider, error) {
-	repo, err := InitClientRepository(ctx, db, keepDisconnectedClients, logger)
+	repo, err := InitClientRepository(ctx, db, keepDisconnectedClients, remoteClients, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init Client Repository: %v", err)
 	}
@@ -407,6 +437,7 @@ func (s *ClientServiceProvider) StartClient(
 	s.UpdateClientStatus()
 
 	if !client.IsPaused() {
+		s.resolveAuthHeaders(ctx, client, req.Remotes, clog)
 		_, err = s.startClientTunnels(client, req.Remotes, clog)
 
 		if err != nil {
@@ -426,6 +457,22 @@ func (s *ClientServiceProvider) StartClient(
 	return client, nil
 }
 
+// resolveAuthHeaders reads the auth headers of re-established tunnels from the vault. If it fails, e.g. while the vault
+// is locked, the tunnel is started without the header.
+func (s *ClientServiceProvider) resolveAuthHeaders(ctx context.Context, client *clientdata.Client, remotes []*models.Remote, clog *logger.Logger) {
+	if s.authHeaderResolver == nil {
+		return
+	}
+	for _, remote := range remotes {
+		if remote.AuthHeaderVaultID == 0 || remote.AuthHeaderValue != "" {
+			continue
+		}
+		if err := s.authHeaderResolver(ctx, client, remote); err != nil {
+			clog.Errorf("failed to read auth header of tunnel %s from vault value %d: %v", remote, remote.AuthHeaderVaultID, err)
+		}
+	}
+}
+
 func getRemotes(tunnels []*clienttunnel.Tunnel) []*models.Remote {
 	r := make([]*models.Remote, 0, len(tunnels))
 	for _, t := range tunnels {
@@ -517,7 +564,7 @@ func (s *ClientServiceProvider) startClientTunnels(client *clientdata.Client, re
 	for _, remote := range remotes {
 		if !remote.IsLocalSpecified() {
 			clog.Debugf("no local specified")
-			port, err := s.portDistributor.GetRandomPort(remote.Protocol)
+			port, err := s.portDistributor.GetRandomPort(remote.ListenProtocol())
 			if err != nil {
 				return nil, err
 			}
@@ -527,7 +574,7 @@ func (s *ClientServiceProvider) startClientTunnels(client *clientdata.Client, re
 			clog.Debugf("using random port %s", remote.LocalPort)
 		} else {
 			clog.Debugf("checking local port %s", remote.LocalPort)
-			if err := s.checkLocalPort(remote.Protocol, remote.LocalPort); err != nil {
+			if err := s.checkLocalPort(remote.ListenProtocol(), remote.LocalPort); err != nil {
 				return nil, err
 			}
 		}
@@ -577,6 +624,11 @@ func (s *ClientServiceProvider) checkLocalPort(protocol, port string) error {
 
 func (s *ClientServiceProvider) Terminate(client *clientdata.Client) error {
 	s.log().Infof("terminating client: %s: %s", client.GetID(), client.GetName())
+	// the tunnels are closed with the ssh connection
+	for _, t := range client.GetTunnels() {
+		s.auditTunnelClose(client, t, TunnelCloseReasonClientDisconnected)
+	}
+
 	keepDisconnectedClientsDuration := s.repo.GetKeepDisconnectedClients()
 	if keepDisconnectedClientsDuration != nil && *keepDisconnectedClientsDuration == 0 {
 		return s.repo.Delete(client)
@@ -700,9 +752,8 @@ func (s *ClientServiceProvider) CheckClientsAccess(clients []*clientdata.Client,
 	}
 
 	var clientsWithNoAccess []string
-	userGroups := user.GetGroups()
 	for _, client := range clients {
-		if client.HasAccessViaUserGroups(userGroups) || client.UserGroupHasAccessViaClientGroup(userGroups, clientGroups) {
+		if s.repo.UserHasAccess(user, client, clientGroups) {
 			continue
 		}
 
@@ -746,6 +797,11 @@ func (s *ClientServiceProvider) getExistingClientByID(clientID string) (*clientd
 func (s *ClientServiceProvider) excludeNotAllowedTunnels(clog *logger.Logger, tunnels []*models.Remote, conn ssh.Conn) ([]*models.Remote, error) {
 	filtered := make([]*models.Remote, 0, len(tunnels))
 	for _, t := range tunnels {
+		// socks5 destinations are checked by the client on every connection
+		if t.IsSOCKS5() {
+			filtered = append(filtered, t)
+			continue
+		}
 		allowed, err := clienttunnel.IsAllowed(t.Remote(), conn, s.log())
 		if err != nil {
 			if strings.Contains(err.Error(), "unknown request") {
@@ -787,6 +843,26 @@ func (s *ClientServiceProvider) SetCaddyAPI(capi caddy.API) {
 	s.caddyAPI = capi
 }
 
+func (s *ClientServiceProvider) SetTunnelSessionAuth(auth *clienttunnel.TunnelSessionAuth) {
+	// unguarded as set during initialization
+	s.tunnelSessionAuth = auth
+}
+
+func (s *ClientServiceProvider) SetAuditLog(auditLog *auditlog.AuditLog) {
+	// unguarded as set during initialization
+	s.auditLog = auditLog
+}
+
+func (s *ClientServiceProvider) SetTunnelConnectionLog(connLog *tunnellog.Log) {
+	// unguarded as set during initialization
+	s.tunnelConnLog = connLog
+}
+
+func (s *ClientServiceProvider) SetAuthHeaderResolver(resolver AuthHeaderResolver) {
+	// unguarded as set during initialization
+	s.authHeaderResolver = resolver
+}
+
 func (s *ClientServiceProvider) StartTunnel(
 	client *clientdata.Client,
 	remote *models.Remote,
@@ -838,6 +914,10 @@ func (s *ClientServiceProvider) StartTunnel(
 		go s.terminateTunnelOnIdleTimeout(ctx, tunnel, client)
 	}
 
+	if tunnel.Quota > 0 {
+		go s.terminateTunnelOnQuotaExceeded(ctx, tunnel, client)
+	}
+
 	existingTunnels := client.GetTunnels()
 	existingTunnels = append(existingTunnels, tunnel)
 	client.SetTunnels(existingTunnels)
@@ -888,7 +968,7 @@ func (s *ClientServiceProvider) startCaddyDownstreamProxy(
 func (s *ClientServiceProvider) startRegularTunnel(ctx context.Context, client *clientdata.Client, remote *models.Remote, acl *clienttunnel.TunnelACL) (*clienttunnel.Tunnel, error) {
 	tunnelID := client.NewTunnelID()
 
-	tunnel, err := clienttunnel.NewTunnel(client.Log(), client.GetConnection(), tunnelID, *remote, acl)
+	tunnel, err := clienttunnel.NewTunnel(client.Log(), client.GetConnection(), tunnelID, *remote, acl, s.tunnelConnLog.ForTunnel(client.GetID(), tunnelID))
 	if err != nil {
 		return nil, err
 	}
@@ -935,7 +1015,7 @@ func (s *ClientServiceProvider) startTunnelWithProxy(
 	tunnelID := client.NewTunnelID()
 
 	// original tunnel will use the reconfigured original remote
-	t, err := clienttunnel.NewTunnel(clientLogger, client.GetConnection(), tunnelID, *remote, acl)
+	t, err := clienttunnel.NewTunnel(clientLogger, client.GetConnection(), tunnelID, *remote, acl, s.tunnelConnLog.ForTunnel(client.GetID(), tunnelID))
 	if err != nil {
 		return nil, err
 	}
@@ -947,7 +1027,9 @@ func (s *ClientServiceProvider) startTunnelWithProxy(
 	}
 
 	// create new proxy tunnel listening at the original tunnel local host addr
-	tProxy := clienttunnel.NewInternalTunnelProxy(t, clientLogger, s.tunnelProxyConfig, proxyHost, proxyPort, proxyACL, s.acme)
+	tProxy := clienttunnel.NewInternalTunnelProxy(t, clientLogger, client.GetConnection(), s.tunnelProxyConfig, proxyHost, proxyPort, proxyACL, s.acme)
+	tProxy.ClientID = clientID
+	tProxy.SessionAuth = s.tunnelSessionAuth
 	clientLogger.Debugf("client %s starting tunnel proxy", clientID)
 	if err := tProxy.Start(ctx); err != nil {
 		clientLogger.Debugf("tunnel proxy could not be started, tunnel must be terminated: %v", err)
@@ -973,7 +1055,7 @@ func (s *ClientServiceProvider) cleanupOnAutoCloseDeadlineExceeded(ctx context.C
 	<-ctx.Done()
 	// DeadlineExceeded err is expected when tunnel AutoClose period is reached, otherwise skip cleanup
 	if ctx.Err() == context.DeadlineExceeded {
-		s.cleanupAfterAutoClose(c, t)
+		s.cleanupAfterAutoClose(c, t, TunnelCloseReasonAutoClose)
 	}
 }
 
@@ -990,7 +1072,7 @@ func (s *ClientServiceProvider) terminateTunnelOnIdleTimeout(ctx context.Context
 			if sinceLastActive > idleTimeout {
 				c.Log().Infof("Terminating... inactivity period is reached: %d minute(s)", t.IdleTimeoutMinutes)
 				_ = t.Terminate(true)
-				s.cleanupAfterAutoClose(c, t)
+				s.cleanupAfterAutoClose(c, t, TunnelCloseReasonIdleTimeout)
 				return
 			}
 			timer.Reset(idleTimeout - sinceLastActive)
@@ -998,10 +1080,20 @@ func (s *ClientServiceProvider) terminateTunnelOnIdleTimeout(ctx context.Context
 	}
 }
 
-func (s *ClientServiceProvider) cleanupAfterAutoClose(c *clientdata.Client, t *clienttunnel.Tunnel) {
+func (s *ClientServiceProvider) terminateTunnelOnQuotaExceeded(ctx context.Context, t *clienttunnel.Tunnel, c *clientdata.Client) {
+	select {
+	case <-ctx.Done():
+	case <-t.Traffic.QuotaExceeded():
+		c.Log().Infof("Terminating... traffic quota of %d bytes is exceeded", t.Quota)
+		_ = t.Terminate(true)
+		s.cleanupAfterAutoClose(c, t, TunnelCloseReasonQuotaExceeded)
+	}
+}
+
+func (s *ClientServiceProvider) cleanupAfterAutoClose(c *clientdata.Client, t *clienttunnel.Tunnel, reason string) {
 	clientLogger := c.Log()
 
-	clientLogger.Infof("Auto closing tunnel %s ...", t.ID)
+	clientLogger.Infof("Auto closing tunnel %s (reason: %s) ...", t.ID, reason)
 
 	// stop tunnel proxy
 	if t.InternalTunnelProxy != nil {
@@ -1020,9 +1112,22 @@ func (s *ClientServiceProvider) cleanupAfterAutoClose(c *clientdata.Client, t *c
 		clientLogger.Errorf("unable to save client after auto close cleanup: %v", err)
 	}
 
+	s.auditTunnelClose(c, t, reason)
+
 	clientLogger.Debugf("auto closed tunnel with id=%s removed", t.ID)
 }
 
+func (s *ClientServiceProvider) auditTunnelClose(c *clientdata.Client, t *clienttunnel.Tunnel, reason string) {
+	s.auditLog.Entry(auditlog.ApplicationClientTunnel, auditlog.ActionClose).
+		WithClient(c).
+		WithID(t.ID).
+		WithResponse(TunnelCloseResponse{
+			Reason:       reason,
+			TrafficStats: t.TrafficStats(),
+		}).
+		Save()
+}
+
 func (s *ClientServiceProvider) TerminateTunnel(c *clientdata.Client, t *clienttunnel.Tunnel, force bool) error {
 	clientLogger := c.Log()
 
@@ -1060,6 +1165,10 @@ func (s *ClientServiceProvider) SetTunnelACL(c *clientdata.Client, t *clienttunn
 	var err error
 	var acl *clienttunnel.TunnelACL
 
+	if t.Direct && (aclStr == nil || *aclStr == "") {
+		return fmt.Errorf("direct tunnels require an acl")
+	}
+
 	t.Remote.ACL = aclStr
 
 	if aclStr != nil {
@@ -157,7 +157,7 @@ func TestStartClientConcurrency(t *testing.T) {
 		mockConns = append(mockConns, mockConn)
 	}
 
-	repo, err := InitClientRepository(context.Background(), clientDB, nil, testLog)
+	repo, err := InitClientRepository(context.Background(), clientDB, nil, nil, testLog)
 	require.NoError(t, err)
 
 	cs := &ClientServiceProvider{
@@ -229,6 +229,36 @@ func TestStartClientDisconnected(t *testing.T) {
 	assert.Equal(t, 13, client.UpdatesStatus.UpdatesAvailable)
 }
 
+func TestResolveAuthHeaders(t *testing.T) {
+	client := New(t).Logger(testLog).Build()
+	cs := &ClientServiceProvider{logger: testLog}
+	remotes := []*models.Remote{
+		{RemotePort: "80", AuthHeaderName: "Authorization", AuthHeaderVaultID: 1},
+		{RemotePort: "81", AuthHeaderName: "Authorization", AuthHeaderVaultID: 2},
+		{RemotePort: "82", AuthHeaderName: "Authorization", AuthHeaderVaultID: 3, AuthHeaderValue: "Bearer kept"},
+		{RemotePort: "83"},
+	}
+
+	var resolved []int
+	cs.SetAuthHeaderResolver(func(ctx context.Context, c *clientdata.Client, remote *models.Remote) error {
+		assert.Equal(t, client, c)
+		resolved = append(resolved, remote.AuthHeaderVaultID)
+		if remote.AuthHeaderVaultID == 2 {
+			return errors.New("vault is locked")
+		}
+		remote.AuthHeaderValue = "Bearer secret"
+		return nil
+	})
+	cs.resolveAuthHeaders(context.Background(), client, remotes, testLog)
+
+	assert.Equal(t, []int{1, 2}, resolved)
+	assert.Equal(t, "Bearer secret", remotes[0].AuthHeaderValue)
+	// tunnels are started without the header if it can't be read
+	assert.Empty(t, remotes[1].AuthHeaderValue)
+	assert.Equal(t, "Bearer kept", remotes[2].AuthHeaderValue)
+	assert.Empty(t, remotes[3].AuthHeaderValue)
+}
+
 func TestDeleteOfflineClient(t *testing.T) {
 	c1Active := New(t).Logger(testLog).Build()
 	c2Active := New(t).Logger(testLog).Build()
@@ -478,6 +508,19 @@ func TestCheckClientsAccess(t *testing.T) {
 	}
 }
 
+func TestCheckClientsAccessWithoutRepository(t *testing.T) {
+	c1 := New(t).AllowedUserGroups([]string{"group1"}).Logger(testLog).Build()
+	clientService := NewClientService(nil, nil, nil, testLog, nil)
+
+	assert.NoError(t, clientService.CheckClientsAccess([]*clientdata.Client{c1}, &users.User{Groups: []string{"group1"}}, nil))
+
+	gotErr := clientService.CheckClientsAccess([]*clientdata.Client{c1}, &users.User{Groups: []string{"group2"}}, nil)
+	assert.Equal(t, apiErrors.APIError{
+		Message:    fmt.Sprintf("Access denied to client(s) with ID(s): %v", c1.GetID()),
+		HTTPStatus: http.StatusForbidden,
+	}, gotErr)
+}
+
 func TestGetTunnelsToReestablish(t *testing.T) {
 	var randomPorts = []string{"5001", "5002", "5003", "5004", "5005", "5006", "5007", "5008", "5009"}
 	testCases := []struct {
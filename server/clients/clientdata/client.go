@@ -3,6 +3,7 @@ package clientdata
 import (
 	"context"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -73,12 +74,17 @@ type Client struct {
 
 	// DisconnectedAt is a time when a client was disconnected. If nil - it's connected.
 	DisconnectedAt      *time.Time            `json:"disconnected_at"`
+	ConnectedAt         *time.Time            `json:"connected_at"`
 	LastHeartbeatAt     *time.Time            `json:"last_heartbeat_at"`
 	ClientAuthID        string                `json:"client_auth_id"`
 	AllowedUserGroups   []string              `json:"allowed_user_groups"`
 	UpdatesStatus       *models.UpdatesStatus `json:"updates_status"`
 	IPAddresses         *models.IPAddresses   `json:"ext_ip_addresses"`
 	ClientConfiguration *clientconfig.Config  `json:"client_configuration"`
+	// PendingAttributes are attribute changes queued while the client was disconnected, they are applied on reconnect
+	PendingAttributes []models.AttributesOperation `json:"pending_attributes"`
+	// InventorySummary is loaded from the inventory store, it's neither persisted with the client nor part of the api
+	InventorySummary *models.InventorySummary `json:"-"`
 
 	Connection   ssh.Conn        `json:"-"`
 	Context      context.Context `json:"-"`
@@ -448,6 +454,12 @@ func (c *Client) SetUpdatesStatus(status *models.UpdatesStatus) {
 	c.flock.Unlock()
 }
 
+func (c *Client) SetInventorySummary(summary *models.InventorySummary) {
+	c.flock.Lock()
+	c.InventorySummary = summary
+	c.flock.Unlock()
+}
+
 func (c *Client) SetIPAddresses(IPAddresses *models.IPAddresses) {
 	c.flock.Lock()
 	c.IPAddresses = IPAddresses
@@ -489,8 +501,12 @@ func (c *Client) IsConnected() bool {
 }
 
 func (c *Client) SetConnected() {
-	c.Log().Debugf("%s: set to connected at %s", c.GetID(), time.Now())
+	now := Now()
+	c.Log().Debugf("%s: set to connected at %s", c.GetID(), now)
 	c.SetDisconnectedAt(nil)
+	c.flock.Lock()
+	c.ConnectedAt = &now
+	c.flock.Unlock()
 }
 
 func (c *Client) SetDisconnectedNow() {
@@ -631,9 +647,131 @@ func (c *Client) BelongsTo(group *cgroups.ClientGroup) bool {
 		return false
 	}
 
+	if !c.belongsByInventory(p) {
+		return false
+	}
+
+	if !p.Expression.Matches(c.expressionValue) {
+		return false
+	}
+
+	return true
+}
+
+func (c *Client) belongsByInventory(p *cgroups.ClientParams) bool {
+	inventory := c.InventorySummary
+	if inventory == nil {
+		// clients without inventory don't match any inventory param
+		inventory = &models.InventorySummary{}
+	}
+
+	if !p.Package.MatchesOneOf(inventory.Packages...) {
+		return false
+	}
+	if !p.ListeningPort.MatchesOneOf(inventory.ListeningPorts...) {
+		return false
+	}
+	if !p.LocalUser.MatchesOneOf(inventory.LocalUsers...) {
+		return false
+	}
+	if !p.SystemVendor.MatchesOneOf(nonEmpty(inventory.SystemVendor)...) {
+		return false
+	}
+	if !p.SystemModel.MatchesOneOf(nonEmpty(inventory.SystemModel)...) {
+		return false
+	}
 	return true
 }
 
+// expressionValue returns the value of a variable of group expressions, the caller must hold the lock
+func (c *Client) expressionValue(name string) interface{} {
+	if strings.HasPrefix(name, cgroups.ExpressionLabelsPrefix) {
+		// a missing label is empty to allow comparing it with != and ""
+		return c.Labels[strings.TrimPrefix(name, cgroups.ExpressionLabelsPrefix)]
+	}
+
+	switch name {
+	case "id":
+		return c.ID
+	case "name":
+		return c.Name
+	case "os":
+		return c.OS
+	case "os_arch":
+		return c.OSArch
+	case "os_family":
+		return c.OSFamily
+	case "os_kernel":
+		return c.OSKernel
+	case "os_full_name":
+		return c.OSFullName
+	case "os_version":
+		return c.OSVersion
+	case "os_virtualization_system":
+		return c.OSVirtualizationSystem
+	case "os_virtualization_role":
+		return c.OSVirtualizationRole
+	case "cpu_vendor":
+		return c.CPUVendor
+	case "cpu_model_name":
+		return c.CPUModelName
+	case "timezone":
+		return c.Timezone
+	case "hostname":
+		return c.Hostname
+	case "version":
+		return c.Version
+	case "address":
+		return c.Address
+	case "client_auth_id":
+		return c.ClientAuthID
+	case "connection_state":
+		if c.DisconnectedAt != nil {
+			return string(Disconnected)
+		}
+		return string(Connected)
+	case "tags":
+		return c.Tags
+	case "ipv4":
+		return c.IPv4
+	case "ipv6":
+		return c.IPv6
+	case "num_cpus":
+		return float64(c.NumCPUs)
+	case "mem_total":
+		return float64(c.MemoryTotal)
+	case "updates.available", "updates.security", "updates.reboot_pending":
+		if c.UpdatesStatus == nil {
+			return nil
+		}
+		switch name {
+		case "updates.available":
+			return float64(c.UpdatesStatus.UpdatesAvailable)
+		case "updates.security":
+			return float64(c.UpdatesStatus.SecurityUpdatesAvailable)
+		}
+		return c.UpdatesStatus.RebootPending
+	case "connection_age":
+		// the time since the client connected or disconnected
+		since := c.ConnectedAt
+		if c.DisconnectedAt != nil {
+			since = c.DisconnectedAt
+		}
+		if since == nil {
+			return nil
+		}
+		return Now().Sub(*since)
+	}
+	return nil
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
 func (c *Client) CalculateConnectionState() ConnectionState {
 	if c.IsConnected() {
 		return Connected
@@ -682,6 +820,33 @@ func (c *Client) SetAttributes(attributes models.Attributes) {
 	c.flock.Unlock()
 }
 
+// QueueAttributes adds attribute changes to be applied when the client reconnects
+func (c *Client) QueueAttributes(operations []models.AttributesOperation) {
+	c.flock.Lock()
+	defer c.flock.Unlock()
+	c.PendingAttributes = append(c.PendingAttributes, operations...)
+}
+
+func (c *Client) GetPendingAttributes() []models.AttributesOperation {
+	c.flock.RLock()
+	defer c.flock.RUnlock()
+	res := make([]models.AttributesOperation, len(c.PendingAttributes))
+	copy(res, c.PendingAttributes)
+	return res
+}
+
+// RemovePendingAttributes removes the first n pending attribute changes after they were applied,
+// changes queued in the meantime are kept
+func (c *Client) RemovePendingAttributes(n int) {
+	c.flock.Lock()
+	defer c.flock.Unlock()
+	if n >= len(c.PendingAttributes) {
+		c.PendingAttributes = nil
+		return
+	}
+	c.PendingAttributes = c.PendingAttributes[n:]
+}
+
 // NewClientID generates a new client ID.
 func NewClientID() (string, error) {
 	return random.UUID4()
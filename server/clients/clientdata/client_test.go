@@ -10,6 +10,7 @@ import (
 
 	"github.com/riportdev/riport/server/api/users"
 	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/share/models"
 )
 
 func NewTestClient(id string, address string, hostname string, clientAuthID string, connection ssh.Conn) (c *Client) {
@@ -234,6 +235,134 @@ func TestClientBelongsToGroup(t *testing.T) {
 	}
 }
 
+func TestClientBelongsToGroupByInventory(t *testing.T) {
+	withInventory := &Client{
+		ID: "client-1",
+		InventorySummary: &models.InventorySummary{
+			Packages:       []string{"nginx", "openssl"},
+			ListeningPorts: []string{"tcp/22", "tcp/443"},
+			LocalUsers:     []string{"root", "deploy"},
+			SystemVendor:   "Dell Inc.",
+			SystemModel:    "PowerEdge R640",
+		},
+	}
+	withoutInventory := &Client{
+		ID: "client-2",
+	}
+
+	testCases := []struct {
+		name    string
+		client  *Client
+		params  *cgroups.ClientParams
+		wantRes bool
+	}{
+		{
+			name:   "all inventory params match",
+			client: withInventory,
+			params: &cgroups.ClientParams{
+				Package:       &cgroups.ParamValues{"nginx*"},
+				ListeningPort: &cgroups.ParamValues{"tcp/443"},
+				LocalUser:     &cgroups.ParamValues{"deploy"},
+				SystemVendor:  &cgroups.ParamValues{"dell*"},
+				SystemModel:   &cgroups.ParamValues{"poweredge*"},
+			},
+			wantRes: true,
+		},
+		{
+			name:    "package not installed",
+			client:  withInventory,
+			params:  &cgroups.ClientParams{Package: &cgroups.ParamValues{"apache2"}},
+			wantRes: false,
+		},
+		{
+			name:    "client without inventory",
+			client:  withoutInventory,
+			params:  &cgroups.ClientParams{SystemVendor: &cgroups.ParamValues{"*"}},
+			wantRes: false,
+		},
+		{
+			name:    "no inventory params",
+			client:  withoutInventory,
+			params:  &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2"}},
+			wantRes: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantRes, tc.client.BelongsTo(&cgroups.ClientGroup{ID: "group-1", Params: tc.params}))
+		})
+	}
+}
+
+func TestClientBelongsToGroupByExpression(t *testing.T) {
+	connectedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	Now = func() time.Time {
+		return connectedAt.Add(48 * time.Hour)
+	}
+	defer func() { Now = time.Now }()
+
+	client := &Client{
+		ID:                   "client-1",
+		Version:              "0.9.12",
+		OSVirtualizationRole: "guest",
+		Labels:               map[string]string{"env": "prod"},
+		UpdatesStatus:        &models.UpdatesStatus{UpdatesAvailable: 5, SecurityUpdatesAvailable: 1},
+		ConnectedAt:          &connectedAt,
+	}
+	withoutUpdates := &Client{
+		ID:          "client-2",
+		Version:     "0.8.0",
+		ConnectedAt: &connectedAt,
+	}
+
+	testCases := []struct {
+		name       string
+		client     *Client
+		expression string
+		wantRes    bool
+	}{
+		{
+			name:       "all match",
+			client:     client,
+			expression: `labels.env == "prod" && updates.security > 0 && os_virtualization_role == "guest" && version >= "0.9.0" && connection_age > 1d`,
+			wantRes:    true,
+		},
+		{
+			name:       "connection age",
+			client:     client,
+			expression: `connection_state == "connected" && connection_age < 1d`,
+			wantRes:    false,
+		},
+		{
+			name:       "missing label",
+			client:     withoutUpdates,
+			expression: `labels.env != "prod" && version < "0.9"`,
+			wantRes:    true,
+		},
+		{
+			name:       "unknown updates",
+			client:     withoutUpdates,
+			expression: `updates.security == 0`,
+			wantRes:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := &cgroups.ClientParams{Expression: cgroups.NewExpression(tc.expression)}
+			assert.Equal(t, tc.wantRes, tc.client.BelongsTo(&cgroups.ClientGroup{ID: "group-1", Params: params}))
+		})
+	}
+
+	// other params must match as well
+	params := &cgroups.ClientParams{
+		ClientID:   &cgroups.ParamValues{"client-2"},
+		Expression: cgroups.NewExpression(`labels.env == "prod"`),
+	}
+	assert.False(t, client.BelongsTo(&cgroups.ClientGroup{ID: "group-1", Params: params}))
+}
+
 func TestClientBelongsToGroupLogicalOps(t *testing.T) {
 	c1 := &Client{
 		ID:           "test-client-id-1",
@@ -436,3 +565,20 @@ func TestToCalculatedWhenDisconnected(t *testing.T) {
 	assert.Equal(t, client, calculated.Client)
 	assert.Equal(t, "disconnected", string(calculated.ConnectionState))
 }
+
+func TestPendingAttributes(t *testing.T) {
+	client := &Client{}
+	addTag := models.AttributesOperation{Op: models.AttributesOperationAddTag, Tag: "web"}
+	setLabel := models.AttributesOperation{Op: models.AttributesOperationSetLabel, Key: "env", Value: "prod"}
+
+	client.QueueAttributes([]models.AttributesOperation{addTag})
+	pending := client.GetPendingAttributes()
+	client.QueueAttributes([]models.AttributesOperation{setLabel})
+
+	// changes queued after the pending ones were read are kept
+	client.RemovePendingAttributes(len(pending))
+	assert.Equal(t, []models.AttributesOperation{setLabel}, client.GetPendingAttributes())
+
+	client.RemovePendingAttributes(1)
+	assert.Empty(t, client.GetPendingAttributes())
+}
@@ -0,0 +1,355 @@
+package clientsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/inventory"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+const (
+	FilterPackage               = "package"
+	FilterPackageVersion        = "package_version"
+	FilterProcess               = "process"
+	FilterProcessCmdline        = "process_cmdline"
+	FilterMountpoint            = "mountpoint"
+	FilterMountpointUsedPercent = "mountpoint_used_percent"
+)
+
+// FilterColumns are the columns searched in the inventory and the latest measurement of the clients,
+// all other filters are applied to the clients like on the clients list
+var FilterColumns = map[string]bool{
+	FilterPackage:               true,
+	FilterPackageVersion:        true,
+	FilterProcess:               true,
+	FilterProcessCmdline:        true,
+	FilterMountpoint:            true,
+	FilterMountpointUsedPercent: true,
+}
+
+var SupportedFilters = map[string]bool{
+	FilterPackage:                        true,
+	FilterPackageVersion:                 true,
+	FilterPackageVersion + "[eq]":        true,
+	FilterPackageVersion + "[lt]":        true,
+	FilterPackageVersion + "[gt]":        true,
+	FilterProcess:                        true,
+	FilterProcessCmdline:                 true,
+	FilterMountpoint:                     true,
+	FilterMountpointUsedPercent:          true,
+	FilterMountpointUsedPercent + "[eq]": true,
+	FilterMountpointUsedPercent + "[lt]": true,
+	FilterMountpointUsedPercent + "[gt]": true,
+}
+
+type InventoryProvider interface {
+	ListItemsOfAllClients(ctx context.Context, options *query.ListOptions) ([]*inventory.Item, error)
+}
+
+type MeasurementProvider interface {
+	ListLatestMeasurements(ctx context.Context) ([]*models.Measurement, error)
+}
+
+type Searcher struct {
+	inventory    InventoryProvider
+	measurements MeasurementProvider
+}
+
+func NewSearcher(inventory InventoryProvider, measurements MeasurementProvider) *Searcher {
+	return &Searcher{
+		inventory:    inventory,
+		measurements: measurements,
+	}
+}
+
+type filterGroups struct {
+	packages    []query.FilterOption
+	processes   []query.FilterOption
+	mountpoints []query.FilterOption
+}
+
+// Search returns the sorted ids of the given clients matching all filters.
+// Each filter group is matched by a single package, process or mountpoint of a client,
+// e.g. package and package_version filters must be satisfied by the same package.
+func (s *Searcher) Search(ctx context.Context, clientIDs []string, filters []query.FilterOption) ([]string, error) {
+	groups, err := groupFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make(map[string]bool, len(clientIDs))
+	for _, clientID := range clientIDs {
+		matching[clientID] = true
+	}
+
+	if len(groups.packages) > 0 {
+		matched, err := s.searchPackages(ctx, groups.packages)
+		if err != nil {
+			return nil, err
+		}
+		intersect(matching, matched)
+	}
+
+	if len(groups.processes) > 0 || len(groups.mountpoints) > 0 {
+		matched, err := s.searchMeasurements(ctx, groups)
+		if err != nil {
+			return nil, err
+		}
+		intersect(matching, matched)
+	}
+
+	res := make([]string, 0, len(matching))
+	for clientID := range matching {
+		res = append(res, clientID)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func groupFilters(filters []query.FilterOption) (*filterGroups, error) {
+	groups := &filterGroups{}
+	for _, f := range filters {
+		if len(f.Column) != 1 {
+			return nil, newBadRequest("filter %s must not combine columns", f)
+		}
+		if f.ValuesLogicalOperator == query.FilterLogicalOperatorTypeAND {
+			return nil, newBadRequest("filter %s does not support and(...) values", f)
+		}
+
+		switch f.Column[0] {
+		case FilterPackage, FilterPackageVersion:
+			groups.packages = append(groups.packages, f)
+		case FilterProcess, FilterProcessCmdline:
+			groups.processes = append(groups.processes, f)
+		case FilterMountpoint:
+			groups.mountpoints = append(groups.mountpoints, f)
+		case FilterMountpointUsedPercent:
+			for _, v := range f.Values {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					return nil, newBadRequest("filter %s expects a number, got %q", f, v)
+				}
+			}
+			groups.mountpoints = append(groups.mountpoints, f)
+		default:
+			return nil, newBadRequest("unsupported filter field '%s'", f)
+		}
+	}
+	return groups, nil
+}
+
+func (s *Searcher) searchPackages(ctx context.Context, filters []query.FilterOption) (map[string]bool, error) {
+	options := &query.ListOptions{
+		Filters: []query.FilterOption{{Column: []string{"category"}, Values: []string{models.InventoryCategoryPackage}}},
+	}
+	var versionFilters []query.FilterOption
+	for _, f := range filters {
+		if f.Column[0] == FilterPackage {
+			// package names are filtered by the db
+			options.Filters = append(options.Filters, query.FilterOption{Column: []string{"name"}, Values: f.Values})
+			continue
+		}
+		versionFilters = append(versionFilters, f)
+	}
+
+	items, err := s.inventory.ListItemsOfAllClients(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool)
+	for _, item := range items {
+		if matched[item.ClientID] {
+			continue
+		}
+		if matchesAll(versionFilters, func(f query.FilterOption, value string) bool {
+			return matchesVersion(item.Version, f.Operator, value)
+		}) {
+			matched[item.ClientID] = true
+		}
+	}
+	return matched, nil
+}
+
+func (s *Searcher) searchMeasurements(ctx context.Context, groups *filterGroups) (map[string]bool, error) {
+	measurements, err := s.measurements.ListLatestMeasurements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool)
+	for _, m := range measurements {
+		if len(groups.processes) > 0 && !matchesAnyProcess(m.Processes, groups.processes) {
+			continue
+		}
+		if len(groups.mountpoints) > 0 && !matchesAnyMountpoint(m.Mountpoints, groups.mountpoints) {
+			continue
+		}
+		matched[m.ClientID] = true
+	}
+	return matched, nil
+}
+
+type process struct {
+	Name    string `json:"name"`
+	Cmdline string `json:"cmdline"`
+}
+
+func matchesAnyProcess(processesJSON string, filters []query.FilterOption) bool {
+	var processes []process
+	if err := json.Unmarshal([]byte(processesJSON), &processes); err != nil {
+		return false
+	}
+
+	for _, p := range processes {
+		if matchesAll(filters, func(f query.FilterOption, value string) bool {
+			if f.Column[0] == FilterProcessCmdline {
+				return matchesWildcard(p.Cmdline, value)
+			}
+			return matchesWildcard(p.Name, value)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+type mountpoint struct {
+	path        string
+	usedPercent float64
+}
+
+func matchesAnyMountpoint(mountpointsJSON string, filters []query.FilterOption) bool {
+	for _, mp := range parseMountpoints(mountpointsJSON) {
+		if matchesAll(filters, func(f query.FilterOption, value string) bool {
+			if f.Column[0] == FilterMountpointUsedPercent {
+				return matchesNumber(mp.usedPercent, f.Operator, value)
+			}
+			return matchesWildcard(mp.path, value)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMountpoints reads the used percent of each mountpoint from the metrics of a measurement.
+// They are keyed by metric and path, e.g. "free_b./data". The used percent is calculated from the
+// default metrics free_b and total_b if the client doesn't report used_percent itself.
+func parseMountpoints(mountpointsJSON string) []mountpoint {
+	metrics := make(map[string]float64)
+	if err := json.Unmarshal([]byte(mountpointsJSON), &metrics); err != nil {
+		return nil
+	}
+
+	paths := make(map[string]bool)
+	for key := range metrics {
+		for _, prefix := range []string{"total_b.", "used_percent."} {
+			if strings.HasPrefix(key, prefix) {
+				paths[strings.TrimPrefix(key, prefix)] = true
+			}
+		}
+	}
+
+	res := make([]mountpoint, 0, len(paths))
+	for path := range paths {
+		usedPercent, ok := metrics["used_percent."+path]
+		if !ok {
+			total := metrics["total_b."+path]
+			free, hasFree := metrics["free_b."+path]
+			if !hasFree || total <= 0 {
+				continue
+			}
+			usedPercent = (total - free) / total * 100
+		}
+		res = append(res, mountpoint{path: path, usedPercent: usedPercent})
+	}
+	return res
+}
+
+// matchesAll returns true if each filter is matched by any of its values
+func matchesAll(filters []query.FilterOption, match func(f query.FilterOption, value string) bool) bool {
+	for _, f := range filters {
+		matched := false
+		for _, value := range f.Values {
+			if match(f, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesWildcard matches case-insensitively, * matches any characters like in the other list filters
+func matchesWildcard(actual, pattern string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.EqualFold(actual, pattern)
+	}
+	actual = strings.ToLower(actual)
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	if !strings.HasPrefix(actual, parts[0]) {
+		return false
+	}
+	actual = actual[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(actual, part)
+		if i < 0 {
+			return false
+		}
+		actual = actual[i+len(part):]
+	}
+	return strings.HasSuffix(actual, last)
+}
+
+func matchesVersion(actual string, operator query.FilterOperatorType, value string) bool {
+	switch operator {
+	case query.FilterOperatorTypeEQ:
+		return CompareVersions(actual, value) == 0
+	case query.FilterOperatorTypeLT:
+		return CompareVersions(actual, value) < 0
+	case query.FilterOperatorTypeGT:
+		return CompareVersions(actual, value) > 0
+	}
+	return matchesWildcard(actual, value)
+}
+
+func matchesNumber(actual float64, operator query.FilterOperatorType, value string) bool {
+	expected, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	switch operator {
+	case query.FilterOperatorTypeLT:
+		return actual < expected
+	case query.FilterOperatorTypeGT:
+		return actual > expected
+	}
+	return actual == expected
+}
+
+func intersect(matching map[string]bool, matched map[string]bool) {
+	for clientID := range matching {
+		if !matched[clientID] {
+			delete(matching, clientID)
+		}
+	}
+}
+
+func newBadRequest(format string, args ...interface{}) error {
+	return errors.APIError{
+		Message:    fmt.Sprintf(format, args...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
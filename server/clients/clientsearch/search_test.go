@@ -0,0 +1,210 @@
+package clientsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/inventory"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+type mockInventoryProvider struct {
+	items   []*inventory.Item
+	options *query.ListOptions
+}
+
+func (p *mockInventoryProvider) ListItemsOfAllClients(ctx context.Context, options *query.ListOptions) ([]*inventory.Item, error) {
+	p.options = options
+	return p.items, nil
+}
+
+type mockMeasurementProvider struct {
+	measurements []*models.Measurement
+}
+
+func (p *mockMeasurementProvider) ListLatestMeasurements(ctx context.Context) ([]*models.Measurement, error) {
+	return p.measurements, nil
+}
+
+func TestSearch(t *testing.T) {
+	inventoryProvider := &mockInventoryProvider{
+		items: []*inventory.Item{
+			{ClientID: "client-1", Category: models.InventoryCategoryPackage, Name: "openssl", Version: "1.1.1f-1ubuntu2.19"},
+			{ClientID: "client-2", Category: models.InventoryCategoryPackage, Name: "openssl", Version: "3.0.2-0ubuntu1.10"},
+			{ClientID: "client-3", Category: models.InventoryCategoryPackage, Name: "openssl", Version: "1:1.0.2k-26.el7_9"},
+		},
+	}
+	measurementProvider := &mockMeasurementProvider{
+		measurements: []*models.Measurement{
+			{
+				ClientID:    "client-1",
+				Processes:   `[{"pid":1,"name":"systemd","cmdline":"/sbin/init"},{"pid":812,"name":"nginx","cmdline":"nginx: master process /usr/sbin/nginx"}]`,
+				Mountpoints: `{"free_b./":80000,"total_b./":100000,"free_b./data":5000,"total_b./data":100000}`,
+			},
+			{
+				ClientID:    "client-2",
+				Processes:   `[{"pid":1,"name":"systemd","cmdline":"/sbin/init"}]`,
+				Mountpoints: `{"used_percent./data":95.5}`,
+			},
+			{
+				ClientID:    "client-3",
+				Processes:   "",
+				Mountpoints: `{"free_b./data":50000,"total_b./data":100000}`,
+			},
+		},
+	}
+	searcher := NewSearcher(inventoryProvider, measurementProvider)
+	allClients := []string{"client-1", "client-2", "client-3"}
+
+	testCases := []struct {
+		Name        string
+		ClientIDs   []string
+		Filters     []query.FilterOption
+		ExpectedIDs []string
+	}{
+		{
+			Name:        "no filters",
+			ClientIDs:   allClients,
+			ExpectedIDs: allClients,
+		},
+		{
+			Name:      "package version lower than",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"package"}, Values: []string{"openssl"}},
+				{Column: []string{"package_version"}, Operator: query.FilterOperatorTypeLT, Values: []string{"3.0"}},
+			},
+			ExpectedIDs: []string{"client-1", "client-3"},
+		},
+		{
+			Name:      "package version wildcard",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"package_version"}, Values: []string{"*ubuntu*"}},
+			},
+			ExpectedIDs: []string{"client-1", "client-2"},
+		},
+		{
+			Name:      "only given clients",
+			ClientIDs: []string{"client-2", "client-3"},
+			Filters: []query.FilterOption{
+				{Column: []string{"package"}, Values: []string{"openssl"}},
+			},
+			ExpectedIDs: []string{"client-2", "client-3"},
+		},
+		{
+			Name:      "process",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"process"}, Values: []string{"NGINX", "httpd"}},
+			},
+			ExpectedIDs: []string{"client-1"},
+		},
+		{
+			Name:      "process name and cmdline of the same process",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"process"}, Values: []string{"systemd"}},
+				{Column: []string{"process_cmdline"}, Values: []string{"*nginx*"}},
+			},
+			ExpectedIDs: []string{},
+		},
+		{
+			Name:      "mountpoint used percent",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"mountpoint"}, Values: []string{"/data"}},
+				{Column: []string{"mountpoint_used_percent"}, Operator: query.FilterOperatorTypeGT, Values: []string{"90"}},
+			},
+			ExpectedIDs: []string{"client-1", "client-2"},
+		},
+		{
+			Name:      "package and mountpoint",
+			ClientIDs: allClients,
+			Filters: []query.FilterOption{
+				{Column: []string{"package_version"}, Operator: query.FilterOperatorTypeGT, Values: []string{"3"}},
+				{Column: []string{"mountpoint_used_percent"}, Operator: query.FilterOperatorTypeGT, Values: []string{"90"}},
+			},
+			ExpectedIDs: []string{"client-2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ids, err := searcher.Search(context.Background(), tc.ClientIDs, tc.Filters)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedIDs, ids)
+		})
+	}
+
+	_, err := searcher.Search(context.Background(), allClients, []query.FilterOption{
+		{Column: []string{"package"}, Values: []string{"openssl*"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []query.FilterOption{
+		{Column: []string{"category"}, Values: []string{models.InventoryCategoryPackage}},
+		{Column: []string{"name"}, Values: []string{"openssl*"}},
+	}, inventoryProvider.options.Filters)
+}
+
+func TestSearchInvalidFilters(t *testing.T) {
+	searcher := NewSearcher(&mockInventoryProvider{}, &mockMeasurementProvider{})
+
+	testCases := []struct {
+		Name          string
+		Filter        query.FilterOption
+		ExpectedError string
+	}{
+		{
+			Name:          "combined columns",
+			Filter:        query.FilterOption{Column: []string{"process", "package"}, Values: []string{"nginx"}},
+			ExpectedError: "filter filter[process|package] must not combine columns",
+		},
+		{
+			Name:          "and values",
+			Filter:        query.FilterOption{Column: []string{"package"}, Values: []string{"nginx", "openssl"}, ValuesLogicalOperator: query.FilterLogicalOperatorTypeAND},
+			ExpectedError: "filter filter[package] does not support and(...) values",
+		},
+		{
+			Name:          "not a number",
+			Filter:        query.FilterOption{Column: []string{"mountpoint_used_percent"}, Operator: query.FilterOperatorTypeGT, Values: []string{"full"}},
+			ExpectedError: `filter filter[mountpoint_used_percent][gt] expects a number, got "full"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := searcher.Search(context.Background(), nil, []query.FilterOption{tc.Filter})
+			assert.Equal(t, errors.APIError{Message: tc.ExpectedError, HTTPStatus: http.StatusBadRequest}, err)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		A        string
+		B        string
+		Expected int
+	}{
+		{A: "1.1.1f", B: "1.1.1n", Expected: -1},
+		{A: "3.0.2-0ubuntu1.10", B: "3.0", Expected: 1},
+		{A: "3.0", B: "3.0.0", Expected: -1},
+		{A: "3.0.10", B: "3.0.9", Expected: 1},
+		{A: "1.24.0", B: "1.24.0", Expected: 0},
+		{A: "1:1.0.2k-26.el7_9", B: "3.0", Expected: -1},
+		{A: "1:1.0.2k", B: "2:0.1", Expected: -1},
+		{A: "1.0a", B: "1.0.1", Expected: -1},
+		{A: "20230101", B: "020230101", Expected: 0},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.Expected, CompareVersions(tc.A, tc.B), "%s <=> %s", tc.A, tc.B)
+		assert.Equal(t, -tc.Expected, CompareVersions(tc.B, tc.A), "%s <=> %s", tc.B, tc.A)
+	}
+}
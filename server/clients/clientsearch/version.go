@@ -0,0 +1,103 @@
+package clientsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CompareVersions compares package versions like dpkg and rpm do, segment by segment.
+// Numeric segments are compared by value, others alphabetically and a numeric segment is newer than a non-numeric one,
+// e.g. 1.1.1f < 1.1.1n < 3.0 < 3.0.2-0ubuntu1.10.
+// An epoch like "1:" is only compared if the other version has one, so "3.0" can be used to compare "1:3.0.2".
+func CompareVersions(a, b string) int {
+	epochA, restA, hasEpochA := splitEpoch(a)
+	epochB, restB, hasEpochB := splitEpoch(b)
+	if hasEpochA && hasEpochB {
+		if c := compareSegments(epochA, epochB); c != 0 {
+			return c
+		}
+	}
+	segmentsA := splitSegments(restA)
+	segmentsB := splitSegments(restB)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if c := compareSegments(segmentsA[i], segmentsB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(segmentsA) < len(segmentsB):
+		return -1
+	case len(segmentsA) > len(segmentsB):
+		return 1
+	}
+	return 0
+}
+
+func splitEpoch(v string) (string, string, bool) {
+	i := strings.Index(v, ":")
+	if i < 0 || !isNumeric(v[:i]) {
+		return "", v, false
+	}
+	return v[:i], v[i+1:], true
+}
+
+// splitSegments splits a version into runs of digits and runs of letters, other characters are separators
+func splitSegments(v string) []string {
+	var segments []string
+	start := -1
+	startDigit := false
+	for i, r := range v {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				segments = append(segments, v[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsDigit(r) != startDigit {
+			segments = append(segments, v[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start = i
+			startDigit = unicode.IsDigit(r)
+		}
+	}
+	if start >= 0 {
+		segments = append(segments, v[start:])
+	}
+	return segments
+}
+
+func compareSegments(a, b string) int {
+	numericA, numericB := isNumeric(a), isNumeric(b)
+	switch {
+	case numericA && numericB:
+		// compare by length first to not overflow on long numbers like dates
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case numericA:
+		return 1
+	case numericB:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package clienttunnel
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 as defined in RFC 1928 with the username/password authentication of RFC 1929, only CONNECT is supported
+const (
+	socks5Version         = 0x05
+	socks5PasswordVersion = 0x01
+
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthNoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5RepSucceeded               = 0x00
+	socks5RepGeneralFailure          = 0x01
+	socks5RepNotAllowed              = 0x02
+	socks5RepCommandNotSupported     = 0x07
+	socks5RepAddressTypeNotSupported = 0x08
+)
+
+var errSOCKS5AuthFailed = errors.New("socks5 authentication failed")
+
+type socks5Credentials struct {
+	user     string
+	password string
+}
+
+// socks5Handshake authenticates the client and returns the requested destination in host:port format.
+// Failures are replied to the client already.
+func socks5Handshake(rw io.ReadWriter, credentials *socks5Credentials) (string, error) {
+	err := socks5Authenticate(rw, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	// VER CMD RSV ATYP
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return "", err
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	if header[1] != socks5CmdConnect {
+		_ = socks5Reply(rw, socks5RepCommandNotSupported)
+		return "", fmt.Errorf("unsupported socks5 command %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if header[3] == socks5AddrIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(rw, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		domain, err := readSOCKS5String(rw)
+		if err != nil {
+			return "", err
+		}
+		host = domain
+	default:
+		_ = socks5Reply(rw, socks5RepAddressTypeNotSupported)
+		return "", fmt.Errorf("unsupported socks5 address type %d", header[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(rw, port); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+func socks5Authenticate(rw io.ReadWriter, credentials *socks5Credentials) error {
+	// VER NMETHODS METHODS
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return err
+	}
+
+	wantMethod := byte(socks5AuthNone)
+	if credentials != nil {
+		wantMethod = socks5AuthPassword
+	}
+
+	method := byte(socks5AuthNoAcceptable)
+	for _, m := range methods {
+		if m == wantMethod {
+			method = m
+			break
+		}
+	}
+	if _, err := rw.Write([]byte{socks5Version, method}); err != nil {
+		return err
+	}
+	if method == socks5AuthNoAcceptable {
+		return errors.New("no acceptable socks5 authentication method")
+	}
+	if method == socks5AuthNone {
+		return nil
+	}
+
+	// VER ULEN UNAME PLEN PASSWD
+	version := make([]byte, 1)
+	if _, err := io.ReadFull(rw, version); err != nil {
+		return err
+	}
+	user, err := readSOCKS5String(rw)
+	if err != nil {
+		return err
+	}
+	password, err := readSOCKS5String(rw)
+	if err != nil {
+		return err
+	}
+
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(credentials.user))
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(credentials.password))
+	if version[0] != socks5PasswordVersion || userMatch&passwordMatch != 1 {
+		_, _ = rw.Write([]byte{socks5PasswordVersion, 0x01})
+		return errSOCKS5AuthFailed
+	}
+
+	_, err = rw.Write([]byte{socks5PasswordVersion, 0x00})
+	return err
+}
+
+func readSOCKS5String(r io.Reader) (string, error) {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(r, length); err != nil {
+		return "", err
+	}
+	value := make([]byte, length[0])
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// socks5Reply sends the reply to a request, the bound address is not known to the server so it is always 0.0.0.0:0
+func socks5Reply(w io.Writer, rep byte) error {
+	_, err := w.Write([]byte{socks5Version, rep, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
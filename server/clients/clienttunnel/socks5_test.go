@@ -0,0 +1,117 @@
+package clienttunnel
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSOCKS5Handshake(t *testing.T) {
+	testCases := []struct {
+		name            string
+		credentials     *socks5Credentials
+		request         []byte
+		wantResponse    []byte
+		wantDestination string
+		wantErr         string
+	}{
+		{
+			name: "no auth, ipv4",
+			request: []byte{
+				0x05, 0x01, 0x00,
+				0x05, 0x01, 0x00, 0x01, 192, 168, 1, 10, 0x0d, 0x3d,
+			},
+			wantResponse:    []byte{0x05, 0x00},
+			wantDestination: "192.168.1.10:3389",
+		},
+		{
+			name: "no auth, domain",
+			request: []byte{
+				0x05, 0x02, 0x00, 0x02,
+				0x05, 0x01, 0x00, 0x03, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', 0x00, 0x50,
+			},
+			wantResponse:    []byte{0x05, 0x00},
+			wantDestination: "example.com:80",
+		},
+		{
+			name: "no auth, ipv6",
+			request: []byte{
+				0x05, 0x01, 0x00,
+				0x05, 0x01, 0x00, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x00, 0x16,
+			},
+			wantResponse:    []byte{0x05, 0x00},
+			wantDestination: "[::1]:22",
+		},
+		{
+			name:        "password auth",
+			credentials: &socks5Credentials{user: "admin", password: "foobaz"},
+			request: []byte{
+				0x05, 0x02, 0x00, 0x02,
+				0x01, 0x05, 'a', 'd', 'm', 'i', 'n', 0x06, 'f', 'o', 'o', 'b', 'a', 'z',
+				0x05, 0x01, 0x00, 0x01, 10, 0, 0, 1, 0x00, 0x16,
+			},
+			wantResponse:    []byte{0x05, 0x02, 0x01, 0x00},
+			wantDestination: "10.0.0.1:22",
+		},
+		{
+			name:        "wrong password",
+			credentials: &socks5Credentials{user: "admin", password: "foobaz"},
+			request: []byte{
+				0x05, 0x01, 0x02,
+				0x01, 0x05, 'a', 'd', 'm', 'i', 'n', 0x03, 'f', 'o', 'o',
+			},
+			wantResponse: []byte{0x05, 0x02, 0x01, 0x01},
+			wantErr:      errSOCKS5AuthFailed.Error(),
+		},
+		{
+			name:        "password required",
+			credentials: &socks5Credentials{user: "admin", password: "foobaz"},
+			request: []byte{
+				0x05, 0x01, 0x00,
+			},
+			wantResponse: []byte{0x05, 0xff},
+			wantErr:      "no acceptable socks5 authentication method",
+		},
+		{
+			name: "bind not supported",
+			request: []byte{
+				0x05, 0x01, 0x00,
+				0x05, 0x02, 0x00, 0x01, 10, 0, 0, 1, 0x00, 0x16,
+			},
+			wantResponse: []byte{0x05, 0x00, 0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0},
+			wantErr:      "unsupported socks5 command 2",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+
+			go func() {
+				_, _ = client.Write(tc.request)
+			}()
+
+			response := make(chan []byte)
+			go func() {
+				buf, _ := io.ReadAll(client)
+				response <- buf
+			}()
+
+			destination, err := socks5Handshake(server, tc.credentials)
+			server.Close()
+
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantDestination, destination)
+			}
+			assert.Equal(t, tc.wantResponse, <-response)
+		})
+	}
+}
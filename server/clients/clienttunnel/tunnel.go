@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 
+	"github.com/riportdev/riport/server/tunnellog"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/models"
 )
@@ -72,33 +73,104 @@ type Tunnel struct {
 	TunnelProtocol      `json:"-"`
 	InternalTunnelProxy *InternalTunnelProxy `json:"-"`
 	CreatedAt           time.Time            `json:"created_at"`
+	// Mode is direct if the client accepts connections on DirectAddresses, the relay is available in both modes
+	Mode            string   `json:"mode,omitempty"`
+	DirectAddresses []string `json:"direct_addresses,omitempty"`
+	// Traffic counts the bytes relayed by the server, direct connections are not counted
+	Traffic *TunnelTraffic `json:"-"`
 }
 
-func NewTunnel(logger *logger.Logger, ssh ssh.Conn, id string, remote models.Remote, acl *TunnelACL) (*Tunnel, error) {
+func NewTunnel(logger *logger.Logger, ssh ssh.Conn, id string, remote models.Remote, acl *TunnelACL, connLog *tunnellog.TunnelLog) (*Tunnel, error) {
 	logger = logger.Fork("tunnel#%s:%s", id, remote)
 	logger.Debugf("new tunnel with remote = %#v", remote)
 
+	traffic := newTunnelTraffic(remote)
 	var tunnelProtocol TunnelProtocol
 	switch remote.Protocol {
 	case models.ProtocolUDP:
-		tunnelProtocol = newTunnelUDP(logger, ssh, remote, acl)
+		tunnelProtocol = newTunnelUDP(logger, ssh, remote, acl, traffic, connLog)
 	case models.ProtocolTCP:
-		tunnelProtocol = newTunnelTCP(logger, ssh, remote, acl)
+		tunnelProtocol = newTunnelTCP(logger, ssh, remote, acl, traffic, connLog)
+	case models.ProtocolSOCKS5:
+		tunnelProtocol = newTunnelSOCKS5(logger, ssh, remote, acl, traffic, connLog)
 	case models.ProtocolTCPUDP:
 		tunnelProtocol = &MultiProtocolTunnel{
 			Protocols: []TunnelProtocol{
-				newTunnelTCP(logger, ssh, remote, acl),
-				newTunnelUDP(logger, ssh, remote, acl),
+				newTunnelTCP(logger, ssh, remote, acl, traffic, connLog),
+				newTunnelUDP(logger, ssh, remote, acl, traffic, connLog),
 			},
 		}
 	default:
 		return nil, errors.Errorf("unsupported protocol %q", remote.Protocol)
 	}
 
+	if remote.Direct {
+		tunnelProtocol = newDirectTunnel(logger, ssh, id, remote, tunnelProtocol)
+	}
+
 	return &Tunnel{
 		Remote:         remote,
 		ID:             id,
 		TunnelProtocol: tunnelProtocol,
 		CreatedAt:      time.Now(),
+		Mode:           TunnelModeRelayed,
+		Traffic:        traffic,
 	}, nil
 }
+
+func (t *Tunnel) Start(ctx context.Context) error {
+	err := t.TunnelProtocol.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dt, ok := t.TunnelProtocol.(*directTunnel); ok {
+		if addresses := dt.Addresses(); len(addresses) > 0 {
+			t.Mode = TunnelModeDirect
+			t.DirectAddresses = addresses
+		}
+	}
+	return nil
+}
+
+// LastActive considers the requests of the tunnel proxy as well, routed requests don't pass the tunnel listener
+func (t *Tunnel) LastActive() time.Time {
+	lastActive := t.TunnelProtocol.LastActive()
+	if t.InternalTunnelProxy != nil {
+		if proxyLastActive := t.InternalTunnelProxy.LastActive(); proxyLastActive.After(lastActive) {
+			return proxyLastActive
+		}
+	}
+	return lastActive
+}
+
+// Connections returns the active tcp and socks5 connections relayed by the server, nil for udp tunnels
+func (t *Tunnel) Connections() []TunnelConnection {
+	return connectionsOf(t.TunnelProtocol)
+}
+
+func connectionsOf(tp TunnelProtocol) []TunnelConnection {
+	switch p := tp.(type) {
+	case *tunnelTCP:
+		return p.Connections()
+	case *tunnelSOCKS5:
+		return p.Connections()
+	case *directTunnel:
+		return connectionsOf(p.TunnelProtocol)
+	case *MultiProtocolTunnel:
+		var result []TunnelConnection
+		for _, mp := range p.Protocols {
+			result = append(result, connectionsOf(mp)...)
+		}
+		return result
+	}
+	return nil
+}
+
+// TrafficStats returns the bytes transferred since the tunnel was started
+func (t *Tunnel) TrafficStats() TrafficStats {
+	if t.Traffic == nil {
+		return TrafficStats{}
+	}
+	return t.Traffic.Stats()
+}
@@ -0,0 +1,66 @@
+package clienttunnel
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/riportdev/riport/server/tunnellog"
+)
+
+// logConnection stores a closed connection, the reason is replaced if the tunnel was closed or ran out of quota
+func logConnection(ctx context.Context, connLog *tunnellog.TunnelLog, traffic *TunnelTraffic, protocol string, conn *TunnelConnection, reason string) {
+	if connLog == nil {
+		return
+	}
+
+	if traffic.isQuotaExceeded() {
+		reason = tunnellog.CloseReasonQuotaExceeded
+	} else if ctx.Err() != nil {
+		reason = tunnellog.CloseReasonTunnelClosed
+	}
+
+	ip, port := splitSource(conn.Source)
+	connLog.Save(&tunnellog.Record{
+		Protocol:    protocol,
+		SourceIP:    ip,
+		SourcePort:  port,
+		Destination: conn.Destination,
+		Status:      tunnellog.StatusAccepted,
+		StartedAt:   conn.StartedAt,
+		EndedAt:     time.Now(),
+		BytesIn:     conn.BytesIn,
+		BytesOut:    conn.BytesOut,
+		CloseReason: reason,
+	})
+}
+
+// logRejectedConnection stores a connection which was closed right after it was accepted
+func logRejectedConnection(connLog *tunnellog.TunnelLog, protocol, source, destination, reason string) {
+	if connLog == nil {
+		return
+	}
+
+	now := time.Now()
+	ip, port := splitSource(source)
+	connLog.Save(&tunnellog.Record{
+		Protocol:    protocol,
+		SourceIP:    ip,
+		SourcePort:  port,
+		Destination: destination,
+		Status:      tunnellog.StatusRejected,
+		StartedAt:   now,
+		EndedAt:     now,
+		CloseReason: reason,
+	})
+}
+
+func splitSource(source string) (string, int) {
+	host, portStr, err := net.SplitHostPort(source)
+	if err != nil {
+		return source, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
@@ -0,0 +1,112 @@
+package clienttunnel
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/server/tunnellog"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+type connLogProviderMock struct {
+	mtx     sync.Mutex
+	records []*tunnellog.Record
+}
+
+func (p *connLogProviderMock) Save(ctx context.Context, r *tunnellog.Record) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.records = append(p.records, r)
+	return nil
+}
+
+func (p *connLogProviderMock) List(ctx context.Context, options *query.ListOptions) ([]*tunnellog.Record, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return append([]*tunnellog.Record(nil), p.records...), nil
+}
+
+func (p *connLogProviderMock) Count(ctx context.Context, options *query.ListOptions) (int, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.records), nil
+}
+
+func (p *connLogProviderMock) DeleteEndedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (p *connLogProviderMock) Close() error {
+	return nil
+}
+
+func (p *connLogProviderMock) Records() []*tunnellog.Record {
+	records, _ := p.List(context.Background(), nil)
+	return records
+}
+
+func newTestConnLog() (*connLogProviderMock, *tunnellog.TunnelLog) {
+	provider := &connLogProviderMock{}
+	log := tunnellog.New(logger.NewLogger("tunnellog-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug), provider)
+	return provider, log.ForTunnel("client-1", "1")
+}
+
+func TestTunnelTCPConnectionLog(t *testing.T) {
+	provider, connLog := newTestConnLog()
+	tunnel, _ := startTestTCPTunnelWithLog(t, models.Remote{Protocol: models.ProtocolTCP, RemoteHost: "127.0.0.1", RemotePort: "22"}, nil, connLog)
+
+	conn, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = conn.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.Eventually(t, func() bool {
+		return len(provider.Records()) == 1
+	}, time.Second, 10*time.Millisecond)
+	r := provider.Records()[0]
+	assert.Equal(t, "client-1", r.ClientID)
+	assert.Equal(t, "1", r.TunnelID)
+	assert.Equal(t, models.ProtocolTCP, r.Protocol)
+	assert.Equal(t, "127.0.0.1", r.SourceIP)
+	assert.Equal(t, conn.LocalAddr().(*net.TCPAddr).Port, r.SourcePort)
+	assert.Equal(t, "127.0.0.1:22", r.Destination)
+	assert.Equal(t, tunnellog.StatusAccepted, r.Status)
+	assert.Equal(t, int64(5), r.BytesIn)
+	assert.Equal(t, int64(5), r.BytesOut)
+	assert.Equal(t, tunnellog.CloseReasonClosed, r.CloseReason)
+	assert.False(t, r.EndedAt.Before(r.StartedAt))
+}
+
+func TestTunnelTCPConnectionLogRejected(t *testing.T) {
+	acl, err := ParseTunnelACL("192.0.2.1")
+	require.NoError(t, err)
+	provider, connLog := newTestConnLog()
+	tunnel, _ := startTestTCPTunnelWithLog(t, models.Remote{Protocol: models.ProtocolTCP, RemoteHost: "127.0.0.1", RemotePort: "22"}, acl, connLog)
+
+	conn, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		return len(provider.Records()) == 1
+	}, time.Second, 10*time.Millisecond)
+	r := provider.Records()[0]
+	assert.Equal(t, tunnellog.StatusRejected, r.Status)
+	assert.Equal(t, "127.0.0.1", r.SourceIP)
+	assert.Equal(t, tunnellog.CloseReasonACL, r.CloseReason)
+	assert.Equal(t, int64(0), r.BytesIn)
+	assert.Equal(t, int64(0), r.BytesOut)
+}
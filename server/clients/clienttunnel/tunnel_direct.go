@@ -0,0 +1,185 @@
+package clienttunnel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+const (
+	TunnelModeRelayed = "relayed"
+	TunnelModeDirect  = "direct"
+)
+
+// directTunnel asks the client to accept connections directly, the server only exchanges the addresses.
+// The relayed tunnel keeps running as a fallback for users who can't reach the client.
+type directTunnel struct {
+	TunnelProtocol
+	*logger.Logger
+	sshConn ssh.Conn
+	id      string
+	remote  models.Remote
+
+	mu        sync.Mutex
+	addresses []string
+	stopOnce  sync.Once
+}
+
+func newDirectTunnel(logger *logger.Logger, ssh ssh.Conn, id string, remote models.Remote, relay TunnelProtocol) *directTunnel {
+	return &directTunnel{
+		TunnelProtocol: relay,
+		Logger:         logger,
+		sshConn:        ssh,
+		id:             id,
+		remote:         remote,
+	}
+}
+
+func (t *directTunnel) Start(ctx context.Context) error {
+	err := t.TunnelProtocol.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if t.sshConn == nil {
+		return nil
+	}
+
+	var acl *TunnelACL
+	if t.remote.ACL != nil {
+		acl, _ = ParseTunnelACL(*t.remote.ACL)
+	}
+	addresses, err := t.sendStartRequest(acl)
+	if err != nil {
+		t.Infof("direct connections not possible, tunnel is relayed: %v", err)
+		return nil
+	}
+
+	t.mu.Lock()
+	t.addresses = t.withPublicAddress(addresses)
+	t.mu.Unlock()
+	t.Infof("client accepts direct connections on %v", t.addresses)
+
+	// the listener on the client is closed on auto close and when the client disconnects
+	go func() {
+		<-ctx.Done()
+		t.stopDirect()
+	}()
+
+	return nil
+}
+
+func (t *directTunnel) Terminate(force bool) error {
+	err := t.TunnelProtocol.Terminate(force)
+	if err != nil {
+		return err
+	}
+
+	t.stopDirect()
+	return nil
+}
+
+// LastActive considers the direct connections reported by the client as well
+func (t *directTunnel) LastActive() time.Time {
+	lastActive := t.TunnelProtocol.LastActive()
+	if len(t.Addresses()) == 0 {
+		return lastActive
+	}
+
+	resp := &comm.DirectTunnelStatusResponse{}
+	err := comm.SendRequestAndGetResponse(t.sshConn, comm.RequestTypeDirectTunnelStatus, &comm.DirectTunnelRequest{ID: t.id}, resp, t.Logger)
+	if err != nil {
+		t.Errorf("failed to get direct tunnel status: %v", err)
+		return lastActive
+	}
+
+	if resp.LastActive.After(lastActive) {
+		return resp.LastActive
+	}
+	return lastActive
+}
+
+func (t *directTunnel) SetACL(acl *TunnelACL) {
+	t.TunnelProtocol.SetACL(acl)
+	if len(t.Addresses()) == 0 {
+		return
+	}
+
+	// the client updates the ACL of an existing direct tunnel
+	_, err := t.sendStartRequest(acl)
+	if err != nil {
+		t.Errorf("failed to update ACL of direct tunnel: %v", err)
+	}
+}
+
+// Addresses returns the addresses the client accepts direct connections on, empty if the tunnel is relayed only
+func (t *directTunnel) Addresses() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.addresses
+}
+
+func (t *directTunnel) sendStartRequest(acl *TunnelACL) ([]string, error) {
+	req := &comm.StartDirectTunnelRequest{
+		ID:     t.id,
+		Remote: t.remote.Remote(),
+	}
+	if acl != nil {
+		for _, ipNet := range acl.AllowedIPs {
+			req.AllowedIPs = append(req.AllowedIPs, ipNet.String())
+		}
+	}
+
+	resp := &comm.StartDirectTunnelResponse{}
+	err := comm.SendRequestAndGetResponse(t.sshConn, comm.RequestTypeStartDirectTunnel, req, resp, t.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Addresses, nil
+}
+
+func (t *directTunnel) stopDirect() {
+	if len(t.Addresses()) == 0 {
+		return
+	}
+
+	t.stopOnce.Do(func() {
+		err := comm.SendRequestAndGetResponse(t.sshConn, comm.RequestTypeStopDirectTunnel, &comm.DirectTunnelRequest{ID: t.id}, nil, t.Logger)
+		if err != nil {
+			t.Debugf("failed to stop direct tunnel: %v", err)
+		}
+	})
+}
+
+// withPublicAddress adds the address the client connects from, it is reachable if the client has a public IP or the port is forwarded
+func (t *directTunnel) withPublicAddress(addresses []string) []string {
+	if len(addresses) == 0 {
+		return addresses
+	}
+
+	_, port, err := net.SplitHostPort(addresses[0])
+	if err != nil {
+		return addresses
+	}
+	tcpAddr, ok := t.sshConn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return addresses
+	}
+
+	publicAddress := net.JoinHostPort(tcpAddr.IP.String(), port)
+	for _, address := range addresses {
+		if address == publicAddress {
+			return addresses
+		}
+	}
+	return append(addresses, publicAddress)
+}
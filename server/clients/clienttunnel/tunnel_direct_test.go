@@ -0,0 +1,99 @@
+package clienttunnel
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/share/comm"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/test"
+)
+
+func TestDirectTunnel(t *testing.T) {
+	testCases := []struct {
+		name                string
+		clientOk            bool
+		clientResp          *comm.StartDirectTunnelResponse
+		wantMode            string
+		wantDirectAddresses []string
+	}{
+		{
+			name:     "client accepts direct connections",
+			clientOk: true,
+			clientResp: &comm.StartDirectTunnelResponse{
+				Addresses: []string{"192.168.1.10:40123"},
+			},
+			wantMode:            TunnelModeDirect,
+			wantDirectAddresses: []string{"192.168.1.10:40123", "203.0.113.5:40123"},
+		},
+		{
+			name:     "client behind public address",
+			clientOk: true,
+			clientResp: &comm.StartDirectTunnelResponse{
+				Addresses: []string{"203.0.113.5:40123"},
+			},
+			wantMode:            TunnelModeDirect,
+			wantDirectAddresses: []string{"203.0.113.5:40123"},
+		},
+		{
+			name:     "direct tunnels disabled on client",
+			clientOk: false,
+			wantMode: TunnelModeRelayed,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			connMock := test.NewConnMock()
+			connMock.ReturnOk = tc.clientOk
+			connMock.ReturnRemoteAddr = &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 51234}
+			if tc.clientResp != nil {
+				resp, err := json.Marshal(tc.clientResp)
+				require.NoError(t, err)
+				connMock.ReturnResponsePayload = resp
+			}
+
+			acl := "198.51.100.0/24"
+			remote := models.Remote{
+				Protocol:   models.ProtocolTCP,
+				LocalHost:  "127.0.0.1",
+				LocalPort:  "0",
+				RemoteHost: "127.0.0.1",
+				RemotePort: "3389",
+				ACL:        &acl,
+				Direct:     true,
+			}
+			log := logger.NewLogger("direct-tunnel-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+			tunnel, err := NewTunnel(log, connMock, "1", remote, nil, nil)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, tunnel.Start(ctx))
+
+			name, _, payload := connMock.InputSendRequest()
+			assert.Equal(t, comm.RequestTypeStartDirectTunnel, name)
+			startReq := &comm.StartDirectTunnelRequest{}
+			require.NoError(t, json.Unmarshal(payload, startReq))
+			assert.Equal(t, &comm.StartDirectTunnelRequest{ID: "1", Remote: "127.0.0.1:3389", AllowedIPs: []string{"198.51.100.0/24"}}, startReq)
+
+			assert.Equal(t, tc.wantMode, tunnel.Mode)
+			assert.Equal(t, tc.wantDirectAddresses, tunnel.DirectAddresses)
+
+			require.NoError(t, tunnel.Terminate(true))
+			if tc.wantMode == TunnelModeDirect {
+				name, _, payload = connMock.InputSendRequest()
+				assert.Equal(t, comm.RequestTypeStopDirectTunnel, name)
+				assert.JSONEq(t, `{"ID":"1"}`, string(payload))
+			}
+		})
+	}
+}
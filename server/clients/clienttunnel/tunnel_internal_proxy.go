@@ -10,16 +10,19 @@ import (
 	"html/template"
 	"net"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/riportdev/riport/server/acme"
 	chshare "github.com/riportdev/riport/share"
 	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
 	"github.com/riportdev/riport/share/security"
 )
 
@@ -104,6 +107,9 @@ func (c *InternalTunnelProxyConfig) validateHost() error {
 }
 
 type InternalTunnelProxy struct {
+	// Declare 64-bit integers before the other fields for alignment when compiling Go on 32-bit ARM platforms
+	lastRequestEnd       int64 // time stored as int64 so it can be used with atomic
+	activeRequests       int64
 	Tunnel               *Tunnel
 	Logger               *logger.Logger
 	Config               *InternalTunnelProxyConfig
@@ -115,9 +121,14 @@ type InternalTunnelProxy struct {
 	proxyServer          *http.Server
 	tunnelProxyConnector TunnelProxyConnector
 	acme                 *acme.Acme
+	sshConn              ssh.Conn
+	accessLogger         *logger.Logger
+	// ClientID and SessionAuth are required by tunnels with session auth mode
+	ClientID    string
+	SessionAuth *TunnelSessionAuth
 }
 
-func NewInternalTunnelProxy(tunnel *Tunnel, logger *logger.Logger, config *InternalTunnelProxyConfig, host string, port string, acl *TunnelACL, acme *acme.Acme) *InternalTunnelProxy {
+func NewInternalTunnelProxy(tunnel *Tunnel, logger *logger.Logger, sshConn ssh.Conn, config *InternalTunnelProxyConfig, host string, port string, acl *TunnelACL, acme *acme.Acme) *InternalTunnelProxy {
 	tp := &InternalTunnelProxy{
 		Tunnel:     tunnel,
 		Config:     config,
@@ -126,9 +137,11 @@ func NewInternalTunnelProxy(tunnel *Tunnel, logger *logger.Logger, config *Inter
 		TunnelHost: tunnel.Remote.LocalHost,
 		TunnelPort: tunnel.Remote.LocalPort,
 		acme:       acme,
+		sshConn:    sshConn,
 	}
 	tp.SetACL(acl)
 	tp.Logger = logger.Fork("tunnel-proxy:%s", tp.Addr())
+	tp.accessLogger = tp.Logger.Fork("access")
 	tp.tunnelProxyConnector = NewTunnelProxyConnector(tp)
 	return tp
 }
@@ -137,6 +150,15 @@ func (tp *InternalTunnelProxy) Start(ctx context.Context) error {
 	router := mux.NewRouter()
 	router.Use(tp.handleACL)
 
+	if tp.Tunnel.Remote.AuthMode == models.TunnelAuthModeSession {
+		if tp.SessionAuth == nil {
+			return errors.New("tunnel session auth not available")
+		}
+		router.Handle(TunnelSessionAuthPath, http.HandlerFunc(tp.handleSessionAuthToken))
+		router.Use(tp.handleSessionAuth)
+	}
+	router.Use(tp.removeSessionCookies)
+
 	router.Handle("/css/tunnel-proxy.css", http.FileServer(http.FS(tunnelProxyCSS)))
 	router.Handle("/css/semantic.css", http.FileServer(http.FS(semanticCSS)))
 
@@ -233,6 +255,89 @@ func (tp *InternalTunnelProxy) handleACL(next http.Handler) http.Handler {
 	})
 }
 
+// sessionCookieName includes the port, because browsers don't separate cookies of the same host by port
+func (tp *InternalTunnelProxy) sessionCookieName() string {
+	return tunnelSessionCookiePrefix + tp.Port
+}
+
+// handleSessionAuthToken validates a token issued by the API and stores it as session cookie
+func (tp *InternalTunnelProxy) handleSessionAuthToken(w http.ResponseWriter, r *http.Request) {
+	claims, err := tp.SessionAuth.validate(r.Context(), r.URL.Query().Get("token"), tunnelSessionAudienceToken, tp.ClientID, tp.Tunnel.ID)
+	if err != nil {
+		tp.Logger.Infof("Proxy Access rejected. Invalid tunnel token from %s: %v", chshare.RemoteIP(r), err)
+		tp.sendHTML(w, http.StatusUnauthorized, "Invalid or expired token, please open the tunnel from riport again")
+		return
+	}
+
+	value, expiresAt, err := tp.SessionAuth.newCookieValue(claims)
+	if err != nil {
+		tp.handleProxyError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     tp.sessionCookieName(),
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	tp.Logger.Infof("tunnel session of user %s started from %s", claims.Username, chshare.RemoteIP(r))
+
+	// only redirect within the tunnel
+	redirect := r.URL.Query().Get("path")
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		redirect = "/"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handleSessionAuth middleware requires a session cookie of a user still allowed to use the tunnel
+func (tp *InternalTunnelProxy) handleSessionAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == TunnelSessionAuthPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(tp.sessionCookieName())
+		if err != nil {
+			tp.sendHTML(w, http.StatusUnauthorized, "A riport session is required, please open the tunnel from riport")
+			return
+		}
+		claims, err := tp.SessionAuth.validate(r.Context(), cookie.Value, tunnelSessionAudienceCookie, tp.ClientID, tp.Tunnel.ID)
+		if err != nil {
+			tp.Logger.Infof("Proxy Access rejected. Invalid tunnel session from %s: %v", chshare.RemoteIP(r), err)
+			tp.sendHTML(w, http.StatusUnauthorized, "The riport session is not valid anymore, please open the tunnel from riport again")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionUserCtxKey{}, claims.Username)))
+	})
+}
+
+// removeSessionCookies middleware keeps the session cookies of all tunnels from reaching the remote. Browsers send the
+// cookies of the tunnels on other ports of the same host as well, so every tunnel proxy removes them.
+func (tp *InternalTunnelProxy) removeSessionCookies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookies := r.Cookies()
+		kept := make([]*http.Cookie, 0, len(cookies))
+		for _, c := range cookies {
+			if !strings.HasPrefix(c.Name, tunnelSessionCookiePrefix) {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) < len(cookies) {
+			r.Header.Del("Cookie")
+			for _, c := range kept {
+				r.AddCookie(c)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (tp *InternalTunnelProxy) serveTemplate(w http.ResponseWriter, r *http.Request, templateContent string, templateData map[string]interface{}) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -269,3 +374,33 @@ func (tp *InternalTunnelProxy) noCache(next http.Handler) http.Handler {
 func (tp *InternalTunnelProxy) SetACL(acl *TunnelACL) {
 	tp.acl.Store(acl)
 }
+
+// LastActive returns the end of the last request or now if requests are in progress
+func (tp *InternalTunnelProxy) LastActive() time.Time {
+	if atomic.LoadInt64(&tp.activeRequests) > 0 {
+		return time.Now()
+	}
+	return time.Unix(atomic.LoadInt64(&tp.lastRequestEnd), 0)
+}
+
+func (tp *InternalTunnelProxy) requestStarted() (done func()) {
+	atomic.AddInt64(&tp.activeRequests, 1)
+	return func() {
+		atomic.StoreInt64(&tp.lastRequestEnd, time.Now().Unix())
+		atomic.AddInt64(&tp.activeRequests, -1)
+	}
+}
+
+// dialClient opens a connection to the target on the client network, the client checks the target against tunnel_allowed
+func (tp *InternalTunnelProxy) dialClient(target string) (net.Conn, error) {
+	if tp.sshConn == nil {
+		return nil, errors.New("no remote connection")
+	}
+	dst, reqs, err := tp.sshConn.OpenChannel("riport", []byte(target))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %v", target, err)
+	}
+	go ssh.DiscardRequests(reqs)
+
+	return chshare.NewRWCConn(dst), nil
+}
@@ -1,12 +1,22 @@
 package clienttunnel
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/models"
 )
 
 // TunnelProxyConnectorHTTP uses the standard ReverseProxy from package httputil to connect to HTTP/HTTPS server on tunnel endpoint
@@ -20,15 +30,27 @@ func NewTunnelConnectorHTTP(tp *InternalTunnelProxy) *TunnelProxyConnectorHTTP {
 }
 
 func (tc *TunnelProxyConnectorHTTP) InitRouter(router *mux.Router) *mux.Router {
-	router.PathPrefix("/").HandlerFunc(tc.serveHTTP)
+	router.Use(tc.logAccess)
+
+	// mux matches in the order of registration, so the most specific prefix must come first
+	routes := make([]models.HTTPRoute, len(tc.tunnelProxy.Tunnel.Remote.HTTPRoutes))
+	copy(routes, tc.tunnelProxy.Tunnel.Remote.HTTPRoutes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].PathPrefix) > len(routes[j].PathPrefix)
+	})
+	for _, route := range routes {
+		tc.tunnelProxy.Logger.Debugf("routing %s to %s", route.PathPrefix, route.Target)
+		router.MatcherFunc(matchPathPrefix(route.PathPrefix)).Handler(tc.serveHTTP(tc.createRouteReverseProxy(route)))
+	}
+
+	tc.createReverseProxy()
+	router.PathPrefix("/").Handler(tc.serveHTTP(tc.reverseProxy))
 
 	if tc.tunnelProxy.Tunnel.Remote.HostHeader != "" {
 		tc.tunnelProxy.Logger.Debugf("using host header %s", tc.tunnelProxy.Tunnel.HostHeader)
 		router.Use(tc.addHostHeader)
 	}
 
-	tc.createReverseProxy()
-
 	return router
 }
 
@@ -39,26 +61,96 @@ func (tc *TunnelProxyConnectorHTTP) createReverseProxy() {
 	}
 
 	tc.tunnelProxy.Logger.Infof("create https reverse proxy with ssl offloading forwarding to %s", tunnelURL.String())
-	tc.reverseProxy = httputil.NewSingleHostReverseProxy(&tunnelURL)
-	sslOffloadingTransport := &http.Transport{
+	tc.reverseProxy = tc.newReverseProxy(&tunnelURL, nil)
+	tc.reverseProxy.Transport = &http.Transport{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true, //nolint:gosec
 		},
 	}
-	tc.reverseProxy.Transport = sslOffloadingTransport
-	tc.reverseProxy.ErrorHandler = tc.tunnelProxy.handleProxyError
 }
 
-func (tc *TunnelProxyConnectorHTTP) serveHTTP(w http.ResponseWriter, r *http.Request) {
-	if tc.tunnelProxy.Tunnel.Remote.AuthUser != "" && tc.tunnelProxy.Tunnel.Remote.AuthPassword != "" {
-		user, password, ok := r.BasicAuth()
-		if !ok || user != tc.tunnelProxy.Tunnel.Remote.AuthUser || password != tc.tunnelProxy.Tunnel.Remote.AuthPassword {
-			w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+// createRouteReverseProxy creates a reverse proxy opening a new channel to the client for every connection to the route target
+func (tc *TunnelProxyConnectorHTTP) createRouteReverseProxy(route models.HTTPRoute) *httputil.ReverseProxy {
+	routeURL := url.URL{
+		Scheme: *tc.tunnelProxy.Tunnel.Remote.Scheme,
+		Host:   route.Target,
+	}
+
+	reverseProxy := tc.newReverseProxy(&routeURL, &route)
+	reverseProxy.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return tc.tunnelProxy.dialClient(route.Target)
+		},
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec
+		},
+	}
+	return reverseProxy
+}
+
+// newReverseProxy creates a reverse proxy applying the header rules of the tunnel, WebSocket upgrades are handled by the ReverseProxy
+func (tc *TunnelProxyConnectorHTTP) newReverseProxy(target *url.URL, route *models.HTTPRoute) *httputil.ReverseProxy {
+	remote := tc.tunnelProxy.Tunnel.Remote
+	reverseProxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := reverseProxy.Director
+	reverseProxy.Director = func(r *http.Request) {
+		if route != nil && route.StripPrefix {
+			stripPathPrefix(r.URL, route.PathPrefix)
+		}
+		director(r)
+		for _, rule := range remote.RequestHeaders {
+			rule.Apply(r.Header)
+		}
+		if remote.AuthHeaderValue != "" {
+			r.Header.Set(remote.AuthHeaderName, remote.AuthHeaderValue)
+		}
+	}
+	if len(remote.ResponseHeaders) > 0 {
+		reverseProxy.ModifyResponse = func(resp *http.Response) error {
+			for _, rule := range remote.ResponseHeaders {
+				rule.Apply(resp.Header)
+			}
+			return nil
 		}
 	}
-	tc.reverseProxy.ServeHTTP(w, r)
+	reverseProxy.ErrorHandler = tc.tunnelProxy.handleProxyError
+
+	return reverseProxy
+}
+
+// matchPathPrefix matches whole path segments, so /api matches /api and /api/users but not /apix
+func matchPathPrefix(prefix string) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		return hasPathPrefix(r.URL.Path, prefix)
+	}
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func stripPathPrefix(u *url.URL, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	u.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, prefix), "/")
+	if u.RawPath != "" {
+		u.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(u.RawPath, prefix), "/")
+	}
+}
+
+func (tc *TunnelProxyConnectorHTTP) serveHTTP(reverseProxy *httputil.ReverseProxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tc.tunnelProxy.Tunnel.Remote.AuthUser != "" && tc.tunnelProxy.Tunnel.Remote.AuthPassword != "" {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != tc.tunnelProxy.Tunnel.Remote.AuthUser || password != tc.tunnelProxy.Tunnel.Remote.AuthPassword {
+				w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		reverseProxy.ServeHTTP(w, r)
+	})
 }
 
 func (tc *TunnelProxyConnectorHTTP) addHostHeader(next http.Handler) http.Handler {
@@ -69,3 +161,69 @@ func (tc *TunnelProxyConnectorHTTP) addHostHeader(next http.Handler) http.Handle
 		next.ServeHTTP(w, r)
 	})
 }
+
+// logAccess middleware writes an access log line for every request tied to the tunnel and the riport user, which is
+// the user of the tunnel session if the tunnel requires one and the owner of the tunnel otherwise
+func (tc *TunnelProxyConnectorHTTP) logAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessLogResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		done := tc.tunnelProxy.requestStarted()
+		next.ServeHTTP(rec, r)
+		done()
+
+		user := tc.tunnelProxy.Tunnel.Owner
+		if sessionUser, ok := r.Context().Value(sessionUserCtxKey{}).(string); ok {
+			user = sessionUser
+		}
+		authUser, _, _ := r.BasicAuth()
+		if authUser == "" {
+			authUser = "-"
+		}
+		tc.tunnelProxy.accessLogger.Infof(
+			"tunnel=%s user=%s auth_user=%s remote_addr=%s %q %d %d %s",
+			tc.tunnelProxy.Tunnel.ID,
+			user,
+			authUser,
+			chshare.RemoteIP(r),
+			r.Method+" "+r.RequestURI+" "+r.Proto,
+			rec.status,
+			rec.bytes,
+			time.Since(start).Round(time.Millisecond),
+		)
+	})
+}
+
+// accessLogResponseWriter records the status and the size of responses, hijacking is supported for WebSocket upgrades
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *accessLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessLogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
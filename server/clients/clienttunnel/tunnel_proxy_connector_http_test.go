@@ -0,0 +1,172 @@
+package clienttunnel
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+// dialingConnMock opens channels by dialing the requested target directly
+type dialingConnMock struct {
+	ssh.Conn
+	mu     sync.Mutex
+	opened []string
+}
+
+func (c *dialingConnMock) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	c.mu.Lock()
+	c.opened = append(c.opened, string(data))
+	c.mu.Unlock()
+	conn, err := net.Dial("tcp", string(data))
+	if err != nil {
+		return nil, nil, &ssh.OpenChannelError{Reason: ssh.ConnectionFailed, Message: err.Error()}
+	}
+	return &socks5ChannelMock{Conn: conn}, make(chan *ssh.Request), nil
+}
+
+func newEchoRequestServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "websocket" {
+			conn, brw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+			_ = brw.Flush()
+			_, _ = io.Copy(conn, brw)
+			return
+		}
+		w.Header().Set("Server", name)
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Auth", r.Header.Get("Authorization"))
+		w.Header().Set("X-Request-Rule", r.Header.Get("X-Forwarded-Proto"))
+		_, _ = w.Write([]byte(name))
+	}))
+}
+
+func TestTunnelProxyConnectorHTTP(t *testing.T) {
+	main := newEchoRequestServer("main")
+	defer main.Close()
+	cam := newEchoRequestServer("cam")
+	defer cam.Close()
+	camAddr := cam.Listener.Addr().String()
+	mainHost, mainPort, err := net.SplitHostPort(main.Listener.Addr().String())
+	require.NoError(t, err)
+
+	scheme := "http"
+	sshConn := &dialingConnMock{}
+	tp := &InternalTunnelProxy{
+		Tunnel: &Tunnel{
+			ID: "3",
+			Remote: models.Remote{
+				Owner:  "admin",
+				Scheme: &scheme,
+				HTTPRoutes: []models.HTTPRoute{
+					{PathPrefix: "/cam", Target: camAddr},
+					{PathPrefix: "/cam/strip/", Target: camAddr, StripPrefix: true},
+				},
+				RequestHeaders:  []models.HeaderRule{{Action: models.HeaderRuleSet, Name: "X-Forwarded-Proto", Value: "https"}},
+				ResponseHeaders: []models.HeaderRule{{Action: models.HeaderRuleRemove, Name: "Server"}},
+				AuthHeaderName:  "Authorization",
+				AuthHeaderValue: "Bearer secret",
+			},
+		},
+		Logger:     logger.NewLogger("tunnel-proxy-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+		TunnelHost: mainHost,
+		TunnelPort: mainPort,
+		sshConn:    sshConn,
+	}
+	tp.accessLogger = tp.Logger.Fork("access")
+	tp.tunnelProxyConnector = NewTunnelConnectorHTTP(tp)
+	proxy := httptest.NewServer(tp.tunnelProxyConnector.InitRouter(mux.NewRouter()))
+	defer proxy.Close()
+
+	testCases := []struct {
+		path     string
+		wantBody string
+		wantPath string
+	}{
+		{
+			path:     "/index.html",
+			wantBody: "main",
+			wantPath: "/index.html",
+		},
+		{
+			path:     "/cam/snapshot.jpg",
+			wantBody: "cam",
+			wantPath: "/cam/snapshot.jpg",
+		},
+		{
+			path:     "/cam/strip/snapshot.jpg",
+			wantBody: "cam",
+			wantPath: "/snapshot.jpg",
+		},
+		{
+			path:     "/cam/strip",
+			wantBody: "cam",
+			wantPath: "/",
+		},
+		{
+			path:     "/cam",
+			wantBody: "cam",
+			wantPath: "/cam",
+		},
+		{
+			// prefixes match whole path segments only
+			path:     "/camera.jpg",
+			wantBody: "main",
+			wantPath: "/camera.jpg",
+		},
+	}
+	for _, tc := range testCases {
+		resp, err := http.Get(proxy.URL + tc.path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode, tc.path)
+		assert.Equal(t, tc.wantBody, string(body), tc.path)
+		assert.Equal(t, tc.wantPath, resp.Header.Get("X-Path"), tc.path)
+		assert.Equal(t, "Bearer secret", resp.Header.Get("X-Auth"), tc.path)
+		assert.Equal(t, "https", resp.Header.Get("X-Request-Rule"), tc.path)
+		assert.Empty(t, resp.Header.Get("Server"), tc.path)
+	}
+	sshConn.mu.Lock()
+	assert.Equal(t, []string{camAddr, camAddr}, sshConn.opened)
+	sshConn.mu.Unlock()
+	assert.WithinDuration(t, time.Now(), tp.LastActive(), 2*time.Second)
+
+	// websocket upgrade to a routed target
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /cam/ws HTTP/1.1\r\nHost: device\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	require.NoError(t, err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(br, echo)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(echo))
+}
@@ -0,0 +1,111 @@
+package clienttunnel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// TunnelSessionAuthPath is the path of the tunnel proxy exchanging a token issued by the API for a session cookie
+	TunnelSessionAuthPath = "/riport-tunnel-auth"
+
+	TunnelSessionTokenLifetime  = time.Minute
+	tunnelSessionCookieLifetime = 12 * time.Hour
+
+	tunnelSessionCookiePrefix = "riport_tunnel_"
+
+	tunnelSessionAudienceToken  = "riport-tunnel-token"
+	tunnelSessionAudienceCookie = "riport-tunnel-cookie"
+)
+
+// TunnelSessionAuthorizer checks that the user of a riport API session is still allowed to use the tunnels of a client
+type TunnelSessionAuthorizer interface {
+	AuthorizeTunnelSession(ctx context.Context, username string, sessionID int64, clientID string) error
+}
+
+// sessionUserCtxKey holds the name of the user of a validated tunnel session in the request context
+type sessionUserCtxKey struct{}
+
+type TunnelSessionClaims struct {
+	Username  string `json:"username"`
+	SessionID int64  `json:"session_id"`
+	ClientID  string `json:"client_id"`
+	TunnelID  string `json:"tunnel_id"`
+	jwt.StandardClaims
+}
+
+// TunnelSessionAuth issues and validates the tokens and cookies of tunnels requiring a riport API session
+type TunnelSessionAuth struct {
+	key        []byte
+	authorizer TunnelSessionAuthorizer
+}
+
+// NewTunnelSessionAuth derives the signing key from the API jwt secret, so tunnel tokens can't be used as API tokens
+func NewTunnelSessionAuth(jwtSecret string, authorizer TunnelSessionAuthorizer) *TunnelSessionAuth {
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte("riport tunnel session"))
+	return &TunnelSessionAuth{
+		key:        mac.Sum(nil),
+		authorizer: authorizer,
+	}
+}
+
+// NewToken returns a short-lived token the user opens the tunnel with
+func (a *TunnelSessionAuth) NewToken(username string, sessionID int64, clientID, tunnelID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(TunnelSessionTokenLifetime)
+	token, err := a.sign(TunnelSessionClaims{
+		Username:  username,
+		SessionID: sessionID,
+		ClientID:  clientID,
+		TunnelID:  tunnelID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  tunnelSessionAudienceToken,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	return token, expiresAt, err
+}
+
+func (a *TunnelSessionAuth) newCookieValue(claims *TunnelSessionClaims) (string, time.Time, error) {
+	expiresAt := time.Now().Add(tunnelSessionCookieLifetime)
+	cookieClaims := *claims
+	cookieClaims.Audience = tunnelSessionAudienceCookie
+	cookieClaims.ExpiresAt = expiresAt.Unix()
+	value, err := a.sign(cookieClaims)
+	return value, expiresAt, err
+}
+
+func (a *TunnelSessionAuth) sign(claims TunnelSessionClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.key)
+}
+
+// validate parses a token or cookie for the given tunnel and checks the access of the user to the client
+func (a *TunnelSessionAuth) validate(ctx context.Context, tokenStr, audience, clientID, tunnelID string) (*TunnelSessionClaims, error) {
+	claims := &TunnelSessionClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(audience, true) {
+		return nil, errors.New("invalid audience")
+	}
+	if claims.ClientID != clientID || claims.TunnelID != tunnelID {
+		return nil, errors.New("issued for another tunnel")
+	}
+
+	if err := a.authorizer.AuthorizeTunnelSession(ctx, claims.Username, claims.SessionID, claims.ClientID); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
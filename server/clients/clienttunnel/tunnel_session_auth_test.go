@@ -0,0 +1,190 @@
+package clienttunnel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+type tunnelSessionAuthorizerMock struct {
+	err error
+}
+
+func (a *tunnelSessionAuthorizerMock) AuthorizeTunnelSession(ctx context.Context, username string, sessionID int64, clientID string) error {
+	if username != "admin" || sessionID != 7 {
+		return errors.New("unexpected session")
+	}
+	return a.err
+}
+
+func TestTunnelSessionAuthValidate(t *testing.T) {
+	authorizer := &tunnelSessionAuthorizerMock{}
+	auth := NewTunnelSessionAuth("jwt-secret", authorizer)
+
+	token, _, err := auth.NewToken("admin", 7, "client-1", "2")
+	require.NoError(t, err)
+
+	claims, err := auth.validate(context.Background(), token, tunnelSessionAudienceToken, "client-1", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", claims.Username)
+
+	_, err = auth.validate(context.Background(), token, tunnelSessionAudienceCookie, "client-1", "2")
+	assert.EqualError(t, err, "invalid audience")
+
+	_, err = auth.validate(context.Background(), token, tunnelSessionAudienceToken, "client-1", "3")
+	assert.EqualError(t, err, "issued for another tunnel")
+
+	// tokens signed with the API secret directly are rejected
+	apiToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, TunnelSessionClaims{
+		Username:       "admin",
+		SessionID:      7,
+		ClientID:       "client-1",
+		TunnelID:       "2",
+		StandardClaims: jwt.StandardClaims{Audience: tunnelSessionAudienceToken},
+	}).SignedString([]byte("jwt-secret"))
+	require.NoError(t, err)
+	_, err = auth.validate(context.Background(), apiToken, tunnelSessionAudienceToken, "client-1", "2")
+	assert.EqualError(t, err, "signature is invalid")
+
+	authorizer.err = errors.New("access denied")
+	_, err = auth.validate(context.Background(), token, tunnelSessionAudienceToken, "client-1", "2")
+	assert.EqualError(t, err, "access denied")
+}
+
+func TestInternalTunnelProxySessionAuth(t *testing.T) {
+	accessLog, err := os.CreateTemp(t.TempDir(), "access.log")
+	require.NoError(t, err)
+	defer accessLog.Close()
+
+	authorizer := &tunnelSessionAuthorizerMock{}
+	tp := &InternalTunnelProxy{
+		Tunnel: &Tunnel{
+			ID:     "2",
+			Remote: models.Remote{AuthMode: models.TunnelAuthModeSession, Owner: "owner"},
+		},
+		Logger:      logger.NewLogger("tunnel-proxy-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+		Port:        "4000",
+		ClientID:    "client-1",
+		SessionAuth: NewTunnelSessionAuth("jwt-secret", authorizer),
+	}
+	router := mux.NewRouter()
+	router.Handle(TunnelSessionAuthPath, http.HandlerFunc(tp.handleSessionAuthToken))
+	router.Use(tp.handleSessionAuth)
+	router.Use(tp.removeSessionCookies)
+	tp.accessLogger = logger.NewLogger("access", logger.LogOutput{File: accessLog}, logger.LogLevelInfo)
+	router.Use(NewTunnelConnectorHTTP(tp).logAccess)
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Cookie")))
+	})
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/page", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(httptest.NewRequest(http.MethodGet, TunnelSessionAuthPath+"?token=invalid", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	token, _, err := tp.SessionAuth.NewToken("admin", 7, "client-1", "2")
+	require.NoError(t, err)
+	w = serve(httptest.NewRequest(http.MethodGet, TunnelSessionAuthPath+"?path=//example.com&token="+token, nil))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+
+	w = serve(httptest.NewRequest(http.MethodGet, TunnelSessionAuthPath+"?path=/page&token="+token, nil))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/page", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "riport_tunnel_4000", cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+
+	// the token itself is not accepted as cookie
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+	r.AddCookie(&http.Cookie{Name: "riport_tunnel_4000", Value: token})
+	w = serve(r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/page", nil)
+	r.AddCookie(cookies[0])
+	r.AddCookie(&http.Cookie{Name: "device", Value: "1"})
+	r.AddCookie(&http.Cookie{Name: "riport_tunnel_4001", Value: "other tunnel"})
+	w = serve(r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "device=1", w.Body.String())
+
+	// the access log names the user of the session instead of the owner of the tunnel
+	logged, err := os.ReadFile(accessLog.Name())
+	require.NoError(t, err)
+	assert.Contains(t, string(logged), `tunnel=2 user=admin auth_user=- remote_addr=192.0.2.1 "GET /page HTTP/1.1" 200`)
+
+	authorizer.err = errors.New("riport session expired")
+	r = httptest.NewRequest(http.MethodGet, "/page", nil)
+	r.AddCookie(cookies[0])
+	w = serve(r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestInternalTunnelProxyRemoveSessionCookies(t *testing.T) {
+	// tunnels without session auth must not receive the session cookies of other tunnels of the same host either
+	tp := &InternalTunnelProxy{
+		Tunnel: &Tunnel{ID: "3"},
+		Logger: logger.NewLogger("tunnel-proxy-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug),
+		Port:   "4002",
+	}
+	handler := tp.removeSessionCookies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Cookie")))
+	}))
+
+	testCases := []struct {
+		name    string
+		cookies []*http.Cookie
+		want    string
+	}{
+		{
+			name: "no cookies",
+			want: "",
+		},
+		{
+			name:    "other cookies only",
+			cookies: []*http.Cookie{{Name: "device", Value: "1"}, {Name: "lang", Value: "en"}},
+			want:    "device=1; lang=en",
+		},
+		{
+			name: "session cookies of this and other tunnels",
+			cookies: []*http.Cookie{
+				{Name: "riport_tunnel_4000", Value: "a"},
+				{Name: "device", Value: "1"},
+				{Name: "riport_tunnel_4002", Value: "b"},
+			},
+			want: "device=1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/page", nil)
+			for _, c := range tc.cookies {
+				r.AddCookie(c)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tc.want, w.Body.String())
+		})
+	}
+}
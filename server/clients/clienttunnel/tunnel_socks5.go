@@ -0,0 +1,210 @@
+package clienttunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jpillora/sizestr"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/server/tunnellog"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+// tunnelSOCKS5 is a socks5 proxy on the server, every CONNECT request is forwarded to the client
+type tunnelSOCKS5 struct {
+	// Declare 64-bit integer before 32-bit for alignment when compiling Go on 32-bit ARM platforms
+	lastConnClose int64 // time stored as int64 so it can be used with atomic
+	*logger.Logger
+	models.Remote
+	sshConn     ssh.Conn
+	acl         atomic.Pointer[TunnelACL] // parsed Remote.ACL field
+	credentials *socks5Credentials
+	traffic     *TunnelTraffic
+	connLog     *tunnellog.TunnelLog
+	connections *tunnelConnections
+
+	stopFn func()
+	wg     sync.WaitGroup
+}
+
+func newTunnelSOCKS5(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl *TunnelACL, traffic *TunnelTraffic, connLog *tunnellog.TunnelLog) *tunnelSOCKS5 {
+	t := &tunnelSOCKS5{
+		Logger:      logger,
+		Remote:      remote,
+		sshConn:     ssh,
+		traffic:     traffic,
+		connLog:     connLog,
+		connections: newTunnelConnections(),
+	}
+	if remote.AuthUser != "" {
+		t.credentials = &socks5Credentials{
+			user:     remote.AuthUser,
+			password: remote.AuthPassword,
+		}
+	}
+	t.SetACL(acl)
+	return t
+}
+
+func (t *tunnelSOCKS5) Start(ctx context.Context) error {
+	t.Logger.Debugf("starting socks5 tunnel...")
+	t.Logger.Debugf("listening on %+v", t.Local())
+
+	l, err := net.Listen("tcp", t.Local())
+	if err != nil {
+		return fmt.Errorf("%s: %s", t.Logger.Prefix(), err)
+	}
+
+	ctx, t.stopFn = context.WithCancel(ctx)
+	t.wg.Add(1)
+	go t.listen(ctx, l)
+	return nil
+}
+
+func (t *tunnelSOCKS5) Terminate(force bool) error {
+	n := t.connections.len()
+	if !force && n > 0 {
+		return fmt.Errorf("tunnel has %d active connection(s)", n)
+	}
+	if t.stopFn == nil {
+		return nil
+	}
+
+	t.stopFn()
+	t.wg.Wait()
+	t.Infof("stopped")
+	t.stopFn = nil
+	return nil
+}
+
+func (t *tunnelSOCKS5) LastActive() time.Time {
+	if t.connections.len() > 0 {
+		return time.Now()
+	}
+	return time.Unix(atomic.LoadInt64(&t.lastConnClose), 0)
+}
+
+func (t *tunnelSOCKS5) SetACL(acl *TunnelACL) {
+	t.acl.Store(acl)
+}
+
+// Connections returns the active connections ordered by ID
+func (t *tunnelSOCKS5) Connections() []TunnelConnection {
+	return t.connections.list()
+}
+
+func (t *tunnelSOCKS5) listen(ctx context.Context, l net.Listener) {
+	defer t.wg.Done()
+
+	t.Infof("socks5 tunnel listening")
+
+	// background goroutine to close the listener when context is canceled
+	go func() {
+		<-ctx.Done()
+		if err := l.Close(); err != nil {
+			t.Errorf("Failed to close tunnel listener: %v", err)
+			return
+		}
+		t.Debugf("tunnel listener closed")
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				//listener closed
+			default:
+				t.Errorf("Failed to accept connection: %v", err)
+			}
+			return
+		}
+
+		acl := t.acl.Load()
+		if acl != nil {
+			tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+			if !ok || !acl.CheckAccess(tcpAddr.IP) {
+				t.Debugf("Access rejected. Remote addr: %s", conn.RemoteAddr())
+				logRejectedConnection(t.connLog, models.ProtocolSOCKS5, conn.RemoteAddr().String(), "", tunnellog.CloseReasonACL)
+				conn.Close()
+				continue
+			}
+		}
+
+		t.wg.Add(1)
+		go func() {
+			t.accept(ctx, conn)
+			t.wg.Done()
+			atomic.StoreInt64(&t.lastConnClose, time.Now().Unix())
+		}()
+	}
+}
+
+func (t *tunnelSOCKS5) accept(ctx context.Context, src net.Conn) {
+	defer src.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	// link ctx to conn
+	go func() {
+		select {
+		case <-ctx.Done():
+			src.Close()
+		case <-done:
+		}
+	}()
+
+	destination, err := socks5Handshake(src, t.credentials)
+	if err != nil {
+		t.Debugf("socks5 handshake with %s failed: %v", src.RemoteAddr(), err)
+		if ctx.Err() == nil {
+			logRejectedConnection(t.connLog, models.ProtocolSOCKS5, src.RemoteAddr().String(), "", fmt.Sprintf("socks5 handshake failed: %v", err))
+		}
+		return
+	}
+
+	conn := t.connections.add(src.RemoteAddr().String(), destination)
+	closeReason := tunnellog.CloseReasonClosed
+	defer func() {
+		t.connections.remove(conn.ID)
+		logConnection(ctx, t.connLog, t.traffic, models.ProtocolSOCKS5, conn, closeReason)
+	}()
+	l := t.Fork("conn#%d", conn.ID)
+	l.Debugf("CONNECT %s", destination)
+
+	if t.sshConn == nil {
+		l.Debugf("No remote connection")
+		closeReason = "no remote connection"
+		_ = socks5Reply(src, socks5RepGeneralFailure)
+		return
+	}
+	// the client checks the destination against tunnel_allowed before dialing
+	dst, reqs, err := t.sshConn.OpenChannel("riport", []byte(destination))
+	if err != nil {
+		l.Infof("Could not connect to %s: %v", destination, err)
+		closeReason = fmt.Sprintf("could not connect: %v", err)
+		rep := byte(socks5RepGeneralFailure)
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) && openErr.Reason == ssh.Prohibited {
+			rep = socks5RepNotAllowed
+		}
+		_ = socks5Reply(src, rep)
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	if err := socks5Reply(src, socks5RepSucceeded); err != nil {
+		dst.Close()
+		return
+	}
+
+	s, r := t.traffic.pipe(src, dst, conn)
+	l.Debugf("Close (sent %s received %s)", sizestr.ToString(s), sizestr.ToString(r))
+}
@@ -0,0 +1,97 @@
+package clienttunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+// socks5ConnMock opens channels to an echo server, destinations other than allowed are prohibited like by tunnel_allowed
+type socks5ConnMock struct {
+	ssh.Conn
+	allowed string
+}
+
+func (c *socks5ConnMock) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	if string(data) != c.allowed {
+		return nil, nil, &ssh.OpenChannelError{Reason: ssh.Prohibited}
+	}
+
+	server, client := net.Pipe()
+	go func() {
+		_, _ = io.Copy(server, server)
+		server.Close()
+	}()
+	return &socks5ChannelMock{Conn: client}, make(chan *ssh.Request), nil
+}
+
+type socks5ChannelMock struct {
+	ssh.Channel
+	net.Conn
+}
+
+func (c *socks5ChannelMock) Read(p []byte) (int, error)  { return c.Conn.Read(p) }
+func (c *socks5ChannelMock) Write(p []byte) (int, error) { return c.Conn.Write(p) }
+func (c *socks5ChannelMock) Close() error                { return c.Conn.Close() }
+
+func TestTunnelSOCKS5(t *testing.T) {
+	remote, err := models.NewSOCKS5Remote("127.0.0.1:0")
+	require.NoError(t, err)
+	log := logger.NewLogger("socks5-tunnel-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+
+	// pick a free port as the listener address is not exposed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	remote.LocalPort = strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, l.Close())
+
+	tunnel := newTunnelSOCKS5(log, &socks5ConnMock{allowed: "192.168.1.10:22"}, *remote, nil, newTunnelTraffic(*remote), nil)
+	require.NoError(t, tunnel.Start(context.Background()))
+	defer func() {
+		assert.NoError(t, tunnel.Terminate(true))
+	}()
+
+	connect := func(ip net.IP) (net.Conn, []byte) {
+		conn, err := net.Dial("tcp", remote.Local())
+		require.NoError(t, err)
+		_, err = conn.Write(append([]byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x01}, append(ip.To4(), 0x00, 0x16)...))
+		require.NoError(t, err)
+		resp := make([]byte, 12)
+		_, err = io.ReadFull(conn, resp)
+		require.NoError(t, err)
+		return conn, resp
+	}
+
+	conn, resp := connect(net.ParseIP("192.168.1.10"))
+	defer conn.Close()
+	assert.Equal(t, []byte{0x05, 0x00, 0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}, resp)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(conn, echo)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(echo))
+
+	connections := tunnel.Connections()
+	require.Len(t, connections, 1)
+	assert.Equal(t, "192.168.1.10:22", connections[0].Destination)
+	assert.Equal(t, conn.LocalAddr().String(), connections[0].Source)
+	assert.WithinDuration(t, time.Now(), tunnel.LastActive(), time.Second)
+	assert.EqualError(t, tunnel.Terminate(false), "tunnel has 1 active connection(s)")
+
+	notAllowed, resp := connect(net.ParseIP("192.168.1.11"))
+	defer notAllowed.Close()
+	assert.Equal(t, []byte{0x05, 0x00, 0x05, 0x02, 0x00, 0x01, 0, 0, 0, 0, 0, 0}, resp)
+}
@@ -3,7 +3,6 @@ package clienttunnel
 import (
 	"context"
 	"fmt"
-	"io"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -12,7 +11,7 @@ import (
 	"github.com/jpillora/sizestr"
 	"golang.org/x/crypto/ssh"
 
-	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/server/tunnellog"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/models"
 )
@@ -24,18 +23,23 @@ type tunnelTCP struct {
 	models.Remote
 	sshConn ssh.Conn
 	acl     atomic.Pointer[TunnelACL] // parsed Remote.ACL field
+	traffic *TunnelTraffic
+	connLog *tunnellog.TunnelLog
 
-	stopFn                    func()
-	connectionIDAutoIncrement int
-	connCount                 int32
-	wg                        sync.WaitGroup // TODO: verify whether wait group is needed here
+	stopFn      func()
+	connections *tunnelConnections
+	connCount   int32
+	wg          sync.WaitGroup // TODO: verify whether wait group is needed here
 }
 
-func newTunnelTCP(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl *TunnelACL) *tunnelTCP {
+func newTunnelTCP(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl *TunnelACL, traffic *TunnelTraffic, connLog *tunnellog.TunnelLog) *tunnelTCP {
 	t := &tunnelTCP{
-		Logger:  logger,
-		Remote:  remote,
-		sshConn: ssh,
+		Logger:      logger,
+		Remote:      remote,
+		sshConn:     ssh,
+		traffic:     traffic,
+		connLog:     connLog,
+		connections: newTunnelConnections(),
 	}
 	t.SetACL(acl)
 	return t
@@ -115,6 +119,7 @@ func (t *tunnelTCP) listen(ctx context.Context, l net.Listener) {
 
 			if !acl.CheckAccess(tcpAddr.IP) {
 				t.Debugf("Access rejected. Remote addr: %s", tcpAddr)
+				logRejectedConnection(t.connLog, models.ProtocolTCP, tcpAddr.String(), t.Remote.Remote(), tunnellog.CloseReasonACL)
 				conn.Close()
 				continue
 			}
@@ -136,14 +141,23 @@ func (t *tunnelTCP) LastActive() time.Time {
 	return time.Unix(atomic.LoadInt64(&t.lastConnClose), 0)
 }
 
-func (t *tunnelTCP) accept(ctx context.Context, src io.ReadWriteCloser) {
+// Connections returns the active connections ordered by ID
+func (t *tunnelTCP) Connections() []TunnelConnection {
+	return t.connections.list()
+}
+
+func (t *tunnelTCP) accept(ctx context.Context, src net.Conn) {
 	defer src.Close()
-	t.connectionIDAutoIncrement++
 	atomic.AddInt32(&t.connCount, 1)
 	defer atomic.AddInt32(&t.connCount, -1)
 
-	cid := t.connectionIDAutoIncrement
-	l := t.Fork("conn#%d", cid)
+	conn := t.connections.add(src.RemoteAddr().String(), t.Remote.Remote())
+	closeReason := tunnellog.CloseReasonClosed
+	defer func() {
+		t.connections.remove(conn.ID)
+		logConnection(ctx, t.connLog, t.traffic, models.ProtocolTCP, conn, closeReason)
+	}()
+	l := t.Fork("conn#%d", conn.ID)
 
 	l.Debugf("Accept")
 
@@ -162,12 +176,14 @@ func (t *tunnelTCP) accept(ctx context.Context, src io.ReadWriteCloser) {
 
 	if t.sshConn == nil {
 		l.Debugf("No remote connection")
+		closeReason = "no remote connection"
 		return
 	}
 	// ssh request to open connection to this tunnel's remote
 	dst, reqs, err := t.sshConn.OpenChannel("riport", []byte(t.Remote.Remote()))
 	if err != nil {
 		l.Errorf("Could not establish TCP tunnel: %v", err)
+		closeReason = fmt.Sprintf("could not connect: %v", err)
 		return
 	}
 
@@ -176,7 +192,7 @@ func (t *tunnelTCP) accept(ctx context.Context, src io.ReadWriteCloser) {
 
 	go ssh.DiscardRequests(reqs)
 	//then pipe
-	s, r := chshare.Pipe(src, dst)
+	s, r := t.traffic.pipe(src, dst, conn)
 	l.Debugf("Close (sent %s received %s)", sizestr.ToString(s), sizestr.ToString(r))
 	close(done)
 }
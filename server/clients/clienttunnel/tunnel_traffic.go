@@ -0,0 +1,206 @@
+package clienttunnel
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	chshare "github.com/riportdev/riport/share"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/ratelimit"
+)
+
+var ErrTrafficQuotaExceeded = errors.New("traffic quota exceeded")
+
+// TrafficStats are the bytes transferred by a tunnel, in is sent by the users to the client, out is sent back by the client
+type TrafficStats struct {
+	BytesIn  int64 `json:"bytes_in"`
+	BytesOut int64 `json:"bytes_out"`
+}
+
+// TunnelTraffic counts the bytes of all connections of a tunnel and applies the rate limit and quota of the tunnel
+type TunnelTraffic struct {
+	// Declare 64-bit integer before 32-bit for alignment when compiling Go on 32-bit ARM platforms
+	bytesIn  int64
+	bytesOut int64
+	quota    int64
+
+	// the rate limit applies to each direction separately
+	limiterIn  *ratelimit.Limiter
+	limiterOut *ratelimit.Limiter
+
+	quotaExceeded     chan struct{}
+	quotaExceededOnce sync.Once
+}
+
+func newTunnelTraffic(remote models.Remote) *TunnelTraffic {
+	return &TunnelTraffic{
+		quota:         remote.Quota,
+		limiterIn:     ratelimit.NewLimiter(remote.MaxRate),
+		limiterOut:    ratelimit.NewLimiter(remote.MaxRate),
+		quotaExceeded: make(chan struct{}),
+	}
+}
+
+func (t *TunnelTraffic) Stats() TrafficStats {
+	return TrafficStats{
+		BytesIn:  atomic.LoadInt64(&t.bytesIn),
+		BytesOut: atomic.LoadInt64(&t.bytesOut),
+	}
+}
+
+// QuotaExceeded is closed once the tunnel transferred more than its quota
+func (t *TunnelTraffic) QuotaExceeded() <-chan struct{} {
+	return t.quotaExceeded
+}
+
+func (t *TunnelTraffic) isQuotaExceeded() bool {
+	select {
+	case <-t.quotaExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// remaining returns the bytes left until the quota is exceeded, -1 if the tunnel has no quota
+func (t *TunnelTraffic) remaining() int64 {
+	if t.quota <= 0 {
+		return -1
+	}
+	remaining := t.quota - atomic.LoadInt64(&t.bytesIn) - atomic.LoadInt64(&t.bytesOut)
+	if remaining <= 0 {
+		t.quotaExceededOnce.Do(func() {
+			close(t.quotaExceeded)
+		})
+		return 0
+	}
+	return remaining
+}
+
+// addIn counts n bytes sent to the client, it returns false if the quota is exceeded
+func (t *TunnelTraffic) addIn(n int) bool {
+	t.limiterIn.Wait(n)
+	atomic.AddInt64(&t.bytesIn, int64(n))
+	return t.remaining() != 0
+}
+
+// addOut counts n bytes received from the client, it returns false if the quota is exceeded
+func (t *TunnelTraffic) addOut(n int) bool {
+	t.limiterOut.Wait(n)
+	atomic.AddInt64(&t.bytesOut, int64(n))
+	return t.remaining() != 0
+}
+
+// pipe connects the user connection src with the channel to the client dst and counts the bytes of both directions
+func (t *TunnelTraffic) pipe(src, dst io.ReadWriteCloser, conn *TunnelConnection) (sent int64, received int64) {
+	return chshare.Pipe(
+		&trafficReadWriteCloser{ReadWriteCloser: src, add: t.addIn, connBytes: &conn.BytesIn, remaining: t.remaining},
+		&trafficReadWriteCloser{ReadWriteCloser: dst, add: t.addOut, connBytes: &conn.BytesOut, remaining: t.remaining},
+	)
+}
+
+type trafficReadWriteCloser struct {
+	io.ReadWriteCloser
+	add       func(n int) bool
+	connBytes *int64
+	remaining func() int64
+}
+
+func (c *trafficReadWriteCloser) Read(p []byte) (int, error) {
+	remaining := c.remaining()
+	if remaining == 0 {
+		return 0, ErrTrafficQuotaExceeded
+	}
+	if remaining > 0 && int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := c.ReadWriteCloser.Read(p)
+	// reads of other connections might have used up the quota meanwhile, the rest is dropped
+	if remaining := c.remaining(); remaining >= 0 && int64(n) > remaining {
+		n = int(remaining)
+		err = ErrTrafficQuotaExceeded
+	}
+	atomic.AddInt64(c.connBytes, int64(n))
+	c.add(n)
+	return n, err
+}
+
+// TunnelConnection is an active connection of a tunnel
+type TunnelConnection struct {
+	// Declare 64-bit integer before 32-bit for alignment when compiling Go on 32-bit ARM platforms
+	BytesIn     int64     `json:"bytes_in"`
+	BytesOut    int64     `json:"bytes_out"`
+	ID          int       `json:"id"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// tunnelConnections keeps the active connections of a tunnel
+type tunnelConnections struct {
+	mu                        sync.Mutex
+	connectionIDAutoIncrement int
+	connections               map[int]*TunnelConnection
+}
+
+func newTunnelConnections() *tunnelConnections {
+	return &tunnelConnections{
+		connections: make(map[int]*TunnelConnection),
+	}
+}
+
+func (c *tunnelConnections) add(source, destination string) *TunnelConnection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectionIDAutoIncrement++
+	conn := &TunnelConnection{
+		ID:          c.connectionIDAutoIncrement,
+		Source:      source,
+		Destination: destination,
+		StartedAt:   time.Now(),
+	}
+	c.connections[conn.ID] = conn
+	return conn
+}
+
+func (c *tunnelConnections) remove(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.connections, id)
+}
+
+func (c *tunnelConnections) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.connections)
+}
+
+// list returns the active connections ordered by ID
+func (c *tunnelConnections) list() []TunnelConnection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	connections := make([]TunnelConnection, 0, len(c.connections))
+	for _, conn := range c.connections {
+		connections = append(connections, TunnelConnection{
+			BytesIn:     atomic.LoadInt64(&conn.BytesIn),
+			BytesOut:    atomic.LoadInt64(&conn.BytesOut),
+			ID:          conn.ID,
+			Source:      conn.Source,
+			Destination: conn.Destination,
+			StartedAt:   conn.StartedAt,
+		})
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ID < connections[j].ID
+	})
+	return connections
+}
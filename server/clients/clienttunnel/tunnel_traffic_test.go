@@ -0,0 +1,117 @@
+package clienttunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/server/tunnellog"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+)
+
+// echoConnMock opens channels to an echo server like socks5ConnMock
+type echoConnMock struct {
+	socks5ConnMock
+}
+
+func (c *echoConnMock) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+}
+
+func startTestTCPTunnel(t *testing.T, remote models.Remote) (*tunnelTCP, *TunnelTraffic) {
+	return startTestTCPTunnelWithLog(t, remote, nil, nil)
+}
+
+func startTestTCPTunnelWithLog(t *testing.T, remote models.Remote, acl *TunnelACL, connLog *tunnellog.TunnelLog) (*tunnelTCP, *TunnelTraffic) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	remote.LocalHost = "127.0.0.1"
+	remote.LocalPort = strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, l.Close())
+
+	log := logger.NewLogger("tcp-tunnel-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	traffic := newTunnelTraffic(remote)
+	tunnel := newTunnelTCP(log, &echoConnMock{socks5ConnMock{allowed: remote.Remote()}}, remote, acl, traffic, connLog)
+	require.NoError(t, tunnel.Start(context.Background()))
+	t.Cleanup(func() {
+		_ = tunnel.Terminate(true)
+	})
+	return tunnel, traffic
+}
+
+func TestTunnelTCPTraffic(t *testing.T) {
+	tunnel, traffic := startTestTCPTunnel(t, models.Remote{Protocol: models.ProtocolTCP, RemoteHost: "127.0.0.1", RemotePort: "22"})
+
+	conn, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return traffic.Stats() == TrafficStats{BytesIn: 5, BytesOut: 5}
+	}, time.Second, 10*time.Millisecond)
+
+	connections := tunnel.Connections()
+	require.Len(t, connections, 1)
+	assert.Equal(t, "127.0.0.1:22", connections[0].Destination)
+	assert.Equal(t, int64(5), connections[0].BytesIn)
+	assert.Equal(t, int64(5), connections[0].BytesOut)
+
+	require.NoError(t, conn.Close())
+	assert.Eventually(t, func() bool {
+		return len(tunnel.Connections()) == 0
+	}, time.Second, 10*time.Millisecond)
+	// the totals are kept after the connection is closed
+	assert.Equal(t, TrafficStats{BytesIn: 5, BytesOut: 5}, traffic.Stats())
+}
+
+func TestTunnelTCPTrafficQuota(t *testing.T) {
+	tunnel, traffic := startTestTCPTunnel(t, models.Remote{Protocol: models.ProtocolTCP, RemoteHost: "127.0.0.1", RemotePort: "22", Quota: 8})
+
+	conn, err := net.Dial("tcp", tunnel.Local())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	select {
+	case <-traffic.QuotaExceeded():
+	case <-time.After(time.Second):
+		t.Fatal("quota not exceeded")
+	}
+
+	// the connection is closed after the quota is used up
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	received, _ := io.ReadAll(conn)
+	assert.Equal(t, "hel", string(received))
+	assert.Equal(t, TrafficStats{BytesIn: 5, BytesOut: 3}, traffic.Stats())
+}
+
+func TestTunnelTrafficMaxRate(t *testing.T) {
+	traffic := newTunnelTraffic(models.Remote{MaxRate: 1000})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.True(t, traffic.addIn(500))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// the directions are limited separately
+	start = time.Now()
+	assert.True(t, traffic.addOut(500))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, TrafficStats{BytesIn: 1500, BytesOut: 500}, traffic.Stats())
+}
@@ -10,6 +10,7 @@ import (
 
 	"golang.org/x/crypto/ssh"
 
+	"github.com/riportdev/riport/server/tunnellog"
 	"github.com/riportdev/riport/share/comm"
 	"github.com/riportdev/riport/share/logger"
 	"github.com/riportdev/riport/share/models"
@@ -17,12 +18,27 @@ import (
 
 var udpReadTimeout = time.Second
 
+// udpConnectionTimeout is the inactivity after which the datagrams of a source are logged as a closed connection
+var udpConnectionTimeout = time.Minute
+
+// udpConnection are the datagrams from and to a source address
+type udpConnection struct {
+	source     string
+	status     string
+	startedAt  time.Time
+	lastActive time.Time
+	bytesIn    int64
+	bytesOut   int64
+}
+
 type tunnelUDP struct {
 	*logger.Logger
 	models.Remote
 	sshConn     ssh.Conn
 	acl         atomic.Pointer[TunnelACL] // parsed Remote.ACL field
 	idleTimeout time.Duration
+	traffic     *TunnelTraffic
+	connLog     *tunnellog.TunnelLog
 
 	conn    *net.UDPConn
 	channel *comm.UDPChannel
@@ -31,9 +47,14 @@ type tunnelUDP struct {
 
 	mtx        sync.Mutex
 	lastActive time.Time
+
+	connectionsMtx    sync.Mutex
+	connections       map[string]*udpConnection
+	connectionsClosed bool
+	lastExpire        time.Time
 }
 
-func newTunnelUDP(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl *TunnelACL) *tunnelUDP {
+func newTunnelUDP(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl *TunnelACL, traffic *TunnelTraffic, connLog *tunnellog.TunnelLog) *tunnelUDP {
 	t := &tunnelUDP{
 		Logger:      logger,
 		Remote:      remote,
@@ -41,6 +62,9 @@ func newTunnelUDP(logger *logger.Logger, ssh ssh.Conn, remote models.Remote, acl
 		done:        make(chan struct{}),
 		lastActive:  time.Now(),
 		idleTimeout: time.Duration(remote.IdleTimeoutMinutes) * time.Minute,
+		traffic:     traffic,
+		connLog:     connLog,
+		connections: make(map[string]*udpConnection),
 	}
 	t.SetACL(acl)
 	return t
@@ -92,6 +116,7 @@ func (t *tunnelUDP) start(ctx context.Context, sshChan io.ReadWriter) error {
 func (t *tunnelUDP) runInbound(ctx context.Context) error {
 	defer t.conn.Close()
 	defer close(t.done)
+	defer t.closeConnections()
 
 	const maxMTU = 9012
 	buff := make([]byte, maxMTU)
@@ -102,6 +127,8 @@ func (t *tunnelUDP) runInbound(ctx context.Context) error {
 		default:
 		}
 
+		t.expireConnections()
+
 		err := t.conn.SetReadDeadline(time.Now().Add(udpReadTimeout))
 		if err != nil {
 			return err
@@ -124,10 +151,16 @@ func (t *tunnelUDP) runInbound(ctx context.Context) error {
 		if acl != nil {
 			if !acl.CheckAccess(sourceAddr.IP) {
 				t.Debugf("Access rejected. Remote addr: %s", sourceAddr)
+				t.trackConnection(sourceAddr, tunnellog.StatusRejected, 0, 0)
 				continue
 			}
 		}
 
+		t.trackConnection(sourceAddr, tunnellog.StatusAccepted, n, 0)
+		if !t.traffic.addIn(n) {
+			return ErrTrafficQuotaExceeded
+		}
+
 		err = t.channel.Encode(sourceAddr, buff[:n])
 		if err != nil {
 			return err
@@ -153,6 +186,11 @@ func (t *tunnelUDP) runOutbound(ctx context.Context) error {
 
 		t.setLastActive()
 
+		t.trackConnection(addr, tunnellog.StatusAccepted, 0, len(data))
+		if !t.traffic.addOut(len(data)) {
+			return ErrTrafficQuotaExceeded
+		}
+
 		_, err = t.conn.WriteToUDP(data, addr)
 		if err != nil {
 			return err
@@ -184,3 +222,89 @@ func (t *tunnelUDP) setLastActive() {
 func (t *tunnelUDP) SetACL(acl *TunnelACL) {
 	t.acl.Store(acl)
 }
+
+// trackConnection counts the datagrams of a source, a change of the status is logged as a new connection
+func (t *tunnelUDP) trackConnection(source *net.UDPAddr, status string, bytesIn, bytesOut int) {
+	t.connectionsMtx.Lock()
+	defer t.connectionsMtx.Unlock()
+
+	if t.connectionsClosed {
+		return
+	}
+
+	now := time.Now()
+	key := source.String()
+	conn := t.connections[key]
+	if conn != nil && conn.status != status {
+		t.logConnection(conn, tunnellog.CloseReasonClosed)
+		conn = nil
+	}
+	if conn == nil {
+		conn = &udpConnection{
+			source:    key,
+			status:    status,
+			startedAt: now,
+		}
+		t.connections[key] = conn
+	}
+	conn.lastActive = now
+	conn.bytesIn += int64(bytesIn)
+	conn.bytesOut += int64(bytesOut)
+}
+
+// expireConnections logs the connections without datagrams for udpConnectionTimeout
+func (t *tunnelUDP) expireConnections() {
+	t.connectionsMtx.Lock()
+	defer t.connectionsMtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.lastExpire) < udpReadTimeout {
+		return
+	}
+	t.lastExpire = now
+
+	for key, conn := range t.connections {
+		if now.Sub(conn.lastActive) >= udpConnectionTimeout {
+			t.logConnection(conn, tunnellog.CloseReasonIdle)
+			delete(t.connections, key)
+		}
+	}
+}
+
+func (t *tunnelUDP) closeConnections() {
+	t.connectionsMtx.Lock()
+	defer t.connectionsMtx.Unlock()
+
+	reason := tunnellog.CloseReasonTunnelClosed
+	if t.traffic.isQuotaExceeded() {
+		reason = tunnellog.CloseReasonQuotaExceeded
+	}
+	for _, conn := range t.connections {
+		t.logConnection(conn, reason)
+	}
+	t.connections = nil
+	t.connectionsClosed = true
+}
+
+func (t *tunnelUDP) logConnection(conn *udpConnection, reason string) {
+	if t.connLog == nil {
+		return
+	}
+
+	if conn.status == tunnellog.StatusRejected {
+		reason = tunnellog.CloseReasonACL
+	}
+	ip, port := splitSource(conn.source)
+	t.connLog.Save(&tunnellog.Record{
+		Protocol:    models.ProtocolUDP,
+		SourceIP:    ip,
+		SourcePort:  port,
+		Destination: t.Remote.Remote(),
+		Status:      conn.status,
+		StartedAt:   conn.startedAt,
+		EndedAt:     conn.lastActive,
+		BytesIn:     conn.bytesIn,
+		BytesOut:    conn.bytesOut,
+		CloseReason: reason,
+	})
+}
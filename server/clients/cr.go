@@ -25,6 +25,8 @@ type ClientRepository struct {
 
 	postSaveHandlerFn func(cl *clientdata.Client)
 
+	accessGrantChecker AccessGrantChecker
+
 	logger *logger.Logger
 
 	mu sync.RWMutex
@@ -33,6 +35,13 @@ type ClientRepository struct {
 type User interface {
 	IsAdmin() bool
 	GetGroups() []string
+	GetUsername() string
+}
+
+// AccessGrantChecker decides whether a temporary access grant gives a user access to a client
+// in addition to the client ACL and the client groups.
+type AccessGrantChecker interface {
+	HasClientAccess(username string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool
 }
 
 // NewClientRepository returns a new thread-safe in-memory cache to store client connections populated with given clients if any.
@@ -62,10 +71,11 @@ func InitClientRepository(
 	ctx context.Context,
 	db *sqlx.DB,
 	keepDisconnectedClients *time.Duration,
+	remoteClients RemoteClientChecker,
 	logger *logger.Logger,
 ) (*ClientRepository, error) {
 	provider := newSqliteProvider(db, keepDisconnectedClients)
-	initialClients, err := LoadInitialClients(ctx, provider, logger)
+	initialClients, err := LoadInitialClients(ctx, provider, remoteClients, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +87,34 @@ func (r *ClientRepository) SetPostSaveHandlerFn(handlerFn func(cl *clientdata.Cl
 	r.postSaveHandlerFn = handlerFn
 }
 
+func (r *ClientRepository) SetAccessGrantChecker(checker AccessGrantChecker) {
+	r.mu.Lock()
+	r.accessGrantChecker = checker
+	r.mu.Unlock()
+}
+
+// getAccessGrantChecker returns nil if no checker is set, access is granted by user and client groups only then
+func (r *ClientRepository) getAccessGrantChecker() (checker AccessGrantChecker) {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	checker = r.accessGrantChecker
+	r.mu.RUnlock()
+	return checker
+}
+
+// UserHasAccess returns whether a user has access to a client either by user group, by client group or by an access grant.
+func (r *ClientRepository) UserHasAccess(user User, client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool {
+	userGroups := user.GetGroups()
+	if user.IsAdmin() || client.HasAccessViaUserGroups(userGroups) || client.UserGroupHasAccessViaClientGroup(userGroups, clientGroups) {
+		return true
+	}
+
+	checker := r.getAccessGrantChecker()
+	return checker != nil && checker.HasClientAccess(user.GetUsername(), userGroups, client, clientGroups)
+}
+
 func (r *ClientRepository) GetPostSaveHandlerFn() (handlerFn func(cl *clientdata.Client)) {
 	r.mu.RLock()
 	handlerFn = r.postSaveHandlerFn
@@ -209,6 +247,12 @@ func (r *ClientRepository) DeleteObsolete() ([]*clientdata.Client, error) {
 		}
 	}
 
+	return r.DeleteObsoleteInMemory(), nil
+}
+
+// DeleteObsoleteInMemory deletes obsolete disconnected clients from memory only and returns them. Nodes of a cluster
+// use it to leave the deletion from the shared store to the leader.
+func (r *ClientRepository) DeleteObsoleteInMemory() []*clientdata.Client {
 	clientsToDelete := r.queryClients(func(c *clientdata.Client) (match bool) {
 		return c.Obsolete(r.GetKeepDisconnectedClients())
 	})
@@ -219,7 +263,7 @@ func (r *ClientRepository) DeleteObsolete() ([]*clientdata.Client, error) {
 		r.removeClient(clientID)
 	}
 
-	return clientsToDelete, nil
+	return clientsToDelete
 }
 
 // Count returns a number of non-obsolete active and disconnected clients.
@@ -352,18 +396,11 @@ func (r *ClientRepository) getNonObsoleteClients() (matchingClients []*clientdat
 	return matchingClients
 }
 
-// getNonObsoleteByUser return connected clients the user has access to either by user group or by client group.
+// getNonObsoleteByUser return connected clients the user has access to either by user group, by client group or by an access grant.
 // returns a new client array that can be used without locks (assuming not shared)
 func (r *ClientRepository) getNonObsoleteClientsByUser(user User, clientGroups []*cgroups.ClientGroup) (matchingClients []*clientdata.Client) {
-	userGroups := user.GetGroups()
-
 	matchingClients = r.queryClients(func(c *clientdata.Client) (match bool) {
-		if !c.Obsolete(r.GetKeepDisconnectedClients()) {
-			if user.IsAdmin() || c.HasAccessViaUserGroups(userGroups) || c.UserGroupHasAccessViaClientGroup(userGroups, clientGroups) {
-				return true
-			}
-		}
-		return false
+		return !c.Obsolete(r.GetKeepDisconnectedClients()) && r.UserHasAccess(user, c, clientGroups)
 	})
 
 	return matchingClients
@@ -15,8 +15,9 @@ import (
 )
 
 type UserMock struct {
-	ReturnIsAdmin bool
-	ReturnGroups  []string
+	ReturnIsAdmin  bool
+	ReturnGroups   []string
+	ReturnUsername string
 }
 
 func (u UserMock) IsAdmin() bool {
@@ -27,6 +28,10 @@ func (u UserMock) GetGroups() []string {
 	return u.ReturnGroups
 }
 
+func (u UserMock) GetUsername() string {
+	return u.ReturnUsername
+}
+
 var admin = UserMock{
 	ReturnIsAdmin: true,
 }
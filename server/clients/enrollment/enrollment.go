@@ -0,0 +1,241 @@
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/share/query"
+	"github.com/riportdev/riport/share/random"
+	"github.com/riportdev/riport/share/types"
+)
+
+const (
+	DefaultExpiry = 24 * time.Hour
+	tokenIDLength = 12
+)
+
+var ErrInvalidToken = errors.New("invalid enrollment token")
+
+var (
+	supportedFilters = map[string]bool{
+		"id":          true,
+		"description": true,
+		"created_by":  true,
+		"group_id":    true,
+	}
+	supportedSorts = map[string]bool{
+		"created_at": true,
+		"expires_at": true,
+		"uses":       true,
+	}
+	listDefaultSort = map[string][]string{
+		"sort": {"-created_at"},
+	}
+)
+
+type Provider interface {
+	Get(context.Context, string) (*Token, error)
+	List(context.Context, *query.ListOptions) ([]*Token, error)
+	Count(context.Context, *query.ListOptions) (int, error)
+	Insert(context.Context, *Token) error
+	Revoke(context.Context, string, time.Time) error
+	Enroll(context.Context, *Enrollment) (bool, error)
+	ListEnrollments(context.Context, string) ([]*Enrollment, error)
+}
+
+type Manager struct {
+	provider Provider
+	now      func() time.Time
+}
+
+func New(db *sqlx.DB) *Manager {
+	return &Manager{
+		provider: newSQLiteProvider(db),
+		now:      time.Now,
+	}
+}
+
+func (m *Manager) List(ctx context.Context, req *http.Request) (*api.SuccessPayload, error) {
+	options := query.NewOptions(req, listDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, supportedSorts, supportedFilters, nil, &query.PaginationConfig{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.provider.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := m.provider.Count(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SuccessPayload{
+		Data: entries,
+		Meta: api.NewMeta(count),
+	}, nil
+}
+
+// Get returns a token with the clients that enrolled with it or nil if it doesn't exist
+func (m *Manager) Get(ctx context.Context, id string) (*Token, error) {
+	t, err := m.provider.Get(ctx, id)
+	if err != nil || t == nil {
+		return nil, err
+	}
+
+	t.Enrollments, err = m.provider.ListEnrollments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Create stores a new token, the returned token contains the value to configure on the clients.
+// The value is not stored and can't be retrieved later.
+func (m *Manager) Create(ctx context.Context, t *Token, username string) (*Token, error) {
+	now := m.now().UTC()
+	err := m.validate(t, now)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	secretHash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ID = random.AlphaNum(tokenIDLength)
+	t.SecretHash = string(secretHash)
+	t.CreatedAt = now
+	t.CreatedBy = username
+	t.ExpiresAt = t.ExpiresAt.UTC()
+	t.Uses = 0
+	t.RevokedAt = nil
+	t.Enrollments = nil
+
+	err = m.provider.Insert(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Token = t.ID + ":" + secret
+	return t, nil
+}
+
+// Revoke prevents further enrollments with a token, clients that enrolled already keep their credentials
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	existing, err := m.provider.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors2.APIError{
+			Message:    fmt.Sprintf("enrollment token with id %q not found", id),
+			HTTPStatus: http.StatusNotFound,
+		}
+	}
+
+	return m.provider.Revoke(ctx, id, m.now().UTC())
+}
+
+// Authenticate checks the secret of a token and that it can still be used
+func (m *Manager) Authenticate(ctx context.Context, id, secret string) (*Token, error) {
+	t, err := m.provider.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || bcrypt.CompareHashAndPassword([]byte(t.SecretHash), []byte(secret)) != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = t.Usable(m.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Enroll uses a token once for the given client. It fails if the token was used up, revoked or expired since
+// the client authenticated.
+func (m *Manager) Enroll(ctx context.Context, tokenID, clientID, clientAuthID, remoteIP string) error {
+	ok, err := m.provider.Enroll(ctx, &Enrollment{
+		TokenID:      tokenID,
+		ClientID:     clientID,
+		ClientAuthID: clientAuthID,
+		RemoteIP:     remoteIP,
+		EnrolledAt:   m.now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("enrollment token %s can't be used anymore", tokenID)
+	}
+	return nil
+}
+
+func (m *Manager) validate(t *Token, now time.Time) error {
+	t.Description = strings.TrimSpace(t.Description)
+
+	if t.MaxUses == 0 {
+		t.MaxUses = 1
+	}
+	if t.MaxUses < 0 {
+		return errors2.APIError{
+			Message:    "max_uses must be positive",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+
+	if t.ExpiresAt.IsZero() {
+		t.ExpiresAt = now.Add(DefaultExpiry)
+	}
+	if !t.ExpiresAt.After(now) {
+		return errors2.APIError{
+			Message:    "expires_at must be in the future",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+
+	for _, tag := range t.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors2.APIError{
+				Message:    "tags must not be empty",
+				HTTPStatus: http.StatusBadRequest,
+			}
+		}
+	}
+	for key := range t.Labels {
+		if strings.TrimSpace(key) == "" {
+			return errors2.APIError{
+				Message:    "label keys must not be empty",
+				HTTPStatus: http.StatusBadRequest,
+			}
+		}
+	}
+	if t.Tags == nil {
+		t.Tags = types.StringSlice{}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package enrollment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/db/migration/clients"
+	"github.com/riportdev/riport/db/sqlite"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func TestEnrollmentTokens(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", clients.AssetNames(), clients.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	manager := New(db)
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	token, err := manager.Create(ctx, &Token{
+		Description: " web servers ",
+		MaxUses:     2,
+		Tags:        []string{"web"},
+		Labels:      Labels{"env": "prod"},
+	}, "admin")
+	require.NoError(t, err)
+	assert.Len(t, token.ID, tokenIDLength)
+	assert.Equal(t, "web servers", token.Description)
+	assert.Equal(t, "admin", token.CreatedBy)
+	assert.Equal(t, now.Add(DefaultExpiry), token.ExpiresAt)
+	require.True(t, strings.HasPrefix(token.Token, token.ID+":"))
+	secret := strings.TrimPrefix(token.Token, token.ID+":")
+
+	_, err = manager.Create(ctx, &Token{MaxUses: -1}, "admin")
+	assertAPIError(t, http.StatusBadRequest, err)
+	_, err = manager.Create(ctx, &Token{ExpiresAt: now.Add(-time.Minute)}, "admin")
+	assertAPIError(t, http.StatusBadRequest, err)
+
+	single, err := manager.Create(ctx, &Token{ExpiresAt: now.Add(time.Hour)}, "other")
+	require.NoError(t, err)
+	assert.Equal(t, 1, single.MaxUses)
+
+	result, err := manager.List(ctx, httptest.NewRequest(http.MethodGet, "/enrollment-tokens?filter[created_by]=admin", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Meta.Count)
+	listed := result.Data.([]*Token)
+	assert.Equal(t, token.ID, listed[0].ID)
+	assert.Empty(t, listed[0].Token)
+	assert.Equal(t, Labels{"env": "prod"}, listed[0].Labels)
+
+	_, err = manager.Authenticate(ctx, token.ID, "wrong")
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = manager.Authenticate(ctx, "unknown", secret)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// the token can be used twice
+	for _, clientID := range []string{"client-1", "client-2"} {
+		_, err = manager.Authenticate(ctx, token.ID, secret)
+		require.NoError(t, err)
+		err = manager.Enroll(ctx, token.ID, clientID, clientID+"-auth", "192.0.2.1")
+		require.NoError(t, err)
+	}
+	_, err = manager.Authenticate(ctx, token.ID, secret)
+	assert.EqualError(t, err, "enrollment token "+token.ID+" is used up")
+	err = manager.Enroll(ctx, token.ID, "client-3", "client-3", "192.0.2.1")
+	assert.EqualError(t, err, "enrollment token "+token.ID+" can't be used anymore")
+
+	stored, err := manager.Get(ctx, token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Uses)
+	require.Len(t, stored.Enrollments, 2)
+	assert.Equal(t, "client-1", stored.Enrollments[0].ClientID)
+	assert.Equal(t, "client-1-auth", stored.Enrollments[0].ClientAuthID)
+	assert.Equal(t, "192.0.2.1", stored.Enrollments[0].RemoteIP)
+
+	// revoked tokens can't be used
+	err = manager.Revoke(ctx, single.ID)
+	require.NoError(t, err)
+	err = manager.Enroll(ctx, single.ID, "client-4", "client-4", "192.0.2.1")
+	assert.Error(t, err)
+	stored, err = manager.Get(ctx, single.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.RevokedAt)
+	assert.Error(t, stored.Usable(now))
+
+	err = manager.Revoke(ctx, "unknown")
+	assertAPIError(t, http.StatusNotFound, err)
+
+	// expired tokens can't be used
+	expiring, err := manager.Create(ctx, &Token{ExpiresAt: now.Add(time.Hour)}, "admin")
+	require.NoError(t, err)
+	now = now.Add(2 * time.Hour)
+	_, err = manager.Authenticate(ctx, expiring.ID, strings.TrimPrefix(expiring.Token, expiring.ID+":"))
+	assert.EqualError(t, err, "enrollment token "+expiring.ID+" is expired")
+	err = manager.Enroll(ctx, expiring.ID, "client-5", "client-5", "192.0.2.1")
+	assert.Error(t, err)
+}
+
+func assertAPIError(t *testing.T, status int, err error) {
+	t.Helper()
+	require.Error(t, err)
+	apiErr, ok := err.(errors2.APIError)
+	require.True(t, ok, "expected APIError, got %T", err)
+	assert.Equal(t, status, apiErr.HTTPStatus)
+}
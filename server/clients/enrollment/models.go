@@ -0,0 +1,79 @@
+package enrollment
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/riportdev/riport/share/types"
+)
+
+// Token allows new clients to connect once or a limited number of times and to get their own client auth credentials
+type Token struct {
+	ID          string            `json:"id" db:"id"`
+	SecretHash  string            `json:"-" db:"secret_hash"`
+	Description string            `json:"description" db:"description"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	CreatedBy   string            `json:"created_by" db:"created_by"`
+	ExpiresAt   time.Time         `json:"expires_at" db:"expires_at"`
+	MaxUses     int               `json:"max_uses" db:"max_uses"`
+	Uses        int               `json:"uses" db:"uses"`
+	RevokedAt   *time.Time        `json:"revoked_at" db:"revoked_at"`
+	Tags        types.StringSlice `json:"tags" db:"tags"`
+	Labels      Labels            `json:"labels" db:"labels"`
+	GroupID     string            `json:"group_id" db:"group_id"`
+
+	// Token is the value to configure on the client, it's only returned when the token is created
+	Token string `json:"token,omitempty" db:"-"`
+	// Enrollments are only returned for a single token
+	Enrollments []*Enrollment `json:"enrollments,omitempty" db:"-"`
+}
+
+// Usable returns an error if the token can't be used to enroll a client anymore
+func (t *Token) Usable(now time.Time) error {
+	switch {
+	case t.RevokedAt != nil:
+		return fmt.Errorf("enrollment token %s is revoked", t.ID)
+	case !now.Before(t.ExpiresAt):
+		return fmt.Errorf("enrollment token %s is expired", t.ID)
+	case t.Uses >= t.MaxUses:
+		return fmt.Errorf("enrollment token %s is used up", t.ID)
+	}
+	return nil
+}
+
+// Enrollment is a client that enrolled with a token
+type Enrollment struct {
+	TokenID      string    `json:"token_id" db:"token_id"`
+	ClientID     string    `json:"client_id" db:"client_id"`
+	ClientAuthID string    `json:"client_auth_id" db:"client_auth_id"`
+	RemoteIP     string    `json:"remote_ip" db:"remote_ip"`
+	EnrolledAt   time.Time `json:"enrolled_at" db:"enrolled_at"`
+}
+
+// Labels is used for storing the labels of a token in sqlite
+type Labels map[string]string
+
+func (l *Labels) Scan(value interface{}) error {
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	err := json.Unmarshal([]byte(valueStr), l)
+	if err != nil {
+		return fmt.Errorf("failed to decode labels: %v", err)
+	}
+	return nil
+}
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode labels: %v", err)
+	}
+	return string(b), nil
+}
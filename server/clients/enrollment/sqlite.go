@@ -0,0 +1,161 @@
+package enrollment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/query"
+)
+
+type SQLiteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+}
+
+func newSQLiteProvider(db *sqlx.DB) *SQLiteProvider {
+	return &SQLiteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+	}
+}
+
+func (p *SQLiteProvider) Get(ctx context.Context, id string) (*Token, error) {
+	t := &Token{}
+	err := p.db.GetContext(ctx, t, "SELECT * FROM enrollment_tokens WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *SQLiteProvider) List(ctx context.Context, options *query.ListOptions) ([]*Token, error) {
+	values := []*Token{}
+
+	q := "SELECT * FROM enrollment_tokens"
+	q, params := p.converter.ConvertListOptionsToQuery(options, q)
+
+	err := p.db.SelectContext(ctx, &values, q, params...)
+	if err != nil {
+		return values, err
+	}
+
+	return values, nil
+}
+
+func (p *SQLiteProvider) Count(ctx context.Context, options *query.ListOptions) (int, error) {
+	var result int
+
+	countOptions := *options
+	countOptions.Pagination = nil
+	countOptions.Sorts = nil
+	q, params := p.converter.ConvertListOptionsToQuery(&countOptions, "SELECT COUNT(*) FROM enrollment_tokens")
+
+	err := p.db.GetContext(ctx, &result, q, params...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+func (p *SQLiteProvider) Insert(ctx context.Context, t *Token) error {
+	_, err := p.db.NamedExecContext(ctx,
+		`INSERT INTO enrollment_tokens (
+			id,
+			secret_hash,
+			description,
+			created_at,
+			created_by,
+			expires_at,
+			max_uses,
+			uses,
+			revoked_at,
+			tags,
+			labels,
+			group_id
+		) VALUES (
+			:id,
+			:secret_hash,
+			:description,
+			:created_at,
+			:created_by,
+			:expires_at,
+			:max_uses,
+			:uses,
+			:revoked_at,
+			:tags,
+			:labels,
+			:group_id
+		)`,
+		t,
+	)
+
+	return err
+}
+
+func (p *SQLiteProvider) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	_, err := p.db.ExecContext(ctx, "UPDATE enrollment_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", revokedAt, id)
+	return err
+}
+
+// Enroll uses the token once and records the enrollment. It returns false without recording the enrollment if
+// the token is not usable anymore, e.g. because a concurrent enrollment used it up.
+func (p *SQLiteProvider) Enroll(ctx context.Context, e *Enrollment) (bool, error) {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE enrollment_tokens SET uses = uses + 1
+		WHERE id = ? AND revoked_at IS NULL AND expires_at > ? AND uses < max_uses`,
+		e.TokenID, e.EnrolledAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.NamedExecContext(ctx,
+		`INSERT INTO enrollments (
+			token_id,
+			client_id,
+			client_auth_id,
+			remote_ip,
+			enrolled_at
+		) VALUES (
+			:token_id,
+			:client_id,
+			:client_auth_id,
+			:remote_ip,
+			:enrolled_at
+		)`,
+		e,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (p *SQLiteProvider) ListEnrollments(ctx context.Context, tokenID string) ([]*Enrollment, error) {
+	values := []*Enrollment{}
+	err := p.db.SelectContext(ctx, &values, "SELECT * FROM enrollments WHERE token_id = ? ORDER BY enrolled_at", tokenID)
+	return values, err
+}
@@ -8,8 +8,14 @@ import (
 	"github.com/riportdev/riport/share/logger"
 )
 
+// RemoteClientChecker tells whether a client is connected to another alive node of a cluster sharing the clients DB.
+type RemoteClientChecker interface {
+	IsConnectedToOtherNode(ctx context.Context, clientID string) (bool, error)
+}
+
 // LoadInitialClients returns an initial Client Repository state populated with clients from the internal storage.
-func LoadInitialClients(ctx context.Context, p ClientStore, logger *logger.Logger) ([]*clientdata.Client, error) {
+// remoteClients may be nil if clustering is disabled.
+func LoadInitialClients(ctx context.Context, p ClientStore, remoteClients RemoteClientChecker, logger *logger.Logger) ([]*clientdata.Client, error) {
 	logger.Debugf("loading existing clients")
 
 	// setup a logger for the clients
@@ -26,12 +32,25 @@ func LoadInitialClients(ctx context.Context, p ClientStore, logger *logger.Logge
 	now := clientdata.Now()
 
 	for _, client := range all {
-		if client.IsConnected() {
-			client.SetDisconnectedAt(&now)
-			err := p.Save(ctx, client)
+		if !client.IsConnected() {
+			continue
+		}
+		client.SetDisconnectedAt(&now)
+
+		// clients connected to another node are only disconnected from this node, the stored state belongs to the other node
+		if remoteClients != nil {
+			remote, err := remoteClients.IsConnectedToOtherNode(ctx, client.GetID())
 			if err != nil {
-				return nil, fmt.Errorf("failed to save client: %v", err)
+				return nil, fmt.Errorf("failed to get node of client: %v", err)
 			}
+			if remote {
+				continue
+			}
+		}
+
+		err := p.Save(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save client: %v", err)
 		}
 	}
 
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/riportdev/riport/server/clients/clientdata"
 )
@@ -50,7 +51,7 @@ func TestGetInitState(t *testing.T) {
 			p := NewFakeClientProvider(t, &tc.expiration, tc.dbClients...)
 			defer p.Close()
 
-			gotClients, gotErr := LoadInitialClients(ctx, p, testLog)
+			gotClients, gotErr := LoadInitialClients(ctx, p, nil, testLog)
 			assert.NoError(t, gotErr)
 			assert.Len(t, gotClients, len(tc.wantRes))
 
@@ -63,3 +64,33 @@ func TestGetInitState(t *testing.T) {
 		})
 	}
 }
+
+type remoteClientCheckerMock map[string]bool
+
+func (m remoteClientCheckerMock) IsConnectedToOtherNode(ctx context.Context, clientID string) (bool, error) {
+	return m[clientID], nil
+}
+
+func TestGetInitStateInCluster(t *testing.T) {
+	ctx := context.Background()
+	local := New(t).ID("client-1").Logger(testLog).Build()
+	remote := New(t).ID("client-2").Logger(testLog).Build()
+	p := NewFakeClientProvider(t, &hour, local, remote)
+	defer p.Close()
+
+	gotClients, err := LoadInitialClients(ctx, p, remoteClientCheckerMock{"client-2": true}, testLog)
+	require.NoError(t, err)
+	require.Len(t, gotClients, 2)
+	// both are disconnected from this node
+	for _, c := range gotClients {
+		assert.False(t, c.IsConnected(), c.GetID())
+	}
+
+	// the client connected to another node stays connected for the other nodes
+	stored, err := p.get(ctx, "client-1", testLog)
+	require.NoError(t, err)
+	assert.False(t, stored.IsConnected())
+	stored, err = p.get(ctx, "client-2", testLog)
+	require.NoError(t, err)
+	assert.True(t, stored.IsConnected())
+}
@@ -159,6 +159,8 @@ func convertToSqlite(c *clientdata.Client) (res *clientSqlite) {
 			UpdatesStatus:          c.UpdatesStatus,
 			IPAddresses:            c.IPAddresses,
 			ClientConfig:           c.ClientConfiguration,
+			ConnectedAt:            c.ConnectedAt,
+			PendingAttributes:      c.PendingAttributes,
 		},
 	}
 	c.GetLock().RUnlock()
@@ -177,34 +179,36 @@ type clientSqlite struct {
 }
 
 type clientDetails struct {
-	NumCPUs                int                    `json:"num_cpus"`
-	MemoryTotal            uint64                 `json:"mem_total"`
-	Name                   string                 `json:"name"`
-	OS                     string                 `json:"os"`
-	OSArch                 string                 `json:"os_arch"`
-	OSFamily               string                 `json:"os_family"`
-	OSKernel               string                 `json:"os_kernel"`
-	OSFullName             string                 `json:"os_full_name"`
-	OSVersion              string                 `json:"os_version"`
-	OSVirtualizationSystem string                 `json:"os_virtualization_system"`
-	OSVirtualizationRole   string                 `json:"os_virtualization_role"`
-	CPUFamily              string                 `json:"cpu_family"`
-	CPUModel               string                 `json:"cpu_model"`
-	CPUModelName           string                 `json:"cpu_model_name"`
-	CPUVendor              string                 `json:"cpu_vendor"`
-	Timezone               string                 `json:"timezone"`
-	Hostname               string                 `json:"hostname"`
-	Version                string                 `json:"version"`
-	Address                string                 `json:"address"`
-	IPv4                   []string               `json:"ipv4"`
-	IPv6                   []string               `json:"ipv6"`
-	Tags                   []string               `json:"tags"`
-	Labels                 map[string]string      `json:"labels"`
-	Tunnels                []*clienttunnel.Tunnel `json:"tunnels"`
-	AllowedUserGroups      []string               `json:"allowed_user_groups"`
-	UpdatesStatus          *models.UpdatesStatus  `json:"updates_status"`
-	IPAddresses            *models.IPAddresses    `json:"ext_ip_addresses"`
-	ClientConfig           *chshare.Config        `json:"client_configuration"`
+	NumCPUs                int                          `json:"num_cpus"`
+	MemoryTotal            uint64                       `json:"mem_total"`
+	Name                   string                       `json:"name"`
+	OS                     string                       `json:"os"`
+	OSArch                 string                       `json:"os_arch"`
+	OSFamily               string                       `json:"os_family"`
+	OSKernel               string                       `json:"os_kernel"`
+	OSFullName             string                       `json:"os_full_name"`
+	OSVersion              string                       `json:"os_version"`
+	OSVirtualizationSystem string                       `json:"os_virtualization_system"`
+	OSVirtualizationRole   string                       `json:"os_virtualization_role"`
+	CPUFamily              string                       `json:"cpu_family"`
+	CPUModel               string                       `json:"cpu_model"`
+	CPUModelName           string                       `json:"cpu_model_name"`
+	CPUVendor              string                       `json:"cpu_vendor"`
+	Timezone               string                       `json:"timezone"`
+	Hostname               string                       `json:"hostname"`
+	Version                string                       `json:"version"`
+	Address                string                       `json:"address"`
+	IPv4                   []string                     `json:"ipv4"`
+	IPv6                   []string                     `json:"ipv6"`
+	Tags                   []string                     `json:"tags"`
+	Labels                 map[string]string            `json:"labels"`
+	Tunnels                []*clienttunnel.Tunnel       `json:"tunnels"`
+	AllowedUserGroups      []string                     `json:"allowed_user_groups"`
+	UpdatesStatus          *models.UpdatesStatus        `json:"updates_status"`
+	IPAddresses            *models.IPAddresses          `json:"ext_ip_addresses"`
+	ClientConfig           *chshare.Config              `json:"client_configuration"`
+	ConnectedAt            *time.Time                   `json:"connected_at,omitempty"`
+	PendingAttributes      []models.AttributesOperation `json:"pending_attributes,omitempty"`
 }
 
 func (d *clientDetails) Scan(value interface{}) error {
@@ -266,6 +270,8 @@ func (s *clientSqlite) convert(l *logger.Logger) (res *clientdata.Client) {
 		UpdatesStatus:          d.UpdatesStatus,
 		IPAddresses:            d.IPAddresses,
 		ClientConfiguration:    d.ClientConfig,
+		ConnectedAt:            d.ConnectedAt,
+		PendingAttributes:      d.PendingAttributes,
 		Logger:                 l,
 	}
 	if s.DisconnectedAt.Valid {
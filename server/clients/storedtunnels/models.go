@@ -6,9 +6,17 @@ import (
 	"github.com/riportdev/riport/share/types"
 )
 
+const (
+	HealthRunning            = "running"
+	HealthFailed             = "failed"
+	HealthStopped            = "stopped"
+	HealthClientDisconnected = "client disconnected"
+)
+
 type StoredTunnel struct {
 	ID             string            `json:"id" db:"id"`
 	ClientID       string            `json:"-" db:"client_id"`
+	ClientGroupID  string            `json:"client_group_id,omitempty" db:"client_group_id"`
 	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
 	Name           string            `json:"name" db:"name"`
 	Scheme         *string           `json:"scheme" db:"scheme"`
@@ -17,4 +25,19 @@ type StoredTunnel struct {
 	PublicPort     *int              `json:"public_port" db:"public_port"`
 	ACL            *string           `json:"acl" db:"acl"`
 	FurtherOptions *types.JSONString `json:"further_options" db:"further_options"`
+	// Persistent tunnels are started by the server whenever the client connects
+	Persistent bool `json:"persistent" db:"persistent"`
+	// Owner is the user who created or last changed the tunnel, persistent tunnels read the vault on its behalf
+	Owner  string    `json:"owner" db:"owner"`
+	Health []*Health `json:"health,omitempty" db:"-"`
+}
+
+// Health is the state of a persistent tunnel on a client, a tunnel of a client group has one per member client
+type Health struct {
+	ClientID   string    `json:"client_id"`
+	Status     string    `json:"status"`
+	TunnelID   string    `json:"tunnel_id,omitempty"`
+	PublicPort int       `json:"public_port,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
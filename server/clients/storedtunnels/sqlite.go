@@ -2,6 +2,8 @@ package storedtunnels
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
@@ -25,6 +27,7 @@ func (p *SQLiteProvider) Insert(ctx context.Context, t *StoredTunnel) error {
 		`INSERT INTO stored_tunnels (
 			id,
 			client_id,
+			client_group_id,
 			created_at,
 			name,
 			scheme,
@@ -32,10 +35,13 @@ func (p *SQLiteProvider) Insert(ctx context.Context, t *StoredTunnel) error {
 			remote_port,
 			public_port,
 			acl,
-			further_options
+			further_options,
+			persistent,
+			owner
 		) VALUES (
 			:id,
 			:client_id,
+			:client_group_id,
 			:created_at,
 			:name,
 			:scheme,
@@ -43,7 +49,9 @@ func (p *SQLiteProvider) Insert(ctx context.Context, t *StoredTunnel) error {
 			:remote_port,
 			:public_port,
 			:acl,
-			:further_options
+			:further_options,
+			:persistent,
+			:owner
 		)`,
 		t,
 	)
@@ -60,8 +68,10 @@ func (p *SQLiteProvider) Update(ctx context.Context, t *StoredTunnel) error {
 			remote_port = :remote_port,
 			public_port = :public_port,
 			acl = :acl,
-			further_options = :further_options
-		WHERE client_id = :client_id AND id = :id`,
+			further_options = :further_options,
+			persistent = :persistent,
+			owner = :owner
+		WHERE client_id = :client_id AND client_group_id = :client_group_id AND id = :id`,
 		t,
 	)
 
@@ -69,10 +79,88 @@ func (p *SQLiteProvider) Update(ctx context.Context, t *StoredTunnel) error {
 }
 
 func (p *SQLiteProvider) List(ctx context.Context, clientID string, options *query.ListOptions) ([]*StoredTunnel, error) {
+	return p.list(ctx, "client_id", clientID, options)
+}
+
+func (p *SQLiteProvider) Count(ctx context.Context, clientID string, options *query.ListOptions) (int, error) {
+	return p.count(ctx, "client_id", clientID, options)
+}
+
+func (p *SQLiteProvider) Delete(ctx context.Context, clientID, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM stored_tunnels WHERE client_id = ? AND id = ?", clientID, id)
+	return err
+}
+
+func (p *SQLiteProvider) Get(ctx context.Context, id string) (*StoredTunnel, error) {
+	t := &StoredTunnel{}
+	err := p.db.GetContext(ctx, t, "SELECT * FROM stored_tunnels WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *SQLiteProvider) ListForGroup(ctx context.Context, groupID string, options *query.ListOptions) ([]*StoredTunnel, error) {
+	return p.list(ctx, "client_group_id", groupID, options)
+}
+
+func (p *SQLiteProvider) CountForGroup(ctx context.Context, groupID string, options *query.ListOptions) (int, error) {
+	return p.count(ctx, "client_group_id", groupID, options)
+}
+
+func (p *SQLiteProvider) DeleteForGroup(ctx context.Context, groupID, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM stored_tunnels WHERE client_group_id = ? AND id = ?", groupID, id)
+	return err
+}
+
+// ListPersistent returns the persistent tunnels of the client and of the given client groups
+func (p *SQLiteProvider) ListPersistent(ctx context.Context, clientID string, groupIDs []string) ([]*StoredTunnel, error) {
 	values := []*StoredTunnel{}
 
-	q := "SELECT * FROM stored_tunnels WHERE client_id = ?"
+	q := "SELECT * FROM stored_tunnels WHERE persistent AND (client_id = ?"
 	params := []interface{}{clientID}
+	if len(groupIDs) > 0 {
+		q += " OR client_group_id IN (?" + strings.Repeat(", ?", len(groupIDs)-1) + ")"
+		for _, id := range groupIDs {
+			params = append(params, id)
+		}
+	}
+	q += ") ORDER BY created_at"
+
+	err := p.db.SelectContext(ctx, &values, q, params...)
+	if err != nil {
+		return values, err
+	}
+
+	return values, nil
+}
+
+// GetPublicPort returns the port allocated to a persistent tunnel without public port on the client or 0
+func (p *SQLiteProvider) GetPublicPort(ctx context.Context, storedTunnelID, clientID string) (int, error) {
+	var port int
+	err := p.db.GetContext(ctx, &port, "SELECT public_port FROM stored_tunnel_ports WHERE stored_tunnel_id = ? AND client_id = ?", storedTunnelID, clientID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return port, err
+}
+
+func (p *SQLiteProvider) SavePublicPort(ctx context.Context, storedTunnelID, clientID string, port int) error {
+	_, err := p.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO stored_tunnel_ports (stored_tunnel_id, client_id, public_port) VALUES (?, ?, ?)",
+		storedTunnelID, clientID, port,
+	)
+	return err
+}
+
+func (p *SQLiteProvider) list(ctx context.Context, column, value string, options *query.ListOptions) ([]*StoredTunnel, error) {
+	values := []*StoredTunnel{}
+
+	q := "SELECT * FROM stored_tunnels WHERE " + column + " = ?"
+	params := []interface{}{value}
 
 	q, params = p.converter.AppendOptionsToQuery(options, q, params)
 
@@ -84,11 +172,11 @@ func (p *SQLiteProvider) List(ctx context.Context, clientID string, options *que
 	return values, nil
 }
 
-func (p *SQLiteProvider) Count(ctx context.Context, clientID string, options *query.ListOptions) (int, error) {
+func (p *SQLiteProvider) count(ctx context.Context, column, value string, options *query.ListOptions) (int, error) {
 	var result int
 
-	q := "SELECT COUNT(*) FROM stored_tunnels WHERE client_id = ?"
-	params := []interface{}{clientID}
+	q := "SELECT COUNT(*) FROM stored_tunnels WHERE " + column + " = ?"
+	params := []interface{}{value}
 
 	countOptions := *options
 	countOptions.Pagination = nil
@@ -101,8 +189,3 @@ func (p *SQLiteProvider) Count(ctx context.Context, clientID string, options *qu
 
 	return result, nil
 }
-
-func (p *SQLiteProvider) Delete(ctx context.Context, clientID, id string) error {
-	_, err := p.db.ExecContext(ctx, "DELETE FROM stored_tunnels WHERE client_id = ? AND id = ?", clientID, id)
-	return err
-}
@@ -2,6 +2,8 @@ package storedtunnels
 
 import (
 	"context"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -33,15 +35,28 @@ type Provider interface {
 	Update(context.Context, *StoredTunnel) error
 	List(context.Context, string, *query.ListOptions) ([]*StoredTunnel, error)
 	Count(context.Context, string, *query.ListOptions) (int, error)
+	Get(context.Context, string) (*StoredTunnel, error)
+	DeleteForGroup(context.Context, string, string) error
+	ListForGroup(context.Context, string, *query.ListOptions) ([]*StoredTunnel, error)
+	CountForGroup(context.Context, string, *query.ListOptions) (int, error)
+	ListPersistent(context.Context, string, []string) ([]*StoredTunnel, error)
+	GetPublicPort(context.Context, string, string) (int, error)
+	SavePublicPort(context.Context, string, string, int) error
 }
 
 type Manager struct {
 	provider Provider
+
+	healthMtx sync.RWMutex
+	// health of persistent tunnels by stored tunnel id and client id, it's not persisted as the tunnels are started
+	// again after a server restart
+	health map[string]map[string]*Health
 }
 
 func New(db *sqlx.DB) *Manager {
 	return &Manager{
 		provider: newSQLiteProvider(db),
+		health:   make(map[string]map[string]*Health),
 	}
 }
 
@@ -64,6 +79,7 @@ func (m *Manager) List(ctx context.Context, options *query.ListOptions, clientID
 	if err != nil {
 		return nil, err
 	}
+	m.addHealth(entries)
 
 	return &api.SuccessPayload{
 		Data: entries,
@@ -79,6 +95,7 @@ func (m *Manager) Create(ctx context.Context, clientID string, t *StoredTunnel)
 	t.ID = id
 	t.CreatedAt = time.Now()
 	t.ClientID = clientID
+	t.ClientGroupID = ""
 
 	err = m.provider.Insert(ctx, t)
 	if err != nil {
@@ -90,6 +107,7 @@ func (m *Manager) Create(ctx context.Context, clientID string, t *StoredTunnel)
 
 func (m *Manager) Update(ctx context.Context, clientID string, t *StoredTunnel) (*StoredTunnel, error) {
 	t.ClientID = clientID
+	t.ClientGroupID = ""
 
 	err := m.provider.Update(ctx, t)
 	if err != nil {
@@ -99,6 +117,153 @@ func (m *Manager) Update(ctx context.Context, clientID string, t *StoredTunnel)
 	return t, nil
 }
 
+// Get returns a stored tunnel of a client or client group, nil if it doesn't exist
+func (m *Manager) Get(ctx context.Context, id string) (*StoredTunnel, error) {
+	return m.provider.Get(ctx, id)
+}
+
 func (m *Manager) Delete(ctx context.Context, clientID, id string) error {
 	return m.provider.Delete(ctx, clientID, id)
 }
+
+func (m *Manager) ListForGroup(ctx context.Context, options *query.ListOptions, groupID string) (*api.SuccessPayload, error) {
+	err := query.ValidateListOptions(options, supportedSorts, supportedFilters, nil, &query.PaginationConfig{
+		DefaultLimit: 10,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.provider.ListForGroup(ctx, groupID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := m.provider.CountForGroup(ctx, groupID, options)
+	if err != nil {
+		return nil, err
+	}
+	m.addHealth(entries)
+
+	return &api.SuccessPayload{
+		Data: entries,
+		Meta: api.NewMeta(count),
+	}, nil
+}
+
+// CreateForGroup stores a tunnel for all clients of a client group
+func (m *Manager) CreateForGroup(ctx context.Context, groupID string, t *StoredTunnel) (*StoredTunnel, error) {
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	t.ID = id
+	t.CreatedAt = time.Now()
+	t.ClientID = ""
+	t.ClientGroupID = groupID
+
+	err = m.provider.Insert(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (m *Manager) UpdateForGroup(ctx context.Context, groupID string, t *StoredTunnel) (*StoredTunnel, error) {
+	t.ClientID = ""
+	t.ClientGroupID = groupID
+
+	err := m.provider.Update(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (m *Manager) DeleteForGroup(ctx context.Context, groupID, id string) error {
+	return m.provider.DeleteForGroup(ctx, groupID, id)
+}
+
+// ListPersistent returns the persistent tunnels to start on a client which belongs to the given client groups
+func (m *Manager) ListPersistent(ctx context.Context, clientID string, groupIDs []string) ([]*StoredTunnel, error) {
+	return m.provider.ListPersistent(ctx, clientID, groupIDs)
+}
+
+// GetPublicPort returns the port the tunnel used on the client before, 0 if it wasn't started yet
+func (m *Manager) GetPublicPort(ctx context.Context, t *StoredTunnel, clientID string) (int, error) {
+	if t.PublicPort != nil {
+		return *t.PublicPort, nil
+	}
+	return m.provider.GetPublicPort(ctx, t.ID, clientID)
+}
+
+// SavePublicPort keeps the port allocated to a tunnel without public port to reuse it when the tunnel is started again
+func (m *Manager) SavePublicPort(ctx context.Context, t *StoredTunnel, clientID string, port int) error {
+	if t.PublicPort != nil {
+		return nil
+	}
+	return m.provider.SavePublicPort(ctx, t.ID, clientID, port)
+}
+
+func (m *Manager) SetHealth(storedTunnelID string, h *Health) {
+	m.healthMtx.Lock()
+	defer m.healthMtx.Unlock()
+
+	h.UpdatedAt = time.Now()
+	if m.health[storedTunnelID] == nil {
+		m.health[storedTunnelID] = make(map[string]*Health)
+	}
+	m.health[storedTunnelID][h.ClientID] = h
+}
+
+// GetHealth returns the health of a persistent tunnel on all clients it was started on
+func (m *Manager) GetHealth(storedTunnelID string) []*Health {
+	m.healthMtx.RLock()
+	defer m.healthMtx.RUnlock()
+
+	var result []*Health
+	for _, h := range m.health[storedTunnelID] {
+		hCopy := *h
+		result = append(result, &hCopy)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ClientID < result[j].ClientID
+	})
+	return result
+}
+
+// SetClientDisconnected marks the persistent tunnels of a client as disconnected
+func (m *Manager) SetClientDisconnected(clientID string) {
+	m.healthMtx.Lock()
+	defer m.healthMtx.Unlock()
+
+	for _, byClient := range m.health {
+		if h, ok := byClient[clientID]; ok {
+			byClient[clientID] = &Health{
+				ClientID:   clientID,
+				Status:     HealthClientDisconnected,
+				PublicPort: h.PublicPort,
+				UpdatedAt:  time.Now(),
+			}
+		}
+	}
+}
+
+// DeleteHealth forgets the health of a tunnel after it was stopped
+func (m *Manager) DeleteHealth(storedTunnelID string) {
+	m.healthMtx.Lock()
+	defer m.healthMtx.Unlock()
+
+	delete(m.health, storedTunnelID)
+}
+
+func (m *Manager) addHealth(entries []*StoredTunnel) {
+	for _, t := range entries {
+		if t.Persistent {
+			t.Health = m.GetHealth(t.ID)
+		}
+	}
+}
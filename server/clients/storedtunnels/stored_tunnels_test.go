@@ -54,3 +54,86 @@ func TestStoredTunnels(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, results.Meta.Count)
 }
+
+func TestPersistentStoredTunnels(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", clients.AssetNames(), clients.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	options := &query.ListOptions{}
+	manager := New(db)
+
+	clientTunnel, err := manager.Create(ctx, "client-1", &StoredTunnel{Name: "ssh", Persistent: true})
+	require.NoError(t, err)
+	groupTunnel, err := manager.CreateForGroup(ctx, "group-1", &StoredTunnel{Name: "rdp", Persistent: true})
+	require.NoError(t, err)
+	_, err = manager.CreateForGroup(ctx, "group-1", &StoredTunnel{Name: "bookmark"})
+	require.NoError(t, err)
+	_, err = manager.CreateForGroup(ctx, "group-2", &StoredTunnel{Name: "other", Persistent: true})
+	require.NoError(t, err)
+
+	// group tunnels are not listed for the client
+	results, err := manager.List(ctx, options, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, results.Meta.Count)
+	results, err = manager.ListForGroup(ctx, options, "group-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, results.Meta.Count)
+
+	persistent, err := manager.ListPersistent(ctx, "client-1", []string{"group-1"})
+	require.NoError(t, err)
+	require.Len(t, persistent, 2)
+	assert.Equal(t, clientTunnel.ID, persistent[0].ID)
+	assert.Equal(t, groupTunnel.ID, persistent[1].ID)
+
+	persistent, err = manager.ListPersistent(ctx, "client-2", nil)
+	require.NoError(t, err)
+	assert.Len(t, persistent, 0)
+
+	// the allocated port is kept per client
+	port, err := manager.GetPublicPort(ctx, groupTunnel, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, port)
+	require.NoError(t, manager.SavePublicPort(ctx, groupTunnel, "client-1", 20001))
+	require.NoError(t, manager.SavePublicPort(ctx, groupTunnel, "client-2", 20002))
+	port, err = manager.GetPublicPort(ctx, groupTunnel, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, 20001, port)
+
+	// the public port of the stored tunnel is used if set
+	publicPort := 2222
+	clientTunnel.PublicPort = &publicPort
+	_, err = manager.Update(ctx, "client-1", clientTunnel)
+	require.NoError(t, err)
+	port, err = manager.GetPublicPort(ctx, clientTunnel, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2222, port)
+
+	manager.SetHealth(groupTunnel.ID, &Health{ClientID: "client-2", Status: HealthFailed, Error: "port in use"})
+	manager.SetHealth(groupTunnel.ID, &Health{ClientID: "client-1", Status: HealthRunning, TunnelID: "1", PublicPort: 20001})
+	manager.SetClientDisconnected("client-2")
+
+	results, err = manager.ListForGroup(ctx, &query.ListOptions{Sorts: []query.SortOption{{Column: "name", IsASC: true}}}, "group-1")
+	require.NoError(t, err)
+	entries := results.Data.([]*StoredTunnel)
+	require.Len(t, entries, 2)
+	assert.Nil(t, entries[0].Health)
+	require.Len(t, entries[1].Health, 2)
+	assert.Equal(t, "client-1", entries[1].Health[0].ClientID)
+	assert.Equal(t, HealthRunning, entries[1].Health[0].Status)
+	assert.Equal(t, 20001, entries[1].Health[0].PublicPort)
+	assert.Equal(t, "client-2", entries[1].Health[1].ClientID)
+	assert.Equal(t, HealthClientDisconnected, entries[1].Health[1].Status)
+	assert.Equal(t, "", entries[1].Health[1].Error)
+
+	// a group tunnel can't be deleted through another group
+	require.NoError(t, manager.DeleteForGroup(ctx, "group-2", groupTunnel.ID))
+	existing, err := manager.Get(ctx, groupTunnel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "group-1", existing.ClientGroupID)
+
+	require.NoError(t, manager.DeleteForGroup(ctx, "group-1", groupTunnel.ID))
+	existing, err = manager.Get(ctx, groupTunnel.ID)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
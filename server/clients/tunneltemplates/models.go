@@ -0,0 +1,24 @@
+package tunneltemplates
+
+import (
+	"time"
+
+	"github.com/riportdev/riport/share/types"
+)
+
+// TunnelTemplate is a tunnel definition that can be started on many clients at once
+type TunnelTemplate struct {
+	ID                 string            `json:"id" db:"id"`
+	Name               string            `json:"name" db:"name"`
+	CreatedAt          time.Time         `json:"created_at" db:"created_at"`
+	CreatedBy          string            `json:"created_by" db:"created_by"`
+	Protocol           string            `json:"protocol" db:"protocol"`
+	Scheme             *string           `json:"scheme" db:"scheme"`
+	RemoteIP           *string           `json:"remote_ip" db:"remote_ip"`
+	RemotePort         *int              `json:"remote_port" db:"remote_port"`
+	ACL                *string           `json:"acl" db:"acl"`
+	IdleTimeoutMinutes *int              `json:"idle_timeout_minutes" db:"idle_timeout_minutes"`
+	HTTPProxy          bool              `json:"http_proxy" db:"http_proxy"`
+	HostHeader         string            `json:"host_header" db:"host_header"`
+	FurtherOptions     *types.JSONString `json:"further_options" db:"further_options"`
+}
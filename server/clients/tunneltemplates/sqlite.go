@@ -0,0 +1,138 @@
+package tunneltemplates
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/query"
+)
+
+type SQLiteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+}
+
+func newSQLiteProvider(db *sqlx.DB) *SQLiteProvider {
+	return &SQLiteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+	}
+}
+
+func (p *SQLiteProvider) Get(ctx context.Context, id string) (*TunnelTemplate, error) {
+	t := &TunnelTemplate{}
+	err := p.db.GetContext(ctx, t, "SELECT * FROM tunnel_templates WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *SQLiteProvider) GetByName(ctx context.Context, name string) (*TunnelTemplate, error) {
+	t := &TunnelTemplate{}
+	err := p.db.GetContext(ctx, t, "SELECT * FROM tunnel_templates WHERE name = ?", name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (p *SQLiteProvider) List(ctx context.Context, options *query.ListOptions) ([]*TunnelTemplate, error) {
+	values := []*TunnelTemplate{}
+
+	q := "SELECT * FROM tunnel_templates"
+	q, params := p.converter.ConvertListOptionsToQuery(options, q)
+
+	err := p.db.SelectContext(ctx, &values, q, params...)
+	if err != nil {
+		return values, err
+	}
+
+	return values, nil
+}
+
+func (p *SQLiteProvider) Count(ctx context.Context, options *query.ListOptions) (int, error) {
+	var result int
+
+	countOptions := *options
+	countOptions.Pagination = nil
+	countOptions.Sorts = nil
+	q, params := p.converter.ConvertListOptionsToQuery(&countOptions, "SELECT COUNT(*) FROM tunnel_templates")
+
+	err := p.db.GetContext(ctx, &result, q, params...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+func (p *SQLiteProvider) Insert(ctx context.Context, t *TunnelTemplate) error {
+	_, err := p.db.NamedExecContext(ctx,
+		`INSERT INTO tunnel_templates (
+			id,
+			name,
+			created_at,
+			created_by,
+			protocol,
+			scheme,
+			remote_ip,
+			remote_port,
+			acl,
+			idle_timeout_minutes,
+			http_proxy,
+			host_header,
+			further_options
+		) VALUES (
+			:id,
+			:name,
+			:created_at,
+			:created_by,
+			:protocol,
+			:scheme,
+			:remote_ip,
+			:remote_port,
+			:acl,
+			:idle_timeout_minutes,
+			:http_proxy,
+			:host_header,
+			:further_options
+		)`,
+		t,
+	)
+
+	return err
+}
+
+func (p *SQLiteProvider) Update(ctx context.Context, t *TunnelTemplate) error {
+	_, err := p.db.NamedExecContext(ctx,
+		`UPDATE tunnel_templates SET
+			name = :name,
+			protocol = :protocol,
+			scheme = :scheme,
+			remote_ip = :remote_ip,
+			remote_port = :remote_port,
+			acl = :acl,
+			idle_timeout_minutes = :idle_timeout_minutes,
+			http_proxy = :http_proxy,
+			host_header = :host_header,
+			further_options = :further_options
+		WHERE id = :id`,
+		t,
+	)
+
+	return err
+}
+
+func (p *SQLiteProvider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM tunnel_templates WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,183 @@
+package tunneltemplates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/server/api"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/share/query"
+	"github.com/riportdev/riport/share/random"
+)
+
+var (
+	supportedFilters = map[string]bool{
+		"name":        true,
+		"protocol":    true,
+		"scheme":      true,
+		"remote_port": true,
+		"created_by":  true,
+	}
+	supportedSorts = map[string]bool{
+		"name":        true,
+		"created_at":  true,
+		"protocol":    true,
+		"scheme":      true,
+		"remote_port": true,
+	}
+	listDefaultSort = map[string][]string{
+		"sort": {"name"},
+	}
+)
+
+type Provider interface {
+	Get(context.Context, string) (*TunnelTemplate, error)
+	GetByName(context.Context, string) (*TunnelTemplate, error)
+	List(context.Context, *query.ListOptions) ([]*TunnelTemplate, error)
+	Count(context.Context, *query.ListOptions) (int, error)
+	Insert(context.Context, *TunnelTemplate) error
+	Update(context.Context, *TunnelTemplate) error
+	Delete(context.Context, string) error
+}
+
+type Manager struct {
+	provider Provider
+}
+
+func New(db *sqlx.DB) *Manager {
+	return &Manager{
+		provider: newSQLiteProvider(db),
+	}
+}
+
+func (m *Manager) List(ctx context.Context, req *http.Request) (*api.SuccessPayload, error) {
+	options := query.NewOptions(req, listDefaultSort, nil, nil)
+	err := query.ValidateListOptions(options, supportedSorts, supportedFilters, nil, &query.PaginationConfig{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.provider.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := m.provider.Count(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SuccessPayload{
+		Data: entries,
+		Meta: api.NewMeta(count),
+	}, nil
+}
+
+// Get returns a template or nil if it doesn't exist
+func (m *Manager) Get(ctx context.Context, id string) (*TunnelTemplate, error) {
+	return m.provider.Get(ctx, id)
+}
+
+func (m *Manager) Create(ctx context.Context, t *TunnelTemplate, username string) (*TunnelTemplate, error) {
+	err := m.validate(ctx, t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	t.ID, err = random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedAt = time.Now()
+	t.CreatedBy = username
+
+	err = m.provider.Insert(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (m *Manager) Update(ctx context.Context, id string, t *TunnelTemplate) (*TunnelTemplate, error) {
+	existing, err := m.provider.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors2.APIError{
+			Message:    fmt.Sprintf("tunnel template with id %q not found", id),
+			HTTPStatus: http.StatusNotFound,
+		}
+	}
+
+	err = m.validate(ctx, t, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ID = id
+	t.CreatedAt = existing.CreatedAt
+	t.CreatedBy = existing.CreatedBy
+
+	err = m.provider.Update(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	existing, err := m.provider.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors2.APIError{
+			Message:    fmt.Sprintf("tunnel template with id %q not found", id),
+			HTTPStatus: http.StatusNotFound,
+		}
+	}
+
+	return m.provider.Delete(ctx, id)
+}
+
+// validate checks the fields stored with the template, the tunnel options are checked by the api as on tunnel creation
+func (m *Manager) validate(ctx context.Context, t *TunnelTemplate, existingID string) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors2.APIError{
+			Message:    "name is required",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+
+	sameName, err := m.provider.GetByName(ctx, t.Name)
+	if err != nil {
+		return err
+	}
+	if sameName != nil && sameName.ID != existingID {
+		return errors2.APIError{
+			Message:    fmt.Sprintf("another tunnel template with the same name %q exists", t.Name),
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+
+	if t.IdleTimeoutMinutes != nil && *t.IdleTimeoutMinutes < 0 {
+		return errors2.APIError{
+			Message:    "idle_timeout_minutes must not be negative",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package tunneltemplates
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/db/migration/clients"
+	"github.com/riportdev/riport/db/sqlite"
+	errors2 "github.com/riportdev/riport/server/api/errors"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func TestTunnelTemplates(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", clients.AssetNames(), clients.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	manager := New(db)
+
+	port := 22
+	acl := "192.0.2.0/24"
+	ssh, err := manager.Create(ctx, &TunnelTemplate{
+		Name:       " ssh ",
+		Protocol:   "tcp",
+		RemotePort: &port,
+		ACL:        &acl,
+	}, "admin")
+	require.NoError(t, err)
+	assert.NotEmpty(t, ssh.ID)
+	assert.Equal(t, "ssh", ssh.Name)
+	assert.Equal(t, "admin", ssh.CreatedBy)
+
+	_, err = manager.Create(ctx, &TunnelTemplate{Name: "ssh"}, "admin")
+	assertAPIError(t, http.StatusConflict, err)
+
+	_, err = manager.Create(ctx, &TunnelTemplate{Name: ""}, "admin")
+	assertAPIError(t, http.StatusBadRequest, err)
+
+	_, err = manager.Create(ctx, &TunnelTemplate{Name: "web", HTTPProxy: true}, "other")
+	require.NoError(t, err)
+
+	result, err := manager.List(ctx, httptest.NewRequest(http.MethodGet, "/tunnel-templates", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Meta.Count)
+	templates := result.Data.([]*TunnelTemplate)
+	assert.Equal(t, "ssh", templates[0].Name)
+	assert.Equal(t, 22, *templates[0].RemotePort)
+	assert.Equal(t, "192.0.2.0/24", *templates[0].ACL)
+
+	result, err = manager.List(ctx, httptest.NewRequest(http.MethodGet, "/tunnel-templates?filter[created_by]=other", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Meta.Count)
+
+	// the name of another template can't be taken
+	_, err = manager.Update(ctx, ssh.ID, &TunnelTemplate{Name: "web"})
+	assertAPIError(t, http.StatusConflict, err)
+
+	updated, err := manager.Update(ctx, ssh.ID, &TunnelTemplate{Name: "ssh", Protocol: "tcp", RemotePort: &port})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", updated.CreatedBy)
+	assert.True(t, ssh.CreatedAt.Equal(updated.CreatedAt))
+
+	stored, err := manager.Get(ctx, ssh.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.ACL)
+
+	_, err = manager.Update(ctx, "unknown", &TunnelTemplate{Name: "unknown"})
+	assertAPIError(t, http.StatusNotFound, err)
+
+	err = manager.Delete(ctx, ssh.ID)
+	require.NoError(t, err)
+	err = manager.Delete(ctx, ssh.ID)
+	assertAPIError(t, http.StatusNotFound, err)
+
+	stored, err = manager.Get(ctx, ssh.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func assertAPIError(t *testing.T, status int, err error) {
+	t.Helper()
+	require.Error(t, err)
+	apiErr, ok := err.(errors2.APIError)
+	require.True(t, ok, "expected APIError, got %T", err)
+	assert.Equal(t, status, apiErr.HTTPStatus)
+}
@@ -0,0 +1,263 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	clustermigration "github.com/riportdev/riport/db/migration/cluster"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/share/logger"
+)
+
+const leaderLease = "leader"
+
+// Node is this riportd instance in a cluster of instances sharing a database. It records the clients connected to it
+// and takes part in the election of the leader that runs the tasks which must run only once in the cluster.
+// All methods can be called on a nil node, it behaves like a single instance that is always the leader.
+type Node struct {
+	log      *logger.Logger
+	config   Config
+	provider Provider
+	info     *NodeInfo
+	leader   atomic.Bool
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func New(log *logger.Logger, config Config, options sqlite.DataSourceOptions) (*Node, error) {
+	db, err := sqlite.New(config.DB, clustermigration.AssetNames(), clustermigration.Asset, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster DB instance: %v", err)
+	}
+	return newNode(log, config, NewSqliteProvider(db)), nil
+}
+
+func newNode(log *logger.Logger, config Config, provider Provider) *Node {
+	return &Node{
+		log:      log,
+		config:   config,
+		provider: provider,
+		info: &NodeInfo{
+			ID:     config.NodeID,
+			APIURL: config.APIURL,
+		},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start registers the node and keeps sending heartbeats until the context is canceled
+func (n *Node) Start(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	n.info.StartedAt = time.Now().UTC()
+	// clients owned by this node before a crash aren't connected anymore
+	err := n.provider.DeleteClientOwnersOfNode(ctx, n.info.ID)
+	if err != nil {
+		return err
+	}
+	err = n.heartbeat(ctx)
+	if err != nil {
+		return err
+	}
+	n.log.Infof("Node %s joined the cluster, leader: %t", n.info.ID, n.IsLeader())
+
+	go n.run(ctx)
+	return nil
+}
+
+func (n *Node) run(ctx context.Context) {
+	tick := time.NewTicker(n.config.HeartbeatInterval)
+	defer tick.Stop()
+	defer close(n.stopped)
+	for {
+		select {
+		case <-tick.C:
+			if err := n.heartbeat(ctx); err != nil {
+				n.log.Errorf("Failed to send cluster heartbeat: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+func (n *Node) heartbeat(ctx context.Context) error {
+	now := time.Now().UTC()
+	n.info.HeartbeatAt = now
+	err := n.provider.SaveNode(ctx, n.info)
+	if err != nil {
+		n.setLeader(false)
+		return err
+	}
+
+	acquired, err := n.provider.AcquireLease(ctx, &Lease{
+		Name:      leaderLease,
+		NodeID:    n.info.ID,
+		ExpiresAt: now.Add(n.config.NodeTimeout),
+	}, now)
+	if err != nil {
+		n.setLeader(false)
+		return err
+	}
+	n.setLeader(acquired)
+	return nil
+}
+
+func (n *Node) setLeader(leader bool) {
+	if n.leader.Swap(leader) != leader {
+		if leader {
+			n.log.Infof("Node %s became the cluster leader", n.info.ID)
+		} else {
+			n.log.Infof("Node %s is no longer the cluster leader", n.info.ID)
+		}
+	}
+}
+
+// leave removes the node from the cluster, so another node can take over the leadership right away
+func (n *Node) leave() {
+	ctx := context.Background()
+	n.leader.Store(false)
+	if err := n.provider.ReleaseLease(ctx, leaderLease, n.info.ID); err != nil {
+		n.log.Errorf("Failed to release cluster leadership: %v", err)
+	}
+	if err := n.provider.DeleteClientOwnersOfNode(ctx, n.info.ID); err != nil {
+		n.log.Errorf("Failed to delete clients of node: %v", err)
+	}
+	if err := n.provider.DeleteNode(ctx, n.info.ID); err != nil {
+		n.log.Errorf("Failed to delete node: %v", err)
+	}
+	n.log.Infof("Node %s left the cluster", n.info.ID)
+}
+
+// ID returns the id of the node or an empty string if clustering is disabled
+func (n *Node) ID() string {
+	if n == nil {
+		return ""
+	}
+	return n.info.ID
+}
+
+// IsLeader returns true if this node holds the leadership or clustering is disabled
+func (n *Node) IsLeader() bool {
+	if n == nil {
+		return true
+	}
+	return n.leader.Load()
+}
+
+// ClientConnected records this node as the owner of a client
+func (n *Node) ClientConnected(ctx context.Context, clientID string) {
+	if n == nil {
+		return
+	}
+	err := n.provider.SetClientOwner(ctx, &ClientOwner{
+		ClientID:    clientID,
+		NodeID:      n.info.ID,
+		ConnectedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		n.log.Errorf("Failed to save node of client %s: %v", clientID, err)
+	}
+}
+
+// ClientDisconnected removes the ownership of a client unless it's connected to another node meanwhile
+func (n *Node) ClientDisconnected(ctx context.Context, clientID string) {
+	if n == nil {
+		return
+	}
+	err := n.provider.DeleteClientOwner(ctx, clientID, n.info.ID)
+	if err != nil {
+		n.log.Errorf("Failed to delete node of client %s: %v", clientID, err)
+	}
+}
+
+// GetRemoteOwner returns the alive node, other than this one, a client is connected to or nil
+func (n *Node) GetRemoteOwner(ctx context.Context, clientID string) (*NodeInfo, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	owner, err := n.provider.GetClientOwner(ctx, clientID)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	return n.GetRemoteNode(ctx, owner.NodeID)
+}
+
+// GetRemoteNode returns the alive node with the given id if it's not this one or nil
+func (n *Node) GetRemoteNode(ctx context.Context, nodeID string) (*NodeInfo, error) {
+	if n == nil || nodeID == n.info.ID {
+		return nil, nil
+	}
+
+	node, err := n.provider.GetNode(ctx, nodeID)
+	if err != nil || node == nil || !n.isAlive(node) {
+		return nil, err
+	}
+	return node, nil
+}
+
+// IsConnectedToOtherNode returns true if a client is connected to another alive node
+func (n *Node) IsConnectedToOtherNode(ctx context.Context, clientID string) (bool, error) {
+	owner, err := n.GetRemoteOwner(ctx, clientID)
+	return owner != nil, err
+}
+
+// Nodes returns all nodes of the cluster
+func (n *Node) Nodes(ctx context.Context) ([]*NodeStatus, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	nodes, err := n.provider.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := n.provider.CountClientsByNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lease, err := n.provider.GetLease(ctx, leaderLease)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*NodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		alive := n.isAlive(node)
+		res = append(res, &NodeStatus{
+			NodeInfo: node,
+			Alive:    alive,
+			Leader:   alive && lease != nil && lease.NodeID == node.ID && lease.ExpiresAt.After(time.Now()),
+			Self:     node.ID == n.info.ID,
+			Clients:  clients[node.ID],
+		})
+	}
+	return res, nil
+}
+
+func (n *Node) isAlive(node *NodeInfo) bool {
+	return time.Since(node.HeartbeatAt) <= n.config.NodeTimeout
+}
+
+// Close stops the heartbeats and leaves the cluster
+func (n *Node) Close() error {
+	if n == nil {
+		return nil
+	}
+	if n.info.StartedAt.IsZero() {
+		return n.provider.Close()
+	}
+
+	close(n.stop)
+	<-n.stopped
+	n.leave()
+	return n.provider.Close()
+}
@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/share/logger"
+)
+
+const testSecret = "0123456789abcdef"
+
+var testLog = logger.NewLogger("cluster-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+
+func newTestNode(t *testing.T, db, id, apiURL string) *Node {
+	n, err := New(testLog, Config{
+		Enabled: true,
+		NodeID:  id,
+		APIURL:  apiURL,
+		DB:      db,
+		Secret:  testSecret,
+		// heartbeats are sent by the tests
+		HeartbeatInterval: time.Hour,
+		NodeTimeout:       200 * time.Millisecond,
+	}, sqlite.DataSourceOptions{WALEnabled: true})
+	require.NoError(t, err)
+	return n
+}
+
+func TestLeaderElection(t *testing.T) {
+	ctx := context.Background()
+	db := filepath.Join(t.TempDir(), DefaultDBName)
+	node1 := newTestNode(t, db, "node-1", "http://127.0.0.1:3001")
+	node2 := newTestNode(t, db, "node-2", "http://127.0.0.1:3002")
+
+	require.NoError(t, node1.Start(ctx))
+	require.NoError(t, node2.Start(ctx))
+	assert.True(t, node1.IsLeader())
+	assert.False(t, node2.IsLeader())
+
+	// the leader keeps its leadership with every heartbeat
+	require.NoError(t, node1.heartbeat(ctx))
+	require.NoError(t, node2.heartbeat(ctx))
+	assert.True(t, node1.IsLeader())
+	assert.False(t, node2.IsLeader())
+
+	nodes, err := node2.Nodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.True(t, nodes[0].Leader)
+	assert.False(t, nodes[0].Self)
+	assert.False(t, nodes[1].Leader)
+	assert.True(t, nodes[1].Self)
+
+	// another node takes over once the leader stops sending heartbeats
+	time.Sleep(250 * time.Millisecond)
+	require.NoError(t, node2.heartbeat(ctx))
+	assert.True(t, node2.IsLeader())
+	require.NoError(t, node1.heartbeat(ctx))
+	assert.False(t, node1.IsLeader())
+
+	// leaving the cluster releases the leadership right away
+	require.NoError(t, node1.heartbeat(ctx))
+	require.NoError(t, node2.Close())
+	require.NoError(t, node1.heartbeat(ctx))
+	assert.True(t, node1.IsLeader())
+	require.NoError(t, node1.Close())
+}
+
+func TestClientOwners(t *testing.T) {
+	ctx := context.Background()
+	db := filepath.Join(t.TempDir(), DefaultDBName)
+	node1 := newTestNode(t, db, "node-1", "http://127.0.0.1:3001")
+	node2 := newTestNode(t, db, "node-2", "http://127.0.0.1:3002")
+	defer node1.Close()
+	defer node2.Close()
+	require.NoError(t, node1.Start(ctx))
+	require.NoError(t, node2.Start(ctx))
+
+	node1.ClientConnected(ctx, "client-1")
+
+	owner, err := node2.GetRemoteOwner(ctx, "client-1")
+	require.NoError(t, err)
+	require.NotNil(t, owner)
+	assert.Equal(t, "node-1", owner.ID)
+	assert.Equal(t, "http://127.0.0.1:3001", owner.APIURL)
+
+	// the own node isn't remote
+	owner, err = node1.GetRemoteOwner(ctx, "client-1")
+	require.NoError(t, err)
+	assert.Nil(t, owner)
+
+	// a client that reconnected to another node isn't removed by the disconnect on the old node
+	node2.ClientConnected(ctx, "client-1")
+	node1.ClientDisconnected(ctx, "client-1")
+	owner, err = node1.GetRemoteOwner(ctx, "client-1")
+	require.NoError(t, err)
+	require.NotNil(t, owner)
+	assert.Equal(t, "node-2", owner.ID)
+
+	nodes, err := node1.Nodes(ctx)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, 0, nodes[0].Clients)
+	assert.Equal(t, 1, nodes[1].Clients)
+
+	// clients of a node that stopped sending heartbeats aren't reachable
+	time.Sleep(250 * time.Millisecond)
+	owner, err = node1.GetRemoteOwner(ctx, "client-1")
+	require.NoError(t, err)
+	assert.Nil(t, owner)
+}
+
+func TestForward(t *testing.T) {
+	ctx := context.Background()
+	db := filepath.Join(t.TempDir(), DefaultDBName)
+	node1 := newTestNode(t, db, "node-1", "http://127.0.0.1:3001")
+	defer node1.Close()
+
+	var forwardedUser string
+	var forwardedOK bool
+	var authorization string
+	node2Srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedUser, forwardedOK = node1.ForwardedUser(r)
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer node2Srv.Close()
+	require.NoError(t, node1.Start(ctx))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/client-1", nil)
+	req.SetBasicAuth("admin", "foobaz")
+	assert.False(t, IsForwarded(req))
+	w := httptest.NewRecorder()
+	node1.Forward(w, req, &NodeInfo{ID: "node-2", APIURL: node2Srv.URL}, "admin", func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("unexpected error: %v", err)
+	})
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.True(t, forwardedOK)
+	assert.Equal(t, "admin", forwardedUser)
+	assert.Empty(t, authorization)
+
+	// requests with a wrong secret are not trusted
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/clients/client-1", nil)
+	req.Header.Set(HeaderNode, "node-2")
+	req.Header.Set(HeaderSecret, "wrong")
+	req.Header.Set(HeaderUser, "admin")
+	_, ok := node1.ForwardedUser(req)
+	assert.False(t, ok)
+
+	// a nil node doesn't trust any request
+	var disabled *Node
+	_, ok = disabled.ForwardedUser(req)
+	assert.False(t, ok)
+	assert.True(t, disabled.IsLeader())
+}
+
+func TestConfigParseAndValidate(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Config        Config
+		APIAddress    string
+		ExpectedError string
+	}{
+		{
+			Name:   "disabled",
+			Config: Config{},
+		},
+		{
+			Name:          "api disabled",
+			Config:        Config{Enabled: true, NodeID: "node-1", APIURL: "http://127.0.0.1:3000", Secret: testSecret},
+			ExpectedError: "the api must be enabled to run a cluster",
+		},
+		{
+			Name:          "invalid api url",
+			Config:        Config{Enabled: true, NodeID: "node-1", APIURL: "127.0.0.1:3000", Secret: testSecret},
+			APIAddress:    "0.0.0.0:3000",
+			ExpectedError: `invalid 'api_url' "127.0.0.1:3000": expected http(s)://<host>:<port>`,
+		},
+		{
+			Name:          "short secret",
+			Config:        Config{Enabled: true, NodeID: "node-1", APIURL: "http://127.0.0.1:3000", Secret: "secret"},
+			APIAddress:    "0.0.0.0:3000",
+			ExpectedError: "'secret' must be at least 16 characters long and the same on all nodes",
+		},
+		{
+			Name:          "node timeout",
+			Config:        Config{Enabled: true, NodeID: "node-1", APIURL: "http://127.0.0.1:3000", Secret: testSecret, NodeTimeout: time.Second},
+			APIAddress:    "0.0.0.0:3000",
+			ExpectedError: "'node_timeout' must be greater than 'heartbeat_interval' (5s)",
+		},
+		{
+			Name:       "valid",
+			Config:     Config{Enabled: true, NodeID: "node-1", APIURL: "http://127.0.0.1:3000", Secret: testSecret},
+			APIAddress: "0.0.0.0:3000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Config.ParseAndValidate("/var/lib/riport", tc.APIAddress)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			if tc.Config.Enabled {
+				assert.Equal(t, "/var/lib/riport/cluster.db", tc.Config.DB)
+				assert.Equal(t, 15*time.Second, tc.Config.NodeTimeout)
+			}
+		})
+	}
+}
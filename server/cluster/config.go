@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	DefaultHeartbeatInterval = 5 * time.Second
+	DefaultDBName            = "cluster.db"
+
+	minSecretLength = 16
+)
+
+type Config struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	NodeID            string        `mapstructure:"node_id"`
+	APIURL            string        `mapstructure:"api_url"`
+	DB                string        `mapstructure:"db"`
+	Secret            string        `mapstructure:"secret"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	NodeTimeout       time.Duration `mapstructure:"node_timeout"`
+}
+
+func (c *Config) ParseAndValidate(serverDataDir string, apiAddress string) error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if apiAddress == "" {
+		return errors.New("the api must be enabled to run a cluster")
+	}
+
+	if c.NodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("'node_id' not set and failed to get hostname: %v", err)
+		}
+		c.NodeID = hostname
+	}
+
+	if c.APIURL == "" {
+		return errors.New("'api_url' must be set to the url the api of this node is reachable by the other nodes")
+	}
+	u, err := url.Parse(c.APIURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid 'api_url' %q: expected http(s)://<host>:<port>", c.APIURL)
+	}
+
+	if len(c.Secret) < minSecretLength {
+		return fmt.Errorf("'secret' must be at least %d characters long and the same on all nodes", minSecretLength)
+	}
+
+	if c.DB == "" {
+		c.DB = path.Join(serverDataDir, DefaultDBName)
+	}
+
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if c.NodeTimeout == 0 {
+		c.NodeTimeout = 3 * c.HeartbeatInterval
+	}
+	if c.NodeTimeout <= c.HeartbeatInterval {
+		return fmt.Errorf("'node_timeout' must be greater than 'heartbeat_interval' (%s)", c.HeartbeatInterval)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+const (
+	HeaderNode   = "X-Riport-Cluster-Node"
+	HeaderSecret = "X-Riport-Cluster-Secret"
+	HeaderUser   = "X-Riport-Cluster-User"
+)
+
+// Forward proxies an api request to the node that owns the client. The user was authenticated by this node already,
+// the owner trusts the user given along with the cluster secret.
+func (n *Node) Forward(w http.ResponseWriter, r *http.Request, target *NodeInfo, username string, onError func(http.ResponseWriter, *http.Request, error)) {
+	targetURL, err := url.Parse(target.APIURL)
+	if err != nil {
+		onError(w, r, err)
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = targetURL.Host
+		req.Header.Del("Authorization")
+		req.Header.Del("Cookie")
+		req.Header.Set(HeaderNode, n.info.ID)
+		req.Header.Set(HeaderSecret, n.config.Secret)
+		req.Header.Set(HeaderUser, username)
+	}
+	proxy.ErrorHandler = onError
+
+	n.log.Debugf("Forwarding %s %s to node %s", r.Method, r.URL.Path, target.ID)
+	proxy.ServeHTTP(w, r)
+}
+
+// IsForwarded returns true if a request was forwarded by another node, it's never forwarded again
+func IsForwarded(r *http.Request) bool {
+	return r.Header.Get(HeaderNode) != ""
+}
+
+// ForwardedUser returns the user of a request forwarded by another node of the cluster
+func (n *Node) ForwardedUser(r *http.Request) (string, bool) {
+	if n == nil || !IsForwarded(r) {
+		return "", false
+	}
+
+	secret := r.Header.Get(HeaderSecret)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(n.config.Secret)) != 1 {
+		return "", false
+	}
+	username := r.Header.Get(HeaderUser)
+	return username, username != ""
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import "time"
+
+// NodeInfo is a riportd instance of the cluster as stored in the shared database
+type NodeInfo struct {
+	ID          string    `json:"id" db:"id"`
+	APIURL      string    `json:"api_url" db:"api_url"`
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at" db:"heartbeat_at"`
+}
+
+// NodeStatus is a node as returned by the api
+type NodeStatus struct {
+	*NodeInfo
+	Alive   bool `json:"alive"`
+	Leader  bool `json:"leader"`
+	Self    bool `json:"self"`
+	Clients int  `json:"clients"`
+}
+
+// ClientOwner is the node a client is connected to
+type ClientOwner struct {
+	ClientID    string    `db:"client_id"`
+	NodeID      string    `db:"node_id"`
+	ConnectedAt time.Time `db:"connected_at"`
+}
+
+// Lease is held by a single node until it expires, it's renewed with every heartbeat
+type Lease struct {
+	Name      string    `db:"name"`
+	NodeID    string    `db:"node_id"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Provider interface {
+	SaveNode(ctx context.Context, n *NodeInfo) error
+	DeleteNode(ctx context.Context, id string) error
+	ListNodes(ctx context.Context) ([]*NodeInfo, error)
+	GetNode(ctx context.Context, id string) (*NodeInfo, error)
+	SetClientOwner(ctx context.Context, o *ClientOwner) error
+	DeleteClientOwner(ctx context.Context, clientID, nodeID string) error
+	DeleteClientOwnersOfNode(ctx context.Context, nodeID string) error
+	GetClientOwner(ctx context.Context, clientID string) (*ClientOwner, error)
+	CountClientsByNode(ctx context.Context) (map[string]int, error)
+	AcquireLease(ctx context.Context, l *Lease, now time.Time) (bool, error)
+	ReleaseLease(ctx context.Context, name, nodeID string) error
+	GetLease(ctx context.Context, name string) (*Lease, error)
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db: db,
+	}
+}
+
+func (p *SqliteProvider) SaveNode(ctx context.Context, n *NodeInfo) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT INTO nodes (id, api_url, started_at, heartbeat_at) VALUES (:id, :api_url, :started_at, :heartbeat_at)
+		ON CONFLICT (id) DO UPDATE SET api_url = excluded.api_url, started_at = excluded.started_at, heartbeat_at = excluded.heartbeat_at`,
+		n,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeleteNode(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM nodes WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) ListNodes(ctx context.Context) ([]*NodeInfo, error) {
+	res := []*NodeInfo{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM nodes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) GetNode(ctx context.Context, id string) (*NodeInfo, error) {
+	res := &NodeInfo{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM nodes WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SetClientOwner(ctx context.Context, o *ClientOwner) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO client_owners (client_id, node_id, connected_at) VALUES (:client_id, :node_id, :connected_at)",
+		o,
+	)
+	return err
+}
+
+// DeleteClientOwner deletes the owner of a client unless the client reconnected to another node meanwhile
+func (p *SqliteProvider) DeleteClientOwner(ctx context.Context, clientID, nodeID string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM client_owners WHERE client_id = ? AND node_id = ?", clientID, nodeID)
+	return err
+}
+
+func (p *SqliteProvider) DeleteClientOwnersOfNode(ctx context.Context, nodeID string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM client_owners WHERE node_id = ?", nodeID)
+	return err
+}
+
+func (p *SqliteProvider) GetClientOwner(ctx context.Context, clientID string) (*ClientOwner, error) {
+	res := &ClientOwner{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM client_owners WHERE client_id = ?", clientID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) CountClientsByNode(ctx context.Context) (map[string]int, error) {
+	rows := []struct {
+		NodeID string `db:"node_id"`
+		Count  int    `db:"count"`
+	}{}
+	err := p.db.SelectContext(ctx, &rows, "SELECT node_id, COUNT(*) AS count FROM client_owners GROUP BY node_id")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]int, len(rows))
+	for _, r := range rows {
+		res[r.NodeID] = r.Count
+	}
+	return res, nil
+}
+
+// AcquireLease takes or renews a lease, it returns false if another node holds a lease that didn't expire yet
+func (p *SqliteProvider) AcquireLease(ctx context.Context, l *Lease, now time.Time) (bool, error) {
+	res, err := p.db.ExecContext(
+		ctx,
+		`INSERT INTO leases (name, node_id, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET node_id = excluded.node_id, expires_at = excluded.expires_at
+		WHERE leases.node_id = excluded.node_id OR leases.expires_at < ?`,
+		l.Name, l.NodeID, l.ExpiresAt.UTC(), now.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (p *SqliteProvider) ReleaseLease(ctx context.Context, name, nodeID string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM leases WHERE name = ? AND node_id = ?", name, nodeID)
+	return err
+}
+
+func (p *SqliteProvider) GetLease(ctx context.Context, name string) (*Lease, error) {
+	res := &Lease{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM leases WHERE name = ?", name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
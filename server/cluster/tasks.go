@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/riportdev/riport/server/scheduler"
+)
+
+type leaderOnlyTask struct {
+	node *Node
+	task scheduler.Task
+}
+
+// LeaderOnly returns a task that is run on the leader only, all other nodes skip it
+func (n *Node) LeaderOnly(task scheduler.Task) scheduler.Task {
+	if n == nil {
+		return task
+	}
+	return &leaderOnlyTask{
+		node: n,
+		task: task,
+	}
+}
+
+func (t *leaderOnlyTask) Run(ctx context.Context) error {
+	if !t.node.IsLeader() {
+		t.node.log.Debugf("Skipping task %T, node is not the cluster leader", t.task)
+		return nil
+	}
+	return t.task.Run(ctx)
+}
@@ -0,0 +1,232 @@
+package inventory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+// Manager stores the latest inventory of each client with the changes between the received inventories
+type Manager struct {
+	provider Provider
+	logger   *logger.Logger
+}
+
+func NewManager(logger *logger.Logger, provider Provider) *Manager {
+	return &Manager{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// Save replaces the items of a client by the received inventory and records the changes. The first inventory of a
+// client is the baseline without changes. Items of a category that failed to collect are kept unchanged.
+// It returns the summary of the resulting items.
+func (m *Manager) Save(ctx context.Context, clientID string, inventory *models.Inventory) (*models.InventorySummary, error) {
+	now := time.Now().UTC()
+
+	prevStatus, err := m.provider.GetStatus(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	prevItems, err := m.provider.ListAllItems(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	prevByKey := make(map[string]*Item, len(prevItems))
+	for _, item := range prevItems {
+		prevByKey[item.key()] = item
+	}
+
+	var upserts, deletes, items []*Item
+	var changes []*Change
+	received := make(map[string]bool, len(inventory.Items))
+	for _, r := range inventory.Items {
+		item := &Item{
+			ClientID:    clientID,
+			Category:    r.Category,
+			Name:        r.Name,
+			Version:     r.Version,
+			Details:     r.Details,
+			FirstSeenAt: now,
+			UpdatedAt:   now,
+		}
+		if received[item.key()] {
+			continue
+		}
+		received[item.key()] = true
+
+		prev := prevByKey[item.key()]
+		switch {
+		case prev == nil:
+			upserts = append(upserts, item)
+			changes = append(changes, newChange(ActionAdded, nil, item, now))
+		case prev.Version != item.Version || !prev.Details.equals(item.Details):
+			item.FirstSeenAt = prev.FirstSeenAt
+			upserts = append(upserts, item)
+			changes = append(changes, newChange(ActionChanged, prev, item, now))
+		default:
+			item = prev
+		}
+		items = append(items, item)
+	}
+
+	for _, prev := range prevItems {
+		if received[prev.key()] {
+			continue
+		}
+		if _, failed := inventory.Errors[prev.Category]; failed {
+			items = append(items, prev)
+			continue
+		}
+		deletes = append(deletes, prev)
+		changes = append(changes, newChange(ActionRemoved, prev, nil, now))
+	}
+
+	if prevStatus == nil {
+		changes = nil
+	}
+
+	status := &Status{
+		ClientID:    clientID,
+		CollectedAt: inventory.CollectedAt.UTC(),
+		ReceivedAt:  now,
+		Errors:      inventory.Errors,
+	}
+	err = m.provider.Save(ctx, status, upserts, deletes, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changes) > 0 {
+		m.logger.Infof("Inventory of client %s changed: %d items added, changed or removed", clientID, len(changes))
+	}
+	return NewSummary(items), nil
+}
+
+func newChange(action string, prev, item *Item, detectedAt time.Time) *Change {
+	change := &Change{
+		DetectedAt: detectedAt,
+		Action:     action,
+	}
+	if prev != nil {
+		change.ClientID = prev.ClientID
+		change.Category = prev.Category
+		change.Name = prev.Name
+		change.OldVersion = prev.Version
+		change.OldDetails = prev.Details
+	}
+	if item != nil {
+		change.ClientID = item.ClientID
+		change.Category = item.Category
+		change.Name = item.Name
+		change.NewVersion = item.Version
+		change.NewDetails = item.Details
+	}
+	return change
+}
+
+// Summary returns the summary of the stored inventory of a client, it's nil if the client never sent an inventory
+func (m *Manager) Summary(ctx context.Context, clientID string) (*models.InventorySummary, error) {
+	status, err := m.provider.GetStatus(ctx, clientID)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	items, err := m.provider.ListAllItems(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return NewSummary(items), nil
+}
+
+// Summaries returns the summaries of all clients that sent an inventory
+func (m *Manager) Summaries(ctx context.Context) (map[string]*models.InventorySummary, error) {
+	items, err := m.provider.ListSummaryItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byClient := make(map[string][]*Item)
+	for _, item := range items {
+		byClient[item.ClientID] = append(byClient[item.ClientID], item)
+	}
+	summaries := make(map[string]*models.InventorySummary, len(byClient))
+	for clientID, clientItems := range byClient {
+		summaries[clientID] = NewSummary(clientItems)
+	}
+	return summaries, nil
+}
+
+func (m *Manager) GetStatus(ctx context.Context, clientID string) (*Status, error) {
+	return m.provider.GetStatus(ctx, clientID)
+}
+
+func (m *Manager) ListItems(ctx context.Context, clientID string, options *query.ListOptions) ([]*Item, int, error) {
+	items, err := m.provider.ListItems(ctx, clientID, options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.provider.CountItems(ctx, clientID, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, count, nil
+}
+
+// ListItemsOfAllClients returns the items of the latest inventories of all clients matching the given options
+func (m *Manager) ListItemsOfAllClients(ctx context.Context, options *query.ListOptions) ([]*Item, error) {
+	return m.provider.ListItemsOfAllClients(ctx, options)
+}
+
+func (m *Manager) ListChanges(ctx context.Context, clientID string, options *query.ListOptions) ([]*Change, int, error) {
+	changes, err := m.provider.ListChanges(ctx, clientID, options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.provider.CountChanges(ctx, clientID, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	return changes, count, nil
+}
+
+func (m *Manager) Close() error {
+	return m.provider.Close()
+}
+
+// NewSummary returns the values of the items client groups can match on
+func NewSummary(items []*Item) *models.InventorySummary {
+	summary := &models.InventorySummary{}
+	ports := make(map[string]bool)
+	for _, item := range items {
+		switch item.Category {
+		case models.InventoryCategoryPackage:
+			summary.Packages = append(summary.Packages, item.Name)
+		case models.InventoryCategoryListeningPort:
+			// the same port is often listened on for ipv4 and ipv6
+			port := item.Details["protocol"] + "/" + item.Details["port"]
+			if !ports[port] {
+				ports[port] = true
+				summary.ListeningPorts = append(summary.ListeningPorts, port)
+			}
+		case models.InventoryCategoryUser:
+			summary.LocalUsers = append(summary.LocalUsers, item.Name)
+		case models.InventoryCategorySystem:
+			if item.Name == "system" {
+				summary.SystemVendor = strings.TrimSpace(item.Details["vendor"])
+				summary.SystemModel = strings.TrimSpace(item.Details["model"])
+			}
+		}
+	}
+	sort.Strings(summary.Packages)
+	sort.Strings(summary.ListeningPorts)
+	sort.Strings(summary.LocalUsers)
+	return summary
+}
@@ -0,0 +1,150 @@
+package inventory
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inventorymigration "github.com/riportdev/riport/db/migration/inventory"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func newTestManager(t *testing.T) *Manager {
+	db, err := sqlite.New(":memory:", inventorymigration.AssetNames(), inventorymigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	testLog := logger.NewLogger("inventory-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug)
+	m := NewManager(testLog, NewSqliteProvider(db, testLog))
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	summary, err := m.Save(ctx, "client-1", &models.Inventory{
+		CollectedAt: time.Now(),
+		Items: []*models.InventoryItem{
+			{Category: models.InventoryCategoryPackage, Name: "openssl", Version: "1.1.1"},
+			{Category: models.InventoryCategoryPackage, Name: "telnet", Version: "0.17"},
+			{Category: models.InventoryCategoryUser, Name: "root", Details: map[string]string{"uid": "0"}},
+			{Category: models.InventoryCategoryListeningPort, Name: "tcp/0.0.0.0:22", Details: map[string]string{"protocol": "tcp", "port": "22"}},
+			{Category: models.InventoryCategoryListeningPort, Name: "tcp/[::]:22", Details: map[string]string{"protocol": "tcp", "port": "22"}},
+			{Category: models.InventoryCategorySystem, Name: "system", Details: map[string]string{"vendor": "Dell Inc.", "model": "PowerEdge R640"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.InventorySummary{
+		Packages:       []string{"openssl", "telnet"},
+		ListeningPorts: []string{"tcp/22"},
+		LocalUsers:     []string{"root"},
+		SystemVendor:   "Dell Inc.",
+		SystemModel:    "PowerEdge R640",
+	}, summary)
+
+	// the first inventory is the baseline
+	_, count, err := m.ListChanges(ctx, "client-1", &query.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	summary, err = m.Save(ctx, "client-1", &models.Inventory{
+		CollectedAt: time.Now(),
+		Items: []*models.InventoryItem{
+			{Category: models.InventoryCategoryPackage, Name: "nginx", Version: "1.24.0"},
+			{Category: models.InventoryCategoryPackage, Name: "openssl", Version: "3.0.2"},
+			{Category: models.InventoryCategoryListeningPort, Name: "tcp/0.0.0.0:22", Details: map[string]string{"protocol": "tcp", "port": "22"}},
+			{Category: models.InventoryCategorySystem, Name: "system", Details: map[string]string{"vendor": "Dell Inc.", "model": "PowerEdge R640"}},
+		},
+		Errors: map[string]string{models.InventoryCategoryUser: "permission denied"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nginx", "openssl"}, summary.Packages)
+	// the users are kept as they failed to collect
+	assert.Equal(t, []string{"root"}, summary.LocalUsers)
+
+	changes, count, err := m.ListChanges(ctx, "client-1", &query.ListOptions{
+		Sorts: []query.SortOption{{Column: "category", IsASC: true}, {Column: "name", IsASC: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	require.Len(t, changes, 4)
+	assert.Equal(t, "tcp/[::]:22", changes[0].Name)
+	assert.Equal(t, ActionRemoved, changes[0].Action)
+	assert.Equal(t, "nginx", changes[1].Name)
+	assert.Equal(t, ActionAdded, changes[1].Action)
+	assert.Equal(t, "1.24.0", changes[1].NewVersion)
+	assert.Equal(t, "openssl", changes[2].Name)
+	assert.Equal(t, ActionChanged, changes[2].Action)
+	assert.Equal(t, "1.1.1", changes[2].OldVersion)
+	assert.Equal(t, "3.0.2", changes[2].NewVersion)
+	assert.Equal(t, "telnet", changes[3].Name)
+	assert.Equal(t, ActionRemoved, changes[3].Action)
+
+	status, err := m.GetStatus(ctx, "client-1")
+	require.NoError(t, err)
+	assert.Equal(t, Details{models.InventoryCategoryUser: "permission denied"}, status.Errors)
+
+	items, count, err := m.ListItems(ctx, "client-1", &query.ListOptions{
+		Filters: []query.FilterOption{
+			{Column: []string{"category"}, Values: []string{models.InventoryCategoryPackage}},
+			{Column: []string{"name"}, Values: []string{"open*"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Len(t, items, 1)
+	assert.Equal(t, "3.0.2", items[0].Version)
+	assert.True(t, items[0].FirstSeenAt.Before(items[0].UpdatedAt))
+
+	items, _, err = m.ListItems(ctx, "client-1", &query.ListOptions{
+		Filters: []query.FilterOption{{Column: []string{"details"}, Values: []string{"*poweredge*"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "system", items[0].Name)
+}
+
+func TestSummaries(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	for _, clientID := range []string{"client-1", "client-2"} {
+		_, err := m.Save(ctx, clientID, &models.Inventory{
+			CollectedAt: time.Now(),
+			Items: []*models.InventoryItem{
+				{Category: models.InventoryCategoryPackage, Name: clientID + "-package"},
+				{Category: models.InventoryCategoryDisk, Name: "sda"},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	summaries, err := m.Summaries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*models.InventorySummary{
+		"client-1": {Packages: []string{"client-1-package"}},
+		"client-2": {Packages: []string{"client-2-package"}},
+	}, summaries)
+
+	items, err := m.ListItemsOfAllClients(ctx, &query.ListOptions{
+		Filters: []query.FilterOption{{Column: []string{"category"}, Values: []string{models.InventoryCategoryPackage}}},
+		Sorts:   []query.SortOption{{Column: "client_id", IsASC: true}},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "client-1", items[0].ClientID)
+	assert.Equal(t, "client-2-package", items[1].Name)
+
+	summary, err := m.Summary(ctx, "client-3")
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+}
@@ -0,0 +1,134 @@
+package inventory
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionAdded   = "added"
+	ActionRemoved = "removed"
+	ActionChanged = "changed"
+)
+
+var SupportedItemFilters = map[string]bool{
+	"category":             true,
+	"name":                 true,
+	"version":              true,
+	"details":              true,
+	"first_seen_at[gt]":    true,
+	"first_seen_at[lt]":    true,
+	"first_seen_at[since]": true,
+	"first_seen_at[until]": true,
+}
+
+var SupportedItemSorts = map[string]bool{
+	"category":      true,
+	"name":          true,
+	"version":       true,
+	"first_seen_at": true,
+	"updated_at":    true,
+}
+
+var ItemsDefaultSort = map[string][]string{
+	"sort": {"category", "name"},
+}
+
+var SupportedChangeFilters = map[string]bool{
+	"category":           true,
+	"name":               true,
+	"action":             true,
+	"old_version":        true,
+	"new_version":        true,
+	"detected_at[gt]":    true,
+	"detected_at[lt]":    true,
+	"detected_at[since]": true,
+	"detected_at[until]": true,
+}
+
+var SupportedChangeSorts = map[string]bool{
+	"detected_at": true,
+	"category":    true,
+	"name":        true,
+	"action":      true,
+}
+
+var ChangesDefaultSort = map[string][]string{
+	"sort": {"-detected_at", "category", "name"},
+}
+
+// Details is used for storing the details of an item as a json db column.
+type Details map[string]string
+
+func (d *Details) Scan(value interface{}) error {
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	if err := json.Unmarshal([]byte(valueStr), d); err != nil {
+		return fmt.Errorf("failed to decode details: %v", err)
+	}
+	return nil
+}
+
+func (d Details) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode details: %v", err)
+	}
+	return string(b), nil
+}
+
+func (d Details) equals(other Details) bool {
+	if len(d) != len(other) {
+		return false
+	}
+	for k, v := range d {
+		if ov, ok := other[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Item is the latest state of an inventory item of a client
+type Item struct {
+	ClientID    string    `json:"-" db:"client_id"`
+	Category    string    `json:"category" db:"category"`
+	Name        string    `json:"name" db:"name"`
+	Version     string    `json:"version" db:"version"`
+	Details     Details   `json:"details" db:"details"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (i *Item) key() string {
+	return i.Category + "/" + i.Name
+}
+
+// Change is an item added, removed or changed between two inventories of a client
+type Change struct {
+	ID         int64     `json:"id" db:"id"`
+	ClientID   string    `json:"-" db:"client_id"`
+	DetectedAt time.Time `json:"detected_at" db:"detected_at"`
+	Category   string    `json:"category" db:"category"`
+	Name       string    `json:"name" db:"name"`
+	Action     string    `json:"action" db:"action"`
+	OldVersion string    `json:"old_version" db:"old_version"`
+	NewVersion string    `json:"new_version" db:"new_version"`
+	OldDetails Details   `json:"old_details" db:"old_details"`
+	NewDetails Details   `json:"new_details" db:"new_details"`
+}
+
+// Status is the state of the last inventory received from a client
+type Status struct {
+	ClientID    string    `json:"-" db:"client_id"`
+	CollectedAt time.Time `json:"collected_at" db:"collected_at"`
+	ReceivedAt  time.Time `json:"received_at" db:"received_at"`
+	Errors      Details   `json:"errors" db:"errors"`
+}
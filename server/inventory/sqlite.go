@@ -0,0 +1,222 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/models"
+	"github.com/riportdev/riport/share/query"
+)
+
+type Provider interface {
+	GetStatus(ctx context.Context, clientID string) (*Status, error)
+	ListAllItems(ctx context.Context, clientID string) ([]*Item, error)
+	ListSummaryItems(ctx context.Context) ([]*Item, error)
+	Save(ctx context.Context, status *Status, upserts []*Item, deletes []*Item, changes []*Change) error
+	ListItems(ctx context.Context, clientID string, options *query.ListOptions) ([]*Item, error)
+	ListItemsOfAllClients(ctx context.Context, options *query.ListOptions) ([]*Item, error)
+	CountItems(ctx context.Context, clientID string, options *query.ListOptions) (int, error)
+	ListChanges(ctx context.Context, clientID string, options *query.ListOptions) ([]*Change, error)
+	CountChanges(ctx context.Context, clientID string, options *query.ListOptions) (int, error)
+	Close() error
+}
+
+type SqliteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+	logger    *logger.Logger
+}
+
+func NewSqliteProvider(db *sqlx.DB, logger *logger.Logger) *SqliteProvider {
+	return &SqliteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+		logger:    logger,
+	}
+}
+
+func (p *SqliteProvider) GetStatus(ctx context.Context, clientID string) (*Status, error) {
+	status := &Status{}
+	err := p.db.GetContext(ctx, status, "SELECT * FROM inventories WHERE client_id = ?", clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return status, nil
+}
+
+func (p *SqliteProvider) ListAllItems(ctx context.Context, clientID string) ([]*Item, error) {
+	res := []*Item{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM inventory_items WHERE client_id = ?", clientID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListSummaryItems returns the items of all clients of the categories summaries are made of
+func (p *SqliteProvider) ListSummaryItems(ctx context.Context) ([]*Item, error) {
+	res := []*Item{}
+	err := p.db.SelectContext(
+		ctx,
+		&res,
+		"SELECT * FROM inventory_items WHERE category IN (?, ?, ?, ?) ORDER BY client_id",
+		models.InventoryCategoryPackage, models.InventoryCategoryListeningPort, models.InventoryCategoryUser, models.InventoryCategorySystem,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Save stores the status and the item and change records of a received inventory in a single transaction
+func (p *SqliteProvider) Save(ctx context.Context, status *Status, upserts []*Item, deletes []*Item, changes []*Change) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	err = p.save(ctx, tx, status, upserts, deletes, changes)
+	if err != nil {
+		p.handleRollback(tx)
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *SqliteProvider) save(ctx context.Context, tx *sqlx.Tx, status *Status, upserts []*Item, deletes []*Item, changes []*Change) error {
+	_, err := tx.NamedExecContext(
+		ctx,
+		`INSERT INTO inventories (client_id, collected_at, received_at, errors)
+		VALUES (:client_id, :collected_at, :received_at, :errors)
+		ON CONFLICT(client_id) DO UPDATE SET
+			collected_at = excluded.collected_at, received_at = excluded.received_at, errors = excluded.errors`,
+		status,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(upserts) > 0 {
+		stmt, err := tx.PrepareNamedContext(
+			ctx,
+			`INSERT INTO inventory_items (client_id, category, name, version, details, first_seen_at, updated_at)
+			VALUES (:client_id, :category, :name, :version, :details, :first_seen_at, :updated_at)
+			ON CONFLICT(client_id, category, name) DO UPDATE SET
+				version = excluded.version, details = excluded.details, updated_at = excluded.updated_at`,
+		)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, item := range upserts {
+			if _, err := stmt.ExecContext(ctx, item); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, item := range deletes {
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM inventory_items WHERE client_id = ? AND category = ? AND name = ?",
+			item.ClientID, item.Category, item.Name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes) > 0 {
+		stmt, err := tx.PrepareNamedContext(
+			ctx,
+			`INSERT INTO inventory_changes (
+				client_id, detected_at, category, name, action, old_version, new_version, old_details, new_details
+			) VALUES (
+				:client_id, :detected_at, :category, :name, :action, :old_version, :new_version, :old_details, :new_details
+			)`,
+		)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, change := range changes {
+			if _, err := stmt.ExecContext(ctx, change); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *SqliteProvider) ListItems(ctx context.Context, clientID string, options *query.ListOptions) ([]*Item, error) {
+	q, params := p.converter.AppendOptionsToQuery(options, "SELECT * FROM inventory_items WHERE client_id = ?", []interface{}{clientID})
+
+	res := []*Item{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) ListItemsOfAllClients(ctx context.Context, options *query.ListOptions) ([]*Item, error) {
+	q, params := p.converter.AppendOptionsToQuery(options, "SELECT * FROM inventory_items", nil)
+
+	res := []*Item{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) CountItems(ctx context.Context, clientID string, options *query.ListOptions) (int, error) {
+	return p.count(ctx, "SELECT COUNT(*) FROM inventory_items WHERE client_id = ?", clientID, options)
+}
+
+func (p *SqliteProvider) ListChanges(ctx context.Context, clientID string, options *query.ListOptions) ([]*Change, error) {
+	q, params := p.converter.AppendOptionsToQuery(options, "SELECT * FROM inventory_changes WHERE client_id = ?", []interface{}{clientID})
+
+	res := []*Change{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) CountChanges(ctx context.Context, clientID string, options *query.ListOptions) (int, error) {
+	return p.count(ctx, "SELECT COUNT(*) FROM inventory_changes WHERE client_id = ?", clientID, options)
+}
+
+func (p *SqliteProvider) count(ctx context.Context, q string, clientID string, options *query.ListOptions) (int, error) {
+	countOptions := *options
+	countOptions.Sorts = nil
+	countOptions.Pagination = nil
+	q, params := p.converter.AppendOptionsToQuery(&countOptions, q, []interface{}{clientID})
+
+	var result int
+	err := p.db.GetContext(ctx, &result, q, params...)
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
+
+func (p *SqliteProvider) handleRollback(tx *sqlx.Tx) {
+	err := tx.Rollback()
+	if err != nil {
+		p.logger.Errorf("Failed to rollback transaction: %v", err)
+	}
+}
@@ -16,6 +16,7 @@ type DBProviderMock struct {
 	MetricsListPayload           []*ClientMetricsPayload
 	ProcessesListPayload         []*ClientProcessesPayload
 	MountpointsListPayload       []*ClientMountpointsPayload
+	LatestMeasurements           []*models.Measurement
 }
 
 func (p *DBProviderMock) CountByClientID(ctx context.Context, clientID string, fo *query.ListOptions) (int, error) {
@@ -42,6 +43,10 @@ func (p *DBProviderMock) ListGraphMetricsByClientID(ctx context.Context, clientI
 	return p.GraphMetricsListPayload, nil
 }
 
+func (p *DBProviderMock) ListLatestMeasurements(ctx context.Context) ([]*models.Measurement, error) {
+	return p.LatestMeasurements, nil
+}
+
 func (p *DBProviderMock) CreateMeasurement(ctx context.Context, measurement *models.Measurement) error {
 	return nil
 }
@@ -24,6 +24,7 @@ type Service interface {
 	ListClientGraphMetrics(context.Context, string, *query.ListOptions, *query.RequestInfo, bool, bool) (*api.SuccessPayload, error)
 	ListClientMountpoints(context.Context, string, *query.ListOptions) (*api.SuccessPayload, error)
 	ListClientProcesses(context.Context, string, *query.ListOptions) (*api.SuccessPayload, error)
+	ListLatestMeasurements(context.Context) ([]*models.Measurement, error)
 }
 
 const layoutAPI = time.RFC3339
@@ -63,6 +64,10 @@ func (s *monitoringService) SaveMeasurement(ctx context.Context, measurement *mo
 	return s.DBProvider.CreateMeasurement(ctx, measurement)
 }
 
+func (s *monitoringService) ListLatestMeasurements(ctx context.Context) ([]*models.Measurement, error) {
+	return s.DBProvider.ListLatestMeasurements(ctx)
+}
+
 func (s *monitoringService) DeleteMeasurementsOlderThan(ctx context.Context, period time.Duration) (int64, error) {
 	compare := time.Now().Add(-period)
 	return s.DBProvider.DeleteMeasurementsBefore(ctx, compare)
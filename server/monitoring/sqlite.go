@@ -26,6 +26,7 @@ type DBProvider interface {
 	ListMountpointsByClientID(context.Context, string, *query.ListOptions) ([]*ClientMountpointsPayload, error)
 	ListProcessesByClientID(context.Context, string, *query.ListOptions) ([]*ClientProcessesPayload, error)
 	CountByClientID(context.Context, string, *query.ListOptions) (int, error)
+	ListLatestMeasurements(context.Context) ([]*models.Measurement, error)
 	Close() error
 }
 
@@ -106,6 +107,17 @@ func (p *SqliteProvider) CountByClientID(ctx context.Context, clientID string, o
 	return result, nil
 }
 
+// ListLatestMeasurements returns the processes and mountpoints of the latest measurement of each client
+func (p *SqliteProvider) ListLatestMeasurements(ctx context.Context) ([]*models.Measurement, error) {
+	q := `SELECT client_id, timestamp, COALESCE(processes, '') AS processes, COALESCE(mountpoints, '') AS mountpoints
+		FROM measurements AS m
+		WHERE timestamp = (SELECT MAX(timestamp) FROM measurements WHERE client_id = m.client_id)`
+
+	val := []*models.Measurement{}
+	err := p.db.SelectContext(ctx, &val, q)
+	return val, err
+}
+
 func (p *SqliteProvider) ListGraphMetricsByClientID(ctx context.Context, clientID string, hours float64, lo *query.ListOptions) ([]*ClientGraphMetricsPayload, error) {
 	params := []interface{}{}
 	params = append(params, clientID)
@@ -128,6 +128,39 @@ func TestSqliteProvider_CountByClientID(t *testing.T) {
 	require.Equal(t, 3, count)
 }
 
+func TestSqliteProvider_ListLatestMeasurements(t *testing.T) {
+	dbProvider, err := NewSqliteProvider(":memory:", DataSourceOptions, testLog)
+	require.NoError(t, err)
+	defer dbProvider.Close()
+
+	ctx := context.Background()
+
+	err = createTestData(ctx, dbProvider)
+	require.NoError(t, err)
+	err = dbProvider.CreateMeasurement(ctx, &models.Measurement{
+		ClientID:  "test_client_2",
+		Timestamp: measurement1,
+	})
+	require.NoError(t, err)
+
+	measurements, err := dbProvider.ListLatestMeasurements(ctx)
+	require.NoError(t, err)
+	require.Len(t, measurements, 2)
+	for _, m := range measurements {
+		switch m.ClientID {
+		case "test_client_1":
+			require.Equal(t, measurement3, m.Timestamp.UTC())
+			require.Equal(t, testData[2].Processes, m.Processes)
+			require.Equal(t, testData[2].Mountpoints, m.Mountpoints)
+		case "test_client_2":
+			require.Equal(t, "", m.Processes)
+			require.Equal(t, "", m.Mountpoints)
+		default:
+			t.Fatalf("unexpected client %s", m.ClientID)
+		}
+	}
+}
+
 func TestSqliteProvider_ListMetricsLatestByClientID(t *testing.T) {
 	dbProvider, err := NewSqliteProvider(":memory:", DataSourceOptions, testLog)
 	require.NoError(t, err)
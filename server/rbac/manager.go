@@ -0,0 +1,312 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+)
+
+var ErrRoleInUse = errors.New("role is assigned to user groups")
+
+type GroupGetter interface {
+	GetGroup(name string) (users.Group, error)
+}
+
+// Manager persists custom roles and role bindings and keeps them in memory, so authorization doesn't hit the database.
+type Manager struct {
+	provider Provider
+	groups   GroupGetter
+
+	mu       sync.RWMutex
+	roles    map[string]*Role
+	bindings []*Binding
+}
+
+func NewManager(ctx context.Context, provider Provider, groups GroupGetter) (*Manager, error) {
+	roles, err := provider.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	bindings, err := provider.ListBindings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role bindings: %w", err)
+	}
+
+	m := &Manager{
+		provider: provider,
+		groups:   groups,
+		roles:    make(map[string]*Role, len(roles)),
+		bindings: bindings,
+	}
+	for _, r := range roles {
+		m.roles[r.Name] = r
+	}
+	return m, nil
+}
+
+// ListRoles returns the built-in roles followed by the custom roles sorted by name.
+func (m *Manager) ListRoles() []*Role {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]*Role, 0, len(builtInRoles)+len(m.roles))
+	res = append(res, builtInRoles...)
+	custom := make([]*Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		custom = append(custom, r)
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+	return append(res, custom...)
+}
+
+// GetRole returns a built-in or a custom role by name or nil if it doesn't exist.
+func (m *Manager) GetRole(name string) *Role {
+	if r := BuiltInRole(name); r != nil {
+		return r
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.roles[name]
+}
+
+func (m *Manager) SaveRole(ctx context.Context, r *Role) error {
+	if err := m.provider.SaveRole(ctx, r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roles[r.Name] = r
+	return nil
+}
+
+// DeleteRole deletes a custom role. Roles assigned to user groups cannot be deleted.
+func (m *Manager) DeleteRole(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, b := range m.bindings {
+		if b.Role == name {
+			return ErrRoleInUse
+		}
+	}
+	if err := m.provider.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *Manager) ListBindings() []*Binding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Binding{}, m.bindings...)
+}
+
+func (m *Manager) GetBinding(id string) *Binding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, b := range m.bindings {
+		if b.ID == id {
+			return b
+		}
+	}
+	return nil
+}
+
+func (m *Manager) AddBinding(ctx context.Context, b *Binding) error {
+	if m.GetRole(b.Role) == nil {
+		return fmt.Errorf("role %q not found", b.Role)
+	}
+	if err := m.provider.SaveBinding(ctx, b); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bindings = append(m.bindings, b)
+	return nil
+}
+
+func (m *Manager) DeleteBinding(ctx context.Context, id string) error {
+	if err := m.provider.DeleteBinding(ctx, id); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, b := range m.bindings {
+		if b.ID == id {
+			m.bindings = append(m.bindings[:i], m.bindings[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Request is an action on a resource type to authorize.
+type Request struct {
+	Resource string
+	Action   string
+	// Client is the client the action is performed on, if any. Roles scoped to client groups apply to it only.
+	Client       *clientdata.Client
+	ClientGroups []*cgroups.ClientGroup
+}
+
+// Decision explains why an action was allowed or denied.
+type Decision struct {
+	Allowed  bool   `json:"allowed"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	ClientID string `json:"client_id,omitempty"`
+	// Reasons lists how each role of the user was evaluated.
+	Reasons []string `json:"reasons"`
+}
+
+func (d *Decision) String() string {
+	return strings.Join(d.Reasons, "; ")
+}
+
+// Authorize decides whether a user may perform an action. The permissions of the user groups map onto
+// built-in roles, the role bindings of the user groups are evaluated in addition.
+func (m *Manager) Authorize(user *users.User, req Request) (*Decision, error) {
+	d := &Decision{
+		Resource: req.Resource,
+		Action:   req.Action,
+		Reasons:  []string{},
+	}
+	if req.Client != nil {
+		d.ClientID = req.Client.GetID()
+	}
+
+	if user.IsAdmin() {
+		d.Allowed = true
+		d.Reasons = append(d.Reasons, fmt.Sprintf("user group %q: role %q allows everything", users.Administrators, RoleAdmin))
+		return d, nil
+	}
+
+	for _, groupName := range user.Groups {
+		group, err := m.groups.GetGroup(groupName)
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range users.AllPermissions {
+			if !group.Permissions.Has(permission) {
+				continue
+			}
+			role := BuiltInRole(permission)
+			if role.Allows(req.Resource, req.Action) {
+				d.allow("user group %q: role %q from permission %q allows %s on %s", groupName, role.Name, permission, req.Action, req.Resource)
+			} else {
+				d.deny("user group %q: role %q from permission %q does not allow %s on %s", groupName, role.Name, permission, req.Action, req.Resource)
+			}
+		}
+	}
+
+	for _, b := range m.ListBindings() {
+		if !contains(user.Groups, b.UserGroup) {
+			continue
+		}
+		m.evaluateBinding(d, b, req)
+	}
+
+	if len(d.Reasons) == 0 {
+		d.Reasons = append(d.Reasons, "no roles are assigned to the user groups")
+	}
+	return d, nil
+}
+
+func (m *Manager) evaluateBinding(d *Decision, b *Binding, req Request) {
+	role := m.GetRole(b.Role)
+	if role == nil {
+		d.deny("user group %q: role %q of binding %q not found", b.UserGroup, b.Role, b.ID)
+		return
+	}
+	if !role.Allows(req.Resource, req.Action) {
+		d.deny("user group %q: role %q does not allow %s on %s", b.UserGroup, role.Name, req.Action, req.Resource)
+		return
+	}
+	if len(b.ClientGroupIDs) == 0 {
+		d.allow("user group %q: role %q allows %s on %s", b.UserGroup, role.Name, req.Action, req.Resource)
+		return
+	}
+	if req.Client == nil {
+		d.deny("user group %q: role %q is scoped to client groups %v and the request doesn't target a single client", b.UserGroup, role.Name, b.ClientGroupIDs)
+		return
+	}
+	if !belongsToOneOf(req.Client, b.ClientGroupIDs, req.ClientGroups) {
+		d.deny("user group %q: role %q is scoped to client groups %v the client doesn't belong to", b.UserGroup, role.Name, b.ClientGroupIDs)
+		return
+	}
+	d.allow("user group %q: role %q scoped to client groups %v allows %s on %s", b.UserGroup, role.Name, b.ClientGroupIDs, req.Action, req.Resource)
+}
+
+func (d *Decision) allow(format string, args ...interface{}) {
+	d.Allowed = true
+	d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+}
+
+func (d *Decision) deny(format string, args ...interface{}) {
+	d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+}
+
+// HasClientAccess returns whether a role binding of the user groups allows to read a client.
+func (m *Manager) HasClientAccess(_ string, userGroups []string, client *clientdata.Client, clientGroups []*cgroups.ClientGroup) bool {
+	for _, b := range m.ListBindings() {
+		if !contains(userGroups, b.UserGroup) {
+			continue
+		}
+		role := m.GetRole(b.Role)
+		if role == nil || !role.Allows(ResourceClients, ActionRead) {
+			continue
+		}
+		if len(b.ClientGroupIDs) == 0 || belongsToOneOf(client, b.ClientGroupIDs, clientGroups) {
+			return true
+		}
+	}
+	return false
+}
+
+func belongsToOneOf(client *clientdata.Client, clientGroupIDs []string, clientGroups []*cgroups.ClientGroup) bool {
+	for _, group := range clientGroups {
+		if contains(clientGroupIDs, group.ID) && client.BelongsTo(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync reloads the roles and role bindings, so changes made by other servers sharing the database take effect.
+func (m *Manager) Sync(ctx context.Context) error {
+	// the lock is held while loading, so changes made meanwhile by this server are not overwritten
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles, err := m.provider.ListRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+	bindings, err := m.provider.ListBindings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load role bindings: %w", err)
+	}
+
+	m.roles = make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		m.roles[r.Name] = r
+	}
+	m.bindings = bindings
+	return nil
+}
+
+func (m *Manager) Close() error {
+	return m.provider.Close()
+}
@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rbacmigration "github.com/riportdev/riport/db/migration/rbac"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/server/cgroups"
+	"github.com/riportdev/riport/server/clients/clientdata"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+type groupsMock map[string]users.Group
+
+func (m groupsMock) GetGroup(name string) (users.Group, error) {
+	return m[name], nil
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", rbacmigration.AssetNames(), rbacmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+
+	groups := groupsMock{
+		"ops": users.NewGroup("ops", nil, nil, users.PermissionTunnels),
+		"dba": users.NewGroup("dba", nil, nil),
+	}
+	m, err := NewManager(ctx, NewSqliteProvider(db), groups)
+	require.NoError(t, err)
+	defer m.Close()
+
+	role := &Role{
+		Name: "db-operator",
+		Rules: Rules{
+			{Resource: ResourceClients, Actions: []string{ActionRead}},
+			{Resource: ResourceCommands, Actions: []string{ActionRead, ActionExecute}},
+		},
+		CreatedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBy: "admin",
+	}
+	require.NoError(t, m.SaveRole(ctx, role))
+	binding := &Binding{
+		ID:             "b1",
+		UserGroup:      "dba",
+		Role:           role.Name,
+		ClientGroupIDs: []string{"databases"},
+		CreatedAt:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBy:      "admin",
+	}
+	require.NoError(t, m.AddBinding(ctx, binding))
+	assert.EqualError(t, m.AddBinding(ctx, &Binding{ID: "b2", UserGroup: "dba", Role: "unknown"}), `role "unknown" not found`)
+
+	clientGroups := []*cgroups.ClientGroup{
+		{ID: "databases", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"db-*"}}},
+	}
+	db1 := &clientdata.Client{ID: "db-1"}
+	web1 := &clientdata.Client{ID: "web-1"}
+
+	opsUser := &users.User{Username: "ops-user", Groups: []string{"ops"}}
+	dbaUser := &users.User{Username: "dba-user", Groups: []string{"dba"}}
+
+	d, err := m.Authorize(opsUser, Request{Resource: ResourceTunnels, Action: ActionCreate})
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, []string{`user group "ops": role "tunnels" from permission "tunnels" allows create on tunnels`}, d.Reasons)
+
+	d, err = m.Authorize(dbaUser, Request{Resource: ResourceCommands, Action: ActionExecute, Client: db1, ClientGroups: clientGroups})
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = m.Authorize(dbaUser, Request{Resource: ResourceCommands, Action: ActionExecute, Client: web1, ClientGroups: clientGroups})
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, `user group "dba": role "db-operator" is scoped to client groups [databases] the client doesn't belong to`, d.String())
+
+	d, err = m.Authorize(dbaUser, Request{Resource: ResourceVault, Action: ActionRead})
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, `user group "dba": role "db-operator" does not allow read on vault`, d.String())
+
+	assert.True(t, m.HasClientAccess("dba-user", dbaUser.Groups, db1, clientGroups))
+	assert.False(t, m.HasClientAccess("dba-user", dbaUser.Groups, web1, clientGroups))
+	assert.False(t, m.HasClientAccess("ops-user", opsUser.Groups, db1, clientGroups))
+
+	assert.ErrorIs(t, m.DeleteRole(ctx, role.Name), ErrRoleInUse)
+	require.NoError(t, m.DeleteBinding(ctx, binding.ID))
+	require.NoError(t, m.DeleteRole(ctx, role.Name))
+	assert.Nil(t, m.GetRole(role.Name))
+
+	// reload from the database
+	m2, err := NewManager(ctx, NewSqliteProvider(db), groups)
+	require.NoError(t, err)
+	assert.Empty(t, m2.ListBindings())
+	assert.Len(t, m2.ListRoles(), len(BuiltInRoles()))
+}
+
+func TestManagerSync(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", rbacmigration.AssetNames(), rbacmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	p := NewSqliteProvider(db)
+
+	// two servers sharing the database
+	groups := groupsMock{"dba": users.NewGroup("dba", nil, nil)}
+	m1, err := NewManager(ctx, p, groups)
+	require.NoError(t, err)
+	defer m1.Close()
+	m2, err := NewManager(ctx, p, groups)
+	require.NoError(t, err)
+
+	role := &Role{
+		Name:  "db-operator",
+		Rules: Rules{{Resource: ResourceCommands, Actions: []string{ActionExecute}}},
+	}
+	require.NoError(t, m1.SaveRole(ctx, role))
+	require.NoError(t, m1.AddBinding(ctx, &Binding{ID: "b1", UserGroup: "dba", Role: role.Name}))
+
+	dbaUser := &users.User{Username: "dba-user", Groups: []string{"dba"}}
+	d, err := m2.Authorize(dbaUser, Request{Resource: ResourceCommands, Action: ActionExecute})
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+
+	require.NoError(t, m2.Sync(ctx))
+	assert.NotNil(t, m2.GetRole(role.Name))
+	d, err = m2.Authorize(dbaUser, Request{Resource: ResourceCommands, Action: ActionExecute})
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	require.NoError(t, m1.DeleteBinding(ctx, "b1"))
+	require.NoError(t, m2.Sync(ctx))
+	assert.Empty(t, m2.ListBindings())
+}
@@ -0,0 +1,260 @@
+package rbac
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/share/types"
+)
+
+const (
+	ActionRead = "read"
+	// ActionCreate covers creating and updating resources.
+	ActionCreate  = "create"
+	ActionDelete  = "delete"
+	ActionExecute = "execute"
+)
+
+var AllActions = []string{ActionRead, ActionCreate, ActionDelete, ActionExecute}
+
+const (
+	ResourceClients    = "clients"
+	ResourceTunnels    = "tunnels"
+	ResourceCommands   = "commands"
+	ResourceScripts    = "scripts"
+	ResourceLibrary    = "library"
+	ResourceVault      = "vault"
+	ResourceSchedules  = "schedules"
+	ResourceAuditLog   = "auditlog"
+	ResourceUsers      = "users"
+	ResourceMonitoring = "monitoring"
+	ResourceUploads    = "uploads"
+)
+
+var AllResources = []string{
+	ResourceClients,
+	ResourceTunnels,
+	ResourceCommands,
+	ResourceScripts,
+	ResourceLibrary,
+	ResourceVault,
+	ResourceSchedules,
+	ResourceAuditLog,
+	ResourceUsers,
+	ResourceMonitoring,
+	ResourceUploads,
+}
+
+// RoleAdmin is the built-in role of the Administrators group.
+const RoleAdmin = "admin"
+
+var roleNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// Rule allows actions on a resource type.
+type Rule struct {
+	Resource string   `json:"resource"`
+	Actions  []string `json:"actions"`
+}
+
+func (r Rule) allows(resource, action string) bool {
+	return r.Resource == resource && contains(r.Actions, action)
+}
+
+// Rules is used for storing rules as a json db column.
+type Rules []Rule
+
+func (r *Rules) Scan(value interface{}) error {
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	if err := json.Unmarshal([]byte(valueStr), r); err != nil {
+		return fmt.Errorf("failed to decode rules: %v", err)
+	}
+	return nil
+}
+
+func (r Rules) Value() (driver.Value, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %v", err)
+	}
+	return string(b), nil
+}
+
+// Role combines actions on resource types.
+type Role struct {
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Rules       Rules     `json:"rules" db:"rules"`
+	BuiltIn     bool      `json:"built_in" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+}
+
+func (r *Role) Validate() error {
+	if !roleNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("invalid name %q, only lowercase letters, digits, '_', '.' and '-' are allowed", r.Name)
+	}
+	if BuiltInRole(r.Name) != nil {
+		return fmt.Errorf("%q is a built-in role", r.Name)
+	}
+	if len(r.Rules) == 0 {
+		return errors.New("rules cannot be empty")
+	}
+	for _, rule := range r.Rules {
+		if !contains(AllResources, rule.Resource) {
+			return fmt.Errorf("unknown resource %q, allowed resources: %v", rule.Resource, AllResources)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("actions of resource %q cannot be empty", rule.Resource)
+		}
+		for _, action := range rule.Actions {
+			if !contains(AllActions, action) {
+				return fmt.Errorf("unknown action %q, allowed actions: %v", action, AllActions)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows returns whether the role allows an action on a resource type.
+func (r *Role) Allows(resource, action string) bool {
+	for _, rule := range r.Rules {
+		if rule.allows(resource, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Binding assigns a role to a user group.
+type Binding struct {
+	ID        string `json:"id" db:"id"`
+	UserGroup string `json:"user_group" db:"user_group"`
+	Role      string `json:"role" db:"role"`
+	// ClientGroupIDs scope the role to clients of the given client groups. Empty means the role is not scoped.
+	ClientGroupIDs types.StringSlice `json:"client_group_ids" db:"client_group_ids"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	CreatedBy      string            `json:"created_by" db:"created_by"`
+}
+
+func (b *Binding) Validate() error {
+	if b.UserGroup == "" {
+		return errors.New("user_group cannot be empty")
+	}
+	if b.Role == "" {
+		return errors.New("role cannot be empty")
+	}
+	return nil
+}
+
+var builtInRoles = []*Role{
+	{
+		Name:        RoleAdmin,
+		Description: "All actions on all resources, given to the Administrators group.",
+		Rules:       allRules(),
+	},
+	{
+		Name:        users.PermissionTunnels,
+		Description: `Given to user groups with the "tunnels" permission.`,
+		Rules: Rules{
+			{Resource: ResourceTunnels, Actions: []string{ActionRead, ActionCreate, ActionDelete}},
+		},
+	},
+	{
+		Name:        users.PermissionCommands,
+		Description: `Given to user groups with the "commands" permission.`,
+		Rules: Rules{
+			{Resource: ResourceCommands, Actions: []string{ActionRead, ActionExecute}},
+			{Resource: ResourceLibrary, Actions: []string{ActionRead, ActionCreate, ActionDelete}},
+		},
+	},
+	{
+		Name:        users.PermissionScripts,
+		Description: `Given to user groups with the "scripts" permission.`,
+		Rules: Rules{
+			{Resource: ResourceScripts, Actions: []string{ActionRead, ActionExecute}},
+			{Resource: ResourceLibrary, Actions: []string{ActionRead, ActionCreate, ActionDelete}},
+		},
+	},
+	{
+		Name:        users.PermissionVault,
+		Description: `Given to user groups with the "vault" permission.`,
+		Rules: Rules{
+			{Resource: ResourceVault, Actions: []string{ActionRead, ActionCreate, ActionDelete}},
+		},
+	},
+	{
+		Name:        users.PermissionScheduler,
+		Description: `Given to user groups with the "scheduler" permission.`,
+		Rules: Rules{
+			{Resource: ResourceSchedules, Actions: []string{ActionRead, ActionCreate, ActionDelete, ActionExecute}},
+		},
+	},
+	{
+		Name:        users.PermissionMonitoring,
+		Description: `Given to user groups with the "monitoring" permission.`,
+		Rules: Rules{
+			{Resource: ResourceMonitoring, Actions: []string{ActionRead}},
+		},
+	},
+	{
+		Name:        users.PermissionUploads,
+		Description: `Given to user groups with the "uploads" permission.`,
+		Rules: Rules{
+			{Resource: ResourceUploads, Actions: []string{ActionCreate}},
+		},
+	},
+	{
+		Name:        users.PermissionsAuditLog,
+		Description: `Given to user groups with the "auditlog" permission.`,
+		Rules: Rules{
+			{Resource: ResourceAuditLog, Actions: []string{ActionRead}},
+		},
+	},
+}
+
+func init() {
+	for _, r := range builtInRoles {
+		r.BuiltIn = true
+	}
+}
+
+func allRules() Rules {
+	rules := make(Rules, 0, len(AllResources))
+	for _, resource := range AllResources {
+		rules = append(rules, Rule{Resource: resource, Actions: AllActions})
+	}
+	return rules
+}
+
+// BuiltInRoles returns the roles the user group permissions map onto.
+func BuiltInRoles() []*Role {
+	return builtInRoles
+}
+
+// BuiltInRole returns a built-in role by name or nil if it doesn't exist.
+// The built-in roles are named after the user group permissions they map onto.
+func BuiltInRole(name string) *Role {
+	for _, r := range builtInRoles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
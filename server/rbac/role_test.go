@@ -0,0 +1,88 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riportdev/riport/server/api/users"
+)
+
+func TestRoleValidate(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Role          Role
+		ExpectedError string
+	}{
+		{
+			Name: "valid",
+			Role: Role{
+				Name:  "db-operator",
+				Rules: Rules{{Resource: ResourceCommands, Actions: []string{ActionRead, ActionExecute}}},
+			},
+		},
+		{
+			Name: "invalid name",
+			Role: Role{
+				Name:  "DB Operator",
+				Rules: Rules{{Resource: ResourceCommands, Actions: []string{ActionRead}}},
+			},
+			ExpectedError: `invalid name "DB Operator", only lowercase letters, digits, '_', '.' and '-' are allowed`,
+		},
+		{
+			Name: "built-in name",
+			Role: Role{
+				Name:  users.PermissionVault,
+				Rules: Rules{{Resource: ResourceVault, Actions: []string{ActionRead}}},
+			},
+			ExpectedError: `"vault" is a built-in role`,
+		},
+		{
+			Name:          "no rules",
+			Role:          Role{Name: "empty"},
+			ExpectedError: "rules cannot be empty",
+		},
+		{
+			Name: "unknown action",
+			Role: Role{
+				Name:  "reader",
+				Rules: Rules{{Resource: ResourceVault, Actions: []string{"write"}}},
+			},
+			ExpectedError: "unknown action \"write\", allowed actions: [read create delete execute]",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Role.Validate()
+
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBuiltInRoles(t *testing.T) {
+	for _, permission := range users.AllPermissions {
+		role := BuiltInRole(permission)
+		if assert.NotNil(t, role, permission) {
+			assert.True(t, role.BuiltIn)
+		}
+	}
+
+	admin := BuiltInRole(RoleAdmin)
+	for _, resource := range AllResources {
+		for _, action := range AllActions {
+			assert.True(t, admin.Allows(resource, action))
+		}
+	}
+
+	commands := BuiltInRole(users.PermissionCommands)
+	assert.True(t, commands.Allows(ResourceCommands, ActionExecute))
+	assert.True(t, commands.Allows(ResourceLibrary, ActionCreate))
+	assert.False(t, commands.Allows(ResourceScripts, ActionExecute))
+}
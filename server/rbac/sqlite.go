@@ -0,0 +1,79 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Provider interface {
+	ListRoles(ctx context.Context) ([]*Role, error)
+	SaveRole(ctx context.Context, r *Role) error
+	DeleteRole(ctx context.Context, name string) error
+	ListBindings(ctx context.Context) ([]*Binding, error)
+	SaveBinding(ctx context.Context, b *Binding) error
+	DeleteBinding(ctx context.Context, id string) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db: db,
+	}
+}
+
+func (p *SqliteProvider) ListRoles(ctx context.Context) ([]*Role, error) {
+	res := []*Role{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM roles ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SaveRole(ctx context.Context, r *Role) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO roles (name, description, rules, created_at, created_by)
+		VALUES (:name, :description, :rules, :created_at, :created_by)`,
+		r,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeleteRole(ctx context.Context, name string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM roles WHERE name = ?", name)
+	return err
+}
+
+func (p *SqliteProvider) ListBindings(ctx context.Context) ([]*Binding, error) {
+	res := []*Binding{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM role_bindings ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SaveBinding(ctx context.Context, b *Binding) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO role_bindings (id, user_group, role, client_group_ids, created_at, created_by)
+		VALUES (:id, :user_group, :role, :client_group_ids, :created_at, :created_by)`,
+		b,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeleteBinding(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM role_bindings WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
@@ -16,6 +16,14 @@ const (
 	ParamProblemID        = "problem_id"
 	ParamNotificationID   = "notification_id"
 	ParamSampleDataChoice = "sample_data_choice"
+	ParamPolicyID         = "policy_id"
+	ParamApprovalID       = "approval_id"
+	ParamGrantID          = "grant_id"
+	ParamRoleName         = "role_name"
+	ParamBindingID        = "binding_id"
+	ParamSCIMID           = "scim_id"
+	ParamCredentialID     = "credential_id"
+	ParamEnrollmentToken  = "enrollment_token_id"
 
 	AllRoutesPrefix             = "/api/v1"
 	AuthRoutesPrefix            = "/auth"
@@ -29,6 +37,8 @@ const (
 	ASRunTestRulesRoute         = "/test"
 	ASSampleDataRoute           = "/sample-data"
 	TotPRoutes                  = "/me/totp-secret"
+	WebAuthnRegistrationRoute   = "/me/webauthn/registration"
+	WebAuthnCredentialsRoute    = "/me/webauthn/credentials"
 	Verify2FaRoute              = "/verify-2fa"
 	FilesUploadRouteName        = "files"
 )
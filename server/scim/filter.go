@@ -0,0 +1,115 @@
+package scim
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	opEqual      = "eq"
+	opNotEqual   = "ne"
+	opContains   = "co"
+	opStartsWith = "sw"
+	opEndsWith   = "ew"
+	opPresent    = "pr"
+)
+
+// caseExactAttributes are compared case sensitive, all other attributes are compared case insensitive.
+var caseExactAttributes = map[string]bool{
+	"id":         true,
+	"externalid": true,
+}
+
+// Filter is a single attribute expression like `userName eq "john"`. Identity providers use filters to look up
+// resources before they create them, logical operators and grouping are not supported.
+type Filter struct {
+	Attribute string
+	Operator  string
+	Value     string
+}
+
+type filterable interface {
+	attributeValues(attr string) []string
+}
+
+// ParseFilter parses the filter query parameter. An empty string results in a nil filter which matches everything.
+func ParseFilter(s string) (*Filter, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(s, " ", 3)
+	if len(parts) < 2 {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidFilter, "invalid filter %q", s)
+	}
+
+	f := &Filter{
+		Attribute: strings.ToLower(parts[0]),
+		Operator:  strings.ToLower(parts[1]),
+	}
+	switch f.Operator {
+	case opPresent:
+		if len(parts) > 2 {
+			return nil, NewError(http.StatusBadRequest, ErrInvalidFilter, "invalid filter %q", s)
+		}
+		return f, nil
+	case opEqual, opNotEqual, opContains, opStartsWith, opEndsWith:
+	default:
+		return nil, NewError(http.StatusBadRequest, ErrInvalidFilter, "unsupported filter operator %q", parts[1])
+	}
+
+	if len(parts) < 3 {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidFilter, "invalid filter %q", s)
+	}
+	value := strings.TrimSpace(parts[2])
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+	} else if strings.ContainsAny(value, ` "`) {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidFilter, "invalid filter value %s", value)
+	}
+	f.Value = value
+
+	return f, nil
+}
+
+func (f *Filter) matches(r filterable) bool {
+	if f == nil {
+		return true
+	}
+
+	values := r.attributeValues(f.Attribute)
+	if f.Operator == opPresent {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	expected := f.Value
+	if !caseExactAttributes[f.Attribute] {
+		expected = strings.ToLower(expected)
+	}
+	for _, v := range values {
+		if !caseExactAttributes[f.Attribute] {
+			v = strings.ToLower(v)
+		}
+		var ok bool
+		switch f.Operator {
+		case opEqual, opNotEqual:
+			ok = v == expected
+		case opContains:
+			ok = strings.Contains(v, expected)
+		case opStartsWith:
+			ok = strings.HasPrefix(v, expected)
+		case opEndsWith:
+			ok = strings.HasSuffix(v, expected)
+		}
+		if ok {
+			return f.Operator != opNotEqual
+		}
+	}
+	return f.Operator == opNotEqual
+}
@@ -0,0 +1,103 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		filter        string
+		expected      *Filter
+		expectedError string
+	}{
+		{
+			name:   "empty",
+			filter: " ",
+		},
+		{
+			name:     "eq",
+			filter:   `userName eq "John.Doe@example.com"`,
+			expected: &Filter{Attribute: "username", Operator: "eq", Value: "John.Doe@example.com"},
+		},
+		{
+			name:     "value with spaces and quotes",
+			filter:   `displayName Eq "DevOps \"EU\" team"`,
+			expected: &Filter{Attribute: "displayname", Operator: "eq", Value: `DevOps "EU" team`},
+		},
+		{
+			name:     "unquoted value",
+			filter:   `active eq true`,
+			expected: &Filter{Attribute: "active", Operator: "eq", Value: "true"},
+		},
+		{
+			name:     "present",
+			filter:   `externalId pr`,
+			expected: &Filter{Attribute: "externalid", Operator: "pr"},
+		},
+		{
+			name:          "missing value",
+			filter:        `userName eq`,
+			expectedError: `invalid filter "userName eq"`,
+		},
+		{
+			name:          "unsupported operator",
+			filter:        `meta.lastModified gt "2011-05-13T04:42:34Z"`,
+			expectedError: `unsupported filter operator "gt"`,
+		},
+		{
+			name:          "logical operator",
+			filter:        `userName eq "a" and active eq true`,
+			expectedError: `invalid filter value "a" and active eq true`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFilter(tc.filter)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	user := &User{
+		ID:         "5f1a",
+		ExternalID: "Ext-1",
+		UserName:   "John.Doe",
+		Emails:     []Email{{Value: "john@example.com"}},
+	}
+
+	testCases := []struct {
+		filter   string
+		expected bool
+	}{
+		{filter: ``, expected: true},
+		{filter: `userName eq "john.doe"`, expected: true},
+		{filter: `userName ne "john.doe"`, expected: false},
+		{filter: `userName sw "john"`, expected: true},
+		{filter: `emails.value ew "@example.com"`, expected: true},
+		{filter: `emails co "other"`, expected: false},
+		{filter: `externalId eq "Ext-1"`, expected: true},
+		{filter: `externalId eq "ext-1"`, expected: false},
+		{filter: `active eq "true"`, expected: true},
+		{filter: `externalId pr`, expected: true},
+		{filter: `title pr`, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filter, func(t *testing.T) {
+			f, err := ParseFilter(tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f.matches(user))
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (p *PatchRequest) Validate() error {
+	if len(p.Operations) == 0 {
+		return NewError(http.StatusBadRequest, ErrInvalidSyntax, "at least one operation is required")
+	}
+	for _, o := range p.Operations {
+		switch strings.ToLower(o.Op) {
+		case patchOpAdd, patchOpReplace:
+			if len(o.Value) == 0 {
+				return NewError(http.StatusBadRequest, ErrInvalidSyntax, "operation %q requires a value", o.Op)
+			}
+		case patchOpRemove:
+			if o.Path == "" {
+				return NewError(http.StatusBadRequest, ErrNoTarget, "operation %q requires a path", o.Op)
+			}
+		default:
+			return NewError(http.StatusBadRequest, ErrInvalidSyntax, "unsupported operation %q", o.Op)
+		}
+	}
+	return nil
+}
+
+// applyToUser applies the operations to the user. Attributes that can't be stored, like name or title, are ignored
+// because identity providers send them regardless of the attribute mapping.
+func (p *PatchRequest) applyToUser(u *User) error {
+	for _, o := range p.Operations {
+		op := strings.ToLower(o.Op)
+		if o.Path == "" {
+			values, err := decodeObject(o.Value)
+			if err != nil {
+				return err
+			}
+			for attr, value := range values {
+				if err := setUserAttribute(u, attr, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if op == patchOpRemove {
+			removeUserAttribute(u, o.Path)
+			continue
+		}
+		if err := setUserAttribute(u, o.Path, o.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setUserAttribute(u *User, path string, value json.RawMessage) error {
+	attr := normalizePath(path, SchemaUser)
+	switch {
+	case attr == "active":
+		active, err := decodeBool(path, value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case attr == "username":
+		return decodeString(path, value, &u.UserName)
+	case attr == "externalid":
+		return decodeString(path, value, &u.ExternalID)
+	case attr == "password":
+		return decodeString(path, value, &u.Password)
+	case attr == "emails":
+		if err := json.Unmarshal(value, &u.Emails); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "invalid value of %q: %v", path, err)
+		}
+	case strings.HasPrefix(attr, "emails[") && strings.HasSuffix(attr, "].value"):
+		var email string
+		if err := decodeString(path, value, &email); err != nil {
+			return err
+		}
+		setPrimaryEmail(u, email)
+	}
+	return nil
+}
+
+func removeUserAttribute(u *User, path string) {
+	switch normalizePath(path, SchemaUser) {
+	case "externalid":
+		u.ExternalID = ""
+	case "emails":
+		u.Emails = nil
+	}
+}
+
+func setPrimaryEmail(u *User, email string) {
+	for i := range u.Emails {
+		if u.Emails[i].Primary {
+			u.Emails[i].Value = email
+			return
+		}
+	}
+	if len(u.Emails) > 0 {
+		u.Emails[0].Value = email
+		return
+	}
+	u.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+}
+
+func (p *PatchRequest) applyToGroup(g *Group) error {
+	for _, o := range p.Operations {
+		op := strings.ToLower(o.Op)
+		if o.Path == "" {
+			values, err := decodeObject(o.Value)
+			if err != nil {
+				return err
+			}
+			for attr, value := range values {
+				if err := setGroupAttribute(g, op, attr, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if op == patchOpRemove {
+			if err := removeGroupAttribute(g, o.Path, o.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setGroupAttribute(g, op, o.Path, o.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setGroupAttribute(g *Group, op, path string, value json.RawMessage) error {
+	switch normalizePath(path, SchemaGroup) {
+	case "displayname":
+		return decodeString(path, value, &g.DisplayName)
+	case "externalid":
+		return decodeString(path, value, &g.ExternalID)
+	case "members":
+		var members []Member
+		if err := json.Unmarshal(value, &members); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "invalid value of %q: %v", path, err)
+		}
+		if op == patchOpReplace {
+			g.Members = nil
+		}
+		for _, m := range members {
+			if !hasMember(g, m.Value) {
+				g.Members = append(g.Members, m)
+			}
+		}
+	}
+	return nil
+}
+
+func removeGroupAttribute(g *Group, path string, value json.RawMessage) error {
+	attr := normalizePath(path, SchemaGroup)
+	switch {
+	case attr == "externalid":
+		g.ExternalID = ""
+	case attr == "members":
+		if len(value) == 0 {
+			g.Members = nil
+			return nil
+		}
+		var members []Member
+		if err := json.Unmarshal(value, &members); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "invalid value of %q: %v", path, err)
+		}
+		for _, m := range members {
+			removeMember(g, m.Value)
+		}
+	case strings.HasPrefix(attr, "members[") && strings.HasSuffix(attr, "]"):
+		// the value filter is parsed from the original path to keep the case of the member ID
+		start := strings.Index(path, "[")
+		f, err := ParseFilter(path[start+1 : len(path)-1])
+		if err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidPath, "invalid path %q", path)
+		}
+		if f == nil || f.Attribute != "value" || f.Operator != opEqual {
+			return NewError(http.StatusBadRequest, ErrInvalidPath, "unsupported path %q", path)
+		}
+		removeMember(g, f.Value)
+	}
+	return nil
+}
+
+func hasMember(g *Group, id string) bool {
+	for _, m := range g.Members {
+		if m.Value == id {
+			return true
+		}
+	}
+	return false
+}
+
+func removeMember(g *Group, id string) {
+	members := g.Members[:0]
+	for _, m := range g.Members {
+		if m.Value != id {
+			members = append(members, m)
+		}
+	}
+	g.Members = members
+}
+
+// normalizePath lowercases the attribute path and strips the optional schema prefix.
+func normalizePath(path, schema string) string {
+	attr := strings.ToLower(strings.TrimSpace(path))
+	return strings.TrimPrefix(attr, strings.ToLower(schema)+":")
+}
+
+func decodeObject(value json.RawMessage) (map[string]json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	if err := json.Unmarshal(value, &values); err != nil {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "operations without a path require an object value: %v", err)
+	}
+	return values, nil
+}
+
+func decodeString(path string, value json.RawMessage, dst *string) error {
+	if err := json.Unmarshal(value, dst); err != nil {
+		return NewError(http.StatusBadRequest, ErrInvalidValue, "invalid value of %q: string expected", path)
+	}
+	return nil
+}
+
+// decodeBool accepts booleans as well as strings, some identity providers send "True" and "False".
+func decodeBool(path string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, NewError(http.StatusBadRequest, ErrInvalidValue, "invalid value of %q: boolean expected", path)
+}
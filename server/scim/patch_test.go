@@ -0,0 +1,108 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parsePatch(t *testing.T, data string) *PatchRequest {
+	p := &PatchRequest{}
+	require.NoError(t, json.Unmarshal([]byte(data), p))
+	require.NoError(t, p.Validate())
+	return p
+}
+
+func TestPatchRequestValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		patch         PatchRequest
+		expectedError string
+	}{
+		{
+			name:          "no operations",
+			expectedError: "at least one operation is required",
+		},
+		{
+			name:          "unsupported operation",
+			patch:         PatchRequest{Operations: []PatchOperation{{Op: "move", Path: "active"}}},
+			expectedError: `unsupported operation "move"`,
+		},
+		{
+			name:          "missing value",
+			patch:         PatchRequest{Operations: []PatchOperation{{Op: "Replace", Path: "active"}}},
+			expectedError: `operation "Replace" requires a value`,
+		},
+		{
+			name:          "remove without path",
+			patch:         PatchRequest{Operations: []PatchOperation{{Op: "remove"}}},
+			expectedError: `operation "remove" requires a path`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, tc.patch.Validate(), tc.expectedError)
+		})
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	user := &User{
+		UserName:   "john",
+		ExternalID: "ext-1",
+	}
+
+	p := parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "john@example.com"},
+			{"op": "replace", "value": {"userName": "john.doe", "name.givenName": "John", "password": "secret-password"}},
+			{"op": "remove", "path": "externalId"}
+		]
+	}`)
+	require.NoError(t, p.applyToUser(user))
+
+	assert.Equal(t, "john.doe", user.UserName)
+	assert.Equal(t, "", user.ExternalID)
+	assert.Equal(t, "secret-password", user.Password)
+	assert.False(t, user.IsActive())
+	assert.Equal(t, "john@example.com", user.PrimaryEmail())
+
+	p = parsePatch(t, `{"Operations": [{"op": "replace", "path": "active", "value": 1}]}`)
+	assert.EqualError(t, p.applyToUser(user), `invalid value of "active": boolean expected`)
+}
+
+func TestPatchGroup(t *testing.T) {
+	group := &Group{
+		DisplayName: "ops",
+		Members:     []Member{{Value: "u1"}, {Value: "u2"}},
+	}
+
+	p := parsePatch(t, `{
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "u2"}, {"value": "u3"}]},
+			{"op": "remove", "path": "members[value eq \"u1\"]"},
+			{"op": "Remove", "path": "members", "value": [{"value": "u3"}]},
+			{"op": "replace", "value": {"id": "g1", "displayName": "operations"}}
+		]
+	}`)
+	require.NoError(t, p.applyToGroup(group))
+
+	assert.Equal(t, "operations", group.DisplayName)
+	assert.Equal(t, []Member{{Value: "u2"}}, group.Members)
+
+	p = parsePatch(t, `{"Operations": [{"op": "replace", "path": "members", "value": [{"value": "u4"}]}]}`)
+	require.NoError(t, p.applyToGroup(group))
+	assert.Equal(t, []Member{{Value: "u4"}}, group.Members)
+
+	p = parsePatch(t, `{"Operations": [{"op": "remove", "path": "members"}]}`)
+	require.NoError(t, p.applyToGroup(group))
+	assert.Empty(t, group.Members)
+
+	p = parsePatch(t, `{"Operations": [{"op": "remove", "path": "members[display eq \"john\"]"}]}`)
+	assert.EqualError(t, p.applyToGroup(group), `unsupported path "members[display eq \"john\"]"`)
+}
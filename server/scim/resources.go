@@ -0,0 +1,245 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	ContentType = "application/scim+json"
+
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+
+	DefaultCount = 100
+	MaxCount     = 1000
+)
+
+// scimType values of error responses, see RFC 7644 section 3.12.
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrUniqueness    = "uniqueness"
+	ErrNoTarget      = "noTarget"
+)
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type GroupRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type User struct {
+	Schemas    []string   `json:"schemas"`
+	ID         string     `json:"id,omitempty"`
+	ExternalID string     `json:"externalId,omitempty"`
+	UserName   string     `json:"userName"`
+	Password   string     `json:"password,omitempty"`
+	Active     *bool      `json:"active,omitempty"`
+	Emails     []Email    `json:"emails,omitempty"`
+	Groups     []GroupRef `json:"groups,omitempty"`
+	Meta       *Meta      `json:"meta,omitempty"`
+}
+
+// IsActive returns true unless the user was explicitly deactivated. Identity providers omit "active" on create.
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+// PrimaryEmail returns the email marked as primary, otherwise the first one.
+func (u *User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+func (u *User) attributeValues(attr string) []string {
+	switch attr {
+	case "id":
+		return []string{u.ID}
+	case "externalid":
+		return []string{u.ExternalID}
+	case "username":
+		return []string{u.UserName}
+	case "active":
+		return []string{strconv.FormatBool(u.IsActive())}
+	case "emails", "emails.value":
+		values := make([]string, 0, len(u.Emails))
+		for _, e := range u.Emails {
+			values = append(values, e.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+func (g *Group) attributeValues(attr string) []string {
+	switch attr {
+	case "id":
+		return []string{g.ID}
+	case "externalid":
+		return []string{g.ExternalID}
+	case "displayname":
+		return []string{g.DisplayName}
+	case "members", "members.value":
+		values := make([]string, 0, len(g.Members))
+		for _, m := range g.Members {
+			values = append(values, m.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// newListResponse returns the page of resources selected by the 1-based startIndex and count.
+func newListResponse(resources []interface{}, startIndex, count int) *ListResponse {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	start := startIndex - 1
+	if start > len(resources) {
+		start = len(resources)
+	}
+	end := start + count
+	if end > len(resources) {
+		end = len(resources)
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: end - start,
+		Resources:    resources[start:end],
+	}
+}
+
+// Error is an error response as defined in RFC 7644 section 3.12.
+type Error struct {
+	Schemas    []string `json:"schemas"`
+	ScimType   string   `json:"scimType,omitempty"`
+	Detail     string   `json:"detail"`
+	Status     string   `json:"status"`
+	HTTPStatus int      `json:"-"`
+}
+
+func NewError(httpStatus int, scimType string, format string, args ...interface{}) *Error {
+	return &Error{
+		Schemas:    []string{SchemaError},
+		ScimType:   scimType,
+		Detail:     fmt.Sprintf(format, args...),
+		Status:     strconv.Itoa(httpStatus),
+		HTTPStatus: httpStatus,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func errNotFound(resourceType, id string) *Error {
+	return NewError(http.StatusNotFound, "", "%s %q not found", resourceType, id)
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulk                   `json:"bulk"`
+	Filter                filterSupport          `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulk struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func NewServiceProviderConfig() *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          supported{Supported: true},
+		Filter:         filterSupport{Supported: true, MaxResults: MaxCount},
+		ChangePassword: supported{Supported: true},
+		AuthenticationSchemes: []authenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "API token",
+				Description: "API token with the scim scope sent as bearer token in the format <username>:<token>",
+			},
+			{
+				Type:        "httpbasic",
+				Name:        "HTTP Basic",
+				Description: "username and API token with the scim scope",
+			},
+		},
+	}
+}
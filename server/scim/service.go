@@ -0,0 +1,757 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	errors2 "github.com/riportdev/riport/server/api/errors"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/share/enums"
+	"github.com/riportdev/riport/share/random"
+)
+
+var now = time.Now
+
+type UserService interface {
+	GetProviderType() enums.ProviderSource
+	GetAll() ([]*users.User, error)
+	GetByUsername(username string) (*users.User, error)
+	Change(*users.User, string) error
+	Delete(string) error
+	ListGroups() ([]users.Group, error)
+	GetGroup(string) (users.Group, error)
+	UpdateGroup(string, users.Group) (users.Group, error)
+	DeleteGroup(string) error
+	SupportsGroupPermissions() bool
+}
+
+// Service maps SCIM users and groups to API users and user groups. The API users stay the single source of truth,
+// only the SCIM ids, external ids and the active flag are stored separately.
+type Service struct {
+	provider Provider
+	users    UserService
+	twoFAOn  bool
+
+	// mu serializes changes, user groups are spread over all users and must be updated consistently.
+	mu           sync.Mutex
+	userRecords  map[string]*UserRecord
+	groupRecords map[string]*GroupRecord
+
+	inactiveMu sync.RWMutex
+	inactive   map[string]bool
+}
+
+// NewService loads the SCIM records. If twoFAOn is set, the primary email of a user is used as 2FA receiver.
+func NewService(ctx context.Context, provider Provider, userService UserService, twoFAOn bool) (*Service, error) {
+	userRecords, err := provider.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scim users: %w", err)
+	}
+	groupRecords, err := provider.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scim groups: %w", err)
+	}
+
+	s := &Service{
+		provider:     provider,
+		users:        userService,
+		twoFAOn:      twoFAOn,
+		userRecords:  make(map[string]*UserRecord, len(userRecords)),
+		groupRecords: make(map[string]*GroupRecord, len(groupRecords)),
+		inactive:     make(map[string]bool),
+	}
+	for _, r := range userRecords {
+		s.userRecords[r.Username] = r
+		if !r.Active {
+			s.inactive[r.Username] = true
+		}
+	}
+	for _, r := range groupRecords {
+		s.groupRecords[r.Name] = r
+	}
+	return s, nil
+}
+
+// CheckSupported returns an error if the API users are not stored in a database.
+func (s *Service) CheckSupported() error {
+	if t := s.users.GetProviderType(); t != enums.ProviderSourceDB {
+		return NewError(
+			http.StatusNotImplemented,
+			"",
+			"SCIM provisioning requires the API users to be stored in a database, the server uses %q as user source. Use 'auth_user_table' to enable it.",
+			t,
+		)
+	}
+	return nil
+}
+
+// IsDeactivated returns true if the user was deactivated by the identity provider.
+func (s *Service) IsDeactivated(username string) bool {
+	s.inactiveMu.RLock()
+	defer s.inactiveMu.RUnlock()
+	return s.inactive[username]
+}
+
+func (s *Service) setInactive(username string, inactive bool) {
+	s.inactiveMu.Lock()
+	defer s.inactiveMu.Unlock()
+	if inactive {
+		s.inactive[username] = true
+	} else {
+		delete(s.inactive, username)
+	}
+}
+
+func (s *Service) ListUsers(ctx context.Context, f *Filter, startIndex, count int) (*ListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usrs, err := s.users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]interface{}, 0, len(usrs))
+	for _, u := range usrs {
+		rec, err := s.userRecord(ctx, u.Username)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.toUser(ctx, rec, u)
+		if err != nil {
+			return nil, err
+		}
+		if f.matches(res) {
+			resources = append(resources, res)
+		}
+	}
+	return newListResponse(resources, startIndex, count), nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, u, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(ctx, rec, u)
+}
+
+func (s *Service) CreateUser(ctx context.Context, in *User) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if in.UserName == "" {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "userName is required")
+	}
+	existing, err := s.users.GetByUsername(in.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, NewError(http.StatusConflict, ErrUniqueness, "user %q already exists", in.UserName)
+	}
+
+	password := in.Password
+	if password == "" {
+		// users provisioned without a password log in via the identity provider only
+		password, err = random.UUID4()
+		if err != nil {
+			return nil, err
+		}
+	}
+	u := &users.User{
+		Username: in.UserName,
+		Password: password,
+	}
+	if s.twoFAOn {
+		u.TwoFASendTo = in.PrimaryEmail()
+	}
+	if err := s.users.Change(u, ""); err != nil {
+		return nil, toError(err)
+	}
+
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	rec := &UserRecord{
+		ID:         id,
+		Username:   in.UserName,
+		ExternalID: in.ExternalID,
+		Active:     in.IsActive(),
+		CreatedAt:  now().UTC(),
+	}
+	rec.UpdatedAt = rec.CreatedAt
+	if err := s.saveUserRecord(ctx, rec, ""); err != nil {
+		return nil, err
+	}
+
+	return s.reloadUser(ctx, rec)
+}
+
+// ReplaceUser replaces the user attributes. The password is kept if it's not given.
+func (s *Service) ReplaceUser(ctx context.Context, id string, in *User) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, u, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateUser(ctx, rec, u, in)
+}
+
+func (s *Service) PatchUser(ctx context.Context, id string, p *PatchRequest) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, u, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.toUser(ctx, rec, u)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyToUser(current); err != nil {
+		return nil, err
+	}
+	return s.updateUser(ctx, rec, u, current)
+}
+
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, _, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.users.Delete(rec.Username); err != nil {
+		return toError(err)
+	}
+	return s.deleteUserRecord(ctx, rec)
+}
+
+func (s *Service) ListGroups(ctx context.Context, f *Filter, startIndex, count int) (*ListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.groupNames()
+	if err != nil {
+		return nil, err
+	}
+	usrs, err := s.users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		rec, err := s.groupRecord(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.toGroup(ctx, rec, usrs)
+		if err != nil {
+			return nil, err
+		}
+		if f.matches(res) {
+			resources = append(resources, res)
+		}
+	}
+	return newListResponse(resources, startIndex, count), nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id string) (*Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.groupRecordByID(id)
+	if rec == nil {
+		return nil, errNotFound(ResourceTypeGroup, id)
+	}
+	return s.reloadGroup(ctx, rec)
+}
+
+func (s *Service) CreateGroup(ctx context.Context, in *Group) (*Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if in.DisplayName == "" {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "displayName is required")
+	}
+	if err := s.checkGroupNameAvailable(in.DisplayName); err != nil {
+		return nil, err
+	}
+
+	if err := s.setMembers(in.DisplayName, "", in.Members); err != nil {
+		return nil, err
+	}
+	if s.users.SupportsGroupPermissions() {
+		// store the group details so the group exists without members
+		if _, err := s.users.UpdateGroup(in.DisplayName, users.NewGroup(in.DisplayName, nil, nil)); err != nil {
+			return nil, toError(err)
+		}
+	}
+
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	rec := &GroupRecord{
+		ID:         id,
+		Name:       in.DisplayName,
+		ExternalID: in.ExternalID,
+		CreatedAt:  now().UTC(),
+	}
+	rec.UpdatedAt = rec.CreatedAt
+	if err := s.saveGroupRecord(ctx, rec, ""); err != nil {
+		return nil, err
+	}
+
+	return s.reloadGroup(ctx, rec)
+}
+
+func (s *Service) ReplaceGroup(ctx context.Context, id string, in *Group) (*Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.groupRecordByID(id)
+	if rec == nil {
+		return nil, errNotFound(ResourceTypeGroup, id)
+	}
+	return s.updateGroup(ctx, rec, in)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, id string, p *PatchRequest) (*Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.groupRecordByID(id)
+	if rec == nil {
+		return nil, errNotFound(ResourceTypeGroup, id)
+	}
+	current, err := s.reloadGroup(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyToGroup(current); err != nil {
+		return nil, err
+	}
+	return s.updateGroup(ctx, rec, current)
+}
+
+// DeleteGroup removes all members from the group and deletes the group details.
+func (s *Service) DeleteGroup(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.groupRecordByID(id)
+	if rec == nil {
+		return errNotFound(ResourceTypeGroup, id)
+	}
+	if err := s.setMembers(rec.Name, "", nil); err != nil {
+		return err
+	}
+	if s.users.SupportsGroupPermissions() {
+		if err := s.users.DeleteGroup(rec.Name); err != nil {
+			return toError(err)
+		}
+	}
+	if err := s.provider.DeleteGroup(ctx, rec.ID); err != nil {
+		return err
+	}
+	delete(s.groupRecords, rec.Name)
+	return nil
+}
+
+func (s *Service) getUser(ctx context.Context, id string) (*UserRecord, *users.User, error) {
+	rec := s.userRecordByID(id)
+	if rec == nil {
+		return nil, nil, errNotFound(ResourceTypeUser, id)
+	}
+	u, err := s.users.GetByUsername(rec.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil {
+		// the user was deleted outside of SCIM
+		if err := s.deleteUserRecord(ctx, rec); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errNotFound(ResourceTypeUser, id)
+	}
+	return rec, u, nil
+}
+
+func (s *Service) updateUser(ctx context.Context, rec *UserRecord, u *users.User, in *User) (*User, error) {
+	if in.UserName == "" {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "userName is required")
+	}
+
+	change := &users.User{}
+	changed := false
+	if in.UserName != rec.Username {
+		existing, err := s.users.GetByUsername(in.UserName)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, NewError(http.StatusConflict, ErrUniqueness, "user %q already exists", in.UserName)
+		}
+		change.Username = in.UserName
+		changed = true
+	}
+	if in.Password != "" {
+		change.Password = in.Password
+		changed = true
+	}
+	if email := in.PrimaryEmail(); s.twoFAOn && email != "" && email != u.TwoFASendTo {
+		change.TwoFASendTo = email
+		changed = true
+	}
+	if changed {
+		if err := s.users.Change(change, rec.Username); err != nil {
+			return nil, toError(err)
+		}
+	}
+
+	oldUsername := rec.Username
+	rec.Username = in.UserName
+	rec.ExternalID = in.ExternalID
+	rec.Active = in.IsActive()
+	rec.UpdatedAt = now().UTC()
+	if err := s.saveUserRecord(ctx, rec, oldUsername); err != nil {
+		return nil, err
+	}
+
+	return s.reloadUser(ctx, rec)
+}
+
+func (s *Service) reloadUser(ctx context.Context, rec *UserRecord) (*User, error) {
+	u, err := s.users.GetByUsername(rec.Username)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errNotFound(ResourceTypeUser, rec.ID)
+	}
+	return s.toUser(ctx, rec, u)
+}
+
+func (s *Service) toUser(ctx context.Context, rec *UserRecord, u *users.User) (*User, error) {
+	active := rec.Active
+	res := &User{
+		Schemas:    []string{SchemaUser},
+		ID:         rec.ID,
+		ExternalID: rec.ExternalID,
+		UserName:   u.Username,
+		Active:     &active,
+		Meta: &Meta{
+			ResourceType: ResourceTypeUser,
+			Created:      &rec.CreatedAt,
+			LastModified: &rec.UpdatedAt,
+		},
+	}
+	if strings.Contains(u.TwoFASendTo, "@") {
+		res.Emails = []Email{{Value: u.TwoFASendTo, Type: "work", Primary: true}}
+	}
+	for _, name := range u.Groups {
+		g, err := s.groupRecord(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		res.Groups = append(res.Groups, GroupRef{Value: g.ID, Display: name})
+	}
+	return res, nil
+}
+
+// userRecord returns the record of the user and creates it for users which were not provisioned via SCIM.
+func (s *Service) userRecord(ctx context.Context, username string) (*UserRecord, error) {
+	if rec, ok := s.userRecords[username]; ok {
+		return rec, nil
+	}
+
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	rec := &UserRecord{
+		ID:        id,
+		Username:  username,
+		Active:    true,
+		CreatedAt: now().UTC(),
+	}
+	rec.UpdatedAt = rec.CreatedAt
+	if err := s.saveUserRecord(ctx, rec, ""); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *Service) userRecordByID(id string) *UserRecord {
+	for _, rec := range s.userRecords {
+		if rec.ID == id {
+			return rec
+		}
+	}
+	return nil
+}
+
+// saveUserRecord stores the record, oldUsername is the username before a rename.
+func (s *Service) saveUserRecord(ctx context.Context, rec *UserRecord, oldUsername string) error {
+	if stale, ok := s.userRecords[rec.Username]; ok && stale.ID != rec.ID {
+		// the user was deleted and recreated outside of SCIM
+		if err := s.deleteUserRecord(ctx, stale); err != nil {
+			return err
+		}
+	}
+	if err := s.provider.SaveUser(ctx, rec); err != nil {
+		return err
+	}
+
+	if oldUsername != "" && oldUsername != rec.Username {
+		delete(s.userRecords, oldUsername)
+		s.setInactive(oldUsername, false)
+	}
+	s.userRecords[rec.Username] = rec
+	s.setInactive(rec.Username, !rec.Active)
+	return nil
+}
+
+func (s *Service) deleteUserRecord(ctx context.Context, rec *UserRecord) error {
+	if err := s.provider.DeleteUser(ctx, rec.ID); err != nil {
+		return err
+	}
+	delete(s.userRecords, rec.Username)
+	s.setInactive(rec.Username, false)
+	return nil
+}
+
+func (s *Service) updateGroup(ctx context.Context, rec *GroupRecord, in *Group) (*Group, error) {
+	if in.DisplayName == "" {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "displayName is required")
+	}
+
+	oldName := ""
+	if in.DisplayName != rec.Name {
+		if err := s.checkGroupNameAvailable(in.DisplayName); err != nil {
+			return nil, err
+		}
+		oldName = rec.Name
+	}
+
+	if oldName != "" && s.users.SupportsGroupPermissions() {
+		// move the permissions to the new name, the old group is deleted after the members were moved
+		g, err := s.users.GetGroup(oldName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.users.UpdateGroup(in.DisplayName, g); err != nil {
+			return nil, toError(err)
+		}
+	}
+
+	if err := s.setMembers(in.DisplayName, oldName, in.Members); err != nil {
+		return nil, err
+	}
+
+	if oldName != "" && s.users.SupportsGroupPermissions() {
+		if err := s.users.DeleteGroup(oldName); err != nil {
+			return nil, toError(err)
+		}
+	}
+
+	rec.Name = in.DisplayName
+	rec.ExternalID = in.ExternalID
+	rec.UpdatedAt = now().UTC()
+	if err := s.saveGroupRecord(ctx, rec, oldName); err != nil {
+		return nil, err
+	}
+
+	return s.reloadGroup(ctx, rec)
+}
+
+// setMembers makes the given users the only members of the group. If oldName is set, the group is renamed.
+func (s *Service) setMembers(name, oldName string, members []Member) error {
+	wanted := make(map[string]bool, len(members))
+	for _, m := range members {
+		rec := s.userRecordByID(m.Value)
+		if rec == nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "member %q not found", m.Value)
+		}
+		wanted[rec.Username] = true
+	}
+
+	usrs, err := s.users.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, u := range usrs {
+		groups := make([]string, 0, len(u.Groups)+1)
+		isMember := false
+		renamed := false
+		for _, g := range u.Groups {
+			switch g {
+			case name:
+				isMember = true
+			case oldName:
+				isMember = true
+				renamed = true
+			default:
+				groups = append(groups, g)
+			}
+		}
+		if isMember == wanted[u.Username] && !renamed {
+			continue
+		}
+		if wanted[u.Username] {
+			groups = append(groups, name)
+		}
+		if err := s.users.Change(&users.User{Groups: groups}, u.Username); err != nil {
+			return toError(err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) reloadGroup(ctx context.Context, rec *GroupRecord) (*Group, error) {
+	usrs, err := s.users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(ctx, rec, usrs)
+}
+
+func (s *Service) toGroup(ctx context.Context, rec *GroupRecord, usrs []*users.User) (*Group, error) {
+	res := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          rec.ID,
+		ExternalID:  rec.ExternalID,
+		DisplayName: rec.Name,
+		Members:     []Member{},
+		Meta: &Meta{
+			ResourceType: ResourceTypeGroup,
+			Created:      &rec.CreatedAt,
+			LastModified: &rec.UpdatedAt,
+		},
+	}
+	for _, u := range usrs {
+		for _, g := range u.Groups {
+			if g != rec.Name {
+				continue
+			}
+			userRec, err := s.userRecord(ctx, u.Username)
+			if err != nil {
+				return nil, err
+			}
+			res.Members = append(res.Members, Member{Value: userRec.ID, Display: u.Username})
+			break
+		}
+	}
+	return res, nil
+}
+
+// groupNames returns the names of the user groups and of the groups created via SCIM without members.
+func (s *Service) groupNames() ([]string, error) {
+	groups, err := s.users.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(groups)+len(s.groupRecords))
+	seen := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name)
+		seen[g.Name] = true
+	}
+	for name := range s.groupRecords {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *Service) checkGroupNameAvailable(name string) error {
+	names, err := s.groupNames()
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		if n == name {
+			return NewError(http.StatusConflict, ErrUniqueness, "group %q already exists", name)
+		}
+	}
+	return nil
+}
+
+// groupRecord returns the record of the group and creates it for groups which were not provisioned via SCIM.
+func (s *Service) groupRecord(ctx context.Context, name string) (*GroupRecord, error) {
+	if rec, ok := s.groupRecords[name]; ok {
+		return rec, nil
+	}
+
+	id, err := random.UUID4()
+	if err != nil {
+		return nil, err
+	}
+	rec := &GroupRecord{
+		ID:        id,
+		Name:      name,
+		CreatedAt: now().UTC(),
+	}
+	rec.UpdatedAt = rec.CreatedAt
+	if err := s.saveGroupRecord(ctx, rec, ""); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *Service) groupRecordByID(id string) *GroupRecord {
+	for _, rec := range s.groupRecords {
+		if rec.ID == id {
+			return rec
+		}
+	}
+	return nil
+}
+
+func (s *Service) saveGroupRecord(ctx context.Context, rec *GroupRecord, oldName string) error {
+	if err := s.provider.SaveGroup(ctx, rec); err != nil {
+		return err
+	}
+	if oldName != "" && oldName != rec.Name {
+		delete(s.groupRecords, oldName)
+	}
+	s.groupRecords[rec.Name] = rec
+	return nil
+}
+
+// toError converts validation errors of the user service to SCIM errors.
+func toError(err error) error {
+	var apiErr errors2.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatus != 0 {
+		return NewError(apiErr.HTTPStatus, "", "%s", apiErr.Error())
+	}
+	var apiErrs errors2.APIErrors
+	if errors.As(err, &apiErrs) && len(apiErrs) > 0 {
+		return NewError(apiErrs[0].HTTPStatus, "", "%s", apiErrs.Error())
+	}
+	return err
+}
+
+func (s *Service) Close() error {
+	return s.provider.Close()
+}
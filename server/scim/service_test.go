@@ -0,0 +1,258 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	scimmigration "github.com/riportdev/riport/db/migration/scim"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/server/api/users"
+	"github.com/riportdev/riport/share/enums"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+type userServiceMock struct {
+	providerType enums.ProviderSource
+	users        map[string]*users.User
+	groups       map[string]users.Group
+}
+
+func newUserServiceMock(usrs ...*users.User) *userServiceMock {
+	m := &userServiceMock{
+		providerType: enums.ProviderSourceDB,
+		users:        map[string]*users.User{},
+		groups:       map[string]users.Group{},
+	}
+	for _, u := range usrs {
+		m.users[u.Username] = u
+	}
+	return m
+}
+
+func (m *userServiceMock) GetProviderType() enums.ProviderSource {
+	return m.providerType
+}
+
+func (m *userServiceMock) GetAll() ([]*users.User, error) {
+	res := make([]*users.User, 0, len(m.users))
+	for _, u := range m.users {
+		c := *u
+		res = append(res, &c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Username < res[j].Username })
+	return res, nil
+}
+
+func (m *userServiceMock) GetByUsername(username string) (*users.User, error) {
+	u, ok := m.users[username]
+	if !ok {
+		return nil, nil
+	}
+	c := *u
+	return &c, nil
+}
+
+func (m *userServiceMock) Change(usr *users.User, username string) error {
+	if username == "" {
+		m.users[usr.Username] = usr
+		return nil
+	}
+	u := m.users[username]
+	if usr.Username != "" && usr.Username != username {
+		delete(m.users, username)
+		u.Username = usr.Username
+		m.users[u.Username] = u
+	}
+	if usr.Password != "" {
+		u.Password = usr.Password
+	}
+	if usr.Groups != nil {
+		u.Groups = usr.Groups
+	}
+	return nil
+}
+
+func (m *userServiceMock) Delete(username string) error {
+	delete(m.users, username)
+	return nil
+}
+
+func (m *userServiceMock) ListGroups() ([]users.Group, error) {
+	names := map[string]bool{}
+	for name := range m.groups {
+		names[name] = true
+	}
+	for _, u := range m.users {
+		for _, g := range u.Groups {
+			names[g] = true
+		}
+	}
+	res := []users.Group{}
+	for name := range names {
+		res = append(res, users.NewGroup(name, nil, nil))
+	}
+	return res, nil
+}
+
+func (m *userServiceMock) GetGroup(name string) (users.Group, error) {
+	return m.groups[name], nil
+}
+
+func (m *userServiceMock) UpdateGroup(name string, g users.Group) (users.Group, error) {
+	g.Name = name
+	m.groups[name] = g
+	return g, nil
+}
+
+func (m *userServiceMock) DeleteGroup(name string) error {
+	delete(m.groups, name)
+	return nil
+}
+
+func (m *userServiceMock) SupportsGroupPermissions() bool {
+	return true
+}
+
+func newTestService(t *testing.T, userService UserService) *Service {
+	db, err := sqlite.New(":memory:", scimmigration.AssetNames(), scimmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	s, err := NewService(context.Background(), NewSqliteProvider(db), userService, false)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return s
+}
+
+func TestCheckSupported(t *testing.T) {
+	userService := newUserServiceMock()
+	s := newTestService(t, userService)
+	assert.NoError(t, s.CheckSupported())
+
+	userService.providerType = enums.ProviderSourceFile
+	err := s.CheckSupported()
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotImplemented, err.(*Error).HTTPStatus)
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	userService := newUserServiceMock(&users.User{Username: "admin", Groups: []string{users.Administrators}})
+	s := newTestService(t, userService)
+
+	created, err := s.CreateUser(ctx, &User{UserName: "john", ExternalID: "ext-1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.True(t, created.IsActive())
+	assert.NotEmpty(t, userService.users["john"].Password, "a random password is set")
+
+	_, err = s.CreateUser(ctx, &User{UserName: "john"})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*Error).HTTPStatus)
+
+	f, err := ParseFilter(`userName eq "JOHN"`)
+	require.NoError(t, err)
+	list, err := s.ListUsers(ctx, f, 1, DefaultCount)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.TotalResults)
+	assert.Equal(t, created.ID, list.Resources[0].(*User).ID)
+
+	list, err = s.ListUsers(ctx, nil, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.TotalResults)
+	assert.Equal(t, 1, list.ItemsPerPage)
+	assert.Equal(t, "john", list.Resources[0].(*User).UserName)
+
+	p := &PatchRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"Operations": [{"op": "replace", "value": {"active": false, "userName": "john.doe"}}]}`), p))
+	patched, err := s.PatchUser(ctx, created.ID, p)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, patched.ID)
+	assert.Equal(t, "john.doe", patched.UserName)
+	assert.Equal(t, "ext-1", patched.ExternalID)
+	assert.False(t, patched.IsActive())
+	assert.True(t, s.IsDeactivated("john.doe"))
+	assert.False(t, s.IsDeactivated("john"))
+
+	active := true
+	replaced, err := s.ReplaceUser(ctx, created.ID, &User{UserName: "john.doe", Active: &active})
+	require.NoError(t, err)
+	assert.True(t, replaced.IsActive())
+	assert.Empty(t, replaced.ExternalID)
+	assert.False(t, s.IsDeactivated("john.doe"))
+
+	require.NoError(t, s.DeleteUser(ctx, created.ID))
+	assert.NotContains(t, userService.users, "john.doe")
+	_, err = s.GetUser(ctx, created.ID)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, err.(*Error).HTTPStatus)
+}
+
+func TestGroups(t *testing.T) {
+	ctx := context.Background()
+	userService := newUserServiceMock(
+		&users.User{Username: "admin", Groups: []string{users.Administrators}},
+		&users.User{Username: "john", Groups: []string{"dev"}},
+		&users.User{Username: "jane"},
+	)
+	s := newTestService(t, userService)
+
+	list, err := s.ListUsers(ctx, nil, 1, DefaultCount)
+	require.NoError(t, err)
+	ids := map[string]string{}
+	for _, r := range list.Resources {
+		u := r.(*User)
+		ids[u.UserName] = u.ID
+	}
+
+	group, err := s.CreateGroup(ctx, &Group{DisplayName: "ops", Members: []Member{{Value: ids["john"]}}})
+	require.NoError(t, err)
+	assert.Equal(t, []Member{{Value: ids["john"], Display: "john"}}, group.Members)
+	assert.Equal(t, []string{"dev", "ops"}, userService.users["john"].Groups)
+	assert.Contains(t, userService.groups, "ops")
+
+	_, err = s.CreateGroup(ctx, &Group{DisplayName: "dev"})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*Error).HTTPStatus)
+
+	_, err = s.CreateGroup(ctx, &Group{DisplayName: "qa", Members: []Member{{Value: "unknown"}}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*Error).HTTPStatus)
+
+	p := &PatchRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "`+ids["jane"]+`"}]},
+		{"op": "remove", "path": "members[value eq \"`+ids["john"]+`\"]"},
+		{"op": "replace", "path": "displayName", "value": "operations"}
+	]}`), p))
+	group, err = s.PatchGroup(ctx, group.ID, p)
+	require.NoError(t, err)
+	assert.Equal(t, "operations", group.DisplayName)
+	assert.Equal(t, []Member{{Value: ids["jane"], Display: "jane"}}, group.Members)
+	assert.Equal(t, []string{"dev"}, userService.users["john"].Groups)
+	assert.Equal(t, []string{"operations"}, userService.users["jane"].Groups)
+	assert.Contains(t, userService.groups, "operations")
+	assert.NotContains(t, userService.groups, "ops")
+
+	f, err := ParseFilter(`displayName eq "operations"`)
+	require.NoError(t, err)
+	groups, err := s.ListGroups(ctx, f, 1, DefaultCount)
+	require.NoError(t, err)
+	require.Equal(t, 1, groups.TotalResults)
+	assert.Equal(t, group.ID, groups.Resources[0].(*Group).ID)
+
+	user, err := s.GetUser(ctx, ids["jane"])
+	require.NoError(t, err)
+	assert.Equal(t, []GroupRef{{Value: group.ID, Display: "operations"}}, user.Groups)
+
+	require.NoError(t, s.DeleteGroup(ctx, group.ID))
+	assert.Empty(t, userService.users["jane"].Groups)
+	assert.NotContains(t, userService.groups, "operations")
+	_, err = s.GetGroup(ctx, group.ID)
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UserRecord holds the SCIM attributes of an API user which are not part of the user database.
+type UserRecord struct {
+	ID         string    `db:"id"`
+	Username   string    `db:"username"`
+	ExternalID string    `db:"external_id"`
+	Active     bool      `db:"active"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+// GroupRecord holds the SCIM attributes of a user group.
+type GroupRecord struct {
+	ID         string    `db:"id"`
+	Name       string    `db:"name"`
+	ExternalID string    `db:"external_id"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+type Provider interface {
+	ListUsers(ctx context.Context) ([]*UserRecord, error)
+	SaveUser(ctx context.Context, r *UserRecord) error
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context) ([]*GroupRecord, error)
+	SaveGroup(ctx context.Context, r *GroupRecord) error
+	DeleteGroup(ctx context.Context, id string) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db: db,
+	}
+}
+
+func (p *SqliteProvider) ListUsers(ctx context.Context) ([]*UserRecord, error) {
+	res := []*UserRecord{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM scim_users ORDER BY username")
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SaveUser(ctx context.Context, r *UserRecord) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO scim_users (id, username, external_id, active, created_at, updated_at)
+		VALUES (:id, :username, :external_id, :active, :created_at, :updated_at)`,
+		r,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeleteUser(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM scim_users WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) ListGroups(ctx context.Context) ([]*GroupRecord, error) {
+	res := []*GroupRecord{}
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM scim_groups ORDER BY name")
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) SaveGroup(ctx context.Context, r *GroupRecord) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO scim_groups (id, name, external_id, created_at, updated_at)
+		VALUES (:id, :name, :external_id, :created_at, :updated_at)`,
+		r,
+	)
+	return err
+}
+
+func (p *SqliteProvider) DeleteGroup(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM scim_groups WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
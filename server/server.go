@@ -22,10 +22,13 @@ import (
 
 	"github.com/riportdev/riport/db/migration/client_groups"
 	clientsmigration "github.com/riportdev/riport/db/migration/clients"
+	inventorymigration "github.com/riportdev/riport/db/migration/inventory"
 	jobsmigration "github.com/riportdev/riport/db/migration/jobs"
+	tunnelconnsmigration "github.com/riportdev/riport/db/migration/tunnel_connections"
 	"github.com/riportdev/riport/db/sqlite"
 	rportplus "github.com/riportdev/riport/plus"
 	alertingcap "github.com/riportdev/riport/plus/capabilities/alerting"
+	"github.com/riportdev/riport/server/accessgrants"
 	"github.com/riportdev/riport/server/acme"
 	"github.com/riportdev/riport/server/api/jobs"
 	"github.com/riportdev/riport/server/api/jobs/schedule"
@@ -35,11 +38,18 @@ import (
 	"github.com/riportdev/riport/server/cgroups"
 	"github.com/riportdev/riport/server/chconfig"
 	"github.com/riportdev/riport/server/clients"
+	"github.com/riportdev/riport/server/clients/clientsearch"
+	"github.com/riportdev/riport/server/clients/clienttunnel"
+	"github.com/riportdev/riport/server/clients/enrollment"
 	"github.com/riportdev/riport/server/clientsauth"
+	"github.com/riportdev/riport/server/cluster"
+	"github.com/riportdev/riport/server/inventory"
 	"github.com/riportdev/riport/server/monitoring"
 	"github.com/riportdev/riport/server/notifications"
 	"github.com/riportdev/riport/server/ports"
+	"github.com/riportdev/riport/server/rbac"
 	"github.com/riportdev/riport/server/scheduler"
+	"github.com/riportdev/riport/server/tunnellog"
 	chshare "github.com/riportdev/riport/share"
 	"github.com/riportdev/riport/share/capabilities"
 	"github.com/riportdev/riport/share/files"
@@ -52,6 +62,10 @@ const (
 	cleanupMeasurementsInterval = time.Minute * 2
 	cleanupAPISessionsInterval  = time.Hour
 	cleanupJobsInterval         = time.Hour
+	expireAccessGrantsInterval  = time.Minute
+	cleanupTunnelConnsInterval  = time.Hour
+	syncSchedulesInterval       = time.Minute
+	syncAccessInterval          = 10 * time.Second
 	LogNumGoRoutinesInterval    = time.Minute * 2
 
 	DefaultMaxClientDBConnections = 50
@@ -76,8 +90,18 @@ type Server struct {
 	authDB              *sqlx.DB
 	uiJobWebSockets     ws.WebSocketCache // used to push job result to UI
 	uploadWebSockets    sync.Map
-	jobsDoneChannel     jobResultChanMap // used for sequential command execution to know when command is finished
+	uploadWebSocketsMu  sync.Mutex         // used to send progress events and results of uploads one at a time
+	jobsDoneChannel     jobResultChanMap   // used for sequential command execution to know when command is finished
+	multiJobControls    multiJobControlMap // used to pause and resume multi-client jobs that run with an execution strategy
+	pendingJobsLocks    clientLockMap      // used to deliver jobs queued for a disconnected client only once
+	attributesLocks     clientLockMap      // used to change the attributes of a client one at a time
+	approvalsMu         sync.Mutex         // used to decide on approval requests one at a time
+	enrollmentGroupsMu  sync.Mutex         // used to add enrolled clients to client groups one at a time
 	auditLog            *auditlog.AuditLog
+	tunnelConnLog       *tunnellog.Log
+	inventory           *inventory.Manager
+	clientSearcher      *clientsearch.Searcher
+	enrollmentTokens    *enrollment.Manager
 	capabilities        *models.Capabilities
 	scheduleManager     *schedule.Manager
 	filesAPI            files.FileAPI
@@ -85,7 +109,9 @@ type Server struct {
 	caddyServer         *caddy.Server
 	acme                *acme.Acme
 	alertingService     alertingcap.Service
+	alertsDB            *bbolt.DB // nil unless alerting is enabled, used to backup the running server
 	monitoringQueue     monitoring.MeasurementSaver
+	cluster             *cluster.Node // nil unless clustering is enabled
 }
 
 type ServerOpts struct {
@@ -105,6 +131,9 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 		jobsDoneChannel: jobResultChanMap{
 			m: make(map[string]chan *models.Job),
 		},
+		multiJobControls: multiJobControlMap{
+			m: make(map[string]*multiJobControl),
+		},
 	}
 
 	s.acme = acme.New(s.Logger.Fork("acme"), config.Server.DataDir, config.Server.AcmeHTTPPort)
@@ -216,6 +245,7 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clients DB instance: %v", err)
 	}
+	s.enrollmentTokens = enrollment.New(s.clientDB)
 
 	// keepDisconnectedClients is nil when cleanup of clients is disabled (keep clients forever)
 	var keepDisconnectedClients *time.Duration
@@ -223,12 +253,21 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 		keepDisconnectedClients = &config.Server.KeepDisconnectedClients
 	}
 
+	if config.Cluster.Enabled {
+		s.cluster, err = cluster.New(s.Logger.Fork("cluster"), config.Cluster, config.Server.GetSQLiteDataSourceOptions())
+		if err != nil {
+			return nil, err
+		}
+		s.Infof("Clustering enabled, node id: %s", config.Cluster.NodeID)
+	}
+
 	s.clientService, err = clients.InitClientService(
 		ctx,
 		&s.config.Server.InternalTunnelProxyConfig,
 		ports.NewPortDistributor(config.AllowedPorts()),
 		s.clientDB,
 		keepDisconnectedClients,
+		s.cluster,
 		s.Logger,
 		s.acme,
 	)
@@ -252,6 +291,38 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 	if err != nil {
 		return nil, err
 	}
+	s.clientService.SetAuditLog(s.auditLog)
+
+	if config.Server.GetTunnelConnectionsRetention() > 0 {
+		tunnelConnsDB, err := sqlite.New(
+			path.Join(config.Server.DataDir, "tunnel_connections.db"),
+			tunnelconnsmigration.AssetNames(),
+			tunnelconnsmigration.Asset,
+			config.Server.GetSQLiteDataSourceOptions(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed init tunnel connections DB instance: %w", err)
+		}
+		s.tunnelConnLog = tunnellog.New(s.Logger.Fork("tunnel-connections"), tunnellog.NewSqliteProvider(tunnelConnsDB))
+		s.clientService.SetTunnelConnectionLog(s.tunnelConnLog)
+	}
+
+	inventoryDB, err := sqlite.New(
+		path.Join(config.Server.DataDir, "inventory.db"),
+		inventorymigration.AssetNames(),
+		inventorymigration.Asset,
+		config.Server.GetSQLiteDataSourceOptions(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed init inventory DB instance: %w", err)
+	}
+	inventoryLogger := s.Logger.Fork("inventory")
+	s.inventory = inventory.NewManager(inventoryLogger, inventory.NewSqliteProvider(inventoryDB, inventoryLogger))
+	err = s.loadInventorySummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.clientSearcher = clientsearch.NewSearcher(s.inventory, s.monitoringService)
 
 	if config.Database.Driver != "" {
 		s.authDB, err = sqlx.Connect(config.Database.Driver, config.Database.Dsn)
@@ -261,6 +332,14 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 		s.Infof("DB: successfully connected to %s", config.Database.DsnForLogs())
 	}
 
+	interrupted, err := s.jobProvider.InterruptMultiJobs(ctx, s.cluster.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to interrupt unfinished multi-client jobs: %v", err)
+	}
+	if interrupted > 0 {
+		s.Infof("%d multi-client jobs left unfinished by the previous run were marked as interrupted", interrupted)
+	}
+
 	s.clientAuthProvider, err = getClientProvider(config, s.authDB)
 	if err != nil {
 		return nil, err
@@ -277,6 +356,8 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 	if err != nil {
 		return nil, err
 	}
+	s.clientService.SetTunnelSessionAuth(clienttunnel.NewTunnelSessionAuth(config.API.JWTSecret, s.apiListener))
+	s.clientService.SetAuthHeaderResolver(s.apiListener.resolveTunnelAuthHeader)
 
 	s.capabilities = capabilities.NewServerCapabilities(&config.Monitoring)
 
@@ -284,6 +365,9 @@ func NewServer(ctx context.Context, config *chconfig.Config, opts *ServerOpts) (
 	if err != nil {
 		return nil, err
 	}
+	if s.cluster != nil {
+		s.scheduleManager.SetLeader(s.cluster)
+	}
 
 	if s.config.CaddyEnabled() {
 		cfg := s.config
@@ -325,6 +409,7 @@ func (s *Server) StartPlusAlertingService(alertingCap alertingcap.CapabilityEx,
 	if err != nil {
 		return nil, err
 	}
+	s.alertsDB = bdb
 
 	err = alertingCap.Init(bdb)
 	if err != nil {
@@ -373,6 +458,10 @@ func initPrivateKey(seed string) (ssh.Signer, error) {
 
 // Run is responsible for starting the riport service
 func (s *Server) Run(ctx context.Context) error {
+	if err := s.cluster.Start(ctx); err != nil {
+		return fmt.Errorf("failed to join the cluster: %v", err)
+	}
+
 	if err := s.Start(ctx); err != nil {
 		return err
 	}
@@ -382,7 +471,11 @@ func (s *Server) Run(ctx context.Context) error {
 	// TODO(m-terel): add graceful shutdown of background task
 	if s.config.Server.PurgeDisconnectedClients {
 		s.Infof("Period to keep disconnected clients is set to %v", s.config.Server.KeepDisconnectedClients)
-		go scheduler.Run(ctx, s.Logger, clients.NewCleanupTask(s.Logger, s.clientListener.server.clientService.GetRepo()), s.config.Server.PurgeDisconnectedClientsInterval)
+		go scheduler.Run(ctx, s.Logger, s.cluster.LeaderOnly(clients.NewCleanupTask(s.Logger, s.clientListener.server.clientService.GetRepo())), s.config.Server.PurgeDisconnectedClientsInterval)
+		if s.cluster != nil {
+			// the leader purges the shared clients DB, every other node only removes the clients from its memory
+			go scheduler.Run(ctx, s.Logger, clients.NewInMemoryCleanupTask(s.Logger, s.clientListener.server.clientService.GetRepo()), s.config.Server.PurgeDisconnectedClientsInterval)
+		}
 		s.Infof("Task to purge disconnected clients will run with interval %v", s.config.Server.PurgeDisconnectedClientsInterval)
 	} else {
 		s.Debugf("Task to purge disconnected clients disabled")
@@ -409,20 +502,45 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 
 		monitoringCleanupTask := monitoring.NewCleanupTask(s.Logger, s.monitoringService, cleaningPeriod)
-		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", monitoringCleanupTask)), monitoringCleanupTask, cleanupMeasurementsInterval)
+		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", monitoringCleanupTask)), s.cluster.LeaderOnly(monitoringCleanupTask), cleanupMeasurementsInterval)
 		s.Infof("Task to cleanup measurements will run with interval %v", cleanupMeasurementsInterval)
 	} else {
 		s.Infof("Measurement disabled")
 	}
 
 	sessionsCleanupTask := session.NewCleanupTask(s.apiListener.apiSessions)
-	go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", sessionsCleanupTask)), sessionsCleanupTask, cleanupAPISessionsInterval)
+	go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", sessionsCleanupTask)), s.cluster.LeaderOnly(sessionsCleanupTask), cleanupAPISessionsInterval)
 	s.Infof("Task to cleanup expired api sessions will run with interval %v", cleanupAPISessionsInterval)
 
 	jobsCleanupTask := jobs.NewCleanupTask(s.jobProvider, s.config.Server.JobsMaxResults)
-	go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", jobsCleanupTask)), jobsCleanupTask, cleanupJobsInterval)
+	go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", jobsCleanupTask)), s.cluster.LeaderOnly(jobsCleanupTask), cleanupJobsInterval)
 	s.Infof("Task to cleanup jobs will run with interval %v", cleanupJobsInterval)
 
+	accessGrantsExpiryTask := accessgrants.NewExpiryTask(s.Logger.Fork("access-grants"), s.apiListener.accessGrants, s.auditLog)
+	go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", accessGrantsExpiryTask)), s.cluster.LeaderOnly(accessGrantsExpiryTask), expireAccessGrantsInterval)
+	s.Infof("Task to expire access grants will run with interval %v", expireAccessGrantsInterval)
+
+	if s.tunnelConnLog != nil {
+		s.Infof("Period to keep tunnel connections will be %s", s.config.Server.TunnelConnectionsRetention)
+		tunnelConnsCleanupTask := tunnellog.NewCleanupTask(s.Logger, s.tunnelConnLog, s.config.Server.GetTunnelConnectionsRetention())
+		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", tunnelConnsCleanupTask)), s.cluster.LeaderOnly(tunnelConnsCleanupTask), cleanupTunnelConnsInterval)
+		s.Infof("Task to cleanup tunnel connections will run with interval %v", cleanupTunnelConnsInterval)
+	}
+
+	if s.cluster != nil {
+		// every node keeps its schedules in sync with the other nodes to run them once it becomes the leader
+		syncSchedulesTask := schedule.NewSyncTask(s.scheduleManager)
+		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", syncSchedulesTask)), syncSchedulesTask, syncSchedulesInterval)
+		s.Infof("Task to sync schedules with the cluster will run with interval %v", syncSchedulesInterval)
+
+		// access checks use the access grants and roles kept in memory
+		syncAccessGrantsTask := accessgrants.NewSyncTask(s.apiListener.accessGrants)
+		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", syncAccessGrantsTask)), syncAccessGrantsTask, syncAccessInterval)
+		syncRolesTask := rbac.NewSyncTask(s.apiListener.roles)
+		go scheduler.Run(ctx, s.Logger.Fork(fmt.Sprintf("task %T", syncRolesTask)), syncRolesTask, syncAccessInterval)
+		s.Infof("Tasks to sync access grants and roles with the cluster will run with interval %v", syncAccessInterval)
+	}
+
 	// Only on debug mode, log the number of running go routines
 	if s.config.Logging.LogLevel == logger.LogLevelDebug {
 		go func() {
@@ -497,11 +615,17 @@ func (s *Server) Close() error {
 
 	wg.Go(s.clientGroupProvider.Close)
 	wg.Go(s.uiJobWebSockets.CloseConnections)
+	wg.Go(s.cluster.Close)
 
 	if s.auditLog != nil {
 		wg.Go(s.auditLog.Close)
 	}
 
+	if s.tunnelConnLog != nil {
+		wg.Go(s.tunnelConnLog.Close)
+	}
+	wg.Go(s.inventory.Close)
+
 	s.uploadWebSockets.Range(func(key, value interface{}) bool {
 		if wsConn, ok := value.(*ws.ConcurrentWebSocket); ok {
 			wg.Go(wsConn.Close)
@@ -32,6 +32,7 @@ type JobBuilder struct {
 	result     *models.JobResult
 	isSudo     bool
 	cwd        string
+	expiresAt  *time.Time
 }
 
 // New returns a builder to generate a job that can be used in tests.
@@ -99,6 +100,11 @@ func (b JobBuilder) Cwd(cwd string) JobBuilder {
 	return b
 }
 
+func (b JobBuilder) ExpiresAt(expiresAt time.Time) JobBuilder {
+	b.expiresAt = &expiresAt
+	return b
+}
+
 func (b JobBuilder) Build() *models.Job {
 	if b.jid == "" {
 		jid, err := generateRandomJID()
@@ -120,6 +126,7 @@ func (b JobBuilder) Build() *models.Job {
 		TimeoutSec: 60,
 		Result:     b.result,
 		MultiJobID: b.multiJobID,
+		ExpiresAt:  b.expiresAt,
 	}
 }
 
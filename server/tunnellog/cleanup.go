@@ -0,0 +1,33 @@
+package tunnellog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riportdev/riport/share/logger"
+)
+
+type CleanupTask struct {
+	log       *logger.Logger
+	tunnelLog *Log
+	retention time.Duration
+}
+
+// NewCleanupTask returns a task to delete the connections which ended before the retention period
+func NewCleanupTask(log *logger.Logger, tunnelLog *Log, retention time.Duration) *CleanupTask {
+	return &CleanupTask{
+		log:       log,
+		tunnelLog: tunnelLog,
+		retention: retention,
+	}
+}
+
+func (t *CleanupTask) Run(ctx context.Context) error {
+	deleted, err := t.tunnelLog.DeleteOlderThan(ctx, t.retention)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup tunnel connections: %v", err)
+	}
+	t.log.Debugf("tunnellog.CleanupTask: %d tunnel connection records deleted", deleted)
+	return nil
+}
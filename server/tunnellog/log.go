@@ -0,0 +1,87 @@
+package tunnellog
+
+import (
+	"context"
+	"time"
+
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/query"
+)
+
+// Log stores the connections of all tunnels. A nil Log doesn't store anything, it's nil if the retention is 0.
+type Log struct {
+	provider Provider
+	logger   *logger.Logger
+}
+
+func New(logger *logger.Logger, provider Provider) *Log {
+	return &Log{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// Save stores a record, errors are only logged as they must not affect the connection
+func (l *Log) Save(r *Record) {
+	if l == nil {
+		return
+	}
+
+	r.StartedAt = r.StartedAt.UTC()
+	r.EndedAt = r.EndedAt.UTC()
+	r.DurationMS = r.EndedAt.Sub(r.StartedAt).Milliseconds()
+	err := l.provider.Save(context.Background(), r)
+	if err != nil {
+		l.logger.Errorf("Could not save tunnel connection of client %s tunnel %s: %v", r.ClientID, r.TunnelID, err)
+	}
+}
+
+// ForTunnel returns the log of a single tunnel, it's nil if l is nil
+func (l *Log) ForTunnel(clientID, tunnelID string) *TunnelLog {
+	if l == nil {
+		return nil
+	}
+	return &TunnelLog{
+		log:      l,
+		clientID: clientID,
+		tunnelID: tunnelID,
+	}
+}
+
+func (l *Log) List(ctx context.Context, options *query.ListOptions) ([]*Record, int, error) {
+	records, err := l.provider.List(ctx, options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := l.provider.Count(ctx, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, count, nil
+}
+
+func (l *Log) DeleteOlderThan(ctx context.Context, period time.Duration) (int64, error) {
+	return l.provider.DeleteEndedBefore(ctx, time.Now().Add(-period).UTC())
+}
+
+func (l *Log) Close() error {
+	return l.provider.Close()
+}
+
+// TunnelLog adds the client and tunnel to the records of a tunnel
+type TunnelLog struct {
+	log      *Log
+	clientID string
+	tunnelID string
+}
+
+func (l *TunnelLog) Save(r *Record) {
+	if l == nil {
+		return
+	}
+
+	r.ClientID = l.clientID
+	r.TunnelID = l.tunnelID
+	l.log.Save(r)
+}
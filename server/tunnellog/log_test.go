@@ -0,0 +1,91 @@
+package tunnellog
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tunnelconnsmigration "github.com/riportdev/riport/db/migration/tunnel_connections"
+	"github.com/riportdev/riport/db/sqlite"
+	"github.com/riportdev/riport/share/logger"
+	"github.com/riportdev/riport/share/query"
+)
+
+var DataSourceOptions = sqlite.DataSourceOptions{WALEnabled: false}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.New(":memory:", tunnelconnsmigration.AssetNames(), tunnelconnsmigration.Asset, DataSourceOptions)
+	require.NoError(t, err)
+	l := New(logger.NewLogger("tunnellog-test", logger.LogOutput{File: os.Stdout}, logger.LogLevelDebug), NewSqliteProvider(db))
+	defer l.Close()
+
+	now := time.Now()
+	l.ForTunnel("client-1", "1").Save(&Record{
+		Protocol:    "tcp",
+		SourceIP:    "192.0.2.1",
+		SourcePort:  50000,
+		Destination: "127.0.0.1:22",
+		Status:      StatusAccepted,
+		StartedAt:   now.Add(-40 * 24 * time.Hour),
+		EndedAt:     now.Add(-40*24*time.Hour + time.Minute),
+		BytesIn:     10,
+		BytesOut:    20,
+		CloseReason: CloseReasonClosed,
+	})
+	l.ForTunnel("client-1", "1").Save(&Record{
+		Protocol:    "tcp",
+		SourceIP:    "192.0.2.2",
+		Destination: "127.0.0.1:22",
+		Status:      StatusRejected,
+		StartedAt:   now,
+		EndedAt:     now,
+		CloseReason: CloseReasonACL,
+	})
+	l.ForTunnel("client-1", "2").Save(&Record{
+		Protocol:  "udp",
+		SourceIP:  "192.0.2.1",
+		Status:    StatusAccepted,
+		StartedAt: now.Add(-time.Hour),
+		EndedAt:   now,
+	})
+	// a nil log doesn't store anything
+	var disabled *Log
+	disabled.ForTunnel("client-1", "1").Save(&Record{})
+
+	options := &query.ListOptions{
+		Filters: []query.FilterOption{
+			{Column: []string{"client_id"}, Values: []string{"client-1"}},
+			{Column: []string{"tunnel_id"}, Values: []string{"1"}},
+		},
+		Sorts: []query.SortOption{{Column: "started_at", IsASC: true}},
+	}
+	records, count, err := l.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	require.Len(t, records, 2)
+	assert.Equal(t, "192.0.2.1", records[0].SourceIP)
+	assert.Equal(t, 50000, records[0].SourcePort)
+	assert.Equal(t, int64(time.Minute/time.Millisecond), records[0].DurationMS)
+	assert.Equal(t, int64(10), records[0].BytesIn)
+	assert.Equal(t, int64(20), records[0].BytesOut)
+	assert.Equal(t, StatusRejected, records[1].Status)
+	assert.Equal(t, CloseReasonACL, records[1].CloseReason)
+
+	options.Filters = append(options.Filters, query.FilterOption{Column: []string{"status"}, Values: []string{StatusRejected}})
+	_, count, err = l.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	deleted, err := l.DeleteOlderThan(ctx, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, count, err = l.List(ctx, &query.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,64 @@
+package tunnellog
+
+import (
+	"time"
+)
+
+const (
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+
+	CloseReasonClosed        = "closed"
+	CloseReasonTunnelClosed  = "tunnel closed"
+	CloseReasonQuotaExceeded = "quota exceeded"
+	CloseReasonIdle          = "idle"
+	CloseReasonACL           = "denied by acl"
+)
+
+var SupportedFilters = map[string]bool{
+	"protocol":          true,
+	"source_ip":         true,
+	"destination":       true,
+	"status":            true,
+	"close_reason":      true,
+	"started_at[gt]":    true,
+	"started_at[lt]":    true,
+	"started_at[since]": true,
+	"started_at[until]": true,
+	"ended_at[gt]":      true,
+	"ended_at[lt]":      true,
+	"ended_at[since]":   true,
+	"ended_at[until]":   true,
+}
+
+var SupportedSorts = map[string]bool{
+	"started_at":  true,
+	"ended_at":    true,
+	"duration_ms": true,
+	"bytes_in":    true,
+	"bytes_out":   true,
+	"source_ip":   true,
+}
+
+var ListDefaultSort = map[string][]string{
+	"sort": {"-started_at"},
+}
+
+// Record is a connection to a tunnel. Accepted connections are stored when they are closed, UDP connections when no
+// datagram was sent for a while. Rejected connections have no bytes.
+type Record struct {
+	ID          int64     `json:"id" db:"id"`
+	ClientID    string    `json:"client_id" db:"client_id"`
+	TunnelID    string    `json:"tunnel_id" db:"tunnel_id"`
+	Protocol    string    `json:"protocol" db:"protocol"`
+	SourceIP    string    `json:"source_ip" db:"source_ip"`
+	SourcePort  int       `json:"source_port" db:"source_port"`
+	Destination string    `json:"destination" db:"destination"`
+	Status      string    `json:"status" db:"status"`
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	EndedAt     time.Time `json:"ended_at" db:"ended_at"`
+	DurationMS  int64     `json:"duration_ms" db:"duration_ms"`
+	BytesIn     int64     `json:"bytes_in" db:"bytes_in"`
+	BytesOut    int64     `json:"bytes_out" db:"bytes_out"`
+	CloseReason string    `json:"close_reason" db:"close_reason"`
+}
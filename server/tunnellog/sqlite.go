@@ -0,0 +1,86 @@
+package tunnellog
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/riportdev/riport/share/query"
+)
+
+type Provider interface {
+	Save(ctx context.Context, r *Record) error
+	List(ctx context.Context, options *query.ListOptions) ([]*Record, error)
+	Count(ctx context.Context, options *query.ListOptions) (int, error)
+	DeleteEndedBefore(ctx context.Context, t time.Time) (int64, error)
+	Close() error
+}
+
+type SqliteProvider struct {
+	db        *sqlx.DB
+	converter *query.SQLConverter
+}
+
+func NewSqliteProvider(db *sqlx.DB) *SqliteProvider {
+	return &SqliteProvider{
+		db:        db,
+		converter: query.NewSQLConverter(db.DriverName()),
+	}
+}
+
+func (p *SqliteProvider) Save(ctx context.Context, r *Record) error {
+	res, err := p.db.NamedExecContext(
+		ctx,
+		`INSERT INTO tunnel_connections (
+			client_id, tunnel_id, protocol, source_ip, source_port, destination, status, started_at, ended_at,
+			duration_ms, bytes_in, bytes_out, close_reason
+		) VALUES (
+			:client_id, :tunnel_id, :protocol, :source_ip, :source_port, :destination, :status, :started_at, :ended_at,
+			:duration_ms, :bytes_in, :bytes_out, :close_reason
+		)`,
+		r,
+	)
+	if err != nil {
+		return err
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+func (p *SqliteProvider) List(ctx context.Context, options *query.ListOptions) ([]*Record, error) {
+	q, params := p.converter.ConvertListOptionsToQuery(options, "SELECT * FROM tunnel_connections")
+
+	res := []*Record{}
+	err := p.db.SelectContext(ctx, &res, q, params...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Count(ctx context.Context, options *query.ListOptions) (int, error) {
+	countOptions := *options
+	countOptions.Sorts = nil
+	countOptions.Pagination = nil
+	q, params := p.converter.ConvertListOptionsToQuery(&countOptions, "SELECT COUNT(*) FROM tunnel_connections")
+
+	var result int
+	err := p.db.GetContext(ctx, &result, q, params...)
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+func (p *SqliteProvider) DeleteEndedBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM tunnel_connections WHERE ended_at < ?", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
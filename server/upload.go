@@ -1,8 +1,11 @@
 package chserver
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
@@ -29,6 +32,8 @@ import (
 
 const uploadBufSize = 1000000 // 1Mb
 
+var uploadResumeCheckInterval = time.Second
+
 type UploadRequest struct {
 	File                 multipart.File
 	FileHeader           *multipart.FileHeader
@@ -75,7 +80,11 @@ func (al *APIListener) handleFileUploads(w http.ResponseWriter, req *http.Reques
 		al.Infof("created directory %s", al.config.GetUploadDir())
 	}
 
-	uploadRequest.SourceFilePath = al.genFilePath(uploadRequest.ID)
+	if uploadRequest.Extract {
+		uploadRequest.SourceFilePath = al.genDirPath(uploadRequest.ID)
+	} else {
+		uploadRequest.SourceFilePath = al.genFilePath(uploadRequest.ID)
+	}
 
 	err = uploadRequest.Validate()
 	if err != nil {
@@ -83,6 +92,12 @@ func (al *APIListener) handleFileUploads(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	err = validateArchiveOptions(uploadRequest)
+	if err != nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	if err = validateRemoteDestination(uploadRequest); err != nil {
 		al.jsonErrorResponseWithDetail(w, http.StatusBadRequest, "BAD_DESTINATION", "upload denied", err.Error())
 		return
@@ -98,52 +113,149 @@ func (al *APIListener) handleFileUploads(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	copiedBytes, err := al.filesAPI.CreateFile(uploadRequest.SourceFilePath, uploadRequest.File)
+	var copiedBytes int64
+	if uploadRequest.Extract {
+		copiedBytes, err = al.storeUploadedArchive(uploadRequest)
+	} else {
+		copiedBytes, err = al.storeUploadedFile(uploadRequest)
+	}
 	if err != nil {
 		al.jsonError(w, err)
 		return
 	}
 
+	uploadRep := &models.UploadResponseShort{
+		ID:        uploadRequest.ID,
+		Filepath:  uploadRequest.DestinationPath,
+		SizeBytes: copiedBytes,
+	}
+	al.auditLog.Entry(auditlog.ApplicationUploads, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithRequest(uploadRequest.UploadedFile).
+		WithResponse(uploadRep).
+		WithID(uploadRequest.UploadedFile.ID).
+		SaveForMultipleClients(uploadRequest.Clients)
+
+	go al.sendFileToClients(uploadRequest)
+
+	response := api.NewSuccessPayload(uploadRep)
+
+	al.writeJSONResponse(w, http.StatusOK, response)
+}
+
+func (al *APIListener) storeUploadedFile(uploadRequest *UploadRequest) (int64, error) {
+	copiedBytes, err := al.filesAPI.CreateFile(uploadRequest.SourceFilePath, uploadRequest.File)
+	if err != nil {
+		return 0, err
+	}
+
 	file, err := al.filesAPI.Open(uploadRequest.SourceFilePath)
 	if err != nil {
-		al.jsonError(w, err)
-		return
+		return 0, err
 	}
+	defer file.Close()
 
-	md5Checksum, err := files.Md5HashFromReader(file)
+	sha256Hash := sha256.New()
+	md5Checksum, err := files.Md5HashFromReader(io.TeeReader(file, sha256Hash))
 	if err != nil {
-		al.jsonError(w, err)
-		return
+		return 0, err
 	}
 
 	uploadRequest.Md5Checksum = md5Checksum
+	uploadRequest.Sha256Checksum = sha256Hash.Sum(nil)
+	uploadRequest.Size = copiedBytes
 
 	al.Debugf(
-		"stored file %s on server, size %d, Content-Type %s, temp location: %s, md5 checksum: %x",
+		"stored file %s on server, size %d, Content-Type %s, temp location: %s, sha256 checksum: %x",
 		uploadRequest.FileHeader.Filename,
 		uploadRequest.FileHeader.Size,
 		uploadRequest.FileHeader.Header.Get("Content-Type"),
 		uploadRequest.SourceFilePath,
-		md5Checksum,
+		uploadRequest.Sha256Checksum,
 	)
 
-	uploadRep := &models.UploadResponseShort{
-		ID:        uploadRequest.ID,
-		Filepath:  uploadRequest.DestinationPath,
-		SizeBytes: copiedBytes,
+	return copiedBytes, nil
+}
+
+// storeUploadedArchive unpacks the uploaded archive to a temp directory, so that clients can fetch the contained files one by one.
+func (al *APIListener) storeUploadedArchive(uploadRequest *UploadRequest) (int64, error) {
+	_, err := al.filesAPI.CreateDirIfNotExists(uploadRequest.SourceFilePath, files.DefaultMode)
+	if err != nil {
+		return 0, err
 	}
-	al.auditLog.Entry(auditlog.ApplicationUploads, auditlog.ActionCreate).
-		WithHTTPRequest(req).
-		WithRequest(uploadRequest.UploadedFile).
-		WithResponse(uploadRep).
-		WithID(uploadRequest.UploadedFile.ID).
-		SaveForMultipleClients(uploadRequest.Clients)
 
-	go al.sendFileToClients(uploadRequest)
+	var copiedBytes int64
+	var storeErr error
+	paths := make(map[string]bool)
+	err = files.ExtractArchive(
+		uploadRequest.File,
+		uploadRequest.FileHeader.Size,
+		files.ArchiveFormat(uploadRequest.FileHeader.Filename),
+		al.config.API.MaxFilePushSize,
+		func(name string, r io.Reader) error {
+			if paths[name] {
+				return fmt.Errorf("duplicate archive entry %q", name)
+			}
+			paths[name] = true
 
-	response := api.NewSuccessPayload(uploadRep)
+			sourceFilePath := filepath.Join(
+				uploadRequest.SourceFilePath,
+				fmt.Sprintf("%s_%d_rport_filepush", uploadRequest.ID, len(uploadRequest.Files)),
+			)
+			md5Hash := md5.New()
+			sha256Hash := sha256.New()
+			n, err := al.filesAPI.CreateFile(sourceFilePath, io.TeeReader(r, io.MultiWriter(md5Hash, sha256Hash)))
+			if err != nil {
+				storeErr = err
+				return err
+			}
+			copiedBytes += n
+
+			uploadRequest.Files = append(uploadRequest.Files, models.UploadedDirEntry{
+				Path:           name,
+				SourceFilePath: sourceFilePath,
+				Md5Checksum:    md5Hash.Sum(nil),
+				Sha256Checksum: sha256Hash.Sum(nil),
+				Size:           n,
+			})
+			return nil
+		},
+	)
+	if err != nil {
+		al.removeUploadedFiles(uploadRequest)
+		if storeErr != nil {
+			return 0, err
+		}
+		return 0, errors2.APIError{
+			Message:    "failed to extract archive",
+			Err:        err,
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
 
-	al.writeJSONResponse(w, http.StatusOK, response)
+	al.Debugf(
+		"extracted archive %s on server, size %d, %d files, temp location: %s",
+		uploadRequest.FileHeader.Filename,
+		uploadRequest.FileHeader.Size,
+		len(uploadRequest.Files),
+		uploadRequest.SourceFilePath,
+	)
+
+	return copiedBytes, nil
+}
+
+func (al *APIListener) removeUploadedFiles(uploadRequest *UploadRequest) {
+	for _, entry := range uploadRequest.Files {
+		err := al.filesAPI.Remove(entry.SourceFilePath)
+		if err != nil {
+			al.Errorf("failed to delete temp file path %s: %v", entry.SourceFilePath, err)
+		}
+	}
+
+	err := al.filesAPI.Remove(uploadRequest.SourceFilePath)
+	if err != nil {
+		al.Errorf("failed to delete temp file path %s: %v", uploadRequest.SourceFilePath, err)
+	}
 }
 
 func (al *APIListener) handleUploadsWS(w http.ResponseWriter, req *http.Request) {
@@ -181,6 +293,12 @@ func (al *APIListener) genFilePath(uuid string) string {
 	return filepath.Join(al.config.GetUploadDir(), uniqueFilename)
 }
 
+func (al *APIListener) genDirPath(uuid string) string {
+	uniqueDirname := fmt.Sprintf("%s_rport_dirpush", uuid)
+
+	return filepath.Join(al.config.GetUploadDir(), uniqueDirname)
+}
+
 type uploadResult struct {
 	resp   *models.UploadResponse
 	err    error
@@ -209,10 +327,7 @@ func (al *APIListener) sendFileToClients(uploadRequest *UploadRequest) {
 
 	al.consumeUploadResults(resChan, uploadRequest)
 
-	err := al.filesAPI.Remove(uploadRequest.SourceFilePath)
-	if err != nil {
-		al.Errorf("failed to delete temp file path %s: %v", uploadRequest.SourceFilePath, err)
-	}
+	al.removeUploadedFiles(uploadRequest)
 }
 
 func (al *APIListener) consumeUploadResults(resChan chan *uploadResult, uploadRequest *UploadRequest) {
@@ -226,6 +341,9 @@ func (al *APIListener) consumeUploadResults(resChan chan *uploadResult, uploadRe
 			errTxt := res.err.Error()
 			if errTxt == "client error: unknown request" {
 				errTxt = "client doens't support uploads, please upgrade client to the latest version to make it work"
+				if uploadRequest.Extract {
+					errTxt = "client doesn't support archive uploads, please upgrade client to the latest version to make it work"
+				}
 			}
 			output.UploadResponse = &models.UploadResponse{
 				Message: errTxt,
@@ -278,8 +396,21 @@ func (al *APIListener) sendFileToClient(wg *sync.WaitGroup, file *models.Uploade
 		}
 		return
 	}
+	requestType := comm.RequestTypeUpload
+	if file.Extract {
+		requestType = comm.RequestTypeUploadDir
+	}
+
 	resp := &models.UploadResponse{}
-	err := comm.SendRequestAndGetResponse(cl.GetConnection(), comm.RequestTypeUpload, file, resp, al.Log())
+	err := comm.SendRequestAndGetResponse(cl.GetConnection(), requestType, file, resp, al.Log())
+	for err != nil && !isClientError(err) {
+		// the connection was lost, the client continues the transfer from the received offset after reconnecting
+		cl = al.waitForUploadClientReconnect(file, cl, err)
+		if cl == nil {
+			break
+		}
+		err = comm.SendRequestAndGetResponse(cl.GetConnection(), requestType, file, resp, al.Log())
+	}
 
 	resChan <- &uploadResult{
 		err:    err,
@@ -288,7 +419,71 @@ func (al *APIListener) sendFileToClient(wg *sync.WaitGroup, file *models.Uploade
 	}
 }
 
+func newUploadProgressOutput(clientID string, progress *models.UploadProgress) *UploadOutput {
+	return &UploadOutput{
+		ClientID: clientID,
+		UploadResponse: &models.UploadResponse{
+			UploadResponseShort: models.UploadResponseShort{
+				ID:        progress.ID,
+				Filepath:  progress.Filepath,
+				SizeBytes: progress.ReceivedBytes,
+			},
+			Message:    fmt.Sprintf("received %d of %d bytes", progress.ReceivedBytes, progress.TotalBytes),
+			Status:     "progress",
+			TotalBytes: progress.TotalBytes,
+		},
+	}
+}
+
+func isClientError(err error) bool {
+	var clientErr *comm.ClientError
+	return errors.As(err, &clientErr)
+}
+
+// waitForUploadClientReconnect returns the reconnected client or nil if the client didn't reconnect within the resume timeout.
+func (al *APIListener) waitForUploadClientReconnect(file *models.UploadedFile, cl *clientdata.Client, sendErr error) *clientdata.Client {
+	clientID := cl.GetID()
+	if al.config.API.FilePushResumeTimeout <= 0 {
+		return nil
+	}
+
+	al.Infof("upload %s to client %s interrupted: %v, waiting for the client to reconnect", file.ID, clientID, sendErr)
+	al.notifyUploadEventListeners(&UploadOutput{
+		ClientID: clientID,
+		UploadResponse: &models.UploadResponse{
+			UploadResponseShort: models.UploadResponseShort{
+				ID:       file.ID,
+				Filepath: file.DestinationPath,
+			},
+			Message: fmt.Sprintf("transfer interrupted, waiting up to %s for the client to reconnect", al.config.API.FilePushResumeTimeout),
+			Status:  "interrupted",
+		},
+	})
+
+	oldConn := cl.GetConnection()
+	deadline := time.Now().Add(al.config.API.FilePushResumeTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(uploadResumeCheckInterval)
+
+		reconnected, err := al.clientService.GetActiveByID(clientID)
+		if err != nil {
+			al.Errorf("failed to get client %s: %v", clientID, err)
+			continue
+		}
+		if reconnected != nil && reconnected.GetConnection() != nil && reconnected.GetConnection() != oldConn {
+			al.Infof("client %s reconnected, resuming upload %s", clientID, file.ID)
+			return reconnected
+		}
+	}
+
+	return nil
+}
+
 func (al *APIListener) notifyUploadEventListeners(msg interface{}) {
+	// progress events and results are sent concurrently
+	al.uploadWebSocketsMu.Lock()
+	defer al.uploadWebSocketsMu.Unlock()
+
 	al.uploadWebSockets.Range(func(key, value interface{}) bool {
 		if wsConn, ok := value.(*websocket.Conn); ok {
 			err := wsConn.WriteJSON(msg)
@@ -382,11 +577,29 @@ func getClientTagsFromReqForm(req *http.Request) (clientTags *models.JobClientTa
 	return clientTags, nil
 }
 
+func validateArchiveOptions(ur *UploadRequest) error {
+	if ur.Extract && files.ArchiveFormat(ur.FileHeader.Filename) == "" {
+		return errors.New("unsupported archive format, only zip and tar.gz archives can be extracted")
+	}
+
+	if ur.DeleteExtraneous && (!ur.Extract || !ur.Sync) {
+		return errors.New("deletion of extraneous files requires extract and sync")
+	}
+
+	return nil
+}
+
 func validateRemoteDestination(ur *UploadRequest) error {
+	destinationPath := ur.DestinationPath
+	if ur.Extract {
+		// the destination is a directory
+		destinationPath = strings.TrimSuffix(destinationPath, "/") + "/"
+	}
+
 	// deny uploads to the below unix folders because there is no reason why a user should do that.
 	denied := []string{"/proc/", "/sys/", "/dev/", "/run/"}
 	for _, v := range denied {
-		if strings.HasPrefix(ur.DestinationPath, v) {
+		if strings.HasPrefix(destinationPath, v) {
 			return fmt.Errorf("uploads to %s are forbidden", v)
 		}
 	}
@@ -1,15 +1,18 @@
 package chserver
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -123,6 +126,8 @@ func TestHandleFileUploads(t *testing.T) {
 				ForceWrite:           true,
 				Sync:                 true,
 				Md5Checksum:          test.Md5Hash("some content"),
+				Sha256Checksum:       test.Sha256Hash("some content"),
+				Size:                 10,
 			},
 		},
 		{
@@ -179,6 +184,8 @@ func TestHandleFileUploads(t *testing.T) {
 				ForceWrite:           true,
 				Sync:                 true,
 				Md5Checksum:          test.Md5Hash("some content"),
+				Sha256Checksum:       test.Sha256Hash("some content"),
+				Size:                 10,
 			},
 		},
 		{
@@ -435,3 +442,127 @@ func assertClientPayload(t *testing.T, connMock *test.ConnMock, wantClientInputF
 	assert.Equal(t, wantClientInputFile, actualInputFile)
 	assert.True(t, wantReply)
 }
+
+func TestHandleFileUploadsArchive(t *testing.T) {
+	cl := clients.New(t).ID("22114341234").Logger(testLog).Build()
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	done := make(chan bool)
+	connMock.DoneChannel = done
+	cl.SetConnection(connMock)
+
+	dirPath := "/data/filepush/id-123_rport_dirpush"
+	fileAPIMock := test.NewFileAPIMock()
+	fileAPIMock.On("CreateDirIfNotExists", "/data/"+files.DefaultUploadTempFolder, files.DefaultMode).Return(true, nil)
+	fileAPIMock.On("CreateDirIfNotExists", dirPath, files.DefaultMode).Return(true, nil)
+	// the readers are consumed when the files are created, a matcher reading them would consume them too early
+	var mu sync.Mutex
+	createdFiles := make(map[string]string)
+	for i, content := range []string{"server {}", "index"} {
+		sourceFilePath := fmt.Sprintf("%s/id-123_%d_rport_filepush", dirPath, i)
+		fileAPIMock.On("CreateFile", sourceFilePath, mock.Anything).Run(func(args mock.Arguments) {
+			actualFileContent, err := io.ReadAll(args.Get(1).(io.Reader))
+			require.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			createdFiles[args.String(0)] = string(actualFileContent)
+		}).Return(int64(len(content)), nil)
+		fileAPIMock.On("Remove", sourceFilePath).Return(nil)
+	}
+	fileAPIMock.On("Remove", dirPath).Return(nil)
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: clients.NewClientService(
+				nil,
+				nil,
+				clients.NewClientRepository([]*clientdata.Client{cl}, &hour, testLog),
+				testLog,
+				nil,
+			),
+			clientGroupProvider: mockClientGroupProvider{},
+			config: &chconfig.Config{
+				Server: chconfig.ServerConfig{
+					DataDir: "/data",
+				},
+				API: chconfig.APIConfig{
+					MaxFilePushSize: int64(10 << 20),
+				},
+			},
+			filesAPI: fileAPIMock,
+		},
+		Logger:      testLog,
+		userService: MockUserService("admin", users.Administrators),
+	}
+	al.initRouter()
+
+	archive := &bytes.Buffer{}
+	zw := zip.NewWriter(archive)
+	for _, entry := range []struct{ name, content string }{{"conf.d/default.conf", "server {}"}, {"html/index.html", "index"}} {
+		w, err := zw.Create(entry.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("upload", "nginx.zip")
+	require.NoError(t, err)
+	_, err = io.Copy(part, archive)
+	require.NoError(t, err)
+	for key, val := range map[string]string{
+		"client_id":         "22114341234",
+		"dest":              "/etc/nginx",
+		"id":                "id-123",
+		"extract":           "1",
+		"sync":              "1",
+		"delete_extraneous": "1",
+	} {
+		require.NoError(t, writer.WriteField(key, val))
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files", body)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(api.WithUser(context.Background(), "admin"))
+
+	rec := httptest.NewRecorder()
+	al.router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"data":{"uuid":"id-123","filepath":"/etc/nginx","size":14}}`, rec.Body.String())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 2):
+	}
+
+	name, wantReply, payload := connMock.InputSendRequest()
+	actualInputFile := &models.UploadedFile{}
+	require.NoError(t, actualInputFile.FromBytes(payload))
+
+	assert.Equal(t, "upload_dir", name)
+	assert.True(t, wantReply)
+	assert.Equal(t, &models.UploadedFile{
+		ID:               "id-123",
+		SourceFilePath:   dirPath,
+		DestinationPath:  "/etc/nginx",
+		Sync:             true,
+		Extract:          true,
+		DeleteExtraneous: true,
+		Files: []models.UploadedDirEntry{
+			{Path: "conf.d/default.conf", SourceFilePath: dirPath + "/id-123_0_rport_filepush", Md5Checksum: test.Md5Hash("server {}"), Sha256Checksum: test.Sha256Hash("server {}"), Size: 9},
+			{Path: "html/index.html", SourceFilePath: dirPath + "/id-123_1_rport_filepush", Md5Checksum: test.Md5Hash("index"), Sha256Checksum: test.Sha256Hash("index"), Size: 5},
+		},
+	}, actualInputFile)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]string{
+		dirPath + "/id-123_0_rport_filepush": "server {}",
+		dirPath + "/id-123_1_rport_filepush": "index",
+	}, createdFiles)
+}
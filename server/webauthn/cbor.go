@@ -0,0 +1,143 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const maxCBORDepth = 16
+
+var errCBORUnexpectedEnd = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes a single CBOR data item and returns it together with the remaining bytes.
+// Only the subset required by WebAuthn is supported: definite-length items, integer and text map keys.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nesting too deep")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORUnexpectedEnd
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		default:
+			return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+
+	arg, data, err := readCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), data, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), data, nil
+	case 2, 3:
+		if uint64(len(data)) < arg {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		if major == 3 {
+			return string(data[:arg]), data[arg:], nil
+		}
+		return data[:arg], data[arg:], nil
+	case 4:
+		// every item takes at least one byte, so a longer array can't be valid
+		if uint64(len(data)) < arg {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		arr := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, data, nil
+	case 5:
+		if uint64(len(data)) < arg {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	default:
+		// tags carry no meaning for WebAuthn structures, the tagged item is returned as is
+		return decodeCBORItem(data, depth+1)
+	}
+}
+
+func readCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, errors.New("cbor: indefinite length items are not supported")
+	}
+	if len(data) < size {
+		return 0, nil, errCBORUnexpectedEnd
+	}
+
+	switch size {
+	case 1:
+		return uint64(data[0]), data[1:], nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	default:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+}
@@ -0,0 +1,52 @@
+package webauthn
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// test vectors from https://www.rfc-editor.org/rfc/rfc8949#appendix-A
+	testCases := []struct {
+		hex           string
+		expected      interface{}
+		expectedError string
+	}{
+		{hex: "0a", expected: int64(10)},
+		{hex: "1903e8", expected: int64(1000)},
+		{hex: "1b000000e8d4a51000", expected: int64(1000000000000)},
+		{hex: "3903e7", expected: int64(-1000)},
+		{hex: "4401020304", expected: []byte{1, 2, 3, 4}},
+		{hex: "6449455446", expected: "IETF"},
+		{hex: "83010203", expected: []interface{}{int64(1), int64(2), int64(3)}},
+		{hex: "a201020304", expected: map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{hex: "a26161016162820203", expected: map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{hex: "f5", expected: true},
+		{hex: "f6", expected: nil},
+		{hex: "c074323031332d30332d32315432303a30343a30305a", expected: "2013-03-21T20:04:00Z"},
+		{hex: "9f018202039f0405ffff", expectedError: "cbor: indefinite length items are not supported"},
+		{hex: "5a00000010", expectedError: "cbor: unexpected end of data"},
+		{hex: "a1410102", expectedError: "cbor: unsupported map key type []uint8"},
+		{hex: "9bffffffffffffffff", expectedError: "cbor: unexpected end of data"},
+		{hex: "fb3ff199999999999a", expectedError: "cbor: unsupported simple value 27"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hex, func(t *testing.T) {
+			data, err := hex.DecodeString(tc.hex)
+			require.NoError(t, err)
+
+			actual, rest, err := decodeCBOR(data)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Empty(t, rest)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms supported for security keys, see https://www.iana.org/assignments/cose/cose.xhtml#algorithms
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in order of preference.
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+const (
+	coseKeyType   int64 = 1
+	coseAlgorithm int64 = 3
+	coseCurve     int64 = -1
+	coseX         int64 = -2
+	coseY         int64 = -3
+	coseRSAN      int64 = -1
+	coseRSAE      int64 = -2
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey parses a COSE encoded public key and returns the number of bytes it occupies in data.
+func parsePublicKey(data []byte) (*publicKey, int, error) {
+	item, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("public key is not a COSE key")
+	}
+	kty, _ := m[coseKeyType].(int64)
+	alg, _ := m[coseAlgorithm].(int64)
+
+	pk := &publicKey{alg: alg}
+	switch alg {
+	case AlgES256:
+		crv, _ := m[coseCurve].(int64)
+		x, _ := m[coseX].([]byte)
+		y, _ := m[coseY].([]byte)
+		if kty != coseKeyTypeEC2 || crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("invalid ES256 public key")
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, 0, errors.New("invalid ES256 public key")
+		}
+		pk.key = key
+	case AlgEdDSA:
+		crv, _ := m[coseCurve].(int64)
+		x, _ := m[coseX].([]byte)
+		if kty != coseKeyTypeOKP || crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("invalid EdDSA public key")
+		}
+		pk.key = ed25519.PublicKey(x)
+	case AlgRS256:
+		n, _ := m[coseRSAN].([]byte)
+		e, _ := m[coseRSAE].([]byte)
+		if kty != coseKeyTypeRSA || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("invalid RS256 public key")
+		}
+		pk.key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	default:
+		return nil, 0, fmt.Errorf("unsupported public key algorithm %d", alg)
+	}
+
+	return pk, len(data) - len(rest), nil
+}
+
+func (k *publicKey) verify(message, signature []byte) error {
+	digest := sha256.Sum256(message)
+	valid := false
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, message, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return errInvalidSignature
+	}
+	return nil
+}
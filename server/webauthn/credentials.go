@@ -0,0 +1,85 @@
+package webauthn
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Credential is a security key registered by a user.
+type Credential struct {
+	// ID is the base64url encoded credential ID assigned by the authenticator.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey  []byte     `json:"public_key"`
+	SignCount  uint32     `json:"sign_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Credentials are stored alongside the user record, as JSON text in a database column or as a list in the auth file.
+type Credentials []Credential
+
+// Find returns the index of the credential with the given ID or -1.
+func (c Credentials) Find(id string) int {
+	for i := range c {
+		if c[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove returns a copy of the credentials without the credential with the given ID.
+func (c Credentials) Remove(id string) Credentials {
+	res := make(Credentials, 0, len(c))
+	for _, cred := range c {
+		if cred.ID != id {
+			res = append(res, cred)
+		}
+	}
+	return res
+}
+
+func (c Credentials) descriptors() []CredentialDescriptor {
+	res := make([]CredentialDescriptor, 0, len(c))
+	for _, cred := range c {
+		res = append(res, CredentialDescriptor{
+			Type: publicKeyCredentialType,
+			ID:   cred.ID,
+		})
+	}
+	return res
+}
+
+func (c Credentials) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (c *Credentials) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("expected to have string or []byte, got %T", value)
+	}
+
+	if len(data) == 0 {
+		*c = nil
+		return nil
+	}
+	return json.Unmarshal(data, c)
+}
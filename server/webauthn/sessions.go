@@ -0,0 +1,60 @@
+package webauthn
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the challenges of pending registrations and logins until they are used or expire.
+type SessionStore struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+type session struct {
+	challenge []byte
+	expiry    time.Time
+}
+
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	return &SessionStore{
+		ttl:      ttl,
+		sessions: make(map[string]session),
+	}
+}
+
+// Save stores the challenge under the given key, a previous challenge for the same key is replaced.
+func (s *SessionStore) Save(key string, challenge []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := now()
+	for k, v := range s.sessions {
+		if current.After(v.expiry) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[key] = session{
+		challenge: challenge,
+		expiry:    current.Add(s.ttl),
+	}
+}
+
+// Take returns the challenge stored under the given key and removes it, so that every challenge can be used only once.
+func (s *SessionStore) Take(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[key]
+	if !ok {
+		return nil, false
+	}
+	delete(s.sessions, key)
+
+	if now().After(sess.expiry) {
+		return nil, false
+	}
+	return sess.challenge, true
+}
@@ -0,0 +1,366 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the time a user has to complete a registration or login with a security key.
+const DefaultTimeout = 2 * time.Minute
+
+const (
+	publicKeyCredentialType = "public-key"
+	clientDataTypeCreate    = "webauthn.create"
+	clientDataTypeGet       = "webauthn.get"
+	attestationFormatNone   = "none"
+
+	flagUserPresent            byte = 0x01
+	flagAttestedCredentialData byte = 0x40
+
+	challengeLength       = 32
+	maxCredentialIDLength = 1023
+)
+
+var now = time.Now
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create() to register a new security key.
+// Binary values are base64url encoded as in PublicKeyCredentialCreationOptionsJSON.
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get() to sign in with a registered security key.
+// Binary values are base64url encoded as in PublicKeyCredentialRequestOptionsJSON.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the base64url encoded result of navigator.credentials.create().
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the base64url encoded result of navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// RelyingParty verifies registrations and assertions for the server identified by ID.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+func NewRelyingParty(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		ID:      id,
+		Name:    name,
+		Origins: origins,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// NewChallenge generates a random challenge for a single registration or login.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate webauthn challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+func (rp *RelyingParty) CreationOptions(username string, challenge []byte, existing Credentials) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: publicKeyCredentialType, Alg: alg})
+	}
+
+	// the user handle must not contain personal information, so the username is hashed
+	userID := sha256.Sum256([]byte(username))
+
+	return &CreationOptions{
+		RP: RelyingPartyEntity{
+			ID:   rp.ID,
+			Name: rp.Name,
+		},
+		User: UserEntity{
+			ID:          encode(userID[:]),
+			Name:        username,
+			DisplayName: username,
+		},
+		Challenge:          encode(challenge),
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: existing.descriptors(),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "discouraged",
+			UserVerification: "preferred",
+		},
+		Attestation: attestationFormatNone,
+	}
+}
+
+func (rp *RelyingParty) RequestOptions(challenge []byte, creds Credentials) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        encode(challenge),
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: creds.descriptors(),
+		UserVerification: "preferred",
+	}
+}
+
+// VerifyRegistration checks the response of the authenticator to the given challenge and returns the new credential.
+// Attestation statements are not verified, the server requests "none" attestation and trusts any authenticator.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, resp *RegistrationResponse) (*Credential, error) {
+	if resp.Type != publicKeyCredentialType {
+		return nil, fmt.Errorf("unsupported credential type %q", resp.Type)
+	}
+
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	if err := rp.verifyClientData(rawClientData, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestationObject: %w", err)
+	}
+	item, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestationObject: %w", err)
+	}
+	attestation, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid attestationObject")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestationObject has no authData")
+	}
+	if format, _ := attestation["fmt"].(string); format == attestationFormatNone {
+		if stmt, ok := attestation["attStmt"].(map[interface{}]interface{}); !ok || len(stmt) != 0 {
+			return nil, errors.New("attestation statement must be empty for none attestation")
+		}
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredentialData == 0 {
+		return nil, errors.New("authenticator data has no attested credential")
+	}
+
+	return &Credential{
+		ID:        encode(authData.credentialID),
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+		CreatedAt: now().UTC(),
+	}, nil
+}
+
+// VerifyAssertion checks the signature of the authenticator over the given challenge. On success the matching credential
+// gets updated in creds and is returned.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, creds Credentials, resp *AssertionResponse) (*Credential, error) {
+	if resp.Type != publicKeyCredentialType {
+		return nil, fmt.Errorf("unsupported credential type %q", resp.Type)
+	}
+
+	i := creds.Find(resp.ID)
+	if i < 0 {
+		return nil, errors.New("unknown security key")
+	}
+	cred := &creds[i]
+
+	rawClientData, err := decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+	if err := rp.verifyClientData(rawClientData, clientDataTypeGet, challenge); err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authenticatorData: %w", err)
+	}
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decode(resp.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	pk, _, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := pk.verify(signed, signature); err != nil {
+		return nil, err
+	}
+
+	// authenticators without a counter always report zero, otherwise it has to increase with every use
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return nil, errors.New("signature counter did not increase, the security key might be cloned")
+	}
+
+	usedAt := now().UTC()
+	cred.SignCount = authData.signCount
+	cred.LastUsedAt = &usedAt
+	return cred, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, expectedType string, challenge []byte) error {
+	cd := clientData{}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+
+	if cd.Type != expectedType {
+		return fmt.Errorf("unexpected client data type %q, expected %q", cd.Type, expectedType)
+	}
+
+	received, err := decode(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return errors.New("challenge mismatch")
+	}
+
+	for _, origin := range rp.Origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", cd.Origin)
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (rp *RelyingParty) parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data[:32], rpIDHash[:]) {
+		return nil, errors.New("authenticator data belongs to another relying party")
+	}
+
+	ad := &authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return nil, errors.New("user presence is required")
+	}
+
+	if ad.flags&flagAttestedCredentialData != 0 {
+		// aaguid (16 bytes), credential ID length (2 bytes), credential ID, public key
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > maxCredentialIDLength || len(rest) < idLen {
+			return nil, errors.New("invalid credential ID")
+		}
+		ad.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, n, err := parsePublicKey(rest)
+		if err != nil {
+			return nil, err
+		}
+		ad.publicKey = rest[:n]
+	}
+
+	return ad, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode accepts base64url with and without padding since browsers and client libraries differ.
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,292 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "rport.example.com"
+	testOrigin = "https://rport.example.com"
+)
+
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+// encodeCBOR is a minimal encoder to build authenticator responses, maps are given as ordered pairs.
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n int) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 256:
+			return []byte{major<<5 | 24, byte(n)}
+		default:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(n))
+			return b
+		}
+	}
+	switch t := v.(type) {
+	case int:
+		if t < 0 {
+			return head(1, -1-t)
+		}
+		return head(0, t)
+	case []byte:
+		return append(head(2, len(t)), t...)
+	case string:
+		return append(head(3, len(t)), t...)
+	case []cborPair:
+		res := head(5, len(t))
+		for _, p := range t {
+			res = append(res, encodeCBOR(p.key)...)
+			res = append(res, encodeCBOR(p.value)...)
+		}
+		return res
+	}
+	panic("unsupported type")
+}
+
+type testAuthenticator struct {
+	credentialID []byte
+	cose         []byte
+	sign         func(message []byte) []byte
+	signCount    uint32
+}
+
+func newES256Authenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	return &testAuthenticator{
+		credentialID: []byte("es256-credential"),
+		cose: encodeCBOR([]cborPair{
+			{int(coseKeyType), int(coseKeyTypeEC2)},
+			{int(coseAlgorithm), int(AlgES256)},
+			{int(coseCurve), int(coseCurveP256)},
+			{int(coseX), x},
+			{int(coseY), y},
+		}),
+		sign: func(message []byte) []byte {
+			digest := sha256.Sum256(message)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			require.NoError(t, err)
+			return sig
+		},
+	}
+}
+
+func newEdDSAAuthenticator(t *testing.T) *testAuthenticator {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return &testAuthenticator{
+		credentialID: []byte("eddsa-credential"),
+		cose: encodeCBOR([]cborPair{
+			{int(coseKeyType), int(coseKeyTypeOKP)},
+			{int(coseAlgorithm), int(AlgEdDSA)},
+			{int(coseCurve), int(coseCurveEd25519)},
+			{int(coseX), []byte(pub)},
+		}),
+		sign: func(message []byte) []byte {
+			return ed25519.Sign(priv, message)
+		},
+	}
+}
+
+func (a *testAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.cose...)
+	}
+	return data
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	b, err := json.Marshal(clientData{Type: typ, Challenge: encode(challenge), Origin: origin})
+	require.NoError(t, err)
+	return b
+}
+
+func (a *testAuthenticator) register(t *testing.T, challenge []byte) *RegistrationResponse {
+	resp := &RegistrationResponse{
+		ID:   encode(a.credentialID),
+		Type: publicKeyCredentialType,
+	}
+	resp.Response.ClientDataJSON = encode(clientDataJSON(t, clientDataTypeCreate, challenge, testOrigin))
+	resp.Response.AttestationObject = encode(encodeCBOR([]cborPair{
+		{"fmt", attestationFormatNone},
+		{"attStmt", []cborPair{}},
+		{"authData", a.authData(testRPID, flagUserPresent|flagAttestedCredentialData, true)},
+	}))
+	return resp
+}
+
+func (a *testAuthenticator) assert(t *testing.T, challenge []byte, origin string) *AssertionResponse {
+	a.signCount++
+	authData := a.authData(testRPID, flagUserPresent, false)
+	cd := clientDataJSON(t, clientDataTypeGet, challenge, origin)
+	cdHash := sha256.Sum256(cd)
+
+	resp := &AssertionResponse{
+		ID:   encode(a.credentialID),
+		Type: publicKeyCredentialType,
+	}
+	resp.Response.ClientDataJSON = encode(cd)
+	resp.Response.AuthenticatorData = encode(authData)
+	resp.Response.Signature = encode(a.sign(append(append([]byte{}, authData...), cdHash[:]...)))
+	return resp
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	usedAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return usedAt }
+	defer func() { now = time.Now }()
+
+	rp := NewRelyingParty(testRPID, "Riport", []string{testOrigin})
+
+	for name, newAuthenticator := range map[string]func(t *testing.T) *testAuthenticator{
+		"ES256": newES256Authenticator,
+		"EdDSA": newEdDSAAuthenticator,
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := newAuthenticator(t)
+
+			challenge, err := NewChallenge()
+			require.NoError(t, err)
+			cred, err := rp.VerifyRegistration(challenge, a.register(t, challenge))
+			require.NoError(t, err)
+			assert.Equal(t, encode(a.credentialID), cred.ID)
+			assert.Equal(t, a.cose, cred.PublicKey)
+			assert.Equal(t, usedAt, cred.CreatedAt)
+
+			creds := Credentials{*cred}
+			challenge, err = NewChallenge()
+			require.NoError(t, err)
+			resp := a.assert(t, challenge, testOrigin)
+			used, err := rp.VerifyAssertion(challenge, creds, resp)
+			require.NoError(t, err)
+			assert.Equal(t, uint32(1), used.SignCount)
+			assert.Equal(t, &usedAt, creds[0].LastUsedAt)
+
+			_, err = rp.VerifyAssertion(challenge, creds, resp)
+			assert.EqualError(t, err, "signature counter did not increase, the security key might be cloned")
+		})
+	}
+}
+
+func TestVerifyRegistrationErrors(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Riport", []string{testOrigin})
+	a := newES256Authenticator(t)
+	challenge := []byte("challenge")
+
+	resp := a.register(t, []byte("other challenge"))
+	_, err := rp.VerifyRegistration(challenge, resp)
+	assert.EqualError(t, err, "challenge mismatch")
+
+	resp = a.register(t, challenge)
+	resp.Response.ClientDataJSON = encode(clientDataJSON(t, clientDataTypeGet, challenge, testOrigin))
+	_, err = rp.VerifyRegistration(challenge, resp)
+	assert.EqualError(t, err, `unexpected client data type "webauthn.get", expected "webauthn.create"`)
+
+	resp = a.register(t, challenge)
+	resp.Response.ClientDataJSON = encode(clientDataJSON(t, clientDataTypeCreate, challenge, "https://evil.example.com"))
+	_, err = rp.VerifyRegistration(challenge, resp)
+	assert.EqualError(t, err, `origin "https://evil.example.com" is not allowed`)
+
+	resp = a.register(t, challenge)
+	otherRP := NewRelyingParty("other.example.com", "Riport", []string{testOrigin})
+	_, err = otherRP.VerifyRegistration(challenge, resp)
+	assert.EqualError(t, err, "authenticator data belongs to another relying party")
+
+	resp.Response.AttestationObject = encode(encodeCBOR([]cborPair{
+		{"fmt", attestationFormatNone},
+		{"attStmt", []cborPair{}},
+		{"authData", a.authData(testRPID, flagAttestedCredentialData, true)},
+	}))
+	_, err = rp.VerifyRegistration(challenge, resp)
+	assert.EqualError(t, err, "user presence is required")
+}
+
+func TestVerifyAssertionErrors(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Riport", []string{testOrigin})
+	a := newES256Authenticator(t)
+	challenge := []byte("challenge")
+	cred, err := rp.VerifyRegistration(challenge, a.register(t, challenge))
+	require.NoError(t, err)
+	creds := Credentials{*cred}
+
+	resp := a.assert(t, challenge, testOrigin)
+	resp.ID = "unknown"
+	_, err = rp.VerifyAssertion(challenge, creds, resp)
+	assert.EqualError(t, err, "unknown security key")
+
+	resp = a.assert(t, challenge, testOrigin)
+	resp.Response.Signature = encode(newES256Authenticator(t).sign([]byte("other message")))
+	_, err = rp.VerifyAssertion(challenge, creds, resp)
+	assert.EqualError(t, err, "invalid signature")
+
+	resp = a.assert(t, []byte("other challenge"), testOrigin)
+	_, err = rp.VerifyAssertion(challenge, creds, resp)
+	assert.EqualError(t, err, "challenge mismatch")
+	assert.Equal(t, uint32(0), creds[0].SignCount)
+}
+
+func TestCredentialsScanValue(t *testing.T) {
+	creds := Credentials{{ID: "abc", Name: "YubiKey", PublicKey: []byte{1, 2}, SignCount: 3}}
+	value, err := creds.Value()
+	require.NoError(t, err)
+
+	scanned := Credentials{}
+	require.NoError(t, scanned.Scan(value))
+	assert.Equal(t, creds, scanned)
+
+	value, err = Credentials{}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "", value)
+	require.NoError(t, scanned.Scan(value))
+	assert.Nil(t, scanned)
+}
+
+func TestSessionStore(t *testing.T) {
+	current := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	s := NewSessionStore(time.Minute)
+	s.Save("login:admin", []byte("1"))
+	s.Save("registration:admin", []byte("2"))
+
+	challenge, ok := s.Take("login:admin")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), challenge)
+	_, ok = s.Take("login:admin")
+	assert.False(t, ok, "challenges are used only once")
+
+	current = current.Add(2 * time.Minute)
+	_, ok = s.Take("registration:admin")
+	assert.False(t, ok, "expired challenges are rejected")
+}
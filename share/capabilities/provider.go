@@ -8,9 +8,10 @@ import (
 
 func NewServerCapabilities(cfg *chconfig.MonitoringConfig) *models.Capabilities {
 	caps := models.Capabilities{
-		ServerVersion:      chshare.BuildVersion,
-		MonitoringVersion:  chshare.MonitoringVersion,
-		IPAddressesVersion: chshare.IPAddressesVersion,
+		ServerVersion:         chshare.BuildVersion,
+		MonitoringVersion:     chshare.MonitoringVersion,
+		IPAddressesVersion:    chshare.IPAddressesVersion,
+		UploadProgressVersion: chshare.UploadProgressVersion,
 	}
 
 	if !cfg.Enabled {
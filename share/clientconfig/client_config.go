@@ -26,27 +26,36 @@ type Config struct {
 }
 
 type ClientConfig struct {
-	AttributesFilePath       string            `json:"-" mapstructure:"attributes_file_path"`
-	Server                   string            `json:"server" mapstructure:"server"`
-	FallbackServers          []string          `json:"fallback_servers" mapstructure:"fallback_servers"`
-	ServerSwitchbackInterval time.Duration     `json:"server_switchback_interval" mapstructure:"server_switchback_interval"`
-	Fingerprint              string            `json:"fingerprint" mapstructure:"fingerprint"`
-	Auth                     string            `json:"auth" mapstructure:"auth"`
-	Proxy                    string            `json:"proxy" mapstructure:"proxy"`
-	ID                       string            `json:"id" mapstructure:"id"`
-	UseSystemID              bool              `json:"use_system_id" mapstructure:"use_system_id"`
-	Name                     string            `json:"name" mapstructure:"name"`
-	UseHostname              bool              `json:"use_hostname" mapstructure:"use_hostname"`
-	Tags                     []string          `json:"tags" mapstructure:"tags"`
-	Labels                   map[string]string `json:"labels" mapstructure:"labels"`
-	Remotes                  []string          `json:"remotes" mapstructure:"remotes"`
-	TunnelAllowed            []string          `json:"tunnel_allowed" mapstructure:"tunnel_allowed"`
-	AllowRoot                bool              `json:"allow_root" mapstructure:"allow_root"`
-	UpdatesInterval          time.Duration     `json:"updates_interval" mapstructure:"updates_interval"`
-	DataDir                  string            `json:"data_dir" mapstructure:"data_dir"`
-	BindInterface            string            `json:"bind_interface" mapstructure:"bind_interface"`
-	IPAPIURL                 string            `json:"ip_api_url" mapstructure:"ip_api_url"`
-	IPRefreshMin             time.Duration     `json:"ip_refresh_min" mapstructure:"ip_refresh_min"`
+	AttributesFilePath         string            `json:"-" mapstructure:"attributes_file_path"`
+	Server                     string            `json:"server" mapstructure:"server"`
+	FallbackServers            []string          `json:"fallback_servers" mapstructure:"fallback_servers"`
+	ServerSwitchbackInterval   time.Duration     `json:"server_switchback_interval" mapstructure:"server_switchback_interval"`
+	Fingerprint                string            `json:"fingerprint" mapstructure:"fingerprint"`
+	Auth                       string            `json:"auth" mapstructure:"auth"`
+	EnrollmentToken            string            `json:"-" mapstructure:"enrollment_token"`
+	TLSCertFile                string            `json:"tls_cert_file" mapstructure:"tls_cert_file"`
+	TLSKeyFile                 string            `json:"tls_key_file" mapstructure:"tls_key_file"`
+	TLSCAFile                  string            `json:"tls_ca_file" mapstructure:"tls_ca_file"`
+	Proxy                      string            `json:"proxy" mapstructure:"proxy"`
+	ID                         string            `json:"id" mapstructure:"id"`
+	UseSystemID                bool              `json:"use_system_id" mapstructure:"use_system_id"`
+	Name                       string            `json:"name" mapstructure:"name"`
+	UseHostname                bool              `json:"use_hostname" mapstructure:"use_hostname"`
+	Tags                       []string          `json:"tags" mapstructure:"tags"`
+	Labels                     map[string]string `json:"labels" mapstructure:"labels"`
+	Remotes                    []string          `json:"remotes" mapstructure:"remotes"`
+	TunnelAllowed              []string          `json:"tunnel_allowed" mapstructure:"tunnel_allowed"`
+	DirectTunnelsEnabled       bool              `json:"direct_tunnels_enabled" mapstructure:"direct_tunnels_enabled"`
+	DirectTunnelsHost          string            `json:"direct_tunnels_host" mapstructure:"direct_tunnels_host"`
+	DirectTunnelsNATPMP        bool              `json:"direct_tunnels_nat_pmp" mapstructure:"direct_tunnels_nat_pmp"`
+	DirectTunnelsNATPMPGateway string            `json:"direct_tunnels_nat_pmp_gateway" mapstructure:"direct_tunnels_nat_pmp_gateway"`
+	AllowRoot                  bool              `json:"allow_root" mapstructure:"allow_root"`
+	UpdatesInterval            time.Duration     `json:"updates_interval" mapstructure:"updates_interval"`
+	InventoryInterval          time.Duration     `json:"inventory_interval" mapstructure:"inventory_interval"`
+	DataDir                    string            `json:"data_dir" mapstructure:"data_dir"`
+	BindInterface              string            `json:"bind_interface" mapstructure:"bind_interface"`
+	IPAPIURL                   string            `json:"ip_api_url" mapstructure:"ip_api_url"`
+	IPRefreshMin               time.Duration     `json:"ip_refresh_min" mapstructure:"ip_refresh_min"`
 
 	ProxyURL *url.URL         `json:"proxy_url"`
 	Tunnels  []*models.Remote `json:"tunnels"`
@@ -110,8 +119,9 @@ type MonitoringConfig struct {
 }
 
 type FileReceptionConfig struct {
-	Protected []string `json:"protected" mapstructure:"protected"`
-	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
+	Protected      []string `json:"protected" mapstructure:"protected"`
+	Enabled        bool     `json:"enabled" mapstructure:"enabled"`
+	BandwidthLimit int64    `json:"bandwidth_limit" mapstructure:"bandwidth_limit"`
 }
 
 type InterpreterAliasEncoding struct {
@@ -13,6 +13,12 @@ const (
 	RequestTypeRefreshUpdatesStatus = "refresh_updates_status"
 	RequestTypePutCapabilities      = "put_capabilities"
 	RequestTypeCheckTunnelAllowed   = "check_tunnel_allowed"
+	RequestTypeStartDirectTunnel    = "start_direct_tunnel"
+	RequestTypeStopDirectTunnel     = "stop_direct_tunnel"
+	RequestTypeDirectTunnelStatus   = "direct_tunnel_status"
+	RequestTypeRefreshInventory     = "refresh_inventory"
+	RequestTypeEnrollment           = "enrollment"
+	RequestTypeRenewCertificate     = "renew_certificate"
 
 	RequestTypeUpdateClientAttributes = "update_client_metadata"
 
@@ -21,7 +27,10 @@ const (
 	RequestTypeUpdatesStatus   = "updates_status"
 	RequestTypeSaveMeasurement = "save_measurement"
 	RequestTypeUpload          = "upload"
+	RequestTypeUploadDir       = "upload_dir"
+	RequestTypeUploadProgress  = "upload_progress"
 	RequestTypeIPAddresses     = "ip_addresses"
+	RequestTypeInventory       = "inventory"
 
 	// RequestTypePing request types understood on both sides, client and server
 	RequestTypePing = "ping"
@@ -57,3 +66,24 @@ type CheckTunnelAllowedRequest struct {
 type CheckTunnelAllowedResponse struct {
 	IsAllowed bool
 }
+
+// StartDirectTunnelRequest asks the client to accept tunnel connections directly, repeated requests for the same ID update the ACL
+type StartDirectTunnelRequest struct {
+	ID     string
+	Remote string
+	// AllowedIPs contains CIDR ranges, clients refuse direct tunnels without them
+	AllowedIPs []string
+}
+
+type StartDirectTunnelResponse struct {
+	// Addresses the client accepts direct connections on, in host:port format
+	Addresses []string
+}
+
+type DirectTunnelRequest struct {
+	ID string
+}
+
+type DirectTunnelStatusResponse struct {
+	LastActive time.Time
+}
@@ -0,0 +1,151 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+var ErrArchiveTooLarge = errors.New("extracted archive exceeds the size limit")
+
+// ArchiveEntryHandler is called for every regular file of an archive with its cleaned slash separated relative path.
+type ArchiveEntryHandler func(name string, r io.Reader) error
+
+// ArchiveFormat returns the archive format derived from the file name or an empty string for unsupported formats.
+func ArchiveFormat(fileName string) string {
+	lower := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveFormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveFormatTarGz
+	}
+	return ""
+}
+
+// ExtractArchive reads a zip or tar.gz archive of the given size and passes all regular files to the handler.
+// Directories, links and other special entries are skipped. If maxBytes is positive, it limits the total size of the extracted files.
+func ExtractArchive(r io.ReaderAt, size int64, format string, maxBytes int64, handle ArchiveEntryHandler) error {
+	e := &archiveExtractor{
+		limited:   maxBytes > 0,
+		remaining: maxBytes,
+		handle:    handle,
+	}
+
+	switch format {
+	case ArchiveFormatZip:
+		return e.extractZip(r, size)
+	case ArchiveFormatTarGz:
+		return e.extractTarGz(io.NewSectionReader(r, 0, size))
+	}
+	return fmt.Errorf("unsupported archive format %q", format)
+}
+
+type archiveExtractor struct {
+	limited   bool
+	remaining int64
+	handle    ArchiveEntryHandler
+}
+
+func (e *archiveExtractor) extractZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		err := e.extractZipFile(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *archiveExtractor) extractZipFile(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open archive entry %q: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	return e.extractEntry(f.Name, rc)
+}
+
+func (e *archiveExtractor) extractTarGz(r io.Reader) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open tar.gz archive: %v", err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar.gz archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		err = e.extractEntry(hdr.Name, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *archiveExtractor) extractEntry(name string, r io.Reader) error {
+	cleanName, err := CleanArchiveEntryName(name)
+	if err != nil {
+		return err
+	}
+
+	if e.limited {
+		r = &limitedEntryReader{r: r, e: e}
+	}
+
+	return e.handle(cleanName, r)
+}
+
+// limitedEntryReader fails instead of silently truncating an entry when the size limit is exceeded.
+type limitedEntryReader struct {
+	r io.Reader
+	e *archiveExtractor
+}
+
+func (l *limitedEntryReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.e.remaining -= int64(n)
+	if l.e.remaining < 0 {
+		return n, ErrArchiveTooLarge
+	}
+	return n, err
+}
+
+// CleanArchiveEntryName returns the cleaned relative path of an archive entry and rejects paths pointing outside of the extraction root.
+func CleanArchiveEntryName(name string) (string, error) {
+	cleanName := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if cleanName == "." || path.IsAbs(cleanName) || cleanName == ".." || strings.HasPrefix(cleanName, "../") || strings.Contains(cleanName, ":") {
+		return "", fmt.Errorf("invalid archive entry %q", name)
+	}
+	return cleanName, nil
+}
@@ -0,0 +1,116 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeZip(t *testing.T, entries map[string]string) *bytes.Reader {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	_, err := zw.Create("dir/")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func writeTarGz(t *testing.T, entries map[string]string) *bytes.Reader {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestArchiveFormat(t *testing.T) {
+	assert.Equal(t, ArchiveFormatZip, ArchiveFormat("conf.ZIP"))
+	assert.Equal(t, ArchiveFormatTarGz, ArchiveFormat("conf.tar.gz"))
+	assert.Equal(t, ArchiveFormatTarGz, ArchiveFormat("conf.tgz"))
+	assert.Equal(t, "", ArchiveFormat("conf.rar"))
+}
+
+func TestExtractArchive(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		write    func(t *testing.T, entries map[string]string) *bytes.Reader
+		entries  map[string]string
+		maxBytes int64
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name:    "zip",
+			format:  ArchiveFormatZip,
+			write:   writeZip,
+			entries: map[string]string{"a.txt": "a", "dir/b.txt": "bb", "./dir/c.txt": "ccc"},
+			want:    map[string]string{"a.txt": "a", "dir/b.txt": "bb", "dir/c.txt": "ccc"},
+		},
+		{
+			name:    "tar.gz",
+			format:  ArchiveFormatTarGz,
+			write:   writeTarGz,
+			entries: map[string]string{"a.txt": "a", "dir/b.txt": "bb"},
+			want:    map[string]string{"a.txt": "a", "dir/b.txt": "bb"},
+		},
+		{
+			name:    "path traversal",
+			format:  ArchiveFormatTarGz,
+			write:   writeTarGz,
+			entries: map[string]string{"../a.txt": "a"},
+			wantErr: `invalid archive entry "../a.txt"`,
+		},
+		{
+			name:     "size limit",
+			format:   ArchiveFormatZip,
+			write:    writeZip,
+			entries:  map[string]string{"a.txt": "aaaa", "b.txt": "bbbb"},
+			maxBytes: 6,
+			wantErr:  ErrArchiveTooLarge.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			archive := tc.write(t, tc.entries)
+
+			actual := map[string]string{}
+			err := ExtractArchive(archive, archive.Size(), tc.format, tc.maxBytes, func(name string, r io.Reader) error {
+				content, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				actual[name] = string(content)
+				return nil
+			})
+
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, actual)
+		})
+	}
+}
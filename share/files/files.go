@@ -3,6 +3,7 @@ package files
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,12 +28,14 @@ type FileAPI interface {
 	Open(file string) (io.ReadWriteCloser, error)
 	Exist(path string) (bool, error)
 	CreateFile(path string, sourceReader io.Reader) (writtenBytes int64, err error)
+	AppendFile(path string, sourceReader io.Reader) (writtenBytes int64, err error)
 	ChangeOwner(path, owner, group string) error
 	ChangeMode(path string, targetMode os.FileMode) error
 	CreateDirIfNotExists(path string, mode os.FileMode) (wasCreated bool, err error)
 	Remove(name string) error
 	Rename(oldPath, newPath string) error
 	GetFileMode(file string) (os.FileMode, error)
+	GetFileSize(file string) (int64, error)
 	GetFileOwnerAndGroup(file string) (uid, gid uint32, err error)
 }
 
@@ -125,6 +128,15 @@ func (f *FileSystem) GetFileMode(file string) (os.FileMode, error) {
 	return fileInfo.Mode(), nil
 }
 
+func (f *FileSystem) GetFileSize(file string) (int64, error) {
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+
+	return fileInfo.Size(), nil
+}
+
 func (f *FileSystem) GetFileOwnerAndGroup(file string) (uid, gid uint32, err error) {
 	return GetFileUIDAndGID(file)
 }
@@ -199,6 +211,18 @@ func (f *FileSystem) CreateFile(path string, sourceReader io.Reader) (writtenByt
 	return copiedBytes, nil
 }
 
+// AppendFile writes the content of the reader to the end of the file, the file is created if it doesn't exist.
+// Bytes written before an error occurred are kept, so that an interrupted transfer can be continued.
+func (f *FileSystem) AppendFile(path string, sourceReader io.Reader) (writtenBytes int64, err error) {
+	targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, DefaultMode)
+	if err != nil {
+		return 0, err
+	}
+	defer targetFile.Close()
+
+	return io.Copy(targetFile, sourceReader)
+}
+
 func (f *FileSystem) Remove(name string) error {
 	return os.Remove(name)
 }
@@ -217,6 +241,35 @@ func Md5HashFromReader(source io.Reader) (hashSum []byte, err error) {
 	return md5Hash.Sum(nil), nil
 }
 
+func Sha256HashFromReader(source io.Reader) (hashSum []byte, err error) {
+	sha256Hash := sha256.New()
+	_, err = io.Copy(sha256Hash, source)
+	if err != nil {
+		return nil, errors2.Wrapf(err, "failed to calculate sha256 checksum")
+	}
+
+	return sha256Hash.Sum(nil), nil
+}
+
+// ChecksumMatch compares the sha256 checksum of the file if it's given and falls back to md5 otherwise.
+func ChecksumMatch(expectedSha256HashSum, expectedMd5HashSum []byte, path string, fileAPI FileAPI) (match bool, err error) {
+	if len(expectedSha256HashSum) == 0 {
+		return Md5HashMatch(expectedMd5HashSum, path, fileAPI)
+	}
+
+	file, err := fileAPI.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	destinationSha256Hash, err := Sha256HashFromReader(file)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(expectedSha256HashSum, destinationSha256Hash), nil
+}
+
 func Md5HashMatch(expectedHashSum []byte, path string, fileAPI FileAPI) (match bool, err error) {
 	file, err := fileAPI.Open(path)
 	if err != nil {
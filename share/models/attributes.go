@@ -1,6 +1,89 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Attributes struct {
 	Tags   []string          `json:"tags"`
 	Labels map[string]string `json:"labels"`
 }
+
+const (
+	AttributesOperationAddTag      = "add_tag"
+	AttributesOperationRemoveTag   = "remove_tag"
+	AttributesOperationSetLabel    = "set_label"
+	AttributesOperationDeleteLabel = "delete_label"
+)
+
+// AttributesOperation is a single change of the tags or labels of a client
+type AttributesOperation struct {
+	Op    string `json:"op"`
+	Tag   string `json:"tag,omitempty"`
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+func (o AttributesOperation) Validate() error {
+	switch o.Op {
+	case AttributesOperationAddTag, AttributesOperationRemoveTag:
+		if o.Tag == "" {
+			return fmt.Errorf("%s requires a tag", o.Op)
+		}
+	case AttributesOperationSetLabel, AttributesOperationDeleteLabel:
+		if o.Key == "" {
+			return fmt.Errorf("%s requires a key", o.Op)
+		}
+	case "":
+		return errors.New("op is required")
+	default:
+		return fmt.Errorf("unsupported op %q, use one of %s, %s, %s and %s", o.Op,
+			AttributesOperationAddTag, AttributesOperationRemoveTag, AttributesOperationSetLabel, AttributesOperationDeleteLabel)
+	}
+	return nil
+}
+
+// Apply returns a copy of the attributes with the given operations applied in order.
+// Adding an existing tag or removing a missing tag or label is not an error.
+func (a Attributes) Apply(operations []AttributesOperation) Attributes {
+	res := Attributes{
+		Tags:   make([]string, 0, len(a.Tags)),
+		Labels: make(map[string]string, len(a.Labels)),
+	}
+	res.Tags = append(res.Tags, a.Tags...)
+	for k, v := range a.Labels {
+		res.Labels[k] = v
+	}
+
+	for _, o := range operations {
+		switch o.Op {
+		case AttributesOperationAddTag:
+			if !res.hasTag(o.Tag) {
+				res.Tags = append(res.Tags, o.Tag)
+			}
+		case AttributesOperationRemoveTag:
+			tags := res.Tags[:0]
+			for _, tag := range res.Tags {
+				if tag != o.Tag {
+					tags = append(tags, tag)
+				}
+			}
+			res.Tags = tags
+		case AttributesOperationSetLabel:
+			res.Labels[o.Key] = o.Value
+		case AttributesOperationDeleteLabel:
+			delete(res.Labels, o.Key)
+		}
+	}
+	return res
+}
+
+func (a Attributes) hasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributesApply(t *testing.T) {
+	attributes := Attributes{
+		Tags:   []string{"linux", "vm"},
+		Labels: map[string]string{"city": "Cologne", "env": "test"},
+	}
+
+	res := attributes.Apply([]AttributesOperation{
+		{Op: AttributesOperationAddTag, Tag: "web"},
+		{Op: AttributesOperationAddTag, Tag: "linux"},
+		{Op: AttributesOperationRemoveTag, Tag: "vm"},
+		{Op: AttributesOperationRemoveTag, Tag: "unknown"},
+		{Op: AttributesOperationSetLabel, Key: "env", Value: "prod"},
+		{Op: AttributesOperationSetLabel, Key: "team", Value: "ops"},
+		{Op: AttributesOperationDeleteLabel, Key: "city"},
+		{Op: AttributesOperationDeleteLabel, Key: "unknown"},
+	})
+
+	assert.Equal(t, Attributes{
+		Tags:   []string{"linux", "web"},
+		Labels: map[string]string{"env": "prod", "team": "ops"},
+	}, res)
+	// the original attributes are not changed
+	assert.Equal(t, Attributes{
+		Tags:   []string{"linux", "vm"},
+		Labels: map[string]string{"city": "Cologne", "env": "test"},
+	}, attributes)
+
+	assert.Equal(t, Attributes{Tags: []string{}, Labels: map[string]string{}}, Attributes{}.Apply(nil))
+}
+
+func TestAttributesOperationValidate(t *testing.T) {
+	testCases := []struct {
+		Operation AttributesOperation
+		WantErr   string
+	}{
+		{Operation: AttributesOperation{Op: AttributesOperationAddTag, Tag: "web"}},
+		{Operation: AttributesOperation{Op: AttributesOperationSetLabel, Key: "env"}},
+		{Operation: AttributesOperation{Op: AttributesOperationRemoveTag}, WantErr: "remove_tag requires a tag"},
+		{Operation: AttributesOperation{Op: AttributesOperationDeleteLabel, Tag: "env"}, WantErr: "delete_label requires a key"},
+		{Operation: AttributesOperation{}, WantErr: "op is required"},
+		{Operation: AttributesOperation{Op: "rename_tag"}, WantErr: `unsupported op "rename_tag", use one of add_tag, remove_tag, set_label and delete_label`},
+	}
+
+	for _, tc := range testCases {
+		err := tc.Operation.Validate()
+		if tc.WantErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.WantErr)
+		}
+	}
+}
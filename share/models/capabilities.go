@@ -1,7 +1,8 @@
 package models
 
 type Capabilities struct {
-	ServerVersion      string
-	MonitoringVersion  int
-	IPAddressesVersion int
+	ServerVersion         string
+	MonitoringVersion     int
+	IPAddressesVersion    int
+	UploadProgressVersion int
 }
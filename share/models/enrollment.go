@@ -0,0 +1,18 @@
+package models
+
+// EnrollmentTokenUserPrefix is prepended to the token id by clients that connect with an enrollment token
+const EnrollmentTokenUserPrefix = "enrollment-token:"
+
+// EnrollmentCredentials are the client auth credentials created for a client that enrolled with a token
+type EnrollmentCredentials struct {
+	ClientAuthID string `json:"client_auth_id"`
+	Password     string `json:"password"`
+	// Certificate is only set if the server issues client certificates
+	Certificate *ClientCertificate `json:"certificate,omitempty"`
+}
+
+// ClientCertificate is a PEM encoded client certificate and its private key issued by the server
+type ClientCertificate struct {
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+}
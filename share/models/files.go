@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/riportdev/riport/share/files"
 	"github.com/riportdev/riport/share/logger"
 
 	errors2 "github.com/pkg/errors"
@@ -21,6 +22,9 @@ const (
 	uploadedFileModeKey            = "mode"
 	fileWriteForcedKey             = "force"
 	fileSyncdKey                   = "sync"
+	fileExtractKey                 = "extract"
+	fileDeleteExtraneousKey        = "delete_extraneous"
+	fileBandwidthLimitKey          = "bandwidth_limit"
 	IDKey                          = "id"
 )
 
@@ -34,6 +38,26 @@ type UploadedFile struct {
 	ForceWrite           bool
 	Sync                 bool
 	Md5Checksum          []byte
+	Sha256Checksum       []byte `json:",omitempty"`
+	// Size is used to resume interrupted transfers and to report the progress
+	Size int64 `json:",omitempty"`
+	// BandwidthLimit limits the transfer to the given bytes per second
+	BandwidthLimit int64 `json:",omitempty"`
+	// Extract indicates that the uploaded file is an archive which should be unpacked to the destination directory
+	Extract bool `json:",omitempty"`
+	// DeleteExtraneous removes files from the destination directory which are not contained in the archive
+	DeleteExtraneous bool `json:",omitempty"`
+	// Files contains the unpacked files of an archive, SourceFilePath is the directory holding them on the server
+	Files []UploadedDirEntry `json:",omitempty"`
+}
+
+type UploadedDirEntry struct {
+	// Path is the slash separated file path relative to the destination directory
+	Path           string
+	SourceFilePath string
+	Md5Checksum    []byte
+	Sha256Checksum []byte
+	Size           int64
 }
 
 func (uf UploadedFile) Validate() error {
@@ -48,6 +72,38 @@ func (uf UploadedFile) Validate() error {
 	return nil
 }
 
+func (uf UploadedFile) ValidateDirEntries() error {
+	for _, entry := range uf.Files {
+		cleanPath, err := files.CleanArchiveEntryName(entry.Path)
+		if err != nil || cleanPath != entry.Path {
+			return fmt.Errorf("invalid file path %q", entry.Path)
+		}
+		if entry.SourceFilePath == "" {
+			return fmt.Errorf("empty source file name for %s", entry.Path)
+		}
+	}
+
+	return nil
+}
+
+// DirEntryFile returns the upload of a single file of an unpacked archive, it inherits the owner, group, mode and write flags.
+func (uf UploadedFile) DirEntryFile(entry UploadedDirEntry) *UploadedFile {
+	return &UploadedFile{
+		ID:                   uf.ID,
+		SourceFilePath:       entry.SourceFilePath,
+		DestinationPath:      filepath.Join(uf.DestinationPath, filepath.FromSlash(entry.Path)),
+		DestinationFileMode:  uf.DestinationFileMode,
+		DestinationFileOwner: uf.DestinationFileOwner,
+		DestinationFileGroup: uf.DestinationFileGroup,
+		ForceWrite:           uf.ForceWrite,
+		Sync:                 uf.Sync,
+		Md5Checksum:          entry.Md5Checksum,
+		Sha256Checksum:       entry.Sha256Checksum,
+		Size:                 entry.Size,
+		BandwidthLimit:       uf.BandwidthLimit,
+	}
+}
+
 func (uf UploadedFile) ValidateDestinationPath(globPatters []string, log *logger.Logger) error {
 	destinationDir := filepath.Dir(uf.DestinationPath)
 	for _, p := range globPatters {
@@ -113,6 +169,30 @@ func (uf *UploadedFile) FromMultipartRequest(req *http.Request) error {
 			return err
 		}
 	}
+	if len(req.MultipartForm.Value[fileExtractKey]) > 0 {
+		uf.Extract, err = strconv.ParseBool(req.MultipartForm.Value[fileExtractKey][0])
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(req.MultipartForm.Value[fileDeleteExtraneousKey]) > 0 {
+		uf.DeleteExtraneous, err = strconv.ParseBool(req.MultipartForm.Value[fileDeleteExtraneousKey][0])
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(req.MultipartForm.Value[fileBandwidthLimitKey]) > 0 {
+		uf.BandwidthLimit, err = strconv.ParseInt(req.MultipartForm.Value[fileBandwidthLimitKey][0], 10, 64)
+		if err != nil {
+			return errors2.Wrapf(err, "failed to parse bandwidth limit value %s", req.MultipartForm.Value[fileBandwidthLimitKey][0])
+		}
+		if uf.BandwidthLimit < 0 {
+			return errors.New("bandwidth limit cannot be negative")
+		}
+	}
+
 	if len(req.MultipartForm.Value[IDKey]) > 0 {
 		uf.ID = req.MultipartForm.Value[IDKey][0]
 	}
@@ -132,6 +212,16 @@ type UploadResponse struct {
 	UploadResponseShort
 	Message string `json:"message"`
 	Status  string `json:"status"`
+	// TotalBytes is set for progress events only, SizeBytes contains the received bytes then
+	TotalBytes int64 `json:"total_size,omitempty"`
+}
+
+// UploadProgress is sent by clients while receiving a file
+type UploadProgress struct {
+	ID            string `json:"id"`
+	Filepath      string `json:"filepath"`
+	ReceivedBytes int64  `json:"received_bytes"`
+	TotalBytes    int64  `json:"total_bytes"`
 }
 
 type UploadResponseShort struct {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/riportdev/riport/share/logger"
@@ -246,6 +247,53 @@ func TestUploadedFileFromMultipartRequest(t *testing.T) {
 				Sync:                 true,
 			},
 		},
+		{
+			name: "archive",
+			formParts: map[string][]string{
+				"dest": {
+					"/etc/nginx",
+				},
+				"sync": {
+					"true",
+				},
+				"extract": {
+					"true",
+				},
+				"delete_extraneous": {
+					"true",
+				},
+			},
+			wantUploadedFile: &UploadedFile{
+				DestinationPath:  "/etc/nginx",
+				Sync:             true,
+				Extract:          true,
+				DeleteExtraneous: true,
+			},
+		},
+		{
+			name: "bandwidth limit",
+			formParts: map[string][]string{
+				"dest": {
+					"/destination/myfile.txt",
+				},
+				"bandwidth_limit": {
+					"1048576",
+				},
+			},
+			wantUploadedFile: &UploadedFile{
+				DestinationPath: "/destination/myfile.txt",
+				BandwidthLimit:  1048576,
+			},
+		},
+		{
+			name: "negative_bandwidth_limit",
+			formParts: map[string][]string{
+				"bandwidth_limit": {
+					"-1",
+				},
+			},
+			wantErr: "bandwidth limit cannot be negative",
+		},
 		{
 			name: "invalid_file_mode",
 			formParts: map[string][]string{
@@ -299,3 +347,74 @@ func TestFromBytes(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, validUploadedFile, actualUploadedFile)
 }
+
+func TestValidateDirEntries(t *testing.T) {
+	testCases := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			path: "conf.d/default.conf",
+		},
+		{
+			name:    "parent dir",
+			path:    "../passwd",
+			wantErr: `invalid file path "../passwd"`,
+		},
+		{
+			name:    "absolute",
+			path:    "/etc/passwd",
+			wantErr: `invalid file path "/etc/passwd"`,
+		},
+		{
+			name:    "not clean",
+			path:    "conf.d/../../passwd",
+			wantErr: `invalid file path "conf.d/../../passwd"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			uf := UploadedFile{
+				Files: []UploadedDirEntry{{Path: tc.path, SourceFilePath: "/source/1"}},
+			}
+
+			err := uf.ValidateDirEntries()
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDirEntryFile(t *testing.T) {
+	uf := UploadedFile{
+		ID:                   "123",
+		SourceFilePath:       "/source/dir",
+		DestinationPath:      "/etc/nginx",
+		DestinationFileMode:  0644,
+		DestinationFileOwner: "www",
+		DestinationFileGroup: "www",
+		Sync:                 true,
+		Extract:              true,
+		DeleteExtraneous:     true,
+	}
+
+	actual := uf.DirEntryFile(UploadedDirEntry{Path: "conf.d/default.conf", SourceFilePath: "/source/dir/1", Md5Checksum: []byte("md5")})
+
+	assert.Equal(t, &UploadedFile{
+		ID:                   "123",
+		SourceFilePath:       "/source/dir/1",
+		DestinationPath:      filepath.Join("/etc/nginx", "conf.d", "default.conf"),
+		DestinationFileMode:  0644,
+		DestinationFileOwner: "www",
+		DestinationFileGroup: "www",
+		Sync:                 true,
+		Md5Checksum:          []byte("md5"),
+	}, actual)
+}
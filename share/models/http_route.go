@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderRuleSet    = "set"
+	HeaderRuleAdd    = "add"
+	HeaderRuleRemove = "remove"
+
+	httpRouteStripPrefixOption = "strip_prefix"
+)
+
+// HTTPRoute forwards the requests of a tunnel proxy matching the path prefix to another target on the client network
+type HTTPRoute struct {
+	PathPrefix  string `json:"path_prefix"`
+	Target      string `json:"target"`
+	StripPrefix bool   `json:"strip_prefix"`
+}
+
+// ParseHTTPRoute parses routes in the format <path prefix>=<host>:<port>[;strip_prefix]
+func ParseHTTPRoute(s string) (HTTPRoute, error) {
+	route := HTTPRoute{}
+
+	value, option, found := strings.Cut(s, ";")
+	if found {
+		if option != httpRouteStripPrefixOption {
+			return route, fmt.Errorf("invalid route option %q", option)
+		}
+		route.StripPrefix = true
+	}
+
+	prefix, target, found := strings.Cut(value, "=")
+	if !found {
+		return route, errors.New("route must be in the format <path prefix>=<host>:<port>")
+	}
+	if !strings.HasPrefix(prefix, "/") || prefix == "/" {
+		return route, fmt.Errorf("invalid path prefix %q: must start with / and must not be the root path", prefix)
+	}
+	host, port, err := net.SplitHostPort(target)
+	if err != nil || host == "" || !isPort(port) {
+		return route, fmt.Errorf("invalid target %q: must be <host>:<port>", target)
+	}
+
+	route.PathPrefix = prefix
+	route.Target = target
+	return route, nil
+}
+
+// HeaderRule rewrites a header of requests or responses passing a tunnel proxy, the value is ignored for remove
+type HeaderRule struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+}
+
+// ParseHeaderRule parses rules in the format set:<name>:<value>, add:<name>:<value> or remove:<name>
+func ParseHeaderRule(s string) (HeaderRule, error) {
+	parts := strings.SplitN(s, ":", 3)
+	rule := HeaderRule{
+		Action: parts[0],
+	}
+	if len(parts) > 1 {
+		rule.Name = strings.TrimSpace(parts[1])
+	}
+	if len(parts) > 2 {
+		rule.Value = strings.TrimSpace(parts[2])
+	}
+
+	switch rule.Action {
+	case HeaderRuleSet, HeaderRuleAdd:
+		if len(parts) != 3 {
+			return rule, fmt.Errorf("header rule %q requires a name and a value", rule.Action)
+		}
+	case HeaderRuleRemove:
+		if len(parts) != 2 {
+			return rule, errors.New("header rule remove requires a name only")
+		}
+	default:
+		return rule, fmt.Errorf("invalid header rule action %q", rule.Action)
+	}
+	if rule.Name == "" {
+		return rule, errors.New("header rule name must not be empty")
+	}
+	return rule, nil
+}
+
+// Apply rewrites the given headers according to the rule
+func (r HeaderRule) Apply(header http.Header) {
+	switch r.Action {
+	case HeaderRuleSet:
+		header.Set(r.Name, r.Value)
+	case HeaderRuleAdd:
+		header.Add(r.Name, r.Value)
+	case HeaderRuleRemove:
+		header.Del(r.Name)
+	}
+}
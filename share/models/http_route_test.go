@@ -0,0 +1,128 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPRoute(t *testing.T) {
+	testCases := []struct {
+		Input     string
+		WantRoute HTTPRoute
+		WantErr   string
+	}{
+		{
+			Input: "/cam1=192.168.1.20:80",
+			WantRoute: HTTPRoute{
+				PathPrefix: "/cam1",
+				Target:     "192.168.1.20:80",
+			},
+		},
+		{
+			Input: "/printer/=printer.local:8080;strip_prefix",
+			WantRoute: HTTPRoute{
+				PathPrefix:  "/printer/",
+				Target:      "printer.local:8080",
+				StripPrefix: true,
+			},
+		},
+		{
+			Input:   "/cam1=192.168.1.20:80;other",
+			WantErr: `invalid route option "other"`,
+		},
+		{
+			Input:   "/cam1",
+			WantErr: "route must be in the format <path prefix>=<host>:<port>",
+		},
+		{
+			Input:   "cam1=192.168.1.20:80",
+			WantErr: `invalid path prefix "cam1": must start with / and must not be the root path`,
+		},
+		{
+			Input:   "/=192.168.1.20:80",
+			WantErr: `invalid path prefix "/": must start with / and must not be the root path`,
+		},
+		{
+			Input:   "/cam1=192.168.1.20",
+			WantErr: `invalid target "192.168.1.20": must be <host>:<port>`,
+		},
+		{
+			Input:   "/cam1=:80",
+			WantErr: `invalid target ":80": must be <host>:<port>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Input, func(t *testing.T) {
+			route, err := ParseHTTPRoute(tc.Input)
+			if tc.WantErr != "" {
+				assert.EqualError(t, err, tc.WantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.WantRoute, route)
+		})
+	}
+}
+
+func TestHeaderRules(t *testing.T) {
+	testCases := []struct {
+		Input      string
+		WantRule   HeaderRule
+		WantHeader http.Header
+		WantErr    string
+	}{
+		{
+			Input:      "set:X-Forwarded-Proto:https",
+			WantRule:   HeaderRule{Action: HeaderRuleSet, Name: "X-Forwarded-Proto", Value: "https"},
+			WantHeader: http.Header{"X-Forwarded-Proto": {"https"}, "Server": {"nginx"}, "Via": {"proxy"}},
+		},
+		{
+			Input:      "add:Via: riport",
+			WantRule:   HeaderRule{Action: HeaderRuleAdd, Name: "Via", Value: "riport"},
+			WantHeader: http.Header{"Server": {"nginx"}, "Via": {"proxy", "riport"}},
+		},
+		{
+			Input:      "remove:Server",
+			WantRule:   HeaderRule{Action: HeaderRuleRemove, Name: "Server"},
+			WantHeader: http.Header{"Via": {"proxy"}},
+		},
+		{
+			Input:   "set:X-Foo",
+			WantErr: `header rule "set" requires a name and a value`,
+		},
+		{
+			Input:   "remove:Server:nginx",
+			WantErr: "header rule remove requires a name only",
+		},
+		{
+			Input:   "replace:Server:nginx",
+			WantErr: `invalid header rule action "replace"`,
+		},
+		{
+			Input:   "remove: ",
+			WantErr: "header rule name must not be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Input, func(t *testing.T) {
+			rule, err := ParseHeaderRule(tc.Input)
+			if tc.WantErr != "" {
+				assert.EqualError(t, err, tc.WantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.WantRule, rule)
+
+			header := http.Header{"Server": {"nginx"}, "Via": {"proxy"}}
+			rule.Apply(header)
+			assert.Equal(t, tc.WantHeader, header)
+		})
+	}
+}
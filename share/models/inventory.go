@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	InventoryCategoryPackage       = "package"
+	InventoryCategoryDisk          = "disk"
+	InventoryCategoryNIC           = "nic"
+	InventoryCategoryListeningPort = "listening_port"
+	InventoryCategoryUser          = "user"
+	InventoryCategorySystem        = "system"
+)
+
+// Inventory holds the installed software, hardware and network details collected by a client
+type Inventory struct {
+	CollectedAt time.Time        `json:"collected_at"`
+	Items       []*InventoryItem `json:"items"`
+	// Errors by category of failed collectors, the items of the other collectors are sent anyway
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// InventoryItem is identified by category and name, e.g. package "openssl" or nic "eth0"
+type InventoryItem struct {
+	Category string            `json:"category"`
+	Name     string            `json:"name"`
+	Version  string            `json:"version,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+func (i *InventoryItem) Key() string {
+	return i.Category + "/" + i.Name
+}
+
+// InventorySummary holds the inventory values client groups can match on
+type InventorySummary struct {
+	// Packages are the names of the installed packages
+	Packages []string `json:"packages"`
+	// ListeningPorts are protocol and port, e.g. tcp/22, regardless of the listening address
+	ListeningPorts []string `json:"listening_ports"`
+	LocalUsers     []string `json:"local_users"`
+	SystemVendor   string   `json:"system_vendor"`
+	SystemModel    string   `json:"system_model"`
+}
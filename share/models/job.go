@@ -11,9 +11,22 @@ const (
 	JobStatusRunning    = "running"
 	JobStatusFailed     = "failed"
 	JobStatusUnknown    = "unknown"
+	JobStatusPending    = "pending" // waits for the client to reconnect
 
 	ChannelStdout = "stdout"
 	ChannelStderr = "stderr"
+
+	MultiJobStatusRunning         = "running"
+	MultiJobStatusPaused          = "paused"
+	MultiJobStatusWaitingApproval = "waiting_approval"
+	MultiJobStatusFinished        = "finished"
+	MultiJobStatusAborted         = "aborted"
+	MultiJobStatusInterrupted     = "interrupted" // the server stopped while the job was running
+
+	BatchStatusPending    = "pending"
+	BatchStatusRunning    = "running"
+	BatchStatusSuccessful = "successful"
+	BatchStatusFailed     = "failed"
 )
 
 type Job struct {
@@ -36,6 +49,8 @@ type Job struct {
 	IsSudo       bool       `json:"is_sudo"`
 	IsScript     bool       `json:"is_script"`
 	StreamResult bool       `json:"stream_result"`
+	// ExpiresAt is set only for pending jobs, if the client doesn't reconnect until then the job fails
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type JobResult struct {
@@ -64,6 +79,60 @@ type MultiJob struct {
 	Jobs        []*Job         `json:"jobs"`
 	IsSudo      bool           `json:"is_sudo"`
 	IsScript    bool           `json:"is_script"`
+
+	DeliverOnReconnect bool `json:"deliver_on_reconnect"`
+	DeliverExpirySec   int  `json:"deliver_expiry_sec"`
+
+	// fields below are set only for multi-client jobs started with an execution strategy
+	Strategy           *MultiJobStrategy `json:"strategy,omitempty"`
+	Status             string            `json:"status,omitempty"`
+	Batches            []*MultiJobBatch  `json:"batches,omitempty"`
+	RemainingClientIDs []string          `json:"remaining_client_ids,omitempty"`
+	// NodeID is the cluster node that runs the job, empty unless clustering is enabled
+	NodeID string `json:"-"`
+}
+
+// MultiJobStrategy defines how a multi-client job is rolled out across its clients.
+type MultiJobStrategy struct {
+	// BatchSize is a number of clients to run the job on at a time. 0 means all remaining clients in one batch.
+	BatchSize int `json:"batch_size"`
+	// MaxFailureRate is a max percentage (0-100) of failed jobs in a batch that still allows to proceed
+	// with the next batch. If not set, all batches are executed regardless of failures.
+	MaxFailureRate *float64 `json:"max_failure_rate"`
+	// CanarySize is a number of clients to run the job on first before the rest. 0 disables the canary batch.
+	CanarySize int `json:"canary_size"`
+	// CanaryWaitSec is a delay after a successful canary batch before the rest is started.
+	// 0 means the rest is started only after a manual approval.
+	CanaryWaitSec int `json:"canary_wait_sec"`
+}
+
+type MultiJobBatch struct {
+	Index      int        `json:"index"`
+	Canary     bool       `json:"canary"`
+	ClientIDs  []string   `json:"client_ids"`
+	Status     string     `json:"status"`
+	Successful int        `json:"successful"`
+	Failed     int        `json:"failed"`
+	Pending    int        `json:"pending"` // jobs of disconnected clients waiting for a reconnect
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// FailureRate returns a percentage of failed jobs in the batch.
+func (b *MultiJobBatch) FailureRate() float64 {
+	if len(b.ClientIDs) == 0 {
+		return 0
+	}
+	return float64(b.Failed) * 100 / float64(len(b.ClientIDs))
+}
+
+// DeliverUntil returns a time until jobs of disconnected clients wait for a reconnect, nil if it's not enabled.
+func (mj *MultiJob) DeliverUntil() *time.Time {
+	if !mj.DeliverOnReconnect {
+		return nil
+	}
+	until := mj.StartedAt.Add(time.Duration(mj.DeliverExpirySec) * time.Second)
+	return &until
 }
 
 type MultiJobSummary struct {
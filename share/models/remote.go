@@ -30,6 +30,10 @@ const (
 	ProtocolTCP    = "tcp"
 	ProtocolUDP    = "udp"
 	ProtocolTCPUDP = "tcp+udp"
+	// ProtocolSOCKS5 tunnels forward every CONNECT request to the requested destination instead of a fixed remote
+	ProtocolSOCKS5 = "socks5"
+	// TunnelAuthModeSession requires a riport API session to use the tunnel proxy
+	TunnelAuthModeSession = "session"
 )
 
 var protocolRe = regexp.MustCompile(`(.*)\/(tcp|udp|tcp\+udp)$`)
@@ -53,6 +57,19 @@ type Remote struct {
 	AuthUser           string        `json:"auth_user"`
 	AuthPassword       string        `json:"auth_password"`
 	TunnelURL          string        `json:"tunnel_url"`
+	Direct             bool          `json:"direct,omitempty"`
+	AuthMode           string        `json:"auth_mode,omitempty"`
+	HTTPRoutes         []HTTPRoute   `json:"http_routes,omitempty"`
+	RequestHeaders     []HeaderRule  `json:"request_headers,omitempty"`
+	ResponseHeaders    []HeaderRule  `json:"response_headers,omitempty"`
+	AuthHeaderName     string        `json:"auth_header_name,omitempty"`
+	AuthHeaderVaultID  int           `json:"auth_header_vault_id,omitempty"`
+	MaxRate            int64         `json:"max_rate,omitempty"` // bytes per second in each direction
+	Quota              int64         `json:"quota,omitempty"`    // total bytes after which the tunnel is terminated
+	// StoredTunnelID is set if the tunnel was started from a persistent stored tunnel
+	StoredTunnelID string `json:"stored_tunnel_id,omitempty"`
+	// AuthHeaderValue is read from the vault when the tunnel is created, it's never exposed
+	AuthHeaderValue string `json:"-"`
 }
 
 func NewRemote(s string) (*Remote, error) {
@@ -102,6 +119,36 @@ func NewRemote(s string) (*Remote, error) {
 	return r, nil
 }
 
+// NewSOCKS5Remote returns a socks5 remote listening on the given local address, a random port is used if local is empty
+func NewSOCKS5Remote(local string) (*Remote, error) {
+	r := &Remote{
+		Protocol: ProtocolSOCKS5,
+	}
+	if local == "" {
+		return r, nil
+	}
+
+	parts := strings.Split(local, ":")
+	switch len(parts) {
+	case 1:
+		r.LocalHost = ZeroHost
+		r.LocalPort = parts[0]
+	case 2:
+		r.LocalHost = parts[0]
+		r.LocalPort = parts[1]
+	default:
+		return nil, errors.New("Invalid local")
+	}
+
+	if !isPort(r.LocalPort) {
+		return nil, errors.New("Invalid local port")
+	}
+	if !isHost(r.LocalHost) {
+		return nil, errors.New("Invalid host")
+	}
+	return r, nil
+}
+
 var isPortRegExp = regexp.MustCompile(`^\d+$`)
 
 func isPort(s string) bool {
@@ -152,6 +199,19 @@ func (r *Remote) IsProtocol(other string) bool {
 	return false
 }
 
+// IsSOCKS5 returns true for socks5 tunnels, they have no fixed remote host and port
+func (r *Remote) IsSOCKS5() bool {
+	return r.Protocol == ProtocolSOCKS5
+}
+
+// ListenProtocol returns the protocol of the server side listener
+func (r *Remote) ListenProtocol() string {
+	if r.IsSOCKS5() {
+		return ProtocolTCP
+	}
+	return r.Protocol
+}
+
 func (r *Remote) EqualACL(acl *string) bool {
 	if r.ACL != nil && acl != nil {
 		return *r.ACL == *acl
@@ -119,6 +119,56 @@ func TestDecodeRemote(t *testing.T) {
 	}
 }
 
+func TestNewSOCKS5Remote(t *testing.T) {
+	testCases := []struct {
+		Input         string
+		WantLocalHost string
+		WantLocalPort string
+		WantErr       string
+	}{
+		{
+			Input: "",
+		},
+		{
+			Input:         "1080",
+			WantLocalHost: ZeroHost,
+			WantLocalPort: "1080",
+		},
+		{
+			Input:         "127.0.0.1:1080",
+			WantLocalHost: "127.0.0.1",
+			WantLocalPort: "1080",
+		},
+		{
+			Input:   "127.0.0.1:abc",
+			WantErr: "Invalid local port",
+		},
+		{
+			Input:   "1:2:3",
+			WantErr: "Invalid local",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Input, func(t *testing.T) {
+			t.Parallel()
+
+			remote, err := NewSOCKS5Remote(tc.Input)
+			if tc.WantErr != "" {
+				require.EqualError(t, err, tc.WantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, ProtocolSOCKS5, remote.Protocol)
+			assert.Equal(t, ProtocolTCP, remote.ListenProtocol())
+			assert.Equal(t, tc.WantLocalHost, remote.LocalHost)
+			assert.Equal(t, tc.WantLocalPort, remote.LocalPort)
+			assert.Empty(t, remote.RemoteHost)
+		})
+	}
+}
+
 func TestIsProtocol(t *testing.T) {
 	testCases := []struct {
 		Protocol      string
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxChunkDuration limits the size of a single read, so that the limit is applied smoothly to large buffers.
+const maxChunkDuration = 100 * time.Millisecond
+
+// Limiter paces the throughput of one or many readers to the given number of bytes per second.
+// A nil Limiter doesn't limit anything.
+type Limiter struct {
+	bytesPerSec int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter returns a limiter of the given bytes per second or nil if bytesPerSec is not positive.
+func NewLimiter(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &Limiter{
+		bytesPerSec: bytesPerSec,
+	}
+}
+
+// Wait blocks until n more bytes can be transferred without exceeding the limit.
+func (l *Limiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSec))
+	l.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (l *Limiter) chunkSize() int {
+	if l == nil {
+		return 0
+	}
+	size := l.bytesPerSec * int64(maxChunkDuration) / int64(time.Second)
+	if size < 1 {
+		return 1
+	}
+	return int(size)
+}
+
+type reader struct {
+	r        io.Reader
+	limiters []*Limiter
+	maxChunk int
+}
+
+// NewReader returns a reader which doesn't exceed any of the given limiters, nil limiters are ignored.
+func NewReader(r io.Reader, limiters ...*Limiter) io.Reader {
+	res := &reader{
+		r: r,
+	}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		res.limiters = append(res.limiters, l)
+		if res.maxChunk == 0 || l.chunkSize() < res.maxChunk {
+			res.maxChunk = l.chunkSize()
+		}
+	}
+	if len(res.limiters) == 0 {
+		return r
+	}
+	return res
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > r.maxChunk {
+		p = p[:r.maxChunk]
+	}
+
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		l.Wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReaderWithoutLimiters(t *testing.T) {
+	r := bytes.NewReader([]byte("data"))
+
+	assert.Equal(t, r, NewReader(r, nil, NewLimiter(0)))
+}
+
+func TestReaderLimit(t *testing.T) {
+	data := make([]byte, 3000)
+	perTransfer := NewLimiter(10000)
+	perClient := NewLimiter(5000)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, NewReader(bytes.NewReader(data), perTransfer, perClient))
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+
+	assert.Equal(t, int64(3000), n)
+	// the first chunk passes immediately, the remaining 2500 bytes are paced by the lower limit
+	assert.GreaterOrEqual(t, elapsed, 450*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestLimiterIsShared(t *testing.T) {
+	perClient := NewLimiter(10000)
+
+	start := time.Now()
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, _ = io.Copy(io.Discard, NewReader(bytes.NewReader(make([]byte, 2000)), perClient))
+			done <- struct{}{}
+		}()
+	}
+	<-done
+	<-done
+
+	// 4000 bytes of both readers are limited together
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"io"
 	"os"
 	"time"
@@ -68,6 +69,12 @@ func (f *FileAPIMock) CreateFile(path string, sourceReader io.Reader) (writtenBy
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (f *FileAPIMock) AppendFile(path string, sourceReader io.Reader) (writtenBytes int64, err error) {
+	args := f.Called(path, sourceReader)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (f *FileAPIMock) ChangeOwner(path, owner, group string) error {
 	args := f.Called(path, owner, group)
 
@@ -104,6 +111,12 @@ func (f *FileAPIMock) GetFileMode(file string) (os.FileMode, error) {
 	return args.Get(0).(os.FileMode), args.Error(1)
 }
 
+func (f *FileAPIMock) GetFileSize(file string) (int64, error) {
+	args := f.Called(file)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (f *FileAPIMock) GetFileOwnerAndGroup(file string) (uid, gid uint32, err error) {
 	args := f.Called(file)
 
@@ -129,3 +142,9 @@ func Md5Hash(input string) []byte {
 
 	return hashSum[:]
 }
+
+func Sha256Hash(input string) []byte {
+	hashSum := sha256.Sum256([]byte(input))
+
+	return hashSum[:]
+}
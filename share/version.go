@@ -17,3 +17,6 @@ const MonitoringVersion = 1
 
 // IPAddressesVersion represents the current version of IPAddresses fetching. 0 means no IPAddress fetching available.
 const IPAddressesVersion = 1
+
+// UploadProgressVersion represents the current version of upload progress events. 0 means clients shouldn't send the progress.
+const UploadProgressVersion = 1